@@ -0,0 +1,142 @@
+// Command configcheck validates the server configuration without starting the
+// server, so that configuration mistakes are caught before a restart.
+//
+// Usage:
+//
+//	configcheck [-env settings.env] [-config settings.yaml]
+//
+// By default, configuration is read from the process environment. If -env is
+// set, the variables are read from the given settings file instead. If
+// -config is set, settings are read from the given YAML file, with the
+// variables taking precedence over it.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pchchv/go-icq/config"
+)
+
+var logLevels = []string{"trace", "debug", "info", "warn", "error"}
+
+func main() {
+	envFile := flag.String("env", "", "path to a settings file to check instead of the process environment")
+	yamlFile := flag.String("config", "", "path to a YAML settings file, overridden by the environment")
+	flag.Parse()
+
+	lookup := os.LookupEnv
+	if *envFile != "" {
+		f, err := os.Open(*envFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to open settings file: %s\n", err)
+			os.Exit(1)
+		}
+		vars, err := config.ParseEnvFile(f)
+		_ = f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to parse settings file %s: %s\n", *envFile, err)
+			os.Exit(1)
+		}
+		lookup = func(key string) (string, bool) {
+			v, ok := vars[key]
+			return v, ok
+		}
+	}
+
+	var cfg config.Config
+	var err error
+	if *yamlFile != "" {
+		f, openErr := os.Open(*yamlFile)
+		if openErr != nil {
+			fmt.Fprintf(os.Stderr, "unable to open YAML settings file: %s\n", openErr)
+			os.Exit(1)
+		}
+		cfg, err = config.Load(f, lookup)
+		_ = f.Close()
+	} else {
+		cfg, err = config.FromEnv(lookup)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FAIL load: %s\n", err)
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, c := range checks {
+		if err := c.fn(cfg); err != nil {
+			failed = true
+			fmt.Printf("FAIL %s: %s\n", c.name, err)
+		} else {
+			fmt.Printf("ok   %s\n", c.name)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+var checks = []struct {
+	name string
+	fn   func(cfg config.Config) error
+}{
+	{name: "field values", fn: func(cfg config.Config) error { return cfg.Validate() }},
+	{name: "listeners", fn: checkListeners},
+	{name: "database path", fn: checkDBPath},
+	{name: "log level", fn: checkLogLevel},
+}
+
+// checkListeners verifies that every OSCAR listener has matching listen and
+// advertised addresses.
+func checkListeners(cfg config.Config) error {
+	_, err := cfg.ParseListenersCfg()
+	return err
+}
+
+// checkDBPath verifies that the database file, or the directory it will be
+// created in, is writable. In-memory databases and SQLite URIs aren't
+// checked, since they may not refer to a writable file.
+func checkDBPath(cfg config.Config) error {
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return errors.New("DB_PATH is empty")
+	}
+	if cfg.DBPath == ":memory:" || strings.HasPrefix(cfg.DBPath, "file:") {
+		return nil
+	}
+
+	info, err := os.Stat(cfg.DBPath)
+	switch {
+	case err == nil:
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory, expected a SQLite database file", cfg.DBPath)
+		}
+		f, err := os.OpenFile(cfg.DBPath, os.O_RDWR, 0)
+		if err != nil {
+			return fmt.Errorf("database file is not writable: %w", err)
+		}
+		return f.Close()
+	case errors.Is(err, os.ErrNotExist):
+		dir := filepath.Dir(cfg.DBPath)
+		f, err := os.CreateTemp(dir, ".configcheck-*")
+		if err != nil {
+			return fmt.Errorf("database file does not exist and can't be created in %s: %w", dir, err)
+		}
+		_ = f.Close()
+		return os.Remove(f.Name())
+	default:
+		return err
+	}
+}
+
+func checkLogLevel(cfg config.Config) error {
+	if !slices.Contains(logLevels, cfg.LogLevel) {
+		return fmt.Errorf("unknown log level %q, possible values: %s", cfg.LogLevel, strings.Join(logLevels, ", "))
+	}
+	return nil
+}
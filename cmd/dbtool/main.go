@@ -0,0 +1,214 @@
+// Command dbtool performs maintenance tasks on the server's SQLite database.
+//
+// Usage:
+//
+//	dbtool migrate [-db go-icq.sqlite] [-dry-run]
+//	dbtool seed [-db go-icq.sqlite] -file seed.yaml
+//	dbtool downgrade [-db go-icq.sqlite] -to VERSION
+//
+// migrate applies pending schema migrations. With -dry-run, it lists the
+// migrations that would be applied and runs them against a temporary copy of
+// the database to estimate their duration and catch errors, leaving the
+// database untouched.
+//
+// seed creates the users, bots, chat rooms, keyword categories and reserved
+// screen names declared in a YAML seed file that don't exist yet, like the
+// server does at startup when SEED_FILE is set. The seed file defaults to
+// the SEED_FILE environment variable.
+//
+// downgrade reverts the schema migrations applied after VERSION, so that the
+// database can be opened by an older release. Run it with the release that
+// upgraded the database, since older releases don't know how to revert
+// newer migrations; the older release's error message names the VERSION it
+// needs. Back up the database first: data stored in reverted tables and
+// columns is lost.
+//
+// The database defaults to the DB_PATH environment variable.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/pchchv/go-icq/state"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch os.Args[1] {
+	case "migrate":
+		os.Exit(runMigrate(ctx, os.Args[2:]))
+	case "seed":
+		os.Exit(runSeed(ctx, os.Args[2:]))
+	case "downgrade":
+		os.Exit(runDowngrade(ctx, os.Args[2:]))
+	case "move-offline-messages":
+		os.Exit(runMoveOfflineMessages(ctx, os.Args[2:]))
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: dbtool migrate [-db PATH] [-dry-run]")
+	fmt.Fprintln(os.Stderr, "       dbtool seed [-db PATH] [-file PATH]")
+	fmt.Fprintln(os.Stderr, "       dbtool downgrade [-db PATH] -to VERSION")
+	fmt.Fprintln(os.Stderr, "       dbtool move-offline-messages [-db PATH] -from SCREEN_NAME -to SCREEN_NAME")
+	os.Exit(2)
+}
+
+func runMigrate(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dbPath := fs.String("db", os.Getenv("DB_PATH"), "path to the SQLite database file")
+	dryRun := fs.Bool("dry-run", false, "report pending migrations and run them against a temporary copy of the database")
+	_ = fs.Parse(args)
+
+	if *dbPath == "" {
+		fmt.Fprintln(os.Stderr, "no database given, set -db or DB_PATH")
+		return 2
+	}
+
+	if !*dryRun {
+		if _, err := state.NewSQLiteUserStore(*dbPath); err != nil {
+			fmt.Fprintf(os.Stderr, "FAIL migrate: %s\n", err)
+			return 1
+		}
+		fmt.Println("ok   migrations applied")
+		return 0
+	}
+
+	report, err := state.MigrateDryRun(ctx, *dbPath)
+	fmt.Printf("current schema version: %d", report.CurrentVersion)
+	if report.Dirty {
+		fmt.Print(" (dirty)")
+	}
+	fmt.Println()
+
+	if len(report.Pending) == 0 && err == nil {
+		fmt.Println("schema is up to date")
+		return 0
+	}
+	for i, p := range report.Pending {
+		switch {
+		case i < report.Applied:
+			fmt.Printf("ok   %04d_%s (%s)\n", p.Version, p.Name, p.Duration)
+		case i == report.Applied && err != nil:
+			fmt.Printf("FAIL %04d_%s (%s)\n", p.Version, p.Name, p.Duration)
+		default:
+			fmt.Printf("     %04d_%s (not run)\n", p.Version, p.Name)
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FAIL dry run: %s\n", err)
+		return 1
+	}
+	fmt.Printf("%d migrations applied to a copy in %s\n", report.Applied, report.Duration)
+	return 0
+}
+
+func runSeed(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	dbPath := fs.String("db", os.Getenv("DB_PATH"), "path to the SQLite database file")
+	seedPath := fs.String("file", os.Getenv("SEED_FILE"), "path to the YAML seed file")
+	_ = fs.Parse(args)
+
+	if *dbPath == "" {
+		fmt.Fprintln(os.Stderr, "no database given, set -db or DB_PATH")
+		return 2
+	}
+	if *seedPath == "" {
+		fmt.Fprintln(os.Stderr, "no seed file given, set -file or SEED_FILE")
+		return 2
+	}
+
+	seed, err := state.LoadSeed(*seedPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FAIL seed: %s\n", err)
+		return 1
+	}
+	store, err := state.NewSQLiteUserStore(*dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FAIL seed: %s\n", err)
+		return 1
+	}
+	report, err := store.ApplySeed(ctx, seed)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FAIL seed: %s\n", err)
+		return 1
+	}
+
+	fmt.Printf("ok   created %d users, %d chat rooms, %d keyword categories, %d keywords, %d reserved names\n",
+		len(report.Users), len(report.ChatRooms), len(report.KeywordCategories), len(report.Keywords), len(report.ReservedNames))
+	return 0
+}
+
+func runDowngrade(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("downgrade", flag.ExitOnError)
+	dbPath := fs.String("db", os.Getenv("DB_PATH"), "path to the SQLite database file")
+	to := fs.Int("to", -1, "schema version to downgrade to")
+	_ = fs.Parse(args)
+
+	if *dbPath == "" {
+		fmt.Fprintln(os.Stderr, "no database given, set -db or DB_PATH")
+		return 2
+	}
+	if *to < 0 {
+		fmt.Fprintln(os.Stderr, "no target version given, set -to")
+		return 2
+	}
+
+	reverted, err := state.Downgrade(ctx, *dbPath, uint(*to))
+	for i, p := range reverted {
+		if i == len(reverted)-1 && err != nil {
+			fmt.Printf("FAIL %04d_%s (%s)\n", p.Version, p.Name, p.Duration)
+		} else {
+			fmt.Printf("ok   %04d_%s reverted (%s)\n", p.Version, p.Name, p.Duration)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FAIL downgrade: %s\n", err)
+		return 1
+	}
+	fmt.Printf("schema is at version %d\n", *to)
+	return 0
+}
+
+func runMoveOfflineMessages(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("move-offline-messages", flag.ExitOnError)
+	dbPath := fs.String("db", os.Getenv("DB_PATH"), "path to the SQLite database file")
+	from := fs.String("from", "", "screen name whose offline messages are moved")
+	to := fs.String("to", "", "screen name that receives the offline messages")
+	_ = fs.Parse(args)
+
+	if *dbPath == "" {
+		fmt.Fprintln(os.Stderr, "no database given, set -db or DB_PATH")
+		return 2
+	}
+	if *from == "" || *to == "" {
+		fmt.Fprintln(os.Stderr, "no screen names given, set -from and -to")
+		return 2
+	}
+
+	store, err := state.NewSQLiteUserStore(*dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FAIL move-offline-messages: %s\n", err)
+		return 1
+	}
+	moved, err := store.MoveOfflineMessages(ctx, state.NewIdentScreenName(*from), state.NewIdentScreenName(*to))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FAIL move-offline-messages: %s\n", err)
+		return 1
+	}
+	fmt.Printf("ok   moved %d offline messages from %s to %s\n", moved, *from, *to)
+	return 0
+}
@@ -26,10 +26,15 @@ func main() {
 	rd := bytes.NewBuffer(flap.Payload)
 	snac := wire.SNACFrame{}
 	wire.UnmarshalBE(&snac, rd)
+	fmt.Println(snac)
 
 	printByteSlice(rd.Bytes())
 
+	// decode strictly so that fields missing from the SNAC body type show
+	// up as trailing bytes instead of being dropped silently
 	snacBody := wire.SNAC_0x01_0x0F_OServiceUserInfoUpdate{}
-	wire.UnmarshalBE(&snacBody, rd)
+	if err := wire.UnmarshalBEStrict(&snacBody, rd.Bytes()); err != nil {
+		fmt.Println(err)
+	}
 	fmt.Println(snacBody)
 }
@@ -0,0 +1,225 @@
+// Command snac_string_generator generates the name tables behind
+// wire.FoodGroupName, wire.SubGroupName and wire.TLVTagName.
+//
+// It reads the constant declarations of a wire source file and recognizes:
+//   - the const block marked with //oscar:foodgroups as the food groups
+//   - const blocks marked with //oscar:subgroups <FoodGroup> as the
+//     subgroups of that food group
+//   - uint16 constants whose name starts with a TLV family prefix (such as
+//     ICBMTLV or LocateTLVTags) or FeedbagAttributes as TLV tags
+//
+// Usage:
+//
+//	snac_string_generator <input.go> <output.go>
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// tlvFamily matches constants that name TLV tags and captures the family
+// prefix shared by the tags of one TLV list.
+var tlvFamily = regexp.MustCompile(`^(\w*?TLV(?:Tags)?|FeedbagAttributes)[A-Z]`)
+
+type constant struct {
+	Name  string
+	Value uint64
+}
+
+type group struct {
+	Name   string
+	Consts []constant
+}
+
+type tables struct {
+	FoodGroups []constant
+	SubGroups  []group
+	TLVs       []group
+}
+
+var tmpl = template.Must(template.New("").Parse(`// Code generated by snac_string_generator; DO NOT EDIT.
+
+package wire
+
+var (
+	foodGroupName = map[uint16]string{
+		{{- range .FoodGroups}}
+		{{.Name}}: "{{.Name}}",
+		{{- end}}
+	}
+	subGroupName = map[uint16]map[uint16]string{
+		{{- range .SubGroups}}
+		{{.Name}}: {
+			{{- range .Consts}}
+			{{.Name}}: "{{.Name}}",
+			{{- end}}
+		},
+		{{- end}}
+	}
+	tlvTagName = map[string]map[uint16]string{
+		{{- range .TLVs}}
+		"{{.Name}}": {
+			{{- range .Consts}}
+			{{.Name}}: "{{.Name}}",
+			{{- end}}
+		},
+		{{- end}}
+	}
+)
+`))
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: snac_string_generator <input.go> <output.go>")
+		os.Exit(1)
+	}
+
+	if err := run(os.Args[1], os.Args[2]); err != nil {
+		fmt.Fprintf(os.Stderr, "snac_string_generator: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(in, out string) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, in, nil, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+
+	t, err := collect(file)
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, t); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("format generated code: %w", err)
+	}
+
+	return os.WriteFile(out, src, 0o644)
+}
+
+func collect(file *ast.File) (tables, error) {
+	var t tables
+	tlvs := map[string]*group{}
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+
+		consts := uint16Consts(gen)
+
+		switch d := directive(gen.Doc); {
+		case d == "foodgroups":
+			t.FoodGroups = consts
+			continue
+		case strings.HasPrefix(d, "subgroups "):
+			foodGroup := strings.TrimPrefix(d, "subgroups ")
+			t.SubGroups = append(t.SubGroups, group{Name: foodGroup, Consts: dedupe(consts)})
+			continue
+		}
+
+		for _, c := range consts {
+			m := tlvFamily.FindStringSubmatch(c.Name)
+			if m == nil {
+				continue
+			}
+			g, ok := tlvs[m[1]]
+			if !ok {
+				g = &group{Name: m[1]}
+				tlvs[m[1]] = g
+			}
+			g.Consts = append(g.Consts, c)
+		}
+	}
+
+	if len(t.FoodGroups) == 0 {
+		return t, fmt.Errorf("no const block marked with //oscar:foodgroups")
+	}
+	for _, sg := range t.SubGroups {
+		if !slices.ContainsFunc(t.FoodGroups, func(c constant) bool { return c.Name == sg.Name }) {
+			return t, fmt.Errorf("subgroups declared for unknown food group %s", sg.Name)
+		}
+	}
+
+	for _, g := range tlvs {
+		t.TLVs = append(t.TLVs, group{Name: g.Name, Consts: dedupe(g.Consts)})
+	}
+	slices.SortFunc(t.TLVs, func(a, b group) int { return strings.Compare(a.Name, b.Name) })
+
+	return t, nil
+}
+
+// directive returns the text following "//oscar:" in a declaration's doc
+// comment, if any.
+func directive(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	for _, c := range doc.List {
+		if d, ok := strings.CutPrefix(c.Text, "//oscar:"); ok {
+			return strings.TrimSpace(d)
+		}
+	}
+	return ""
+}
+
+// uint16Consts returns the uint16 constants with literal values declared
+// in a const block.
+func uint16Consts(gen *ast.GenDecl) []constant {
+	var consts []constant
+	for _, spec := range gen.Specs {
+		vs := spec.(*ast.ValueSpec)
+		if ident, ok := vs.Type.(*ast.Ident); !ok || ident.Name != "uint16" {
+			continue
+		}
+		for i, name := range vs.Names {
+			if i >= len(vs.Values) {
+				break
+			}
+			lit, ok := vs.Values[i].(*ast.BasicLit)
+			if !ok || lit.Kind != token.INT {
+				continue
+			}
+			val, err := strconv.ParseUint(lit.Value, 0, 16)
+			if err != nil {
+				continue
+			}
+			consts = append(consts, constant{Name: name.Name, Value: val})
+		}
+	}
+	return consts
+}
+
+// dedupe drops constants that alias the value of an earlier constant, since
+// map literals can't contain duplicate keys.
+func dedupe(consts []constant) []constant {
+	seen := map[uint64]bool{}
+	var out []constant
+	for _, c := range consts {
+		if seen[c.Value] {
+			continue
+		}
+		seen[c.Value] = true
+		out = append(out, c)
+	}
+	return out
+}
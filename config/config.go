@@ -3,9 +3,16 @@ package config
 import (
 	"errors"
 	"fmt"
+	"html/template"
+	"math"
 	"net"
+	"net/netip"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 )
 
 var (
@@ -23,6 +30,22 @@ type Listener struct {
 	HasSSL                 bool
 }
 
+// ICBMClassLimits are the ICBM limits set for an account class in
+// ICBM_CLASS_PARAMS.
+type ICBMClassLimits struct {
+	MinInterval   time.Duration
+	MaxMessageLen uint16
+}
+
+// ChatReplayLimit is the number of messages replayed to users joining the
+// rooms of an exchange, or a single room, set in CHAT_REPLAY.
+type ChatReplayLimit struct {
+	Exchange uint16
+	// Room is the room name, or empty for every room in the exchange.
+	Room     string
+	Messages int
+}
+
 type Build struct {
 	Version string `json:"version"`
 	Commit  string `json:"commit"`
@@ -31,15 +54,65 @@ type Build struct {
 
 //go:generate go run ../cmd/config_generator unix settings.env ssl
 type Config struct {
-	BOSListeners            []string `envconfig:"OSCAR_LISTENERS" required:"true" basic:"LOCAL://0.0.0.0:5190" ssl:"LOCAL://0.0.0.0:5190" description:"Network listeners for core OSCAR services. For multi-homed servers, allows users to connect from multiple networks. For example, you can allow both LAN and Internet clients to connect to the same server using different connection settings.\n\nFormat:\n\t- Comma-separated list of [NAME]://[HOSTNAME]:[PORT]\n\t- Listener names and ports must be unique\n\t- Listener names are user-defined\n\t- Each listener needs a listener in OSCAR_ADVERTISED_LISTENERS_PLAIN\n\nExamples:\n\t// Listen on all interfaces\n\tLAN://0.0.0.0:5190\n\t// Separate Internet and LAN config\n\tWAN://142.250.176.206:5190,LAN://192.168.1.10:5191"`
-	BOSAdvertisedHostsPlain []string `envconfig:"OSCAR_ADVERTISED_LISTENERS_PLAIN" required:"true" basic:"LOCAL://127.0.0.1:5190" ssl:"LOCAL://127.0.0.1:5190" description:"Hostnames published by the server that clients connect to for accessing various OSCAR services. These hostnames are NOT the bind addresses. For multi-homed use servers, allows clients to connect using separate hostnames per network.\n\nFormat:\n\t- Comma-separated list of [NAME]://[HOSTNAME]:[PORT]\n\t- Each listener config must correspond to a config in OSCAR_LISTENERS\n\t- Clients MUST be able to connect to these hostnames\n\nExamples:\n\t// Local LAN config, server behind NAT\n\tLAN://192.168.1.10:5190\n\t// Separate Internet and LAN config\n\tWAN://aim.example.com:5190,LAN://192.168.1.10:5191"`
-	BOSAdvertisedHostsSSL   []string `envconfig:"OSCAR_ADVERTISED_LISTENERS_SSL" required:"false" basic:"" ssl:"LOCAL://ras.dev:5193" description:"Same as OSCAR_ADVERTISED_LISTENERS_PLAIN, except the hostname is for the server that terminates SSL."`
-	KerberosListeners       []string `envconfig:"KERBEROS_LISTENERS" required:"false" basic:"" ssl:"LOCAL://0.0.0.0:1088" description:"Network listeners for Kerberos authentication. See OSCAR_LISTENERS doc for more details.\n\nExamples:\n\t// Listen on all interfaces\n\tLAN://0.0.0.0:1088\n\t// Separate Internet and LAN config\n\tWAN://142.250.176.206:1088,LAN://192.168.1.10:1087"`
-	TOCListeners            []string `envconfig:"TOC_LISTENERS" required:"true" basic:"0.0.0.0:9898" ssl:"0.0.0.0:9898" description:"Network listeners for TOC protocol service.\n\nFormat: Comma-separated list of hostname:port pairs.\n\nExamples:\n\t// All interfaces\n\t0.0.0.0:9898\n\t// Multiple listeners\n\t0.0.0.0:9898,192.168.1.10:9899"`
-	DisableAuth             bool     `envconfig:"DISABLE_AUTH" required:"true" basic:"true" ssl:"true" description:"Disable password check and auto-create new users at login time. Useful for quickly creating new accounts during development without having to register new users via the management API."`
-	APIListener             string   `envconfig:"API_LISTENER" required:"true" basic:"127.0.0.1:8080" ssl:"127.0.0.1:8080" description:"Network listener for management API binds to. Only 1 listener can be specified. (Default 127.0.0.1 restricts to same machine only)."`
-	DBPath                  string   `envconfig:"DB_PATH" required:"true" basic:"go-icq.sqlite" ssl:"go-icq.sqlite" description:"The path to the SQLite database file. The file and DB schema are auto-created if they doesn't exist."`
-	LogLevel                string   `envconfig:"LOG_LEVEL" required:"true" basic:"info" ssl:"info" description:"Set logging granularity. Possible values: 'trace', 'debug', 'info', 'warn', 'error'."`
+	BOSListeners            []string      `envconfig:"OSCAR_LISTENERS" required:"true" basic:"LOCAL://0.0.0.0:5190" ssl:"LOCAL://0.0.0.0:5190" description:"Network listeners for core OSCAR services. For multi-homed servers, allows users to connect from multiple networks. For example, you can allow both LAN and Internet clients to connect to the same server using different connection settings.\n\nFormat:\n\t- Comma-separated list of [NAME]://[HOSTNAME]:[PORT]\n\t- Listener names and ports must be unique\n\t- Listener names are user-defined\n\t- Each listener needs a listener in OSCAR_ADVERTISED_LISTENERS_PLAIN\n\nExamples:\n\t// Listen on all interfaces\n\tLAN://0.0.0.0:5190\n\t// Separate Internet and LAN config\n\tWAN://142.250.176.206:5190,LAN://192.168.1.10:5191"`
+	BOSAdvertisedHostsPlain []string      `envconfig:"OSCAR_ADVERTISED_LISTENERS_PLAIN" required:"true" basic:"LOCAL://127.0.0.1:5190" ssl:"LOCAL://127.0.0.1:5190" description:"Hostnames published by the server that clients connect to for accessing various OSCAR services. These hostnames are NOT the bind addresses. For multi-homed use servers, allows clients to connect using separate hostnames per network.\n\nFormat:\n\t- Comma-separated list of [NAME]://[HOSTNAME]:[PORT]\n\t- Each listener config must correspond to a config in OSCAR_LISTENERS\n\t- Clients MUST be able to connect to these hostnames\n\nExamples:\n\t// Local LAN config, server behind NAT\n\tLAN://192.168.1.10:5190\n\t// Separate Internet and LAN config\n\tWAN://aim.example.com:5190,LAN://192.168.1.10:5191"`
+	BOSAdvertisedHostsSSL   []string      `envconfig:"OSCAR_ADVERTISED_LISTENERS_SSL" required:"false" basic:"" ssl:"LOCAL://ras.dev:5193" description:"Same as OSCAR_ADVERTISED_LISTENERS_PLAIN, except the hostname is for the server that terminates SSL."`
+	KerberosListeners       []string      `envconfig:"KERBEROS_LISTENERS" required:"false" basic:"" ssl:"LOCAL://0.0.0.0:1088" description:"Network listeners for Kerberos authentication. See OSCAR_LISTENERS doc for more details.\n\nExamples:\n\t// Listen on all interfaces\n\tLAN://0.0.0.0:1088\n\t// Separate Internet and LAN config\n\tWAN://142.250.176.206:1088,LAN://192.168.1.10:1087"`
+	TOCListeners            []string      `envconfig:"TOC_LISTENERS" required:"true" basic:"0.0.0.0:9898" ssl:"0.0.0.0:9898" description:"Network listeners for TOC protocol service.\n\nFormat: Comma-separated list of hostname:port pairs.\n\nExamples:\n\t// All interfaces\n\t0.0.0.0:9898\n\t// Multiple listeners\n\t0.0.0.0:9898,192.168.1.10:9899"`
+	DisableAuth             bool          `envconfig:"DISABLE_AUTH" required:"true" basic:"true" ssl:"true" description:"Disable password check and auto-create new users at login time. Useful for quickly creating new accounts during development without having to register new users via the management API."`
+	APIListener             string        `envconfig:"API_LISTENER" required:"true" basic:"127.0.0.1:8080" ssl:"127.0.0.1:8080" description:"Network listener for management API binds to. Only 1 listener can be specified. (Default 127.0.0.1 restricts to same machine only)."`
+	DBPath                  string        `envconfig:"DB_PATH" required:"true" basic:"go-icq.sqlite" ssl:"go-icq.sqlite" description:"The path to the SQLite database file. The file and DB schema are auto-created if they doesn't exist. Also accepts :memory: for a database that is lost on shutdown, or a SQLite URI such as file:go-icq.sqlite?mode=ro."`
+	LoginMaxConcurrent      int           `envconfig:"LOGIN_MAX_CONCURRENT" required:"false" basic:"100" ssl:"100" description:"The maximum number of sign-on attempts processed at the same time. Additional attempts wait in a queue, protecting the database when many clients reconnect at once, for example after a server restart. Set to 0 to disable sign-on throttling."`
+	LoginQueueTimeout       time.Duration `envconfig:"LOGIN_QUEUE_TIMEOUT" required:"false" basic:"5s" ssl:"5s" description:"How long a queued sign-on attempt waits for a free slot before it is rejected and the client is told to retry later. Uses Go duration format, such as '5s' or '1m'."`
+	LocateMaxSigLen         int           `envconfig:"LOCATE_MAX_SIG_LEN" required:"false" basic:"1000" ssl:"1000" description:"The maximum length in bytes of a user's profile and away message, advertised to clients in the locate rights reply and enforced when they set their info. Must be between 0 and 65535. Set to 0 to use the default of 1000."`
+	LocateMaxCapabilities   int           `envconfig:"LOCATE_MAX_CAPABILITIES" required:"false" basic:"1000" ssl:"1000" description:"The maximum number of capability UUIDs a client may advertise. Must be between 0 and 65535. Set to 0 to use the default of 1000."`
+	LocateMaxEmailLookups   int           `envconfig:"LOCATE_MAX_EMAIL_LOOKUPS" required:"false" basic:"1000" ssl:"1000" description:"The maximum number of email addresses a client may look up at once. Must be between 0 and 65535. Set to 0 to use the default of 1000."`
+	LocateMaxCertsLen       int           `envconfig:"LOCATE_MAX_CERTS_LEN" required:"false" basic:"1000" ssl:"1000" description:"The maximum length in bytes of a client's encryption certificate. Must be between 0 and 65535. Set to 0 to use the default of 1000."`
+	GuestScreenNamePrefix   string        `envconfig:"GUEST_SCREEN_NAME_PREFIX" required:"false" basic:"Guest" ssl:"Guest" description:"Prefix for the auto-generated screen names of chat-only guest sessions, followed by random digits. Registered users can't create screen names that start with this prefix. Must start with a letter, contain only letters and numbers, and be at most 12 characters long."`
+	GuestChatRooms          []string      `envconfig:"GUEST_CHAT_ROOMS" required:"false" basic:"" ssl:"" description:"Comma-separated list of chat room names that guest sessions may join. Guests can't send IMs or appear in directory searches, and are discarded when they disconnect. Leave empty to disable guest access."`
+	AccountExpiryGrace      time.Duration `envconfig:"ACCOUNT_EXPIRY_GRACE_PERIOD" required:"false" basic:"168h" ssl:"168h" description:"How long an expired trial or temporary account is kept before it is permanently deleted. Expired accounts can't sign on during the grace period. Uses Go duration format, such as '24h' or '168h'."`
+	ChatRoomCreatePerms     []string      `envconfig:"CHAT_ROOM_CREATE_PERMS" required:"false" basic:"4:everyone,5:admins" ssl:"4:everyone,5:admins" description:"Who may create chat rooms in each exchange. Exchanges that aren't listed keep their default: 'everyone' for exchange 4 (private rooms), 'admins' for exchange 5 (public rooms), and 'nobody' for any other exchange.\n\nFormat: Comma-separated list of [EXCHANGE]:[TIER], where TIER is one of 'nobody', 'admins', 'confirmed' (confirmed accounts and admins) or 'everyone'.\n\nExamples:\n\t// Only confirmed accounts create private rooms\n\t4:confirmed,5:admins"`
+	AdminScreenNames        []string      `envconfig:"ADMIN_SCREEN_NAMES" required:"false" basic:"" ssl:"" description:"Comma-separated list of screen names that have server admin privileges, such as creating rooms in exchanges restricted to admins."`
+	SessionMaxQueueDepth    int           `envconfig:"SESSION_MAX_QUEUE_DEPTH" required:"false" basic:"1000" ssl:"1000" description:"The maximum number of outbound messages buffered for a client. A client that stops reading until its queue exceeds this bound is signed off as a slow consumer so it can't hold up messages to other users. Must be between 0 and 1000. Set to 0 to use the default of 1000."`
+	DNDSuppress             []string      `envconfig:"DND_SUPPRESS" required:"false" basic:"dnd:popups+invites+warnings,busy:popups+invites" ssl:"dnd:popups+invites+warnings,busy:popups+invites" description:"Interruptions withheld from users while they are in a do-not-disturb status. Senders of suppressed chat invitations and warnings receive an error.\n\nFormat: Comma-separated list of [STATUS]:[KINDS], where STATUS is one of 'dnd', 'busy', 'away' or 'na' and KINDS is a '+'-separated list of 'popups', 'invites' and 'warnings'.\n\nExamples:\n\t// Only block warnings while busy\n\tdnd:popups+invites+warnings,busy:warnings"`
+	ConnAllowCIDRs          []string      `envconfig:"CONN_ALLOW_CIDRS" required:"false" basic:"" ssl:"" description:"Comma-separated list of IP ranges in CIDR notation (or single IP addresses) allowed to connect to any listener. When set, connections from all other addresses are refused. Addresses in this list are exempt from CONN_BLOCK_COUNTRIES. Leave empty to allow all addresses.\n\nExamples:\n\t// Only allow LAN clients\n\t192.168.0.0/16,10.0.0.0/8"`
+	ConnDenyCIDRs           []string      `envconfig:"CONN_DENY_CIDRS" required:"false" basic:"" ssl:"" description:"Comma-separated list of IP ranges in CIDR notation (or single IP addresses) refused on all listeners. Takes precedence over CONN_ALLOW_CIDRS."`
+	ConnBlockCountries      []string      `envconfig:"CONN_BLOCK_COUNTRIES" required:"false" basic:"" ssl:"" description:"Comma-separated list of two-letter ISO 3166-1 country codes whose connections are refused on all listeners. Requires a GeoIP resolver; connections are allowed if the country can't be determined."`
+	CapOverrides            []string      `envconfig:"CAP_OVERRIDES" required:"false" basic:"" ssl:"" description:"Capabilities stripped from or added to the capabilities that users advertise to others, such as disabling file transfer for guests.\n\nFormat: Comma-separated list of [TARGET]:[CHANGES], where TARGET is 'aim', 'icq', 'guest' or a screen name prefixed with '@', and CHANGES is a '+'-separated list of capability names. A name prefixed with '-' strips the capability; otherwise it is added. Capability names are 'chat', 'voice', 'filetransfer', 'directim', 'buddyicon', 'addins', 'fileshare', 'games', 'buddylisttransfer', 'utf8' and 'icqserverrelay'. Screen name overrides are applied after class overrides.\n\nExamples:\n\t// No file transfer or direct IM for guests, except for one account\n\tguest:-filetransfer+-directim,@ChattingChuck:filetransfer"`
+	WelcomeMode             string        `envconfig:"WELCOME_MODE" required:"false" basic:"off" ssl:"off" description:"When users are sent the welcome message. Possible values: 'off', 'first' (only the first time an account signs on) or 'every' (every sign-on)."`
+	WelcomeMessage          string        `envconfig:"WELCOME_MESSAGE" required:"false" basic:"" ssl:"" description:"Text of the welcome IM, sent from 'AOL System Msg'. May contain HTML. '{{.ScreenName}}' is replaced with the user's screen name. Leave empty to send no IM.\n\nExamples:\n\tWelcome to the server, {{.ScreenName}}!"`
+	WelcomePopup            string        `envconfig:"WELCOME_POPUP" required:"false" basic:"" ssl:"" description:"Text of the welcome popup window. Same format as WELCOME_MESSAGE. Leave empty to show no popup."`
+	WelcomePopupURL         string        `envconfig:"WELCOME_POPUP_URL" required:"false" basic:"" ssl:"" description:"Absolute http or https URL of a web page shown in the welcome popup window. Requires WELCOME_POPUP."`
+	AdminBotScreenName      string        `envconfig:"ADMIN_BOT_SCREEN_NAME" required:"false" basic:"" ssl:"" description:"Screen name of the admin bot, which runs commands such as '/who', '/broadcast' and '/suspend' that admins send to it by IM. Only accounts in ADMIN_SCREEN_NAMES may use it. Leave empty to disable the bot.\n\nExamples:\n\tAdminBot"`
+	OfflineInboxFullNotice  bool          `envconfig:"OFFLINE_INBOX_FULL_NOTICE" required:"false" basic:"true" ssl:"true" description:"Send an IM from the system screen name to users whose message to a signed-off user is rejected because the recipient's offline message inbox is full. The sender's client reports the error either way."`
+	ProfanityWords          []string      `envconfig:"PROFANITY_WORDS" required:"false" basic:"" ssl:"" description:"Comma-separated list of words caught by the profanity filter. Words match whole words of IMs, chat messages and profiles, ignoring case. Leave empty to disable the filter."`
+	ProfanityActions        []string      `envconfig:"PROFANITY_ACTIONS" required:"false" basic:"im:mask,chat:mask,profile:reject" ssl:"im:mask,chat:mask,profile:reject" description:"What the profanity filter does with each kind of content that contains a word from PROFANITY_WORDS. Content kinds are 'im', 'chat' and 'profile' (profiles and away messages). Actions are 'allow', 'mask' (replace the word with asterisks), 'reject' (refuse the content with an error) and 'flag' (let it through and log it for moderators). Kinds that aren't listed are not filtered.\n\nFormat: Comma-separated list of KIND:ACTION\n\nExamples:\n\tim:mask,chat:reject,profile:flag"`
+	ICBMMinInterval         time.Duration `envconfig:"ICBM_MIN_INTERVAL" required:"false" basic:"1s" ssl:"1s" description:"The shortest time allowed between two IMs sent by a user. Clients are told this interval and pace their messages; IMs sent faster are refused with a rate error. Uses Go duration format, such as '500ms' or '1s'. Set to 0 to disable the limit."`
+	ICBMMaxMessageLen       int           `envconfig:"ICBM_MAX_MESSAGE_LEN" required:"false" basic:"8000" ssl:"8000" description:"The maximum size in bytes of an IM. Clients are told this size, and longer IMs are refused. Must be between 0 and 65535. Set to 0 to use the default of 8000."`
+	ICBMClassParams         []string      `envconfig:"ICBM_CLASS_PARAMS" required:"false" basic:"bot:0s/8000,probation:5s/2000" ssl:"bot:0s/8000,probation:5s/2000" description:"IM limits for classes of accounts that override ICBM_MIN_INTERVAL and ICBM_MAX_MESSAGE_LEN, such as letting bots send faster. Classes are 'standard', 'guest' (chat-only guest sessions), 'bot' (accounts flagged as bots) and 'probation' (new accounts on probation, see PROBATION_PERIOD).\n\nFormat: Comma-separated list of [CLASS]:[INTERVAL]/[MAXLEN]\n\nExamples:\n\t// Unthrottled bots, slow guests\n\tbot:0s/8000,guest:5s/1024"`
+	ProbationPeriod         time.Duration `envconfig:"PROBATION_PERIOD" required:"false" basic:"0s" ssl:"0s" description:"How long newly registered accounts stay on probation. Accounts on probation can't create chat rooms, can only IM users who have them on their buddy list, and send IMs under the 'probation' limits of ICBM_CLASS_PARAMS. Probation lifts automatically once the period has passed. Uses Go duration format, such as '24h' or '72h'. Set to 0 to disable probation."`
+	WebhookURL              string        `envconfig:"WEBHOOK_URL" required:"false" basic:"" ssl:"" description:"Absolute http or https URL that server events are posted to as JSON, such as moderation events (warnings, filtered messages and users disconnected for flooding) for an external moderation dashboard. Leave empty to disable webhooks."`
+	WebhookSecret           string        `envconfig:"WEBHOOK_SECRET" required:"false" basic:"" ssl:"" description:"Secret used to sign webhook requests. When set, each request carries the hex HMAC-SHA256 of its body in the X-Webhook-Signature header, prefixed with 'sha256='."`
+	WellKnownURLs           []string      `envconfig:"WELL_KNOWN_URLS" required:"false" basic:"" ssl:"" description:"URLs sent to clients in the well-known URLs message at sign-on, which newer clients use for features that phone home, such as spell-check dictionaries. Point them at WELL_KNOWN_FILES_DIR so these features don't fail against dead domains.\n\nFormat: Comma-separated list of [TAG]=[URL], where TAG is the TLV tag of the URL in decimal or 0x-prefixed hex and URL is an absolute http or https URL.\n\nExamples:\n\t0x0001=http://127.0.0.1:8080/wellknown/spellcheck.dic"`
+	WellKnownFilesDir       string        `envconfig:"WELL_KNOWN_FILES_DIR" required:"false" basic:"" ssl:"" description:"Directory of static files served by the management API under /wellknown/ for the URLs in WELL_KNOWN_URLS. Requests for files that don't exist get an empty response instead of an error. Leave empty to disable."`
+	SNACHistorySize         int           `envconfig:"SNAC_HISTORY_SIZE" required:"false" basic:"32" ssl:"32" description:"The number of recent SNACs kept in memory for each session, in both directions. Operators can dump a user's history through the management API, and it's logged when a client is disconnected for flooding or for not reading its messages. Must be between 0 and 1000. Set to 0 to disable."`
+	SeedFile                string        `envconfig:"SEED_FILE" required:"false" basic:"" ssl:"" description:"Path to a YAML file declaring users, bots, chat rooms, directory keyword categories and reserved screen names to create at startup. Seeding is idempotent: things that already exist are left alone, so existing accounts keep their passwords. Leave empty to disable."`
+	GuestScreenNameWords    bool          `envconfig:"GUEST_SCREEN_NAME_WORDS" required:"false" basic:"false" ssl:"false" description:"Generate guest screen names from an adjective, a noun and digits after GUEST_SCREEN_NAME_PREFIX, such as 'GuestLazyOtter42', instead of digits only. Names containing a word from PROFANITY_WORDS are never generated."`
+	ScreenNameAdjectives    []string      `envconfig:"SCREEN_NAME_ADJECTIVES" required:"false" basic:"" ssl:"" description:"Comma-separated list of adjectives used for generated screen names. Words must contain only letters. Leave empty to use the built-in list."`
+	ScreenNameNouns         []string      `envconfig:"SCREEN_NAME_NOUNS" required:"false" basic:"" ssl:"" description:"Comma-separated list of nouns used for generated screen names. Words must contain only letters. Leave empty to use the built-in list."`
+	ChatReplay              []string      `envconfig:"CHAT_REPLAY" required:"false" basic:"" ssl:"" description:"The number of recent messages replayed to users when they join a chat room, so that late joiners can catch up on the conversation. Replayed messages are marked with the time they were sent. Messages are kept in memory only while the room has occupants. A room setting overrides the setting of its exchange. At most 100 messages.\n\nFormat: Comma-separated list of [EXCHANGE]:[COUNT] or [EXCHANGE]/[ROOM NAME]:[COUNT]\n\nExamples:\n\t// Replay 20 messages in public rooms, none in the lobby\n\t5:20,5/Lobby:0"`
+	PresenceWebhookLimit    int           `envconfig:"PRESENCE_WEBHOOK_LIMIT" required:"false" basic:"10" ssl:"10" description:"The most buddy status changes posted per minute to each user's presence webhook. Users can only register a presence webhook once an operator allows them to. Changes past the limit are dropped. Must be between 0 and 600. Set to 0 to disable presence webhooks."`
+	FeedbagRejectCustom     bool          `envconfig:"FEEDBAG_REJECT_CUSTOM_CLASSES" required:"false" basic:"false" ssl:"false" description:"Refuse buddy list items whose class is above the predefined classes and unknown to the server, such as client-defined classes. By default they are stored opaquely; the management API reports how many are stored so that you can decide whether to refuse them."`
+	LoginCookieLimit        int           `envconfig:"LOGIN_COOKIE_LIMIT" required:"false" basic:"10000" ssl:"10000" description:"The most login cookies held at once. A cookie is issued when a client authenticates and redeemed when it connects to BOS or a chat room. Cookies that are never redeemed expire after a minute; when the limit is reached, the oldest are dropped. Must not be negative. Set to 0 for the default of 10000."`
+	DepartureGrace          time.Duration `envconfig:"DEPARTURE_GRACE_PERIOD" required:"false" basic:"0s" ssl:"0s" description:"How long a user's departure is held back before it is sent to their watchers. A user who signs back on within the grace period, such as after a network blip, never appears to leave. Uses Go duration format (e.g. 10s). Set to 0s to send departures right away."`
+	SoftLimitUsers          int           `envconfig:"SOFT_LIMIT_USERS" required:"false" basic:"0" ssl:"0" description:"Warn when the number of registered accounts reaches this soft limit. Soft limits never refuse requests; crossing one is logged, posted to the webhook and exported as a metric. Set to 0 to disable."`
+	SoftLimitDBSizeMB       int           `envconfig:"SOFT_LIMIT_DB_SIZE_MB" required:"false" basic:"0" ssl:"0" description:"Warn when the database reaches this size in megabytes. Set to 0 to disable."`
+	SoftLimitOfflineMsgs    int           `envconfig:"SOFT_LIMIT_OFFLINE_MESSAGES" required:"false" basic:"0" ssl:"0" description:"Warn when the number of stored offline messages reaches this soft limit. Set to 0 to disable."`
+	URLBlocklist            []string      `envconfig:"URL_BLOCKLIST" required:"false" basic:"" ssl:"" description:"Comma-separated list of host names whose URLs are caught in profiles and away messages before they're stored, protecting users of old clients whose embedded browsers have known vulnerabilities. A host also matches its subdomains. Leave empty to disable URL scanning."`
+	URLBlocklistAction      string        `envconfig:"URL_BLOCKLIST_ACTION" required:"false" basic:"strip" ssl:"strip" description:"What is done with profiles and away messages that link to a host in URL_BLOCKLIST. Possible values: 'strip' (remove the URL and keep the rest of the content) or 'flag' (let it through and log it for moderators)."`
+	ChatRoomQuotas          []string      `envconfig:"CHAT_ROOM_QUOTAS" required:"false" basic:"standard:10" ssl:"standard:10" description:"The most chat rooms that an account of each class may own at once. An account owns the rooms it created; a private room is released when its last occupant leaves. Classes are 'standard', 'guest', 'bot' and 'probation', as in ICBM_CLASS_PARAMS. Classes that aren't listed have no quota.\n\nFormat: Comma-separated list of [CLASS]:[COUNT]\n\nExamples:\n\tstandard:5,bot:50"`
+	BUCPNonceTTL            time.Duration `envconfig:"BUCP_NONCE_TTL" required:"false" basic:"1m" ssl:"1m" description:"How long a client has to answer the challenge of an MD5 (BUCP) login. Each challenge can be answered only once on the connection it was sent on, so a captured login response can't be replayed on that connection once its challenge was answered or expired. It can still be sent on a new connection, since every challenge carries the account's fixed auth key; for the same reason, the stored weakMD5Pass and strongMD5Pass columns can be used to log in and must be protected like passwords. Rejected replays are exported as a metric. Uses Go duration format (e.g. 30s). Must not be negative. Set to 0s for the default of 1m."`
+	LogLevel                string        `envconfig:"LOG_LEVEL" required:"true" basic:"info" ssl:"info" description:"Set logging granularity. Possible values: 'trace', 'debug', 'info', 'warn', 'error'."`
 }
 
 func (c *Config) Validate() error {
@@ -75,9 +148,543 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid API listener %q: missing port. Valid format: HOST:PORT (e.g., 127.0.0.1:8080)", c.APIListener)
 	}
 
+	if c.LoginMaxConcurrent < 0 {
+		return fmt.Errorf("invalid login max concurrent %d: must be 0 (disabled) or greater", c.LoginMaxConcurrent)
+	}
+
+	if c.LoginQueueTimeout < 0 {
+		return fmt.Errorf("invalid login queue timeout %s: must not be negative", c.LoginQueueTimeout)
+	}
+
+	// the upper bound matches the session queue capacity
+	if c.SessionMaxQueueDepth < 0 || c.SessionMaxQueueDepth > 1000 {
+		return fmt.Errorf("invalid session max queue depth %d: must be between 0 and 1000", c.SessionMaxQueueDepth)
+	}
+
+	if c.AccountExpiryGrace < 0 {
+		return fmt.Errorf("invalid account expiry grace period %s: must not be negative", c.AccountExpiryGrace)
+	}
+
+	locateRights := []struct {
+		name string
+		val  int
+	}{
+		{"locate max sig len", c.LocateMaxSigLen},
+		{"locate max capabilities", c.LocateMaxCapabilities},
+		{"locate max email lookups", c.LocateMaxEmailLookups},
+		{"locate max certs len", c.LocateMaxCertsLen},
+	}
+	for _, r := range locateRights {
+		if r.val < 0 || r.val > math.MaxUint16 {
+			return fmt.Errorf("invalid %s %d: must be between 0 and %d", r.name, r.val, math.MaxUint16)
+		}
+	}
+
+	if _, err := c.ParseChatRoomCreatePerms(); err != nil {
+		return err
+	}
+
+	if _, err := c.ParseDNDSuppress(); err != nil {
+		return err
+	}
+
+	if _, err := c.ParseCapOverrides(); err != nil {
+		return err
+	}
+
+	if _, err := c.ParseProfanityActions(); err != nil {
+		return err
+	}
+
+	if c.URLBlocklistAction != "" && !slices.Contains([]string{"strip", "flag"}, c.URLBlocklistAction) {
+		return fmt.Errorf("invalid URL blocklist action %q: must be strip or flag", c.URLBlocklistAction)
+	}
+
+	if c.ICBMMinInterval < 0 {
+		return fmt.Errorf("invalid ICBM min interval %s: must not be negative", c.ICBMMinInterval)
+	}
+
+	if c.ICBMMaxMessageLen < 0 || c.ICBMMaxMessageLen > math.MaxUint16 {
+		return fmt.Errorf("invalid ICBM max message len %d: must be between 0 and %d", c.ICBMMaxMessageLen, math.MaxUint16)
+	}
+
+	if _, err := c.ParseICBMClassParams(); err != nil {
+		return err
+	}
+
+	if _, err := c.ParseChatRoomQuotas(); err != nil {
+		return err
+	}
+
+	if c.ProbationPeriod < 0 {
+		return fmt.Errorf("invalid probation period %s: must not be negative", c.ProbationPeriod)
+	}
+
+	if c.SNACHistorySize < 0 || c.SNACHistorySize > 1000 {
+		return fmt.Errorf("invalid SNAC history size %d: must be between 0 and 1000", c.SNACHistorySize)
+	}
+
+	if c.PresenceWebhookLimit < 0 || c.PresenceWebhookLimit > 600 {
+		return fmt.Errorf("invalid presence webhook limit %d: must be between 0 and 600", c.PresenceWebhookLimit)
+	}
+
+	if c.LoginCookieLimit < 0 {
+		return fmt.Errorf("invalid login cookie limit %d: must not be negative", c.LoginCookieLimit)
+	}
+
+	if c.BUCPNonceTTL < 0 {
+		return fmt.Errorf("invalid BUCP nonce TTL %s: must not be negative", c.BUCPNonceTTL)
+	}
+
+	if c.DepartureGrace < 0 {
+		return fmt.Errorf("invalid departure grace period %s: must not be negative", c.DepartureGrace)
+	}
+
+	if c.SoftLimitUsers < 0 || c.SoftLimitDBSizeMB < 0 || c.SoftLimitOfflineMsgs < 0 {
+		return errors.New("invalid soft limit: soft limits must not be negative")
+	}
+
+	if _, err := c.ParseChatReplay(); err != nil {
+		return err
+	}
+
+	if _, _, err := c.ParseConnCIDRs(); err != nil {
+		return err
+	}
+
+	if err := c.validateWelcome(); err != nil {
+		return err
+	}
+
+	if c.WebhookURL != "" {
+		u, err := url.Parse(c.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook URL %q: must be an absolute http or https URL", c.WebhookURL)
+		}
+	}
+
+	if _, err := c.ParseWellKnownURLs(); err != nil {
+		return err
+	}
+
+	if c.AdminBotScreenName != "" && len(c.AdminScreenNames) == 0 {
+		return errors.New("admin bot requires at least one screen name in ADMIN_SCREEN_NAMES")
+	}
+
+	if _, err := c.ParseConnBlockCountries(); err != nil {
+		return err
+	}
+
+	for _, word := range slices.Concat(c.ScreenNameAdjectives, c.ScreenNameNouns) {
+		if word == "" || strings.ContainsFunc(word, func(r rune) bool { return r > unicode.MaxASCII || !unicode.IsLetter(r) }) {
+			return fmt.Errorf("invalid screen name word %q: must contain only letters", word)
+		}
+	}
+
+	if prefix := c.GuestScreenNamePrefix; prefix != "" {
+		if len(prefix) > 12 {
+			return fmt.Errorf("invalid guest screen name prefix %q: must be at most 12 characters", prefix)
+		}
+		for i, r := range prefix {
+			if !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+				return fmt.Errorf("invalid guest screen name prefix %q: must start with a letter and contain only letters and numbers", prefix)
+			}
+		}
+	}
+
+	return nil
+}
+
+// chatCreateTiers lists the valid CHAT_ROOM_CREATE_PERMS tier names.
+var chatCreateTiers = []string{"nobody", "admins", "confirmed", "everyone"}
+
+// ParseChatRoomCreatePerms parses ChatRoomCreatePerms into a map of exchange
+// ID to creation tier name, which state.ParseChatCreateTiers converts into
+// creation tiers.
+func (c *Config) ParseChatRoomCreatePerms() (map[uint16]string, error) {
+	perms := make(map[uint16]string, len(c.ChatRoomCreatePerms))
+	for _, entry := range c.ChatRoomCreatePerms {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		exchangeStr, tier, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid chat room create perms %q. Valid format: EXCHANGE:TIER (e.g., 4:everyone)", entry)
+		}
+
+		exchange, err := strconv.ParseUint(strings.TrimSpace(exchangeStr), 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chat room create perms %q: exchange must be a number between 0 and 65535", entry)
+		}
+
+		tier = strings.TrimSpace(tier)
+		if !slices.Contains(chatCreateTiers, tier) {
+			return nil, fmt.Errorf("invalid chat room create perms %q: tier must be one of %s", entry, strings.Join(chatCreateTiers, ", "))
+		}
+
+		if _, dup := perms[uint16(exchange)]; dup {
+			return nil, fmt.Errorf("invalid chat room create perms %q: exchange %d listed more than once", entry, exchange)
+		}
+		perms[uint16(exchange)] = tier
+	}
+
+	return perms, nil
+}
+
+var (
+	// dndStatuses lists the valid DND_SUPPRESS status names.
+	dndStatuses = []string{"dnd", "busy", "away", "na"}
+	// dndKinds lists the valid DND_SUPPRESS interruption kinds.
+	dndKinds = []string{"popups", "invites", "warnings"}
+)
+
+// ParseDNDSuppress parses DNDSuppress into a map of status name to the
+// interruption kinds suppressed in that status.
+func (c *Config) ParseDNDSuppress() (map[string][]string, error) {
+	suppress := make(map[string][]string, len(c.DNDSuppress))
+	for _, entry := range c.DNDSuppress {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		status, kindsStr, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid DND suppress setting %q. Valid format: STATUS:KINDS (e.g., dnd:popups+invites)", entry)
+		}
+
+		status = strings.TrimSpace(status)
+		if !slices.Contains(dndStatuses, status) {
+			return nil, fmt.Errorf("invalid DND suppress setting %q: status must be one of %s", entry, strings.Join(dndStatuses, ", "))
+		}
+		if _, dup := suppress[status]; dup {
+			return nil, fmt.Errorf("invalid DND suppress setting %q: status %s listed more than once", entry, status)
+		}
+
+		var kinds []string
+		for _, kind := range strings.Split(kindsStr, "+") {
+			kind = strings.TrimSpace(kind)
+			if !slices.Contains(dndKinds, kind) {
+				return nil, fmt.Errorf("invalid DND suppress setting %q: kind must be one of %s", entry, strings.Join(dndKinds, ", "))
+			}
+			kinds = append(kinds, kind)
+		}
+		suppress[status] = kinds
+	}
+
+	return suppress, nil
+}
+
+var (
+	// profanityKinds lists the valid PROFANITY_ACTIONS content kinds.
+	profanityKinds = []string{"im", "chat", "profile"}
+	// profanityActions lists the valid PROFANITY_ACTIONS actions.
+	profanityActions = []string{"allow", "mask", "reject", "flag"}
+)
+
+// ParseProfanityActions parses ProfanityActions into a map of content kind
+// to profanity filter action.
+func (c *Config) ParseProfanityActions() (map[string]string, error) {
+	actions := make(map[string]string, len(c.ProfanityActions))
+	for _, entry := range c.ProfanityActions {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kind, action, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid profanity action %q. Valid format: KIND:ACTION (e.g., chat:mask)", entry)
+		}
+
+		kind = strings.TrimSpace(kind)
+		if !slices.Contains(profanityKinds, kind) {
+			return nil, fmt.Errorf("invalid profanity action %q: kind must be one of %s", entry, strings.Join(profanityKinds, ", "))
+		}
+		if _, dup := actions[kind]; dup {
+			return nil, fmt.Errorf("invalid profanity action %q: kind %s listed more than once", entry, kind)
+		}
+
+		action = strings.TrimSpace(action)
+		if !slices.Contains(profanityActions, action) {
+			return nil, fmt.Errorf("invalid profanity action %q: action must be one of %s", entry, strings.Join(profanityActions, ", "))
+		}
+		actions[kind] = action
+	}
+
+	return actions, nil
+}
+
+// icbmClasses lists the valid ICBM_CLASS_PARAMS account classes.
+var icbmClasses = []string{"standard", "guest", "bot", "probation"}
+
+// ParseICBMClassParams parses ICBMClassParams into a map of account class to
+// its ICBM limits.
+func (c *Config) ParseICBMClassParams() (map[string]ICBMClassLimits, error) {
+	params := make(map[string]ICBMClassLimits, len(c.ICBMClassParams))
+	for _, entry := range c.ICBMClassParams {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		class, limitsStr, found := strings.Cut(entry, ":")
+		intervalStr, maxLenStr, found2 := strings.Cut(limitsStr, "/")
+		if !found || !found2 {
+			return nil, fmt.Errorf("invalid ICBM class params %q. Valid format: CLASS:INTERVAL/MAXLEN (e.g., bot:0s/8000)", entry)
+		}
+
+		class = strings.TrimSpace(class)
+		if !slices.Contains(icbmClasses, class) {
+			return nil, fmt.Errorf("invalid ICBM class params %q: class must be one of %s", entry, strings.Join(icbmClasses, ", "))
+		}
+		if _, dup := params[class]; dup {
+			return nil, fmt.Errorf("invalid ICBM class params %q: class %s listed more than once", entry, class)
+		}
+
+		interval, err := time.ParseDuration(strings.TrimSpace(intervalStr))
+		if err != nil || interval < 0 {
+			return nil, fmt.Errorf("invalid ICBM class params %q: interval must be a non-negative duration", entry)
+		}
+		maxLen, err := strconv.Atoi(strings.TrimSpace(maxLenStr))
+		if err != nil || maxLen < 1 || maxLen > math.MaxUint16 {
+			return nil, fmt.Errorf("invalid ICBM class params %q: max length must be between 1 and %d", entry, math.MaxUint16)
+		}
+		params[class] = ICBMClassLimits{MinInterval: interval, MaxMessageLen: uint16(maxLen)}
+	}
+
+	return params, nil
+}
+
+// ParseChatRoomQuotas parses ChatRoomQuotas into a map of account class to
+// the number of chat rooms its accounts may own.
+func (c *Config) ParseChatRoomQuotas() (map[string]int, error) {
+	quotas := make(map[string]int, len(c.ChatRoomQuotas))
+	for _, entry := range c.ChatRoomQuotas {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		class, countStr, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid chat room quota %q. Valid format: CLASS:COUNT (e.g., standard:10)", entry)
+		}
+
+		class = strings.TrimSpace(class)
+		if !slices.Contains(icbmClasses, class) {
+			return nil, fmt.Errorf("invalid chat room quota %q: class must be one of %s", entry, strings.Join(icbmClasses, ", "))
+		}
+		if _, dup := quotas[class]; dup {
+			return nil, fmt.Errorf("invalid chat room quota %q: class %s listed more than once", entry, class)
+		}
+
+		count, err := strconv.Atoi(strings.TrimSpace(countStr))
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("invalid chat room quota %q: count must be a non-negative integer", entry)
+		}
+		quotas[class] = count
+	}
+
+	return quotas, nil
+}
+
+// chatReplayMaxMessages is the most messages CHAT_REPLAY may replay.
+const chatReplayMaxMessages = 100
+
+// ParseChatReplay parses ChatReplay into a list of replay limits.
+func (c *Config) ParseChatReplay() ([]ChatReplayLimit, error) {
+	var limits []ChatReplayLimit
+	for _, entry := range c.ChatReplay {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		i := strings.LastIndex(entry, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid chat replay %q. Valid format: EXCHANGE:COUNT or EXCHANGE/ROOM:COUNT (e.g., 5:20)", entry)
+		}
+		target, countStr := entry[:i], entry[i+1:]
+		exchangeStr, room, _ := strings.Cut(target, "/")
+
+		exchange, err := strconv.ParseUint(strings.TrimSpace(exchangeStr), 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chat replay %q: exchange must be a number between 0 and 65535", entry)
+		}
+		room = strings.TrimSpace(room)
+		if strings.Contains(target, "/") && room == "" {
+			return nil, fmt.Errorf("invalid chat replay %q: room name must not be empty", entry)
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(countStr))
+		if err != nil || count < 0 || count > chatReplayMaxMessages {
+			return nil, fmt.Errorf("invalid chat replay %q: count must be between 0 and %d", entry, chatReplayMaxMessages)
+		}
+
+		limit := ChatReplayLimit{Exchange: uint16(exchange), Room: room, Messages: count}
+		if slices.ContainsFunc(limits, func(l ChatReplayLimit) bool { return l.Exchange == limit.Exchange && l.Room == limit.Room }) {
+			return nil, fmt.Errorf("invalid chat replay %q: listed more than once", entry)
+		}
+		limits = append(limits, limit)
+	}
+
+	return limits, nil
+}
+
+// ParseWellKnownURLs parses WellKnownURLs into a map of TLV tag to URL.
+func (c *Config) ParseWellKnownURLs() (map[uint16]string, error) {
+	urls := make(map[uint16]string, len(c.WellKnownURLs))
+	for _, entry := range c.WellKnownURLs {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		tagStr, rawURL, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid well-known URL %q. Valid format: TAG=URL (e.g., 0x0001=http://example.com/spellcheck.dic)", entry)
+		}
+
+		tag, err := strconv.ParseUint(strings.TrimSpace(tagStr), 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid well-known URL %q: tag must be a number between 0 and %d", entry, math.MaxUint16)
+		}
+		if _, dup := urls[uint16(tag)]; dup {
+			return nil, fmt.Errorf("invalid well-known URL %q: tag %d listed more than once", entry, tag)
+		}
+
+		rawURL = strings.TrimSpace(rawURL)
+		u, err := url.Parse(rawURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid well-known URL %q: must be an absolute http or https URL", entry)
+		}
+		urls[uint16(tag)] = rawURL
+	}
+
+	return urls, nil
+}
+
+// capNames lists the valid CAP_OVERRIDES capability names.
+var capNames = []string{"chat", "voice", "filetransfer", "directim", "buddyicon", "addins", "fileshare", "games", "buddylisttransfer", "utf8", "icqserverrelay"}
+
+// ParseCapOverrides parses CapOverrides into a map of target ('aim', 'icq',
+// 'guest' or '@' followed by a screen name) to capability changes. Each
+// change is a capability name, prefixed with '-' if it is stripped.
+func (c *Config) ParseCapOverrides() (map[string][]string, error) {
+	overrides := make(map[string][]string, len(c.CapOverrides))
+	for _, entry := range c.CapOverrides {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		target, changesStr, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid capability override %q. Valid format: TARGET:CHANGES (e.g., guest:-filetransfer+-directim)", entry)
+		}
+
+		target = strings.TrimSpace(target)
+		if target != "aim" && target != "icq" && target != "guest" && (!strings.HasPrefix(target, "@") || len(target) == 1) {
+			return nil, fmt.Errorf("invalid capability override %q: target must be one of aim, icq, guest or @SCREENNAME", entry)
+		}
+		if _, dup := overrides[target]; dup {
+			return nil, fmt.Errorf("invalid capability override %q: target %s listed more than once", entry, target)
+		}
+
+		var changes []string
+		for _, change := range strings.Split(changesStr, "+") {
+			change = strings.TrimSpace(change)
+			if !slices.Contains(capNames, strings.TrimPrefix(change, "-")) {
+				return nil, fmt.Errorf("invalid capability override %q: capability must be one of %s", entry, strings.Join(capNames, ", "))
+			}
+			changes = append(changes, change)
+		}
+		overrides[target] = changes
+	}
+
+	return overrides, nil
+}
+
+// validateWelcome checks the WELCOME_* settings.
+func (c *Config) validateWelcome() error {
+	if c.WelcomeMode != "" && !slices.Contains([]string{"off", "first", "every"}, c.WelcomeMode) {
+		return fmt.Errorf("invalid welcome mode %q: must be one of off, first or every", c.WelcomeMode)
+	}
+	if _, err := template.New("").Parse(c.WelcomeMessage); err != nil {
+		return fmt.Errorf("invalid welcome message: %w", err)
+	}
+	if _, err := template.New("").Parse(c.WelcomePopup); err != nil {
+		return fmt.Errorf("invalid welcome popup: %w", err)
+	}
+	if c.WelcomePopupURL != "" {
+		if c.WelcomePopup == "" {
+			return errors.New("invalid welcome popup URL: WELCOME_POPUP must be set")
+		}
+		u, err := url.Parse(c.WelcomePopupURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid welcome popup URL %q: must be an absolute http or https URL", c.WelcomePopupURL)
+		}
+	}
 	return nil
 }
 
+// ParseConnCIDRs parses ConnAllowCIDRs and ConnDenyCIDRs into IP prefixes.
+// Single IP addresses are converted to prefixes that match only themselves.
+func (c *Config) ParseConnCIDRs() (allow, deny []netip.Prefix, err error) {
+	if allow, err = parseCIDRs(c.ConnAllowCIDRs); err != nil {
+		return nil, nil, fmt.Errorf("invalid connection allow list: %w", err)
+	}
+	if deny, err = parseCIDRs(c.ConnDenyCIDRs); err != nil {
+		return nil, nil, fmt.Errorf("invalid connection deny list: %w", err)
+	}
+	return allow, deny, nil
+}
+
+func parseCIDRs(entries []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an IP address or CIDR range (e.g., 192.168.0.0/16)", entry)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an IP address or CIDR range (e.g., 192.168.0.0/16)", entry)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// ParseConnBlockCountries parses ConnBlockCountries into upper-case ISO
+// 3166-1 alpha-2 country codes.
+func (c *Config) ParseConnBlockCountries() ([]string, error) {
+	var countries []string
+	for _, code := range c.ConnBlockCountries {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" {
+			continue
+		}
+		if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+			return nil, fmt.Errorf("invalid blocked country %q: must be a two-letter ISO 3166-1 country code (e.g., US)", code)
+		}
+		countries = append(countries, code)
+	}
+	return countries, nil
+}
+
 func (c *Config) ParseListenersCfg() ([]Listener, error) {
 	m := make(map[string]*Listener)
 	// parse BOS listeners
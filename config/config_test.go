@@ -1,6 +1,12 @@
 package config
 
-import "testing"
+import (
+	"maps"
+	"net/netip"
+	"slices"
+	"testing"
+	"time"
+)
 
 func TestConfigValidate(t *testing.T) {
 	tests := []struct {
@@ -131,6 +137,589 @@ func TestConfigValidate(t *testing.T) {
 			wantErr:     true,
 			errContains: "APIListener is required and cannot be empty",
 		},
+		{
+			name: "valid login throttling settings",
+			config: Config{
+				APIListener:        "127.0.0.1:8080",
+				LoginMaxConcurrent: 100,
+				LoginQueueTimeout:  5 * time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid login max concurrent",
+			config: Config{
+				APIListener:        "127.0.0.1:8080",
+				LoginMaxConcurrent: -1,
+			},
+			wantErr:     true,
+			errContains: "invalid login max concurrent -1",
+		},
+		{
+			name: "valid locate rights",
+			config: Config{
+				APIListener:           "127.0.0.1:8080",
+				LocateMaxSigLen:       4096,
+				LocateMaxCapabilities: 0,
+				LocateMaxEmailLookups: 10,
+				LocateMaxCertsLen:     65535,
+			},
+			wantErr: false,
+		},
+		{
+			name: "locate max sig len exceeds uint16",
+			config: Config{
+				APIListener:     "127.0.0.1:8080",
+				LocateMaxSigLen: 65536,
+			},
+			wantErr:     true,
+			errContains: "invalid locate max sig len 65536",
+		},
+		{
+			name: "negative locate max certs len",
+			config: Config{
+				APIListener:       "127.0.0.1:8080",
+				LocateMaxCertsLen: -1,
+			},
+			wantErr:     true,
+			errContains: "invalid locate max certs len -1",
+		},
+		{
+			name: "valid guest screen name prefix",
+			config: Config{
+				APIListener:           "127.0.0.1:8080",
+				GuestScreenNamePrefix: "Visitor2",
+			},
+			wantErr: false,
+		},
+		{
+			name: "guest screen name prefix with space",
+			config: Config{
+				APIListener:           "127.0.0.1:8080",
+				GuestScreenNamePrefix: "Web Guest",
+			},
+			wantErr:     true,
+			errContains: `invalid guest screen name prefix "Web Guest"`,
+		},
+		{
+			name: "guest screen name prefix starting with digit",
+			config: Config{
+				APIListener:           "127.0.0.1:8080",
+				GuestScreenNamePrefix: "1Guest",
+			},
+			wantErr:     true,
+			errContains: `invalid guest screen name prefix "1Guest"`,
+		},
+		{
+			name: "guest screen name prefix too long",
+			config: Config{
+				APIListener:           "127.0.0.1:8080",
+				GuestScreenNamePrefix: "VeryLongGuestName",
+			},
+			wantErr:     true,
+			errContains: "must be at most 12 characters",
+		},
+		{
+			name: "invalid account expiry grace period",
+			config: Config{
+				APIListener:        "127.0.0.1:8080",
+				AccountExpiryGrace: -time.Hour,
+			},
+			wantErr:     true,
+			errContains: "invalid account expiry grace period -1h0m0s",
+		},
+		{
+			name: "valid chat room create perms",
+			config: Config{
+				APIListener:         "127.0.0.1:8080",
+				ChatRoomCreatePerms: []string{"4:confirmed", " 5:admins ", "6:nobody"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "chat room create perms missing tier",
+			config: Config{
+				APIListener:         "127.0.0.1:8080",
+				ChatRoomCreatePerms: []string{"4"},
+			},
+			wantErr:     true,
+			errContains: `invalid chat room create perms "4"`,
+		},
+		{
+			name: "chat room create perms bad exchange",
+			config: Config{
+				APIListener:         "127.0.0.1:8080",
+				ChatRoomCreatePerms: []string{"70000:everyone"},
+			},
+			wantErr:     true,
+			errContains: "exchange must be a number between 0 and 65535",
+		},
+		{
+			name: "chat room create perms unknown tier",
+			config: Config{
+				APIListener:         "127.0.0.1:8080",
+				ChatRoomCreatePerms: []string{"4:members"},
+			},
+			wantErr:     true,
+			errContains: "tier must be one of nobody, admins, confirmed, everyone",
+		},
+		{
+			name: "chat room create perms duplicate exchange",
+			config: Config{
+				APIListener:         "127.0.0.1:8080",
+				ChatRoomCreatePerms: []string{"4:everyone", "4:admins"},
+			},
+			wantErr:     true,
+			errContains: "exchange 4 listed more than once",
+		},
+		{
+			name: "valid DND suppress",
+			config: Config{
+				APIListener: "127.0.0.1:8080",
+				DNDSuppress: []string{"dnd:popups+invites+warnings", " busy:warnings "},
+			},
+			wantErr: false,
+		},
+		{
+			name: "DND suppress missing kinds",
+			config: Config{
+				APIListener: "127.0.0.1:8080",
+				DNDSuppress: []string{"dnd"},
+			},
+			wantErr:     true,
+			errContains: `invalid DND suppress setting "dnd"`,
+		},
+		{
+			name: "DND suppress unknown status",
+			config: Config{
+				APIListener: "127.0.0.1:8080",
+				DNDSuppress: []string{"invisible:popups"},
+			},
+			wantErr:     true,
+			errContains: "status must be one of dnd, busy, away, na",
+		},
+		{
+			name: "DND suppress unknown kind",
+			config: Config{
+				APIListener: "127.0.0.1:8080",
+				DNDSuppress: []string{"dnd:popups+ims"},
+			},
+			wantErr:     true,
+			errContains: "kind must be one of popups, invites, warnings",
+		},
+		{
+			name: "DND suppress duplicate status",
+			config: Config{
+				APIListener: "127.0.0.1:8080",
+				DNDSuppress: []string{"dnd:popups", "dnd:warnings"},
+			},
+			wantErr:     true,
+			errContains: "status dnd listed more than once",
+		},
+		{
+			name: "valid capability overrides",
+			config: Config{
+				APIListener:  "127.0.0.1:8080",
+				CapOverrides: []string{"guest:-filetransfer+-directim", " @ChattingChuck:filetransfer ", "icq:utf8"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "capability override missing changes",
+			config: Config{
+				APIListener:  "127.0.0.1:8080",
+				CapOverrides: []string{"guest"},
+			},
+			wantErr:     true,
+			errContains: `invalid capability override "guest"`,
+		},
+		{
+			name: "capability override unknown target",
+			config: Config{
+				APIListener:  "127.0.0.1:8080",
+				CapOverrides: []string{"admins:-chat"},
+			},
+			wantErr:     true,
+			errContains: "target must be one of aim, icq, guest or @SCREENNAME",
+		},
+		{
+			name: "capability override unknown capability",
+			config: Config{
+				APIListener:  "127.0.0.1:8080",
+				CapOverrides: []string{"aim:-telepathy"},
+			},
+			wantErr:     true,
+			errContains: "capability must be one of chat, voice",
+		},
+		{
+			name: "capability override duplicate target",
+			config: Config{
+				APIListener:  "127.0.0.1:8080",
+				CapOverrides: []string{"@chuck:-chat", "@chuck:voice"},
+			},
+			wantErr:     true,
+			errContains: "target @chuck listed more than once",
+		},
+		{
+			name: "welcome mode unknown",
+			config: Config{
+				APIListener: "127.0.0.1:8080",
+				WelcomeMode: "always",
+			},
+			wantErr:     true,
+			errContains: "must be one of off, first or every",
+		},
+		{
+			name: "welcome message bad template",
+			config: Config{
+				APIListener:    "127.0.0.1:8080",
+				WelcomeMessage: "Hi {{.ScreenName",
+			},
+			wantErr:     true,
+			errContains: "invalid welcome message",
+		},
+		{
+			name: "welcome popup URL without popup",
+			config: Config{
+				APIListener:     "127.0.0.1:8080",
+				WelcomePopupURL: "https://example.com/welcome",
+			},
+			wantErr:     true,
+			errContains: "WELCOME_POPUP must be set",
+		},
+		{
+			name: "welcome popup URL relative",
+			config: Config{
+				APIListener:     "127.0.0.1:8080",
+				WelcomePopup:    "Welcome!",
+				WelcomePopupURL: "/welcome",
+			},
+			wantErr:     true,
+			errContains: "must be an absolute http or https URL",
+		},
+		{
+			name: "valid welcome",
+			config: Config{
+				APIListener:     "127.0.0.1:8080",
+				WelcomeMode:     "first",
+				WelcomeMessage:  "Welcome, {{.ScreenName}}!",
+				WelcomePopup:    "<b>Welcome!</b>",
+				WelcomePopupURL: "https://example.com/welcome",
+			},
+		},
+		{
+			name: "admin bot without admins",
+			config: Config{
+				APIListener:        "127.0.0.1:8080",
+				AdminBotScreenName: "AdminBot",
+			},
+			wantErr:     true,
+			errContains: "admin bot requires",
+		},
+		{
+			name: "valid admin bot",
+			config: Config{
+				APIListener:        "127.0.0.1:8080",
+				AdminBotScreenName: "AdminBot",
+				AdminScreenNames:   []string{"chuck"},
+			},
+		},
+		{
+			name: "profanity actions unknown kind",
+			config: Config{
+				APIListener:      "127.0.0.1:8080",
+				ProfanityActions: []string{"email:mask"},
+			},
+			wantErr:     true,
+			errContains: "kind must be one of im, chat, profile",
+		},
+		{
+			name: "profanity actions unknown action",
+			config: Config{
+				APIListener:      "127.0.0.1:8080",
+				ProfanityActions: []string{"chat:ban"},
+			},
+			wantErr:     true,
+			errContains: "action must be one of allow, mask, reject, flag",
+		},
+		{
+			name: "profanity actions duplicate kind",
+			config: Config{
+				APIListener:      "127.0.0.1:8080",
+				ProfanityActions: []string{"im:mask", "im:flag"},
+			},
+			wantErr:     true,
+			errContains: "kind im listed more than once",
+		},
+		{
+			name: "ICBM max message len too large",
+			config: Config{
+				APIListener:       "127.0.0.1:8080",
+				ICBMMaxMessageLen: 70000,
+			},
+			wantErr:     true,
+			errContains: "invalid ICBM max message len 70000",
+		},
+		{
+			name: "ICBM class params unknown class",
+			config: Config{
+				APIListener:     "127.0.0.1:8080",
+				ICBMClassParams: []string{"admin:0s/8000"},
+			},
+			wantErr:     true,
+			errContains: "class must be one of standard, guest, bot, probation",
+		},
+		{
+			name: "ICBM class params bad interval",
+			config: Config{
+				APIListener:     "127.0.0.1:8080",
+				ICBMClassParams: []string{"bot:fast/8000"},
+			},
+			wantErr:     true,
+			errContains: "interval must be a non-negative duration",
+		},
+		{
+			name: "ICBM class params missing max length",
+			config: Config{
+				APIListener:     "127.0.0.1:8080",
+				ICBMClassParams: []string{"guest:5s"},
+			},
+			wantErr:     true,
+			errContains: "Valid format: CLASS:INTERVAL/MAXLEN",
+		},
+		{
+			name: "valid ICBM params",
+			config: Config{
+				APIListener:       "127.0.0.1:8080",
+				ICBMMinInterval:   time.Second,
+				ICBMMaxMessageLen: 2000,
+				ICBMClassParams:   []string{"bot:0s/8000", " guest:5s/1024 "},
+			},
+		},
+		{
+			name: "negative probation period",
+			config: Config{
+				APIListener:     "127.0.0.1:8080",
+				ProbationPeriod: -time.Hour,
+			},
+			wantErr:     true,
+			errContains: "invalid probation period -1h0m0s",
+		},
+		{
+			name: "screen name word with digits",
+			config: Config{
+				APIListener:     "127.0.0.1:8080",
+				ScreenNameNouns: []string{"Otter", "R2D2"},
+			},
+			wantErr:     true,
+			errContains: `invalid screen name word "R2D2"`,
+		},
+		{
+			name: "chat replay count too large",
+			config: Config{
+				APIListener: "127.0.0.1:8080",
+				ChatReplay:  []string{"5:101"},
+			},
+			wantErr:     true,
+			errContains: `invalid chat replay "5:101": count must be between 0 and 100`,
+		},
+		{
+			name: "chat replay room listed twice",
+			config: Config{
+				APIListener: "127.0.0.1:8080",
+				ChatReplay:  []string{"5/Lobby:10", "5/Lobby:20"},
+			},
+			wantErr:     true,
+			errContains: `invalid chat replay "5/Lobby:20": listed more than once`,
+		},
+		{
+			name: "presence webhook limit negative",
+			config: Config{
+				APIListener:          "127.0.0.1:8080",
+				PresenceWebhookLimit: -1,
+			},
+			wantErr:     true,
+			errContains: "invalid presence webhook limit -1",
+		},
+		{
+			name: "login cookie limit negative",
+			config: Config{
+				APIListener:      "127.0.0.1:8080",
+				LoginCookieLimit: -1,
+			},
+			wantErr:     true,
+			errContains: "invalid login cookie limit -1",
+		},
+		{
+			name: "departure grace period negative",
+			config: Config{
+				APIListener:    "127.0.0.1:8080",
+				DepartureGrace: -time.Second,
+			},
+			wantErr:     true,
+			errContains: "invalid departure grace period -1s",
+		},
+		{
+			name: "soft limit negative",
+			config: Config{
+				APIListener:       "127.0.0.1:8080",
+				SoftLimitDBSizeMB: -1,
+			},
+			wantErr:     true,
+			errContains: "invalid soft limit",
+		},
+		{
+			name: "unknown URL blocklist action",
+			config: Config{
+				APIListener:        "127.0.0.1:8080",
+				URLBlocklistAction: "quarantine",
+			},
+			wantErr:     true,
+			errContains: "invalid URL blocklist action",
+		},
+		{
+			name: "chat room quota of unknown class",
+			config: Config{
+				APIListener:    "127.0.0.1:8080",
+				ChatRoomQuotas: []string{"admin:5"},
+			},
+			wantErr:     true,
+			errContains: "class must be one of",
+		},
+		{
+			name: "negative chat room quota",
+			config: Config{
+				APIListener:    "127.0.0.1:8080",
+				ChatRoomQuotas: []string{"standard:-1"},
+			},
+			wantErr:     true,
+			errContains: "count must be a non-negative integer",
+		},
+		{
+			name: "BUCP nonce TTL negative",
+			config: Config{
+				APIListener:  "127.0.0.1:8080",
+				BUCPNonceTTL: -time.Second,
+			},
+			wantErr:     true,
+			errContains: "invalid BUCP nonce TTL -1s",
+		},
+		{
+			name: "SNAC history size too large",
+			config: Config{
+				APIListener:     "127.0.0.1:8080",
+				SNACHistorySize: 1001,
+			},
+			wantErr:     true,
+			errContains: "invalid SNAC history size 1001",
+		},
+		{
+			name: "webhook URL relative",
+			config: Config{
+				APIListener: "127.0.0.1:8080",
+				WebhookURL:  "/hooks",
+			},
+			wantErr:     true,
+			errContains: "invalid webhook URL",
+		},
+		{
+			name: "valid webhook",
+			config: Config{
+				APIListener:   "127.0.0.1:8080",
+				WebhookURL:    "https://mod.example.com/hooks",
+				WebhookSecret: "s3cret",
+			},
+		},
+		{
+			name: "well-known URL bad tag",
+			config: Config{
+				APIListener:   "127.0.0.1:8080",
+				WellKnownURLs: []string{"0x10000=http://example.com/a"},
+			},
+			wantErr:     true,
+			errContains: "tag must be a number between 0 and 65535",
+		},
+		{
+			name: "well-known URL duplicate tag",
+			config: Config{
+				APIListener:   "127.0.0.1:8080",
+				WellKnownURLs: []string{"1=http://example.com/a", "0x0001=http://example.com/b"},
+			},
+			wantErr:     true,
+			errContains: "tag 1 listed more than once",
+		},
+		{
+			name: "well-known URL relative",
+			config: Config{
+				APIListener:   "127.0.0.1:8080",
+				WellKnownURLs: []string{"1=/wellknown/a"},
+			},
+			wantErr:     true,
+			errContains: "must be an absolute http or https URL",
+		},
+		{
+			name: "valid well-known URLs",
+			config: Config{
+				APIListener:       "127.0.0.1:8080",
+				WellKnownURLs:     []string{"0x0001=http://127.0.0.1:8080/wellknown/spellcheck.dic", " 2=https://example.com/?a=b "},
+				WellKnownFilesDir: "wellknown",
+			},
+		},
+		{
+			name: "valid connection policy",
+			config: Config{
+				APIListener:        "127.0.0.1:8080",
+				ConnAllowCIDRs:     []string{"192.168.0.0/16", "10.1.2.3", "2001:db8::/32"},
+				ConnDenyCIDRs:      []string{"192.168.1.0/24"},
+				ConnBlockCountries: []string{"us", " DE "},
+			},
+			wantErr: false,
+		},
+		{
+			name: "connection allow list invalid CIDR",
+			config: Config{
+				APIListener:    "127.0.0.1:8080",
+				ConnAllowCIDRs: []string{"192.168.0.0/33"},
+			},
+			wantErr:     true,
+			errContains: `invalid connection allow list: "192.168.0.0/33" is not an IP address or CIDR range`,
+		},
+		{
+			name: "connection deny list invalid address",
+			config: Config{
+				APIListener:   "127.0.0.1:8080",
+				ConnDenyCIDRs: []string{"example.com"},
+			},
+			wantErr:     true,
+			errContains: `invalid connection deny list: "example.com" is not an IP address or CIDR range`,
+		},
+		{
+			name: "connection blocked country invalid code",
+			config: Config{
+				APIListener:        "127.0.0.1:8080",
+				ConnBlockCountries: []string{"USA"},
+			},
+			wantErr:     true,
+			errContains: `invalid blocked country "USA"`,
+		},
+		{
+			name: "session max queue depth exceeds capacity",
+			config: Config{
+				APIListener:          "127.0.0.1:8080",
+				SessionMaxQueueDepth: 1001,
+			},
+			wantErr:     true,
+			errContains: "invalid session max queue depth 1001",
+		},
+		{
+			name: "invalid login queue timeout",
+			config: Config{
+				APIListener:       "127.0.0.1:8080",
+				LoginQueueTimeout: -time.Second,
+			},
+			wantErr:     true,
+			errContains: "invalid login queue timeout -1s",
+		},
 	}
 
 	for _, tt := range tests {
@@ -615,3 +1204,69 @@ func contains(s, substr string) bool {
 
 	return false
 }
+
+func TestParseConnCIDRs(t *testing.T) {
+	c := Config{
+		ConnAllowCIDRs: []string{"192.168.1.7/16", " 10.1.2.3 ", "", "2001:db8::1"},
+		ConnDenyCIDRs:  []string{"172.16.0.0/12"},
+	}
+
+	allow, deny, err := c.ParseConnCIDRs()
+	if err != nil {
+		t.Fatalf("ParseConnCIDRs() unexpected error = %v", err)
+	}
+
+	wantAllow := []netip.Prefix{
+		netip.MustParsePrefix("192.168.0.0/16"),
+		netip.MustParsePrefix("10.1.2.3/32"),
+		netip.MustParsePrefix("2001:db8::1/128"),
+	}
+	if !slices.Equal(allow, wantAllow) {
+		t.Errorf("ParseConnCIDRs() allow = %v, want %v", allow, wantAllow)
+	}
+
+	wantDeny := []netip.Prefix{netip.MustParsePrefix("172.16.0.0/12")}
+	if !slices.Equal(deny, wantDeny) {
+		t.Errorf("ParseConnCIDRs() deny = %v, want %v", deny, wantDeny)
+	}
+}
+
+func TestParseChatReplay(t *testing.T) {
+	c := Config{
+		ChatReplay: []string{"5:20", " 4/Team: Ops:5 ", "", "5/Lobby:0"},
+	}
+
+	limits, err := c.ParseChatReplay()
+	if err != nil {
+		t.Fatalf("ParseChatReplay() unexpected error = %v", err)
+	}
+
+	want := []ChatReplayLimit{
+		{Exchange: 5, Messages: 20},
+		{Exchange: 4, Room: "Team: Ops", Messages: 5},
+		{Exchange: 5, Room: "Lobby", Messages: 0},
+	}
+	if !slices.Equal(limits, want) {
+		t.Errorf("ParseChatReplay() = %v, want %v", limits, want)
+	}
+}
+
+func TestParseChatRoomCreatePerms(t *testing.T) {
+	c := Config{
+		ChatRoomCreatePerms: []string{"4:confirmed", " 5:admins ", "", "6:nobody"},
+	}
+
+	perms, err := c.ParseChatRoomCreatePerms()
+	if err != nil {
+		t.Fatalf("ParseChatRoomCreatePerms() unexpected error = %v", err)
+	}
+
+	want := map[uint16]string{
+		4: "confirmed",
+		5: "admins",
+		6: "nobody",
+	}
+	if !maps.Equal(perms, want) {
+		t.Errorf("ParseChatRoomCreatePerms() = %v, want %v", perms, want)
+	}
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FromEnv builds a Config from environment variables named by the envconfig
+// struct tags. lookup has the same semantics as os.LookupEnv.
+// List values are comma-separated. An error is returned if a required
+// variable is missing or a value can't be parsed.
+func FromEnv(lookup func(key string) (string, bool)) (Config, error) {
+	cfg := Config{}
+	v := reflect.ValueOf(&cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("envconfig")
+		if key == "" {
+			continue
+		}
+
+		val, ok := lookup(key)
+		if !ok {
+			if field.Tag.Get("required") == "true" {
+				return cfg, fmt.Errorf("required environment variable %s is not set", key)
+			}
+			continue
+		}
+
+		if err := setField(v.Field(i), val); err != nil {
+			return cfg, fmt.Errorf("invalid value %q for %s: %w", val, key, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// ParseEnvFile reads KEY=VALUE pairs from a settings file such as
+// settings.env. Blank lines, comments, and the leading `export` keyword are
+// ignored, and surrounding quotes are stripped from values.
+func ParseEnvFile(r io.Reader) (map[string]string, error) {
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+		key, val, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNum)
+		}
+		val = strings.TrimSpace(val)
+		if unquoted, err := strconv.Unquote(val); err == nil {
+			val = unquoted
+		} else {
+			val = strings.Trim(val, "'")
+		}
+
+		vars[strings.TrimSpace(key)] = val
+	}
+
+	return vars, scanner.Err()
+}
+
+func setField(field reflect.Value, val string) error {
+	switch field.Interface().(type) {
+	case []string:
+		var list []string
+		for _, item := range strings.Split(val, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		field.Set(reflect.ValueOf(list))
+	case string:
+		field.SetString(val)
+	case bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case time.Duration:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case int:
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return nil
+}
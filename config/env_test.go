@@ -0,0 +1,128 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFromEnv(t *testing.T) {
+	required := map[string]string{
+		"OSCAR_LISTENERS":                  "LOCAL://0.0.0.0:5190",
+		"OSCAR_ADVERTISED_LISTENERS_PLAIN": "LOCAL://127.0.0.1:5190",
+		"TOC_LISTENERS":                    "0.0.0.0:9898, 192.168.1.10:9899",
+		"DISABLE_AUTH":                     "true",
+		"API_LISTENER":                     "127.0.0.1:8080",
+		"DB_PATH":                          "go-icq.sqlite",
+		"LOG_LEVEL":                        "info",
+	}
+
+	withVars := func(overrides map[string]string, omit ...string) func(string) (string, bool) {
+		vars := make(map[string]string)
+		for k, v := range required {
+			vars[k] = v
+		}
+		for k, v := range overrides {
+			vars[k] = v
+		}
+		for _, k := range omit {
+			delete(vars, k)
+		}
+		return func(key string) (string, bool) {
+			v, ok := vars[key]
+			return v, ok
+		}
+	}
+
+	tests := []struct {
+		name        string
+		lookup      func(string) (string, bool)
+		want        Config
+		errContains string
+	}{
+		{
+			name: "required and optional variables",
+			lookup: withVars(map[string]string{
+				"LOGIN_MAX_CONCURRENT": "25",
+				"LOGIN_QUEUE_TIMEOUT":  "10s",
+			}),
+			want: Config{
+				BOSListeners:            []string{"LOCAL://0.0.0.0:5190"},
+				BOSAdvertisedHostsPlain: []string{"LOCAL://127.0.0.1:5190"},
+				TOCListeners:            []string{"0.0.0.0:9898", "192.168.1.10:9899"},
+				DisableAuth:             true,
+				APIListener:             "127.0.0.1:8080",
+				DBPath:                  "go-icq.sqlite",
+				LoginMaxConcurrent:      25,
+				LoginQueueTimeout:       10 * time.Second,
+				LogLevel:                "info",
+			},
+		},
+		{
+			name:        "missing required variable",
+			lookup:      withVars(nil, "DB_PATH"),
+			errContains: "required environment variable DB_PATH is not set",
+		},
+		{
+			name:        "invalid bool",
+			lookup:      withVars(map[string]string{"DISABLE_AUTH": "maybe"}),
+			errContains: `invalid value "maybe" for DISABLE_AUTH`,
+		},
+		{
+			name:        "invalid duration",
+			lookup:      withVars(map[string]string{"LOGIN_QUEUE_TIMEOUT": "5"}),
+			errContains: `invalid value "5" for LOGIN_QUEUE_TIMEOUT`,
+		},
+		{
+			name:        "invalid int",
+			lookup:      withVars(map[string]string{"LOGIN_MAX_CONCURRENT": "lots"}),
+			errContains: `invalid value "lots" for LOGIN_MAX_CONCURRENT`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromEnv(tt.lookup)
+			if tt.errContains != "" {
+				if err == nil || !contains(err.Error(), tt.errContains) {
+					t.Errorf("FromEnv() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("FromEnv() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FromEnv() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEnvFile(t *testing.T) {
+	input := `
+# a comment
+export DB_PATH=go-icq.sqlite
+export LOG_LEVEL="debug"
+API_LISTENER='127.0.0.1:8080'
+`
+	got, err := ParseEnvFile(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseEnvFile() unexpected error = %v", err)
+	}
+
+	want := map[string]string{
+		"DB_PATH":      "go-icq.sqlite",
+		"LOG_LEVEL":    "debug",
+		"API_LISTENER": "127.0.0.1:8080",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseEnvFile() = %v, want %v", got, want)
+	}
+
+	if _, err := ParseEnvFile(strings.NewReader("export NOT_A_PAIR")); err == nil {
+		t.Errorf("ParseEnvFile() expected error but got none")
+	}
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Load builds a Config from a YAML settings file, with environment
+// variables taking precedence over the file. lookup has the same semantics
+// as os.LookupEnv.
+//
+// Settings are named like their environment variables, in lower case, such
+// as db_path for DB_PATH. They may be grouped under sections of the
+// operator's choosing, such as listeners, db, limits and features; section
+// names are ignored, and sections can't be nested. Lists may be written as
+// YAML sequences or as comma-separated strings. Durations are strings in Go
+// duration format, such as "10s". Unknown settings are an error, so that
+// typos don't go unnoticed.
+//
+// Example:
+//
+//	listeners:
+//	  oscar_listeners: [LOCAL://0.0.0.0:5190]
+//	  api_listener: 127.0.0.1:8080
+//	db:
+//	  db_path: /var/lib/go-icq/go-icq.sqlite
+//	log_level: info
+func Load(r io.Reader, lookup func(key string) (string, bool)) (Config, error) {
+	doc := map[string]any{}
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("unable to parse YAML settings: %w", err)
+	}
+
+	known := make(map[string]bool)
+	t := reflect.TypeFor[Config]()
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("envconfig"); key != "" {
+			known[key] = true
+		}
+	}
+
+	vars := make(map[string]string)
+	if err := flattenYAML(doc, "", known, vars); err != nil {
+		return Config{}, err
+	}
+
+	return FromEnv(func(key string) (string, bool) {
+		if val, ok := lookup(key); ok {
+			return val, true
+		}
+		val, ok := vars[key]
+		return val, ok
+	})
+}
+
+// flattenYAML adds the settings of doc, found in section, to vars, keyed by
+// their environment variable names.
+func flattenYAML(doc map[string]any, section string, known map[string]bool, vars map[string]string) error {
+	for name, val := range doc {
+		key := strings.ToUpper(name)
+		if !known[key] {
+			nested, ok := val.(map[string]any)
+			if !ok || section != "" {
+				return fmt.Errorf("unknown setting %s", qualifiedName(section, name))
+			}
+			if err := flattenYAML(nested, name, known, vars); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, dup := vars[key]; dup {
+			return fmt.Errorf("setting %s is set more than once", qualifiedName(section, name))
+		}
+
+		s, err := yamlString(val)
+		if err != nil {
+			return fmt.Errorf("invalid value for setting %s: %w", qualifiedName(section, name), err)
+		}
+		vars[key] = s
+	}
+	return nil
+}
+
+// yamlString converts a decoded YAML value into the form of its
+// environment variable.
+func yamlString(val any) (string, error) {
+	switch v := val.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			if _, isList := item.([]any); isList {
+				return "", errors.New("lists can't be nested")
+			}
+			s, err := yamlString(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value of type %T", val)
+	}
+}
+
+func qualifiedName(section, name string) string {
+	if section == "" {
+		return name
+	}
+	return section + "." + name
+}
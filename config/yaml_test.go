@@ -0,0 +1,175 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	const settings = `
+listeners:
+  oscar_listeners: [LOCAL://0.0.0.0:5190]
+  oscar_advertised_listeners_plain: LOCAL://127.0.0.1:5190
+  toc_listeners:
+    - 0.0.0.0:9898
+    - 192.168.1.10:9899
+  api_listener: 127.0.0.1:8080
+db:
+  db_path: go-icq.sqlite
+limits:
+  login_max_concurrent: 25
+  login_queue_timeout: 10s
+  profanity_words:
+features:
+  disable_auth: true
+log_level: info
+`
+
+	// the required settings other than db_path and log_level
+	const base = `
+oscar_listeners: LOCAL://0.0.0.0:5190
+oscar_advertised_listeners_plain: LOCAL://127.0.0.1:5190
+toc_listeners: 0.0.0.0:9898
+api_listener: 127.0.0.1:8080
+disable_auth: false
+`
+	baseConfig := Config{
+		BOSListeners:            []string{"LOCAL://0.0.0.0:5190"},
+		BOSAdvertisedHostsPlain: []string{"LOCAL://127.0.0.1:5190"},
+		TOCListeners:            []string{"0.0.0.0:9898"},
+		APIListener:             "127.0.0.1:8080",
+	}
+
+	noEnv := func(string) (string, bool) { return "", false }
+
+	tests := []struct {
+		name        string
+		settings    string
+		lookup      func(string) (string, bool)
+		want        Config
+		errContains string
+	}{
+		{
+			name:     "settings in sections",
+			settings: settings,
+			lookup:   noEnv,
+			want: Config{
+				BOSListeners:            []string{"LOCAL://0.0.0.0:5190"},
+				BOSAdvertisedHostsPlain: []string{"LOCAL://127.0.0.1:5190"},
+				TOCListeners:            []string{"0.0.0.0:9898", "192.168.1.10:9899"},
+				DisableAuth:             true,
+				APIListener:             "127.0.0.1:8080",
+				DBPath:                  "go-icq.sqlite",
+				LoginMaxConcurrent:      25,
+				LoginQueueTimeout:       10 * time.Second,
+				LogLevel:                "info",
+			},
+		},
+		{
+			name:     "environment overrides the file",
+			settings: settings,
+			lookup: func(key string) (string, bool) {
+				switch key {
+				case "DB_PATH":
+					return "/var/lib/go-icq.sqlite", true
+				case "LOG_LEVEL":
+					return "debug", true
+				}
+				return "", false
+			},
+			want: Config{
+				BOSListeners:            []string{"LOCAL://0.0.0.0:5190"},
+				BOSAdvertisedHostsPlain: []string{"LOCAL://127.0.0.1:5190"},
+				TOCListeners:            []string{"0.0.0.0:9898", "192.168.1.10:9899"},
+				DisableAuth:             true,
+				APIListener:             "127.0.0.1:8080",
+				DBPath:                  "/var/lib/go-icq.sqlite",
+				LoginMaxConcurrent:      25,
+				LoginQueueTimeout:       10 * time.Second,
+				LogLevel:                "debug",
+			},
+		},
+		{
+			name:     "environment fills in missing settings",
+			settings: base,
+			lookup: func(key string) (string, bool) {
+				switch key {
+				case "DB_PATH":
+					return "go-icq.sqlite", true
+				case "LOG_LEVEL":
+					return "info", true
+				}
+				return "", false
+			},
+			want: func() Config {
+				cfg := baseConfig
+				cfg.DBPath = "go-icq.sqlite"
+				cfg.LogLevel = "info"
+				return cfg
+			}(),
+		},
+		{
+			name:        "missing required setting",
+			settings:    base + "db_path: go-icq.sqlite",
+			lookup:      noEnv,
+			errContains: "required environment variable LOG_LEVEL is not set",
+		},
+		{
+			name:        "unknown setting",
+			settings:    "db:\n  db_pth: go-icq.sqlite",
+			lookup:      noEnv,
+			errContains: "unknown setting db.db_pth",
+		},
+		{
+			name:        "nested sections",
+			settings:    "db:\n  sqlite:\n    db_path: go-icq.sqlite",
+			lookup:      noEnv,
+			errContains: "unknown setting db.sqlite",
+		},
+		{
+			name:        "setting repeated in two sections",
+			settings:    "a:\n  db_path: one.sqlite\nb:\n  db_path: two.sqlite\nlog_level: info",
+			lookup:      noEnv,
+			errContains: "is set more than once",
+		},
+		{
+			name:        "nested list",
+			settings:    "toc_listeners: [[0.0.0.0:9898]]",
+			lookup:      noEnv,
+			errContains: "lists can't be nested",
+		},
+		{
+			name:        "invalid value",
+			settings:    base + "db_path: go-icq.sqlite\nlog_level: info\nlogin_queue_timeout: 5",
+			lookup:      noEnv,
+			errContains: `invalid value "5" for LOGIN_QUEUE_TIMEOUT`,
+		},
+		{
+			name:        "malformed YAML",
+			settings:    "db_path: [",
+			lookup:      noEnv,
+			errContains: "unable to parse YAML settings",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(strings.NewReader(tt.settings), tt.lookup)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("Load() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Load() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// AccountPurgeInterval is how often AccountExpiryJob looks for expired
+// accounts to purge.
+const AccountPurgeInterval = time.Hour
+
+// ExpiredUserPurger deletes accounts that expired before a cutoff.
+type ExpiredUserPurger interface {
+	PurgeExpiredUsers(ctx context.Context, cutoff time.Time) ([]IdentScreenName, error)
+}
+
+// AccountExpiryJob periodically deletes trial and temporary accounts once
+// their grace period after expiry has elapsed. Until then, expired accounts
+// are kept but refused at login.
+type AccountExpiryJob struct {
+	store       ExpiredUserPurger
+	gracePeriod time.Duration
+	logger      *slog.Logger
+	nowFn       func() time.Time
+}
+
+// NewAccountExpiryJob creates a new instance of AccountExpiryJob.
+func NewAccountExpiryJob(store ExpiredUserPurger, gracePeriod time.Duration, logger *slog.Logger) *AccountExpiryJob {
+	return &AccountExpiryJob{
+		store:       store,
+		gracePeriod: gracePeriod,
+		logger:      logger,
+		nowFn:       time.Now,
+	}
+}
+
+// Run purges expired accounts every AccountPurgeInterval until ctx is done.
+func (j *AccountExpiryJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(AccountPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.PurgeOnce(ctx); err != nil {
+			j.logger.ErrorContext(ctx, "unable to purge expired accounts", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Schedule adds the purge to s as the "account_expiry" job, to run every
+// AccountPurgeInterval. It is an alternative to Run.
+func (j *AccountExpiryJob) Schedule(s *Scheduler) error {
+	return s.Add("account_expiry", AccountPurgeInterval, AccountPurgeInterval/10, func(ctx context.Context) error {
+		_, err := j.PurgeOnce(ctx)
+		return err
+	})
+}
+
+// PurgeOnce deletes every account whose expiry is older than the grace
+// period and returns the deleted screen names.
+func (j *AccountExpiryJob) PurgeOnce(ctx context.Context) ([]IdentScreenName, error) {
+	purged, err := j.store.PurgeExpiredUsers(ctx, j.nowFn().Add(-j.gracePeriod))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sn := range purged {
+		j.logger.InfoContext(ctx, "purged expired account", "screen_name", sn.String())
+	}
+
+	return purged, nil
+}
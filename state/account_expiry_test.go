@@ -0,0 +1,51 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeExpiredUserPurger struct {
+	cutoff time.Time
+	purged []IdentScreenName
+	err    error
+}
+
+func (f *fakeExpiredUserPurger) PurgeExpiredUsers(ctx context.Context, cutoff time.Time) ([]IdentScreenName, error) {
+	f.cutoff = cutoff
+	return f.purged, f.err
+}
+
+func TestAccountExpiryJob_PurgeOnce(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := &fakeExpiredUserPurger{purged: []IdentScreenName{NewIdentScreenName("trial user")}}
+
+	job := NewAccountExpiryJob(store, 7*24*time.Hour, slog.Default())
+	job.nowFn = func() time.Time { return now }
+
+	purged, err := job.PurgeOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, store.purged, purged)
+	assert.Equal(t, now.Add(-7*24*time.Hour), store.cutoff)
+
+	store.err = errors.New("db is gone")
+	_, err = job.PurgeOnce(context.Background())
+	assert.ErrorIs(t, err, store.err)
+}
+
+func TestAccountExpiryJob_Run(t *testing.T) {
+	store := &fakeExpiredUserPurger{}
+	job := NewAccountExpiryJob(store, time.Hour, slog.Default())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// returns after the initial purge when the context is already done
+	job.Run(ctx)
+	assert.False(t, store.cutoff.IsZero())
+}
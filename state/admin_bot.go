@@ -0,0 +1,209 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// ErrAdminCommandUsage indicates that an admin bot command was called with
+// the wrong arguments. The bot replies with the command's usage.
+var ErrAdminCommandUsage = errors.New("wrong command arguments")
+
+// AdminCommandFunc runs an admin bot command for admin. args is the text
+// that follows the command name, with surrounding spaces removed. The
+// returned text is sent back to the admin.
+type AdminCommandFunc func(ctx context.Context, admin IdentScreenName, args string) (string, error)
+
+type adminCommand struct {
+	usage string
+	fn    AdminCommandFunc
+}
+
+// AdminBotSessions is the session manager used by the admin bot commands.
+type AdminBotSessions interface {
+	AllSessions() []*Session
+	RetrieveSession(screenName IdentScreenName) *Session
+	RelayToAll(ctx context.Context, msg wire.SNACMessage)
+}
+
+// AdminBotStore is the user store used by the admin bot commands.
+type AdminBotStore interface {
+	User(ctx context.Context, screenName IdentScreenName) (*User, error)
+	UpdateSuspendedStatus(ctx context.Context, suspendedStatus uint16, screenName IdentScreenName) error
+}
+
+// AdminBot lets server admins manage the server from any AIM client by
+// sending IMs to the bot's screen name. Messages starting with "/" are
+// routed to commands, such as "/who", "/broadcast <message>" and
+// "/suspend <screen name>". Messages from users who aren't admins are
+// refused. An AdminBot must be fully set up with Handle before it receives
+// messages.
+type AdminBot struct {
+	screenName DisplayScreenName
+	admins     []IdentScreenName
+	sessions   AdminBotSessions
+	store      AdminBotStore
+	logger     *slog.Logger
+	commands   map[string]adminCommand
+}
+
+// NewAdminBot creates a new instance of AdminBot that answers IMs sent to
+// screenName and accepts commands from admins. It comes with the
+// /help, /who, /broadcast, /kick, /suspend and /unsuspend commands.
+func NewAdminBot(screenName DisplayScreenName, admins []IdentScreenName, sessions AdminBotSessions, store AdminBotStore, logger *slog.Logger) *AdminBot {
+	b := &AdminBot{
+		screenName: screenName,
+		admins:     admins,
+		sessions:   sessions,
+		store:      store,
+		logger:     logger,
+		commands:   make(map[string]adminCommand),
+	}
+	b.Handle("help", "/help", b.help)
+	b.Handle("who", "/who", b.who)
+	b.Handle("broadcast", "/broadcast <message>", b.broadcast)
+	b.Handle("kick", "/kick <screen name>", b.kick)
+	b.Handle("suspend", "/suspend <screen name>", b.suspend)
+	b.Handle("unsuspend", "/unsuspend <screen name>", b.unsuspend)
+	return b
+}
+
+// ScreenName returns the screen name that the bot answers IMs for.
+func (b *AdminBot) ScreenName() DisplayScreenName {
+	return b.screenName
+}
+
+// Handle adds a command, called as "/name", or replaces the command of the
+// same name. usage is shown by /help and when fn returns
+// ErrAdminCommandUsage.
+func (b *AdminBot) Handle(name, usage string, fn AdminCommandFunc) {
+	b.commands[strings.ToLower(name)] = adminCommand{usage: usage, fn: fn}
+}
+
+// Dispatch runs the command in an IM from sender and returns the reply.
+// text may contain the HTML markup that clients wrap IMs in.
+func (b *AdminBot) Dispatch(ctx context.Context, sender IdentScreenName, text string) string {
+	if !slices.Contains(b.admins, sender) {
+		b.logger.InfoContext(ctx, "refused admin bot command from non-admin", "screen_name", sender)
+		return "You are not allowed to use this bot."
+	}
+
+	text = strings.TrimSpace(html.UnescapeString(chatHTMLTagRegexp.ReplaceAllString(text, "")))
+	line, ok := strings.CutPrefix(text, "/")
+	if !ok {
+		return "Send /help for a list of commands."
+	}
+	name, args, _ := strings.Cut(line, " ")
+	cmd, ok := b.commands[strings.ToLower(name)]
+	if !ok {
+		return fmt.Sprintf("Unknown command /%s. Send /help for a list of commands.", name)
+	}
+
+	b.logger.InfoContext(ctx, "running admin bot command", "screen_name", sender, "command", name)
+	reply, err := cmd.fn(ctx, sender, strings.TrimSpace(args))
+	switch {
+	case errors.Is(err, ErrAdminCommandUsage):
+		return "Usage: " + cmd.usage
+	case err != nil:
+		b.logger.ErrorContext(ctx, "admin bot command failed", "command", name, "err", err)
+		return fmt.Sprintf("/%s failed: %s", name, err)
+	}
+	return reply
+}
+
+// ReceiveIM runs the command in an IM that sess sent to the bot and sends
+// the reply to sess.
+func (b *AdminBot) ReceiveIM(ctx context.Context, sess *Session, text string) {
+	reply := b.Dispatch(ctx, sess.IdentScreenName(), text)
+	reply = strings.ReplaceAll(html.EscapeString(reply), "\n", "<br>")
+	msg, err := newServerIM(b.screenName.String(), reply)
+	if err != nil {
+		b.logger.ErrorContext(ctx, "unable to build admin bot reply", "err", err)
+		return
+	}
+	if sess.RelayMessage(msg) != SessSendOK {
+		b.logger.DebugContext(ctx, "unable to send admin bot reply", "screen_name", sess.IdentScreenName())
+	}
+}
+
+func (b *AdminBot) help(_ context.Context, _ IdentScreenName, _ string) (string, error) {
+	usages := make([]string, 0, len(b.commands))
+	for _, cmd := range b.commands {
+		usages = append(usages, cmd.usage)
+	}
+	sort.Strings(usages)
+	return "Commands:\n" + strings.Join(usages, "\n"), nil
+}
+
+func (b *AdminBot) who(_ context.Context, _ IdentScreenName, _ string) (string, error) {
+	var names []string
+	for _, sess := range b.sessions.AllSessions() {
+		names = append(names, sess.DisplayScreenName().String())
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("%d online: %s", len(names), strings.Join(names, ", ")), nil
+}
+
+func (b *AdminBot) broadcast(ctx context.Context, _ IdentScreenName, args string) (string, error) {
+	if args == "" {
+		return "", ErrAdminCommandUsage
+	}
+	msg, err := newServerIM(SystemMessageScreenName, html.EscapeString(args))
+	if err != nil {
+		return "", err
+	}
+	b.sessions.RelayToAll(ctx, msg)
+	return "Broadcast sent.", nil
+}
+
+func (b *AdminBot) kick(_ context.Context, _ IdentScreenName, args string) (string, error) {
+	if args == "" {
+		return "", ErrAdminCommandUsage
+	}
+	sess := b.sessions.RetrieveSession(NewIdentScreenName(args))
+	if sess == nil {
+		return args + " is not online.", nil
+	}
+	sess.CloseWithReason(DisconnectKicked)
+	return "Kicked " + args + ".", nil
+}
+
+func (b *AdminBot) suspend(ctx context.Context, _ IdentScreenName, args string) (string, error) {
+	if err := b.setSuspended(ctx, args, wire.LoginErrSuspendedAccount); err != nil {
+		return "", err
+	}
+	if sess := b.sessions.RetrieveSession(NewIdentScreenName(args)); sess != nil {
+		sess.CloseWithReason(DisconnectSuspended)
+	}
+	return "Suspended " + args + ".", nil
+}
+
+func (b *AdminBot) unsuspend(ctx context.Context, _ IdentScreenName, args string) (string, error) {
+	if err := b.setSuspended(ctx, args, 0); err != nil {
+		return "", err
+	}
+	return "Unsuspended " + args + ".", nil
+}
+
+func (b *AdminBot) setSuspended(ctx context.Context, screenName string, status uint16) error {
+	if screenName == "" {
+		return ErrAdminCommandUsage
+	}
+	sn := NewIdentScreenName(screenName)
+	user, err := b.store.User(ctx, sn)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrNoUser
+	}
+	return b.store.UpdateSuspendedStatus(ctx, status, sn)
+}
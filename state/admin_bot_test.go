@@ -0,0 +1,136 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestAdminBot(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, sn := range []DisplayScreenName{"Admin Al", "Troll", "Bystander"} {
+		user, err := NewStubUser(sn)
+		require.NoError(t, err)
+		require.NoError(t, f.InsertUser(ctx, user))
+	}
+	admin := NewIdentScreenName("Admin Al")
+	troll := NewIdentScreenName("Troll")
+
+	sm := NewInMemorySessionManager(slog.Default())
+	sessions := make(map[string]*Session)
+	for _, sn := range []DisplayScreenName{"Admin Al", "Troll", "Bystander"} {
+		sess, err := sm.AddSession(ctx, sn)
+		require.NoError(t, err)
+		sess.SetSignonComplete()
+		sessions[sn.String()] = sess
+	}
+
+	bot := NewAdminBot("AdminBot", []IdentScreenName{admin}, sm, f, slog.Default())
+
+	t.Run("non-admins are refused", func(t *testing.T) {
+		reply := bot.Dispatch(ctx, troll, "/suspend Admin Al")
+		assert.Equal(t, "You are not allowed to use this bot.", reply)
+		user, err := f.User(ctx, admin)
+		require.NoError(t, err)
+		assert.Zero(t, user.SuspendedStatus)
+	})
+
+	t.Run("who", func(t *testing.T) {
+		reply := bot.Dispatch(ctx, admin, `<HTML><BODY><FONT FACE="Arial">/who</FONT></BODY></HTML>`)
+		assert.Equal(t, "3 online: Admin Al, Bystander, Troll", reply)
+	})
+
+	t.Run("help lists commands", func(t *testing.T) {
+		reply := bot.Dispatch(ctx, admin, "/HELP")
+		assert.Contains(t, reply, "/broadcast <message>")
+		assert.Contains(t, reply, "/suspend <screen name>")
+	})
+
+	t.Run("unknown command", func(t *testing.T) {
+		assert.Contains(t, bot.Dispatch(ctx, admin, "/reboot"), "Unknown command /reboot")
+		assert.Contains(t, bot.Dispatch(ctx, admin, "hello"), "Send /help")
+	})
+
+	t.Run("missing arguments", func(t *testing.T) {
+		assert.Equal(t, "Usage: /suspend <screen name>", bot.Dispatch(ctx, admin, "/suspend"))
+	})
+
+	t.Run("broadcast", func(t *testing.T) {
+		reply := bot.Dispatch(ctx, admin, "/broadcast Server restarts in 5 minutes &amp; more")
+		assert.Equal(t, "Broadcast sent.", reply)
+
+		select {
+		case msg := <-sessions["Bystander"].ReceiveMessage():
+			im := msg.Body.(wire.SNAC_0x04_0x07_ICBMChannelMsgToClient)
+			assert.Equal(t, wire.ScreenName(SystemMessageScreenName), im.ScreenName)
+			data, ok := im.Bytes(wire.ICBMTLVAOLIMData)
+			require.True(t, ok)
+			text, err := wire.UnmarshalICBMMessageText(data)
+			require.NoError(t, err)
+			assert.Equal(t, "Server restarts in 5 minutes &amp; more", text)
+		case <-time.After(time.Second):
+			t.Fatal("no broadcast received")
+		}
+	})
+
+	t.Run("suspend and unsuspend", func(t *testing.T) {
+		reply := bot.Dispatch(ctx, admin, "/suspend Troll")
+		assert.Equal(t, "Suspended Troll.", reply)
+
+		user, err := f.User(ctx, troll)
+		require.NoError(t, err)
+		assert.Equal(t, wire.LoginErrSuspendedAccount, user.SuspendedStatus)
+		assert.Equal(t, DisconnectSuspended, sessions["Troll"].DisconnectReason())
+
+		reply = bot.Dispatch(ctx, admin, "/unsuspend Troll")
+		assert.Equal(t, "Unsuspended Troll.", reply)
+		user, err = f.User(ctx, troll)
+		require.NoError(t, err)
+		assert.Zero(t, user.SuspendedStatus)
+
+		reply = bot.Dispatch(ctx, admin, "/suspend nobody")
+		assert.Equal(t, "/suspend failed: user does not exist", reply)
+	})
+
+	t.Run("custom command", func(t *testing.T) {
+		bot.Handle("ping", "/ping", func(ctx context.Context, admin IdentScreenName, args string) (string, error) {
+			return "pong " + admin.String() + " " + args, nil
+		})
+		assert.Equal(t, "pong adminal x", bot.Dispatch(ctx, admin, "/ping   x "))
+	})
+
+	t.Run("reply IM", func(t *testing.T) {
+		adminSess := sessions["Admin Al"]
+		// drain the broadcast sent to the admin
+		for len(adminSess.ReceiveMessage()) > 0 {
+			<-adminSess.ReceiveMessage()
+		}
+
+		bot.ReceiveIM(ctx, adminSess, "/help")
+		select {
+		case msg := <-adminSess.ReceiveMessage():
+			im := msg.Body.(wire.SNAC_0x04_0x07_ICBMChannelMsgToClient)
+			assert.Equal(t, wire.ScreenName("AdminBot"), im.ScreenName)
+			data, _ := im.Bytes(wire.ICBMTLVAOLIMData)
+			text, err := wire.UnmarshalICBMMessageText(data)
+			require.NoError(t, err)
+			assert.Contains(t, text, "Commands:<br>")
+			assert.Contains(t, text, "/broadcast &lt;message&gt;")
+		case <-time.After(time.Second):
+			t.Fatal("no reply received")
+		}
+	})
+}
@@ -0,0 +1,377 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+const (
+	// AutoResponderMaxPatternLen is the longest pattern an auto-responder
+	// rule may have.
+	AutoResponderMaxPatternLen = 1000
+	// AutoResponderMaxReplyLen is the longest reply template an
+	// auto-responder rule may have. Longer rendered replies are cut to this
+	// length.
+	AutoResponderMaxReplyLen = 2000
+)
+
+var (
+	// ErrAutoResponderRuleNotFound indicates that an auto-responder rule
+	// doesn't exist.
+	ErrAutoResponderRuleNotFound = errors.New("auto-responder rule not found")
+	// ErrAutoResponderRuleInvalid indicates that an auto-responder rule has
+	// a bad pattern or reply template, or answers for a screen name that
+	// isn't a bot or system screen name.
+	ErrAutoResponderRuleInvalid = errors.New("invalid auto-responder rule")
+)
+
+// AutoResponderRule replies to IMs sent to a bot or system screen name
+// whose text matches Pattern. Reply is a text/template rendered with an
+// AutoResponderReplyData, such as:
+//
+//	Hi {{.Sender}}, our opening hours are 9-5. You asked: {{index .Groups 0}}
+type AutoResponderRule struct {
+	ID int64 `json:"id"`
+	// ScreenName is the bot or system screen name the rule answers for.
+	ScreenName DisplayScreenName `json:"screen_name"`
+	// Pattern is the regular expression matched against the text of the
+	// IM, with markup removed. Use (?i) for a case-insensitive match.
+	Pattern string `json:"pattern"`
+	// Reply is the template of the reply.
+	Reply string `json:"reply"`
+}
+
+// AutoResponderReplyData is the data an auto-responder reply template is
+// rendered with.
+type AutoResponderReplyData struct {
+	// Sender is the screen name of the user who sent the IM.
+	Sender string
+	// ScreenName is the screen name the IM was sent to.
+	ScreenName string
+	// Message is the text of the IM, with markup removed.
+	Message string
+	// Groups holds the text matched by the whole pattern followed by the
+	// text matched by each of its capture groups.
+	Groups []string
+}
+
+// compiledAutoResponderRule is a rule ready to be matched.
+type compiledAutoResponderRule struct {
+	rule    AutoResponderRule
+	pattern *regexp.Regexp
+	reply   *template.Template
+}
+
+// compile parses the rule's pattern and reply template.
+func (r AutoResponderRule) compile() (compiledAutoResponderRule, error) {
+	if len(r.Pattern) > AutoResponderMaxPatternLen {
+		return compiledAutoResponderRule{}, fmt.Errorf("%w: pattern must be at most %d characters",
+			ErrAutoResponderRuleInvalid, AutoResponderMaxPatternLen)
+	}
+	if len(r.Reply) > AutoResponderMaxReplyLen {
+		return compiledAutoResponderRule{}, fmt.Errorf("%w: reply must be at most %d characters",
+			ErrAutoResponderRuleInvalid, AutoResponderMaxReplyLen)
+	}
+	if strings.TrimSpace(r.Reply) == "" {
+		return compiledAutoResponderRule{}, fmt.Errorf("%w: reply must not be empty", ErrAutoResponderRuleInvalid)
+	}
+	pattern, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return compiledAutoResponderRule{}, fmt.Errorf("%w: %w", ErrAutoResponderRuleInvalid, err)
+	}
+	reply, err := template.New("reply").Option("missingkey=error").Parse(r.Reply)
+	if err != nil {
+		return compiledAutoResponderRule{}, fmt.Errorf("%w: %w", ErrAutoResponderRuleInvalid, err)
+	}
+	return compiledAutoResponderRule{rule: r, pattern: pattern, reply: reply}, nil
+}
+
+// InsertAutoResponderRule validates and stores rule and returns it with its
+// ID set. Rules can only answer for bot accounts, reserved screen names and
+// SystemMessageScreenName, so that they can't impersonate users, and the
+// screen name must have an account, whose deletion removes its rules. It
+// returns ErrAutoResponderRuleInvalid if the rule is malformed or its screen
+// name isn't allowed.
+func (us SQLiteUserStore) InsertAutoResponderRule(ctx context.Context, rule AutoResponderRule) (AutoResponderRule, error) {
+	if _, err := rule.compile(); err != nil {
+		return AutoResponderRule{}, err
+	}
+
+	ident := rule.ScreenName.IdentScreenName()
+	q := `
+		SELECT EXISTS(SELECT 1 FROM users WHERE identScreenName = ?1)
+		     , EXISTS(SELECT 1 FROM users WHERE identScreenName = ?1 AND isBot)
+		    OR EXISTS(SELECT 1 FROM reservedScreenName WHERE identScreenName = ?1)
+		    OR ?1 = ?2
+	`
+	var exists, allowed bool
+	err := us.db.QueryRowContext(ctx, q, ident.String(), NewIdentScreenName(SystemMessageScreenName).String()).Scan(&exists, &allowed)
+	if err != nil {
+		return AutoResponderRule{}, fmt.Errorf("InsertAutoResponderRule: %w", err)
+	}
+	if !allowed {
+		return AutoResponderRule{}, fmt.Errorf("%w: %s isn't a bot or system screen name",
+			ErrAutoResponderRuleInvalid, rule.ScreenName)
+	}
+	if !exists {
+		return AutoResponderRule{}, fmt.Errorf("%w: %s has no account", ErrAutoResponderRuleInvalid, rule.ScreenName)
+	}
+
+	q = `INSERT INTO autoResponderRule (identScreenName, pattern, reply) VALUES (?, ?, ?)`
+	res, err := us.db.ExecContext(ctx, q, ident.String(), rule.Pattern, rule.Reply)
+	if err != nil {
+		return AutoResponderRule{}, fmt.Errorf("InsertAutoResponderRule: %w", err)
+	}
+	if rule.ID, err = res.LastInsertId(); err != nil {
+		return AutoResponderRule{}, fmt.Errorf("InsertAutoResponderRule: %w", err)
+	}
+	rule.ScreenName = DisplayScreenName(ident.String())
+	return rule, nil
+}
+
+// AutoResponderRules returns all auto-responder rules in the order they're
+// matched, oldest first.
+func (us SQLiteUserStore) AutoResponderRules(ctx context.Context) ([]AutoResponderRule, error) {
+	q := `SELECT id, identScreenName, pattern, reply FROM autoResponderRule ORDER BY id`
+	rows, err := us.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("AutoResponderRules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []AutoResponderRule
+	for rows.Next() {
+		var rule AutoResponderRule
+		if err := rows.Scan(&rule.ID, &rule.ScreenName, &rule.Pattern, &rule.Reply); err != nil {
+			return nil, fmt.Errorf("AutoResponderRules: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("AutoResponderRules: %w", err)
+	}
+	return rules, nil
+}
+
+// DeleteAutoResponderRule deletes the rule with id. It returns
+// ErrAutoResponderRuleNotFound if the rule doesn't exist.
+func (us SQLiteUserStore) DeleteAutoResponderRule(ctx context.Context, id int64) error {
+	res, err := us.db.ExecContext(ctx, `DELETE FROM autoResponderRule WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("DeleteAutoResponderRule: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("DeleteAutoResponderRule: %w", err)
+	}
+	if n == 0 {
+		return ErrAutoResponderRuleNotFound
+	}
+	return nil
+}
+
+// AutoResponderStore is the user store used by AutoResponder.
+type AutoResponderStore interface {
+	InsertAutoResponderRule(ctx context.Context, rule AutoResponderRule) (AutoResponderRule, error)
+	AutoResponderRules(ctx context.Context) ([]AutoResponderRule, error)
+	DeleteAutoResponderRule(ctx context.Context, id int64) error
+}
+
+// AutoResponder answers IMs sent to bot and system screen names with the
+// replies of operator-defined AutoResponderRules, which makes FAQ bots
+// possible without writing code. The first rule of the recipient that
+// matches an IM answers it. Rules are kept in the store, managed through
+// Handler and cached in memory until the next Reload. An AutoResponder is
+// safe for concurrent use by multiple goroutines.
+type AutoResponder struct {
+	store  AutoResponderStore
+	logger *slog.Logger
+	mutex  sync.RWMutex
+	rules  map[IdentScreenName][]compiledAutoResponderRule
+}
+
+// NewAutoResponder creates a new instance of AutoResponder. Call Reload to
+// load the stored rules.
+func NewAutoResponder(store AutoResponderStore, logger *slog.Logger) *AutoResponder {
+	return &AutoResponder{
+		store:  store,
+		logger: logger,
+		rules:  make(map[IdentScreenName][]compiledAutoResponderRule),
+	}
+}
+
+// Reload replaces the cached rules with the stored ones. Rules that no
+// longer compile are skipped and logged.
+func (a *AutoResponder) Reload(ctx context.Context) error {
+	stored, err := a.store.AutoResponderRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	rules := make(map[IdentScreenName][]compiledAutoResponderRule)
+	for _, rule := range stored {
+		compiled, err := rule.compile()
+		if err != nil {
+			a.logger.ErrorContext(ctx, "skipping invalid auto-responder rule", "id", rule.ID, "err", err)
+			continue
+		}
+		ident := rule.ScreenName.IdentScreenName()
+		rules[ident] = append(rules[ident], compiled)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.rules = rules
+	return nil
+}
+
+// Answers indicates whether screenName has rules. Handlers route IMs sent
+// to such screen names to ReceiveIM.
+func (a *AutoResponder) Answers(screenName IdentScreenName) bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return len(a.rules[screenName]) > 0
+}
+
+// Respond returns the reply of the first rule of recipient that matches an
+// IM from sender. text may contain the HTML markup that clients wrap IMs
+// in. It returns false if no rule matches. IMs from screen names that have
+// rules themselves are never answered, so that two auto-responders can't
+// keep replying to each other.
+func (a *AutoResponder) Respond(ctx context.Context, recipient DisplayScreenName, sender DisplayScreenName, text string) (string, bool) {
+	a.mutex.RLock()
+	rules := a.rules[recipient.IdentScreenName()]
+	_, senderAnswers := a.rules[sender.IdentScreenName()]
+	a.mutex.RUnlock()
+
+	if senderAnswers {
+		return "", false
+	}
+
+	text = strings.TrimSpace(html.UnescapeString(chatHTMLTagRegexp.ReplaceAllString(text, "")))
+	for _, rule := range rules {
+		groups := rule.pattern.FindStringSubmatch(text)
+		if groups == nil {
+			continue
+		}
+
+		reply := &strings.Builder{}
+		err := rule.reply.Execute(reply, AutoResponderReplyData{
+			Sender:     sender.String(),
+			ScreenName: recipient.String(),
+			Message:    text,
+			Groups:     groups,
+		})
+		if err != nil {
+			a.logger.ErrorContext(ctx, "unable to render auto-responder reply", "id", rule.rule.ID, "err", err)
+			continue
+		}
+		s := reply.String()
+		if len(s) > AutoResponderMaxReplyLen {
+			s = s[:AutoResponderMaxReplyLen]
+		}
+		return s, true
+	}
+	return "", false
+}
+
+// ReceiveIM answers an IM that sess sent to recipient, if a rule matches.
+func (a *AutoResponder) ReceiveIM(ctx context.Context, recipient DisplayScreenName, sess *Session, text string) {
+	reply, ok := a.Respond(ctx, recipient, sess.DisplayScreenName(), text)
+	if !ok {
+		return
+	}
+	reply = strings.ReplaceAll(html.EscapeString(reply), "\n", "<br>")
+	msg, err := newServerIM(recipient.String(), reply)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "unable to build auto-responder reply", "err", err)
+		return
+	}
+	if sess.RelayMessage(msg) != SessSendOK {
+		a.logger.DebugContext(ctx, "unable to send auto-responder reply", "screen_name", sess.IdentScreenName())
+	}
+}
+
+// Handler manages the rules for the management API. GET lists the rules,
+// POST adds the rule in the JSON body and responds with it, and DELETE
+// removes the rule given by the "id" query parameter. Changes take effect
+// immediately.
+func (a *AutoResponder) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		switch r.Method {
+		case http.MethodGet:
+			rules, err := a.store.AutoResponderRules(ctx)
+			if err != nil {
+				a.logger.ErrorContext(ctx, "unable to list auto-responder rules", "err", err)
+				http.Error(w, "Internal server error.", http.StatusInternalServerError)
+				return
+			}
+			if rules == nil {
+				rules = []AutoResponderRule{}
+			}
+			writeHealthJSON(w, http.StatusOK, rules)
+
+		case http.MethodPost:
+			var rule AutoResponderRule
+			r.Body = http.MaxBytesReader(w, r.Body, 16*1024)
+			if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+				http.Error(w, "Invalid JSON body.", http.StatusBadRequest)
+				return
+			}
+			rule.ID = 0
+			rule, err := a.store.InsertAutoResponderRule(ctx, rule)
+			switch {
+			case errors.Is(err, ErrAutoResponderRuleInvalid):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			case err != nil:
+				a.logger.ErrorContext(ctx, "unable to add auto-responder rule", "err", err)
+				http.Error(w, "Internal server error.", http.StatusInternalServerError)
+				return
+			}
+			a.reload(ctx)
+			writeHealthJSON(w, http.StatusCreated, rule)
+
+		case http.MethodDelete:
+			id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+			if err != nil {
+				http.Error(w, "Missing or invalid id.", http.StatusBadRequest)
+				return
+			}
+			err = a.store.DeleteAutoResponderRule(ctx, id)
+			switch {
+			case errors.Is(err, ErrAutoResponderRuleNotFound):
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			case err != nil:
+				a.logger.ErrorContext(ctx, "unable to delete auto-responder rule", "err", err)
+				http.Error(w, "Internal server error.", http.StatusInternalServerError)
+				return
+			}
+			a.reload(ctx)
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// reload reloads the rules after a change made through Handler.
+func (a *AutoResponder) reload(ctx context.Context) {
+	if err := a.Reload(ctx); err != nil {
+		a.logger.ErrorContext(ctx, "unable to reload auto-responder rules", "err", err)
+	}
+}
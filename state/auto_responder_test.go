@@ -0,0 +1,161 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestAutoResponder(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, sn := range []DisplayScreenName{"HelpBot", "Chuck"} {
+		user, err := NewStubUser(sn)
+		require.NoError(t, err)
+		require.NoError(t, f.InsertUser(ctx, user))
+	}
+	require.NoError(t, f.SetBotStatus(ctx, true, NewIdentScreenName("HelpBot")))
+
+	a := NewAutoResponder(f, slog.Default())
+	handler := a.Handler()
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/admin/auto-responder", strings.NewReader(body)))
+		return rec
+	}
+
+	t.Run("rules for users are refused", func(t *testing.T) {
+		rec := post(`{"screen_name":"Chuck","pattern":"hi","reply":"hello"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "isn't a bot or system screen name")
+	})
+
+	t.Run("invalid pattern is refused", func(t *testing.T) {
+		rec := post(`{"screen_name":"HelpBot","pattern":"(hi","reply":"hello"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("add rules", func(t *testing.T) {
+		rec := post(`{"screen_name":"Help Bot","pattern":"(?i)hours\\??$","reply":"Hi {{.Sender}}, we're open 9-5."}`)
+		require.Equal(t, http.StatusCreated, rec.Code)
+		var rule AutoResponderRule
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&rule))
+		assert.Equal(t, AutoResponderRule{ID: 1, ScreenName: "helpbot", Pattern: `(?i)hours\??$`, Reply: "Hi {{.Sender}}, we're open 9-5."}, rule)
+
+		rec = post(`{"screen_name":"HelpBot","pattern":"^price of (\\w+)","reply":"{{index .Groups 1}} costs $5."}`)
+		require.Equal(t, http.StatusCreated, rec.Code)
+		assert.True(t, a.Answers(NewIdentScreenName("HelpBot")))
+	})
+
+	t.Run("respond", func(t *testing.T) {
+		reply, ok := a.Respond(ctx, "HelpBot", "Chuck", `<HTML><BODY>What are your HOURS?</BODY></HTML>`)
+		assert.True(t, ok)
+		assert.Equal(t, "Hi Chuck, we're open 9-5.", reply)
+
+		reply, ok = a.Respond(ctx, "HelpBot", "Chuck", "price of pizza")
+		assert.True(t, ok)
+		assert.Equal(t, "pizza costs $5.", reply)
+
+		_, ok = a.Respond(ctx, "HelpBot", "Chuck", "something else")
+		assert.False(t, ok)
+
+		// auto-responders don't answer each other
+		_, ok = a.Respond(ctx, "HelpBot", "HelpBot", "hours?")
+		assert.False(t, ok)
+	})
+
+	t.Run("receive IM", func(t *testing.T) {
+		sm := NewInMemorySessionManager(slog.Default())
+		sess, err := sm.AddSession(ctx, "Chuck")
+		require.NoError(t, err)
+		sess.SetSignonComplete()
+
+		a.ReceiveIM(ctx, "HelpBot", sess, "price of <b>tea</b> & cake")
+		select {
+		case msg := <-sess.ReceiveMessage():
+			im := msg.Body.(wire.SNAC_0x04_0x07_ICBMChannelMsgToClient)
+			assert.Equal(t, wire.ScreenName("HelpBot"), im.ScreenName)
+			data, ok := im.Bytes(wire.ICBMTLVAOLIMData)
+			require.True(t, ok)
+			text, err := wire.UnmarshalICBMMessageText(data)
+			require.NoError(t, err)
+			assert.Equal(t, "tea costs $5.", text)
+		case <-time.After(time.Second):
+			t.Fatal("no reply received")
+		}
+	})
+
+	t.Run("list and delete", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/admin/auto-responder", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var rules []AutoResponderRule
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&rules))
+		assert.Len(t, rules, 2)
+
+		for _, id := range []string{"1", "2"} {
+			rec = httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodDelete, "/admin/auto-responder?id="+id, nil))
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+		}
+		assert.False(t, a.Answers(NewIdentScreenName("HelpBot")))
+
+		rec = httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodDelete, "/admin/auto-responder?id=1", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestSQLiteUserStore_AutoResponderRuleFollowsAccount(t *testing.T) {
+	t.Parallel()
+
+	f, err := NewSQLiteUserStore(newTestDBPath(t))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	bot, err := NewStubUser("HelpBot")
+	require.NoError(t, err)
+	require.NoError(t, f.InsertUser(ctx, bot))
+	require.NoError(t, f.SetBotStatus(ctx, true, bot.IdentScreenName))
+
+	t.Run("reserved screen name without an account is refused", func(t *testing.T) {
+		_, err := f.ReserveScreenName(ctx, NewIdentScreenName("Staff"))
+		require.NoError(t, err)
+		_, err = f.InsertAutoResponderRule(ctx, AutoResponderRule{ScreenName: "Staff", Pattern: "hi", Reply: "hello"})
+		assert.ErrorIs(t, err, ErrAutoResponderRuleInvalid)
+	})
+
+	_, err = f.InsertAutoResponderRule(ctx, AutoResponderRule{ScreenName: "HelpBot", Pattern: "hi", Reply: "hello"})
+	require.NoError(t, err)
+
+	t.Run("rules follow a rename", func(t *testing.T) {
+		require.NoError(t, f.RenameScreenName(ctx, bot.IdentScreenName, "SupportBot"))
+		rules, err := f.AutoResponderRules(ctx)
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+		assert.Equal(t, DisplayScreenName("supportbot"), rules[0].ScreenName)
+	})
+
+	t.Run("rules are deleted with the account", func(t *testing.T) {
+		require.NoError(t, f.DeleteUser(ctx, NewIdentScreenName("SupportBot")))
+		rules, err := f.AutoResponderRules(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, rules)
+	})
+}
@@ -0,0 +1,121 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// BARTVerifyInterval is how often BARTVerifier samples stored items.
+	BARTVerifyInterval = 15 * time.Minute
+	// DefaultBARTVerifySampleSize is the number of items checked per run
+	// when no sample size is configured.
+	DefaultBARTVerifySampleSize = 100
+)
+
+// BARTItemSampler samples and deletes stored BART items.
+type BARTItemSampler interface {
+	SampleBARTItems(ctx context.Context, n int) ([]BARTBlob, error)
+	DeleteBARTItem(ctx context.Context, hash []byte) error
+}
+
+// BARTVerifier periodically recomputes the MD5 hash of a random sample of
+// stored BART items and flags items whose body no longer matches the hash
+// they are stored under, such as rows left behind by partial writes.
+// Corrupted items are removed when removal is enabled, so that clients
+// re-upload them on their next request.
+type BARTVerifier struct {
+	store      BARTItemSampler
+	sampleSize int
+	remove     bool
+	logger     *slog.Logger
+	corrupted  atomic.Int64
+}
+
+// NewBARTVerifier creates a new instance of BARTVerifier. A sampleSize of
+// zero uses DefaultBARTVerifySampleSize.
+func NewBARTVerifier(store BARTItemSampler, sampleSize int, remove bool, logger *slog.Logger) *BARTVerifier {
+	if sampleSize <= 0 {
+		sampleSize = DefaultBARTVerifySampleSize
+	}
+	return &BARTVerifier{
+		store:      store,
+		sampleSize: sampleSize,
+		remove:     remove,
+		logger:     logger,
+	}
+}
+
+// Run verifies a sample of BART items every BARTVerifyInterval until ctx
+// is done.
+func (v *BARTVerifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(BARTVerifyInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := v.VerifyOnce(ctx); err != nil {
+			v.logger.ErrorContext(ctx, "unable to verify BART items", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Schedule adds the verification to s as the "bart_verify" job, to run
+// every BARTVerifyInterval. It is an alternative to Run.
+func (v *BARTVerifier) Schedule(s *Scheduler) error {
+	return s.Add("bart_verify", BARTVerifyInterval, BARTVerifyInterval/10, func(ctx context.Context) error {
+		_, err := v.VerifyOnce(ctx)
+		return err
+	})
+}
+
+// VerifyOnce checks one sample of BART items and returns the hashes of the
+// corrupted items it found.
+func (v *BARTVerifier) VerifyOnce(ctx context.Context) ([][]byte, error) {
+	items, err := v.store.SampleBARTItems(ctx, v.sampleSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var corrupted [][]byte
+	for _, item := range items {
+		sum := md5.Sum(item.Body)
+		if bytes.Equal(sum[:], item.Hash) {
+			continue
+		}
+
+		corrupted = append(corrupted, item.Hash)
+		v.corrupted.Add(1)
+		v.logger.WarnContext(ctx, "found corrupted BART item",
+			"hash", hex.EncodeToString(item.Hash),
+			"computed_hash", hex.EncodeToString(sum[:]),
+			"type", item.Type,
+			"size", len(item.Body))
+
+		if !v.remove {
+			continue
+		}
+		if err := v.store.DeleteBARTItem(ctx, item.Hash); err != nil && !errors.Is(err, ErrBARTItemNotFound) {
+			return corrupted, err
+		}
+	}
+
+	return corrupted, nil
+}
+
+// CorruptedCount returns the number of corrupted items found since the
+// verifier was created.
+func (v *BARTVerifier) CorruptedCount() int64 {
+	return v.corrupted.Load()
+}
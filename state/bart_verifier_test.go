@@ -0,0 +1,97 @@
+package state
+
+import (
+	"context"
+	"crypto/md5"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestBARTVerifier_VerifyOnce(t *testing.T) {
+	t.Parallel()
+
+	insertItems := func(t *testing.T, f *SQLiteUserStore) (good []byte, bad []byte) {
+		goodBody := []byte("a perfectly fine buddy icon")
+		goodSum := md5.Sum(goodBody)
+		assert.NoError(t, f.InsertBARTItem(context.Background(), goodSum[:], goodBody, wire.BARTTypesBuddyIcon))
+
+		// simulate a partial write that truncated the body
+		badBody := []byte("a buddy icon that got cut off")
+		badSum := md5.Sum(badBody)
+		assert.NoError(t, f.InsertBARTItem(context.Background(), badSum[:], badBody[:10], wire.BARTTypesBuddyIcon))
+
+		return goodSum[:], badSum[:]
+	}
+
+	t.Run("flag and remove corrupted items", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		f, err := NewSQLiteUserStore(testFile)
+		assert.NoError(t, err)
+		good, bad := insertItems(t, f)
+
+		v := NewBARTVerifier(f, 0, true, slog.Default())
+		corrupted, err := v.VerifyOnce(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, [][]byte{bad}, corrupted)
+		assert.Equal(t, int64(1), v.CorruptedCount())
+
+		body, err := f.BARTItem(context.Background(), bad)
+		assert.NoError(t, err)
+		assert.Nil(t, body)
+
+		body, err = f.BARTItem(context.Background(), good)
+		assert.NoError(t, err)
+		assert.NotNil(t, body)
+
+		corrupted, err = v.VerifyOnce(context.Background())
+		assert.NoError(t, err)
+		assert.Empty(t, corrupted)
+		assert.Equal(t, int64(1), v.CorruptedCount())
+	})
+
+	t.Run("flag without removing", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		f, err := NewSQLiteUserStore(testFile)
+		assert.NoError(t, err)
+		_, bad := insertItems(t, f)
+
+		v := NewBARTVerifier(f, 10, false, slog.Default())
+		corrupted, err := v.VerifyOnce(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, [][]byte{bad}, corrupted)
+
+		body, err := f.BARTItem(context.Background(), bad)
+		assert.NoError(t, err)
+		assert.NotNil(t, body)
+	})
+}
+
+func TestSQLiteUserStore_SampleBARTItems(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	for i := range 5 {
+		body := []byte{byte(i)}
+		sum := md5.Sum(body)
+		assert.NoError(t, f.InsertBARTItem(context.Background(), sum[:], body, wire.BARTTypesBuddyIcon))
+	}
+
+	items, err := f.SampleBARTItems(context.Background(), 3)
+	assert.NoError(t, err)
+	assert.Len(t, items, 3)
+	for _, item := range items {
+		sum := md5.Sum(item.Body)
+		assert.Equal(t, sum[:], item.Hash)
+		assert.Equal(t, wire.BARTTypesBuddyIcon, item.Type)
+	}
+}
@@ -0,0 +1,256 @@
+package state
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+	"modernc.org/sqlite"
+	lib "modernc.org/sqlite/lib"
+)
+
+// BlockStatsXMLPlugin is the plugin ID of the ICQ XML request that asks for
+// the user's BlockedMessageStats. It is a go-icq extension; the request is
+// <Query><PluginID>blockStats</PluginID></Query>.
+const BlockStatsXMLPlugin = "blockStats"
+
+// BlockedMessageStat counts the messages from a sender that didn't reach a
+// user because the user's privacy settings block the sender.
+type BlockedMessageStat struct {
+	// ScreenName is the user whose privacy settings blocked the messages.
+	ScreenName IdentScreenName
+	// Sender is the user who sent the messages.
+	Sender IdentScreenName
+	// Count is the number of messages blocked.
+	Count int64
+	// LastBlocked is when the last message was blocked.
+	LastBlocked time.Time
+}
+
+// RecordBlockedMessage counts a message from sender that screenName's
+// privacy settings blocked. It returns ErrNoUser if screenName doesn't
+// exist.
+func (us SQLiteUserStore) RecordBlockedMessage(ctx context.Context, screenName, sender IdentScreenName, now time.Time) error {
+	q := `
+		INSERT INTO blockedMessage (screenName, sender, count, lastBlocked) VALUES (?, ?, 1, ?)
+		ON CONFLICT (screenName, sender) DO UPDATE SET count       = count + 1,
+		                                               lastBlocked = excluded.lastBlocked
+	`
+	_, err := us.db.ExecContext(ctx, q, screenName.String(), sender.String(), now.Unix())
+	if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == lib.SQLITE_CONSTRAINT_FOREIGNKEY {
+		return ErrNoUser
+	} else if err != nil {
+		return fmt.Errorf("RecordBlockedMessage: %w", err)
+	}
+	return nil
+}
+
+// BlockedMessagesTo returns the messages that screenName's privacy settings
+// blocked, per sender, most blocked first.
+func (us SQLiteUserStore) BlockedMessagesTo(ctx context.Context, screenName IdentScreenName) ([]BlockedMessageStat, error) {
+	stats, err := us.blockedMessages(ctx, `screenName = ?`, screenName)
+	if err != nil {
+		return nil, fmt.Errorf("BlockedMessagesTo: %w", err)
+	}
+	return stats, nil
+}
+
+// BlockedMessagesFrom returns the messages from sender that the privacy
+// settings of other users blocked, per user, most blocked first.
+func (us SQLiteUserStore) BlockedMessagesFrom(ctx context.Context, sender IdentScreenName) ([]BlockedMessageStat, error) {
+	stats, err := us.blockedMessages(ctx, `sender = ?`, sender)
+	if err != nil {
+		return nil, fmt.Errorf("BlockedMessagesFrom: %w", err)
+	}
+	return stats, nil
+}
+
+func (us SQLiteUserStore) blockedMessages(ctx context.Context, where string, screenName IdentScreenName) ([]BlockedMessageStat, error) {
+	q := `
+		SELECT screenName, sender, count, lastBlocked
+		FROM blockedMessage
+		WHERE ` + where + `
+		ORDER BY count DESC, sender, screenName
+	`
+	rows, err := us.db.QueryContext(ctx, q, screenName.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []BlockedMessageStat
+	for rows.Next() {
+		var screenName, sender string
+		var lastBlocked int64
+		stat := BlockedMessageStat{}
+		if err := rows.Scan(&screenName, &sender, &stat.Count, &lastBlocked); err != nil {
+			return nil, err
+		}
+		stat.ScreenName = NewIdentScreenName(screenName)
+		stat.Sender = NewIdentScreenName(sender)
+		stat.LastBlocked = time.Unix(lastBlocked, 0).UTC()
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+// BlockStatsStore persists the counts kept by BlockStats.
+type BlockStatsStore interface {
+	RecordBlockedMessage(ctx context.Context, screenName, sender IdentScreenName, now time.Time) error
+	BlockedMessagesTo(ctx context.Context, screenName IdentScreenName) ([]BlockedMessageStat, error)
+	BlockedMessagesFrom(ctx context.Context, sender IdentScreenName) ([]BlockedMessageStat, error)
+}
+
+// BlockStats counts the messages that don't arrive because the recipient's
+// deny list or permit/deny mode blocks the sender, so that users can find
+// out why they miss messages, for example after setting their permit/deny
+// mode to allow only buddies. Stats are only shown to the user who blocks,
+// never to the blocked sender, except through the admin API.
+type BlockStats struct {
+	store  BlockStatsStore
+	logger *slog.Logger
+	nowFn  func() time.Time
+}
+
+// NewBlockStats creates a new instance of BlockStats.
+func NewBlockStats(store BlockStatsStore, logger *slog.Logger) *BlockStats {
+	return &BlockStats{
+		store:  store,
+		logger: logger,
+		nowFn:  time.Now,
+	}
+}
+
+// MessageBlocked counts a message from sender that was not delivered. rel
+// is the sender's relationship with the recipient. The message is only
+// counted if the recipient blocks the sender; messages that the sender's
+// own settings stopped are not.
+func (b *BlockStats) MessageBlocked(ctx context.Context, sender IdentScreenName, rel Relationship) {
+	if !rel.BlocksYou {
+		return
+	}
+	if err := b.store.RecordBlockedMessage(ctx, rel.User, sender, b.nowFn()); err != nil {
+		b.logger.ErrorContext(ctx, "unable to record blocked message", "err", err)
+	}
+}
+
+// IsBlockStatsXMLRequest indicates whether the XML of a
+// SNAC(0x15,0x02)/0x07D0/0x0898 DBQueryMetaReqXMLReq asks for block stats.
+func IsBlockStatsXMLRequest(req string) bool {
+	q := struct {
+		PluginID string `xml:"PluginID"`
+	}{}
+	if err := xml.Unmarshal([]byte(req), &q); err != nil {
+		return false
+	}
+	return strings.EqualFold(q.PluginID, BlockStatsXMLPlugin)
+}
+
+// blockStatsXML is the XML document that lists a user's block stats.
+type blockStatsXML struct {
+	XMLName xml.Name           `xml:"BlockStats"`
+	Senders []blockedSenderXML `xml:"Sender"`
+}
+
+type blockedSenderXML struct {
+	ScreenName  string `xml:"ScreenName"`
+	Count       int64  `xml:"Count"`
+	LastBlocked int64  `xml:"LastBlocked"`
+}
+
+// XMLReply builds the reply to the block stats XML request of user
+// screenName. The XML lists the senders that the user blocked and how many
+// of their messages were blocked, with the time of the last one as a Unix
+// timestamp.
+func (b *BlockStats) XMLReply(ctx context.Context, screenName IdentScreenName, meta wire.ICQMetadata) (wire.ICQ_0x07DA_0x08A2_DBQueryMetaReplyXMLData, error) {
+	stats, err := b.store.BlockedMessagesTo(ctx, screenName)
+	if err != nil {
+		return wire.ICQ_0x07DA_0x08A2_DBQueryMetaReplyXMLData{}, err
+	}
+
+	doc := blockStatsXML{}
+	for _, stat := range stats {
+		doc.Senders = append(doc.Senders, blockedSenderXML{
+			ScreenName:  stat.Sender.String(),
+			Count:       stat.Count,
+			LastBlocked: stat.LastBlocked.Unix(),
+		})
+	}
+	out, err := xml.Marshal(doc)
+	if err != nil {
+		return wire.ICQ_0x07DA_0x08A2_DBQueryMetaReplyXMLData{}, err
+	}
+
+	return wire.ICQ_0x07DA_0x08A2_DBQueryMetaReplyXMLData{
+		ICQMetadata: wire.ICQMetadata{
+			UIN:     meta.UIN,
+			Seq:     meta.Seq,
+			ReqType: wire.ICQDBQueryMetaReply,
+		},
+		ReqSubType: wire.ICQDBQueryMetaReplyXMLData,
+		Success:    wire.ICQStatusCodeOK,
+		XML:        string(out),
+	}, nil
+}
+
+// blockStatJSON is the admin API view of a BlockedMessageStat.
+type blockStatJSON struct {
+	ScreenName  string    `json:"screen_name"`
+	Sender      string    `json:"sender"`
+	Count       int64     `json:"count"`
+	LastBlocked time.Time `json:"last_blocked"`
+}
+
+func toBlockStatsJSON(stats []BlockedMessageStat) []blockStatJSON {
+	out := make([]blockStatJSON, 0, len(stats))
+	for _, stat := range stats {
+		out = append(out, blockStatJSON{
+			ScreenName:  stat.ScreenName.String(),
+			Sender:      stat.Sender.String(),
+			Count:       stat.Count,
+			LastBlocked: stat.LastBlocked,
+		})
+	}
+	return out
+}
+
+// Handler serves the block stats of the user named by the "screen_name"
+// query parameter as JSON, for the admin API. The response lists the
+// messages the user blocked ("blocked_to") and the messages of the user that
+// others blocked ("blocked_from"). It responds 400 if the parameter is
+// missing.
+func (b *BlockStats) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		screenName := NewIdentScreenName(r.URL.Query().Get("screen_name"))
+		if screenName.String() == "" {
+			http.Error(w, "Missing screen_name.", http.StatusBadRequest)
+			return
+		}
+
+		to, err := b.store.BlockedMessagesTo(r.Context(), screenName)
+		if err != nil {
+			b.logger.ErrorContext(r.Context(), "unable to read block stats", "err", err)
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+			return
+		}
+		from, err := b.store.BlockedMessagesFrom(r.Context(), screenName)
+		if err != nil {
+			b.logger.ErrorContext(r.Context(), "unable to read block stats", "err", err)
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+			return
+		}
+
+		writeHealthJSON(w, http.StatusOK, struct {
+			BlockedTo   []blockStatJSON `json:"blocked_to"`
+			BlockedFrom []blockStatJSON `json:"blocked_from"`
+		}{
+			BlockedTo:   toBlockStatsJSON(to),
+			BlockedFrom: toBlockStatsJSON(from),
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestBlockStats(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, sn := range []DisplayScreenName{"Blocker", "Pest", "Spammer"} {
+		user, err := NewStubUser(sn)
+		require.NoError(t, err)
+		require.NoError(t, f.InsertUser(ctx, user))
+	}
+	blocker := NewIdentScreenName("Blocker")
+	pest := NewIdentScreenName("Pest")
+	spammer := NewIdentScreenName("Spammer")
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	stats := NewBlockStats(f, slog.Default())
+	stats.nowFn = func() time.Time { return now }
+
+	stats.MessageBlocked(ctx, pest, Relationship{User: blocker, BlocksYou: true})
+	for range 3 {
+		stats.MessageBlocked(ctx, spammer, Relationship{User: blocker, BlocksYou: true})
+	}
+	now = now.Add(time.Hour)
+	stats.MessageBlocked(ctx, pest, Relationship{User: blocker, BlocksYou: true})
+	// messages stopped by the sender's own settings are not counted
+	stats.MessageBlocked(ctx, blocker, Relationship{User: pest, YouBlock: true})
+
+	to, err := f.BlockedMessagesTo(ctx, blocker)
+	require.NoError(t, err)
+	assert.Equal(t, []BlockedMessageStat{
+		{ScreenName: blocker, Sender: spammer, Count: 3, LastBlocked: now.Add(-time.Hour)},
+		{ScreenName: blocker, Sender: pest, Count: 2, LastBlocked: now},
+	}, to)
+
+	from, err := f.BlockedMessagesFrom(ctx, pest)
+	require.NoError(t, err)
+	assert.Equal(t, []BlockedMessageStat{
+		{ScreenName: blocker, Sender: pest, Count: 2, LastBlocked: now},
+	}, from)
+
+	to, err = f.BlockedMessagesTo(ctx, pest)
+	require.NoError(t, err)
+	assert.Empty(t, to)
+
+	err = f.RecordBlockedMessage(ctx, NewIdentScreenName("nobody"), pest, now)
+	assert.ErrorIs(t, err, ErrNoUser)
+
+	t.Run("XML reply", func(t *testing.T) {
+		assert.True(t, IsBlockStatsXMLRequest("<Query><PluginID>blockStats</PluginID></Query>"))
+		assert.False(t, IsBlockStatsXMLRequest("<Query><PluginID>srvMng</PluginID></Query>"))
+		assert.False(t, IsBlockStatsXMLRequest("not xml"))
+
+		reply, err := stats.XMLReply(ctx, blocker, wire.ICQMetadata{UIN: 100003, Seq: 7, ReqType: wire.ICQDBQueryMetaReq})
+		require.NoError(t, err)
+		assert.Equal(t, wire.ICQMetadata{UIN: 100003, Seq: 7, ReqType: wire.ICQDBQueryMetaReply}, reply.ICQMetadata)
+		assert.Equal(t, wire.ICQDBQueryMetaReplyXMLData, reply.ReqSubType)
+		assert.Equal(t, wire.ICQStatusCodeOK, reply.Success)
+		assert.Equal(t, "<BlockStats>"+
+			"<Sender><ScreenName>spammer</ScreenName><Count>3</Count><LastBlocked>1709294400</LastBlocked></Sender>"+
+			"<Sender><ScreenName>pest</ScreenName><Count>2</Count><LastBlocked>1709298000</LastBlocked></Sender>"+
+			"</BlockStats>", reply.XML)
+	})
+
+	t.Run("admin API", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		stats.Handler()(rec, httptest.NewRequest(http.MethodGet, "/admin/block-stats?screen_name=Pest", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		body := struct {
+			BlockedTo   []blockStatJSON `json:"blocked_to"`
+			BlockedFrom []blockStatJSON `json:"blocked_from"`
+		}{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+		assert.Empty(t, body.BlockedTo)
+		assert.Equal(t, []blockStatJSON{
+			{ScreenName: "blocker", Sender: "pest", Count: 2, LastBlocked: now},
+		}, body.BlockedFrom)
+
+		rec = httptest.NewRecorder()
+		stats.Handler()(rec, httptest.NewRequest(http.MethodGet, "/admin/block-stats", nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
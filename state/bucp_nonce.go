@@ -0,0 +1,164 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultBUCPNonceTTL is how long a BUCP challenge may be answered when
+	// no TTL is given to NewBUCPNonceTracker.
+	DefaultBUCPNonceTTL = time.Minute
+	// DefaultBUCPNonceLimit is the most outstanding challenges a
+	// BUCPNonceTracker holds when no limit is given to NewBUCPNonceTracker.
+	DefaultBUCPNonceLimit = 10_000
+	// BUCPNonceSweepInterval is how often BUCPNonceTracker drops expired
+	// challenges.
+	BUCPNonceSweepInterval = 30 * time.Second
+	// bucpNonceLen is the length of the nonces issued by BUCPNonceTracker.
+	bucpNonceLen = 16
+)
+
+var (
+	// ErrBUCPReplay indicates that a BUCP login response doesn't answer an
+	// outstanding challenge issued to its screen name, such as when a
+	// captured response is replayed.
+	ErrBUCPReplay = errors.New("BUCP login response replayed")
+	// ErrBUCPChallengeExpired indicates that a BUCP login response answers
+	// a challenge that is past its TTL.
+	ErrBUCPChallengeExpired = errors.New("BUCP challenge expired")
+)
+
+// BUCPNonceStats counts the challenges handled by a BUCPNonceTracker since
+// it was created.
+type BUCPNonceStats struct {
+	// Issued is the number of challenges issued.
+	Issued uint64 `json:"issued"`
+	// Accepted is the number of login responses that answered an
+	// outstanding challenge.
+	Accepted uint64 `json:"accepted"`
+	// Replays is the number of login responses rejected because they
+	// didn't answer an outstanding challenge.
+	Replays uint64 `json:"replays"`
+	// Expired is the number of challenges that expired before they were
+	// answered.
+	Expired uint64 `json:"expired"`
+	// Evicted is the number of challenges dropped before they expired to
+	// make room for new ones.
+	Evicted uint64 `json:"evicted"`
+	// Outstanding is the number of challenges currently held.
+	Outstanding int `json:"outstanding"`
+}
+
+// BUCPNonceTracker protects BUCP logins against replayed MD5 responses.
+// The response a client sends depends only on the user's password and the
+// account's fixed AuthKey, so it's the same for every login and can't be
+// bound to a challenge by the hash alone. Instead, the auth server issues a
+// single-use nonce for the screen name with every challenge, keeps it with
+// the connection, and redeems it when the login response arrives. A
+// response is then rejected if it's sent on a connection without an
+// outstanding challenge, after its challenge was answered or after the
+// challenge expired. The nonces are held in a CookieStore, which drops
+// expired nonces in Sweep and never holds more than its limit.
+//
+// This doesn't stop a captured response from being sent on a new
+// connection that asks for its own challenge, since that connection is sent
+// the same AuthKey. For the same reason, the weakMD5Pass and strongMD5Pass
+// columns of the users table can be used to log in as the user; they must
+// be protected like plaintext passwords. A BUCPNonceTracker is safe for
+// concurrent use by multiple goroutines.
+type BUCPNonceTracker struct {
+	nonces   *CookieStore
+	accepted atomic.Uint64
+	replays  atomic.Uint64
+}
+
+// NewBUCPNonceTracker creates a new instance of BUCPNonceTracker. A ttl of
+// 0 uses DefaultBUCPNonceTTL and a limit of 0 uses DefaultBUCPNonceLimit.
+func NewBUCPNonceTracker(ttl time.Duration, limit int) *BUCPNonceTracker {
+	if ttl == 0 {
+		ttl = DefaultBUCPNonceTTL
+	}
+	if limit == 0 {
+		limit = DefaultBUCPNonceLimit
+	}
+	return &BUCPNonceTracker{
+		nonces: newCookieStore(ttl, limit, bucpNonceLen),
+	}
+}
+
+// Issue records a challenge sent to screenName and returns its nonce, which
+// the auth server keeps with the connection until the login response
+// arrives. The nonce is never sent to the client.
+func (t *BUCPNonceTracker) Issue(screenName IdentScreenName) ([]byte, error) {
+	nonce, err := t.nonces.Issue([]byte(screenName.String()))
+	if err != nil {
+		return nil, fmt.Errorf("cannot issue BUCP nonce: %w", err)
+	}
+	return nonce, nil
+}
+
+// Redeem consumes the challenge identified by nonce before a login response
+// from screenName is checked. A challenge can be answered only once. It
+// returns ErrBUCPReplay if nonce wasn't issued to screenName, was already
+// redeemed or was evicted, and ErrBUCPChallengeExpired if it's past its
+// TTL.
+func (t *BUCPNonceTracker) Redeem(screenName IdentScreenName, nonce []byte) error {
+	issuedTo, err := t.nonces.Crack(nonce)
+	switch {
+	case errors.Is(err, ErrCookieExpired):
+		return fmt.Errorf("%w: %s", ErrBUCPChallengeExpired, screenName)
+	case err != nil || string(issuedTo) != screenName.String():
+		t.replays.Add(1)
+		return fmt.Errorf("%w: %s", ErrBUCPReplay, screenName)
+	}
+	t.accepted.Add(1)
+	return nil
+}
+
+// Sweep drops the challenges that expired before they were answered and
+// returns how many it dropped.
+func (t *BUCPNonceTracker) Sweep() int {
+	return t.nonces.Sweep()
+}
+
+// Schedule adds the sweep to s as the "bucp_nonce_sweep" job, to run every
+// BUCPNonceSweepInterval.
+func (t *BUCPNonceTracker) Schedule(s *Scheduler) error {
+	return s.Add("bucp_nonce_sweep", BUCPNonceSweepInterval, BUCPNonceSweepInterval/10, func(ctx context.Context) error {
+		t.Sweep()
+		return nil
+	})
+}
+
+// Stats returns the number of challenges handled by the tracker.
+func (t *BUCPNonceTracker) Stats() BUCPNonceStats {
+	nonces := t.nonces.Stats()
+	return BUCPNonceStats{
+		Issued:      nonces.Issued,
+		Accepted:    t.accepted.Load(),
+		Replays:     t.replays.Load(),
+		Expired:     nonces.Expired,
+		Evicted:     nonces.Evicted,
+		Outstanding: nonces.Outstanding,
+	}
+}
+
+// Handler serves the challenge counters in the Prometheus text exposition
+// format, for scraping from the management API's /metrics endpoint.
+func (t *BUCPNonceTracker) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := t.Stats()
+		m := newMetricsWriter(w)
+		m.counter("icq_bucp_challenges_issued_total", "BUCP login challenges issued.", stats.Issued)
+		m.counter("icq_bucp_challenges_accepted_total", "BUCP login responses that answered an outstanding challenge.", stats.Accepted)
+		m.counter("icq_bucp_replays_rejected_total", "BUCP login responses rejected as replays.", stats.Replays)
+		m.counter("icq_bucp_challenges_expired_total", "BUCP login challenges that expired before they were answered.", stats.Expired)
+		m.counter("icq_bucp_challenges_evicted_total", "BUCP login challenges dropped to stay within the challenge limit.", stats.Evicted)
+		m.gauge("icq_bucp_challenges_outstanding", "BUCP login challenges issued but not yet answered or expired.", stats.Outstanding)
+	}
+}
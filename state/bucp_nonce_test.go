@@ -0,0 +1,123 @@
+package state
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBUCPNonceTracker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	alice := NewIdentScreenName("Alice")
+	bob := NewIdentScreenName("Bob")
+	newTracker := func(limit int) *BUCPNonceTracker {
+		tr := NewBUCPNonceTracker(time.Minute, limit)
+		tr.nonces.nowFn = func() time.Time { return now }
+		return tr
+	}
+
+	t.Run("challenge is answered once", func(t *testing.T) {
+		tr := newTracker(0)
+		nonce, err := tr.Issue(alice)
+		require.NoError(t, err)
+		assert.Len(t, nonce, bucpNonceLen)
+
+		require.NoError(t, tr.Redeem(alice, nonce))
+
+		// the captured response is replayed
+		err = tr.Redeem(alice, nonce)
+		assert.ErrorIs(t, err, ErrBUCPReplay)
+		assert.Equal(t, ErrorCode(ErrCookieInvalid), ErrorCode(err))
+		// the response is sent without a challenge
+		assert.ErrorIs(t, tr.Redeem(alice, nil), ErrBUCPReplay)
+
+		assert.Equal(t, BUCPNonceStats{Issued: 1, Accepted: 1, Replays: 2}, tr.Stats())
+	})
+
+	t.Run("challenge is bound to its screen name", func(t *testing.T) {
+		tr := newTracker(0)
+		nonce, err := tr.Issue(alice)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, tr.Redeem(bob, nonce), ErrBUCPReplay)
+		// the nonce was used up by the attempt
+		assert.ErrorIs(t, tr.Redeem(alice, nonce), ErrBUCPReplay)
+	})
+
+	t.Run("expired challenge can't be answered", func(t *testing.T) {
+		tr := newTracker(0)
+		nonce, err := tr.Issue(alice)
+		require.NoError(t, err)
+
+		tr.nonces.nowFn = func() time.Time { return now.Add(time.Minute) }
+		assert.ErrorIs(t, tr.Redeem(alice, nonce), ErrBUCPChallengeExpired)
+		assert.Equal(t, BUCPNonceStats{Issued: 1, Expired: 1}, tr.Stats())
+	})
+
+	t.Run("sweep drops expired challenges", func(t *testing.T) {
+		tr := newTracker(0)
+		_, err := tr.Issue(alice)
+		require.NoError(t, err)
+		tr.nonces.nowFn = func() time.Time { return now.Add(30 * time.Second) }
+		fresh, err := tr.Issue(bob)
+		require.NoError(t, err)
+
+		tr.nonces.nowFn = func() time.Time { return now.Add(time.Minute) }
+		assert.Equal(t, 1, tr.Sweep())
+		assert.Equal(t, 0, tr.Sweep())
+		assert.Equal(t, BUCPNonceStats{Issued: 2, Expired: 1, Outstanding: 1}, tr.Stats())
+		require.NoError(t, tr.Redeem(bob, fresh))
+	})
+
+	t.Run("oldest challenge is evicted at the limit", func(t *testing.T) {
+		tr := newTracker(2)
+		var nonces [][]byte
+		for range 3 {
+			nonce, err := tr.Issue(alice)
+			require.NoError(t, err)
+			nonces = append(nonces, nonce)
+		}
+		assert.Equal(t, BUCPNonceStats{Issued: 3, Evicted: 1, Outstanding: 2}, tr.Stats())
+
+		assert.ErrorIs(t, tr.Redeem(alice, nonces[0]), ErrBUCPReplay)
+		require.NoError(t, tr.Redeem(alice, nonces[2]))
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		tr := newTracker(0)
+		nonce, err := tr.Issue(alice)
+		require.NoError(t, err)
+		_, err = tr.Issue(bob)
+		require.NoError(t, err)
+		require.NoError(t, tr.Redeem(alice, nonce))
+		assert.ErrorIs(t, tr.Redeem(alice, nonce), ErrBUCPReplay)
+
+		rec := httptest.NewRecorder()
+		tr.Handler()(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+		assert.Equal(t, `# HELP icq_bucp_challenges_issued_total BUCP login challenges issued.
+# TYPE icq_bucp_challenges_issued_total counter
+icq_bucp_challenges_issued_total 2
+# HELP icq_bucp_challenges_accepted_total BUCP login responses that answered an outstanding challenge.
+# TYPE icq_bucp_challenges_accepted_total counter
+icq_bucp_challenges_accepted_total 1
+# HELP icq_bucp_replays_rejected_total BUCP login responses rejected as replays.
+# TYPE icq_bucp_replays_rejected_total counter
+icq_bucp_replays_rejected_total 1
+# HELP icq_bucp_challenges_expired_total BUCP login challenges that expired before they were answered.
+# TYPE icq_bucp_challenges_expired_total counter
+icq_bucp_challenges_expired_total 0
+# HELP icq_bucp_challenges_evicted_total BUCP login challenges dropped to stay within the challenge limit.
+# TYPE icq_bucp_challenges_evicted_total counter
+icq_bucp_challenges_evicted_total 0
+# HELP icq_bucp_challenges_outstanding BUCP login challenges issued but not yet answered or expired.
+# TYPE icq_bucp_challenges_outstanding gauge
+icq_bucp_challenges_outstanding 1
+`, rec.Body.String())
+	})
+}
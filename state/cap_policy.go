@@ -0,0 +1,115 @@
+package state
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// capOverride is a set of capabilities to remove from and add to the
+// capabilities a client advertises.
+type capOverride struct {
+	strip  [][16]byte
+	inject [][16]byte
+}
+
+func (o capOverride) apply(caps [][16]byte) [][16]byte {
+	caps = slices.DeleteFunc(caps, func(c [16]byte) bool {
+		return slices.Contains(o.strip, c)
+	})
+	for _, c := range o.inject {
+		if !slices.Contains(caps, c) {
+			caps = append(caps, c)
+		}
+	}
+	return caps
+}
+
+// CapPolicy strips capabilities from, or injects capabilities into, the
+// capabilities that users advertise to others in their user info. It lets
+// the operator disable features such as file transfer for whole classes of
+// users or for specific accounts. A user's class override (aim or icq) is
+// applied first, then the guest override for guest sessions, then the
+// user's own override, so account overrides win over class overrides.
+// The zero value leaves capabilities untouched.
+type CapPolicy struct {
+	aim   capOverride
+	icq   capOverride
+	guest capOverride
+	users map[IdentScreenName]capOverride
+}
+
+// ParseCapPolicy builds a CapPolicy from targets mapped to capability
+// changes. A target is "aim", "icq", "guest" or a screen name prefixed with
+// "@". A change is a capability name from wire.CapNames, prefixed with "-"
+// to strip the capability.
+func ParseCapPolicy(cfg map[string][]string) (*CapPolicy, error) {
+	policy := &CapPolicy{users: make(map[IdentScreenName]capOverride)}
+	for target, changes := range cfg {
+		override := capOverride{}
+		for _, change := range changes {
+			name, strip := strings.CutPrefix(change, "-")
+			c, ok := wire.CapNames[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown capability %q", name)
+			}
+			if strip {
+				override.strip = append(override.strip, c)
+			} else {
+				override.inject = append(override.inject, c)
+			}
+		}
+
+		switch {
+		case target == "aim":
+			policy.aim = override
+		case target == "icq":
+			policy.icq = override
+		case target == "guest":
+			policy.guest = override
+		case strings.HasPrefix(target, "@") && len(target) > 1:
+			policy.users[NewIdentScreenName(target[1:])] = override
+		default:
+			return nil, fmt.Errorf("unknown capability override target %q", target)
+		}
+	}
+	return policy, nil
+}
+
+// Apply returns the capabilities the user screenName advertises to others
+// when their client reports caps. caps is not modified.
+func (p *CapPolicy) Apply(screenName IdentScreenName, guest, icq bool, caps [][16]byte) [][16]byte {
+	if p == nil {
+		return caps
+	}
+
+	caps = slices.Clone(caps)
+	if icq {
+		caps = p.icq.apply(caps)
+	} else {
+		caps = p.aim.apply(caps)
+	}
+	if guest {
+		caps = p.guest.apply(caps)
+	}
+	if override, ok := p.users[screenName]; ok {
+		caps = override.apply(caps)
+	}
+	return caps
+}
+
+// SetCapPolicy sets the capability policy applied to the user info of
+// sessions added from now on. A nil policy leaves capabilities untouched.
+func (s *InMemorySessionManager) SetCapPolicy(policy *CapPolicy) {
+	s.capPolicy.Store(policy)
+}
+
+// SetCapPolicy sets the policy applied to the capabilities in the session's
+// user info. Caps still returns the capabilities the client reported.
+func (s *Session) SetCapPolicy(policy *CapPolicy) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.capPolicy = policy
+}
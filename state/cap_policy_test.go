@@ -0,0 +1,139 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestParseCapPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     map[string][]string
+		wantErr string
+	}{
+		{
+			name: "valid policy",
+			cfg: map[string][]string{
+				"aim":    {"-voice"},
+				"icq":    {"utf8"},
+				"guest":  {"-filetransfer", "-directim"},
+				"@chuck": {"filetransfer"},
+			},
+		},
+		{
+			name:    "unknown capability",
+			cfg:     map[string][]string{"guest": {"-telepathy"}},
+			wantErr: `unknown capability "telepathy"`,
+		},
+		{
+			name:    "unknown target",
+			cfg:     map[string][]string{"admins": {"-chat"}},
+			wantErr: `unknown capability override target "admins"`,
+		},
+		{
+			name:    "screen name target without screen name",
+			cfg:     map[string][]string{"@": {"-chat"}},
+			wantErr: `unknown capability override target "@"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCapPolicy(tt.cfg)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCapPolicy_Apply(t *testing.T) {
+	policy, err := ParseCapPolicy(map[string][]string{
+		"aim":    {"-voice"},
+		"icq":    {"utf8"},
+		"guest":  {"-filetransfer", "-directim"},
+		"@chuck": {"filetransfer", "-chat"},
+	})
+	require.NoError(t, err)
+
+	clientCaps := [][16]byte{wire.CapChat, wire.CapVoice, wire.CapFileTransfer, wire.CapDirectIM}
+
+	tests := []struct {
+		name       string
+		policy     *CapPolicy
+		screenName IdentScreenName
+		guest      bool
+		icq        bool
+		want       [][16]byte
+	}{
+		{
+			name:       "nil policy leaves caps untouched",
+			screenName: NewIdentScreenName("alice"),
+			want:       clientCaps,
+		},
+		{
+			name:       "aim class override",
+			policy:     policy,
+			screenName: NewIdentScreenName("alice"),
+			want:       [][16]byte{wire.CapChat, wire.CapFileTransfer, wire.CapDirectIM},
+		},
+		{
+			name:       "icq class override",
+			policy:     policy,
+			screenName: NewIdentScreenName("100003"),
+			icq:        true,
+			want:       [][16]byte{wire.CapChat, wire.CapVoice, wire.CapFileTransfer, wire.CapDirectIM, wire.CapUTF8},
+		},
+		{
+			name:       "guest override after class override",
+			policy:     policy,
+			screenName: NewIdentScreenName("Guest123"),
+			guest:      true,
+			want:       [][16]byte{wire.CapChat},
+		},
+		{
+			name:       "screen name override wins over guest override",
+			policy:     policy,
+			screenName: NewIdentScreenName("chuck"),
+			guest:      true,
+			want:       [][16]byte{wire.CapFileTransfer},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caps := append([][16]byte{}, clientCaps...)
+			assert.Equal(t, tt.want, tt.policy.Apply(tt.screenName, tt.guest, tt.icq, caps))
+			assert.Equal(t, clientCaps, caps, "input caps must not be modified")
+		})
+	}
+}
+
+func TestInMemorySessionManager_SetCapPolicy(t *testing.T) {
+	policy, err := ParseCapPolicy(map[string][]string{
+		"@chuck": {"-filetransfer"},
+	})
+	require.NoError(t, err)
+
+	sm := NewInMemorySessionManager(slog.Default())
+	sm.SetCapPolicy(policy)
+
+	sess, err := sm.AddSession(context.Background(), "chuck")
+	require.NoError(t, err)
+	sess.SetCaps([][16]byte{wire.CapChat, wire.CapFileTransfer})
+
+	assert.Equal(t, [][16]byte{wire.CapChat, wire.CapFileTransfer}, sess.Caps())
+
+	info := sess.TLVUserInfo()
+	caps, ok := info.Bytes(wire.OServiceUserInfoOscarCaps)
+	require.True(t, ok)
+	assert.Equal(t, wire.CapChat[:], caps)
+}
@@ -94,7 +94,7 @@ func (c ChatRoom) TLVList() []wire.TLV {
 		// - 8 Occupant Peek Allowed
 		// It's unclear what effect they actually have.
 		wire.NewTLVBE(wire.ChatRoomTLVFlags, uint16(15)),
-		wire.NewTLVBE(wire.ChatRoomTLVCreateTime, uint32(c.createTime.Unix())),
+		wire.NewTLVBE(wire.ChatRoomTLVCreateTime, wire.EpochSeconds(c.createTime)),
 		wire.NewTLVBE(wire.ChatRoomTLVMaxMsgLen, uint16(1024)),
 		wire.NewTLVBE(wire.ChatRoomTLVMaxOccupancy, uint16(100)),
 		// From protocols/oscar/family_chatnav.c in lib purple, these are the
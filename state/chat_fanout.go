@@ -0,0 +1,240 @@
+package state
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+const (
+	// chatFanoutShardSize is the number of occupants a single goroutine
+	// relays a chat message to. Rooms with fewer occupants are relayed to
+	// serially.
+	chatFanoutShardSize = 256
+	// chatPresenceMaxBatch caps the number of users listed in one
+	// ChatUsersJoined or ChatUsersLeft notification.
+	chatPresenceMaxBatch = 100
+)
+
+// ChatBatchWindow returns how long ChatUsersJoined and ChatUsersLeft
+// notifications are held back in a room with the given number of occupants
+// so they can be sent in batches. Small rooms are notified immediately;
+// in busy rooms, batching keeps a burst of arrivals from turning into a
+// notification storm for every occupant.
+func ChatBatchWindow(occupants int) time.Duration {
+	switch {
+	case occupants < 50:
+		return 0
+	case occupants < 500:
+		return 250 * time.Millisecond
+	default:
+		return time.Second
+	}
+}
+
+// fanout calls fn for each session. Large sets of sessions are split into
+// shards processed concurrently by at most GOMAXPROCS goroutines.
+func fanout(sessions []*Session, fn func(sess *Session)) {
+	if len(sessions) <= chatFanoutShardSize {
+		for _, sess := range sessions {
+			fn(sess)
+		}
+		return
+	}
+
+	shards := make(chan []*Session)
+	wg := sync.WaitGroup{}
+	workers := min(runtime.GOMAXPROCS(0), (len(sessions)+chatFanoutShardSize-1)/chatFanoutShardSize)
+	for range workers {
+		wg.Go(func() {
+			for shard := range shards {
+				for _, sess := range shard {
+					fn(sess)
+				}
+			}
+		})
+	}
+	for shard := range slices.Chunk(sessions, chatFanoutShardSize) {
+		shards <- shard
+	}
+	close(shards)
+	wg.Wait()
+}
+
+// chatPresenceBatch holds the pending join and leave notifications of a
+// room, keyed by screen name. A user who joins and leaves (or leaves and
+// rejoins) within the same batch cancels out and isn't announced at all.
+type chatPresenceBatch struct {
+	joined map[IdentScreenName]wire.TLVUserInfo
+	left   map[IdentScreenName]wire.TLVUserInfo
+	order  []IdentScreenName
+	timer  *time.Timer
+}
+
+// SetPresenceSource sets the session manager that holds the users' main
+// (BOS) sessions. It must be called before the chat session manager is
+// used. See OccupantInfo.
+func (s *InMemoryChatSessionManager) SetPresenceSource(sessions SessionRetriever) {
+	s.presence = sessions
+}
+
+// OccupantInfo returns the user info that describes the chat session sess
+// to the room's occupants. Chat sessions don't carry the user's flags,
+// warning level or buddy icon, so the user info is built from the user's
+// main session, as in buddy arrival notifications. If there is no presence
+// source or the user's main session is gone, it falls back to the chat
+// session's own user info.
+func (s *InMemoryChatSessionManager) OccupantInfo(sess *Session) wire.TLVUserInfo {
+	if s.presence != nil {
+		if main := s.presence.RetrieveSession(sess.IdentScreenName()); main != nil {
+			info := main.TLVUserInfo()
+			info.ScreenName = sess.DisplayScreenName().Wire()
+			return info
+		}
+	}
+	return sess.TLVUserInfo()
+}
+
+// OccupantInfos returns the user info of every occupant of the room, for
+// the ChatUsersJoined notification sent to a user who enters the room.
+func (s *InMemoryChatSessionManager) OccupantInfos(cookie string) []wire.TLVUserInfo {
+	sessions := s.AllSessions(cookie)
+	infos := make([]wire.TLVUserInfo, 0, len(sessions))
+	for _, sess := range sessions {
+		infos = append(infos, s.OccupantInfo(sess))
+	}
+	return infos
+}
+
+// NotifyUserJoined tells the room's occupants, except the user who joined,
+// that user entered the room. user is usually the OccupantInfo of the
+// user's chat session. In busy rooms, the notification is batched with
+// others according to ChatBatchWindow.
+func (s *InMemoryChatSessionManager) NotifyUserJoined(ctx context.Context, cookie string, user wire.TLVUserInfo) {
+	s.queuePresence(ctx, cookie, user, true)
+}
+
+// NotifyUserLeft tells the room's occupants that user left the room. In
+// busy rooms, the notification is batched with others according to
+// ChatBatchWindow.
+func (s *InMemoryChatSessionManager) NotifyUserLeft(ctx context.Context, cookie string, user wire.TLVUserInfo) {
+	s.queuePresence(ctx, cookie, user, false)
+}
+
+func (s *InMemoryChatSessionManager) queuePresence(ctx context.Context, cookie string, user wire.TLVUserInfo, joined bool) {
+	window := s.batchWindow(len(s.AllSessions(cookie)))
+
+	s.batchMutex.Lock()
+	batch, ok := s.batches[cookie]
+	if !ok {
+		batch = &chatPresenceBatch{
+			joined: make(map[IdentScreenName]wire.TLVUserInfo),
+			left:   make(map[IdentScreenName]wire.TLVUserInfo),
+		}
+		s.batches[cookie] = batch
+	}
+
+	sn := NewIdentScreenNameFromWire(user.ScreenName)
+	_, wasJoined := batch.joined[sn]
+	_, wasLeft := batch.left[sn]
+	switch {
+	case joined && wasLeft:
+		delete(batch.left, sn)
+	case !joined && wasJoined:
+		delete(batch.joined, sn)
+	case joined:
+		batch.joined[sn] = user
+		batch.order = append(batch.order, sn)
+	default:
+		batch.left[sn] = user
+		batch.order = append(batch.order, sn)
+	}
+
+	flushNow := window == 0 || len(batch.joined) >= chatPresenceMaxBatch || len(batch.left) >= chatPresenceMaxBatch
+	if !flushNow && batch.timer == nil {
+		batch.timer = time.AfterFunc(window, func() {
+			s.flushPresence(context.WithoutCancel(ctx), cookie)
+		})
+	}
+	s.batchMutex.Unlock()
+
+	if flushNow {
+		s.flushPresence(ctx, cookie)
+	}
+}
+
+// flushPresence sends the room's pending join and leave notifications.
+func (s *InMemoryChatSessionManager) flushPresence(ctx context.Context, cookie string) {
+	s.batchMutex.Lock()
+	batch, ok := s.batches[cookie]
+	if ok {
+		delete(s.batches, cookie)
+		if batch.timer != nil {
+			batch.timer.Stop()
+		}
+	}
+	s.batchMutex.Unlock()
+	if !ok {
+		return
+	}
+
+	var joined, left []wire.TLVUserInfo
+	for _, sn := range batch.order {
+		if user, ok := batch.joined[sn]; ok {
+			joined = append(joined, user)
+			delete(batch.joined, sn)
+		} else if user, ok := batch.left[sn]; ok {
+			left = append(left, user)
+			delete(batch.left, sn)
+		}
+	}
+
+	sessions := s.AllSessions(cookie)
+	if len(sessions) == 0 {
+		return
+	}
+	s.mapMutex.RLock()
+	sessionManager := s.store[cookie]
+	s.mapMutex.RUnlock()
+	if sessionManager == nil {
+		return
+	}
+
+	for chunk := range slices.Chunk(left, chatPresenceMaxBatch) {
+		msg := wire.SNACMessage{
+			Frame: wire.SNACFrame{FoodGroup: wire.Chat, SubGroup: wire.ChatUsersLeft},
+			Body:  wire.SNAC_0x0E_0x04_ChatUsersLeft{Users: chunk},
+		}
+		fanout(sessions, func(sess *Session) {
+			sessionManager.maybeRelayMessage(ctx, msg, sess)
+		})
+	}
+
+	for chunk := range slices.Chunk(joined, chatPresenceMaxBatch) {
+		msg := wire.SNACMessage{
+			Frame: wire.SNACFrame{FoodGroup: wire.Chat, SubGroup: wire.ChatUsersJoined},
+			Body:  wire.SNAC_0x0E_0x03_ChatUsersJoined{Users: chunk},
+		}
+		fanout(sessions, func(sess *Session) {
+			// users who joined in this batch already know they're in the room
+			if idx := slices.IndexFunc(chunk, func(u wire.TLVUserInfo) bool {
+				return NewIdentScreenNameFromWire(u.ScreenName) == sess.IdentScreenName()
+			}); idx >= 0 {
+				others := slices.Delete(slices.Clone(chunk), idx, idx+1)
+				if len(others) == 0 {
+					return
+				}
+				sessionManager.maybeRelayMessage(ctx, wire.SNACMessage{
+					Frame: msg.Frame,
+					Body:  wire.SNAC_0x0E_0x03_ChatUsersJoined{Users: others},
+				}, sess)
+				return
+			}
+			sessionManager.maybeRelayMessage(ctx, msg, sess)
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestFanout(t *testing.T) {
+	for _, n := range []int{0, 1, chatFanoutShardSize, 10*chatFanoutShardSize + 7} {
+		t.Run(fmt.Sprintf("%d sessions", n), func(t *testing.T) {
+			sessions := make([]*Session, n)
+			for i := range sessions {
+				sessions[i] = NewSession()
+			}
+
+			mutex := sync.Mutex{}
+			visits := make(map[*Session]int, n)
+			fanout(sessions, func(sess *Session) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				visits[sess]++
+			})
+
+			assert.Len(t, visits, n)
+			for _, count := range visits {
+				assert.Equal(t, 1, count)
+			}
+		})
+	}
+}
+
+func TestChatBatchWindow(t *testing.T) {
+	assert.Zero(t, ChatBatchWindow(10))
+	assert.Equal(t, 250*time.Millisecond, ChatBatchWindow(50))
+	assert.Equal(t, time.Second, ChatBatchWindow(5000))
+}
+
+// newChatRoom adds signed-on sessions for screenNames to a chat room.
+func newChatRoom(t *testing.T, sm *InMemoryChatSessionManager, cookie string, screenNames ...string) map[string]*Session {
+	sessions := make(map[string]*Session)
+	for _, sn := range screenNames {
+		sess, err := sm.AddSession(context.Background(), cookie, DisplayScreenName(sn))
+		require.NoError(t, err)
+		sess.SetSignonComplete()
+		sessions[sn] = sess
+	}
+	return sessions
+}
+
+// presenceUsers returns the screen names listed in each presence
+// notification queued for sess.
+func presenceUsers(t *testing.T, sess *Session) (msgs []string) {
+	for {
+		select {
+		case msg := <-sess.ReceiveMessage():
+			var users []wire.TLVUserInfo
+			switch body := msg.Body.(type) {
+			case wire.SNAC_0x0E_0x03_ChatUsersJoined:
+				users = body.Users
+			case wire.SNAC_0x0E_0x04_ChatUsersLeft:
+				users = body.Users
+			default:
+				t.Fatalf("unexpected message %v", msg.Frame)
+			}
+			desc := wire.SubGroupName(msg.Frame.FoodGroup, msg.Frame.SubGroup) + ":"
+			for _, u := range users {
+				desc += " " + u.ScreenName.String()
+			}
+			msgs = append(msgs, desc)
+		default:
+			return msgs
+		}
+	}
+}
+
+func TestInMemoryChatSessionManager_NotifyUserJoined_SmallRoom(t *testing.T) {
+	sm := NewInMemoryChatSessionManager(slog.Default())
+	room := newChatRoom(t, sm, "the-cookie", "alice", "bob")
+
+	sm.NotifyUserJoined(context.Background(), "the-cookie", wire.TLVUserInfo{ScreenName: "bob"})
+
+	assert.Equal(t, []string{"ChatUsersJoined: bob"}, presenceUsers(t, room["alice"]))
+	assert.Empty(t, presenceUsers(t, room["bob"]))
+}
+
+func TestInMemoryChatSessionManager_NotifyUserJoined_Batched(t *testing.T) {
+	sm := NewInMemoryChatSessionManager(slog.Default())
+	// hold notifications until flushed by the test
+	sm.batchWindow = func(occupants int) time.Duration { return time.Hour }
+	room := newChatRoom(t, sm, "the-cookie", "alice", "bob", "carol")
+
+	ctx := context.Background()
+	sm.NotifyUserJoined(ctx, "the-cookie", wire.TLVUserInfo{ScreenName: "bob"})
+	sm.NotifyUserJoined(ctx, "the-cookie", wire.TLVUserInfo{ScreenName: "carol"})
+	sm.NotifyUserJoined(ctx, "the-cookie", wire.TLVUserInfo{ScreenName: "dave"})
+	sm.NotifyUserLeft(ctx, "the-cookie", wire.TLVUserInfo{ScreenName: "dave"})
+	sm.NotifyUserLeft(ctx, "the-cookie", wire.TLVUserInfo{ScreenName: "erin"})
+
+	assert.Empty(t, presenceUsers(t, room["alice"]))
+	sm.flushPresence(ctx, "the-cookie")
+
+	// dave joined and left within the batch, so he's never announced
+	assert.Equal(t, []string{"ChatUsersLeft: erin", "ChatUsersJoined: bob carol"}, presenceUsers(t, room["alice"]))
+	assert.Equal(t, []string{"ChatUsersLeft: erin", "ChatUsersJoined: carol"}, presenceUsers(t, room["bob"]))
+	assert.Equal(t, []string{"ChatUsersLeft: erin", "ChatUsersJoined: bob"}, presenceUsers(t, room["carol"]))
+
+	// nothing left to flush
+	sm.flushPresence(ctx, "the-cookie")
+	assert.Empty(t, presenceUsers(t, room["alice"]))
+}
+
+func TestInMemoryChatSessionManager_NotifyUserJoined_BatchTimer(t *testing.T) {
+	sm := NewInMemoryChatSessionManager(slog.Default())
+	sm.batchWindow = func(occupants int) time.Duration { return 10 * time.Millisecond }
+	room := newChatRoom(t, sm, "the-cookie", "alice")
+
+	sm.NotifyUserJoined(context.Background(), "the-cookie", wire.TLVUserInfo{ScreenName: "bob"})
+
+	select {
+	case msg := <-room["alice"].ReceiveMessage():
+		assert.Equal(t, wire.SNAC_0x0E_0x03_ChatUsersJoined{
+			Users: []wire.TLVUserInfo{{ScreenName: "bob"}},
+		}, msg.Body)
+	case <-time.After(time.Second):
+		t.Fatal("batch was not flushed")
+	}
+}
+
+func TestInMemoryChatSessionManager_NotifyUserJoined_FullBatch(t *testing.T) {
+	sm := NewInMemoryChatSessionManager(slog.Default())
+	sm.batchWindow = func(occupants int) time.Duration { return time.Hour }
+	room := newChatRoom(t, sm, "the-cookie", "alice")
+
+	for i := range chatPresenceMaxBatch {
+		sm.NotifyUserJoined(context.Background(), "the-cookie", wire.TLVUserInfo{ScreenName: wire.ScreenName(fmt.Sprintf("user%d", i))})
+	}
+
+	// the batch is sent as soon as it's full
+	msgs := presenceUsers(t, room["alice"])
+	assert.Len(t, msgs, 1)
+}
+
+func TestInMemoryChatSessionManager_OccupantInfo(t *testing.T) {
+	ctx := context.Background()
+	presence := NewInMemorySessionManager(slog.Default())
+	main, err := presence.AddSession(ctx, "Alice")
+	require.NoError(t, err)
+	main.SetSignonComplete()
+	main.SetWarning(200)
+	main.SetAwayMessage("brb")
+	icon := wire.BARTID{Type: wire.BARTTypesBuddyIcon, BARTInfo: wire.BARTInfo{Hash: []byte{1, 2, 3}}}
+	main.SetBuddyIcon(icon)
+
+	sm := NewInMemoryChatSessionManager(slog.Default())
+	room := newChatRoom(t, sm, "the-cookie", "Alice", "bob")
+
+	t.Run("without presence source", func(t *testing.T) {
+		info := sm.OccupantInfo(room["Alice"])
+		assert.Equal(t, wire.ScreenName("Alice"), info.ScreenName)
+		assert.Zero(t, info.WarningLevel)
+	})
+
+	sm.SetPresenceSource(presence)
+
+	t.Run("user signed on", func(t *testing.T) {
+		info := sm.OccupantInfo(room["Alice"])
+		assert.Equal(t, wire.ScreenName("Alice"), info.ScreenName)
+		assert.Equal(t, uint16(200), info.WarningLevel)
+
+		flags, ok := info.Uint16BE(wire.OServiceUserInfoUserFlags)
+		require.True(t, ok)
+		assert.NotZero(t, flags&wire.OServiceUserFlagUnavailable)
+
+		b, ok := info.Bytes(wire.OServiceUserInfoBARTInfo)
+		require.True(t, ok)
+		ids, err := wire.UnmarshalBARTIDs(b)
+		require.NoError(t, err)
+		assert.Equal(t, []wire.BARTID{icon}, ids)
+	})
+
+	t.Run("user without main session", func(t *testing.T) {
+		info := sm.OccupantInfo(room["bob"])
+		assert.Equal(t, wire.ScreenName("bob"), info.ScreenName)
+		assert.Zero(t, info.WarningLevel)
+	})
+
+	t.Run("occupant list", func(t *testing.T) {
+		infos := sm.OccupantInfos("the-cookie")
+		require.Len(t, infos, 2)
+		warnings := map[wire.ScreenName]uint16{}
+		for _, info := range infos {
+			warnings[info.ScreenName] = info.WarningLevel
+		}
+		assert.Equal(t, map[wire.ScreenName]uint16{"Alice": 200, "bob": 0}, warnings)
+	})
+}
@@ -0,0 +1,156 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// ChatFilterAction is what to do with a chat message that was checked
+// against a ChatFilterPolicy.
+type ChatFilterAction uint8
+
+const (
+	// ChatFilterAllow relays the message to the room.
+	ChatFilterAllow ChatFilterAction = iota
+	// ChatFilterDrop discards the message.
+	ChatFilterDrop
+	// ChatFilterKick discards the message and removes the sender from the
+	// room.
+	ChatFilterKick
+)
+
+var (
+	chatHTMLTagRegexp = regexp.MustCompile(`<[^>]*>`)
+	chatLinkRegexp    = regexp.MustCompile(`(?i)(\b[a-z][a-z0-9+.-]*://|\bwww\.|\bhref\s*=)`)
+)
+
+// ChatFilterPolicy is the moderation policy of a chat room.
+type ChatFilterPolicy struct {
+	// BlockedWords lists words that get a message dropped. Words match
+	// whole words of the message text, ignoring case and HTML markup.
+	BlockedWords []string
+	// DenyLinks drops messages that contain links, so that clients can't
+	// open or unfurl them.
+	DenyLinks bool
+	// KickRules are patterns matched against the message text, HTML markup
+	// included. A message that matches one of them is dropped and its
+	// sender is kicked from the room.
+	KickRules []*regexp.Regexp
+}
+
+// ChatFilterVerdict is the outcome of filtering a chat message.
+type ChatFilterVerdict struct {
+	Action ChatFilterAction
+	// Reason describes why the message was not allowed, for logging.
+	Reason string
+}
+
+// check applies the policy to the message text. Kick rules are checked
+// first, so that a message breaking several rules gets the harshest action.
+func (p ChatFilterPolicy) check(text string) ChatFilterVerdict {
+	for _, rule := range p.KickRules {
+		if rule.MatchString(text) {
+			return ChatFilterVerdict{
+				Action: ChatFilterKick,
+				Reason: fmt.Sprintf("matched kick rule %q", rule.String()),
+			}
+		}
+	}
+
+	if p.DenyLinks && chatLinkRegexp.MatchString(text) {
+		return ChatFilterVerdict{Action: ChatFilterDrop, Reason: "contains a link"}
+	}
+
+	if len(p.BlockedWords) > 0 {
+		plain := chatHTMLTagRegexp.ReplaceAllString(text, " ")
+		words := strings.FieldsFunc(strings.ToLower(plain), func(r rune) bool {
+			return !isWordRune(r)
+		})
+		for _, blocked := range p.BlockedWords {
+			for _, word := range words {
+				if word == strings.ToLower(blocked) {
+					return ChatFilterVerdict{
+						Action: ChatFilterDrop,
+						Reason: fmt.Sprintf("contains blocked word %q", blocked),
+					}
+				}
+			}
+		}
+	}
+
+	return ChatFilterVerdict{Action: ChatFilterAllow}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' || r == '-' || r == '_'
+}
+
+// ChatFilter is the content moderation hook of the chat service. It holds
+// the filter policies of exchanges and of individual rooms. A room's own
+// policy replaces the policy of its exchange. The zero value allows every
+// message.
+type ChatFilter struct {
+	// Exchanges maps exchange IDs to the policy of their rooms.
+	Exchanges map[uint16]ChatFilterPolicy
+	// Rooms maps room cookies to the policy of the room.
+	Rooms map[string]ChatFilterPolicy
+}
+
+// Policy returns the filter policy that applies to room, if any.
+func (f ChatFilter) Policy(room ChatRoom) (ChatFilterPolicy, bool) {
+	if policy, ok := f.Rooms[room.Cookie()]; ok {
+		return policy, true
+	}
+	policy, ok := f.Exchanges[room.Exchange()]
+	return policy, ok
+}
+
+// FilterChatMessage checks a SNAC(0x0E,0x05) ChatChannelMsgToHost sent to
+// room against the room's policy. It should be called before the message
+// is relayed to the room's occupants. Messages without text are allowed.
+func (f ChatFilter) FilterChatMessage(room ChatRoom, inBody wire.SNAC_0x0E_0x05_ChatChannelMsgToHost) (ChatFilterVerdict, error) {
+	policy, ok := f.Policy(room)
+	if !ok {
+		return ChatFilterVerdict{Action: ChatFilterAllow}, nil
+	}
+
+	msgInfo, ok := inBody.Bytes(wire.ChatTLVMessageInfo)
+	if !ok {
+		return ChatFilterVerdict{Action: ChatFilterAllow}, nil
+	}
+	text, err := wire.UnmarshalChatMessageText(msgInfo)
+	if err != nil {
+		return ChatFilterVerdict{}, fmt.Errorf("FilterChatMessage: %w", err)
+	}
+
+	return policy.check(text), nil
+}
+
+// ChatMessageFilter checks chat messages before they are relayed to a
+// room.
+type ChatMessageFilter interface {
+	FilterChatMessage(room ChatRoom, inBody wire.SNAC_0x0E_0x05_ChatChannelMsgToHost) (ChatFilterVerdict, error)
+}
+
+// ErrChatKickRuleInvalid indicates that a chat kick rule is not a valid
+// regular expression.
+var ErrChatKickRuleInvalid = errors.New("invalid chat kick rule")
+
+// CompileChatKickRules compiles regular expressions for
+// ChatFilterPolicy.KickRules.
+func CompileChatKickRules(patterns []string) ([]*regexp.Regexp, error) {
+	rules := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		rule, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %w", ErrChatKickRuleInvalid, pattern, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
@@ -0,0 +1,137 @@
+package state
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func chatMsgToHost(text string) wire.SNAC_0x0E_0x05_ChatChannelMsgToHost {
+	return wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+		Channel: 3,
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.ChatTLVMessageInfoText, text),
+					},
+				}),
+			},
+		},
+	}
+}
+
+func TestChatFilter_FilterChatMessage(t *testing.T) {
+	kickRules, err := CompileChatKickRules([]string{`(?i)buy\s+cheap`})
+	require.NoError(t, err)
+
+	lobby := NewChatRoom("lobby", NewIdentScreenName("admin"), PublicExchange)
+	kids := NewChatRoom("kids", NewIdentScreenName("admin"), PublicExchange)
+	private := NewChatRoom("hangout", NewIdentScreenName("chuck"), PrivateExchange)
+
+	filter := ChatFilter{
+		Exchanges: map[uint16]ChatFilterPolicy{
+			PublicExchange: {
+				BlockedWords: []string{"Darn"},
+				KickRules:    kickRules,
+			},
+		},
+		Rooms: map[string]ChatFilterPolicy{
+			kids.Cookie(): {
+				BlockedWords: []string{"darn", "heck"},
+				DenyLinks:    true,
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		room ChatRoom
+		text string
+		want ChatFilterAction
+	}{
+		{
+			name: "clean message",
+			room: lobby,
+			text: "<HTML><BODY>hello everyone</BODY></HTML>",
+			want: ChatFilterAllow,
+		},
+		{
+			name: "blocked word ignores case and markup",
+			room: lobby,
+			text: "<B>DARN</B>it",
+			want: ChatFilterDrop,
+		},
+		{
+			name: "blocked word only matches whole words",
+			room: lobby,
+			text: "darnell is here",
+			want: ChatFilterAllow,
+		},
+		{
+			name: "kick rule",
+			room: lobby,
+			text: "BUY   CHEAP watches, darn it",
+			want: ChatFilterKick,
+		},
+		{
+			name: "links allowed by exchange policy",
+			room: lobby,
+			text: `<A HREF="http://example.com">look</A>`,
+			want: ChatFilterAllow,
+		},
+		{
+			name: "room policy denies links",
+			room: kids,
+			text: "go to www.example.com",
+			want: ChatFilterDrop,
+		},
+		{
+			name: "room policy replaces exchange policy",
+			room: kids,
+			text: "buy cheap heck",
+			want: ChatFilterDrop,
+		},
+		{
+			name: "room without policy",
+			room: private,
+			text: "darn, buy cheap at http://example.com",
+			want: ChatFilterAllow,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, err := filter.FilterChatMessage(tt.room, chatMsgToHost(tt.text))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, verdict.Action)
+			if tt.want != ChatFilterAllow {
+				assert.NotEmpty(t, verdict.Reason)
+			}
+		})
+	}
+
+	t.Run("message without text TLV", func(t *testing.T) {
+		inBody := wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{}),
+				},
+			},
+		}
+		_, err := filter.FilterChatMessage(lobby, inBody)
+		assert.ErrorContains(t, err, "FilterChatMessage: ")
+	})
+}
+
+func TestCompileChatKickRules(t *testing.T) {
+	rules, err := CompileChatKickRules([]string{`spam`, `^!`})
+	require.NoError(t, err)
+	assert.Equal(t, []*regexp.Regexp{regexp.MustCompile(`spam`), regexp.MustCompile(`^!`)}, rules)
+
+	_, err = CompileChatKickRules([]string{`(unclosed`})
+	assert.ErrorIs(t, err, ErrChatKickRuleInvalid)
+}
@@ -0,0 +1,149 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// ChatCreateTier is the class of accounts allowed to create chat rooms in
+// an exchange. Each tier also admits every account allowed by the tiers
+// above it, so admins may create rooms wherever anyone can.
+type ChatCreateTier uint8
+
+const (
+	// ChatCreateNobody disallows room creation in the exchange.
+	ChatCreateNobody ChatCreateTier = iota
+	// ChatCreateAdmins allows only server admins to create rooms.
+	ChatCreateAdmins
+	// ChatCreateConfirmed allows admins and accounts that confirmed their
+	// registration to create rooms.
+	ChatCreateConfirmed
+	// ChatCreateEveryone allows any registered account to create rooms.
+	ChatCreateEveryone
+)
+
+// ChatCreateErrorCode is the ChatNav error code returned to users who aren't
+// allowed to create a room in the requested exchange.
+const ChatCreateErrorCode = wire.ErrorCodeInsufficientRights
+
+// ErrChatCreateNotAllowed indicates that a user may not create chat rooms
+// in an exchange.
+var ErrChatCreateNotAllowed = errors.New("not allowed to create chat rooms in this exchange")
+
+// ChatCreateTierNames lists the names of the chat room creation tiers,
+// indexed by ChatCreateTier.
+var ChatCreateTierNames = []string{
+	ChatCreateNobody:    "nobody",
+	ChatCreateAdmins:    "admins",
+	ChatCreateConfirmed: "confirmed",
+	ChatCreateEveryone:  "everyone",
+}
+
+// String returns the name of the tier.
+func (t ChatCreateTier) String() string {
+	if int(t) < len(ChatCreateTierNames) {
+		return ChatCreateTierNames[t]
+	}
+	return fmt.Sprintf("ChatCreateTier(%d)", t)
+}
+
+// ParseChatCreateTier converts a tier name (nobody, admins, confirmed,
+// everyone) into a ChatCreateTier.
+func ParseChatCreateTier(name string) (ChatCreateTier, error) {
+	i := slices.Index(ChatCreateTierNames, name)
+	if i < 0 {
+		return 0, fmt.Errorf("unknown chat room creation tier %q", name)
+	}
+	return ChatCreateTier(i), nil
+}
+
+// ParseChatCreateTiers converts a map of exchange ID to tier name, as
+// returned by config.Config.ParseChatRoomCreatePerms, into
+// ChatCreatePolicy.Tiers.
+func ParseChatCreateTiers(names map[uint16]string) (map[uint16]ChatCreateTier, error) {
+	tiers := make(map[uint16]ChatCreateTier, len(names))
+	for exchange, name := range names {
+		tier, err := ParseChatCreateTier(name)
+		if err != nil {
+			return nil, fmt.Errorf("exchange %d: %w", exchange, err)
+		}
+		tiers[exchange] = tier
+	}
+	return tiers, nil
+}
+
+// ChatCreatePolicy decides who may create chat rooms in each exchange.
+type ChatCreatePolicy struct {
+	// Tiers maps exchange IDs to their creation tier. Exchanges missing from
+	// the map use DefaultChatCreateTier.
+	Tiers map[uint16]ChatCreateTier
+	// Admins lists the screen names of server admins.
+	Admins []IdentScreenName
+	// Probation withholds room creation from new accounts, in every
+	// exchange.
+	Probation ProbationPolicy
+}
+
+// DefaultChatCreateTier returns the creation tier used for exchanges that
+// have no explicit policy. Anyone may create rooms in the private exchange,
+// public rooms are reserved for admins, and other exchanges are closed.
+func DefaultChatCreateTier(exchange uint16) ChatCreateTier {
+	switch exchange {
+	case PrivateExchange:
+		return ChatCreateEveryone
+	case PublicExchange:
+		return ChatCreateAdmins
+	default:
+		return ChatCreateNobody
+	}
+}
+
+// Tier returns the creation tier for exchange.
+func (p ChatCreatePolicy) Tier(exchange uint16) ChatCreateTier {
+	if tier, ok := p.Tiers[exchange]; ok {
+		return tier
+	}
+	return DefaultChatCreateTier(exchange)
+}
+
+// IsAdmin indicates whether screenName is a server admin.
+func (p ChatCreatePolicy) IsAdmin(screenName IdentScreenName) bool {
+	return slices.Contains(p.Admins, screenName)
+}
+
+// CanCreate returns ErrChatCreateNotAllowed if user may not create chat
+// rooms in exchange, also wrapping ErrProbation if the account is on
+// probation. Callers should respond with ChatCreateErrorCode.
+func (p ChatCreatePolicy) CanCreate(exchange uint16, user User) error {
+	if p.Probation.OnProbation(user, time.Now()) && !p.IsAdmin(user.IdentScreenName) {
+		return fmt.Errorf("%w: exchange %d: %w", ErrChatCreateNotAllowed, exchange, ErrProbation)
+	}
+
+	allowed := false
+	switch p.Tier(exchange) {
+	case ChatCreateEveryone:
+		allowed = true
+	case ChatCreateConfirmed:
+		allowed = user.ConfirmStatus || p.IsAdmin(user.IdentScreenName)
+	case ChatCreateAdmins:
+		allowed = p.IsAdmin(user.IdentScreenName)
+	}
+
+	if !allowed {
+		return fmt.Errorf("%w: exchange %d", ErrChatCreateNotAllowed, exchange)
+	}
+	return nil
+}
+
+// NavCreatePerms returns the ChatRoomTLVNavCreatePerms value to advertise to
+// user for exchange: 0 if room creation is not allowed, 2 otherwise.
+func (p ChatCreatePolicy) NavCreatePerms(exchange uint16, user User) uint8 {
+	if p.CanCreate(exchange, user) != nil {
+		return 0
+	}
+	return 2
+}
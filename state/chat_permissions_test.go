@@ -0,0 +1,93 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/pchchv/go-icq/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCreatePolicy_CanCreate(t *testing.T) {
+	admin := User{IdentScreenName: NewIdentScreenName("admin")}
+	confirmed := User{IdentScreenName: NewIdentScreenName("confirmed"), ConfirmStatus: true}
+	regular := User{IdentScreenName: NewIdentScreenName("regular")}
+
+	policy := ChatCreatePolicy{
+		Tiers: map[uint16]ChatCreateTier{
+			PrivateExchange: ChatCreateConfirmed,
+			7:               ChatCreateNobody,
+			8:               ChatCreateEveryone,
+		},
+		Admins: []IdentScreenName{admin.IdentScreenName},
+	}
+
+	tests := []struct {
+		name     string
+		exchange uint16
+		user     User
+		wantErr  error
+	}{
+		{name: "admin creates public room", exchange: PublicExchange, user: admin},
+		{name: "confirmed user can't create public room", exchange: PublicExchange, user: confirmed, wantErr: ErrChatCreateNotAllowed},
+		{name: "confirmed user creates private room", exchange: PrivateExchange, user: confirmed},
+		{name: "admin creates private room", exchange: PrivateExchange, user: admin},
+		{name: "unconfirmed user can't create private room", exchange: PrivateExchange, user: regular, wantErr: ErrChatCreateNotAllowed},
+		{name: "nobody creates in closed exchange", exchange: 7, user: admin, wantErr: ErrChatCreateNotAllowed},
+		{name: "anyone creates in open exchange", exchange: 8, user: regular},
+		{name: "unconfigured exchange is closed", exchange: 9, user: admin, wantErr: ErrChatCreateNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.CanCreate(tt.exchange, tt.user)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, uint8(2), policy.NavCreatePerms(tt.exchange, tt.user))
+			} else {
+				assert.Equal(t, uint8(0), policy.NavCreatePerms(tt.exchange, tt.user))
+			}
+		})
+	}
+}
+
+func TestChatCreatePolicy_DefaultTiers(t *testing.T) {
+	policy := ChatCreatePolicy{}
+	assert.Equal(t, ChatCreateEveryone, policy.Tier(PrivateExchange))
+	assert.Equal(t, ChatCreateAdmins, policy.Tier(PublicExchange))
+	assert.Equal(t, ChatCreateNobody, policy.Tier(1))
+}
+
+func TestParseChatCreateTier(t *testing.T) {
+	tier, err := ParseChatCreateTier("confirmed")
+	assert.NoError(t, err)
+	assert.Equal(t, ChatCreateConfirmed, tier)
+
+	_, err = ParseChatCreateTier("members")
+	assert.Error(t, err)
+
+	for _, name := range ChatCreateTierNames {
+		tier, err := ParseChatCreateTier(name)
+		assert.NoError(t, err)
+		assert.Equal(t, name, tier.String())
+	}
+}
+
+func TestParseChatCreateTiers(t *testing.T) {
+	// every tier name that config accepts must convert
+	c := config.Config{ChatRoomCreatePerms: []string{"1:nobody", "2:admins", "3:confirmed", "4:everyone"}}
+	names, err := c.ParseChatRoomCreatePerms()
+	require.NoError(t, err)
+
+	tiers, err := ParseChatCreateTiers(names)
+	require.NoError(t, err)
+	assert.Equal(t, map[uint16]ChatCreateTier{
+		1: ChatCreateNobody,
+		2: ChatCreateAdmins,
+		3: ChatCreateConfirmed,
+		4: ChatCreateEveryone,
+	}, tiers)
+
+	_, err = ParseChatCreateTiers(map[uint16]string{4: "members"})
+	assert.Error(t, err)
+}
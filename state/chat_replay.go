@@ -0,0 +1,133 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// ChatReplayMaxMessages is the most messages a room may keep for replay.
+const ChatReplayMaxMessages = 100
+
+// ChatReplayPolicy sets how many recent messages each chat room keeps and
+// replays to users who join it. Rooms that aren't covered keep nothing. A
+// room policy replaces the policy of its exchange.
+type ChatReplayPolicy struct {
+	// Exchanges maps exchange IDs to the number of messages their rooms
+	// keep.
+	Exchanges map[uint16]int
+	// Rooms maps room cookies to the number of messages the room keeps.
+	Rooms map[string]int
+}
+
+// Size returns the number of messages room keeps for replay.
+func (p ChatReplayPolicy) Size(room ChatRoom) int {
+	if n, ok := p.Rooms[room.Cookie()]; ok {
+		return min(n, ChatReplayMaxMessages)
+	}
+	return min(p.Exchanges[room.Exchange()], ChatReplayMaxMessages)
+}
+
+// chatTranscriptEntry is a message kept for replay.
+type chatTranscriptEntry struct {
+	sent time.Time
+	body wire.SNAC_0x0E_0x06_ChatChannelMsgToClient
+}
+
+// chatTranscripts holds the recent messages of each occupied chat room,
+// keyed by room cookie.
+type chatTranscripts struct {
+	mutex   sync.Mutex
+	policy  ChatReplayPolicy
+	entries map[string][]chatTranscriptEntry
+	nowFn   func() time.Time
+}
+
+// SetReplayPolicy sets how many recent messages each room keeps for
+// replay. Messages already kept by rooms whose size shrinks are trimmed as
+// new messages arrive.
+func (s *InMemoryChatSessionManager) SetReplayPolicy(policy ChatReplayPolicy) {
+	s.transcripts.mutex.Lock()
+	defer s.transcripts.mutex.Unlock()
+	s.transcripts.policy = policy
+}
+
+// RecordChatMessage keeps msg, a message relayed to every occupant of
+// room, for replay to users who join later. Whispers must not be recorded.
+// Rooms discard their messages when the last occupant leaves.
+func (s *InMemoryChatSessionManager) RecordChatMessage(room ChatRoom, msg wire.SNAC_0x0E_0x06_ChatChannelMsgToClient) {
+	t := &s.transcripts
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	size := t.policy.Size(room)
+	if size <= 0 {
+		return
+	}
+	entries := append(t.entries[room.Cookie()], chatTranscriptEntry{sent: t.nowFn(), body: msg})
+	if len(entries) > size {
+		entries = append([]chatTranscriptEntry(nil), entries[len(entries)-size:]...)
+	}
+	t.entries[room.Cookie()] = entries
+}
+
+// ReplayChatMessages sends the messages kept by room to sess, oldest first,
+// so that a user who just joined can catch up on the conversation. Each
+// message is marked as a replay, with the time it was sent, so that it
+// can't be mistaken for a new one. Handlers call it after the join
+// notifications have been sent.
+func (s *InMemoryChatSessionManager) ReplayChatMessages(ctx context.Context, room ChatRoom, sess *Session) {
+	t := &s.transcripts
+	t.mutex.Lock()
+	entries := append([]chatTranscriptEntry(nil), t.entries[room.Cookie()]...)
+	t.mutex.Unlock()
+
+	for _, entry := range entries {
+		body, err := markChatReplay(entry.body, entry.sent)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "unable to mark replayed chat message", "cookie", room.Cookie(), "err", err)
+			continue
+		}
+		sess.RelayMessage(wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.Chat,
+				SubGroup:  wire.ChatChannelMsgToClient,
+			},
+			Body: body,
+		})
+	}
+}
+
+// discard drops the messages kept by the room with cookie.
+func (t *chatTranscripts) discard(cookie string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.entries, cookie)
+}
+
+// markChatReplay returns a copy of msg with its text prefixed by the time
+// it was originally sent, such as "(replay 15:04) hello".
+func markChatReplay(msg wire.SNAC_0x0E_0x06_ChatChannelMsgToClient, sent time.Time) (wire.SNAC_0x0E_0x06_ChatChannelMsgToClient, error) {
+	b, ok := msg.Bytes(wire.ChatTLVMessageInfo)
+	if !ok {
+		return msg, nil
+	}
+	info := wire.TLVRestBlock{}
+	if err := wire.UnmarshalBE(&info, bytes.NewReader(b)); err != nil {
+		return msg, err
+	}
+	text, ok := info.String(wire.ChatTLVMessageInfoText)
+	if !ok {
+		return msg, nil
+	}
+	info.Replace(wire.NewTLVBE(wire.ChatTLVMessageInfoText, fmt.Sprintf("(replay %s) %s", sent.Format("15:04"), text)))
+
+	marked := msg
+	marked.TLVRestBlock = wire.TLVRestBlock{TLVList: append(wire.TLVList(nil), msg.TLVList...)}
+	marked.Replace(wire.NewTLVBE(wire.ChatTLVMessageInfo, info))
+	return marked, nil
+}
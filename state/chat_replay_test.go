@@ -0,0 +1,102 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func newChatReplayMsg(text string) wire.SNAC_0x0E_0x06_ChatChannelMsgToClient {
+	return wire.SNAC_0x0E_0x06_ChatChannelMsgToClient{
+		Cookie:  1234,
+		Channel: wire.ICBMChannelMIME,
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+				wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.ChatTLVMessageInfoText, text),
+					},
+				}),
+			},
+		},
+	}
+}
+
+func TestChatReplayPolicy_Size(t *testing.T) {
+	lobby := NewChatRoom("Lobby", NewIdentScreenName(""), PublicExchange)
+	other := NewChatRoom("Other", NewIdentScreenName(""), PublicExchange)
+	private := NewChatRoom("Private", NewIdentScreenName(""), PrivateExchange)
+
+	p := ChatReplayPolicy{
+		Exchanges: map[uint16]int{PublicExchange: 20},
+		Rooms:     map[string]int{lobby.Cookie(): 0},
+	}
+	assert.Equal(t, 0, p.Size(lobby))
+	assert.Equal(t, 20, p.Size(other))
+	assert.Equal(t, 0, p.Size(private))
+
+	p.Exchanges[PublicExchange] = 1000
+	assert.Equal(t, ChatReplayMaxMessages, p.Size(other))
+}
+
+func TestInMemoryChatSessionManager_ReplayChatMessages(t *testing.T) {
+	room := NewChatRoom("Lobby", NewIdentScreenName(""), PublicExchange)
+	sm := NewInMemoryChatSessionManager(slog.Default())
+	sm.SetReplayPolicy(ChatReplayPolicy{Exchanges: map[uint16]int{PublicExchange: 2}})
+	sent := time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC)
+	sm.transcripts.nowFn = func() time.Time { return sent }
+
+	speaker, err := sm.AddSession(context.Background(), room.Cookie(), "speaker")
+	require.NoError(t, err)
+
+	for _, text := range []string{"first", "second", "third"} {
+		sm.RecordChatMessage(room, newChatReplayMsg(text))
+	}
+
+	joiner, err := sm.AddSession(context.Background(), room.Cookie(), "joiner")
+	require.NoError(t, err)
+	joiner.SetSignonComplete()
+
+	sm.ReplayChatMessages(context.Background(), room, joiner)
+
+	// the oldest message was trimmed
+	for _, want := range []string{"(replay 15:04) second", "(replay 15:04) third"} {
+		msg := <-joiner.ReceiveMessage()
+		assert.Equal(t, wire.SNACFrame{FoodGroup: wire.Chat, SubGroup: wire.ChatChannelMsgToClient}, msg.Frame)
+		body := msg.Body.(wire.SNAC_0x0E_0x06_ChatChannelMsgToClient)
+		assert.Equal(t, uint64(1234), body.Cookie)
+		assert.True(t, body.HasTag(wire.ChatTLVPublicWhisperFlag))
+		b, ok := body.Bytes(wire.ChatTLVMessageInfo)
+		require.True(t, ok)
+		text, err := wire.UnmarshalChatMessageText(b)
+		require.NoError(t, err)
+		assert.Equal(t, want, text)
+	}
+
+	// the recorded messages aren't modified by the replay
+	b, ok := sm.transcripts.entries[room.Cookie()][0].body.Bytes(wire.ChatTLVMessageInfo)
+	require.True(t, ok)
+	text, err := wire.UnmarshalChatMessageText(b)
+	require.NoError(t, err)
+	assert.Equal(t, "second", text)
+
+	// the transcript is discarded once the room empties
+	sm.RemoveSession(speaker)
+	sm.RemoveSession(joiner)
+	assert.Empty(t, sm.transcripts.entries)
+}
+
+func TestInMemoryChatSessionManager_RecordChatMessage_Disabled(t *testing.T) {
+	room := NewChatRoom("Lobby", NewIdentScreenName(""), PublicExchange)
+	sm := NewInMemoryChatSessionManager(slog.Default())
+
+	sm.RecordChatMessage(room, newChatReplayMsg("hello"))
+	assert.Empty(t, sm.transcripts.entries)
+}
@@ -0,0 +1,134 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// UnlimitedChatRooms is the quota of account classes that may own any
+// number of chat rooms.
+const UnlimitedChatRooms = -1
+
+// ChatRoomQuotaErrorCode is the ChatNav error code returned to users who
+// already own as many chat rooms as their quota allows.
+const ChatRoomQuotaErrorCode = wire.ErrorCodeListOverflow
+
+// ErrChatRoomQuotaExceeded indicates that a user already owns as many chat
+// rooms as their quota allows.
+var ErrChatRoomQuotaExceeded = errors.New("chat room quota exceeded")
+
+// ChatRoomQuotas holds the number of chat rooms that users of each account
+// class may own at once. A user owns the rooms they created until the rooms
+// close, so quotas are counted from the stored rooms and survive restarts.
+type ChatRoomQuotas struct {
+	classes map[ICBMClass]int
+}
+
+// NewChatRoomQuotas creates a new instance of ChatRoomQuotas from class
+// names (standard, guest, bot, probation) mapped to quotas. Classes not in
+// classes are unlimited.
+func NewChatRoomQuotas(classes map[string]int) (*ChatRoomQuotas, error) {
+	q := &ChatRoomQuotas{
+		classes: make(map[ICBMClass]int, len(classes)),
+	}
+	for name, quota := range classes {
+		class, ok := icbmClassNames[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown account class %q", name)
+		}
+		if quota < 0 {
+			return nil, fmt.Errorf("invalid chat room quota %d for class %s", quota, name)
+		}
+		q.classes[class] = quota
+	}
+	return q, nil
+}
+
+// Quota returns the number of chat rooms the user of sess may own, or
+// UnlimitedChatRooms.
+func (q *ChatRoomQuotas) Quota(sess *Session) int {
+	if quota, ok := q.classes[ICBMClassOf(sess)]; ok {
+		return quota
+	}
+	return UnlimitedChatRooms
+}
+
+// ChatRoomsOwned returns the number of open chat rooms created by
+// screenName.
+func (us SQLiteUserStore) ChatRoomsOwned(ctx context.Context, screenName IdentScreenName) (int, error) {
+	q := `SELECT COUNT(*) FROM chatRoom WHERE creator = ?`
+	var count int
+	if err := us.db.QueryRowContext(ctx, q, screenName.String()).Scan(&count); err != nil {
+		return 0, fmt.Errorf("ChatRoomsOwned: %w", err)
+	}
+	return count, nil
+}
+
+// CreateChatRoomWithinQuota creates chatRoom, like CreateChatRoom, unless
+// its creator already owns quota rooms, in which case it returns
+// ErrChatRoomQuotaExceeded. The count and the insert are a single
+// statement, so concurrent requests can't both take the last slot. A quota
+// of UnlimitedChatRooms creates the room unconditionally.
+func (us SQLiteUserStore) CreateChatRoomWithinQuota(ctx context.Context, chatRoom *ChatRoom, quota int) error {
+	if quota < 0 {
+		return us.CreateChatRoom(ctx, chatRoom)
+	}
+
+	createTime := time.Now().UTC()
+	q := `
+		INSERT INTO chatRoom (cookie, exchange, name, created, creator)
+		SELECT ?, ?, ?, ?, ?
+		WHERE (SELECT COUNT(*) FROM chatRoom WHERE creator = ?) < ?
+	`
+	res, err := us.db.ExecContext(ctx,
+		q,
+		chatRoom.Cookie(),
+		chatRoom.Exchange(),
+		chatRoom.Name(),
+		createTime,
+		chatRoom.Creator().String(),
+		chatRoom.Creator().String(),
+		quota,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "constraint failed") {
+			err = ErrDupChatRoom
+		}
+		return fmt.Errorf("CreateChatRoomWithinQuota: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("CreateChatRoomWithinQuota: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("CreateChatRoomWithinQuota: %w: %s owns %d rooms", ErrChatRoomQuotaExceeded,
+			chatRoom.Creator(), quota)
+	}
+	chatRoom.createTime = createTime
+	return nil
+}
+
+// CloseChatRoom deletes the private chat room identified by cookie, which
+// releases it from its creator's quota. Rooms in other exchanges are
+// persistent and are left alone, as are rooms that don't exist.
+func (us SQLiteUserStore) CloseChatRoom(ctx context.Context, cookie string) error {
+	q := `DELETE FROM chatRoom WHERE lower(cookie) = lower(?) AND exchange = ?`
+	if _, err := us.db.ExecContext(ctx, q, cookie, PrivateExchange); err != nil {
+		return fmt.Errorf("CloseChatRoom: %w", err)
+	}
+	return nil
+}
+
+// SetRoomClosedFunc sets fn to be called with the cookie of each chat room
+// whose last occupant leaves, such as to close the room with
+// SQLiteUserStore.CloseChatRoom. fn is called without locks held, after the
+// occupant is removed. It must be called before the session manager is
+// used.
+func (s *InMemoryChatSessionManager) SetRoomClosedFunc(fn func(cookie string)) {
+	s.roomClosed = fn
+}
@@ -0,0 +1,100 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestNewChatRoomQuotas(t *testing.T) {
+	_, err := NewChatRoomQuotas(map[string]int{"admin": 1})
+	assert.ErrorContains(t, err, `unknown account class "admin"`)
+
+	_, err = NewChatRoomQuotas(map[string]int{"standard": -1})
+	assert.ErrorContains(t, err, "invalid chat room quota -1")
+
+	q, err := NewChatRoomQuotas(map[string]int{"standard": 3, "guest": 0})
+	require.NoError(t, err)
+	assert.Equal(t, 3, q.Quota(NewSession()))
+
+	guest := NewSession()
+	guest.SetGuest(true)
+	assert.Equal(t, 0, q.Quota(guest))
+
+	bot := NewSession()
+	bot.SetUserInfoFlag(wire.OServiceUserFlagBot)
+	assert.Equal(t, UnlimitedChatRooms, q.Quota(bot))
+}
+
+func TestSQLiteUserStore_CreateChatRoomWithinQuota(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+	ctx := context.Background()
+	alice := NewIdentScreenName("Alice")
+	bob := NewIdentScreenName("Bob")
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	room1 := NewChatRoom("one", alice, PrivateExchange)
+	require.NoError(t, f.CreateChatRoomWithinQuota(ctx, &room1, 2))
+	assert.NotZero(t, room1.CreateTime())
+	room2 := NewChatRoom("two", alice, PublicExchange)
+	require.NoError(t, f.CreateChatRoomWithinQuota(ctx, &room2, 2))
+
+	room3 := NewChatRoom("three", alice, PrivateExchange)
+	err = f.CreateChatRoomWithinQuota(ctx, &room3, 2)
+	assert.ErrorIs(t, err, ErrChatRoomQuotaExceeded)
+	assert.Equal(t, ChatRoomQuotaErrorCode, ErrorCode(err))
+	_, err = f.ChatRoomByCookie(ctx, room3.Cookie())
+	assert.ErrorIs(t, err, ErrChatRoomNotFound)
+
+	// other users have their own quota
+	bobRoom := NewChatRoom("bob's", bob, PrivateExchange)
+	require.NoError(t, f.CreateChatRoomWithinQuota(ctx, &bobRoom, 1))
+	dup := NewChatRoom("one", bob, PrivateExchange)
+	assert.ErrorIs(t, f.CreateChatRoomWithinQuota(ctx, &dup, UnlimitedChatRooms), ErrDupChatRoom)
+
+	// closing a private room releases it from the quota, which is read
+	// from the database after a restart
+	require.NoError(t, f.CloseChatRoom(ctx, room1.Cookie()))
+	require.NoError(t, f.pool.Close())
+	f, err = NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	owned, err := f.ChatRoomsOwned(ctx, alice)
+	require.NoError(t, err)
+	assert.Equal(t, 1, owned)
+	require.NoError(t, f.CreateChatRoomWithinQuota(ctx, &room3, 2))
+
+	// public rooms aren't closed
+	require.NoError(t, f.CloseChatRoom(ctx, room2.Cookie()))
+	owned, err = f.ChatRoomsOwned(ctx, alice)
+	require.NoError(t, err)
+	assert.Equal(t, 2, owned)
+}
+
+func TestInMemoryChatSessionManager_SetRoomClosedFunc(t *testing.T) {
+	ctx := context.Background()
+	m := NewInMemoryChatSessionManager(slog.Default())
+	var closed []string
+	m.SetRoomClosedFunc(func(cookie string) {
+		closed = append(closed, cookie)
+	})
+
+	alice, err := m.AddSession(ctx, "4-0-room", "Alice")
+	require.NoError(t, err)
+	bob, err := m.AddSession(ctx, "4-0-room", "Bob")
+	require.NoError(t, err)
+
+	m.RemoveSession(alice)
+	assert.Empty(t, closed)
+	m.RemoveSession(bob)
+	assert.Equal(t, []string{"4-0-room"}, closed)
+}
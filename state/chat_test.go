@@ -12,7 +12,7 @@ func TestChatRoom_TLVList(t *testing.T) {
 	have := room.TLVList()
 	want := []wire.TLV{
 		wire.NewTLVBE(wire.ChatRoomTLVFlags, uint16(15)),
-		wire.NewTLVBE(wire.ChatRoomTLVCreateTime, uint32(room.createTime.Unix())),
+		wire.NewTLVBE(wire.ChatRoomTLVCreateTime, wire.EpochSeconds(room.createTime)),
 		wire.NewTLVBE(wire.ChatRoomTLVMaxMsgLen, uint16(1024)),
 		wire.NewTLVBE(wire.ChatRoomTLVMaxOccupancy, uint16(100)),
 		wire.NewTLVBE(wire.ChatRoomTLVNavCreatePerms, uint8(2)),
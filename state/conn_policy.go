@@ -0,0 +1,150 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrConnectionRejected indicates that a connection was refused by the
+// ConnectionPolicy.
+var ErrConnectionRejected = errors.New("connection rejected by policy")
+
+// GeoIPResolver looks up the country an IP address is located in.
+type GeoIPResolver interface {
+	// Country returns the ISO 3166-1 alpha-2 code of the country addr is
+	// located in, or an empty string if it's unknown.
+	Country(addr netip.Addr) (string, error)
+}
+
+// ConnectionPolicyStats counts the connections refused by a
+// ConnectionPolicy, by rule.
+type ConnectionPolicyStats struct {
+	// Denied is the number of connections from addresses in the deny list.
+	Denied int64
+	// NotAllowed is the number of connections from addresses missing from
+	// a non-empty allow list.
+	NotAllowed int64
+	// CountryBlocked is the number of connections from blocked countries.
+	CountryBlocked int64
+}
+
+// ConnectionPolicy decides which remote addresses may connect to the
+// server's listeners. Rules are evaluated in this order:
+//  1. addresses in the deny list are refused
+//  2. addresses in the allow list are accepted
+//  3. if the allow list isn't empty, all other addresses are refused
+//  4. addresses located in a blocked country are refused
+//
+// A ConnectionPolicy is safe for concurrent use by multiple goroutines.
+type ConnectionPolicy struct {
+	allow            []netip.Prefix
+	deny             []netip.Prefix
+	blockedCountries []string
+	geoIP            GeoIPResolver
+	logger           *slog.Logger
+	denied           atomic.Int64
+	notAllowed       atomic.Int64
+	countryBlocked   atomic.Int64
+}
+
+// NewConnectionPolicy creates a new instance of ConnectionPolicy. geoIP may
+// be nil, in which case countries are never blocked.
+func NewConnectionPolicy(allow, deny []netip.Prefix, blockedCountries []string, geoIP GeoIPResolver, logger *slog.Logger) *ConnectionPolicy {
+	countries := make([]string, 0, len(blockedCountries))
+	for _, c := range blockedCountries {
+		countries = append(countries, strings.ToUpper(c))
+	}
+	return &ConnectionPolicy{
+		allow:            allow,
+		deny:             deny,
+		blockedCountries: countries,
+		geoIP:            geoIP,
+		logger:           logger,
+	}
+}
+
+// Check returns ErrConnectionRejected if addr may not connect. Addresses
+// whose country can't be resolved are accepted.
+func (p *ConnectionPolicy) Check(addr netip.Addr) error {
+	addr = addr.Unmap()
+	contains := func(prefix netip.Prefix) bool { return prefix.Contains(addr) }
+
+	if slices.ContainsFunc(p.deny, contains) {
+		p.denied.Add(1)
+		return fmt.Errorf("%w: %s is in the deny list", ErrConnectionRejected, addr)
+	}
+	if slices.ContainsFunc(p.allow, contains) {
+		return nil
+	}
+	if len(p.allow) > 0 {
+		p.notAllowed.Add(1)
+		return fmt.Errorf("%w: %s is not in the allow list", ErrConnectionRejected, addr)
+	}
+
+	if p.geoIP == nil || len(p.blockedCountries) == 0 {
+		return nil
+	}
+	country, err := p.geoIP.Country(addr)
+	if err != nil {
+		p.logger.Warn("unable to resolve connection country, allowing connection", "addr", addr, "err", err)
+		return nil
+	}
+	if slices.Contains(p.blockedCountries, strings.ToUpper(country)) {
+		p.countryBlocked.Add(1)
+		return fmt.Errorf("%w: %s is located in blocked country %s", ErrConnectionRejected, addr, country)
+	}
+
+	return nil
+}
+
+// Stats returns the number of connections refused so far.
+func (p *ConnectionPolicy) Stats() ConnectionPolicyStats {
+	return ConnectionPolicyStats{
+		Denied:         p.denied.Load(),
+		NotAllowed:     p.notAllowed.Load(),
+		CountryBlocked: p.countryBlocked.Load(),
+	}
+}
+
+// Listener wraps ln so that connections refused by the policy are closed as
+// soon as they're accepted, before any data is exchanged. Every listener
+// the server opens should be wrapped.
+func (p *ConnectionPolicy) Listener(ln net.Listener) net.Listener {
+	return policyListener{Listener: ln, policy: p}
+}
+
+type policyListener struct {
+	net.Listener
+	policy *ConnectionPolicy
+}
+
+// Accept waits for and returns the next connection allowed by the policy.
+func (l policyListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		remote, err := netip.ParseAddrPort(conn.RemoteAddr().String())
+		if err != nil {
+			// not an IP connection, such as a unix socket
+			return conn, nil
+		}
+
+		if err := l.policy.Check(remote.Addr()); err != nil {
+			l.policy.logger.DebugContext(context.Background(), "refused connection", "listener", l.Addr(), "err", err)
+			_ = conn.Close()
+			continue
+		}
+
+		return conn, nil
+	}
+}
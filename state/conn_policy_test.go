@@ -0,0 +1,149 @@
+package state
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeGeoIP map[netip.Addr]string
+
+func (f fakeGeoIP) Country(addr netip.Addr) (string, error) {
+	if addr == netip.MustParseAddr("203.0.113.99") {
+		return "", errors.New("lookup failed")
+	}
+	return f[addr], nil
+}
+
+func TestConnectionPolicy_Check(t *testing.T) {
+	geoIP := fakeGeoIP{
+		netip.MustParseAddr("198.51.100.1"): "us",
+		netip.MustParseAddr("198.51.100.2"): "DE",
+		netip.MustParseAddr("192.168.1.5"):  "US",
+	}
+
+	tests := []struct {
+		name      string
+		allow     []netip.Prefix
+		deny      []netip.Prefix
+		countries []string
+		addr      string
+		wantErr   bool
+		wantStats ConnectionPolicyStats
+	}{
+		{
+			name: "no rules",
+			addr: "198.51.100.1",
+		},
+		{
+			name:      "denied",
+			deny:      []netip.Prefix{netip.MustParsePrefix("198.51.100.0/24")},
+			addr:      "198.51.100.1",
+			wantErr:   true,
+			wantStats: ConnectionPolicyStats{Denied: 1},
+		},
+		{
+			name:      "deny list takes precedence over allow list",
+			allow:     []netip.Prefix{netip.MustParsePrefix("198.51.100.0/24")},
+			deny:      []netip.Prefix{netip.MustParsePrefix("198.51.100.1/32")},
+			addr:      "198.51.100.1",
+			wantErr:   true,
+			wantStats: ConnectionPolicyStats{Denied: 1},
+		},
+		{
+			name:      "missing from allow list",
+			allow:     []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16")},
+			addr:      "198.51.100.2",
+			wantErr:   true,
+			wantStats: ConnectionPolicyStats{NotAllowed: 1},
+		},
+		{
+			name:      "allow list exempts from country blocking",
+			allow:     []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16")},
+			countries: []string{"US"},
+			addr:      "192.168.1.5",
+		},
+		{
+			name:      "IPv4-mapped IPv6 address matches IPv4 rules",
+			deny:      []netip.Prefix{netip.MustParsePrefix("198.51.100.0/24")},
+			addr:      "::ffff:198.51.100.1",
+			wantErr:   true,
+			wantStats: ConnectionPolicyStats{Denied: 1},
+		},
+		{
+			name:      "blocked country",
+			countries: []string{"us"},
+			addr:      "198.51.100.1",
+			wantErr:   true,
+			wantStats: ConnectionPolicyStats{CountryBlocked: 1},
+		},
+		{
+			name:      "country not blocked",
+			countries: []string{"US"},
+			addr:      "198.51.100.2",
+		},
+		{
+			name:      "unknown country",
+			countries: []string{"US"},
+			addr:      "203.0.113.1",
+		},
+		{
+			name:      "country lookup failure allows connection",
+			countries: []string{"US"},
+			addr:      "203.0.113.99",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewConnectionPolicy(tt.allow, tt.deny, tt.countries, geoIP, slog.Default())
+			err := p.Check(netip.MustParseAddr(tt.addr))
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrConnectionRejected)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantStats, p.Stats())
+		})
+	}
+}
+
+func TestConnectionPolicy_Listener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	// refuse connections from the test client
+	p := NewConnectionPolicy(nil, []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}, nil, nil, slog.Default())
+	pln := p.Listener(ln)
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := pln.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// the server closes the refused connection
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+	assert.Equal(t, ConnectionPolicyStats{Denied: 1}, p.Stats())
+
+	select {
+	case <-accepted:
+		t.Fatal("refused connection was returned by Accept")
+	default:
+	}
+}
@@ -16,6 +16,14 @@ import (
 // authCookieLen is the fixed auth cookie length.
 const authCookieLen = 256
 
+var (
+	// ErrCookieInvalid indicates that an HMAC cookie wasn't issued by this
+	// server or was tampered with.
+	ErrCookieInvalid = errors.New("invalid HMAC cookie")
+	// ErrCookieExpired indicates that an HMAC cookie has expired.
+	ErrCookieExpired = errors.New("HMAC cookie expired")
+)
+
 // ServerCookie represents a token containing client metadata passed to
 // the BOS service upon connection.
 type ServerCookie struct {
@@ -46,7 +54,7 @@ func (c HMACCookieBaker) Crack(data []byte) ([]byte, error) {
 		return nil, fmt.Errorf("unable to unmarshal HMAC cooie: %w", err)
 	}
 	if !hmacTok.validate(c.key) {
-		return nil, errors.New("invalid HMAC cookie")
+		return nil, ErrCookieInvalid
 	}
 
 	payload := hmacTokenPayload{}
@@ -54,9 +62,9 @@ func (c HMACCookieBaker) Crack(data []byte) ([]byte, error) {
 		return nil, fmt.Errorf("unable to unmarshal HMAC cookie payload: %w", err)
 	}
 
-	expiry := time.Unix(int64(payload.Expiry), 0)
+	expiry := wire.FromEpochSeconds(payload.Expiry)
 	if expiry.Before(time.Now()) {
-		return nil, errors.New("HMAC cookie expired")
+		return nil, ErrCookieExpired
 	}
 
 	return payload.Data, nil
@@ -64,7 +72,7 @@ func (c HMACCookieBaker) Crack(data []byte) ([]byte, error) {
 
 func (c HMACCookieBaker) Issue(data []byte) ([]byte, error) {
 	payload := hmacTokenPayload{
-		Expiry: uint32(time.Now().Add(1 * time.Minute).Unix()),
+		Expiry: wire.EpochSeconds(time.Now().Add(1 * time.Minute)),
 		Data:   data,
 	}
 	buf := &bytes.Buffer{}
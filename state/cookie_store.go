@@ -0,0 +1,203 @@
+package state
+
+import (
+	"container/list"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCookieTTL is how long cookies issued by a CookieStore may be
+	// redeemed when no TTL is given to NewCookieStore. It matches the
+	// expiry of cookies issued by HMACCookieBaker.
+	DefaultCookieTTL = time.Minute
+	// DefaultCookieLimit is the most cookies a CookieStore holds when no
+	// limit is given to NewCookieStore.
+	DefaultCookieLimit = 10_000
+	// CookieSweepInterval is how often CookieStore drops expired cookies.
+	CookieSweepInterval = 30 * time.Second
+)
+
+// storedCookie is a cookie held by CookieStore until it's redeemed.
+type storedCookie struct {
+	key     string
+	data    []byte
+	expires time.Time
+}
+
+// CookieStats counts the cookies handled by a CookieStore since it was
+// created.
+type CookieStats struct {
+	// Issued is the number of cookies issued.
+	Issued uint64 `json:"issued"`
+	// Redeemed is the number of cookies redeemed before they expired.
+	Redeemed uint64 `json:"redeemed"`
+	// Expired is the number of cookies that expired before they were
+	// redeemed.
+	Expired uint64 `json:"expired"`
+	// Evicted is the number of cookies dropped before they expired to make
+	// room for new ones.
+	Evicted uint64 `json:"evicted"`
+	// Outstanding is the number of cookies currently held.
+	Outstanding int `json:"outstanding"`
+}
+
+// CookieStore issues the login and service cookies that clients present
+// when they connect to BOS or a chat room after authenticating. Unlike
+// HMACCookieBaker, it keeps the data of each cookie server-side, so a
+// cookie can be redeemed only once. Cookies that are never redeemed, such
+// as when a client crashes between authenticating and connecting, expire
+// after a TTL and are dropped by Sweep. The store never holds more than
+// its limit; issuing a cookie when it's full evicts the oldest one. A
+// CookieStore is safe for concurrent use by multiple goroutines.
+type CookieStore struct {
+	mutex sync.Mutex
+	ttl   time.Duration
+	limit int
+	// cookieLen is the length of the random cookies issued.
+	cookieLen int
+	cookies   map[string]*list.Element
+	// order holds the cookies from oldest to newest. Since every cookie
+	// has the same TTL, it's also the order in which they expire.
+	order *list.List
+	stats CookieStats
+	nowFn func() time.Time
+}
+
+// NewCookieStore creates a new instance of CookieStore. A ttl of 0 uses
+// DefaultCookieTTL and a limit of 0 uses DefaultCookieLimit.
+func NewCookieStore(ttl time.Duration, limit int) *CookieStore {
+	if ttl == 0 {
+		ttl = DefaultCookieTTL
+	}
+	if limit == 0 {
+		limit = DefaultCookieLimit
+	}
+	return newCookieStore(ttl, limit, authCookieLen)
+}
+
+// newCookieStore creates a CookieStore that issues cookies of cookieLen
+// random bytes, for single-use tokens other than login cookies.
+func newCookieStore(ttl time.Duration, limit, cookieLen int) *CookieStore {
+	return &CookieStore{
+		ttl:       ttl,
+		limit:     limit,
+		cookieLen: cookieLen,
+		cookies:   make(map[string]*list.Element),
+		order:     list.New(),
+		nowFn:     time.Now,
+	}
+}
+
+// Issue stores data and returns a random cookie that redeems it. Cookies
+// of a store created by NewCookieStore are exactly 256 bytes long, like
+// the cookies of HMACCookieBaker.
+func (c *CookieStore) Issue(data []byte) ([]byte, error) {
+	cookie := make([]byte, c.cookieLen)
+	if _, err := io.ReadFull(rand.Reader, cookie); err != nil {
+		return nil, fmt.Errorf("cannot generate random cookie: %w", err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for c.order.Len() >= c.limit {
+		c.remove(c.order.Front())
+		c.stats.Evicted++
+	}
+	key := string(cookie)
+	c.cookies[key] = c.order.PushBack(storedCookie{
+		key:     key,
+		data:    data,
+		expires: c.nowFn().Add(c.ttl),
+	})
+	c.stats.Issued++
+
+	return cookie, nil
+}
+
+// Crack redeems cookie and returns the data it was issued for. A cookie can
+// be redeemed only once. It returns ErrCookieInvalid if the cookie wasn't
+// issued by this store, was already redeemed or was evicted, and
+// ErrCookieExpired if it's past its TTL.
+func (c *CookieStore) Crack(cookie []byte) ([]byte, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, ok := c.cookies[string(cookie)]
+	if !ok {
+		return nil, ErrCookieInvalid
+	}
+	stored := c.remove(elem)
+	if !c.nowFn().Before(stored.expires) {
+		c.stats.Expired++
+		return nil, ErrCookieExpired
+	}
+	c.stats.Redeemed++
+	return stored.data, nil
+}
+
+// Sweep drops the cookies that expired before they were redeemed and
+// returns how many it dropped.
+func (c *CookieStore) Sweep() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.nowFn()
+	n := 0
+	for elem := c.order.Front(); elem != nil; elem = c.order.Front() {
+		if now.Before(elem.Value.(storedCookie).expires) {
+			break
+		}
+		c.remove(elem)
+		n++
+	}
+	c.stats.Expired += uint64(n)
+	return n
+}
+
+// Schedule adds the sweep to s as the "cookie_sweep" job, to run every
+// CookieSweepInterval.
+func (c *CookieStore) Schedule(s *Scheduler) error {
+	return s.Add("cookie_sweep", CookieSweepInterval, CookieSweepInterval/10, func(ctx context.Context) error {
+		c.Sweep()
+		return nil
+	})
+}
+
+// Stats returns the number of cookies handled by the store.
+func (c *CookieStore) Stats() CookieStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stats := c.stats
+	stats.Outstanding = c.order.Len()
+	return stats
+}
+
+// remove drops the cookie held in elem and returns it. The caller must hold
+// the mutex.
+func (c *CookieStore) remove(elem *list.Element) storedCookie {
+	stored := c.order.Remove(elem).(storedCookie)
+	delete(c.cookies, stored.key)
+	return stored
+}
+
+// Handler serves the cookie counters in the Prometheus text exposition
+// format, for scraping from the management API's /metrics endpoint.
+func (c *CookieStore) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := c.Stats()
+		m := newMetricsWriter(w)
+		m.counter("icq_login_cookies_issued_total", "Login and service cookies issued.", stats.Issued)
+		m.counter("icq_login_cookies_redeemed_total", "Login and service cookies redeemed before they expired.", stats.Redeemed)
+		m.counter("icq_login_cookies_expired_total", "Login and service cookies that expired before they were redeemed.", stats.Expired)
+		m.counter("icq_login_cookies_evicted_total", "Login and service cookies dropped to stay within the cookie limit.", stats.Evicted)
+		m.gauge("icq_login_cookies_outstanding", "Login and service cookies issued but not yet redeemed or expired.", stats.Outstanding)
+	}
+}
@@ -0,0 +1,115 @@
+package state
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCookieStore(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	newStore := func(limit int) *CookieStore {
+		c := NewCookieStore(time.Minute, limit)
+		c.nowFn = func() time.Time { return now }
+		return c
+	}
+
+	t.Run("cookie is redeemed once", func(t *testing.T) {
+		c := newStore(0)
+		cookie, err := c.Issue([]byte("alice"))
+		require.NoError(t, err)
+		assert.Len(t, cookie, authCookieLen)
+
+		data, err := c.Crack(cookie)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("alice"), data)
+
+		_, err = c.Crack(cookie)
+		assert.ErrorIs(t, err, ErrCookieInvalid)
+		_, err = c.Crack([]byte("forged"))
+		assert.ErrorIs(t, err, ErrCookieInvalid)
+
+		assert.Equal(t, CookieStats{Issued: 1, Redeemed: 1}, c.Stats())
+	})
+
+	t.Run("expired cookie can't be redeemed", func(t *testing.T) {
+		c := newStore(0)
+		cookie, err := c.Issue([]byte("alice"))
+		require.NoError(t, err)
+
+		c.nowFn = func() time.Time { return now.Add(time.Minute) }
+		_, err = c.Crack(cookie)
+		assert.ErrorIs(t, err, ErrCookieExpired)
+		assert.Equal(t, CookieStats{Issued: 1, Expired: 1}, c.Stats())
+	})
+
+	t.Run("sweep drops expired cookies", func(t *testing.T) {
+		c := newStore(0)
+		_, err := c.Issue([]byte("alice"))
+		require.NoError(t, err)
+		c.nowFn = func() time.Time { return now.Add(30 * time.Second) }
+		fresh, err := c.Issue([]byte("bob"))
+		require.NoError(t, err)
+
+		c.nowFn = func() time.Time { return now.Add(time.Minute) }
+		assert.Equal(t, 1, c.Sweep())
+		assert.Equal(t, 0, c.Sweep())
+		assert.Equal(t, CookieStats{Issued: 2, Expired: 1, Outstanding: 1}, c.Stats())
+
+		data, err := c.Crack(fresh)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("bob"), data)
+	})
+
+	t.Run("oldest cookie is evicted at the limit", func(t *testing.T) {
+		c := newStore(2)
+		var cookies [][]byte
+		for _, data := range []string{"alice", "bob", "carol"} {
+			cookie, err := c.Issue([]byte(data))
+			require.NoError(t, err)
+			cookies = append(cookies, cookie)
+		}
+		assert.Equal(t, CookieStats{Issued: 3, Evicted: 1, Outstanding: 2}, c.Stats())
+
+		_, err := c.Crack(cookies[0])
+		assert.ErrorIs(t, err, ErrCookieInvalid)
+		data, err := c.Crack(cookies[2])
+		require.NoError(t, err)
+		assert.Equal(t, []byte("carol"), data)
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		c := newStore(0)
+		cookie, err := c.Issue([]byte("alice"))
+		require.NoError(t, err)
+		_, err = c.Issue([]byte("bob"))
+		require.NoError(t, err)
+		_, err = c.Crack(cookie)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		c.Handler()(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+		assert.Equal(t, `# HELP icq_login_cookies_issued_total Login and service cookies issued.
+# TYPE icq_login_cookies_issued_total counter
+icq_login_cookies_issued_total 2
+# HELP icq_login_cookies_redeemed_total Login and service cookies redeemed before they expired.
+# TYPE icq_login_cookies_redeemed_total counter
+icq_login_cookies_redeemed_total 1
+# HELP icq_login_cookies_expired_total Login and service cookies that expired before they were redeemed.
+# TYPE icq_login_cookies_expired_total counter
+icq_login_cookies_expired_total 0
+# HELP icq_login_cookies_evicted_total Login and service cookies dropped to stay within the cookie limit.
+# TYPE icq_login_cookies_evicted_total counter
+icq_login_cookies_evicted_total 0
+# HELP icq_login_cookies_outstanding Login and service cookies issued but not yet redeemed or expired.
+# TYPE icq_login_cookies_outstanding gauge
+icq_login_cookies_outstanding 1
+`, rec.Body.String())
+	})
+}
@@ -0,0 +1,151 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+var (
+	// ErrDatabaseCorrupt indicates that SQLite's integrity check found
+	// damage in the database file, or that the file is not a database.
+	ErrDatabaseCorrupt = errors.New("database is corrupt")
+	// ErrSchemaIncomplete indicates that a table created by the migrations
+	// is missing from the database, although its schema version says the
+	// table should exist.
+	ErrSchemaIncomplete = errors.New("database schema is incomplete")
+)
+
+// schemaObject is a table or index of the database schema.
+type schemaObject struct {
+	kind string
+	name string
+	sql  string
+}
+
+// referenceSchema returns the tables and indexes created by the embedded
+// migrations, by applying them to an in-memory database once per process.
+var referenceSchema = sync.OnceValues(func() ([]schemaObject, error) {
+	db, err := sql.Open("sqlite", "file::memory:?_pragma=foreign_keys=on")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	// each connection to :memory: is a separate database
+	db.SetMaxOpenConns(1)
+
+	m, _, err := newMigrate(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return nil, err
+	}
+	return schemaObjects(context.Background(), db)
+})
+
+// schemaObjects lists the tables and the explicitly created indexes of db.
+func schemaObjects(ctx context.Context, db sqlConn) ([]schemaObject, error) {
+	q := `
+		SELECT type, name, COALESCE(sql, '')
+		FROM sqlite_master
+		WHERE type IN ('table', 'index') AND name NOT LIKE 'sqlite_%'
+		ORDER BY type DESC, name
+	`
+	rows, err := db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []schemaObject
+	for rows.Next() {
+		var o schemaObject
+		if err := rows.Scan(&o.kind, &o.name, &o.sql); err != nil {
+			return nil, err
+		}
+		objects = append(objects, o)
+	}
+	return objects, rows.Err()
+}
+
+// quickCheck runs SQLite's PRAGMA quick_check. It returns
+// ErrDatabaseCorrupt with the problems found, if any.
+func (us SQLiteUserStore) quickCheck(ctx context.Context) error {
+	rows, err := us.db.QueryContext(ctx, `PRAGMA quick_check`)
+	if err != nil {
+		// a file that isn't a database fails here with SQLITE_NOTADB
+		return fmt.Errorf("%w: %w", ErrDatabaseCorrupt, err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return fmt.Errorf("%w: %w", ErrDatabaseCorrupt, err)
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrDatabaseCorrupt, err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrDatabaseCorrupt, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// verifySchema checks that the database has every table and index created
+// by the migrations. Missing indexes are recreated and their names
+// returned. Missing tables can't be restored without their data, so they
+// fail the check with ErrSchemaIncomplete.
+func (us SQLiteUserStore) verifySchema(ctx context.Context) ([]string, error) {
+	want, err := referenceSchema()
+	if err != nil {
+		return nil, fmt.Errorf("unable to build reference schema: %w", err)
+	}
+	have, err := schemaObjects(ctx, us.db)
+	if err != nil {
+		return nil, err
+	}
+	exists := make(map[string]bool, len(have))
+	for _, o := range have {
+		exists[o.kind+" "+o.name] = true
+	}
+
+	var missingTables []string
+	for _, o := range want {
+		if o.kind == "table" && !exists[o.kind+" "+o.name] {
+			missingTables = append(missingTables, o.name)
+		}
+	}
+	if len(missingTables) > 0 {
+		return nil, fmt.Errorf("%w: missing tables %s", ErrSchemaIncomplete, strings.Join(missingTables, ", "))
+	}
+
+	var repaired []string
+	for _, o := range want {
+		if o.kind != "index" || exists[o.kind+" "+o.name] {
+			continue
+		}
+		if _, err := us.db.ExecContext(ctx, o.sql); err != nil {
+			return nil, fmt.Errorf("unable to recreate index %s: %w", o.name, err)
+		}
+		repaired = append(repaired, o.name)
+	}
+	return repaired, nil
+}
+
+// RepairedIndexes returns the indexes that were missing from the database
+// and recreated when the store was opened.
+func (us SQLiteUserStore) RepairedIndexes() []string {
+	return us.repairedIndexes
+}
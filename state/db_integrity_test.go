@@ -0,0 +1,67 @@
+package state
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSQLiteUserStore_Integrity(t *testing.T) {
+	t.Parallel()
+
+	t.Run("healthy database", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		store, err := NewSQLiteUserStore(testFile)
+		require.NoError(t, err)
+		assert.Empty(t, store.RepairedIndexes())
+	})
+
+	t.Run("not a database", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		garbage := make([]byte, 4096)
+		for i := range garbage {
+			garbage[i] = byte(i)
+		}
+		require.NoError(t, os.WriteFile(testFile, garbage, 0o600))
+
+		_, err := NewSQLiteUserStore(testFile)
+		assert.ErrorIs(t, err, ErrDatabaseCorrupt)
+	})
+
+	t.Run("missing index is recreated", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		store, err := NewSQLiteUserStore(testFile)
+		require.NoError(t, err)
+		_, err = store.pool.Exec(`DROP INDEX idx_screenNameAlias_primaryScreenName`)
+		require.NoError(t, err)
+		require.NoError(t, store.pool.Close())
+
+		store, err = NewSQLiteUserStore(testFile)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"idx_screenNameAlias_primaryScreenName"}, store.RepairedIndexes())
+
+		var n int
+		err = store.pool.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'idx_screenNameAlias_primaryScreenName'`).Scan(&n)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+	})
+
+	t.Run("missing table", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		store, err := NewSQLiteUserStore(testFile)
+		require.NoError(t, err)
+		_, err = store.pool.Exec(`DROP TABLE screenNameAlias`)
+		require.NoError(t, err)
+		require.NoError(t, store.pool.Close())
+
+		_, err = NewSQLiteUserStore(testFile)
+		assert.ErrorIs(t, err, ErrSchemaIncomplete)
+		assert.ErrorContains(t, err, "screenNameAlias")
+	})
+}
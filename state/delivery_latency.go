@@ -0,0 +1,183 @@
+package state
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DeliveryPath is the route an IM takes to its recipient.
+type DeliveryPath uint8
+
+const (
+	// DeliveryPathOnline is delivery to a signed-on recipient's session
+	// queue.
+	DeliveryPathOnline DeliveryPath = iota
+	// DeliveryPathOffline is delivery to the offline message store.
+	DeliveryPathOffline
+)
+
+// deliveryPaths lists the paths in the order they're exported.
+var deliveryPaths = []DeliveryPath{DeliveryPathOnline, DeliveryPathOffline}
+
+// String returns the metric label value of the path.
+func (p DeliveryPath) String() string {
+	switch p {
+	case DeliveryPathOnline:
+		return "online"
+	case DeliveryPathOffline:
+		return "offline"
+	default:
+		return "unknown"
+	}
+}
+
+// DefaultDeliveryLatencyBuckets are the upper bounds of the latency
+// histogram buckets, from half a millisecond for an idle server to a few
+// seconds for one that's badly overloaded.
+var DefaultDeliveryLatencyBuckets = []time.Duration{
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+}
+
+// deliveryLatencyMetric is the name of the exported histogram.
+const deliveryLatencyMetric = "icq_message_delivery_latency_seconds"
+
+// latencyHistogram is the histogram of one delivery path. counts holds the
+// number of observations per bucket, with the last entry counting the
+// observations past the largest bound.
+type latencyHistogram struct {
+	counts []uint64
+	sum    time.Duration
+	total  uint64
+}
+
+// DeliveryLatency measures the time from receiving an IM from its sender
+// to enqueueing it on the recipient's session or writing it to the offline
+// message store, as a histogram per DeliveryPath. It's exported in the
+// Prometheus text format so that operators can see how delivery holds up
+// under load. See InMemorySessionManager.SetDeliveryLatency and
+// SQLiteUserStore.SetDeliveryLatency. A nil DeliveryLatency records
+// nothing. A DeliveryLatency is safe for concurrent use by multiple
+// goroutines.
+type DeliveryLatency struct {
+	mutex   sync.Mutex
+	buckets []time.Duration
+	paths   map[DeliveryPath]*latencyHistogram
+	nowFn   func() time.Time
+}
+
+// NewDeliveryLatency creates a new instance of DeliveryLatency with the
+// given bucket bounds, which must be strictly increasing. Empty buckets use
+// DefaultDeliveryLatencyBuckets.
+func NewDeliveryLatency(buckets []time.Duration) (*DeliveryLatency, error) {
+	if len(buckets) == 0 {
+		buckets = DefaultDeliveryLatencyBuckets
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			return nil, fmt.Errorf("delivery latency bucket %s doesn't increase on %s", buckets[i], buckets[i-1])
+		}
+	}
+	l := &DeliveryLatency{
+		buckets: buckets,
+		paths:   make(map[DeliveryPath]*latencyHistogram, len(deliveryPaths)),
+		nowFn:   time.Now,
+	}
+	for _, path := range deliveryPaths {
+		l.paths[path] = &latencyHistogram{counts: make([]uint64, len(buckets)+1)}
+	}
+	return l, nil
+}
+
+// Observe records a delivery on path that took d.
+func (l *DeliveryLatency) Observe(path DeliveryPath, d time.Duration) {
+	if l == nil {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	h, ok := l.paths[path]
+	if !ok {
+		return
+	}
+	i := 0
+	for i < len(l.buckets) && d > l.buckets[i] {
+		i++
+	}
+	h.counts[i]++
+	h.sum += d
+	h.total++
+}
+
+// ObserveSince records a delivery on path of an IM received at received,
+// which must be the time the IM was read from its sender's connection, so
+// that both paths measure from the same starting point. A zero received
+// records nothing.
+func (l *DeliveryLatency) ObserveSince(path DeliveryPath, received time.Time) {
+	if l == nil || received.IsZero() {
+		return
+	}
+	l.Observe(path, l.nowFn().Sub(received))
+}
+
+// SetDeliveryLatency sets where DeliverToScreenName and DeliverICBM record
+// the time from when a message was received from its sender until it's
+// enqueued on the recipient's sessions, on DeliveryPathOnline. It must be
+// called before the session manager is used.
+func (s *InMemorySessionManager) SetDeliveryLatency(latency *DeliveryLatency) {
+	s.latency = latency
+}
+
+// SetDeliveryLatency sets where SaveMessage records the time from when an
+// offline message was received from its sender until it's stored, on
+// DeliveryPathOffline. Callers must set OfflineMessage.Sent to the same
+// receipt time they passed to DeliverICBM or DeliverToScreenName. It must
+// be called before the store is used.
+func (us *SQLiteUserStore) SetDeliveryLatency(latency *DeliveryLatency) {
+	us.latency = latency
+}
+
+// Handler serves the histograms in the Prometheus text exposition format,
+// for scraping from the management API's /metrics endpoint.
+func (l *DeliveryLatency) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// copy the histograms so that a slow scraper doesn't hold up
+		// deliveries
+		l.mutex.Lock()
+		snapshot := make(map[DeliveryPath]latencyHistogram, len(l.paths))
+		for path, h := range l.paths {
+			snapshot[path] = latencyHistogram{counts: slices.Clone(h.counts), sum: h.sum, total: h.total}
+		}
+		l.mutex.Unlock()
+
+		m := newMetricsWriter(w)
+		m.family(deliveryLatencyMetric, "Time from receiving an IM to enqueueing it for the recipient or storing it offline.", "histogram")
+		for _, path := range deliveryPaths {
+			h := snapshot[path]
+			var cumulative uint64
+			for i, bound := range l.buckets {
+				cumulative += h.counts[i]
+				m.sample(deliveryLatencyMetric+"_bucket", cumulative, "path", path.String(), "le", strconv.FormatFloat(bound.Seconds(), 'g', -1, 64))
+			}
+			m.sample(deliveryLatencyMetric+"_bucket", h.total, "path", path.String(), "le", "+Inf")
+			m.sample(deliveryLatencyMetric+"_sum", h.sum.Seconds(), "path", path.String())
+			m.sample(deliveryLatencyMetric+"_count", h.total, "path", path.String())
+		}
+	}
+}
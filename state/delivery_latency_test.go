@@ -0,0 +1,106 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestDeliveryLatency(t *testing.T) {
+	l, err := NewDeliveryLatency([]time.Duration{time.Millisecond, 10 * time.Millisecond})
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l.nowFn = func() time.Time { return now }
+
+	l.Observe(DeliveryPathOnline, 500*time.Microsecond)
+	l.Observe(DeliveryPathOnline, time.Millisecond)
+	l.ObserveSince(DeliveryPathOnline, now.Add(-5*time.Millisecond))
+	l.ObserveSince(DeliveryPathOffline, now.Add(-time.Second))
+
+	rec := httptest.NewRecorder()
+	l.Handler()(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+	assert.Equal(t, `# HELP icq_message_delivery_latency_seconds Time from receiving an IM to enqueueing it for the recipient or storing it offline.
+# TYPE icq_message_delivery_latency_seconds histogram
+icq_message_delivery_latency_seconds_bucket{path="online",le="0.001"} 2
+icq_message_delivery_latency_seconds_bucket{path="online",le="0.01"} 3
+icq_message_delivery_latency_seconds_bucket{path="online",le="+Inf"} 3
+icq_message_delivery_latency_seconds_sum{path="online"} 0.0065
+icq_message_delivery_latency_seconds_count{path="online"} 3
+icq_message_delivery_latency_seconds_bucket{path="offline",le="0.001"} 0
+icq_message_delivery_latency_seconds_bucket{path="offline",le="0.01"} 0
+icq_message_delivery_latency_seconds_bucket{path="offline",le="+Inf"} 1
+icq_message_delivery_latency_seconds_sum{path="offline"} 1
+icq_message_delivery_latency_seconds_count{path="offline"} 1
+`, rec.Body.String())
+}
+
+func TestNewDeliveryLatency_DefaultBuckets(t *testing.T) {
+	l, err := NewDeliveryLatency(nil)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultDeliveryLatencyBuckets, l.buckets)
+}
+
+func TestNewDeliveryLatency_BucketsNotIncreasing(t *testing.T) {
+	for _, buckets := range [][]time.Duration{
+		{10 * time.Millisecond, time.Millisecond},
+		{time.Millisecond, time.Millisecond},
+	} {
+		_, err := NewDeliveryLatency(buckets)
+		assert.Error(t, err, buckets)
+	}
+}
+
+func TestDeliveryLatency_Delivery(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewDeliveryLatency(nil)
+	require.NoError(t, err)
+
+	sm := NewInMemorySessionManager(slog.Default())
+	sm.SetDeliveryLatency(l)
+	alice, err := sm.AddSession(ctx, "alice")
+	require.NoError(t, err)
+	alice.SetSignonComplete()
+	bob, err := sm.AddSession(ctx, "bob")
+	require.NoError(t, err)
+	bob.SetSignonComplete()
+
+	state, _, _ := sendIM(sm, alice, newAckRequestIM(1, "bob"))
+	require.Equal(t, DeliveryEnqueued, state)
+	// duplicates and messages to offline users aren't counted
+	sendIM(sm, alice, newAckRequestIM(1, "bob"))
+	sendIM(sm, alice, newAckRequestIM(2, "carol"))
+	// latency is measured from when the caller received the message
+	assert.Equal(t, DeliveryEnqueued, sm.DeliverToScreenName(ctx, bob.IdentScreenName(), wire.SNACMessage{}, time.Now().Add(-time.Millisecond)))
+	assert.Equal(t, uint64(2), l.paths[DeliveryPathOnline].total)
+	assert.GreaterOrEqual(t, l.paths[DeliveryPathOnline].sum, time.Millisecond)
+	// a message without a receipt time isn't counted
+	assert.Equal(t, DeliveryEnqueued, sm.DeliverToScreenName(ctx, bob.IdentScreenName(), wire.SNACMessage{}, time.Time{}))
+	assert.Equal(t, uint64(2), l.paths[DeliveryPathOnline].total)
+
+	store, err := NewSQLiteUserStore(newTestDBPath(t))
+	require.NoError(t, err)
+	store.SetDeliveryLatency(l)
+	for _, sn := range []DisplayScreenName{"alice", "bob"} {
+		u, err := NewStubUser(sn)
+		require.NoError(t, err)
+		require.NoError(t, store.InsertUser(ctx, u))
+	}
+	_, err = store.SaveMessage(ctx, OfflineMessage{
+		Sent:      time.Now().Add(-time.Millisecond),
+		Sender:    alice.IdentScreenName(),
+		Recipient: bob.IdentScreenName(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), l.paths[DeliveryPathOffline].total)
+	assert.GreaterOrEqual(t, l.paths[DeliveryPathOffline].sum, time.Millisecond)
+}
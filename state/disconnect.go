@@ -0,0 +1,93 @@
+package state
+
+import "github.com/pchchv/go-icq/wire"
+
+// DisconnectReason records why a session was closed.
+type DisconnectReason uint8
+
+const (
+	// DisconnectNone indicates the session hasn't been closed.
+	DisconnectNone DisconnectReason = iota
+	// DisconnectSignoff indicates the client signed off or dropped its
+	// connection.
+	DisconnectSignoff
+	// DisconnectNewLogin indicates the session was replaced by a new login
+	// for the same screen name.
+	DisconnectNewLogin
+	// DisconnectSuspended indicates an admin suspended the account.
+	DisconnectSuspended
+	// DisconnectAccountDeleted indicates the account was deleted.
+	DisconnectAccountDeleted
+	// DisconnectKicked indicates an admin kicked the user off the server.
+	DisconnectKicked
+	// DisconnectSlowConsumer indicates the client stopped reading messages
+	// until its queue filled up.
+	DisconnectSlowConsumer
+	// DisconnectPasswordReset indicates the account's password was reset,
+	// which signs off sessions authenticated with the old password.
+	DisconnectPasswordReset
+	// DisconnectParentalControls indicates the account's allowed hours
+	// ended.
+	DisconnectParentalControls
+	// DisconnectRateLimited indicates the client sent SNACs fast enough to
+	// reach a rate class's disconnect level.
+	DisconnectRateLimited
+)
+
+// String returns a human-readable name for the reason, suitable for logs.
+func (r DisconnectReason) String() string {
+	switch r {
+	case DisconnectNone:
+		return "none"
+	case DisconnectSignoff:
+		return "signoff"
+	case DisconnectNewLogin:
+		return "new login"
+	case DisconnectSuspended:
+		return "suspended"
+	case DisconnectAccountDeleted:
+		return "account deleted"
+	case DisconnectKicked:
+		return "kicked"
+	case DisconnectSlowConsumer:
+		return "slow consumer"
+	case DisconnectPasswordReset:
+		return "password reset"
+	case DisconnectParentalControls:
+		return "parental controls"
+	case DisconnectRateLimited:
+		return "rate limited"
+	default:
+		return "unknown"
+	}
+}
+
+// DiscErrCode returns the OService disconnect error code sent to the client
+// in its sign-off FLAP frame, if the protocol defines one for the reason.
+func (r DisconnectReason) DiscErrCode() (uint8, bool) {
+	switch r {
+	case DisconnectNewLogin:
+		return wire.OServiceDiscErrNewLogin, true
+	case DisconnectAccountDeleted:
+		return wire.OServiceDiscErrAccDeleted, true
+	default:
+		return 0, false
+	}
+}
+
+// AnnounceDeparture indicates whether watchers should be sent a departure
+// notification. PresenceCoalescer.Departed drops departures for which it
+// returns false. The reason reaches the PresenceBroadcaster, but the
+// SNAC(0x03,0x0C) BuddyDeparted has no field to tell watchers why the user
+// left. Sessions replaced by a new login therefore skip the departure; the
+// new session's arrival tells watchers the user is still online without the
+// buddy appearing to flap.
+func (r DisconnectReason) AnnounceDeparture() bool {
+	return r != DisconnectNewLogin
+}
+
+// Abnormal indicates whether the session was closed because of client
+// misbehavior, in which case the server logs the session's SNAC history.
+func (r DisconnectReason) Abnormal() bool {
+	return r == DisconnectSlowConsumer || r == DisconnectRateLimited
+}
@@ -0,0 +1,92 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestSession_CloseWithReason(t *testing.T) {
+	sess := NewSession()
+	assert.Equal(t, DisconnectNone, sess.DisconnectReason())
+
+	sess.CloseWithReason(DisconnectSuspended)
+	assert.Equal(t, DisconnectSuspended, sess.DisconnectReason())
+
+	// the first reason wins
+	sess.CloseWithReason(DisconnectKicked)
+	sess.Close()
+	assert.Equal(t, DisconnectSuspended, sess.DisconnectReason())
+
+	select {
+	case <-sess.Closed():
+	default:
+		t.Fatal("expected session to be closed")
+	}
+}
+
+func TestSession_Close_RecordsSignoff(t *testing.T) {
+	sess := NewSession()
+	sess.Close()
+	assert.Equal(t, DisconnectSignoff, sess.DisconnectReason())
+}
+
+func TestInMemorySessionManager_AddSession_NewLoginReason(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+
+	first, err := sm.AddSession(context.Background(), "user-screen-name")
+	require.NoError(t, err)
+
+	go func() {
+		<-first.Closed()
+		sm.RemoveSession(first)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = sm.AddSession(ctx, "user-screen-name")
+	require.NoError(t, err)
+
+	assert.Equal(t, DisconnectNewLogin, first.DisconnectReason())
+}
+
+func TestDisconnectReason_DiscErrCode(t *testing.T) {
+	tests := []struct {
+		reason DisconnectReason
+		code   uint8
+		ok     bool
+	}{
+		{reason: DisconnectNewLogin, code: wire.OServiceDiscErrNewLogin, ok: true},
+		{reason: DisconnectAccountDeleted, code: wire.OServiceDiscErrAccDeleted, ok: true},
+		{reason: DisconnectSignoff},
+		{reason: DisconnectSuspended},
+		{reason: DisconnectSlowConsumer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reason.String(), func(t *testing.T) {
+			code, ok := tt.reason.DiscErrCode()
+			assert.Equal(t, tt.code, code)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
+
+func TestDisconnectReason_AnnounceDeparture(t *testing.T) {
+	assert.False(t, DisconnectNewLogin.AnnounceDeparture())
+	assert.True(t, DisconnectSignoff.AnnounceDeparture())
+	assert.True(t, DisconnectSuspended.AnnounceDeparture())
+	assert.True(t, DisconnectKicked.AnnounceDeparture())
+}
+
+func TestDisconnectReason_Abnormal(t *testing.T) {
+	assert.True(t, DisconnectSlowConsumer.Abnormal())
+	assert.True(t, DisconnectRateLimited.Abnormal())
+	assert.False(t, DisconnectSignoff.Abnormal())
+	assert.False(t, DisconnectKicked.Abnormal())
+}
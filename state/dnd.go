@@ -0,0 +1,100 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// Interruption is a kind of server-initiated or third-party interruption
+// that users may withhold while in a do-not-disturb status. Values may be
+// combined as a bitmask.
+type Interruption uint8
+
+const (
+	// InterruptPopup is a server popup message.
+	InterruptPopup Interruption = 1 << iota
+	// InterruptChatInvite is an invitation to join a chat room.
+	InterruptChatInvite
+	// InterruptWarning is a warning (evil) attempt against the user.
+	InterruptWarning
+)
+
+// DNDErrorCode is the error code returned to users whose chat invitation or
+// warning was suppressed because the recipient doesn't want to be disturbed.
+const DNDErrorCode = wire.ErrorCodeUserTempUnavail
+
+// ErrDoNotDisturb indicates that an interruption was suppressed because the
+// recipient is in a do-not-disturb status.
+var ErrDoNotDisturb = errors.New("recipient does not want to be disturbed")
+
+var (
+	dndStatusNames = map[string]uint32{
+		"dnd":  wire.OServiceUserStatusDND,
+		"busy": wire.OServiceUserStatusBusy,
+		"away": wire.OServiceUserStatusAway,
+		"na":   wire.OServiceUserStatusOut,
+	}
+	interruptionNames = map[string]Interruption{
+		"popups":   InterruptPopup,
+		"invites":  InterruptChatInvite,
+		"warnings": InterruptWarning,
+	}
+)
+
+// DNDPolicy maps user status flags to the interruptions suppressed while
+// the flag is set. A user with several flags set gets the union of their
+// suppressed interruptions.
+type DNDPolicy map[uint32]Interruption
+
+// DefaultDNDPolicy suppresses popups, chat invitations and warnings for DND
+// users, and popups and chat invitations for busy users.
+func DefaultDNDPolicy() DNDPolicy {
+	return DNDPolicy{
+		wire.OServiceUserStatusDND:  InterruptPopup | InterruptChatInvite | InterruptWarning,
+		wire.OServiceUserStatusBusy: InterruptPopup | InterruptChatInvite,
+	}
+}
+
+// ParseDNDPolicy builds a DNDPolicy from status names (dnd, busy, away, na)
+// mapped to interruption kind names (popups, invites, warnings).
+func ParseDNDPolicy(cfg map[string][]string) (DNDPolicy, error) {
+	policy := make(DNDPolicy, len(cfg))
+	for statusName, kinds := range cfg {
+		status, ok := dndStatusNames[statusName]
+		if !ok {
+			return nil, fmt.Errorf("unknown do-not-disturb status %q", statusName)
+		}
+		for _, kindName := range kinds {
+			kind, ok := interruptionNames[kindName]
+			if !ok {
+				return nil, fmt.Errorf("unknown interruption kind %q", kindName)
+			}
+			policy[status] |= kind
+		}
+	}
+	return policy, nil
+}
+
+// Suppressed returns the interruptions withheld from a user with the given
+// status bitmask.
+func (p DNDPolicy) Suppressed(status uint32) Interruption {
+	var suppressed Interruption
+	for flag, kinds := range p {
+		if status&flag == flag {
+			suppressed |= kinds
+		}
+	}
+	return suppressed
+}
+
+// Check returns ErrDoNotDisturb if kind is suppressed for recipient's
+// current status. Callers should drop popups silently and respond to the
+// sender of a chat invitation or warning with DNDErrorCode.
+func (p DNDPolicy) Check(recipient *Session, kind Interruption) error {
+	if p.Suppressed(recipient.UserStatusBitmask())&kind != kind {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrDoNotDisturb, recipient.IdentScreenName())
+}
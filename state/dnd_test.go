@@ -0,0 +1,98 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestDNDPolicy_Check(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  DNDPolicy
+		status  uint32
+		kind    Interruption
+		wantErr bool
+	}{
+		{
+			name:   "available user accepts everything",
+			policy: DefaultDNDPolicy(),
+			status: wire.OServiceUserStatusAvailable,
+			kind:   InterruptPopup | InterruptChatInvite | InterruptWarning,
+		},
+		{
+			name:    "DND user rejects warnings",
+			policy:  DefaultDNDPolicy(),
+			status:  wire.OServiceUserStatusDND,
+			kind:    InterruptWarning,
+			wantErr: true,
+		},
+		{
+			name:    "busy user rejects chat invitations",
+			policy:  DefaultDNDPolicy(),
+			status:  wire.OServiceUserStatusBusy,
+			kind:    InterruptChatInvite,
+			wantErr: true,
+		},
+		{
+			name:   "busy user accepts warnings by default",
+			policy: DefaultDNDPolicy(),
+			status: wire.OServiceUserStatusBusy,
+			kind:   InterruptWarning,
+		},
+		{
+			name:    "ICQ occupied status combines away and busy flags",
+			policy:  DefaultDNDPolicy(),
+			status:  wire.OServiceUserStatusAway | wire.OServiceUserStatusBusy,
+			kind:    InterruptPopup,
+			wantErr: true,
+		},
+		{
+			name:   "empty policy suppresses nothing",
+			policy: DNDPolicy{},
+			status: wire.OServiceUserStatusDND,
+			kind:   InterruptWarning,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess := NewSession()
+			sess.SetIdentScreenName(NewIdentScreenName("recipient"))
+			sess.SetUserStatusBitmask(tt.status)
+
+			err := tt.policy.Check(sess, tt.kind)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrDoNotDisturb)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParseDNDPolicy(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		policy, err := ParseDNDPolicy(map[string][]string{
+			"dnd":  {"popups", "invites", "warnings"},
+			"busy": {"warnings"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, DNDPolicy{
+			wire.OServiceUserStatusDND:  InterruptPopup | InterruptChatInvite | InterruptWarning,
+			wire.OServiceUserStatusBusy: InterruptWarning,
+		}, policy)
+	})
+
+	t.Run("unknown status", func(t *testing.T) {
+		_, err := ParseDNDPolicy(map[string][]string{"invisible": {"popups"}})
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown kind", func(t *testing.T) {
+		_, err := ParseDNDPolicy(map[string][]string{"dnd": {"ims"}})
+		assert.Error(t, err)
+	})
+}
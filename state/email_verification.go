@@ -0,0 +1,203 @@
+package state
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// EmailVerificationTTL is how long an email verification link stays valid.
+const EmailVerificationTTL = 48 * time.Hour
+
+// EmailSender delivers email, such as address verification links.
+type EmailSender interface {
+	SendEmail(ctx context.Context, to *mail.Address, subject, body string) error
+}
+
+// EmailVerificationStore persists email verification tokens.
+type EmailVerificationStore interface {
+	NewEmailVerificationToken(ctx context.Context, screenName IdentScreenName, emailAddress *mail.Address, expiresAt time.Time) (string, error)
+	ConfirmEmailAddress(ctx context.Context, token string, now time.Time) (IdentScreenName, error)
+}
+
+// NewEmailVerificationToken creates a token that verifies emailAddress for
+// the user when passed to ConfirmEmailAddress before expiresAt. It replaces
+// any token previously issued to the user.
+func (us SQLiteUserStore) NewEmailVerificationToken(ctx context.Context, screenName IdentScreenName, emailAddress *mail.Address, expiresAt time.Time) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("NewEmailVerificationToken: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	err := us.WithTx(ctx, func(tx *SQLiteUserStore) error {
+		q := `DELETE FROM emailVerification WHERE screenName = ?`
+		if _, err := tx.db.ExecContext(ctx, q, screenName.String()); err != nil {
+			return err
+		}
+		q = `
+			INSERT INTO emailVerification (token, screenName, emailAddress, expiresAt)
+			VALUES (?, ?, ?, ?)
+		`
+		_, err := tx.db.ExecContext(ctx, q, token, screenName.String(), emailAddress.Address, expiresAt.Unix())
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("NewEmailVerificationToken: %w", err)
+	}
+
+	return token, nil
+}
+
+// ConfirmEmailAddress marks the email address a token was issued for as
+// verified and returns the owner's screen name. It returns
+// ErrEmailVerificationFailed if the token is unknown or expired, or if the
+// user has changed their address since it was issued. Tokens can only be
+// used once.
+func (us SQLiteUserStore) ConfirmEmailAddress(ctx context.Context, token string, now time.Time) (IdentScreenName, error) {
+	var screenName string
+	verified := false
+	err := us.WithTx(ctx, func(tx *SQLiteUserStore) error {
+		var emailAddress string
+		var expiresAt int64
+		q := `SELECT screenName, emailAddress, expiresAt FROM emailVerification WHERE token = ?`
+		err := tx.db.QueryRowContext(ctx, q, token).Scan(&screenName, &emailAddress, &expiresAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if _, err := tx.db.ExecContext(ctx, `DELETE FROM emailVerification WHERE token = ?`, token); err != nil {
+			return err
+		}
+		if now.Unix() >= expiresAt {
+			return nil
+		}
+
+		q = `
+			UPDATE users
+			SET emailVerified = true
+			WHERE identScreenName = ?
+			  AND emailAddress = ?
+		`
+		res, err := tx.db.ExecContext(ctx, q, screenName, emailAddress)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		verified = n == 1
+		return err
+	})
+	if err != nil {
+		return IdentScreenName{}, fmt.Errorf("ConfirmEmailAddress: %w", err)
+	}
+	if !verified {
+		return IdentScreenName{}, ErrEmailVerificationFailed
+	}
+	return NewIdentScreenName(screenName), nil
+}
+
+// EmailVerifier sends email address verification links and serves the
+// endpoint they point to.
+type EmailVerifier struct {
+	store      EmailVerificationStore
+	sender     EmailSender
+	confirmURL string
+	logger     *slog.Logger
+	nowFn      func() time.Time
+}
+
+// NewEmailVerifier creates a new instance of EmailVerifier. confirmURL is
+// the public URL of ConfirmHandler; the token is appended to it as the
+// "token" query parameter.
+func NewEmailVerifier(store EmailVerificationStore, sender EmailSender, confirmURL string, logger *slog.Logger) *EmailVerifier {
+	return &EmailVerifier{
+		store:      store,
+		sender:     sender,
+		confirmURL: confirmURL,
+		logger:     logger,
+		nowFn:      time.Now,
+	}
+}
+
+// RequestVerification issues a verification token for the user's email
+// address and mails the verification link to it. It should be called
+// whenever the user sets a new address, as well as in response to
+// SNAC(0x07,0x06) AdminConfirmRequest.
+func (v *EmailVerifier) RequestVerification(ctx context.Context, screenName DisplayScreenName, emailAddress *mail.Address) error {
+	token, err := v.store.NewEmailVerificationToken(ctx, screenName.IdentScreenName(), emailAddress, v.nowFn().Add(EmailVerificationTTL))
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(v.confirmURL)
+	if err != nil {
+		return fmt.Errorf("parse confirmation URL: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	body := fmt.Sprintf("Hello %s,\n\n"+
+		"Please confirm that this is your email address by visiting the link below within %s:\n\n"+
+		"%s\n\n"+
+		"If you didn't request this, you can ignore this message.\n",
+		screenName, EmailVerificationTTL, link)
+	if err := v.sender.SendEmail(ctx, emailAddress, "Confirm your email address", body); err != nil {
+		return fmt.Errorf("send verification email: %w", err)
+	}
+
+	v.logger.InfoContext(ctx, "sent email verification link", "screen_name", screenName)
+	return nil
+}
+
+// ConfirmHandler serves the link sent by RequestVerification. It responds
+// 200 once the address is verified and 400 if the token is invalid.
+func (v *EmailVerifier) ConfirmHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+
+		screenName, err := v.store.ConfirmEmailAddress(r.Context(), r.URL.Query().Get("token"), v.nowFn())
+		switch {
+		case errors.Is(err, ErrEmailVerificationFailed):
+			http.Error(w, "This verification link is invalid or has expired.", http.StatusBadRequest)
+		case err != nil:
+			v.logger.ErrorContext(r.Context(), "unable to confirm email address", "err", err)
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		default:
+			v.logger.InfoContext(r.Context(), "email address verified", "screen_name", screenName)
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = fmt.Fprintln(w, "Your email address has been verified.")
+		}
+	}
+}
+
+// AdminEmailInfoReply builds the SNAC(0x07,0x03) AdminInfoReply answering a
+// query for the user's email address. Besides AdminTLVEmailAddress it
+// carries AdminTLVEmailVerified, set to 1 if the address is verified.
+func AdminEmailInfoReply(user User) wire.SNAC_0x07_0x03_AdminInfoReply {
+	verified := uint8(0)
+	if user.EmailVerified {
+		verified = 1
+	}
+	return wire.SNAC_0x07_0x03_AdminInfoReply{
+		Permissions: wire.AdminInfoPermissionsReadWrite,
+		TLVBlock: wire.TLVBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.AdminTLVEmailAddress, user.EmailAddress),
+				wire.NewTLVBE(wire.AdminTLVEmailVerified, verified),
+			},
+		},
+	}
+}
@@ -0,0 +1,212 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+type fakeEmailSender struct {
+	to   *mail.Address
+	body string
+}
+
+func (f *fakeEmailSender) SendEmail(ctx context.Context, to *mail.Address, subject, body string) error {
+	f.to = to
+	f.body = body
+	return nil
+}
+
+func TestSQLiteUserStore_ConfirmEmailAddress(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	addr := &mail.Address{Address: "me@example.com"}
+
+	tests := []struct {
+		name string
+		// given runs after the user sets addr and returns the token to confirm
+		given        func(t *testing.T, f *SQLiteUserStore, sn IdentScreenName) string
+		confirmAt    time.Time
+		wantErr      error
+		wantVerified bool
+	}{
+		{
+			name: "valid token verifies the address",
+			given: func(t *testing.T, f *SQLiteUserStore, sn IdentScreenName) string {
+				token, err := f.NewEmailVerificationToken(context.Background(), sn, addr, now.Add(time.Hour))
+				require.NoError(t, err)
+				return token
+			},
+			confirmAt:    now,
+			wantVerified: true,
+		},
+		{
+			name: "unknown token",
+			given: func(t *testing.T, f *SQLiteUserStore, sn IdentScreenName) string {
+				return "0123456789abcdef"
+			},
+			confirmAt: now,
+			wantErr:   ErrEmailVerificationFailed,
+		},
+		{
+			name: "expired token",
+			given: func(t *testing.T, f *SQLiteUserStore, sn IdentScreenName) string {
+				token, err := f.NewEmailVerificationToken(context.Background(), sn, addr, now.Add(time.Hour))
+				require.NoError(t, err)
+				return token
+			},
+			confirmAt: now.Add(time.Hour),
+			wantErr:   ErrEmailVerificationFailed,
+		},
+		{
+			name: "token superseded by a newer one",
+			given: func(t *testing.T, f *SQLiteUserStore, sn IdentScreenName) string {
+				token, err := f.NewEmailVerificationToken(context.Background(), sn, addr, now.Add(time.Hour))
+				require.NoError(t, err)
+				_, err = f.NewEmailVerificationToken(context.Background(), sn, addr, now.Add(time.Hour))
+				require.NoError(t, err)
+				return token
+			},
+			confirmAt: now,
+			wantErr:   ErrEmailVerificationFailed,
+		},
+		{
+			name: "address changed after the token was issued",
+			given: func(t *testing.T, f *SQLiteUserStore, sn IdentScreenName) string {
+				token, err := f.NewEmailVerificationToken(context.Background(), sn, addr, now.Add(time.Hour))
+				require.NoError(t, err)
+				require.NoError(t, f.UpdateEmailAddress(context.Background(), sn, &mail.Address{Address: "other@example.com"}))
+				return token
+			},
+			confirmAt: now,
+			wantErr:   ErrEmailVerificationFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := newTestDBPath(t)
+
+			f, err := NewSQLiteUserStore(testFile)
+			require.NoError(t, err)
+
+			sn := NewIdentScreenName("me")
+			require.NoError(t, f.InsertUser(context.Background(), User{IdentScreenName: sn}))
+			require.NoError(t, f.UpdateEmailAddress(context.Background(), sn, addr))
+
+			token := tt.given(t, f, sn)
+			have, err := f.ConfirmEmailAddress(context.Background(), token, tt.confirmAt)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, sn, have)
+			}
+
+			u, err := f.User(context.Background(), sn)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantVerified, u.EmailVerified)
+
+			// tokens are single-use
+			_, err = f.ConfirmEmailAddress(context.Background(), token, tt.confirmAt)
+			assert.ErrorIs(t, err, ErrEmailVerificationFailed)
+		})
+	}
+}
+
+func TestSQLiteUserStore_UpdateEmailAddress_ResetsVerification(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	sn := NewIdentScreenName("me")
+	addr := &mail.Address{Address: "me@example.com"}
+	require.NoError(t, f.InsertUser(ctx, User{IdentScreenName: sn}))
+	require.NoError(t, f.UpdateEmailAddress(ctx, sn, addr))
+
+	token, err := f.NewEmailVerificationToken(ctx, sn, addr, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	_, err = f.ConfirmEmailAddress(ctx, token, time.Now())
+	require.NoError(t, err)
+
+	// setting the same address keeps it verified
+	require.NoError(t, f.UpdateEmailAddress(ctx, sn, addr))
+	u, err := f.User(ctx, sn)
+	require.NoError(t, err)
+	assert.True(t, u.EmailVerified)
+
+	require.NoError(t, f.UpdateEmailAddress(ctx, sn, &mail.Address{Address: "new@example.com"}))
+	u, err = f.User(ctx, sn)
+	require.NoError(t, err)
+	assert.False(t, u.EmailVerified)
+}
+
+func TestEmailVerifier(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	sn := DisplayScreenName("Me")
+	addr := &mail.Address{Address: "me@example.com"}
+	require.NoError(t, f.InsertUser(ctx, User{IdentScreenName: sn.IdentScreenName(), DisplayScreenName: sn}))
+	require.NoError(t, f.UpdateEmailAddress(ctx, sn.IdentScreenName(), addr))
+
+	sender := &fakeEmailSender{}
+	v := NewEmailVerifier(f, sender, "https://aim.example.com/verify-email?lang=en", slog.Default())
+	require.NoError(t, v.RequestVerification(ctx, sn, addr))
+	assert.Equal(t, addr, sender.to)
+
+	link := regexp.MustCompile(`https://\S+`).FindString(sender.body)
+	require.NotEmpty(t, link)
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	assert.Equal(t, "en", u.Query().Get("lang"))
+
+	rec := httptest.NewRecorder()
+	v.ConfirmHandler()(rec, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	user, err := f.User(ctx, sn.IdentScreenName())
+	require.NoError(t, err)
+	assert.True(t, user.EmailVerified)
+
+	// the link can't be reused
+	rec = httptest.NewRecorder()
+	v.ConfirmHandler()(rec, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestAdminEmailInfoReply(t *testing.T) {
+	reply := AdminEmailInfoReply(User{EmailAddress: "me@example.com", EmailVerified: true})
+	assert.Equal(t, wire.AdminInfoPermissionsReadWrite, reply.Permissions)
+
+	email, ok := reply.String(wire.AdminTLVEmailAddress)
+	assert.True(t, ok)
+	assert.Equal(t, "me@example.com", email)
+
+	verified, ok := reply.Uint8(wire.AdminTLVEmailVerified)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(1), verified)
+
+	reply = AdminEmailInfoReply(User{EmailAddress: "me@example.com"})
+	verified, _ = reply.Uint8(wire.AdminTLVEmailVerified)
+	assert.Equal(t, uint8(0), verified)
+}
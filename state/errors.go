@@ -0,0 +1,89 @@
+package state
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// errorCodes maps sentinel errors to the SNAC error code reported to the
+// client, in the order they are checked. Every exported sentinel error of
+// this package must be listed; see TestErrorCodes_Exhaustive.
+var errorCodes = []struct {
+	errs []error
+	code uint16
+}{
+	{
+		errs: []error{
+			ErrNoUser, ErrChatRoomNotFound, ErrBARTItemNotFound, ErrFeedbagBackupNotFound,
+			ErrFeedbagGroupNotFound, ErrKeywordNotFound, ErrKeywordCategoryNotFound,
+			ErrScreenNameAliasNotFound, ErrNoAPIKey, ErrVanityURLNotFound, ErrBridgeSessionNotFound,
+			ErrNoEmailAddress, ErrSharedGroupNotFound, ErrPresenceWebhookNotFound, ErrPasswordResetNoEmail,
+			ErrAutoResponderRuleNotFound,
+		},
+		code: wire.ErrorCodeNoMatch,
+	},
+	{
+		errs: []error{
+			ErrDupUser, ErrDupChatRoom, ErrBARTItemExists, ErrFeedbagGroupExists, ErrKeywordExists,
+			ErrKeywordCategoryExists, ErrAccountLinked, ErrDupAPIKey, ErrVanityURLTaken, ErrKeywordInUse,
+			ErrRenameNotAllowed, ErrAccountLinkInvalid, ErrProfanityRejected, ErrSharedGroupSubscribed,
+			ErrSharedGroupOwner, ErrBARTItemInUse, ErrScreenNameReserved, ErrPresenceWebhookNotAllowed,
+			ErrGuestNameUnavailable, ErrScreenNameUnavailable, ErrJobExists,
+		},
+		code: wire.ErrorCodeRequestDenied,
+	},
+	{
+		errs: []error{
+			ErrPasswordInvalid, ErrAIMHandleLength, ErrAIMHandleInvalidFormat, ErrICQUINInvalidFormat,
+			ErrFeedbagGroupInvalid, ErrWebPagerInvalid, ErrVanityURLInvalid, ErrBirthDateInvalid, ErrAllowedHoursInvalid,
+			ErrICBMTooLong, ErrPresenceWebhookInvalid, ErrAutoResponderRuleInvalid, ErrChatKickRuleInvalid,
+			ErrAdminCommandUsage, ErrUnknownScheduledMessageKind,
+		},
+		code: wire.ErrorCodeBustedSnacPayload,
+	},
+	{
+		errs: []error{
+			ErrChatCreateNotAllowed, ErrGuestNotAllowed, ErrConnectionRejected, ErrProbation,
+		},
+		code: wire.ErrorCodeInsufficientRights,
+	},
+	{
+		errs: []error{
+			ErrEmailVerificationFailed, ErrPasswordResetFailed, ErrSessionTokenInvalid,
+			ErrSessionTokenExpired, ErrSessionTokenStale, ErrWebAPITokenInvalid, ErrWebAPITokenExpired,
+			ErrCookieInvalid, ErrCookieExpired, ErrBUCPReplay, ErrBUCPChallengeExpired,
+		},
+		code: wire.ErrorCodeNotLoggedOn,
+	},
+	{errs: []error{ErrRestrictedByParentalControls}, code: wire.ErrorCodeRestrictedByPc},
+	{errs: []error{ErrOfflineInboxFull}, code: wire.ErrorCodeQueueFull},
+	{errs: []error{ErrICBMTooFast}, code: wire.ErrorCodeRateToHost},
+	{errs: []error{ErrLocateRightsExceeded, errTooManyCategories, errTooManyKeywords, ErrChatRoomQuotaExceeded}, code: wire.ErrorCodeListOverflow},
+	{errs: []error{ErrDoNotDisturb}, code: DNDErrorCode},
+	{errs: []error{context.DeadlineExceeded}, code: wire.ErrorCodeTimeout},
+	// server faults that no client request can cause
+	{
+		errs: []error{
+			ErrDatabaseCorrupt, ErrSchemaIncomplete, ErrSchemaOutdated, ErrSchemaTooNew, ErrSchedulerStopped,
+		},
+		code: wire.ErrorCodeGeneralFailure,
+	},
+}
+
+// ErrorCode returns the SNAC error code that reports err to the client. Store
+// and session errors wrap the sentinel errors declared in this package, so
+// handlers can pass them to ErrorCode as they are. Errors that don't wrap a
+// known sentinel, such as database failures, map to
+// wire.ErrorCodeGeneralFailure.
+func ErrorCode(err error) uint16 {
+	for _, c := range errorCodes {
+		for _, target := range c.errs {
+			if errors.Is(err, target) {
+				return c.code
+			}
+		}
+	}
+	return wire.ErrorCodeGeneralFailure
+}
@@ -0,0 +1,236 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want uint16
+	}{
+		{
+			name: "user not found",
+			err:  ErrNoUser,
+			want: wire.ErrorCodeNoMatch,
+		},
+		{
+			name: "wrapped chat room not found",
+			err:  fmt.Errorf("ChatRoomByCookie: %w", ErrChatRoomNotFound),
+			want: wire.ErrorCodeNoMatch,
+		},
+		{
+			name: "duplicate user",
+			err:  fmt.Errorf("InsertUser: %w", ErrDupUser),
+			want: wire.ErrorCodeRequestDenied,
+		},
+		{
+			name: "ICQ rename",
+			err:  fmt.Errorf("RenameScreenName: %w", ErrRenameNotAllowed),
+			want: wire.ErrorCodeRequestDenied,
+		},
+		{
+			name: "invalid password",
+			err:  fmt.Errorf("%w: too short", ErrPasswordInvalid),
+			want: wire.ErrorCodeBustedSnacPayload,
+		},
+		{
+			name: "invalid feedbag group",
+			err:  fmt.Errorf("RenameFeedbagGroup: %w", ErrFeedbagGroupInvalid),
+			want: wire.ErrorCodeBustedSnacPayload,
+		},
+		{
+			name: "chat creation denied",
+			err:  ErrChatCreateNotAllowed,
+			want: wire.ErrorCodeInsufficientRights,
+		},
+		{
+			name: "expired cookie",
+			err:  ErrCookieExpired,
+			want: wire.ErrorCodeNotLoggedOn,
+		},
+		{
+			name: "offline inbox full",
+			err:  fmt.Errorf("SaveMessage: %w", ErrOfflineInboxFull),
+			want: wire.ErrorCodeQueueFull,
+		},
+		{
+			name: "ICBM too long",
+			err:  fmt.Errorf("%w: 9000 > 8000 bytes", ErrICBMTooLong),
+			want: wire.ErrorCodeBustedSnacPayload,
+		},
+		{
+			name: "ICBM too fast",
+			err:  ErrICBMTooFast,
+			want: wire.ErrorCodeRateToHost,
+		},
+		{
+			name: "shared group not found",
+			err:  fmt.Errorf("SubscribeSharedGroup: %w", ErrSharedGroupNotFound),
+			want: wire.ErrorCodeNoMatch,
+		},
+		{
+			name: "already subscribed to shared group",
+			err:  ErrSharedGroupSubscribed,
+			want: wire.ErrorCodeRequestDenied,
+		},
+		{
+			name: "auto-responder rule not found",
+			err:  fmt.Errorf("DeleteAutoResponderRule: %w", ErrAutoResponderRuleNotFound),
+			want: wire.ErrorCodeNoMatch,
+		},
+		{
+			name: "password reset without email",
+			err:  ErrPasswordResetNoEmail,
+			want: wire.ErrorCodeNoMatch,
+		},
+		{
+			name: "no guest name available",
+			err:  ErrGuestNameUnavailable,
+			want: wire.ErrorCodeRequestDenied,
+		},
+		{
+			name: "no generated screen name available",
+			err:  ErrScreenNameUnavailable,
+			want: wire.ErrorCodeRequestDenied,
+		},
+		{
+			name: "job already scheduled",
+			err:  fmt.Errorf("%w: cookie_sweep", ErrJobExists),
+			want: wire.ErrorCodeRequestDenied,
+		},
+		{
+			name: "do not disturb",
+			err:  ErrDoNotDisturb,
+			want: DNDErrorCode,
+		},
+		{
+			name: "deadline exceeded",
+			err:  fmt.Errorf("FeedbagUpsert: %w", context.DeadlineExceeded),
+			want: wire.ErrorCodeTimeout,
+		},
+		{
+			name: "unknown error",
+			err:  errors.New("disk I/O error"),
+			want: wire.ErrorCodeGeneralFailure,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ErrorCode(tt.err))
+		})
+	}
+}
+
+func TestSQLiteUserStore_ErrorWrapping(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	icqUser, err := NewStubUser("100003")
+	require.NoError(t, err)
+	require.NoError(t, f.InsertUser(ctx, icqUser))
+
+	t.Run("delete missing user", func(t *testing.T) {
+		err := f.DeleteUser(ctx, NewIdentScreenName("nobody"))
+		assert.ErrorIs(t, err, ErrNoUser)
+		assert.Equal(t, wire.ErrorCodeNoMatch, ErrorCode(err))
+	})
+
+	t.Run("insert duplicate user", func(t *testing.T) {
+		err := f.InsertUser(ctx, icqUser)
+		assert.ErrorIs(t, err, ErrDupUser)
+		assert.Equal(t, wire.ErrorCodeRequestDenied, ErrorCode(err))
+	})
+
+	t.Run("missing bridge session", func(t *testing.T) {
+		bridge := f.NewOSCARBridgeStore()
+		_, err := bridge.GetBridgeSession(ctx, "nope")
+		assert.ErrorIs(t, err, ErrBridgeSessionNotFound)
+		_, err = bridge.ValidateOSCARCookie(ctx, []byte("nope"))
+		assert.ErrorIs(t, err, ErrBridgeSessionNotFound)
+		assert.Equal(t, wire.ErrorCodeNoMatch, ErrorCode(err))
+	})
+
+	t.Run("rename root group", func(t *testing.T) {
+		err := f.RenameFeedbagGroup(ctx, icqUser.IdentScreenName, 0, "Buddies")
+		assert.ErrorIs(t, err, ErrFeedbagGroupInvalid)
+	})
+
+	t.Run("rename ICQ account", func(t *testing.T) {
+		err := f.RenameScreenName(ctx, icqUser.IdentScreenName, "NewName")
+		assert.ErrorIs(t, err, ErrRenameNotAllowed)
+	})
+
+	t.Run("database errors name the operation", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		err := f.DeleteUser(canceled, icqUser.IdentScreenName)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorContains(t, err, "DeleteUser: ")
+		assert.Equal(t, wire.ErrorCodeGeneralFailure, ErrorCode(err))
+	})
+}
+
+// TestErrorCodes_Exhaustive fails if an exported sentinel error declared in
+// this package is missing from errorCodes, which would report it to clients
+// as a general failure.
+func TestErrorCodes_Exhaustive(t *testing.T) {
+	paths, err := filepath.Glob("*.go")
+	require.NoError(t, err)
+
+	var sentinels []string
+	mapped := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		require.NoError(t, err)
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				for _, name := range spec.(*ast.ValueSpec).Names {
+					if strings.HasPrefix(name.Name, "Err") {
+						sentinels = append(sentinels, name.Name)
+					}
+					if name.Name == "errorCodes" {
+						ast.Inspect(spec, func(n ast.Node) bool {
+							if ident, ok := n.(*ast.Ident); ok {
+								mapped[ident.Name] = true
+							}
+							return true
+						})
+					}
+				}
+			}
+		}
+	}
+
+	require.NotEmpty(t, mapped, "errorCodes not found")
+	for _, name := range sentinels {
+		assert.True(t, mapped[name], "%s is missing from errorCodes", name)
+	}
+}
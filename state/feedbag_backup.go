@@ -0,0 +1,211 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+const (
+	// FeedbagBackupVersions is the number of feedbag backups kept per user.
+	FeedbagBackupVersions = 10
+	// FeedbagBackupInterval is the minimum time between two automatic
+	// backups of a feedbag, unless it has grown since the last one.
+	FeedbagBackupInterval = time.Hour
+)
+
+// FeedbagBackup describes a stored version of a user's feedbag.
+type FeedbagBackup struct {
+	// Version identifies the backup. Versions increase with every backup.
+	Version int
+	// CreatedAt is when the backup was taken.
+	CreatedAt time.Time
+	// ItemCount is the number of feedbag items in the backup.
+	ItemCount int
+}
+
+// feedbagSnapshot is the serialized form of a feedbag backup.
+type feedbagSnapshot struct {
+	Items []wire.FeedbagItem `oscar:"count_prefix=uint16"`
+}
+
+// isSignificantFeedbagItem indicates whether removing item counts as a
+// significant change worth backing up the feedbag for.
+func isSignificantFeedbagItem(item wire.FeedbagItem) bool {
+	return item.ClassID == wire.FeedbagClassIdBuddy || item.ClassID == wire.FeedbagClassIdGroup
+}
+
+// BackupFeedbag stores the user's current feedbag as a new backup version,
+// unless it's empty or identical to the latest backup. Only the last
+// FeedbagBackupVersions backups are kept.
+//
+// Feedbags are also backed up automatically before buddies or groups are
+// deleted, at most once per FeedbagBackupInterval unless the feedbag has
+// grown since the last backup. This keeps the list as it was before a buggy
+// client wipes it one item at a time.
+func (us SQLiteUserStore) BackupFeedbag(ctx context.Context, screenName IdentScreenName) error {
+	if err := backupFeedbagTx(ctx, us.db, screenName, time.Now(), true); err != nil {
+		return fmt.Errorf("BackupFeedbag: %w", err)
+	}
+	return nil
+}
+
+// FeedbagBackups returns the user's feedbag backups, newest first.
+func (us SQLiteUserStore) FeedbagBackups(ctx context.Context, screenName IdentScreenName) ([]FeedbagBackup, error) {
+	q := `
+		SELECT version, createdAt, itemCount
+		FROM feedbagBackup
+		WHERE screenName = ?
+		ORDER BY version DESC
+	`
+	rows, err := us.db.QueryContext(ctx, q, screenName.String())
+	if err != nil {
+		return nil, fmt.Errorf("FeedbagBackups: %w", err)
+	}
+	defer rows.Close()
+
+	var backups []FeedbagBackup
+	for rows.Next() {
+		var b FeedbagBackup
+		var createdAt int64
+		if err := rows.Scan(&b.Version, &createdAt, &b.ItemCount); err != nil {
+			return nil, fmt.Errorf("FeedbagBackups: %w", err)
+		}
+		b.CreatedAt = time.Unix(createdAt, 0).UTC()
+		backups = append(backups, b)
+	}
+
+	return backups, rows.Err()
+}
+
+// FeedbagBackupItems returns the feedbag items stored in a backup. It
+// returns ErrFeedbagBackupNotFound if the version doesn't exist.
+func (us SQLiteUserStore) FeedbagBackupItems(ctx context.Context, screenName IdentScreenName, version int) ([]wire.FeedbagItem, error) {
+	items, err := feedbagBackupItemsTx(ctx, us.db, screenName, version)
+	if err != nil {
+		return nil, fmt.Errorf("FeedbagBackupItems: %w", err)
+	}
+	return items, nil
+}
+
+// RestoreFeedbagBackup replaces the user's feedbag with the items of a
+// backup. The current feedbag is backed up first, so a restore can itself
+// be undone. Connected clients only see the restored list once they sign on
+// again. It returns ErrFeedbagBackupNotFound if the version doesn't exist.
+func (us SQLiteUserStore) RestoreFeedbagBackup(ctx context.Context, screenName IdentScreenName, version int) (err error) {
+	var tx storeTx
+	tx, err = us.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var items []wire.FeedbagItem
+	items, err = feedbagBackupItemsTx(ctx, tx, screenName, version)
+	if err != nil {
+		return fmt.Errorf("RestoreFeedbagBackup: %w", err)
+	}
+
+	if err = backupFeedbagTx(ctx, tx, screenName, time.Now(), true); err != nil {
+		return fmt.Errorf("RestoreFeedbagBackup: backup current feedbag: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM feedbag WHERE screenName = ?`, screenName.String()); err != nil {
+		return fmt.Errorf("RestoreFeedbagBackup: delete feedbag: %w", err)
+	}
+	if err = feedbagUpsertTx(ctx, tx, screenName, items); err != nil {
+		return fmt.Errorf("RestoreFeedbagBackup: insert items: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+func feedbagBackupItemsTx(ctx context.Context, tx sqlConn, screenName IdentScreenName, version int) ([]wire.FeedbagItem, error) {
+	var blob []byte
+	q := `SELECT items FROM feedbagBackup WHERE screenName = ? AND version = ?`
+	err := tx.QueryRowContext(ctx, q, screenName.String(), version).Scan(&blob)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFeedbagBackupNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var snap feedbagSnapshot
+	if err := wire.UnmarshalBE(&snap, bytes.NewReader(blob)); err != nil {
+		return nil, fmt.Errorf("decode backup: %w", err)
+	}
+	return snap.Items, nil
+}
+
+// backupFeedbagTx stores the current feedbag as a new backup version. Unless
+// force is set, the backup is skipped if the latest one was taken less than
+// FeedbagBackupInterval before now and has at least as many items. Backups
+// belong to the account, so screen names without one, such as guests',
+// aren't backed up.
+func backupFeedbagTx(ctx context.Context, tx sqlConn, screenName IdentScreenName, now time.Time, force bool) error {
+	items, err := feedbagTx(ctx, tx, screenName)
+	if err != nil {
+		return fmt.Errorf("select feedbag: %w", err)
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	if err := wire.MarshalBE(feedbagSnapshot{Items: items}, buf); err != nil {
+		return fmt.Errorf("encode backup: %w", err)
+	}
+
+	var latest struct {
+		version   int
+		createdAt int64
+		itemCount int
+		items     []byte
+	}
+	q := `
+		SELECT version, createdAt, itemCount, items
+		FROM feedbagBackup
+		WHERE screenName = ?
+		ORDER BY version DESC
+		LIMIT 1
+	`
+	err = tx.QueryRowContext(ctx, q, screenName.String()).Scan(&latest.version, &latest.createdAt, &latest.itemCount, &latest.items)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return fmt.Errorf("select latest backup: %w", err)
+	case bytes.Equal(latest.items, buf.Bytes()):
+		return nil
+	case !force && len(items) <= latest.itemCount && now.Sub(time.Unix(latest.createdAt, 0)) < FeedbagBackupInterval:
+		return nil
+	}
+
+	q = `
+		INSERT INTO feedbagBackup (screenName, version, createdAt, itemCount, items)
+		SELECT ?, ?, ?, ?, ?
+		WHERE EXISTS (SELECT 1 FROM users WHERE identScreenName = ?)
+	`
+	if _, err := tx.ExecContext(ctx, q, screenName.String(), latest.version+1, now.Unix(), len(items), buf.Bytes(),
+		screenName.String()); err != nil {
+		return fmt.Errorf("insert backup: %w", err)
+	}
+
+	q = `DELETE FROM feedbagBackup WHERE screenName = ? AND version <= ?`
+	if _, err := tx.ExecContext(ctx, q, screenName.String(), latest.version+1-FeedbagBackupVersions); err != nil {
+		return fmt.Errorf("prune backups: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// insertBackupTestUser registers screenName, since feedbag backups belong to
+// an account.
+func insertBackupTestUser(t *testing.T, store *SQLiteUserStore, screenName DisplayScreenName) {
+	u, err := NewStubUser(screenName)
+	require.NoError(t, err)
+	require.NoError(t, store.InsertUser(context.Background(), u))
+}
+
+func TestSQLiteUserStore_BackupFeedbag(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	me := NewIdentScreenName("me")
+	insertBackupTestUser(t, store, "me")
+
+	// nothing to back up yet
+	require.NoError(t, store.BackupFeedbag(ctx, me))
+	backups, err := store.FeedbagBackups(ctx, me)
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+
+	require.NoError(t, store.FeedbagUpsert(ctx, me, []wire.FeedbagItem{
+		newFeedbagItem(wire.FeedbagClassIdBuddy, 1, "buddy1"),
+		newFeedbagItem(wire.FeedbagClassIdBuddy, 2, "buddy2"),
+	}))
+	require.NoError(t, store.BackupFeedbag(ctx, me))
+	// identical to the latest backup, skipped
+	require.NoError(t, store.BackupFeedbag(ctx, me))
+
+	backups, err = store.FeedbagBackups(ctx, me)
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, 1, backups[0].Version)
+	assert.Equal(t, 2, backups[0].ItemCount)
+	assert.WithinDuration(t, time.Now(), backups[0].CreatedAt, time.Minute)
+
+	want, err := store.Feedbag(ctx, me)
+	require.NoError(t, err)
+	have, err := store.FeedbagBackupItems(ctx, me, 1)
+	require.NoError(t, err)
+	assert.Equal(t, want, have)
+
+	_, err = store.FeedbagBackupItems(ctx, me, 2)
+	assert.ErrorIs(t, err, ErrFeedbagBackupNotFound)
+}
+
+func TestSQLiteUserStore_BackupFeedbag_KeepsLastVersions(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	me := NewIdentScreenName("me")
+	insertBackupTestUser(t, store, "me")
+
+	for i := range FeedbagBackupVersions + 3 {
+		require.NoError(t, store.FeedbagUpsert(ctx, me, []wire.FeedbagItem{
+			newFeedbagItem(wire.FeedbagClassIdBuddy, uint16(i+1), "buddy"),
+		}))
+		require.NoError(t, store.BackupFeedbag(ctx, me))
+	}
+
+	backups, err := store.FeedbagBackups(ctx, me)
+	require.NoError(t, err)
+	require.Len(t, backups, FeedbagBackupVersions)
+	assert.Equal(t, FeedbagBackupVersions+3, backups[0].Version)
+	assert.Equal(t, 4, backups[len(backups)-1].Version)
+}
+
+func TestSQLiteUserStore_FeedbagDelete_BacksUpBeforeWipe(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	me := NewIdentScreenName("me")
+	insertBackupTestUser(t, store, "me")
+
+	items := []wire.FeedbagItem{
+		newFeedbagItem(wire.FeedbagClassIdBuddy, 1, "buddy1"),
+		newFeedbagItem(wire.FeedbagClassIdBuddy, 2, "buddy2"),
+		newFeedbagItem(wire.FeedbagClassIdBuddy, 3, "buddy3"),
+		pdInfoItem(4, wire.FeedbagPDModePermitAll),
+	}
+	require.NoError(t, store.FeedbagUpsert(ctx, me, items))
+
+	// deleting non-buddy items isn't a significant change
+	require.NoError(t, store.FeedbagDelete(ctx, me, items[3:]))
+	backups, err := store.FeedbagBackups(ctx, me)
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+
+	// a client wiping the list one buddy at a time leaves a single backup
+	// of the full list
+	for _, item := range items[:3] {
+		require.NoError(t, store.FeedbagDelete(ctx, me, []wire.FeedbagItem{item}))
+	}
+
+	backups, err = store.FeedbagBackups(ctx, me)
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, 3, backups[0].ItemCount)
+}
+
+func TestSQLiteUserStore_RestoreFeedbagBackup(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	me := NewIdentScreenName("me")
+	insertBackupTestUser(t, store, "me")
+
+	assert.ErrorIs(t, store.RestoreFeedbagBackup(ctx, me, 1), ErrFeedbagBackupNotFound)
+
+	original := []wire.FeedbagItem{
+		newFeedbagItem(wire.FeedbagClassIdBuddy, 1, "buddy1"),
+		newFeedbagItem(wire.FeedbagClassIdBuddy, 2, "buddy2"),
+	}
+	require.NoError(t, store.FeedbagUpsert(ctx, me, original))
+	want, err := store.Feedbag(ctx, me)
+	require.NoError(t, err)
+
+	require.NoError(t, store.FeedbagDelete(ctx, me, original))
+	require.NoError(t, store.FeedbagUpsert(ctx, me, []wire.FeedbagItem{
+		newFeedbagItem(wire.FeedbagClassIdBuddy, 3, "buddy3"),
+	}))
+
+	require.NoError(t, store.RestoreFeedbagBackup(ctx, me, 1))
+
+	have, err := store.Feedbag(ctx, me)
+	require.NoError(t, err)
+	assert.Equal(t, want, have)
+
+	// the replaced feedbag was backed up, so the restore can be undone
+	backups, err := store.FeedbagBackups(ctx, me)
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	replaced, err := store.FeedbagBackupItems(ctx, me, backups[0].Version)
+	require.NoError(t, err)
+	require.Len(t, replaced, 1)
+	assert.Equal(t, "buddy3", replaced[0].Name)
+}
+
+func TestSQLiteUserStore_FeedbagBackups_DeletedWithAccount(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	me := NewIdentScreenName("me")
+	insertBackupTestUser(t, store, "me")
+
+	require.NoError(t, store.FeedbagUpsert(ctx, me, []wire.FeedbagItem{
+		newFeedbagItem(wire.FeedbagClassIdBuddy, 1, "buddy1"),
+	}))
+	require.NoError(t, store.BackupFeedbag(ctx, me))
+
+	// whoever registers the screen name next can't see the previous
+	// owner's buddy list
+	require.NoError(t, store.DeleteUser(ctx, me))
+	insertBackupTestUser(t, store, "me")
+	backups, err := store.FeedbagBackups(ctx, me)
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+	assert.ErrorIs(t, store.RestoreFeedbagBackup(ctx, me, 1), ErrFeedbagBackupNotFound)
+
+	// screen names without an account aren't backed up
+	guest := NewIdentScreenName("guest")
+	require.NoError(t, store.FeedbagUpsert(ctx, guest, []wire.FeedbagItem{
+		newFeedbagItem(wire.FeedbagClassIdBuddy, 1, "buddy1"),
+	}))
+	require.NoError(t, store.BackupFeedbag(ctx, guest))
+	backups, err = store.FeedbagBackups(ctx, guest)
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+}
@@ -0,0 +1,121 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// FeedbagItemStatus validates each item of a feedbag insert or update
+// request and returns the results to send in SNAC(0x13,0x0E) FeedbagStatus
+// along with the items that passed. Malformed items get
+// wire.FeedbagStatusBadRequest and must not be passed to FeedbagUpsert,
+// since they could break the client on its next sync. It doesn't apply the
+// store's class policy; see SQLiteUserStore.FeedbagItemStatus.
+func FeedbagItemStatus(items []wire.FeedbagItem) (results []uint16, valid []wire.FeedbagItem) {
+	return feedbagItemStatus(items, func(item wire.FeedbagItem) error { return item.Validate() })
+}
+
+// FeedbagItemStatus is like the package-level FeedbagItemStatus, but also
+// refuses items of unknown custom classes if the store rejects them.
+func (us SQLiteUserStore) FeedbagItemStatus(items []wire.FeedbagItem) (results []uint16, valid []wire.FeedbagItem) {
+	return feedbagItemStatus(items, us.validateFeedbagItem)
+}
+
+func feedbagItemStatus(items []wire.FeedbagItem, validate func(wire.FeedbagItem) error) (results []uint16, valid []wire.FeedbagItem) {
+	results = make([]uint16, len(items))
+	for i, item := range items {
+		if validate(item) != nil {
+			results[i] = wire.FeedbagStatusBadRequest
+			continue
+		}
+		results[i] = wire.FeedbagStatusSuccess
+		valid = append(valid, item)
+	}
+	return results, valid
+}
+
+// RejectCustomFeedbagClasses sets whether the store refuses feedbag items
+// whose class ID is above wire.FeedbagClassIdMaxPredefined and unknown to
+// the server, such as client-defined classes. Clients can otherwise fill
+// feedbags with opaque items that the server stores but never uses.
+func (us *SQLiteUserStore) RejectCustomFeedbagClasses(reject bool) {
+	us.rejectCustomFeedbagClasses = reject
+}
+
+// validateFeedbagItem returns wire.ErrInvalidFeedbagItem if item is
+// malformed or its class is refused by the store.
+func (us SQLiteUserStore) validateFeedbagItem(item wire.FeedbagItem) error {
+	if err := item.Validate(); err != nil {
+		return err
+	}
+	if us.rejectCustomFeedbagClasses && item.ClassID > wire.FeedbagClassIdMaxPredefined &&
+		!wire.IsKnownFeedbagClass(item.ClassID) {
+		return fmt.Errorf("%w: custom class ID %#04x is not allowed", wire.ErrInvalidFeedbagItem, item.ClassID)
+	}
+	return nil
+}
+
+// FeedbagClassUsage counts the stored feedbag items of a class.
+type FeedbagClassUsage struct {
+	ClassID uint16 `json:"class_id"`
+	// Items is the number of items of the class.
+	Items int `json:"items"`
+	// Users is the number of users who have items of the class.
+	Users int `json:"users"`
+}
+
+// UnknownFeedbagClasses returns the usage of the feedbag classes that the
+// server stores but doesn't understand, by class ID. Operators use it to
+// spot clients that fill feedbags with opaque items before deciding
+// whether to reject custom classes.
+func (us SQLiteUserStore) UnknownFeedbagClasses(ctx context.Context) ([]FeedbagClassUsage, error) {
+	q := `
+		SELECT classID, COUNT(*), COUNT(DISTINCT screenName)
+		FROM feedbag
+		GROUP BY classID
+		ORDER BY classID
+	`
+	rows, err := us.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("UnknownFeedbagClasses: %w", err)
+	}
+	defer rows.Close()
+
+	usages := []FeedbagClassUsage{}
+	for rows.Next() {
+		var usage FeedbagClassUsage
+		if err := rows.Scan(&usage.ClassID, &usage.Items, &usage.Users); err != nil {
+			return nil, fmt.Errorf("UnknownFeedbagClasses: %w", err)
+		}
+		if !wire.IsKnownFeedbagClass(usage.ClassID) {
+			usages = append(usages, usage)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("UnknownFeedbagClasses: %w", err)
+	}
+	return usages, nil
+}
+
+// FeedbagClassReporter reports the usage of unknown feedbag classes.
+type FeedbagClassReporter interface {
+	UnknownFeedbagClasses(ctx context.Context) ([]FeedbagClassUsage, error)
+}
+
+// UnknownFeedbagClassesHandler serves the usage of unknown feedbag classes
+// as JSON for the management API.
+func UnknownFeedbagClassesHandler(reporter FeedbagClassReporter, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usages, err := reporter.UnknownFeedbagClasses(r.Context())
+		if err != nil {
+			logger.ErrorContext(r.Context(), "unable to report unknown feedbag classes", "err", err)
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+			return
+		}
+		writeHealthJSON(w, http.StatusOK, usages)
+	}
+}
@@ -0,0 +1,85 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestFeedbagItemStatus(t *testing.T) {
+	good := wire.FeedbagItem{ClassID: wire.FeedbagClassIdBuddy, GroupID: 1, ItemID: 1, Name: "chattingchuck"}
+	bad := wire.FeedbagItem{ClassID: 0x0100, GroupID: 1, ItemID: 2, Name: "x"}
+
+	results, valid := FeedbagItemStatus([]wire.FeedbagItem{good, bad})
+	assert.Equal(t, []uint16{wire.FeedbagStatusSuccess, wire.FeedbagStatusBadRequest}, results)
+	assert.Equal(t, []wire.FeedbagItem{good}, valid)
+}
+
+func TestSQLiteUserStore_FeedbagUpsert_Invalid(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	me := NewIdentScreenName("me")
+	items := []wire.FeedbagItem{
+		{ClassID: wire.FeedbagClassIdGroup, GroupID: 1, Name: "Buddies"},
+		{ClassID: wire.FeedbagClassIdBuddy, GroupID: 1, ItemID: 1, Name: strings.Repeat("a", wire.FeedbagItemNameMaxLen+1)},
+	}
+
+	err = store.FeedbagUpsert(context.Background(), me, items)
+	assert.ErrorIs(t, err, wire.ErrInvalidFeedbagItem)
+
+	// nothing from the batch is stored
+	have, err := store.Feedbag(context.Background(), me)
+	require.NoError(t, err)
+	assert.Empty(t, have)
+}
+
+func TestSQLiteUserStore_RejectCustomFeedbagClasses(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	me := NewIdentScreenName("me")
+	custom := wire.FeedbagItem{ClassID: wire.FeedbagClassIdMin + 1, GroupID: 0, ItemID: 1, Name: "plugin"}
+	statusNote := wire.FeedbagItem{ClassID: wire.FeedbagClassIdXIcqStatusNote, GroupID: 0, ItemID: 2}
+	gap := wire.FeedbagItem{ClassID: 0x000C, GroupID: 0, ItemID: 3}
+
+	// custom classes are stored opaquely by default
+	require.NoError(t, store.FeedbagUpsert(ctx, me, []wire.FeedbagItem{custom, statusNote, gap}))
+
+	store.RejectCustomFeedbagClasses(true)
+	custom.ItemID = 4
+	assert.ErrorIs(t, store.FeedbagUpsert(ctx, me, []wire.FeedbagItem{custom}), wire.ErrInvalidFeedbagItem)
+
+	results, valid := store.FeedbagItemStatus([]wire.FeedbagItem{custom, statusNote})
+	assert.Equal(t, []uint16{wire.FeedbagStatusBadRequest, wire.FeedbagStatusSuccess}, results)
+	assert.Equal(t, []wire.FeedbagItem{statusNote}, valid)
+
+	usages, err := store.UnknownFeedbagClasses(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []FeedbagClassUsage{
+		{ClassID: 0x000C, Items: 1, Users: 1},
+		{ClassID: wire.FeedbagClassIdMin + 1, Items: 1, Users: 1},
+	}, usages)
+
+	rec := httptest.NewRecorder()
+	UnknownFeedbagClassesHandler(store, slog.Default())(rec, httptest.NewRequest(http.MethodGet, "/admin/feedbag/classes", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"class_id":12,"items":1,"users":1},{"class_id":1025,"items":1,"users":1}]`, rec.Body.String())
+}
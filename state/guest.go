@@ -0,0 +1,161 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// guestNameAttempts is how many random guest screen names are tried before
+// giving up on finding a free one.
+const guestNameAttempts = 10
+
+var (
+	// ErrGuestNotAllowed indicates that a guest session attempted an
+	// operation reserved for registered users, such as sending an IM or
+	// joining a room that isn't open to guests.
+	ErrGuestNotAllowed = errors.New("operation not allowed for guest sessions")
+	// ErrGuestNameUnavailable indicates that no free guest screen name
+	// could be generated.
+	ErrGuestNameUnavailable = errors.New("unable to allocate a guest screen name")
+)
+
+// GuestUserFinder looks up registered users, so that guests aren't given
+// the screen name of an existing account.
+type GuestUserFinder interface {
+	User(ctx context.Context, screenName IdentScreenName) (*User, error)
+}
+
+// GuestPolicy describes which chat rooms ephemeral guest sessions may join
+// and how their screen names are generated. Guests have no user record, so
+// they never appear in directory searches and nothing remains of them once
+// their session is removed.
+type GuestPolicy struct {
+	// Prefix is prepended to the random digits of each guest screen name.
+	// Registered users must not be allowed to create screen names with
+	// this prefix; see IsGuestScreenName and
+	// SQLiteUserStore.ReserveGuestScreenNames.
+	Prefix string
+	// ChatRooms is the list of chat room names that guests may join. Guest
+	// access is disabled when the list is empty.
+	ChatRooms []string
+	// Names, if set, generates guest screen names from words, such as
+	// "GuestLazyOtter42", instead of the prefix followed by digits only.
+	// Its own Prefix is replaced by the policy's.
+	Names *ScreenNameGenerator
+}
+
+// Enabled indicates whether guest sessions are allowed at all.
+func (p GuestPolicy) Enabled() bool {
+	return p.Prefix != "" && len(p.ChatRooms) > 0
+}
+
+// IsGuestScreenName indicates whether screenName falls in the namespace
+// reserved for guest screen names.
+func (p GuestPolicy) IsGuestScreenName(screenName IdentScreenName) bool {
+	return p.Prefix != "" && strings.HasPrefix(screenName.String(), NewIdentScreenName(p.Prefix).String())
+}
+
+// CanJoinChat returns ErrGuestNotAllowed if guests may not join the chat
+// room named roomName. Room names are compared case-insensitively.
+func (p GuestPolicy) CanJoinChat(roomName string) error {
+	for _, room := range p.ChatRooms {
+		if strings.EqualFold(room, roomName) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: chat room %q is not open to guests", ErrGuestNotAllowed, roomName)
+}
+
+// NewScreenName generates a random guest screen name made of the prefix
+// followed by as many digits (up to 6) as fit in a 16 character screen name,
+// or by words and digits if Names is set.
+func (p GuestPolicy) NewScreenName() (DisplayScreenName, error) {
+	if p.Names != nil {
+		g := *p.Names
+		g.Prefix = p.Prefix
+		return g.Generate()
+	}
+
+	digits := min(6, 16-len(p.Prefix))
+	if digits < 1 {
+		return "", fmt.Errorf("guest prefix %q is too long", p.Prefix)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(p.Prefix)
+	for range digits {
+		sb.WriteString(strconv.Itoa(rand.IntN(10)))
+	}
+
+	sn := DisplayScreenName(sb.String())
+	if err := sn.ValidateAIMHandle(); err != nil {
+		return "", fmt.Errorf("invalid guest screen name %q: %w", sn, err)
+	}
+
+	return sn, nil
+}
+
+// AddGuestSession creates a chat-only guest session with a freshly generated
+// screen name. Unlike AddSession, it never replaces an existing session or
+// hands out the screen name of a user found in users; it picks another name
+// instead. Removing the session with RemoveSession discards the guest
+// entirely.
+func (s *InMemorySessionManager) AddGuestSession(ctx context.Context, policy GuestPolicy, users GuestUserFinder) (*Session, error) {
+	if !policy.Enabled() {
+		return nil, fmt.Errorf("%w: guest access is disabled", ErrGuestNotAllowed)
+	}
+
+	for range guestNameAttempts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		screenName, err := policy.NewScreenName()
+		if err != nil {
+			return nil, err
+		}
+
+		u, err := users.User(ctx, screenName.IdentScreenName())
+		if err != nil {
+			return nil, fmt.Errorf("look up guest screen name: %w", err)
+		}
+		if u != nil {
+			continue
+		}
+
+		s.mapMutex.Lock()
+		if s.findRec(screenName.IdentScreenName()) != nil {
+			s.mapMutex.Unlock()
+			continue
+		}
+
+		sess := s.newSession(screenName)
+		sess.SetGuest(true)
+		s.mapMutex.Unlock()
+
+		return sess, nil
+	}
+
+	return nil, ErrGuestNameUnavailable
+}
+
+// ReserveGuestScreenNames sets the guest policy whose screen name prefix
+// InsertUser and RenameScreenName refuse with ErrScreenNameReserved, so that
+// registered users can't take names that AddGuestSession hands out. A policy
+// with an empty prefix reserves nothing.
+func (us *SQLiteUserStore) ReserveGuestScreenNames(policy GuestPolicy) {
+	us.guestPolicy = policy
+}
+
+// checkGuestScreenName returns ErrScreenNameReserved if screenName falls in
+// the reserved guest namespace.
+func (us SQLiteUserStore) checkGuestScreenName(screenName IdentScreenName) error {
+	if us.guestPolicy.IsGuestScreenName(screenName) {
+		return fmt.Errorf("%w: %s is a guest screen name", ErrScreenNameReserved, screenName)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"testing"
+
+	"github.com/pchchv/go-icq/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuestPolicy_NewScreenName(t *testing.T) {
+	policy := GuestPolicy{Prefix: "Guest"}
+
+	sn, err := policy.NewScreenName()
+	require.NoError(t, err)
+	assert.Len(t, sn, len("Guest")+6)
+	assert.True(t, policy.IsGuestScreenName(sn.IdentScreenName()))
+
+	// long prefixes leave room for fewer digits
+	sn, err = GuestPolicy{Prefix: "WebChatVisitor"}.NewScreenName()
+	require.NoError(t, err)
+	assert.Len(t, sn, 16)
+
+	_, err = GuestPolicy{Prefix: "ThisPrefixIsWayTooLong"}.NewScreenName()
+	assert.Error(t, err)
+
+	// word names keep the policy's prefix
+	policy.Names = &ScreenNameGenerator{Prefix: "Ignored", Adjectives: []string{"Lazy"}, Nouns: []string{"Otter"}}
+	sn, err = policy.NewScreenName()
+	require.NoError(t, err)
+	assert.Regexp(t, `^GuestLazyOtter\d{1,2}$`, sn)
+	assert.True(t, policy.IsGuestScreenName(sn.IdentScreenName()))
+}
+
+func TestGuestPolicy_IsGuestScreenName(t *testing.T) {
+	policy := GuestPolicy{Prefix: "Guest"}
+	assert.True(t, policy.IsGuestScreenName(NewIdentScreenName("guest 12345")))
+	assert.False(t, policy.IsGuestScreenName(NewIdentScreenName("ChattingChuck")))
+	assert.False(t, GuestPolicy{}.IsGuestScreenName(NewIdentScreenName("guest12345")))
+}
+
+func TestGuestPolicy_CanJoinChat(t *testing.T) {
+	policy := GuestPolicy{Prefix: "Guest", ChatRooms: []string{"Lobby", "Help Desk"}}
+	assert.NoError(t, policy.CanJoinChat("lobby"))
+	assert.NoError(t, policy.CanJoinChat("Help Desk"))
+	assert.ErrorIs(t, policy.CanJoinChat("Members Only"), ErrGuestNotAllowed)
+}
+
+// stubGuestUsers is a GuestUserFinder backed by a set of registered
+// screen names.
+type stubGuestUsers map[IdentScreenName]bool
+
+func (s stubGuestUsers) User(ctx context.Context, screenName IdentScreenName) (*User, error) {
+	if !s[screenName] {
+		return nil, nil
+	}
+	return &User{IdentScreenName: screenName}, nil
+}
+
+func TestInMemorySessionManager_AddGuestSession(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+	policy := GuestPolicy{Prefix: "Guest", ChatRooms: []string{"Lobby"}}
+
+	sess, err := sm.AddGuestSession(context.Background(), policy, stubGuestUsers{})
+	require.NoError(t, err)
+	assert.True(t, sess.Guest())
+	assert.True(t, policy.IsGuestScreenName(sess.IdentScreenName()))
+	sess.SetSignonComplete()
+	assert.Same(t, sess, sm.RetrieveSession(sess.IdentScreenName()))
+
+	sm.RemoveSession(sess)
+	assert.Nil(t, sm.RetrieveSession(sess.IdentScreenName()))
+
+	t.Run("guest access disabled", func(t *testing.T) {
+		_, err := sm.AddGuestSession(context.Background(), GuestPolicy{Prefix: "Guest"}, stubGuestUsers{})
+		assert.ErrorIs(t, err, ErrGuestNotAllowed)
+	})
+
+	t.Run("guest session queue is bounded", func(t *testing.T) {
+		sm := NewInMemorySessionManager(slog.Default())
+		sm.SetMaxQueueDepth(2)
+
+		sess, err := sm.AddGuestSession(context.Background(), policy, stubGuestUsers{})
+		require.NoError(t, err)
+		for range 2 {
+			assert.Equal(t, SessSendOK, sess.RelayMessage(wire.SNACMessage{}))
+		}
+		assert.Equal(t, SessQueueFull, sess.RelayMessage(wire.SNACMessage{}))
+	})
+
+	t.Run("guest session keeps SNAC history", func(t *testing.T) {
+		sm := NewInMemorySessionManager(slog.Default())
+		sm.SetSNACHistorySize(4)
+
+		sess, err := sm.AddGuestSession(context.Background(), policy, stubGuestUsers{})
+		require.NoError(t, err)
+		sess.RecordSNAC(SNACReceived, wire.SNACMessage{})
+		assert.Len(t, sess.SNACHistory(), 1)
+	})
+
+	t.Run("name space exhausted", func(t *testing.T) {
+		// a 15 character prefix leaves room for a single digit
+		policy := GuestPolicy{Prefix: "VisitorsOfLobby", ChatRooms: []string{"Lobby"}}
+		for i := range 10 {
+			sn := DisplayScreenName(policy.Prefix + strconv.Itoa(i))
+			_, err := sm.AddSession(context.Background(), sn)
+			require.NoError(t, err)
+		}
+		_, err := sm.AddGuestSession(context.Background(), policy, stubGuestUsers{})
+		assert.ErrorIs(t, err, ErrGuestNameUnavailable)
+	})
+
+	t.Run("registered screen names are skipped", func(t *testing.T) {
+		sm := NewInMemorySessionManager(slog.Default())
+		policy := GuestPolicy{Prefix: "VisitorsOfLobby", ChatRooms: []string{"Lobby"}}
+		users := stubGuestUsers{}
+		for i := range 10 {
+			users[NewIdentScreenName(policy.Prefix+strconv.Itoa(i))] = true
+		}
+		_, err := sm.AddGuestSession(context.Background(), policy, users)
+		assert.ErrorIs(t, err, ErrGuestNameUnavailable)
+	})
+}
+
+func TestSQLiteUserStore_ReserveGuestScreenNames(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewSQLiteUserStore(newTestDBPath(t))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	alice, err := NewStubUser("alice")
+	require.NoError(t, err)
+	require.NoError(t, store.InsertUser(ctx, alice))
+
+	// guest screen names can be registered until the prefix is reserved
+	early, err := NewStubUser("Guest1")
+	require.NoError(t, err)
+	require.NoError(t, store.InsertUser(ctx, early))
+
+	store.ReserveGuestScreenNames(GuestPolicy{Prefix: "Guest", ChatRooms: []string{"Lobby"}})
+
+	guest, err := NewStubUser("guest12345")
+	require.NoError(t, err)
+	assert.ErrorIs(t, store.InsertUser(ctx, guest), ErrScreenNameReserved)
+	assert.ErrorIs(t, store.RenameScreenName(ctx, alice.IdentScreenName, "Guest54321"), ErrScreenNameReserved)
+
+	// an account registered before the prefix was reserved keeps its name
+	assert.NoError(t, store.RenameScreenName(ctx, early.IdentScreenName, "GUEST1"))
+}
+
+func TestInMemorySessionManager_DeliverICBM_Guest(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+	policy := GuestPolicy{Prefix: "Guest", ChatRooms: []string{"Lobby"}}
+
+	guest, err := sm.AddGuestSession(context.Background(), policy, stubGuestUsers{})
+	require.NoError(t, err)
+	guest.SetSignonComplete()
+	bob, err := sm.AddSession(context.Background(), "bob")
+	require.NoError(t, err)
+	bob.SetSignonComplete()
+
+	t.Run("guest sends an IM", func(t *testing.T) {
+		state, _, ok := sendIM(sm, guest, newAckRequestIM(1, "bob"))
+		assert.Equal(t, DeliveryRejected, state)
+		assert.False(t, ok)
+		assert.Zero(t, bob.QueueDepth())
+	})
+
+	t.Run("guest receives an IM", func(t *testing.T) {
+		state, _, ok := sendIM(sm, bob, newAckRequestIM(1, guest.IdentScreenName().String()))
+		assert.Equal(t, DeliveryRejected, state)
+		assert.False(t, ok)
+		assert.Zero(t, guest.QueueDepth())
+	})
+}
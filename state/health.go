@@ -0,0 +1,126 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// HealthStatusOK indicates that a component is healthy.
+	HealthStatusOK = "ok"
+	// HealthStatusUnavailable indicates that a component is not healthy.
+	HealthStatusUnavailable = "unavailable"
+)
+
+// healthPingTimeout bounds how long a readiness probe waits on the database.
+const healthPingTimeout = 2 * time.Second
+
+// DBPinger checks database connectivity.
+type DBPinger interface {
+	Ping(ctx context.Context) error
+}
+
+// SessionCounter reports the number of active sessions.
+type SessionCounter interface {
+	SessionCount() int
+}
+
+// HealthReport describes the state of the server at a point in time.
+type HealthReport struct {
+	// Status is HealthStatusOK if every component is healthy.
+	Status string `json:"status"`
+	// Database is the database connectivity status.
+	Database string `json:"database"`
+	// Listeners maps each registered listener to whether it is serving.
+	Listeners map[string]bool `json:"listeners"`
+	// Sessions is the number of signed-on sessions.
+	Sessions int `json:"sessions"`
+}
+
+// HealthMonitor tracks server health and serves liveness and readiness
+// probes, such as those used by Kubernetes.
+// A HealthMonitor is safe for concurrent use by multiple goroutines.
+type HealthMonitor struct {
+	db        DBPinger
+	sessions  SessionCounter
+	listeners map[string]bool
+	mutex     sync.RWMutex
+}
+
+// NewHealthMonitor creates a new instance of HealthMonitor.
+func NewHealthMonitor(db DBPinger, sessions SessionCounter) *HealthMonitor {
+	return &HealthMonitor{
+		db:        db,
+		sessions:  sessions,
+		listeners: make(map[string]bool),
+	}
+}
+
+// SetListenerStatus records whether the named listener is serving.
+// The server isn't ready until every registered listener is up.
+func (h *HealthMonitor) SetListenerStatus(name string, up bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.listeners[name] = up
+}
+
+// Report checks each component and returns the current health report.
+func (h *HealthMonitor) Report(ctx context.Context) HealthReport {
+	h.mutex.RLock()
+	listeners := maps.Clone(h.listeners)
+	h.mutex.RUnlock()
+
+	report := HealthReport{
+		Status:    HealthStatusOK,
+		Database:  HealthStatusOK,
+		Listeners: listeners,
+		Sessions:  h.sessions.SessionCount(),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthPingTimeout)
+	defer cancel()
+	if err := h.db.Ping(ctx); err != nil {
+		report.Database = HealthStatusUnavailable
+		report.Status = HealthStatusUnavailable
+	}
+
+	for _, up := range listeners {
+		if !up {
+			report.Status = HealthStatusUnavailable
+		}
+	}
+
+	return report
+}
+
+// LivenessHandler serves /healthz. It responds 200 as long as the process is
+// able to handle HTTP requests.
+func (h *HealthMonitor) LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeHealthJSON(w, http.StatusOK, map[string]string{"status": HealthStatusOK})
+	}
+}
+
+// ReadinessHandler serves /readyz. It responds 200 with a HealthReport when
+// the database is reachable and all listeners are up, and 503 otherwise.
+func (h *HealthMonitor) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := h.Report(r.Context())
+		code := http.StatusOK
+		if report.Status != HealthStatusOK {
+			code = http.StatusServiceUnavailable
+		}
+		writeHealthJSON(w, code, report)
+	}
+}
+
+func writeHealthJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,103 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubPinger struct {
+	err error
+}
+
+func (s stubPinger) Ping(ctx context.Context) error {
+	return s.err
+}
+
+func TestHealthMonitor_ReadinessHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		pingErr    error
+		listeners  map[string]bool
+		wantCode   int
+		wantReport HealthReport
+	}{
+		{
+			name:      "all components healthy",
+			listeners: map[string]bool{"BOS": true, "TOC": true},
+			wantCode:  http.StatusOK,
+			wantReport: HealthReport{
+				Status:    HealthStatusOK,
+				Database:  HealthStatusOK,
+				Listeners: map[string]bool{"BOS": true, "TOC": true},
+				Sessions:  1,
+			},
+		},
+		{
+			name:      "database unavailable",
+			pingErr:   errors.New("db is gone"),
+			listeners: map[string]bool{"BOS": true},
+			wantCode:  http.StatusServiceUnavailable,
+			wantReport: HealthReport{
+				Status:    HealthStatusUnavailable,
+				Database:  HealthStatusUnavailable,
+				Listeners: map[string]bool{"BOS": true},
+				Sessions:  1,
+			},
+		},
+		{
+			name:      "listener down",
+			listeners: map[string]bool{"BOS": true, "TOC": false},
+			wantCode:  http.StatusServiceUnavailable,
+			wantReport: HealthReport{
+				Status:    HealthStatusUnavailable,
+				Database:  HealthStatusOK,
+				Listeners: map[string]bool{"BOS": true, "TOC": false},
+				Sessions:  1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessionManager := NewInMemorySessionManager(slog.Default())
+			sess, err := sessionManager.AddSession(context.Background(), "user-screen-name")
+			require.NoError(t, err)
+			sess.SetSignonComplete()
+			_, err = sessionManager.AddSession(context.Background(), "incomplete-signon")
+			require.NoError(t, err)
+
+			monitor := NewHealthMonitor(stubPinger{err: tt.pingErr}, sessionManager)
+			for name, up := range tt.listeners {
+				monitor.SetListenerStatus(name, up)
+			}
+
+			rec := httptest.NewRecorder()
+			monitor.ReadinessHandler()(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			var report HealthReport
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+			assert.Equal(t, tt.wantReport, report)
+		})
+	}
+}
+
+func TestHealthMonitor_LivenessHandler(t *testing.T) {
+	monitor := NewHealthMonitor(stubPinger{err: errors.New("db is gone")}, NewInMemorySessionManager(slog.Default()))
+
+	rec := httptest.NewRecorder()
+	monitor.LivenessHandler()(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+}
@@ -0,0 +1,112 @@
+package state
+
+import (
+	"context"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// ICBMDedupWindow is how long a session remembers the ICBMs it received in
+// order to drop retransmissions.
+const ICBMDedupWindow = 30 * time.Second
+
+// icbmDedupKey identifies an ICBM by its sender and cookie.
+type icbmDedupKey struct {
+	sender IdentScreenName
+	cookie uint64
+}
+
+// SeenICBM records that the session received an ICBM from sender with the
+// given cookie and reports whether it already received one within
+// ICBMDedupWindow. Zero cookies are never considered duplicates.
+func (s *Session) SeenICBM(sender IdentScreenName, cookie uint64) bool {
+	if cookie == 0 {
+		return false
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.nowFn()
+	for k, at := range s.recentICBMs {
+		if now.Sub(at) >= ICBMDedupWindow {
+			delete(s.recentICBMs, k)
+		}
+	}
+
+	key := icbmDedupKey{sender: sender, cookie: cookie}
+	if _, seen := s.recentICBMs[key]; seen {
+		return true
+	}
+	if s.recentICBMs == nil {
+		s.recentICBMs = make(map[icbmDedupKey]time.Time)
+	}
+	s.recentICBMs[key] = now
+	return false
+}
+
+// forgetICBM removes an ICBM recorded by SeenICBM, so that a retransmission
+// of a message that couldn't be delivered isn't mistaken for a duplicate.
+func (s *Session) forgetICBM(sender IdentScreenName, cookie uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.recentICBMs, icbmDedupKey{sender: sender, cookie: cookie})
+}
+
+// DeliverICBM relays an ICBM sent by sender to the recipient's sessions like
+// DeliverToScreenName, except that retransmissions of a message with the
+// same sender and cookie within ICBMDedupWindow are dropped and reported as
+// DeliveryDuplicate. Messages sent by or to a guest session are refused and
+// reported as DeliveryRejected.
+func (s *InMemorySessionManager) DeliverICBM(ctx context.Context, sender IdentScreenName, cookie uint64, recipient IdentScreenName, msg wire.SNACMessage, received time.Time) DeliveryState {
+	sessions := s.retrieveLinkedSessions(recipient)
+	if len(sessions) == 0 {
+		s.logger.DebugContext(ctx, "can't deliver message because user is not online", "recipient", recipient)
+		return DeliveryOffline
+	}
+
+	if sessions[0].Guest() || s.isGuest(sender) {
+		s.logger.DebugContext(ctx, "rejecting ICBM exchanged with a guest", "sender", sender, "recipient", recipient)
+		return DeliveryRejected
+	}
+
+	state := DeliveryDropped
+	for _, sess := range sessions {
+		state = state.combine(s.deliverICBM(ctx, sender, cookie, sess, msg))
+	}
+	if state == DeliveryEnqueued {
+		s.latency.ObserveSince(DeliveryPathOnline, received)
+	}
+	return state
+}
+
+// deliverICBM relays an ICBM to one of the recipient's sessions.
+func (s *InMemorySessionManager) deliverICBM(ctx context.Context, sender IdentScreenName, cookie uint64, sess *Session, msg wire.SNACMessage) DeliveryState {
+	if sess.SeenICBM(sender, cookie) {
+		s.duplicateICBMs.Add(1)
+		s.logger.DebugContext(ctx, "dropping duplicate ICBM", "sender", sender, "recipient", sess.IdentScreenName(), "cookie", cookie)
+		return DeliveryDuplicate
+	}
+
+	if s.maybeRelayMessage(ctx, msg, sess) != SessSendOK {
+		sess.forgetICBM(sender, cookie)
+		return DeliveryDropped
+	}
+	return DeliveryEnqueued
+}
+
+// isGuest indicates whether screenName belongs to a guest session.
+func (s *InMemorySessionManager) isGuest(screenName IdentScreenName) bool {
+	s.mapMutex.RLock()
+	defer s.mapMutex.RUnlock()
+
+	rec, ok := s.store[screenName]
+	return ok && rec.sess.Guest()
+}
+
+// DuplicateICBMs returns the number of ICBM retransmissions dropped by
+// DeliverICBM.
+func (s *InMemorySessionManager) DuplicateICBMs() int64 {
+	return s.duplicateICBMs.Load()
+}
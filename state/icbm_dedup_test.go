@@ -0,0 +1,81 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_SeenICBM(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sess := NewSession()
+	sess.nowFn = func() time.Time { return now }
+
+	alice := NewIdentScreenName("alice")
+	bob := NewIdentScreenName("bob")
+
+	assert.False(t, sess.SeenICBM(alice, 1))
+	assert.True(t, sess.SeenICBM(alice, 1))
+	// cookies are scoped to the sender
+	assert.False(t, sess.SeenICBM(bob, 1))
+	assert.False(t, sess.SeenICBM(alice, 2))
+
+	// zero cookies aren't unique
+	assert.False(t, sess.SeenICBM(alice, 0))
+	assert.False(t, sess.SeenICBM(alice, 0))
+
+	now = now.Add(ICBMDedupWindow - time.Second)
+	assert.True(t, sess.SeenICBM(alice, 1))
+
+	// forgotten once the window passes
+	now = now.Add(time.Second)
+	assert.False(t, sess.SeenICBM(alice, 1))
+	assert.Len(t, sess.recentICBMs, 1)
+}
+
+func TestInMemorySessionManager_DeliverICBM_Duplicate(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+
+	alice, err := sm.AddSession(context.Background(), "alice")
+	require.NoError(t, err)
+	alice.SetSignonComplete()
+	bob, err := sm.AddSession(context.Background(), "bob")
+	require.NoError(t, err)
+	bob.SetSignonComplete()
+
+	state, _, ok := sendIM(sm, alice, newAckRequestIM(1, "bob"))
+	assert.Equal(t, DeliveryEnqueued, state)
+	assert.True(t, ok)
+
+	// the retransmission is dropped but still acknowledged, since the
+	// first copy reached bob
+	state, _, ok = sendIM(sm, alice, newAckRequestIM(1, "bob"))
+	assert.Equal(t, DeliveryDuplicate, state)
+	assert.True(t, ok)
+
+	assert.Equal(t, 1, bob.QueueDepth())
+	assert.Equal(t, int64(1), sm.DuplicateICBMs())
+}
+
+func TestInMemorySessionManager_DeliverICBM_DroppedNotRecorded(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+
+	alice, err := sm.AddSession(context.Background(), "alice")
+	require.NoError(t, err)
+	alice.SetSignonComplete()
+	bob, err := sm.AddSession(context.Background(), "bob")
+	require.NoError(t, err)
+	bob.SetSignonComplete()
+	bob.Close()
+
+	state, _, _ := sendIM(sm, alice, newAckRequestIM(1, "bob"))
+	assert.Equal(t, DeliveryDropped, state)
+
+	// a retry of the undelivered message must not count as a duplicate
+	assert.False(t, bob.SeenICBM(alice.IdentScreenName(), 1))
+	assert.Zero(t, sm.DuplicateICBMs())
+}
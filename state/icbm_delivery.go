@@ -0,0 +1,117 @@
+package state
+
+import (
+	"context"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// DeliveryState describes what happened to an ICBM relayed to a recipient.
+type DeliveryState uint8
+
+const (
+	// DeliveryOffline indicates the recipient has no signed-on session.
+	DeliveryOffline DeliveryState = iota
+	// DeliveryEnqueued indicates the message was placed on the outbound
+	// queue of at least one of the recipient's sessions.
+	DeliveryEnqueued
+	// DeliveryDropped indicates the recipient is online but the message
+	// could not be enqueued because the session is closed or its queue
+	// is full.
+	DeliveryDropped
+	// DeliveryDuplicate indicates the recipient already received a message
+	// with the same sender and cookie, so the retransmission was dropped.
+	DeliveryDuplicate
+	// DeliveryRejected indicates the message was refused because the
+	// sender or the recipient is a guest, and guests cannot exchange IMs.
+	// ICBM handlers should reply with the error code for
+	// ErrGuestNotAllowed.
+	DeliveryRejected
+)
+
+// String returns a human-readable name for the delivery state.
+func (d DeliveryState) String() string {
+	switch d {
+	case DeliveryOffline:
+		return "offline"
+	case DeliveryEnqueued:
+		return "enqueued"
+	case DeliveryDropped:
+		return "dropped"
+	case DeliveryDuplicate:
+		return "duplicate"
+	case DeliveryRejected:
+		return "rejected"
+	default:
+		return "unknown"
+	}
+}
+
+// Delivered indicates whether the message reached the recipient's queue,
+// either now or as an earlier copy of a duplicate.
+func (d DeliveryState) Delivered() bool {
+	return d == DeliveryEnqueued || d == DeliveryDuplicate
+}
+
+// DeliverToScreenName relays a message to a session with a matching screen
+// name, and to the session of its linked identity, if any, and reports
+// whether it was actually enqueued for the recipient. Unlike
+// RelayToScreenName, callers can use the result to decide whether to
+// acknowledge the message to the sender. received is when the message was
+// received from its sender; see SetDeliveryLatency.
+func (s *InMemorySessionManager) DeliverToScreenName(ctx context.Context, screenName IdentScreenName, msg wire.SNACMessage, received time.Time) DeliveryState {
+	sessions := s.retrieveLinkedSessions(screenName)
+	if len(sessions) == 0 {
+		s.logger.DebugContext(ctx, "can't deliver message because user is not online", "recipient", screenName)
+		return DeliveryOffline
+	}
+
+	state := DeliveryDropped
+	for _, sess := range sessions {
+		if s.maybeRelayMessage(ctx, msg, sess) == SessSendOK {
+			state = DeliveryEnqueued
+		}
+	}
+	if state == DeliveryEnqueued {
+		s.latency.ObserveSince(DeliveryPathOnline, received)
+	}
+	return state
+}
+
+// combine returns the outcome of delivering a message to several sessions
+// of the same account, given d for the sessions so far and other for the
+// next one. The message counts as enqueued if any session received it.
+func (d DeliveryState) combine(other DeliveryState) DeliveryState {
+	switch {
+	case d == DeliveryEnqueued || other == DeliveryEnqueued:
+		return DeliveryEnqueued
+	case d == DeliveryDuplicate || other == DeliveryDuplicate:
+		return DeliveryDuplicate
+	default:
+		return other
+	}
+}
+
+// HostAck returns the SNAC(0x04,0x0C) ICBMHostAck to send back to the sender
+// of inBody. The ack is only produced when the sender requested one with
+// ICBMTLVRequestHostAck and the message was enqueued for the recipient, so
+// an ack always means the recipient's session received the message, not
+// merely that the server did.
+func HostAck(inFrame wire.SNACFrame, inBody wire.SNAC_0x04_0x06_ICBMChannelMsgToHost, state DeliveryState) (wire.SNACMessage, bool) {
+	if !inBody.HasTag(wire.ICBMTLVRequestHostAck) || !state.Delivered() {
+		return wire.SNACMessage{}, false
+	}
+	return wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.ICBM,
+			SubGroup:  wire.ICBMHostAck,
+			RequestID: inFrame.RequestID,
+		},
+		Body: wire.SNAC_0x04_0x0C_ICBMHostAck{
+			Cookie:     inBody.Cookie,
+			ChannelID:  inBody.ChannelID,
+			ScreenName: inBody.ScreenName,
+		},
+	}, true
+}
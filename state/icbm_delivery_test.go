@@ -0,0 +1,173 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// sendIM simulates the ICBM send path: the message from sender is relayed to
+// the recipient named in inBody and a host ack is returned if applicable.
+func sendIM(sm *InMemorySessionManager, sender *Session, inBody wire.SNAC_0x04_0x06_ICBMChannelMsgToHost) (DeliveryState, wire.SNACMessage, bool) {
+	inFrame := wire.SNACFrame{FoodGroup: wire.ICBM, SubGroup: wire.ICBMChannelMsgToHost, RequestID: 1234}
+	clientIM := wire.SNACMessage{
+		Frame: wire.SNACFrame{FoodGroup: wire.ICBM, SubGroup: wire.ICBMChannelMsgToClient},
+		Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+			Cookie:    inBody.Cookie,
+			ChannelID: inBody.ChannelID,
+			TLVUserInfo: wire.TLVUserInfo{
+				ScreenName: sender.DisplayScreenName().Wire(),
+			},
+		},
+	}
+	state := sm.DeliverICBM(context.Background(), sender.IdentScreenName(), inBody.Cookie, NewIdentScreenNameFromWire(inBody.ScreenName), clientIM, time.Now())
+	ack, ok := HostAck(inFrame, inBody, state)
+	return state, ack, ok
+}
+
+func newAckRequestIM(cookie uint64, recipient string) wire.SNAC_0x04_0x06_ICBMChannelMsgToHost {
+	return wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+		Cookie:     cookie,
+		ChannelID:  wire.ICBMChannelIM,
+		ScreenName: wire.ScreenName(recipient),
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ICBMTLVRequestHostAck, []byte{}),
+			},
+		},
+	}
+}
+
+func TestHostAck_BothEndsRequestAck(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+
+	alice, err := sm.AddSession(context.Background(), "alice")
+	assert.NoError(t, err)
+	alice.SetSignonComplete()
+	bob, err := sm.AddSession(context.Background(), "bob")
+	assert.NoError(t, err)
+	bob.SetSignonComplete()
+
+	// alice -> bob
+	state, ack, ok := sendIM(sm, alice, newAckRequestIM(1, "bob"))
+	assert.Equal(t, DeliveryEnqueued, state)
+	assert.True(t, ok)
+	assert.Equal(t, wire.SNACMessage{
+		Frame: wire.SNACFrame{FoodGroup: wire.ICBM, SubGroup: wire.ICBMHostAck, RequestID: 1234},
+		Body: wire.SNAC_0x04_0x0C_ICBMHostAck{
+			Cookie:     1,
+			ChannelID:  wire.ICBMChannelIM,
+			ScreenName: "bob",
+		},
+	}, ack)
+	assert.Equal(t, 1, bob.QueueDepth())
+
+	// bob -> alice
+	state, ack, ok = sendIM(sm, bob, newAckRequestIM(2, "alice"))
+	assert.Equal(t, DeliveryEnqueued, state)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), ack.Body.(wire.SNAC_0x04_0x0C_ICBMHostAck).Cookie)
+	assert.Equal(t, 1, alice.QueueDepth())
+
+	have := <-bob.ReceiveMessage()
+	assert.Equal(t, uint64(1), have.Body.(wire.SNAC_0x04_0x07_ICBMChannelMsgToClient).Cookie)
+	have = <-alice.ReceiveMessage()
+	assert.Equal(t, uint64(2), have.Body.(wire.SNAC_0x04_0x07_ICBMChannelMsgToClient).Cookie)
+}
+
+func TestHostAck_NotRequested(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+
+	alice, err := sm.AddSession(context.Background(), "alice")
+	assert.NoError(t, err)
+	alice.SetSignonComplete()
+	bob, err := sm.AddSession(context.Background(), "bob")
+	assert.NoError(t, err)
+	bob.SetSignonComplete()
+
+	inBody := newAckRequestIM(1, "bob")
+	inBody.TLVList = nil
+
+	state, _, ok := sendIM(sm, alice, inBody)
+	assert.Equal(t, DeliveryEnqueued, state)
+	assert.False(t, ok)
+	assert.Equal(t, 1, bob.QueueDepth())
+}
+
+func TestHostAck_RecipientOffline(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+
+	alice, err := sm.AddSession(context.Background(), "alice")
+	assert.NoError(t, err)
+	alice.SetSignonComplete()
+
+	state, _, ok := sendIM(sm, alice, newAckRequestIM(1, "bob"))
+	assert.Equal(t, DeliveryOffline, state)
+	assert.False(t, ok)
+}
+
+func TestHostAck_RecipientSignonIncomplete(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+
+	alice, err := sm.AddSession(context.Background(), "alice")
+	assert.NoError(t, err)
+	alice.SetSignonComplete()
+	bob, err := sm.AddSession(context.Background(), "bob")
+	assert.NoError(t, err)
+
+	state, _, ok := sendIM(sm, alice, newAckRequestIM(1, "bob"))
+	assert.Equal(t, DeliveryOffline, state)
+	assert.False(t, ok)
+	assert.Zero(t, bob.QueueDepth())
+}
+
+func TestHostAck_RecipientQueueFull(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+	sm.SetMaxQueueDepth(1)
+
+	alice, err := sm.AddSession(context.Background(), "alice")
+	assert.NoError(t, err)
+	alice.SetSignonComplete()
+	bob, err := sm.AddSession(context.Background(), "bob")
+	assert.NoError(t, err)
+	bob.SetSignonComplete()
+
+	state, _, ok := sendIM(sm, alice, newAckRequestIM(1, "bob"))
+	assert.Equal(t, DeliveryEnqueued, state)
+	assert.True(t, ok)
+
+	// bob's queue is at its bound, so the message is dropped and must not
+	// be acknowledged
+	state, _, ok = sendIM(sm, alice, newAckRequestIM(2, "bob"))
+	assert.Equal(t, DeliveryDropped, state)
+	assert.False(t, ok)
+}
+
+func TestHostAck_RecipientSessionClosed(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+
+	alice, err := sm.AddSession(context.Background(), "alice")
+	assert.NoError(t, err)
+	alice.SetSignonComplete()
+	bob, err := sm.AddSession(context.Background(), "bob")
+	assert.NoError(t, err)
+	bob.SetSignonComplete()
+	bob.Close()
+
+	state, _, ok := sendIM(sm, alice, newAckRequestIM(1, "bob"))
+	assert.Equal(t, DeliveryDropped, state)
+	assert.False(t, ok)
+}
+
+func TestDeliveryState_String(t *testing.T) {
+	assert.Equal(t, "offline", DeliveryOffline.String())
+	assert.Equal(t, "enqueued", DeliveryEnqueued.String())
+	assert.Equal(t, "dropped", DeliveryDropped.String())
+	assert.Equal(t, "duplicate", DeliveryDuplicate.String())
+	assert.Equal(t, "unknown", DeliveryState(99).String())
+}
@@ -0,0 +1,172 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// DefaultICBMMaxMessageLen is the maximum ICBM message size used when none
+// is configured.
+const DefaultICBMMaxMessageLen = 8000
+
+// ICBMClass is a class of accounts that gets its own ICBM limits.
+type ICBMClass uint8
+
+const (
+	// ICBMClassStandard covers accounts that are neither bots nor guests.
+	ICBMClassStandard ICBMClass = iota
+	// ICBMClassGuest covers chat-only guest sessions.
+	ICBMClassGuest
+	// ICBMClassBot covers accounts with wire.OServiceUserFlagBot set.
+	ICBMClassBot
+	// ICBMClassProbation covers new accounts on probation. See
+	// ProbationPolicy.
+	ICBMClassProbation
+)
+
+// String returns the config name of the class.
+func (c ICBMClass) String() string {
+	switch c {
+	case ICBMClassStandard:
+		return "standard"
+	case ICBMClassGuest:
+		return "guest"
+	case ICBMClassBot:
+		return "bot"
+	case ICBMClassProbation:
+		return "probation"
+	default:
+		return "unknown"
+	}
+}
+
+var icbmClassNames = map[string]ICBMClass{
+	"standard":  ICBMClassStandard,
+	"guest":     ICBMClassGuest,
+	"bot":       ICBMClassBot,
+	"probation": ICBMClassProbation,
+}
+
+var (
+	// ErrICBMTooLong indicates that an ICBM is longer than the sender's
+	// MaxMessageLen.
+	ErrICBMTooLong = errors.New("ICBM exceeds maximum message length")
+	// ErrICBMTooFast indicates that an ICBM was sent sooner than the
+	// sender's MinInterval after their previous one.
+	ErrICBMTooFast = errors.New("ICBM sent before minimum interval elapsed")
+)
+
+// ICBMLimits are the ICBM limits that apply to a class of accounts.
+type ICBMLimits struct {
+	// MinInterval is the shortest time allowed between two ICBMs sent by
+	// a user. Zero means no limit.
+	MinInterval time.Duration
+	// MaxMessageLen is the maximum size in bytes of an ICBM message. Zero
+	// means DefaultICBMMaxMessageLen.
+	MaxMessageLen uint16
+}
+
+// ICBMParams holds the ICBM limits of each account class. The limits are
+// both advertised to clients in SNAC(0x04,0x05) ICBMParameterReply and
+// enforced by CheckICBM, so well-behaved clients never exceed them.
+type ICBMParams struct {
+	defaults ICBMLimits
+	classes  map[ICBMClass]ICBMLimits
+}
+
+// NewICBMParams creates a new instance of ICBMParams. defaults applies to
+// standard accounts and to classes not in classes, which maps class names
+// (standard, guest, bot, probation) to their limits.
+func NewICBMParams(defaults ICBMLimits, classes map[string]ICBMLimits) (*ICBMParams, error) {
+	if defaults.MaxMessageLen == 0 {
+		defaults.MaxMessageLen = DefaultICBMMaxMessageLen
+	}
+	p := &ICBMParams{
+		defaults: defaults,
+		classes:  make(map[ICBMClass]ICBMLimits, len(classes)),
+	}
+	for name, limits := range classes {
+		class, ok := icbmClassNames[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown ICBM class %q", name)
+		}
+		if limits.MaxMessageLen == 0 {
+			limits.MaxMessageLen = defaults.MaxMessageLen
+		}
+		p.classes[class] = limits
+	}
+	return p, nil
+}
+
+// ICBMClassOf returns the account class of the user of sess. A bot guest is
+// a bot.
+func ICBMClassOf(sess *Session) ICBMClass {
+	switch {
+	case sess.UserInfoBitmask()&wire.OServiceUserFlagBot == wire.OServiceUserFlagBot:
+		return ICBMClassBot
+	case sess.Guest():
+		return ICBMClassGuest
+	case sess.OnProbation():
+		return ICBMClassProbation
+	default:
+		return ICBMClassStandard
+	}
+}
+
+// Limits returns the ICBM limits that apply to the user of sess.
+func (p *ICBMParams) Limits(sess *Session) ICBMLimits {
+	if limits, ok := p.classes[ICBMClassOf(sess)]; ok {
+		return limits
+	}
+	return p.defaults
+}
+
+// ParameterReply returns the SNAC(0x04,0x05) ICBMParameterReply body that
+// tells the client of sess its ICBM limits.
+func (p *ICBMParams) ParameterReply(sess *Session) wire.SNAC_0x04_0x05_ICBMParameterReply {
+	limits := p.Limits(sess)
+	return wire.SNAC_0x04_0x05_ICBMParameterReply{
+		MaxSlots:             100,
+		ICBMFlags:            3,
+		MaxIncomingICBMLen:   limits.MaxMessageLen,
+		MaxSourceEvil:        999,
+		MaxDestinationEvil:   999,
+		MinInterICBMInterval: uint32(limits.MinInterval.Milliseconds()),
+	}
+}
+
+// CheckICBM checks an ICBM of msgLen bytes that the user of sess is about to
+// send. It returns ErrICBMTooLong or ErrICBMTooFast if the message breaks
+// the user's limits; otherwise the message counts as sent for the next
+// check. Handlers should report the error to the sender with ErrorCode.
+func (p *ICBMParams) CheckICBM(sess *Session, msgLen int) error {
+	limits := p.Limits(sess)
+	if msgLen > int(limits.MaxMessageLen) {
+		return fmt.Errorf("%w: %d > %d bytes", ErrICBMTooLong, msgLen, limits.MaxMessageLen)
+	}
+	if !sess.markICBMSent(limits.MinInterval) {
+		return ErrICBMTooFast
+	}
+	return nil
+}
+
+// markICBMSent records that the session sent an ICBM now, unless its
+// previous ICBM was sent less than minInterval ago. A recorded ICBM counts
+// as activity observed by the server, which TLVUserInfo reports in place of
+// an earlier client-reported idle time. It reports whether the ICBM was
+// recorded.
+func (s *Session) markICBMSent(minInterval time.Duration) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.nowFn()
+	if !s.lastICBMSent.IsZero() && now.Sub(s.lastICBMSent) < minInterval {
+		return false
+	}
+	s.lastICBMSent = now
+	s.lastActivity = now
+	return true
+}
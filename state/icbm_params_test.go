@@ -0,0 +1,76 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestNewICBMParams(t *testing.T) {
+	_, err := NewICBMParams(ICBMLimits{}, map[string]ICBMLimits{"admin": {}})
+	assert.ErrorContains(t, err, `unknown ICBM class "admin"`)
+
+	p, err := NewICBMParams(ICBMLimits{MinInterval: time.Second}, map[string]ICBMLimits{
+		"guest": {MinInterval: 5 * time.Second},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ICBMLimits{MinInterval: time.Second, MaxMessageLen: DefaultICBMMaxMessageLen}, p.Limits(NewSession()))
+
+	guest := NewSession()
+	guest.SetGuest(true)
+	assert.Equal(t, ICBMLimits{MinInterval: 5 * time.Second, MaxMessageLen: DefaultICBMMaxMessageLen}, p.Limits(guest))
+}
+
+func TestICBMParams_ParameterReply(t *testing.T) {
+	p, err := NewICBMParams(ICBMLimits{MinInterval: time.Second, MaxMessageLen: 2000}, map[string]ICBMLimits{
+		"bot": {MaxMessageLen: 8000},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, wire.SNAC_0x04_0x05_ICBMParameterReply{
+		MaxSlots:             100,
+		ICBMFlags:            3,
+		MaxIncomingICBMLen:   2000,
+		MaxSourceEvil:        999,
+		MaxDestinationEvil:   999,
+		MinInterICBMInterval: 1000,
+	}, p.ParameterReply(NewSession()))
+
+	bot := NewSession()
+	bot.SetUserInfoFlag(wire.OServiceUserFlagBot)
+	reply := p.ParameterReply(bot)
+	assert.Equal(t, uint16(8000), reply.MaxIncomingICBMLen)
+	assert.Zero(t, reply.MinInterICBMInterval)
+}
+
+func TestICBMParams_CheckICBM(t *testing.T) {
+	p, err := NewICBMParams(ICBMLimits{MinInterval: time.Second, MaxMessageLen: 100}, map[string]ICBMLimits{
+		"bot": {MaxMessageLen: 100},
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	sess := NewSession()
+	sess.nowFn = func() time.Time { return now }
+
+	assert.ErrorIs(t, p.CheckICBM(sess, 101), ErrICBMTooLong)
+	assert.NoError(t, p.CheckICBM(sess, 100))
+
+	now = now.Add(500 * time.Millisecond)
+	assert.ErrorIs(t, p.CheckICBM(sess, 10), ErrICBMTooFast)
+	assert.Equal(t, wire.ErrorCodeRateToHost, ErrorCode(ErrICBMTooFast))
+
+	now = now.Add(500 * time.Millisecond)
+	assert.NoError(t, p.CheckICBM(sess, 10))
+
+	// bots have no minimum interval
+	bot := NewSession()
+	bot.SetUserInfoFlag(wire.OServiceUserFlagBot)
+	for range 3 {
+		assert.NoError(t, p.CheckICBM(bot, 10))
+	}
+}
@@ -0,0 +1,129 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// ICQMoodStore persists the ICQ moods that users set.
+type ICQMoodStore interface {
+	SetICQMood(ctx context.Context, screenName IdentScreenName, mood uint8) error
+	ClearICQMood(ctx context.Context, screenName IdentScreenName) error
+	ICQMood(ctx context.Context, screenName IdentScreenName) (uint8, bool, error)
+}
+
+// ICQMoods keeps track of the ICQ 6 / Xtraz moods that users set, which
+// clients such as ICQ 6 and QIP show as an icon next to the contact. A mood
+// travels as a BARTTypesICQMood BART ID in the user info TLVs, and is kept
+// across sessions so that it reappears when the user signs on again.
+type ICQMoods struct {
+	store  ICQMoodStore
+	logger *slog.Logger
+}
+
+// NewICQMoods creates a new instance of ICQMoods.
+func NewICQMoods(store ICQMoodStore, logger *slog.Logger) *ICQMoods {
+	return &ICQMoods{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// SignOn restores the mood that the user last set onto sess, so that it
+// is part of the user info sent to buddies when the user arrives.
+func (m *ICQMoods) SignOn(ctx context.Context, sess *Session) error {
+	mood, ok, err := m.store.ICQMood(ctx, sess.IdentScreenName())
+	if err != nil {
+		return err
+	}
+	if ok {
+		sess.SetICQMood(mood)
+	}
+	return nil
+}
+
+// SetUserInfoFields applies the mood carried by a SNAC(0x01,0x1E)
+// OServiceSetUserInfoFields from sess and saves it. A mood BART ID with an
+// empty hash clears the mood. It reports whether the mood changed, in which
+// case the caller should send the user's new user info to their buddies.
+func (m *ICQMoods) SetUserInfoFields(ctx context.Context, sess *Session, body wire.SNAC_0x01_0x1E_OServiceSetUserInfoFields) (bool, error) {
+	b, ok := body.Bytes(wire.OServiceUserInfoBARTInfo)
+	if !ok {
+		return false, nil
+	}
+	ids, err := wire.UnmarshalBARTIDs(b)
+	if err != nil {
+		return false, fmt.Errorf("unable to parse BART IDs: %w", err)
+	}
+
+	for _, id := range ids {
+		if id.Type != wire.BARTTypesICQMood {
+			continue
+		}
+		oldMood, hadMood := sess.ICQMood()
+		mood, hasMood := id.ICQMood()
+		if hasMood == hadMood && mood == oldMood {
+			return false, nil
+		}
+
+		if hasMood {
+			if err := m.store.SetICQMood(ctx, sess.IdentScreenName(), mood); err != nil {
+				return false, err
+			}
+			sess.SetICQMood(mood)
+		} else {
+			if err := m.store.ClearICQMood(ctx, sess.IdentScreenName()); err != nil {
+				return false, err
+			}
+			sess.ClearICQMood()
+		}
+		m.logger.DebugContext(ctx, "ICQ mood changed", "screen_name", sess.IdentScreenName(), "mood", mood, "set", hasMood)
+		return true, nil
+	}
+	return false, nil
+}
+
+// SetICQMood saves the user's ICQ mood. It returns ErrNoUser if the user
+// doesn't exist.
+func (us SQLiteUserStore) SetICQMood(ctx context.Context, screenName IdentScreenName, mood uint8) error {
+	return us.updateICQMood(ctx, screenName, mood)
+}
+
+// ClearICQMood removes the user's ICQ mood. It returns ErrNoUser if the
+// user doesn't exist.
+func (us SQLiteUserStore) ClearICQMood(ctx context.Context, screenName IdentScreenName) error {
+	return us.updateICQMood(ctx, screenName, nil)
+}
+
+func (us SQLiteUserStore) updateICQMood(ctx context.Context, screenName IdentScreenName, mood any) error {
+	res, err := us.db.ExecContext(ctx, `UPDATE users SET icqMood = ? WHERE identScreenName = ?`, mood, screenName.String())
+	if err != nil {
+		return fmt.Errorf("updateICQMood: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("updateICQMood: %w", err)
+	}
+	if n == 0 {
+		return ErrNoUser
+	}
+	return nil
+}
+
+// ICQMood returns the user's ICQ mood. The bool is false if the user has
+// no mood. It returns ErrNoUser if the user doesn't exist.
+func (us SQLiteUserStore) ICQMood(ctx context.Context, screenName IdentScreenName) (uint8, bool, error) {
+	var mood sql.NullInt64
+	err := us.db.QueryRowContext(ctx, `SELECT icqMood FROM users WHERE identScreenName = ?`, screenName.String()).Scan(&mood)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, ErrNoUser
+	} else if err != nil {
+		return 0, false, fmt.Errorf("ICQMood: %w", err)
+	}
+	return uint8(mood.Int64), mood.Valid, nil
+}
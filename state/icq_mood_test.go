@@ -0,0 +1,111 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func newMoodUserInfoFields(t *testing.T, ids ...wire.BARTID) wire.SNAC_0x01_0x1E_OServiceSetUserInfoFields {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	require.NoError(t, wire.MarshalBE(ids, buf))
+	return wire.SNAC_0x01_0x1E_OServiceSetUserInfoFields{
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.OServiceUserInfoBARTInfo, buf.Bytes()),
+			},
+		},
+	}
+}
+
+func TestICQMoods(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	user, err := NewStubUser("100003")
+	require.NoError(t, err)
+	require.NoError(t, f.InsertUser(ctx, user))
+
+	sm := NewInMemorySessionManager(slog.Default())
+	sess, err := sm.AddSession(ctx, "100003")
+	require.NoError(t, err)
+
+	moods := NewICQMoods(f, slog.Default())
+
+	t.Run("set mood", func(t *testing.T) {
+		changed, err := moods.SetUserInfoFields(ctx, sess, newMoodUserInfoFields(t, wire.NewICQMoodBARTID(12)))
+		require.NoError(t, err)
+		assert.True(t, changed)
+
+		mood, ok := sess.ICQMood()
+		assert.True(t, ok)
+		assert.Equal(t, uint8(12), mood)
+
+		mood, ok, err = f.ICQMood(ctx, user.IdentScreenName)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, uint8(12), mood)
+	})
+
+	t.Run("same mood is not a change", func(t *testing.T) {
+		changed, err := moods.SetUserInfoFields(ctx, sess, newMoodUserInfoFields(t, wire.NewICQMoodBARTID(12)))
+		require.NoError(t, err)
+		assert.False(t, changed)
+	})
+
+	t.Run("user info carries buddy icon and mood", func(t *testing.T) {
+		icon := wire.BARTID{Type: wire.BARTTypesBuddyIcon, BARTInfo: wire.BARTInfo{Flags: wire.BARTFlagsCustom, Hash: []byte{1, 2, 3}}}
+		sess.SetBuddyIcon(icon)
+		info := sess.TLVUserInfo()
+		b, ok := info.Bytes(wire.OServiceUserInfoBARTInfo)
+		require.True(t, ok)
+		ids, err := wire.UnmarshalBARTIDs(b)
+		require.NoError(t, err)
+		assert.Equal(t, []wire.BARTID{icon, wire.NewICQMoodBARTID(12)}, ids)
+	})
+
+	t.Run("mood restored at sign on", func(t *testing.T) {
+		next, err := NewInMemorySessionManager(slog.Default()).AddSession(ctx, "100003")
+		require.NoError(t, err)
+		require.NoError(t, moods.SignOn(ctx, next))
+		mood, ok := next.ICQMood()
+		assert.True(t, ok)
+		assert.Equal(t, uint8(12), mood)
+	})
+
+	t.Run("clear mood", func(t *testing.T) {
+		changed, err := moods.SetUserInfoFields(ctx, sess, newMoodUserInfoFields(t, wire.BARTID{Type: wire.BARTTypesICQMood}))
+		require.NoError(t, err)
+		assert.True(t, changed)
+
+		_, ok := sess.ICQMood()
+		assert.False(t, ok)
+		_, ok, err = f.ICQMood(ctx, user.IdentScreenName)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("fields without mood", func(t *testing.T) {
+		changed, err := moods.SetUserInfoFields(ctx, sess, wire.SNAC_0x01_0x1E_OServiceSetUserInfoFields{})
+		require.NoError(t, err)
+		assert.False(t, changed)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		assert.ErrorIs(t, f.SetICQMood(ctx, NewIdentScreenName("nobody"), 1), ErrNoUser)
+		_, _, err := f.ICQMood(ctx, NewIdentScreenName("nobody"))
+		assert.ErrorIs(t, err, ErrNoUser)
+	})
+}
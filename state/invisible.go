@@ -0,0 +1,97 @@
+package state
+
+import (
+	"context"
+	"fmt"
+)
+
+// RelationshipFetcher retrieves privacy relationships between users.
+type RelationshipFetcher interface {
+	AllRelationships(ctx context.Context, me IdentScreenName, filter []IdentScreenName) ([]Relationship, error)
+	Relationship(ctx context.Context, me IdentScreenName, them IdentScreenName) (Relationship, error)
+}
+
+// SessionRetriever looks up signed-on sessions by screen name.
+type SessionRetriever interface {
+	RetrieveSession(screenName IdentScreenName) *Session
+}
+
+// PresenceFilter hides invisible users from everyone except the users on
+// their permit (visible) list. Invisibility only affects what others can
+// observe: messages addressed to an invisible user are still delivered.
+type PresenceFilter struct {
+	sessions      SessionRetriever
+	relationships RelationshipFetcher
+}
+
+// NewPresenceFilter creates a new instance of PresenceFilter.
+func NewPresenceFilter(sessions SessionRetriever, relationships RelationshipFetcher) PresenceFilter {
+	return PresenceFilter{
+		sessions:      sessions,
+		relationships: relationships,
+	}
+}
+
+// VisibleTo indicates whether viewer may see sess as online. Visible users
+// are seen by everyone, invisible users only by themselves and the users on
+// their permit list.
+func (p PresenceFilter) VisibleTo(ctx context.Context, sess *Session, viewer IdentScreenName) (bool, error) {
+	if !sess.Invisible() || sess.IdentScreenName() == viewer {
+		return true, nil
+	}
+
+	rel, err := p.relationships.Relationship(ctx, sess.IdentScreenName(), viewer)
+	if err != nil {
+		return false, fmt.Errorf("relationship: %w", err)
+	}
+
+	return rel.IsOnYourPermitList, nil
+}
+
+// RetrieveSession returns the session of screenName as observed by viewer.
+// It returns nil, as if the user were not logged in, when the user is
+// offline or invisible to viewer. Use SessionRetriever directly to route
+// messages, which must reach invisible users.
+func (p PresenceFilter) RetrieveSession(ctx context.Context, viewer IdentScreenName, screenName IdentScreenName) (*Session, error) {
+	sess := p.sessions.RetrieveSession(screenName)
+	if sess == nil {
+		return nil, nil
+	}
+
+	visible, err := p.VisibleTo(ctx, sess, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, nil
+	}
+
+	return sess, nil
+}
+
+// FilterViewers returns the subset of viewers who may see sess as online,
+// such as the recipients of its arrival notification.
+func (p PresenceFilter) FilterViewers(ctx context.Context, sess *Session, viewers []IdentScreenName) ([]IdentScreenName, error) {
+	if !sess.Invisible() || len(viewers) == 0 {
+		return viewers, nil
+	}
+
+	rels, err := p.relationships.AllRelationships(ctx, sess.IdentScreenName(), viewers)
+	if err != nil {
+		return nil, fmt.Errorf("all relationships: %w", err)
+	}
+
+	permitted := make(map[IdentScreenName]bool, len(rels))
+	for _, rel := range rels {
+		permitted[rel.User] = rel.IsOnYourPermitList
+	}
+
+	var ret []IdentScreenName
+	for _, viewer := range viewers {
+		if viewer == sess.IdentScreenName() || permitted[viewer] {
+			ret = append(ret, viewer)
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,80 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestPresenceFilter(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	ghost := NewIdentScreenName("ghost")
+	friend := NewIdentScreenName("friend")
+	stranger := NewIdentScreenName("stranger")
+	for _, sn := range []IdentScreenName{ghost, friend, stranger} {
+		assert.NoError(t, f.RegisterBuddyList(context.Background(), sn))
+	}
+	assert.NoError(t, f.PermitBuddy(context.Background(), ghost, friend))
+	assert.NoError(t, f.AddBuddy(context.Background(), stranger, ghost))
+
+	sm := NewInMemorySessionManager(slog.Default())
+	sess, err := sm.AddSession(context.Background(), "ghost")
+	assert.NoError(t, err)
+	sess.SetSignonComplete()
+
+	filter := NewPresenceFilter(sm, f)
+
+	t.Run("visible user is seen by everyone", func(t *testing.T) {
+		for _, viewer := range []IdentScreenName{ghost, friend, stranger} {
+			have, err := filter.RetrieveSession(context.Background(), viewer, ghost)
+			assert.NoError(t, err)
+			assert.Equal(t, sess, have)
+		}
+
+		viewers, err := filter.FilterViewers(context.Background(), sess, []IdentScreenName{friend, stranger})
+		assert.NoError(t, err)
+		assert.Equal(t, []IdentScreenName{friend, stranger}, viewers)
+	})
+
+	sess.SetUserStatusBitmask(wire.OServiceUserStatusInvisible)
+
+	t.Run("invisible user is only seen by permitted users", func(t *testing.T) {
+		have, err := filter.RetrieveSession(context.Background(), friend, ghost)
+		assert.NoError(t, err)
+		assert.Equal(t, sess, have)
+
+		have, err = filter.RetrieveSession(context.Background(), stranger, ghost)
+		assert.NoError(t, err)
+		assert.Nil(t, have)
+
+		visible, err := filter.VisibleTo(context.Background(), sess, ghost)
+		assert.NoError(t, err)
+		assert.True(t, visible)
+
+		viewers, err := filter.FilterViewers(context.Background(), sess, []IdentScreenName{friend, stranger})
+		assert.NoError(t, err)
+		assert.Equal(t, []IdentScreenName{friend}, viewers)
+	})
+
+	t.Run("IMs still reach invisible user", func(t *testing.T) {
+		state := sm.DeliverToScreenName(context.Background(), ghost, wire.SNACMessage{}, time.Now())
+		assert.Equal(t, DeliveryEnqueued, state)
+	})
+
+	t.Run("offline user", func(t *testing.T) {
+		have, err := filter.RetrieveSession(context.Background(), friend, NewIdentScreenName("nobody"))
+		assert.NoError(t, err)
+		assert.Nil(t, have)
+	})
+}
@@ -0,0 +1,86 @@
+package state
+
+// SetLinkedScreenNames registers the identities of an account that has an
+// AIM screen name linked to an ICQ UIN, as returned by
+// SQLiteUserStore.LinkedScreenNames, so that messages addressed to either
+// identity reach the signed-on sessions of both, and either identity
+// appears online while the other is signed on. It should be called when one
+// of the identities signs on. A list with only the first identity removes
+// any link previously registered for it. Unlike aliases, links remain
+// registered after the sessions are removed, so that their departure can
+// still be announced for both identities.
+func (s *InMemorySessionManager) SetLinkedScreenNames(screenNames []IdentScreenName) {
+	if len(screenNames) == 0 {
+		return
+	}
+
+	s.mapMutex.Lock()
+	defer s.mapMutex.Unlock()
+
+	if linked, ok := s.links[screenNames[0]]; ok {
+		delete(s.links, linked)
+		delete(s.links, screenNames[0])
+	}
+	if len(screenNames) > 1 {
+		s.links[screenNames[0]] = screenNames[1]
+		s.links[screenNames[1]] = screenNames[0]
+	}
+}
+
+// RetrievePresenceSession returns the session that screenName's presence
+// reflects: its own session, or else the session of its linked identity.
+// Returns nil if neither is signed on.
+func (s *InMemorySessionManager) RetrievePresenceSession(screenName IdentScreenName) *Session {
+	s.mapMutex.RLock()
+	defer s.mapMutex.RUnlock()
+
+	if sessions := s.linkedSessions(screenName); len(sessions) > 0 {
+		return sessions[0]
+	}
+	return nil
+}
+
+// PresenceMirrors returns the linked identity of screenName if it has no
+// signed-on session of its own, in which case its presence follows
+// screenName's. Watchers of the returned identities should be sent the
+// same arrival or departure as the watchers of screenName.
+func (s *InMemorySessionManager) PresenceMirrors(screenName IdentScreenName) []IdentScreenName {
+	s.mapMutex.RLock()
+	defer s.mapMutex.RUnlock()
+
+	linked, ok := s.links[s.resolveAlias(screenName)]
+	if !ok {
+		return nil
+	}
+	if rec, ok := s.store[linked]; ok && rec.sess.SignonComplete() {
+		return nil
+	}
+	return []IdentScreenName{linked}
+}
+
+// linkedSessions returns the signed-on sessions of screenName and of its
+// linked identity, starting with screenName's own. The caller must hold
+// mapMutex.
+func (s *InMemorySessionManager) linkedSessions(screenName IdentScreenName) []*Session {
+	screenName = s.resolveAlias(screenName)
+	screenNames := []IdentScreenName{screenName}
+	if linked, ok := s.links[screenName]; ok {
+		screenNames = append(screenNames, linked)
+	}
+
+	var sessions []*Session
+	for _, sn := range screenNames {
+		if rec, ok := s.store[sn]; ok && rec.sess.SignonComplete() {
+			sessions = append(sessions, rec.sess)
+		}
+	}
+	return sessions
+}
+
+// retrieveLinkedSessions returns the signed-on sessions of screenName and
+// of its linked identity.
+func (s *InMemorySessionManager) retrieveLinkedSessions(screenName IdentScreenName) []*Session {
+	s.mapMutex.RLock()
+	defer s.mapMutex.RUnlock()
+	return s.linkedSessions(screenName)
+}
@@ -0,0 +1,87 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestInMemorySessionManager_LinkedScreenNames(t *testing.T) {
+	ctx := context.Background()
+	aim := NewIdentScreenName("alice")
+	icq := NewIdentScreenName("100003")
+
+	newSession := func(t *testing.T, sm *InMemorySessionManager, sn DisplayScreenName) *Session {
+		sess, err := sm.AddSession(ctx, sn)
+		require.NoError(t, err)
+		sess.SetSignonComplete()
+		return sess
+	}
+
+	t.Run("message reaches both identities", func(t *testing.T) {
+		sm := NewInMemorySessionManager(slog.Default())
+		sm.SetLinkedScreenNames([]IdentScreenName{aim, icq})
+		bob := newSession(t, sm, "bob")
+		aimSess := newSession(t, sm, "alice")
+		icqSess := newSession(t, sm, "100003")
+
+		state, _, ok := sendIM(sm, bob, newAckRequestIM(1, "100003"))
+		assert.Equal(t, DeliveryEnqueued, state)
+		assert.True(t, ok)
+		assert.Equal(t, 1, aimSess.QueueDepth())
+		assert.Equal(t, 1, icqSess.QueueDepth())
+
+		state, _, _ = sendIM(sm, bob, newAckRequestIM(1, "alice"))
+		assert.Equal(t, DeliveryDuplicate, state)
+	})
+
+	t.Run("message to an identity that is signed off", func(t *testing.T) {
+		sm := NewInMemorySessionManager(slog.Default())
+		sm.SetLinkedScreenNames([]IdentScreenName{icq, aim})
+		bob := newSession(t, sm, "bob")
+		aimSess := newSession(t, sm, "alice")
+
+		state, _, _ := sendIM(sm, bob, newAckRequestIM(1, "100003"))
+		assert.Equal(t, DeliveryEnqueued, state)
+		assert.Equal(t, 1, aimSess.QueueDepth())
+		assert.Equal(t, DeliveryEnqueued, sm.DeliverToScreenName(ctx, icq, wire.SNACMessage{}, time.Now()))
+		assert.Equal(t, 2, aimSess.QueueDepth())
+	})
+
+	t.Run("presence follows the linked session", func(t *testing.T) {
+		sm := NewInMemorySessionManager(slog.Default())
+		sm.SetLinkedScreenNames([]IdentScreenName{aim, icq})
+		aimSess := newSession(t, sm, "alice")
+
+		assert.Same(t, aimSess, sm.RetrievePresenceSession(icq))
+		assert.Nil(t, sm.RetrieveSession(icq))
+		assert.Equal(t, []IdentScreenName{icq}, sm.PresenceMirrors(aim))
+
+		icqSess := newSession(t, sm, "100003")
+		assert.Same(t, icqSess, sm.RetrievePresenceSession(icq))
+		assert.Empty(t, sm.PresenceMirrors(aim))
+
+		// the link outlives the session, so the departure is mirrored too
+		sm.RemoveSession(icqSess)
+		sm.RemoveSession(aimSess)
+		assert.Nil(t, sm.RetrievePresenceSession(icq))
+		assert.Equal(t, []IdentScreenName{icq}, sm.PresenceMirrors(aim))
+	})
+
+	t.Run("unlinked", func(t *testing.T) {
+		sm := NewInMemorySessionManager(slog.Default())
+		sm.SetLinkedScreenNames([]IdentScreenName{aim, icq})
+		sm.SetLinkedScreenNames([]IdentScreenName{icq})
+		newSession(t, sm, "alice")
+
+		assert.Nil(t, sm.RetrievePresenceSession(icq))
+		assert.Empty(t, sm.PresenceMirrors(aim))
+		assert.Equal(t, DeliveryOffline, sm.DeliverToScreenName(ctx, icq, wire.SNACMessage{}, time.Now()))
+	})
+}
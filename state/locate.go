@@ -0,0 +1,137 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// DefaultLocateRightsLimit is used for any LocateRights limit left at zero.
+const DefaultLocateRightsLimit uint16 = 1000
+
+// ErrLocateRightsExceeded indicates that a SetInfo request exceeds one of the
+// limits advertised in the locate rights reply.
+var ErrLocateRightsExceeded = errors.New("locate rights exceeded")
+
+// LocateRights holds the limits advertised to clients in
+// SNAC(0x02,0x03) LocateRightsReply and enforced on SNAC(0x02,0x04)
+// LocateSetInfo. Zero values fall back to DefaultLocateRightsLimit.
+type LocateRights struct {
+	// MaxSigLen is the max length of the profile and away message.
+	MaxSigLen uint16
+	// MaxCapabilities is the max number of capability UUIDs.
+	MaxCapabilities uint16
+	// MaxFindByEmailList is the max number of email addresses to look up
+	// at once.
+	MaxFindByEmailList uint16
+	// MaxCertsLen is the max length of the encryption certificate.
+	MaxCertsLen uint16
+}
+
+func (r LocateRights) orDefault(val uint16) uint16 {
+	if val == 0 {
+		return DefaultLocateRightsLimit
+	}
+	return val
+}
+
+// Reply builds the locate rights reply advertising the configured limits.
+func (r LocateRights) Reply() wire.SNAC_0x02_0x03_LocateRightsReply {
+	return wire.SNAC_0x02_0x03_LocateRightsReply{
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.LocateTLVTagsRightsMaxSigLen, r.orDefault(r.MaxSigLen)),
+				wire.NewTLVBE(wire.LocateTLVTagsRightsMaxCapabilitiesLen, r.orDefault(r.MaxCapabilities)),
+				wire.NewTLVBE(wire.LocateTLVTagsRightsMaxFindByEmailList, r.orDefault(r.MaxFindByEmailList)),
+				wire.NewTLVBE(wire.LocateTLVTagsRightsMaxCertsLen, r.orDefault(r.MaxCertsLen)),
+				wire.NewTLVBE(wire.LocateTLVTagsRightsMaxMaxShortCapabilities, r.orDefault(r.MaxCapabilities)),
+			},
+		},
+	}
+}
+
+// CheckSetInfo returns ErrLocateRightsExceeded if the profile, away message,
+// capabilities or certificate in a SetInfo request exceed the limits.
+func (r LocateRights) CheckSetInfo(inBody wire.SNAC_0x02_0x04_LocateSetInfo) error {
+	maxSigLen := int(r.orDefault(r.MaxSigLen))
+	if profile, ok := inBody.Bytes(wire.LocateTLVTagsInfoSigData); ok && len(profile) > maxSigLen {
+		return fmt.Errorf("%w: profile length %d exceeds %d", ErrLocateRightsExceeded, len(profile), maxSigLen)
+	}
+	if awayMsg, ok := inBody.Bytes(wire.LocateTLVTagsInfoUnavailableData); ok && len(awayMsg) > maxSigLen {
+		return fmt.Errorf("%w: away message length %d exceeds %d", ErrLocateRightsExceeded, len(awayMsg), maxSigLen)
+	}
+
+	maxCaps := int(r.orDefault(r.MaxCapabilities))
+	if caps, ok := inBody.Bytes(wire.LocateTLVTagsInfoCapabilities); ok && len(caps)/16 > maxCaps {
+		return fmt.Errorf("%w: %d capabilities exceeds %d", ErrLocateRightsExceeded, len(caps)/16, maxCaps)
+	}
+
+	maxCertsLen := int(r.orDefault(r.MaxCertsLen))
+	if certs, ok := inBody.Bytes(wire.LocateTLVTagsInfoCerts); ok && len(certs) > maxCertsLen {
+		return fmt.Errorf("%w: certificate length %d exceeds %d", ErrLocateRightsExceeded, len(certs), maxCertsLen)
+	}
+
+	return nil
+}
+
+// InfoChangeNotifier tells a user's watchers, the signed-on users who have
+// them on their buddy list, that their profile or away message changed. It
+// sends each watcher a SNAC(0x03,0x0B) BuddyArrived carrying the user's
+// current user info. Every client picks up the away flag from it, and
+// clients that cache profiles and away messages compare the update times in
+// it to decide whether to fetch them again, so changes appear without the
+// watcher re-querying.
+type InfoChangeNotifier struct {
+	sessions      SessionRetriever
+	relationships RelationshipFetcher
+	logger        *slog.Logger
+}
+
+// NewInfoChangeNotifier creates a new instance of InfoChangeNotifier.
+func NewInfoChangeNotifier(sessions SessionRetriever, relationships RelationshipFetcher, logger *slog.Logger) InfoChangeNotifier {
+	return InfoChangeNotifier{
+		sessions:      sessions,
+		relationships: relationships,
+		logger:        logger,
+	}
+}
+
+// InfoChanged notifies the watchers of sess. It should be called after the
+// session's profile or away message is set. Watchers who block the user or
+// are blocked by them aren't notified, and neither are watchers the user is
+// invisible to.
+func (n InfoChangeNotifier) InfoChanged(ctx context.Context, sess *Session) error {
+	rels, err := n.relationships.AllRelationships(ctx, sess.IdentScreenName(), nil)
+	if err != nil {
+		return fmt.Errorf("all relationships: %w", err)
+	}
+
+	msg := wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.Buddy,
+			SubGroup:  wire.BuddyArrived,
+		},
+		Body: wire.SNAC_0x03_0x0B_BuddyArrived{
+			TLVUserInfo: sess.TLVUserInfo(),
+		},
+	}
+
+	invisible := sess.Invisible()
+	for _, rel := range rels {
+		if !rel.IsOnTheirList || rel.BlocksYou || rel.YouBlock || (invisible && !rel.IsOnYourPermitList) {
+			continue
+		}
+		watcher := n.sessions.RetrieveSession(rel.User)
+		if watcher == nil {
+			continue
+		}
+		if watcher.RelayMessage(msg) != SessSendOK {
+			n.logger.DebugContext(ctx, "unable to send info change notification", "screen_name", rel.User)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/pchchv/go-icq/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocateRights_Reply(t *testing.T) {
+	reply := LocateRights{MaxSigLen: 4096, MaxCertsLen: 512}.Reply()
+
+	sigLen, ok := reply.Uint16BE(wire.LocateTLVTagsRightsMaxSigLen)
+	assert.True(t, ok)
+	assert.Equal(t, uint16(4096), sigLen)
+
+	caps, ok := reply.Uint16BE(wire.LocateTLVTagsRightsMaxCapabilitiesLen)
+	assert.True(t, ok)
+	assert.Equal(t, DefaultLocateRightsLimit, caps)
+
+	emails, ok := reply.Uint16BE(wire.LocateTLVTagsRightsMaxFindByEmailList)
+	assert.True(t, ok)
+	assert.Equal(t, DefaultLocateRightsLimit, emails)
+
+	certs, ok := reply.Uint16BE(wire.LocateTLVTagsRightsMaxCertsLen)
+	assert.True(t, ok)
+	assert.Equal(t, uint16(512), certs)
+}
+
+func TestLocateRights_CheckSetInfo(t *testing.T) {
+	rights := LocateRights{MaxSigLen: 10, MaxCapabilities: 2, MaxCertsLen: 4}
+
+	tests := []struct {
+		name    string
+		tlvs    wire.TLVList
+		wantErr error
+	}{
+		{
+			name: "within limits",
+			tlvs: wire.TLVList{
+				wire.NewTLVBE(wire.LocateTLVTagsInfoSigData, []byte("profile")),
+				wire.NewTLVBE(wire.LocateTLVTagsInfoUnavailableData, []byte("away")),
+				wire.NewTLVBE(wire.LocateTLVTagsInfoCapabilities, bytes.Repeat([]byte{0x01}, 32)),
+				wire.NewTLVBE(wire.LocateTLVTagsInfoCerts, []byte{1, 2, 3, 4}),
+			},
+		},
+		{
+			name: "profile too long",
+			tlvs: wire.TLVList{
+				wire.NewTLVBE(wire.LocateTLVTagsInfoSigData, []byte("this profile is too long")),
+			},
+			wantErr: ErrLocateRightsExceeded,
+		},
+		{
+			name: "away message too long",
+			tlvs: wire.TLVList{
+				wire.NewTLVBE(wire.LocateTLVTagsInfoUnavailableData, []byte("gone for a long, long time")),
+			},
+			wantErr: ErrLocateRightsExceeded,
+		},
+		{
+			name: "too many capabilities",
+			tlvs: wire.TLVList{
+				wire.NewTLVBE(wire.LocateTLVTagsInfoCapabilities, bytes.Repeat([]byte{0x01}, 48)),
+			},
+			wantErr: ErrLocateRightsExceeded,
+		},
+		{
+			name: "certificate too long",
+			tlvs: wire.TLVList{
+				wire.NewTLVBE(wire.LocateTLVTagsInfoCerts, []byte{1, 2, 3, 4, 5}),
+			},
+			wantErr: ErrLocateRightsExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inBody := wire.SNAC_0x02_0x04_LocateSetInfo{
+				TLVRestBlock: wire.TLVRestBlock{TLVList: tt.tlvs},
+			}
+			assert.ErrorIs(t, rights.CheckSetInfo(inBody), tt.wantErr)
+		})
+	}
+}
+
+func TestInfoChangeNotifier_InfoChanged(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	me := NewIdentScreenName("me")
+	watcher := NewIdentScreenName("watcher")
+	blocker := NewIdentScreenName("blocker")
+	stranger := NewIdentScreenName("stranger")
+	for _, sn := range []IdentScreenName{me, watcher, blocker, stranger} {
+		require.NoError(t, f.RegisterBuddyList(ctx, sn))
+	}
+	require.NoError(t, f.AddBuddy(ctx, watcher, me))
+	require.NoError(t, f.AddBuddy(ctx, blocker, me))
+	require.NoError(t, f.SetPDMode(ctx, blocker, wire.FeedbagPDModeDenySome))
+	require.NoError(t, f.DenyBuddy(ctx, blocker, me))
+
+	sm := NewInMemorySessionManager(slog.Default())
+	sessions := make(map[IdentScreenName]*Session)
+	for _, sn := range []IdentScreenName{me, watcher, blocker, stranger} {
+		sess, err := sm.AddSession(ctx, DisplayScreenName(sn.String()))
+		require.NoError(t, err)
+		sess.SetSignonComplete()
+		sessions[sn] = sess
+	}
+
+	received := func(sn IdentScreenName) (wire.SNACMessage, bool) {
+		select {
+		case msg := <-sessions[sn].ReceiveMessage():
+			return msg, true
+		default:
+			return wire.SNACMessage{}, false
+		}
+	}
+
+	n := NewInfoChangeNotifier(sm, f, slog.Default())
+
+	sessions[me].SetAwayMessage("gone fishing")
+	require.NoError(t, n.InfoChanged(ctx, sessions[me]))
+
+	msg, ok := received(watcher)
+	require.True(t, ok)
+	assert.Equal(t, wire.SNACFrame{FoodGroup: wire.Buddy, SubGroup: wire.BuddyArrived}, msg.Frame)
+	info := msg.Body.(wire.SNAC_0x03_0x0B_BuddyArrived).TLVUserInfo
+	assert.Equal(t, wire.ScreenName("me"), info.ScreenName)
+	flags, _ := info.Uint16BE(wire.OServiceUserInfoUserFlags)
+	assert.Equal(t, wire.OServiceUserFlagUnavailable, flags&wire.OServiceUserFlagUnavailable)
+	assert.True(t, info.HasTag(wire.OServiceUserInfoAwayTime))
+
+	_, ok = received(blocker)
+	assert.False(t, ok, "watcher who blocks the user was notified")
+	_, ok = received(stranger)
+	assert.False(t, ok, "user without a relationship was notified")
+
+	t.Run("invisible users only notify their permit list", func(t *testing.T) {
+		sessions[me].SetUserStatusBitmask(wire.OServiceUserStatusInvisible)
+		require.NoError(t, n.InfoChanged(ctx, sessions[me]))
+		_, ok := received(watcher)
+		assert.False(t, ok)
+
+		require.NoError(t, f.PermitBuddy(ctx, me, watcher))
+		require.NoError(t, n.InfoChanged(ctx, sessions[me]))
+		_, ok = received(watcher)
+		assert.True(t, ok)
+	})
+}
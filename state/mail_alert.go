@@ -0,0 +1,181 @@
+package state
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// MailStatus is the state of a user's mailbox as reported by a MailSource.
+type MailStatus struct {
+	// ScreenName is the owner of the mailbox.
+	ScreenName IdentScreenName
+	// Unread is the number of unread messages.
+	Unread int
+	// URL is the address of the web mailbox, opened when the user clicks
+	// the mail icon.
+	URL string
+	// Domain is the mail domain shown by the client, such as aol.com.
+	Domain string
+}
+
+// MailSource reports mailbox changes, such as an IMAP poller would. Poll
+// returns the status of every mailbox that changed since the last call.
+type MailSource interface {
+	Poll(ctx context.Context) ([]MailStatus, error)
+}
+
+// MailNotifier sends "You've got mail" notifications to AIM clients using
+// the Alert food group. Mail status is fed to it by an operator-supplied
+// MailSource or by posts to WebhookHandler. The latest status of each
+// mailbox is kept so that users who sign on later still see their unread
+// count. A MailNotifier is safe for concurrent use by multiple goroutines.
+type MailNotifier struct {
+	sessions SessionRetriever
+	logger   *slog.Logger
+	mutex    sync.Mutex
+	statuses map[IdentScreenName]MailStatus
+}
+
+// NewMailNotifier creates a new instance of MailNotifier.
+func NewMailNotifier(sessions SessionRetriever, logger *slog.Logger) *MailNotifier {
+	return &MailNotifier{
+		sessions: sessions,
+		logger:   logger,
+		statuses: make(map[IdentScreenName]MailStatus),
+	}
+}
+
+// Update records a mailbox's status and notifies its owner if they are
+// signed on. The client plays the new mail alert only if the unread count
+// went up.
+func (n *MailNotifier) Update(ctx context.Context, status MailStatus) {
+	n.mutex.Lock()
+	prev := n.statuses[status.ScreenName]
+	if status.Unread > 0 {
+		n.statuses[status.ScreenName] = status
+	} else {
+		delete(n.statuses, status.ScreenName)
+	}
+	n.mutex.Unlock()
+
+	sess := n.sessions.RetrieveSession(status.ScreenName)
+	if sess == nil {
+		return
+	}
+	if sess.RelayMessage(MailAlertNotify(status, status.Unread > prev.Unread)) != SessSendOK {
+		n.logger.DebugContext(ctx, "unable to send mail notification", "screen_name", status.ScreenName)
+	}
+}
+
+// SignOn sends sess the latest known status of its owner's mailbox, if they
+// have unread mail. It should be called once the session's sign-on is
+// complete.
+func (n *MailNotifier) SignOn(sess *Session) {
+	n.mutex.Lock()
+	status, ok := n.statuses[sess.IdentScreenName()]
+	n.mutex.Unlock()
+
+	if ok {
+		sess.RelayMessage(MailAlertNotify(status, true))
+	}
+}
+
+// Schedule adds a "mail_poll" job to s that feeds the changes reported by
+// src to Update every interval.
+func (n *MailNotifier) Schedule(s *Scheduler, src MailSource, interval time.Duration) error {
+	return s.Add("mail_poll", interval, interval/10, func(ctx context.Context) error {
+		statuses, err := src.Poll(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			n.Update(ctx, status)
+		}
+		return nil
+	})
+}
+
+// mailWebhookRequest is the body accepted by WebhookHandler.
+type mailWebhookRequest struct {
+	ScreenName string `json:"screen_name"`
+	Unread     int    `json:"unread"`
+	URL        string `json:"url"`
+	Domain     string `json:"domain"`
+}
+
+// WebhookHandler accepts mailbox updates pushed by a mail system as a JSON
+// POST of the form:
+//
+//	{"screen_name": "...", "unread": 3, "url": "...", "domain": "..."}
+//
+// Requests must carry secret in the Authorization header as a bearer token.
+// It responds 204 once the update is applied.
+func (n *MailNotifier) WebhookHandler(secret string) http.HandlerFunc {
+	want := []byte("Bearer " + secret)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+		if secret == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			http.Error(w, "Unauthorized.", http.StatusUnauthorized)
+			return
+		}
+
+		req := mailWebhookRequest{}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body.", http.StatusBadRequest)
+			return
+		}
+		if req.ScreenName == "" || req.Unread < 0 {
+			http.Error(w, "Invalid mail status.", http.StatusBadRequest)
+			return
+		}
+
+		n.Update(r.Context(), MailStatus{
+			ScreenName: NewIdentScreenName(req.ScreenName),
+			Unread:     req.Unread,
+			URL:        req.URL,
+			Domain:     req.Domain,
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// MailAlertNotify builds the SNAC(0x18,0x07) AlertNotify that shows status
+// in the client's mail indicator. alert asks the client to play the new
+// mail sound.
+func MailAlertNotify(status MailStatus, alert bool) wire.SNACMessage {
+	tlvs := wire.TLVList{
+		wire.NewTLVBE(wire.AlertTLVMailCount, uint16(min(status.Unread, 0xFFFF))),
+		wire.NewTLVBE(wire.AlertTLVMailURL, status.URL),
+		wire.NewTLVBE(wire.AlertTLVMailDomain, status.Domain),
+	}
+	if alert && status.Unread > 0 {
+		tlvs = append(tlvs,
+			wire.NewTLVBE(wire.AlertTLVMailNew, uint8(1)),
+			wire.NewTLVBE(wire.AlertTLVMailAlertFlags, uint16(1)),
+		)
+	}
+
+	return wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.Alert,
+			SubGroup:  wire.AlertNotify,
+		},
+		Body: wire.SNAC_0x18_0x07_AlertNotify{
+			Cookie:   rand.Uint64(),
+			Service:  wire.AlertServiceMail,
+			TLVBlock: wire.TLVBlock{TLVList: tlvs},
+		},
+	}
+}
@@ -0,0 +1,162 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+type stubMailSource struct {
+	statuses []MailStatus
+}
+
+func (s *stubMailSource) Poll(ctx context.Context) ([]MailStatus, error) {
+	statuses := s.statuses
+	s.statuses = nil
+	return statuses, nil
+}
+
+func receiveMailAlert(t *testing.T, sess *Session) wire.SNAC_0x18_0x07_AlertNotify {
+	t.Helper()
+	select {
+	case msg := <-sess.ReceiveMessage():
+		assert.Equal(t, wire.SNACFrame{FoodGroup: wire.Alert, SubGroup: wire.AlertNotify}, msg.Frame)
+		body, ok := msg.Body.(wire.SNAC_0x18_0x07_AlertNotify)
+		require.True(t, ok)
+
+		// the notification must survive a round trip through the wire
+		buf := &bytes.Buffer{}
+		require.NoError(t, wire.MarshalBE(body, buf))
+		have := wire.SNAC_0x18_0x07_AlertNotify{}
+		require.NoError(t, wire.UnmarshalBEStrict(&have, buf.Bytes()))
+		return have
+	case <-time.After(time.Second):
+		t.Fatal("no mail notification")
+	}
+	return wire.SNAC_0x18_0x07_AlertNotify{}
+}
+
+func TestMailNotifier_Update(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+	sess, err := sm.AddSession(context.Background(), "MailMary")
+	require.NoError(t, err)
+	sess.SetSignonComplete()
+
+	n := NewMailNotifier(sm, slog.Default())
+	status := MailStatus{ScreenName: sess.IdentScreenName(), Unread: 2, URL: "https://mail.example.com", Domain: "example.com"}
+
+	n.Update(context.Background(), status)
+	notify := receiveMailAlert(t, sess)
+	assert.Equal(t, wire.AlertServiceMail, notify.Service)
+	count, ok := notify.Uint16BE(wire.AlertTLVMailCount)
+	assert.True(t, ok)
+	assert.Equal(t, uint16(2), count)
+	url, _ := notify.String(wire.AlertTLVMailURL)
+	assert.Equal(t, "https://mail.example.com", url)
+	assert.True(t, notify.HasTag(wire.AlertTLVMailNew))
+
+	// reading mail updates the count without an alert
+	status.Unread = 1
+	n.Update(context.Background(), status)
+	notify = receiveMailAlert(t, sess)
+	count, _ = notify.Uint16BE(wire.AlertTLVMailCount)
+	assert.Equal(t, uint16(1), count)
+	assert.False(t, notify.HasTag(wire.AlertTLVMailNew))
+}
+
+func TestMailNotifier_SignOn(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+	n := NewMailNotifier(sm, slog.Default())
+
+	// mail arrives while the user is offline
+	n.Update(context.Background(), MailStatus{ScreenName: NewIdentScreenName("MailMary"), Unread: 5})
+	n.Update(context.Background(), MailStatus{ScreenName: NewIdentScreenName("NoMailNed"), Unread: 0})
+
+	sess, err := sm.AddSession(context.Background(), "MailMary")
+	require.NoError(t, err)
+	n.SignOn(sess)
+	notify := receiveMailAlert(t, sess)
+	count, _ := notify.Uint16BE(wire.AlertTLVMailCount)
+	assert.Equal(t, uint16(5), count)
+
+	other, err := sm.AddSession(context.Background(), "NoMailNed")
+	require.NoError(t, err)
+	n.SignOn(other)
+	assert.Zero(t, other.QueueDepth())
+}
+
+func TestMailNotifier_Schedule(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+	sess, err := sm.AddSession(context.Background(), "MailMary")
+	require.NoError(t, err)
+	sess.SetSignonComplete()
+
+	n := NewMailNotifier(sm, slog.Default())
+	s := NewScheduler(slog.Default())
+	src := &stubMailSource{statuses: []MailStatus{{ScreenName: sess.IdentScreenName(), Unread: 1}}}
+	require.NoError(t, n.Schedule(s, src, time.Hour))
+
+	s.Start(context.Background())
+	defer s.Stop()
+	receiveMailAlert(t, sess)
+}
+
+func TestMailNotifier_WebhookHandler(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+	sess, err := sm.AddSession(context.Background(), "MailMary")
+	require.NoError(t, err)
+	sess.SetSignonComplete()
+
+	n := NewMailNotifier(sm, slog.Default())
+	handler := n.WebhookHandler("s3cret")
+
+	tests := []struct {
+		name     string
+		auth     string
+		body     string
+		wantCode int
+	}{
+		{
+			name:     "update applied",
+			auth:     "Bearer s3cret",
+			body:     `{"screen_name": "Mail Mary", "unread": 3}`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "wrong secret",
+			auth:     "Bearer guess",
+			body:     `{"screen_name": "Mail Mary", "unread": 3}`,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "bad body",
+			auth:     "Bearer s3cret",
+			body:     `{"unread": -1}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/mail", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", tt.auth)
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+
+	notify := receiveMailAlert(t, sess)
+	count, _ := notify.Uint16BE(wire.AlertTLVMailCount)
+	assert.Equal(t, uint16(3), count)
+}
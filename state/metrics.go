@@ -0,0 +1,60 @@
+package state
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// metricsWriter writes metrics in the Prometheus text exposition format,
+// which the Handler methods serve for scraping from the management API's
+// /metrics endpoint.
+type metricsWriter struct {
+	w io.Writer
+}
+
+// newMetricsWriter sets the Prometheus text format content type on w and
+// returns a metricsWriter that writes to it.
+func newMetricsWriter(w http.ResponseWriter) metricsWriter {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	return metricsWriter{w: w}
+}
+
+// family writes the HELP and TYPE lines that precede the samples of a
+// metric. kind is the metric type, such as "counter" or "gauge".
+func (m metricsWriter) family(name, help, kind string) {
+	fmt.Fprintf(m.w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(m.w, "# TYPE %s %s\n", name, kind)
+}
+
+// sample writes one sample of a metric. labels holds label names, each
+// followed by its value.
+func (m metricsWriter) sample(name string, value any, labels ...string) {
+	var sb strings.Builder
+	sb.WriteString(name)
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i == 0 {
+			sb.WriteByte('{')
+		} else {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, "%s=%q", labels[i], labels[i+1])
+	}
+	if len(labels) > 1 {
+		sb.WriteByte('}')
+	}
+	fmt.Fprintf(m.w, "%s %v\n", sb.String(), value)
+}
+
+// counter writes a counter with a single unlabeled sample.
+func (m metricsWriter) counter(name, help string, value uint64) {
+	m.family(name, help, "counter")
+	m.sample(name, value)
+}
+
+// gauge writes a gauge with a single unlabeled sample.
+func (m metricsWriter) gauge(name, help string, value int) {
+	m.family(name, help, "gauge")
+	m.sample(name, value)
+}
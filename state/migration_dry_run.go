@@ -0,0 +1,160 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// PendingMigration is a schema migration that hasn't been applied to a
+// database yet.
+type PendingMigration struct {
+	// Version is the migration's sequence number.
+	Version uint
+	// Name is the migration's file name without the version and suffix,
+	// such as "screen_name_alias".
+	Name string
+	// Duration is how long the migration took against the copy of the
+	// database. It is zero if the migration wasn't run.
+	Duration time.Duration
+}
+
+// MigrationDryRun is the result of MigrateDryRun.
+type MigrationDryRun struct {
+	// CurrentVersion is the database's schema version, or 0 if no migration
+	// has been applied.
+	CurrentVersion uint
+	// Dirty indicates that a previous migration failed part way, which must
+	// be fixed by hand before migrations can run.
+	Dirty bool
+	// Pending lists the migrations that would be applied, in order.
+	Pending []PendingMigration
+	// Applied is the number of pending migrations that ran successfully
+	// against the copy.
+	Applied int
+	// Duration is the total time taken by the migrations that ran.
+	Duration time.Duration
+}
+
+// MigrateDryRun reports the migrations that NewSQLiteUserStore would apply
+// to the database at dbFilePath and runs them against a temporary copy of
+// it, so that their duration can be estimated and failures caught before
+// production data is touched. The database is opened read-only and may be
+// in use by a running server. It returns an error naming the failing
+// migration if one fails; the returned report covers the migrations up to
+// that point.
+func MigrateDryRun(ctx context.Context, dbFilePath string) (MigrationDryRun, error) {
+	report := MigrationDryRun{}
+
+	if _, err := os.Stat(dbFilePath); err != nil {
+		return report, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "icq-migrate-dry-run-*")
+	if err != nil {
+		return report, fmt.Errorf("unable to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	copyPath := filepath.Join(tmpDir, "copy.db")
+
+	if err := copyDatabase(ctx, dbFilePath, copyPath); err != nil {
+		return report, err
+	}
+
+	db, err := sql.Open("sqlite", sqliteDSN(copyPath, "_pragma=foreign_keys=on"))
+	if err != nil {
+		return report, err
+	}
+	db.SetMaxOpenConns(1)
+
+	m, src, err := newMigrate(db)
+	if err != nil {
+		_ = db.Close()
+		return report, err
+	}
+	defer m.Close()
+
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return report, fmt.Errorf("unable to read schema version: %w", err)
+	}
+	report.CurrentVersion = version
+	report.Dirty = dirty
+
+	if report.Pending, err = pendingMigrations(src, version, errors.Is(err, migrate.ErrNilVersion)); err != nil {
+		return report, err
+	}
+	if dirty {
+		return report, fmt.Errorf("schema version %d is dirty", version)
+	}
+
+	for i := range report.Pending {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		start := time.Now()
+		err := m.Steps(1)
+		report.Pending[i].Duration = time.Since(start)
+		report.Duration += report.Pending[i].Duration
+		if err != nil {
+			return report, fmt.Errorf("migration %d (%s) failed: %w", report.Pending[i].Version, report.Pending[i].Name, err)
+		}
+		report.Applied++
+	}
+
+	return report, nil
+}
+
+// copyDatabase writes a consistent snapshot of the database at src to the
+// new file dst without writing to src.
+func copyDatabase(ctx context.Context, src, dst string) error {
+	db, err := sql.Open("sqlite", sqliteDSN(src, "mode=ro"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, `VACUUM INTO ?`, dst); err != nil {
+		return fmt.Errorf("unable to copy database: %w", err)
+	}
+	return nil
+}
+
+// pendingMigrations lists the migrations in src that come after version. If
+// noVersion is set, the database has no schema yet and every migration is
+// pending.
+func pendingMigrations(src source.Driver, version uint, noVersion bool) ([]PendingMigration, error) {
+	var (
+		next uint
+		err  error
+	)
+	if noVersion {
+		next, err = src.First()
+	} else {
+		next, err = src.Next(version)
+	}
+
+	var pending []PendingMigration
+	for err == nil {
+		r, name, readErr := src.ReadUp(next)
+		if readErr != nil {
+			return nil, fmt.Errorf("unable to read migration %d: %w", next, readErr)
+		}
+		_ = r.Close()
+		pending = append(pending, PendingMigration{Version: next, Name: name})
+		next, err = src.Next(next)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("unable to list migrations: %w", err)
+	}
+
+	return pending, nil
+}
@@ -0,0 +1,78 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateDryRun(t *testing.T) {
+	t.Parallel()
+
+	t.Run("up to date", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		_, err := NewSQLiteUserStore(testFile)
+		require.NoError(t, err)
+
+		report, err := MigrateDryRun(context.Background(), testFile)
+		require.NoError(t, err)
+		assert.NotZero(t, report.CurrentVersion)
+		assert.False(t, report.Dirty)
+		assert.Empty(t, report.Pending)
+		assert.Zero(t, report.Applied)
+	})
+
+	t.Run("pending migrations run against a copy", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		latest := migrateTestFileTo(t, testFile, 0)
+		migrateTestFileTo(t, testFile, int(latest)-2)
+
+		report, err := MigrateDryRun(context.Background(), testFile)
+		require.NoError(t, err)
+		assert.Equal(t, latest-2, report.CurrentVersion)
+		require.Len(t, report.Pending, 2)
+		assert.Equal(t, latest-1, report.Pending[0].Version)
+		assert.Equal(t, latest, report.Pending[1].Version)
+		assert.NotEmpty(t, report.Pending[0].Name)
+		assert.Equal(t, 2, report.Applied)
+
+		// the original database is untouched
+		assert.Equal(t, latest-2, migrateTestFileTo(t, testFile, -1))
+	})
+
+	t.Run("missing database", func(t *testing.T) {
+		_, err := MigrateDryRun(context.Background(), "does-not-exist.sqlite")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		_, err = os.Stat("does-not-exist.sqlite")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+// migrateTestFileTo migrates the database at path to version and returns the resulting
+// schema version. A version of 0 applies every migration and -1 leaves the
+// schema as is.
+func migrateTestFileTo(t *testing.T, path string, version int) uint {
+	db, err := sql.Open("sqlite", sqliteDSN(path, "_pragma=foreign_keys=on"))
+	require.NoError(t, err)
+
+	m, _, err := newMigrate(db)
+	require.NoError(t, err)
+	defer m.Close()
+
+	switch {
+	case version == 0:
+		require.NoError(t, m.Up())
+	case version > 0:
+		require.NoError(t, m.Migrate(uint(version)))
+	}
+
+	v, _, err := m.Version()
+	require.NoError(t, err)
+	return v
+}
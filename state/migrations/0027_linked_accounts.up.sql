@@ -0,0 +1,8 @@
+CREATE TABLE linkedAccount
+(
+    aimScreenName VARCHAR(16) PRIMARY KEY,
+    icqUIN        VARCHAR(16) NOT NULL UNIQUE,
+    created       TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (aimScreenName) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (icqUIN) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1,11 @@
+CREATE TABLE pendingContact
+(
+    recipient  VARCHAR(16) NOT NULL,
+    screenName VARCHAR(16) NOT NULL,
+    sender     VARCHAR(16) NOT NULL,
+    nick       TEXT        NOT NULL DEFAULT '',
+    received   TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (recipient, screenName),
+    FOREIGN KEY (recipient) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (sender) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
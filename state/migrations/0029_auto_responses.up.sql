@@ -0,0 +1,9 @@
+CREATE TABLE autoResponse
+(
+    screenName VARCHAR(16) NOT NULL,
+    msgType    INTEGER     NOT NULL,
+    message    TEXT        NOT NULL,
+    updated    TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (screenName, msgType),
+    FOREIGN KEY (screenName) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
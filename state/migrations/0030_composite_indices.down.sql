@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_feedbag_screenName_classID;
+DROP INDEX IF EXISTS idx_feedbag_name_classID;
+DROP INDEX IF EXISTS idx_clientSideBuddyList_them;
+DROP INDEX IF EXISTS idx_bartItem_type_hash;
@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS idx_feedbag_screenName_classID ON feedbag (screenName, classID);
+CREATE INDEX IF NOT EXISTS idx_feedbag_name_classID ON feedbag (name, classID);
+CREATE INDEX IF NOT EXISTS idx_clientSideBuddyList_them ON clientSideBuddyList (them);
+CREATE INDEX IF NOT EXISTS idx_bartItem_type_hash ON bartItem (type, hash);
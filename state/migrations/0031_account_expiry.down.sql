@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_users_expiresAt;
+
+ALTER TABLE users
+    DROP COLUMN expiresAt;
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN expiresAt INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_users_expiresAt ON users (expiresAt) WHERE expiresAt > 0;
@@ -0,0 +1,9 @@
+CREATE TABLE feedbagBackup
+(
+    screenName VARCHAR(16) NOT NULL,
+    version    INTEGER     NOT NULL,
+    createdAt  INTEGER     NOT NULL,
+    itemCount  INTEGER     NOT NULL,
+    items      BLOB        NOT NULL,
+    PRIMARY KEY (screenName, version)
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS emailVerification;
+
+ALTER TABLE users
+    DROP COLUMN emailVerified;
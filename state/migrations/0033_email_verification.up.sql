@@ -0,0 +1,18 @@
+ALTER TABLE users
+    ADD COLUMN emailVerified BOOLEAN NOT NULL DEFAULT false;
+
+-- addresses set before verification existed are trusted as-is
+UPDATE users
+SET emailVerified = true
+WHERE emailAddress != '';
+
+CREATE TABLE emailVerification
+(
+    token        TEXT         PRIMARY KEY,
+    screenName   VARCHAR(16)  NOT NULL,
+    emailAddress VARCHAR(320) NOT NULL,
+    expiresAt    INTEGER      NOT NULL,
+    FOREIGN KEY (screenName) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX idx_emailVerification_screenName ON emailVerification (screenName);
@@ -0,0 +1,26 @@
+DROP TRIGGER IF EXISTS offlineMessage_sender_delete;
+DROP TRIGGER IF EXISTS offlineMessage_sender_update;
+
+ALTER TABLE offlineMessage
+    RENAME TO offlineMessage_new;
+
+CREATE TABLE offlineMessage
+(
+    sender    VARCHAR(16) NOT NULL,
+    recipient VARCHAR(16) NOT NULL,
+    message   BLOB        NOT NULL,
+    sent      TIMESTAMP   NOT NULL,
+    FOREIGN KEY (sender) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (recipient) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+-- messages from senders without an account can't satisfy the foreign key
+INSERT INTO offlineMessage (sender, recipient, message, sent)
+SELECT sender, recipient, message, sent
+FROM offlineMessage_new
+WHERE sender IN (SELECT identScreenName FROM users);
+
+DROP TABLE offlineMessage_new;
+
+CREATE INDEX idx_offlineMessage_sender ON offlineMessage (sender);
+CREATE INDEX idx_offlineMessage_recipient ON offlineMessage (recipient);
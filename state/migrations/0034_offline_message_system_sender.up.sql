@@ -0,0 +1,38 @@
+-- Web pager and email express messages are stored offline with the ICQ
+-- system UIN as the sender, which has no users row. Drop the foreign key on
+-- sender and use triggers to keep cascading deletes and renames for messages
+-- sent by real users.
+ALTER TABLE offlineMessage
+    RENAME TO offlineMessage_old;
+
+CREATE TABLE offlineMessage
+(
+    sender    VARCHAR(16) NOT NULL,
+    recipient VARCHAR(16) NOT NULL,
+    message   BLOB        NOT NULL,
+    sent      TIMESTAMP   NOT NULL,
+    FOREIGN KEY (recipient) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+INSERT INTO offlineMessage (sender, recipient, message, sent)
+SELECT sender, recipient, message, sent
+FROM offlineMessage_old;
+
+DROP TABLE offlineMessage_old;
+
+CREATE INDEX idx_offlineMessage_sender ON offlineMessage (sender);
+CREATE INDEX idx_offlineMessage_recipient ON offlineMessage (recipient);
+
+CREATE TRIGGER offlineMessage_sender_delete
+    AFTER DELETE
+    ON users
+BEGIN
+    DELETE FROM offlineMessage WHERE sender = OLD.identScreenName;
+END;
+
+CREATE TRIGGER offlineMessage_sender_update
+    AFTER UPDATE OF identScreenName
+    ON users
+BEGIN
+    UPDATE offlineMessage SET sender = NEW.identScreenName WHERE sender = OLD.identScreenName;
+END;
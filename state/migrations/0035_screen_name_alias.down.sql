@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_screenNameAlias_primaryScreenName;
+DROP TABLE IF EXISTS screenNameAlias;
@@ -0,0 +1,10 @@
+CREATE TABLE screenNameAlias
+(
+    alias             VARCHAR(16) PRIMARY KEY,
+    displayAlias      VARCHAR(16) NOT NULL,
+    primaryScreenName VARCHAR(16) NOT NULL,
+    created           TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (primaryScreenName) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX idx_screenNameAlias_primaryScreenName ON screenNameAlias (primaryScreenName);
@@ -0,0 +1,9 @@
+CREATE TABLE passwordReset
+(
+    codeHash   TEXT        PRIMARY KEY,
+    screenName VARCHAR(16) NOT NULL,
+    expiresAt  INTEGER     NOT NULL,
+    FOREIGN KEY (screenName) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX idx_passwordReset_screenName ON passwordReset (screenName);
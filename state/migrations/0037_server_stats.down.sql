@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS dailyActive_insert;
+DROP TABLE IF EXISTS dailyActive;
+DROP TABLE IF EXISTS dailyStat;
+DROP TRIGGER IF EXISTS statCounter_offlineMessage_delete;
+DROP TRIGGER IF EXISTS statCounter_offlineMessage_insert;
+DROP TRIGGER IF EXISTS statCounter_users_delete;
+DROP TRIGGER IF EXISTS statCounter_users_insert;
+DROP TABLE IF EXISTS statCounter;
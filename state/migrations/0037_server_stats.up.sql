@@ -0,0 +1,60 @@
+-- statCounter holds running totals maintained by triggers, so that stats
+-- can be read without counting table rows.
+CREATE TABLE statCounter
+(
+    name  TEXT    PRIMARY KEY,
+    value INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO statCounter (name, value)
+VALUES ('users', (SELECT COUNT(*) FROM users)),
+       ('offlineMessages', (SELECT COUNT(*) FROM offlineMessage));
+
+CREATE TRIGGER statCounter_users_insert
+    AFTER INSERT ON users
+BEGIN
+    UPDATE statCounter SET value = value + 1 WHERE name = 'users';
+END;
+
+CREATE TRIGGER statCounter_users_delete
+    AFTER DELETE ON users
+BEGIN
+    UPDATE statCounter SET value = value - 1 WHERE name = 'users';
+END;
+
+CREATE TRIGGER statCounter_offlineMessage_insert
+    AFTER INSERT ON offlineMessage
+BEGIN
+    UPDATE statCounter SET value = value + 1 WHERE name = 'offlineMessages';
+END;
+
+CREATE TRIGGER statCounter_offlineMessage_delete
+    AFTER DELETE ON offlineMessage
+BEGIN
+    UPDATE statCounter SET value = value - 1 WHERE name = 'offlineMessages';
+END;
+
+-- dailyStat holds per-day activity totals. day is a UTC date such as
+-- 2024-01-31.
+CREATE TABLE dailyStat
+(
+    day      TEXT    PRIMARY KEY,
+    actives  INTEGER NOT NULL DEFAULT 0,
+    messages INTEGER NOT NULL DEFAULT 0
+);
+
+-- dailyActive records who signed on each day so that each user is counted
+-- once per day in dailyStat.actives.
+CREATE TABLE dailyActive
+(
+    day        TEXT        NOT NULL,
+    screenName VARCHAR(16) NOT NULL,
+    PRIMARY KEY (day, screenName)
+);
+
+CREATE TRIGGER dailyActive_insert
+    AFTER INSERT ON dailyActive
+BEGIN
+    INSERT INTO dailyStat (day, actives) VALUES (NEW.day, 1)
+    ON CONFLICT (day) DO UPDATE SET actives = actives + 1;
+END;
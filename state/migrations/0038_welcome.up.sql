@@ -0,0 +1,6 @@
+CREATE TABLE welcomeSent
+(
+    screenName VARCHAR(16) PRIMARY KEY,
+    sentAt     INTEGER     NOT NULL,
+    FOREIGN KEY (screenName) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
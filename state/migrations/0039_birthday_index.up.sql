@@ -0,0 +1 @@
+CREATE INDEX idx_users_birthday ON users (icq_moreInfo_birthMonth, icq_moreInfo_birthDay);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS screenNameSearch_rename;
+DROP TRIGGER IF EXISTS screenNameSearch_delete;
+DROP TRIGGER IF EXISTS screenNameSearch_insert;
+DROP TABLE IF EXISTS screenNameSearch;
@@ -0,0 +1,26 @@
+-- trigram index of screen names for substring search; rows share the rowid
+-- of their user
+CREATE VIRTUAL TABLE screenNameSearch USING fts5(identScreenName, tokenize = 'trigram');
+
+INSERT INTO screenNameSearch (rowid, identScreenName)
+SELECT rowid, identScreenName
+FROM users;
+
+CREATE TRIGGER screenNameSearch_insert
+    AFTER INSERT ON users
+BEGIN
+    INSERT INTO screenNameSearch (rowid, identScreenName) VALUES (NEW.rowid, NEW.identScreenName);
+END;
+
+CREATE TRIGGER screenNameSearch_delete
+    AFTER DELETE ON users
+BEGIN
+    DELETE FROM screenNameSearch WHERE rowid = OLD.rowid;
+END;
+
+CREATE TRIGGER screenNameSearch_rename
+    AFTER UPDATE OF identScreenName ON users
+BEGIN
+    DELETE FROM screenNameSearch WHERE rowid = OLD.rowid;
+    INSERT INTO screenNameSearch (rowid, identScreenName) VALUES (NEW.rowid, NEW.identScreenName);
+END;
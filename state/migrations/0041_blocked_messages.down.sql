@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_blockedMessage_sender;
+DROP TABLE IF EXISTS blockedMessage;
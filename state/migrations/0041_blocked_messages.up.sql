@@ -0,0 +1,13 @@
+-- number of messages per sender that a user's privacy settings kept from
+-- reaching them
+CREATE TABLE blockedMessage
+(
+    screenName  VARCHAR(16) NOT NULL,
+    sender      VARCHAR(16) NOT NULL,
+    count       INTEGER     NOT NULL,
+    lastBlocked INTEGER     NOT NULL,
+    PRIMARY KEY (screenName, sender),
+    FOREIGN KEY (screenName) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX idx_blockedMessage_sender ON blockedMessage (sender);
@@ -0,0 +1,10 @@
+-- parental controls: the daily window in which an account may be signed on,
+-- as minutes after midnight in the account's time zone
+CREATE TABLE allowedHours
+(
+    screenName  VARCHAR(16) PRIMARY KEY,
+    startMinute INTEGER     NOT NULL,
+    endMinute   INTEGER     NOT NULL,
+    timeZone    TEXT        NOT NULL,
+    FOREIGN KEY (screenName) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
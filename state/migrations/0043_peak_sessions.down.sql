@@ -0,0 +1,2 @@
+ALTER TABLE dailyStat
+    DROP COLUMN peakSessions;
@@ -0,0 +1,3 @@
+-- the highest number of concurrent sessions seen each day
+ALTER TABLE dailyStat
+    ADD COLUMN peakSessions INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- the ICQ 6 / Xtraz mood that the user last set, NULL if none
+ALTER TABLE users
+    ADD COLUMN icqMood INTEGER;
@@ -0,0 +1,17 @@
+-- system IMs and popups waiting to be delivered to a user at a later time
+-- or at their next sign-on
+CREATE TABLE scheduledMessage
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    recipient  VARCHAR(16) NOT NULL,
+    kind       INTEGER     NOT NULL,
+    text       TEXT        NOT NULL,
+    url        TEXT        NOT NULL DEFAULT '',
+    -- unix time after which the message is delivered, 0 for the next sign-on
+    deliverAt  INTEGER     NOT NULL,
+    createdAt  INTEGER     NOT NULL,
+    FOREIGN KEY (recipient) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX idx_scheduledMessage_recipient ON scheduledMessage (recipient);
+CREATE INDEX idx_scheduledMessage_deliverAt ON scheduledMessage (deliverAt);
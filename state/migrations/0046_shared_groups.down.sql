@@ -0,0 +1,2 @@
+DROP TABLE sharedGroupSubscription;
+DROP TABLE sharedGroup;
@@ -0,0 +1,23 @@
+-- buddy groups published by their owner for other users to subscribe to
+CREATE TABLE sharedGroup
+(
+    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner     VARCHAR(16) NOT NULL,
+    groupID   INTEGER     NOT NULL,
+    createdAt INTEGER     NOT NULL,
+    UNIQUE (owner, groupID),
+    FOREIGN KEY (owner) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+-- subscribers of shared groups and the group ID of their copy
+CREATE TABLE sharedGroupSubscription
+(
+    sharedGroupID INTEGER     NOT NULL,
+    subscriber    VARCHAR(16) NOT NULL,
+    groupID       INTEGER     NOT NULL,
+    PRIMARY KEY (sharedGroupID, subscriber),
+    FOREIGN KEY (sharedGroupID) REFERENCES sharedGroup (id) ON DELETE CASCADE,
+    FOREIGN KEY (subscriber) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX idx_sharedGroupSubscription_subscriber ON sharedGroupSubscription (subscriber);
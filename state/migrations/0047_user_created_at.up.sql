@@ -0,0 +1,4 @@
+-- when the account was registered, 0 for accounts created before it was
+-- recorded
+ALTER TABLE users
+    ADD COLUMN createdAt INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- screen names that can't be registered, such as names kept for staff
+CREATE TABLE reservedScreenName
+(
+    identScreenName VARCHAR(16) PRIMARY KEY
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS presenceWebhookBuddy;
+DROP TABLE IF EXISTS presenceWebhook;
+ALTER TABLE users DROP COLUMN presenceWebhookAllowed;
@@ -0,0 +1,22 @@
+-- whether an operator has allowed the user to register a presence webhook
+ALTER TABLE users ADD COLUMN presenceWebhookAllowed BOOLEAN NOT NULL DEFAULT false;
+
+-- the callback a user registered to hear about buddies' status changes
+CREATE TABLE presenceWebhook
+(
+    identScreenName VARCHAR(16) PRIMARY KEY,
+    url             TEXT NOT NULL,
+    secret          TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (identScreenName) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+-- the buddies whose status changes fire a user's presence webhook
+CREATE TABLE presenceWebhookBuddy
+(
+    identScreenName VARCHAR(16) NOT NULL,
+    buddy           VARCHAR(16) NOT NULL,
+    PRIMARY KEY (identScreenName, buddy),
+    FOREIGN KEY (identScreenName) REFERENCES presenceWebhook (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX presenceWebhookBuddyIdx ON presenceWebhookBuddy (buddy);
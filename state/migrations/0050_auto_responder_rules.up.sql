@@ -0,0 +1,10 @@
+-- operator-defined replies to IMs sent to bots and system screen names
+CREATE TABLE autoResponderRule
+(
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    identScreenName VARCHAR(16) NOT NULL,
+    pattern         TEXT        NOT NULL,
+    reply           TEXT        NOT NULL
+);
+
+CREATE INDEX autoResponderRuleScreenNameIdx ON autoResponderRule (identScreenName);
@@ -0,0 +1,2 @@
+-- chat room quotas count the rooms each user created
+CREATE INDEX chatRoomCreatorIdx ON chatRoom (creator);
@@ -0,0 +1,16 @@
+CREATE TABLE feedbagBackupOld
+(
+    screenName VARCHAR(16) NOT NULL,
+    version    INTEGER     NOT NULL,
+    createdAt  INTEGER     NOT NULL,
+    itemCount  INTEGER     NOT NULL,
+    items      BLOB        NOT NULL,
+    PRIMARY KEY (screenName, version)
+);
+
+INSERT INTO feedbagBackupOld (screenName, version, createdAt, itemCount, items)
+SELECT screenName, version, createdAt, itemCount, items
+FROM feedbagBackup;
+
+DROP TABLE feedbagBackup;
+ALTER TABLE feedbagBackupOld RENAME TO feedbagBackup;
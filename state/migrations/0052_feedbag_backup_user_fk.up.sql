@@ -0,0 +1,21 @@
+-- feedbag backups belong to their account: they're deleted with it and
+-- follow it when it's renamed
+CREATE TABLE feedbagBackupNew
+(
+    screenName VARCHAR(16) NOT NULL,
+    version    INTEGER     NOT NULL,
+    createdAt  INTEGER     NOT NULL,
+    itemCount  INTEGER     NOT NULL,
+    items      BLOB        NOT NULL,
+    PRIMARY KEY (screenName, version),
+    FOREIGN KEY (screenName) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+-- drop the backups left behind by deleted accounts
+INSERT INTO feedbagBackupNew (screenName, version, createdAt, itemCount, items)
+SELECT screenName, version, createdAt, itemCount, items
+FROM feedbagBackup
+WHERE screenName IN (SELECT identScreenName FROM users);
+
+DROP TABLE feedbagBackup;
+ALTER TABLE feedbagBackupNew RENAME TO feedbagBackup;
@@ -0,0 +1,4 @@
+-- the MD5 of the password, which checks BUCP login responses to
+-- per-challenge auth keys, NULL for passwords set before it was stored
+ALTER TABLE users
+    ADD COLUMN passMD5 BLOB;
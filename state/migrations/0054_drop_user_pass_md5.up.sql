@@ -0,0 +1,4 @@
+-- the unsalted password MD5 added by 0053 worked as the password for any
+-- BUCP challenge, so drop it along with the values already stored
+ALTER TABLE users
+    DROP COLUMN passMD5;
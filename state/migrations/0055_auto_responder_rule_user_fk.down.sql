@@ -0,0 +1,16 @@
+CREATE TABLE autoResponderRuleOld
+(
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    identScreenName VARCHAR(16) NOT NULL,
+    pattern         TEXT        NOT NULL,
+    reply           TEXT        NOT NULL
+);
+
+INSERT INTO autoResponderRuleOld (id, identScreenName, pattern, reply)
+SELECT id, identScreenName, pattern, reply
+FROM autoResponderRule;
+
+DROP TABLE autoResponderRule;
+ALTER TABLE autoResponderRuleOld RENAME TO autoResponderRule;
+
+CREATE INDEX autoResponderRuleScreenNameIdx ON autoResponderRule (identScreenName);
@@ -0,0 +1,21 @@
+-- auto-responder rules belong to the account they answer for: they're
+-- deleted with it and follow it when it's renamed
+CREATE TABLE autoResponderRuleNew
+(
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    identScreenName VARCHAR(16) NOT NULL,
+    pattern         TEXT        NOT NULL,
+    reply           TEXT        NOT NULL,
+    FOREIGN KEY (identScreenName) REFERENCES users (identScreenName) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+-- drop the rules of screen names that have no account
+INSERT INTO autoResponderRuleNew (id, identScreenName, pattern, reply)
+SELECT id, identScreenName, pattern, reply
+FROM autoResponderRule
+WHERE identScreenName IN (SELECT identScreenName FROM users);
+
+DROP TABLE autoResponderRule;
+ALTER TABLE autoResponderRuleNew RENAME TO autoResponderRule;
+
+CREATE INDEX autoResponderRuleScreenNameIdx ON autoResponderRule (identScreenName);
@@ -0,0 +1,167 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// ModerationEventSchema is the WebhookEvent schema of moderation events,
+// whose Data is a ModerationEvent.
+const ModerationEventSchema = "moderation.v1"
+
+// Moderation event types.
+const (
+	// ModerationWarningIssued is sent when a user warns another user.
+	ModerationWarningIssued = "warning_issued"
+	// ModerationMessageBlocked is sent when a content filter rejects,
+	// drops or flags a message.
+	ModerationMessageBlocked = "message_blocked"
+	// ModerationFloodKick is sent when a user is disconnected for
+	// exceeding a rate limit.
+	ModerationFloodKick = "flood_kick"
+)
+
+// ModerationEvent describes moderation-relevant activity of a user. Fields
+// that don't apply to an event type are omitted from the JSON.
+type ModerationEvent struct {
+	// ScreenName is the user who acted: the warner, the sender of a
+	// blocked message or the flooding user.
+	ScreenName string `json:"screen_name"`
+	// Target is who or what the action was aimed at: the warned user,
+	// the recipient of an IM or the cookie of a chat room.
+	Target string `json:"target,omitempty"`
+	// Scope is the kind of content that was filtered, such as "im" or
+	// "chat".
+	Scope string `json:"scope,omitempty"`
+	// Action is what the filter did, such as "reject", "drop", "kick",
+	// "strip" or "flag".
+	Action string `json:"action,omitempty"`
+	// Reason is a human-readable explanation of the event.
+	Reason string `json:"reason,omitempty"`
+	// WarningLevel is the warned user's new warning level, in tenths of a
+	// percent.
+	WarningLevel uint16 `json:"warning_level,omitempty"`
+	// Anonymous indicates that a warning was sent anonymously.
+	Anonymous bool `json:"anonymous,omitempty"`
+}
+
+// ModerationEvents reports moderation-relevant activity, such as warnings
+// and filtered messages, to a webhook sink for external moderation
+// dashboards. A nil *ModerationEvents reports nothing, so callers don't
+// need to check whether moderation webhooks are enabled.
+type ModerationEvents struct {
+	sink   WebhookSink
+	logger *slog.Logger
+	nowFn  func() time.Time
+}
+
+// NewModerationEvents creates a new instance of ModerationEvents.
+func NewModerationEvents(sink WebhookSink, logger *slog.Logger) *ModerationEvents {
+	return &ModerationEvents{
+		sink:   sink,
+		logger: logger,
+		nowFn:  time.Now,
+	}
+}
+
+// WarningIssued reports that sender warned target, raising target's
+// warning level to newLevel.
+func (m *ModerationEvents) WarningIssued(ctx context.Context, sender, target IdentScreenName, anonymous bool, newLevel uint16) {
+	m.emit(ctx, ModerationWarningIssued, ModerationEvent{
+		ScreenName:   sender.String(),
+		Target:       target.String(),
+		WarningLevel: newLevel,
+		Anonymous:    anonymous,
+	})
+}
+
+// ChatFiltered reports a chat message from sender that the chat filter
+// didn't allow into room. Allowed messages are ignored.
+func (m *ModerationEvents) ChatFiltered(ctx context.Context, sender IdentScreenName, room ChatRoom, verdict ChatFilterVerdict) {
+	var action string
+	switch verdict.Action {
+	case ChatFilterDrop:
+		action = "drop"
+	case ChatFilterKick:
+		action = "kick"
+	default:
+		return
+	}
+	m.emit(ctx, ModerationMessageBlocked, ModerationEvent{
+		ScreenName: sender.String(),
+		Target:     room.Cookie(),
+		Scope:      ProfanityScopeChat.String(),
+		Action:     action,
+		Reason:     verdict.Reason,
+	})
+}
+
+// profanityFiltered reports content from sender that the profanity filter
+// rejected or flagged.
+func (m *ModerationEvents) profanityFiltered(ctx context.Context, sender IdentScreenName, scope ProfanityScope, action ProfanityAction, words []string) {
+	name := "flag"
+	if action == ProfanityReject {
+		name = "reject"
+	}
+	m.emit(ctx, ModerationMessageBlocked, ModerationEvent{
+		ScreenName: sender.String(),
+		Scope:      scope.String(),
+		Action:     name,
+		Reason:     "profanity: " + strings.Join(words, ", "),
+	})
+}
+
+// urlsBlocked reports content from sender that the URL scanner stripped
+// or flagged because it links to blocked URLs.
+func (m *ModerationEvents) urlsBlocked(ctx context.Context, sender IdentScreenName, action URLScanAction, urls []string) {
+	m.emit(ctx, ModerationMessageBlocked, ModerationEvent{
+		ScreenName: sender.String(),
+		Scope:      ProfanityScopeProfile.String(),
+		Action:     action.String(),
+		Reason:     "blocked URL: " + strings.Join(urls, ", "),
+	})
+}
+
+// FloodKick reports that sess was disconnected for exceeding a rate limit.
+func (m *ModerationEvents) FloodKick(ctx context.Context, sess *Session) {
+	m.emit(ctx, ModerationFloodKick, ModerationEvent{
+		ScreenName: sess.IdentScreenName().String(),
+		Reason:     DisconnectRateLimited.String(),
+	})
+}
+
+func (m *ModerationEvents) emit(ctx context.Context, eventType string, event ModerationEvent) {
+	if m == nil {
+		return
+	}
+	ok := m.sink.Dispatch(WebhookEvent{
+		Schema: ModerationEventSchema,
+		Type:   eventType,
+		Time:   m.nowFn().UTC(),
+		Data:   event,
+	})
+	if !ok {
+		m.logger.DebugContext(ctx, "moderation event dropped", "type", eventType, "screen_name", event.ScreenName)
+	}
+}
+
+// SetModerationEvents sets where sessions disconnected for exceeding a rate
+// limit are reported as ModerationFloodKick. It must be called before the
+// session manager is used.
+func (s *InMemorySessionManager) SetModerationEvents(events *ModerationEvents) {
+	s.moderation = events
+}
+
+// SetModerationEvents sets where rejected and flagged content is reported
+// as ModerationMessageBlocked. It must be called before the filter is used.
+func (f *ProfanityFilter) SetModerationEvents(events *ModerationEvents) {
+	f.events = events
+}
+
+// SetModerationEvents sets where stripped and flagged content is reported
+// as ModerationMessageBlocked. It must be called before the scanner is used.
+func (s *URLScanner) SetModerationEvents(events *ModerationEvents) {
+	s.events = events
+}
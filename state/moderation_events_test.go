@@ -0,0 +1,92 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookSinkFunc is a WebhookSink backed by a function.
+type webhookSinkFunc func(event WebhookEvent) bool
+
+func (f webhookSinkFunc) Dispatch(event WebhookEvent) bool {
+	return f(event)
+}
+
+func newTestModerationEvents() (*ModerationEvents, *[]WebhookEvent) {
+	var events []WebhookEvent
+	m := NewModerationEvents(webhookSinkFunc(func(event WebhookEvent) bool {
+		events = append(events, event)
+		return true
+	}), slog.Default())
+	m.nowFn = func() time.Time { return time.Unix(1_700_000_000, 0) }
+	return m, &events
+}
+
+func TestModerationEvents(t *testing.T) {
+	ctx := context.Background()
+	m, events := newTestModerationEvents()
+
+	m.WarningIssued(ctx, NewIdentScreenName("Alice"), NewIdentScreenName("Bob"), true, 150)
+	m.ChatFiltered(ctx, NewIdentScreenName("Bob"), NewChatRoom("lobby", NewIdentScreenName("Alice"), 4), ChatFilterVerdict{
+		Action: ChatFilterKick,
+		Reason: `matched kick rule "spam"`,
+	})
+	m.ChatFiltered(ctx, NewIdentScreenName("Bob"), NewChatRoom("lobby", NewIdentScreenName("Alice"), 4), ChatFilterVerdict{Action: ChatFilterAllow})
+
+	filter := NewProfanityFilter([]string{"darn"}, ProfanityPolicy{ProfanityScopeIM: ProfanityReject}, slog.Default())
+	filter.SetModerationEvents(m)
+	_, err := filter.Filter(ctx, ProfanityScopeIM, NewIdentScreenName("Carol"), "oh darn it")
+	assert.ErrorIs(t, err, ErrProfanityRejected)
+
+	at := time.Unix(1_700_000_000, 0).UTC()
+	assert.Equal(t, []WebhookEvent{
+		{
+			Schema: ModerationEventSchema,
+			Type:   ModerationWarningIssued,
+			Time:   at,
+			Data:   ModerationEvent{ScreenName: "alice", Target: "bob", WarningLevel: 150, Anonymous: true},
+		},
+		{
+			Schema: ModerationEventSchema,
+			Type:   ModerationMessageBlocked,
+			Time:   at,
+			Data: ModerationEvent{ScreenName: "bob", Target: "4-0-lobby", Scope: "chat", Action: "kick",
+				Reason: `matched kick rule "spam"`},
+		},
+		{
+			Schema: ModerationEventSchema,
+			Type:   ModerationMessageBlocked,
+			Time:   at,
+			Data:   ModerationEvent{ScreenName: "carol", Scope: "im", Action: "reject", Reason: "profanity: darn"},
+		},
+	}, *events)
+}
+
+func TestModerationEvents_FloodKick(t *testing.T) {
+	m, events := newTestModerationEvents()
+	sm := NewInMemorySessionManager(slog.Default())
+	sm.SetModerationEvents(m)
+
+	for _, reason := range []DisconnectReason{DisconnectSignoff, DisconnectRateLimited} {
+		sess, err := sm.AddSession(context.Background(), DisplayScreenName("Flooder "+reason.String()))
+		require.NoError(t, err)
+		sess.CloseWithReason(reason)
+		sm.RemoveSession(sess)
+	}
+
+	require.Len(t, *events, 1)
+	assert.Equal(t, ModerationFloodKick, (*events)[0].Type)
+	assert.Equal(t, ModerationEvent{ScreenName: "flooderratelimited", Reason: "rate limited"}, (*events)[0].Data)
+}
+
+func TestModerationEvents_Nil(t *testing.T) {
+	var m *ModerationEvents
+	assert.NotPanics(t, func() {
+		m.WarningIssued(context.Background(), NewIdentScreenName("Alice"), NewIdentScreenName("Bob"), false, 30)
+	})
+}
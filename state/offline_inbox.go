@@ -0,0 +1,93 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// OfflineMessageSaver stores IMs sent to recipients who are signed off.
+type OfflineMessageSaver interface {
+	SaveMessage(ctx context.Context, offlineMessage OfflineMessage) (int, error)
+}
+
+// OfflineInbox stores IMs sent to signed-off recipients. Unlike calling
+// SaveMessage directly, it tells the sender when the recipient's inbox is
+// full, so the message isn't dropped silently.
+type OfflineInbox struct {
+	store  OfflineMessageSaver
+	notify bool
+	logger *slog.Logger
+}
+
+// NewOfflineInbox creates a new instance of OfflineInbox. If notify is set,
+// senders whose message doesn't fit in the recipient's inbox also receive
+// an IM from SystemMessageScreenName explaining why.
+func NewOfflineInbox(store OfflineMessageSaver, notify bool, logger *slog.Logger) *OfflineInbox {
+	return &OfflineInbox{
+		store:  store,
+		notify: notify,
+		logger: logger,
+	}
+}
+
+// Save stores msg for its recipient. It returns ErrOfflineInboxFull if the
+// recipient already holds the maximum number of messages from the sender.
+// It returns ErrGuestNotAllowed without storing msg if sender is a guest.
+// ICBM handlers should then reply with OfflineSaveError instead of
+// acknowledging the message.
+func (o *OfflineInbox) Save(ctx context.Context, sender *Session, msg OfflineMessage) error {
+	if sender.Guest() {
+		return fmt.Errorf("%w: guests cannot send offline messages", ErrGuestNotAllowed)
+	}
+
+	_, err := o.store.SaveMessage(ctx, msg)
+	if !errors.Is(err, ErrOfflineInboxFull) {
+		return err
+	}
+
+	o.logger.DebugContext(ctx, "offline inbox full", "sender", msg.Sender, "recipient", msg.Recipient)
+	if o.notify {
+		o.sendInboxFullNotice(ctx, sender, msg.Message.ScreenName.String())
+	}
+	return err
+}
+
+func (o *OfflineInbox) sendInboxFullNotice(ctx context.Context, sender *Session, recipient string) {
+	text := fmt.Sprintf("Your message to %s was not delivered because their offline message inbox is full. "+
+		"Try again after they sign on.", html.EscapeString(recipient))
+	notice, err := newServerIM(SystemMessageScreenName, text)
+	if err != nil {
+		o.logger.ErrorContext(ctx, "unable to build offline inbox full notice", "err", err)
+		return
+	}
+	if sender.RelayMessage(notice) != SessSendOK {
+		o.logger.DebugContext(ctx, "unable to send offline inbox full notice", "screen_name", sender.IdentScreenName())
+	}
+}
+
+// OfflineSaveError returns the SNAC(0x04,0x01) ICBMErr that reports to the
+// sender of the ICBM in inFrame that its message couldn't be stored
+// offline. The error code comes from ErrorCode. A full inbox also carries
+// the wire.ICBMSubErrOfflineIMExceedMax subcode, which clients show as a
+// specific error instead of a generic failure.
+func OfflineSaveError(inFrame wire.SNACFrame, err error) wire.SNACMessage {
+	body := wire.SNACError{
+		Code: ErrorCode(err),
+	}
+	if errors.Is(err, ErrOfflineInboxFull) {
+		body.Append(wire.NewTLVBE(wire.ErrorTLVErrorSubcode, wire.ICBMSubErrOfflineIMExceedMax))
+	}
+	return wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.ICBM,
+			SubGroup:  wire.ICBMErr,
+			RequestID: inFrame.RequestID,
+		},
+		Body: body,
+	}
+}
@@ -0,0 +1,120 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+type offlineMessageSaverFunc func(ctx context.Context, offlineMessage OfflineMessage) (int, error)
+
+func (f offlineMessageSaverFunc) SaveMessage(ctx context.Context, offlineMessage OfflineMessage) (int, error) {
+	return f(ctx, offlineMessage)
+}
+
+func TestOfflineInbox_Save(t *testing.T) {
+	ctx := context.Background()
+	msg := OfflineMessage{
+		Sender:    NewIdentScreenName("Sender"),
+		Recipient: NewIdentScreenName("Recipient"),
+		Message:   wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{ScreenName: "Recipient"},
+	}
+	full := offlineMessageSaverFunc(func(context.Context, OfflineMessage) (int, error) {
+		return 0, fmt.Errorf("SaveMessage: %w", ErrOfflineInboxFull)
+	})
+
+	newSender := func(t *testing.T) *Session {
+		sm := NewInMemorySessionManager(slog.Default())
+		sess, err := sm.AddSession(ctx, "Sender")
+		require.NoError(t, err)
+		return sess
+	}
+
+	t.Run("saved", func(t *testing.T) {
+		var saved []OfflineMessage
+		store := offlineMessageSaverFunc(func(_ context.Context, m OfflineMessage) (int, error) {
+			saved = append(saved, m)
+			return 1, nil
+		})
+		sender := newSender(t)
+		assert.NoError(t, NewOfflineInbox(store, true, slog.Default()).Save(ctx, sender, msg))
+		assert.Equal(t, []OfflineMessage{msg}, saved)
+		assert.Empty(t, sender.ReceiveMessage())
+	})
+
+	t.Run("inbox full sends notice", func(t *testing.T) {
+		sender := newSender(t)
+		err := NewOfflineInbox(full, true, slog.Default()).Save(ctx, sender, msg)
+		assert.ErrorIs(t, err, ErrOfflineInboxFull)
+
+		select {
+		case notice := <-sender.ReceiveMessage():
+			im := notice.Body.(wire.SNAC_0x04_0x07_ICBMChannelMsgToClient)
+			assert.Equal(t, wire.ScreenName(SystemMessageScreenName), im.ScreenName)
+			data, ok := im.Bytes(wire.ICBMTLVAOLIMData)
+			require.True(t, ok)
+			text, err := wire.UnmarshalICBMMessageText(data)
+			require.NoError(t, err)
+			assert.Contains(t, text, "Your message to Recipient was not delivered")
+		case <-time.After(time.Second):
+			t.Fatal("no notice received")
+		}
+	})
+
+	t.Run("inbox full without notice", func(t *testing.T) {
+		sender := newSender(t)
+		err := NewOfflineInbox(full, false, slog.Default()).Save(ctx, sender, msg)
+		assert.ErrorIs(t, err, ErrOfflineInboxFull)
+		assert.Empty(t, sender.ReceiveMessage())
+	})
+
+	t.Run("guest sender", func(t *testing.T) {
+		store := offlineMessageSaverFunc(func(context.Context, OfflineMessage) (int, error) {
+			t.Fatal("message from a guest was stored")
+			return 0, nil
+		})
+		sender := newSender(t)
+		sender.SetGuest(true)
+		err := NewOfflineInbox(store, true, slog.Default()).Save(ctx, sender, msg)
+		assert.ErrorIs(t, err, ErrGuestNotAllowed)
+		assert.Equal(t, wire.ErrorCodeInsufficientRights, OfflineSaveError(wire.SNACFrame{}, err).Body.(wire.SNACError).Code)
+	})
+
+	t.Run("other errors send no notice", func(t *testing.T) {
+		store := offlineMessageSaverFunc(func(context.Context, OfflineMessage) (int, error) {
+			return 0, errors.New("database is locked")
+		})
+		sender := newSender(t)
+		err := NewOfflineInbox(store, true, slog.Default()).Save(ctx, sender, msg)
+		assert.EqualError(t, err, "database is locked")
+		assert.Empty(t, sender.ReceiveMessage())
+	})
+}
+
+func TestOfflineSaveError(t *testing.T) {
+	inFrame := wire.SNACFrame{FoodGroup: wire.ICBM, SubGroup: wire.ICBMChannelMsgToHost, RequestID: 1234}
+
+	t.Run("inbox full", func(t *testing.T) {
+		msg := OfflineSaveError(inFrame, fmt.Errorf("SaveMessage: %w", ErrOfflineInboxFull))
+		assert.Equal(t, wire.SNACFrame{FoodGroup: wire.ICBM, SubGroup: wire.ICBMErr, RequestID: 1234}, msg.Frame)
+		body := msg.Body.(wire.SNACError)
+		assert.Equal(t, wire.ErrorCodeQueueFull, body.Code)
+		subcode, ok := body.Uint16BE(wire.ErrorTLVErrorSubcode)
+		require.True(t, ok)
+		assert.Equal(t, wire.ICBMSubErrOfflineIMExceedMax, subcode)
+	})
+
+	t.Run("unknown recipient", func(t *testing.T) {
+		body := OfflineSaveError(inFrame, ErrNoUser).Body.(wire.SNACError)
+		assert.Equal(t, wire.ErrorCodeNoMatch, body.Code)
+		assert.False(t, body.HasTag(wire.ErrorTLVErrorSubcode))
+	})
+}
@@ -0,0 +1,122 @@
+package state
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// OnlineUserSort selects the ordering of OnlineUsers results.
+type OnlineUserSort uint8
+
+const (
+	// SortByOnlineTime orders users by time since sign-on, longest first.
+	SortByOnlineTime OnlineUserSort = iota
+	// SortByIdleTime orders users by idle time, longest first. Users who
+	// aren't idle come last.
+	SortByIdleTime
+)
+
+// OnlineUserQuery filters and paginates OnlineUsers results.
+type OnlineUserQuery struct {
+	// MinOnline excludes users signed on for less than this duration.
+	MinOnline time.Duration
+	// MinIdle excludes users idle for less than this duration. When set,
+	// users who aren't idle are excluded.
+	MinIdle time.Duration
+	// SortBy is the result ordering.
+	SortBy OnlineUserSort
+	// Offset is the number of matching users to skip.
+	Offset int
+	// Limit is the max number of users to return. Zero means no limit.
+	Limit int
+}
+
+// OnlineUser is a signed-on user with their account record.
+type OnlineUser struct {
+	User
+	// OnlineTime is how long the user has been signed on.
+	OnlineTime time.Duration
+	// IdleTime is how long the user has been idle, or zero if the user
+	// isn't idle.
+	IdleTime time.Duration
+}
+
+// OnlineUsers joins the signed-on sessions with their user records and
+// returns the page selected by q along with the total number of matching
+// users. Sessions without a user record, such as guests, are skipped.
+func (us SQLiteUserStore) OnlineUsers(ctx context.Context, sessions []*Session, q OnlineUserQuery, now time.Time) ([]OnlineUser, int, error) {
+	var matches []OnlineUser
+	for _, sess := range sessions {
+		if sess.Guest() || !sess.SignonComplete() {
+			continue
+		}
+		u := OnlineUser{
+			User:       User{IdentScreenName: sess.IdentScreenName()},
+			OnlineTime: now.Sub(sess.SignonTime()),
+		}
+		if sess.Idle() {
+			u.IdleTime = now.Sub(sess.IdleTime())
+		}
+		if u.OnlineTime < q.MinOnline {
+			continue
+		}
+		if q.MinIdle > 0 && (!sess.Idle() || u.IdleTime < q.MinIdle) {
+			continue
+		}
+		matches = append(matches, u)
+	}
+
+	if len(matches) == 0 {
+		return nil, 0, nil
+	}
+
+	args := make([]any, len(matches))
+	for i, u := range matches {
+		args[i] = u.IdentScreenName.String()
+	}
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(matches)), ",")
+	users, err := us.queryUsers(ctx, fmt.Sprintf(`identScreenName IN (%s)`, placeholders), args)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query users: %w", err)
+	}
+
+	records := make(map[IdentScreenName]User, len(users))
+	for _, u := range users {
+		records[u.IdentScreenName] = u
+	}
+
+	// drop users whose account was deleted while they were signed on
+	matches = slices.DeleteFunc(matches, func(u OnlineUser) bool {
+		_, ok := records[u.IdentScreenName]
+		return !ok
+	})
+	for i := range matches {
+		matches[i].User = records[matches[i].IdentScreenName]
+	}
+
+	slices.SortFunc(matches, func(a, b OnlineUser) int {
+		var c int
+		switch q.SortBy {
+		case SortByIdleTime:
+			c = cmp.Compare(b.IdleTime, a.IdleTime)
+		default:
+			c = cmp.Compare(b.OnlineTime, a.OnlineTime)
+		}
+		if c == 0 {
+			c = strings.Compare(a.IdentScreenName.String(), b.IdentScreenName.String())
+		}
+		return c
+	})
+
+	total := len(matches)
+	page := matches[min(max(q.Offset, 0), total):]
+	if q.Limit > 0 && q.Limit < len(page) {
+		page = page[:q.Limit]
+	}
+
+	return page, total, nil
+}
@@ -0,0 +1,103 @@
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteUserStore_OnlineUsers(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	newSess := func(sn DisplayScreenName, online time.Duration, idle time.Duration) *Session {
+		sess := NewSession()
+		sess.nowFn = func() time.Time { return now }
+		sess.SetIdentScreenName(sn.IdentScreenName())
+		sess.SetDisplayScreenName(sn)
+		sess.SetSignonTime(now.Add(-online))
+		if idle > 0 {
+			sess.SetIdle(idle)
+		}
+		sess.SetSignonComplete()
+		return sess
+	}
+
+	for _, sn := range []DisplayScreenName{"alice", "bob", "carol", "dave"} {
+		assert.NoError(t, f.InsertUser(context.Background(), User{
+			IdentScreenName:   sn.IdentScreenName(),
+			DisplayScreenName: sn,
+		}))
+	}
+
+	guest := newSess("Guest123456", 5*time.Hour, 0)
+	guest.SetGuest(true)
+	signingOn := NewSession()
+	signingOn.SetIdentScreenName(NewIdentScreenName("dave"))
+
+	sessions := []*Session{
+		newSess("alice", 1*time.Hour, 0),
+		newSess("bob", 3*time.Hour, 10*time.Minute),
+		newSess("carol", 2*time.Hour, 90*time.Minute),
+		newSess("deleted", 4*time.Hour, 0),
+		guest,
+		signingOn,
+	}
+
+	screenNames := func(users []OnlineUser) []string {
+		var ret []string
+		for _, u := range users {
+			ret = append(ret, u.DisplayScreenName.String())
+		}
+		return ret
+	}
+
+	t.Run("sort by online time", func(t *testing.T) {
+		users, total, err := f.OnlineUsers(context.Background(), sessions, OnlineUserQuery{}, now)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, total)
+		assert.Equal(t, []string{"bob", "carol", "alice"}, screenNames(users))
+		assert.Equal(t, 3*time.Hour, users[0].OnlineTime)
+		assert.Equal(t, 10*time.Minute, users[0].IdleTime)
+	})
+
+	t.Run("sort by idle time", func(t *testing.T) {
+		users, _, err := f.OnlineUsers(context.Background(), sessions, OnlineUserQuery{SortBy: SortByIdleTime}, now)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"carol", "bob", "alice"}, screenNames(users))
+	})
+
+	t.Run("online longer than", func(t *testing.T) {
+		users, total, err := f.OnlineUsers(context.Background(), sessions, OnlineUserQuery{MinOnline: 2 * time.Hour}, now)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, total)
+		assert.Equal(t, []string{"bob", "carol"}, screenNames(users))
+	})
+
+	t.Run("idle longer than", func(t *testing.T) {
+		users, total, err := f.OnlineUsers(context.Background(), sessions, OnlineUserQuery{MinIdle: time.Hour}, now)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, total)
+		assert.Equal(t, []string{"carol"}, screenNames(users))
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		users, total, err := f.OnlineUsers(context.Background(), sessions, OnlineUserQuery{Offset: 1, Limit: 1}, now)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, total)
+		assert.Equal(t, []string{"carol"}, screenNames(users))
+
+		users, total, err = f.OnlineUsers(context.Background(), sessions, OnlineUserQuery{Offset: 10, Limit: 1}, now)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, total)
+		assert.Empty(t, users)
+	})
+}
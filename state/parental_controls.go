@@ -0,0 +1,246 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"modernc.org/sqlite"
+	lib "modernc.org/sqlite/lib"
+)
+
+// ParentalControlsSweepInterval is how often ParentalControls looks for
+// sessions whose allowed hours ended.
+const ParentalControlsSweepInterval = time.Minute
+
+var (
+	// ErrRestrictedByParentalControls indicates that the account may not
+	// sign on at this time of day.
+	ErrRestrictedByParentalControls = errors.New("sign-on restricted by parental controls")
+	// ErrAllowedHoursInvalid indicates that an allowed hours window is out
+	// of range.
+	ErrAllowedHoursInvalid = errors.New("invalid allowed hours")
+)
+
+// AllowedHours is the daily window in which an account restricted by
+// parental controls may be signed on.
+type AllowedHours struct {
+	// Start is when the window opens, as the time after midnight.
+	Start time.Duration
+	// End is when the window closes, as the time after midnight. A window
+	// that ends before it starts spans midnight.
+	End time.Duration
+	// Location is the time zone that Start and End are in.
+	Location *time.Location
+}
+
+// Validate returns ErrAllowedHoursInvalid if Start or End is not within a
+// day, or if the window is empty.
+func (h AllowedHours) Validate() error {
+	day := 24 * time.Hour
+	if h.Start < 0 || h.Start >= day || h.End < 0 || h.End >= day {
+		return fmt.Errorf("%w: start and end must be between 00:00 and 23:59", ErrAllowedHoursInvalid)
+	}
+	if h.Start == h.End {
+		return fmt.Errorf("%w: start and end must differ", ErrAllowedHoursInvalid)
+	}
+	if h.Location == nil {
+		return fmt.Errorf("%w: missing time zone", ErrAllowedHoursInvalid)
+	}
+	return nil
+}
+
+// Allows indicates whether now is within the window.
+func (h AllowedHours) Allows(now time.Time) bool {
+	// use the wall clock time, which differs from the time elapsed since
+	// midnight on days that daylight saving time starts or ends
+	now = now.In(h.Location)
+	t := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute
+	if h.Start < h.End {
+		return h.Start <= t && t < h.End
+	}
+	return t >= h.Start || t < h.End
+}
+
+// SetAllowedHours restricts the account to sign on within hours. It returns
+// ErrAllowedHoursInvalid if hours is invalid and ErrNoUser if the user
+// doesn't exist.
+func (us SQLiteUserStore) SetAllowedHours(ctx context.Context, screenName IdentScreenName, hours AllowedHours) error {
+	if err := hours.Validate(); err != nil {
+		return err
+	}
+
+	q := `
+		INSERT INTO allowedHours (screenName, startMinute, endMinute, timeZone) VALUES (?, ?, ?, ?)
+		ON CONFLICT (screenName) DO UPDATE SET startMinute = excluded.startMinute,
+		                                       endMinute   = excluded.endMinute,
+		                                       timeZone    = excluded.timeZone
+	`
+	_, err := us.db.ExecContext(ctx, q, screenName.String(), int(hours.Start/time.Minute), int(hours.End/time.Minute), hours.Location.String())
+	if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == lib.SQLITE_CONSTRAINT_FOREIGNKEY {
+		return ErrNoUser
+	} else if err != nil {
+		return fmt.Errorf("SetAllowedHours: %w", err)
+	}
+	return nil
+}
+
+// ClearAllowedHours lifts the account's parental controls restriction.
+func (us SQLiteUserStore) ClearAllowedHours(ctx context.Context, screenName IdentScreenName) error {
+	if _, err := us.db.ExecContext(ctx, `DELETE FROM allowedHours WHERE screenName = ?`, screenName.String()); err != nil {
+		return fmt.Errorf("ClearAllowedHours: %w", err)
+	}
+	return nil
+}
+
+// AllowedHours returns the account's allowed hours. The bool is false if
+// the account is not restricted.
+func (us SQLiteUserStore) AllowedHours(ctx context.Context, screenName IdentScreenName) (AllowedHours, bool, error) {
+	q := `SELECT startMinute, endMinute, timeZone FROM allowedHours WHERE screenName = ?`
+	var start, end int
+	var tz string
+	err := us.db.QueryRowContext(ctx, q, screenName.String()).Scan(&start, &end, &tz)
+	if errors.Is(err, sql.ErrNoRows) {
+		return AllowedHours{}, false, nil
+	} else if err != nil {
+		return AllowedHours{}, false, fmt.Errorf("AllowedHours: %w", err)
+	}
+
+	hours, err := newAllowedHours(start, end, tz)
+	if err != nil {
+		return AllowedHours{}, false, fmt.Errorf("AllowedHours: %w", err)
+	}
+	return hours, true, nil
+}
+
+// AllAllowedHours returns the allowed hours of every restricted account.
+func (us SQLiteUserStore) AllAllowedHours(ctx context.Context) (map[IdentScreenName]AllowedHours, error) {
+	rows, err := us.db.QueryContext(ctx, `SELECT screenName, startMinute, endMinute, timeZone FROM allowedHours`)
+	if err != nil {
+		return nil, fmt.Errorf("AllAllowedHours: %w", err)
+	}
+	defer rows.Close()
+
+	all := make(map[IdentScreenName]AllowedHours)
+	for rows.Next() {
+		var screenName, tz string
+		var start, end int
+		if err := rows.Scan(&screenName, &start, &end, &tz); err != nil {
+			return nil, fmt.Errorf("AllAllowedHours: %w", err)
+		}
+		hours, err := newAllowedHours(start, end, tz)
+		if err != nil {
+			return nil, fmt.Errorf("AllAllowedHours: %w", err)
+		}
+		all[NewIdentScreenName(screenName)] = hours
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("AllAllowedHours: %w", err)
+	}
+	return all, nil
+}
+
+func newAllowedHours(startMinute, endMinute int, tz string) (AllowedHours, error) {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return AllowedHours{}, err
+	}
+	return AllowedHours{
+		Start:    time.Duration(startMinute) * time.Minute,
+		End:      time.Duration(endMinute) * time.Minute,
+		Location: loc,
+	}, nil
+}
+
+// AllowedHoursStore persists the allowed hours enforced by
+// ParentalControls.
+type AllowedHoursStore interface {
+	AllowedHours(ctx context.Context, screenName IdentScreenName) (AllowedHours, bool, error)
+	AllAllowedHours(ctx context.Context) (map[IdentScreenName]AllowedHours, error)
+}
+
+// ParentalControls enforces per-account allowed hours, like AOL parental
+// controls. Outside its window, an account may not sign on, and a session
+// still signed on when the window closes is disconnected by the next sweep.
+type ParentalControls struct {
+	store    AllowedHoursStore
+	sessions SessionRetriever
+	logger   *slog.Logger
+	nowFn    func() time.Time
+}
+
+// NewParentalControls creates a new instance of ParentalControls.
+func NewParentalControls(store AllowedHoursStore, sessions SessionRetriever, logger *slog.Logger) *ParentalControls {
+	return &ParentalControls{
+		store:    store,
+		sessions: sessions,
+		logger:   logger,
+		nowFn:    time.Now,
+	}
+}
+
+// restricted indicates whether the account is outside its allowed hours.
+func (p *ParentalControls) restricted(ctx context.Context, screenName IdentScreenName) (bool, error) {
+	hours, ok, err := p.store.AllowedHours(ctx, screenName)
+	if err != nil || !ok {
+		return false, err
+	}
+	return !hours.Allows(p.nowFn()), nil
+}
+
+// CheckSignon returns ErrRestrictedByParentalControls if the account may
+// not sign on now. Login handlers should reject the login with the error's
+// ErrorCode, wire.ErrorCodeRestrictedByPc.
+func (p *ParentalControls) CheckSignon(ctx context.Context, screenName IdentScreenName) error {
+	restricted, err := p.restricted(ctx, screenName)
+	if err != nil {
+		return err
+	}
+	if restricted {
+		return ErrRestrictedByParentalControls
+	}
+	return nil
+}
+
+// RemoteRestricted indicates whether an IM to recipient is undeliverable
+// because of the recipient's parental controls. ICBM handlers should then
+// reply with wire.ErrorCodeNotLoggedOn and the
+// wire.ICBMSubErrRemoteRestrictedByPC subcode.
+func (p *ParentalControls) RemoteRestricted(ctx context.Context, recipient IdentScreenName) (bool, error) {
+	return p.restricted(ctx, recipient)
+}
+
+// Sweep disconnects the sessions of restricted accounts that are outside
+// their allowed hours and returns their screen names.
+func (p *ParentalControls) Sweep(ctx context.Context) ([]IdentScreenName, error) {
+	all, err := p.store.AllAllowedHours(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := p.nowFn()
+	var closed []IdentScreenName
+	for screenName, hours := range all {
+		if hours.Allows(now) {
+			continue
+		}
+		if sess := p.sessions.RetrieveSession(screenName); sess != nil {
+			sess.CloseWithReason(DisconnectParentalControls)
+			p.logger.InfoContext(ctx, "allowed hours ended, disconnected session", "screen_name", screenName)
+			closed = append(closed, screenName)
+		}
+	}
+	return closed, nil
+}
+
+// Schedule adds the sweep to s as the "parental_controls" job, to run every
+// ParentalControlsSweepInterval.
+func (p *ParentalControls) Schedule(s *Scheduler) error {
+	return s.Add("parental_controls", ParentalControlsSweepInterval, ParentalControlsSweepInterval/10, func(ctx context.Context) error {
+		_, err := p.Sweep(ctx)
+		return err
+	})
+}
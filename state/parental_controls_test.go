@@ -0,0 +1,172 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestAllowedHours_Allows(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	afterSchool := AllowedHours{Start: 15 * time.Hour, End: 20*time.Hour + 30*time.Minute, Location: ny}
+	overnight := AllowedHours{Start: 22 * time.Hour, End: 6 * time.Hour, Location: time.UTC}
+
+	tests := []struct {
+		name  string
+		hours AllowedHours
+		now   time.Time
+		want  bool
+	}{
+		{
+			name:  "before window",
+			hours: afterSchool,
+			now:   time.Date(2024, 3, 1, 14, 59, 0, 0, ny),
+			want:  false,
+		},
+		{
+			name:  "window start",
+			hours: afterSchool,
+			now:   time.Date(2024, 3, 1, 15, 0, 0, 0, ny),
+			want:  true,
+		},
+		{
+			name:  "window end",
+			hours: afterSchool,
+			now:   time.Date(2024, 3, 1, 20, 30, 0, 0, ny),
+			want:  false,
+		},
+		{
+			name:  "time converted to the window's zone",
+			hours: afterSchool,
+			now:   time.Date(2024, 3, 1, 21, 0, 0, 0, time.UTC), // 16:00 in New York
+			want:  true,
+		},
+		{
+			name:  "daylight saving time start uses the wall clock",
+			hours: afterSchool,
+			now:   time.Date(2024, 3, 10, 15, 30, 0, 0, ny),
+			want:  true,
+		},
+		{
+			name:  "overnight window before midnight",
+			hours: overnight,
+			now:   time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC),
+			want:  true,
+		},
+		{
+			name:  "overnight window after midnight",
+			hours: overnight,
+			now:   time.Date(2024, 3, 2, 5, 59, 0, 0, time.UTC),
+			want:  true,
+		},
+		{
+			name:  "outside overnight window",
+			hours: overnight,
+			now:   time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC),
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.hours.Allows(tt.now))
+		})
+	}
+}
+
+func TestAllowedHours_Validate(t *testing.T) {
+	assert.NoError(t, AllowedHours{Start: time.Hour, End: 2 * time.Hour, Location: time.UTC}.Validate())
+	assert.ErrorIs(t, AllowedHours{Start: time.Hour, End: 24 * time.Hour, Location: time.UTC}.Validate(), ErrAllowedHoursInvalid)
+	assert.ErrorIs(t, AllowedHours{Start: time.Hour, End: time.Hour, Location: time.UTC}.Validate(), ErrAllowedHoursInvalid)
+	assert.ErrorIs(t, AllowedHours{Start: time.Hour, End: 2 * time.Hour}.Validate(), ErrAllowedHoursInvalid)
+}
+
+func TestParentalControls(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, sn := range []DisplayScreenName{"Kid", "Parent"} {
+		user, err := NewStubUser(sn)
+		require.NoError(t, err)
+		require.NoError(t, f.InsertUser(ctx, user))
+	}
+	kid := NewIdentScreenName("Kid")
+	parent := NewIdentScreenName("Parent")
+
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	hours := AllowedHours{Start: 15 * time.Hour, End: 20 * time.Hour, Location: ny}
+	require.NoError(t, f.SetAllowedHours(ctx, kid, hours))
+
+	got, ok, err := f.AllowedHours(ctx, kid)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, hours.Start, got.Start)
+	assert.Equal(t, hours.End, got.End)
+	assert.Equal(t, "America/New_York", got.Location.String())
+
+	_, ok, err = f.AllowedHours(ctx, parent)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.ErrorIs(t, f.SetAllowedHours(ctx, NewIdentScreenName("nobody"), hours), ErrNoUser)
+
+	sm := NewInMemorySessionManager(slog.Default())
+	kidSess, err := sm.AddSession(ctx, "Kid")
+	require.NoError(t, err)
+	kidSess.SetSignonComplete()
+	parentSess, err := sm.AddSession(ctx, "Parent")
+	require.NoError(t, err)
+	parentSess.SetSignonComplete()
+
+	pc := NewParentalControls(f, sm, slog.Default())
+	now := time.Date(2024, 3, 1, 16, 0, 0, 0, ny)
+	pc.nowFn = func() time.Time { return now }
+
+	t.Run("within allowed hours", func(t *testing.T) {
+		assert.NoError(t, pc.CheckSignon(ctx, kid))
+		restricted, err := pc.RemoteRestricted(ctx, kid)
+		require.NoError(t, err)
+		assert.False(t, restricted)
+
+		closed, err := pc.Sweep(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, closed)
+	})
+
+	t.Run("outside allowed hours", func(t *testing.T) {
+		now = time.Date(2024, 3, 1, 20, 0, 0, 0, ny)
+
+		err := pc.CheckSignon(ctx, kid)
+		assert.ErrorIs(t, err, ErrRestrictedByParentalControls)
+		assert.Equal(t, wire.ErrorCodeRestrictedByPc, ErrorCode(err))
+		assert.NoError(t, pc.CheckSignon(ctx, parent))
+
+		restricted, err := pc.RemoteRestricted(ctx, kid)
+		require.NoError(t, err)
+		assert.True(t, restricted)
+
+		closed, err := pc.Sweep(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []IdentScreenName{kid}, closed)
+		assert.Equal(t, DisconnectParentalControls, kidSess.DisconnectReason())
+		assert.Equal(t, DisconnectNone, parentSess.DisconnectReason())
+	})
+
+	t.Run("restriction lifted", func(t *testing.T) {
+		require.NoError(t, f.ClearAllowedHours(ctx, kid))
+		assert.NoError(t, pc.CheckSignon(ctx, kid))
+	})
+}
@@ -0,0 +1,305 @@
+package state
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	lib "modernc.org/sqlite/lib"
+)
+
+const (
+	// PasswordResetTTL is how long a password reset code stays valid.
+	PasswordResetTTL = time.Hour
+	// passwordResetCodeLen is the number of characters in a reset code.
+	passwordResetCodeLen = 10
+	// passwordResetAlphabet is the set of characters reset codes are made
+	// of. Characters that are easily confused, such as 0 and O, are left
+	// out so that codes can be read out over the phone.
+	passwordResetAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// ErrPasswordResetNoEmail indicates that a reset code can't be emailed
+// because the user has no verified email address.
+var ErrPasswordResetNoEmail = errors.New("user has no verified email address")
+
+// PasswordResetStore persists password reset codes.
+type PasswordResetStore interface {
+	User(ctx context.Context, screenName IdentScreenName) (*User, error)
+	NewPasswordResetCode(ctx context.Context, screenName IdentScreenName, expiresAt time.Time) (string, error)
+	RedeemPasswordResetCode(ctx context.Context, code string, newPassword string, now time.Time) (IdentScreenName, error)
+}
+
+// NewPasswordResetCode creates a one-time code that sets a new password for
+// the user when passed to RedeemPasswordResetCode before expiresAt. It
+// replaces any code previously issued to the user. Only a hash of the code
+// is stored. It returns ErrNoUser if the user doesn't exist.
+func (us SQLiteUserStore) NewPasswordResetCode(ctx context.Context, screenName IdentScreenName, expiresAt time.Time) (string, error) {
+	b := make([]byte, passwordResetCodeLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("NewPasswordResetCode: %w", err)
+	}
+	for i := range b {
+		// the alphabet has 32 characters, so masking keeps the choice
+		// uniform
+		b[i] = passwordResetAlphabet[b[i]&31]
+	}
+	code := string(b)
+
+	err := us.WithTx(ctx, func(tx *SQLiteUserStore) error {
+		q := `DELETE FROM passwordReset WHERE screenName = ?`
+		if _, err := tx.db.ExecContext(ctx, q, screenName.String()); err != nil {
+			return err
+		}
+		q = `
+			INSERT INTO passwordReset (codeHash, screenName, expiresAt)
+			VALUES (?, ?, ?)
+		`
+		_, err := tx.db.ExecContext(ctx, q, hashPasswordResetCode(code), screenName.String(), expiresAt.Unix())
+		if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == lib.SQLITE_CONSTRAINT_FOREIGNKEY {
+			return ErrNoUser
+		}
+		return err
+	})
+	switch {
+	case errors.Is(err, ErrNoUser):
+		return "", err
+	case err != nil:
+		return "", fmt.Errorf("NewPasswordResetCode: %w", err)
+	}
+
+	return code[:passwordResetCodeLen/2] + "-" + code[passwordResetCodeLen/2:], nil
+}
+
+// RedeemPasswordResetCode sets the password of the user a code was issued
+// to and returns their screen name. It returns ErrPasswordResetFailed if
+// the code is unknown or expired. Codes can only be used once, but a code
+// stays valid if newPassword is rejected, such as with ErrPasswordInvalid.
+// Codes are matched ignoring case, spaces and dashes.
+func (us SQLiteUserStore) RedeemPasswordResetCode(ctx context.Context, code string, newPassword string, now time.Time) (IdentScreenName, error) {
+	var screenName string
+	redeemed := false
+	err := us.WithTx(ctx, func(tx *SQLiteUserStore) error {
+		codeHash := hashPasswordResetCode(code)
+		var expiresAt int64
+		q := `SELECT screenName, expiresAt FROM passwordReset WHERE codeHash = ?`
+		err := tx.db.QueryRowContext(ctx, q, codeHash).Scan(&screenName, &expiresAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if _, err := tx.db.ExecContext(ctx, `DELETE FROM passwordReset WHERE codeHash = ?`, codeHash); err != nil {
+			return err
+		}
+		if now.Unix() >= expiresAt {
+			return nil
+		}
+
+		if err := tx.SetUserPassword(ctx, NewIdentScreenName(screenName), newPassword); err != nil {
+			return err
+		}
+		redeemed = true
+		return nil
+	})
+	switch {
+	case errors.Is(err, ErrPasswordInvalid) || errors.Is(err, ErrNoUser):
+		return IdentScreenName{}, err
+	case err != nil:
+		return IdentScreenName{}, fmt.Errorf("RedeemPasswordResetCode: %w", err)
+	case !redeemed:
+		return IdentScreenName{}, ErrPasswordResetFailed
+	}
+	return NewIdentScreenName(screenName), nil
+}
+
+// hashPasswordResetCode returns the hex-encoded SHA-256 hash of a reset
+// code after normalizing the way users are likely to type it.
+func hashPasswordResetCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.NewReplacer(" ", "", "-", "").Replace(code)
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// PasswordResetter issues password reset codes, either to an admin who
+// passes them on to the user or by email, and redeems them. Redeeming a
+// code signs off the user's session, which was authenticated with the old
+// password.
+type PasswordResetter struct {
+	store     PasswordResetStore
+	sender    EmailSender
+	sessions  SessionRetriever
+	redeemURL string
+	logger    *slog.Logger
+	nowFn     func() time.Time
+}
+
+// NewPasswordResetter creates a new instance of PasswordResetter. redeemURL
+// is the public URL of the page that submits to RedeemHandler; the code is
+// appended to it as the "code" query parameter in reset emails.
+func NewPasswordResetter(store PasswordResetStore, sender EmailSender, sessions SessionRetriever, redeemURL string, logger *slog.Logger) *PasswordResetter {
+	return &PasswordResetter{
+		store:     store,
+		sender:    sender,
+		sessions:  sessions,
+		redeemURL: redeemURL,
+		logger:    logger,
+		nowFn:     time.Now,
+	}
+}
+
+// IssueCode returns a new reset code for the user, valid for
+// PasswordResetTTL. It's meant for admins, who pass the code on to the user
+// out of band. It returns ErrNoUser if the user doesn't exist.
+func (p *PasswordResetter) IssueCode(ctx context.Context, screenName IdentScreenName) (string, error) {
+	code, err := p.store.NewPasswordResetCode(ctx, screenName, p.nowFn().Add(PasswordResetTTL))
+	if err != nil {
+		return "", err
+	}
+	p.logger.InfoContext(ctx, "issued password reset code", "screen_name", screenName)
+	return code, nil
+}
+
+// RequestReset emails a new reset code to the user's verified email
+// address. It returns ErrNoUser if the user doesn't exist and
+// ErrPasswordResetNoEmail if they have no verified address.
+func (p *PasswordResetter) RequestReset(ctx context.Context, screenName IdentScreenName) error {
+	user, err := p.store.User(ctx, screenName)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrNoUser
+	}
+	if user.EmailAddress == "" || !user.EmailVerified {
+		return ErrPasswordResetNoEmail
+	}
+	emailAddress, err := mail.ParseAddress(user.EmailAddress)
+	if err != nil {
+		return fmt.Errorf("parse email address: %w", err)
+	}
+
+	code, err := p.store.NewPasswordResetCode(ctx, user.IdentScreenName, p.nowFn().Add(PasswordResetTTL))
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(p.redeemURL)
+	if err != nil {
+		return fmt.Errorf("parse reset URL: %w", err)
+	}
+	query := link.Query()
+	query.Set("code", code)
+	link.RawQuery = query.Encode()
+
+	body := fmt.Sprintf("Hello %s,\n\n"+
+		"Your password reset code is %s. Enter it at the link below within %s to choose a new password:\n\n"+
+		"%s\n\n"+
+		"If you didn't request this, you can ignore this message. Your password hasn't been changed.\n",
+		user.DisplayScreenName, code, PasswordResetTTL, link)
+	if err := p.sender.SendEmail(ctx, emailAddress, "Reset your password", body); err != nil {
+		return fmt.Errorf("send password reset email: %w", err)
+	}
+
+	p.logger.InfoContext(ctx, "sent password reset code", "screen_name", user.IdentScreenName)
+	return nil
+}
+
+// Redeem sets a new password for the user a code was issued to and signs
+// off their session. It returns ErrPasswordResetFailed if the code is
+// invalid or expired and ErrPasswordInvalid if newPassword is rejected.
+func (p *PasswordResetter) Redeem(ctx context.Context, code string, newPassword string) (IdentScreenName, error) {
+	screenName, err := p.store.RedeemPasswordResetCode(ctx, code, newPassword, p.nowFn())
+	if err != nil {
+		return IdentScreenName{}, err
+	}
+
+	if sess := p.sessions.RetrieveSession(screenName); sess != nil {
+		sess.CloseWithReason(DisconnectPasswordReset)
+	}
+
+	p.logger.InfoContext(ctx, "password reset", "screen_name", screenName)
+	return screenName, nil
+}
+
+// RequestHandler accepts a POST with the form field "screen_name" and
+// emails that user a reset code. It responds 202 whether or not a code was
+// sent, so that it can't be used to probe for accounts or email addresses.
+func (p *PasswordResetter) RequestHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, 4096)
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("screen_name") == "" {
+			http.Error(w, "Invalid form.", http.StatusBadRequest)
+			return
+		}
+
+		screenName := NewIdentScreenName(r.PostForm.Get("screen_name"))
+		err := p.RequestReset(r.Context(), screenName)
+		switch {
+		case errors.Is(err, ErrNoUser), errors.Is(err, ErrPasswordResetNoEmail):
+			p.logger.InfoContext(r.Context(), "password reset not sent", "screen_name", screenName, "err", err)
+		case err != nil:
+			p.logger.ErrorContext(r.Context(), "unable to send password reset code", "err", err)
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = fmt.Fprintln(w, "If the account has a verified email address, a reset code has been sent to it.")
+	}
+}
+
+// RedeemHandler accepts a POST with the form fields "code" and "password"
+// and sets the new password. It responds 200 once the password is reset
+// and 400 if the code or password is invalid.
+func (p *PasswordResetter) RedeemHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, 4096)
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Invalid form.", http.StatusBadRequest)
+			return
+		}
+
+		_, err := p.Redeem(r.Context(), r.PostForm.Get("code"), r.PostForm.Get("password"))
+		switch {
+		case errors.Is(err, ErrPasswordResetFailed):
+			http.Error(w, "This reset code is invalid or has expired.", http.StatusBadRequest)
+		case errors.Is(err, ErrPasswordInvalid):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case err != nil:
+			p.logger.ErrorContext(r.Context(), "unable to reset password", "err", err)
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		default:
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = fmt.Fprintln(w, "Your password has been changed.")
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteUserStore_RedeemPasswordResetCode(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		// given returns the code to redeem
+		given        func(t *testing.T, f *SQLiteUserStore, sn IdentScreenName) string
+		password     string
+		wantErr      error
+		wantPassword string
+		// wantReusable indicates that the code is still valid afterwards
+		wantReusable bool
+	}{
+		{
+			name: "valid code sets the password",
+			given: func(t *testing.T, f *SQLiteUserStore, sn IdentScreenName) string {
+				code, err := f.NewPasswordResetCode(context.Background(), sn, now.Add(time.Hour))
+				require.NoError(t, err)
+				return code
+			},
+			password:     "newpass",
+			wantPassword: "newpass",
+		},
+		{
+			name: "code is matched ignoring case and dashes",
+			given: func(t *testing.T, f *SQLiteUserStore, sn IdentScreenName) string {
+				code, err := f.NewPasswordResetCode(context.Background(), sn, now.Add(time.Hour))
+				require.NoError(t, err)
+				return strings.ToLower(strings.ReplaceAll(code, "-", " "))
+			},
+			password:     "newpass",
+			wantPassword: "newpass",
+		},
+		{
+			name: "unknown code",
+			given: func(t *testing.T, f *SQLiteUserStore, sn IdentScreenName) string {
+				return "ABCDE-FGHJK"
+			},
+			password:     "newpass",
+			wantErr:      ErrPasswordResetFailed,
+			wantPassword: "welcome1",
+		},
+		{
+			name: "expired code",
+			given: func(t *testing.T, f *SQLiteUserStore, sn IdentScreenName) string {
+				code, err := f.NewPasswordResetCode(context.Background(), sn, now)
+				require.NoError(t, err)
+				return code
+			},
+			password:     "newpass",
+			wantErr:      ErrPasswordResetFailed,
+			wantPassword: "welcome1",
+		},
+		{
+			name: "new code replaces the old one",
+			given: func(t *testing.T, f *SQLiteUserStore, sn IdentScreenName) string {
+				code, err := f.NewPasswordResetCode(context.Background(), sn, now.Add(time.Hour))
+				require.NoError(t, err)
+				_, err = f.NewPasswordResetCode(context.Background(), sn, now.Add(time.Hour))
+				require.NoError(t, err)
+				return code
+			},
+			password:     "newpass",
+			wantErr:      ErrPasswordResetFailed,
+			wantPassword: "welcome1",
+		},
+		{
+			name: "rejected password keeps the code valid",
+			given: func(t *testing.T, f *SQLiteUserStore, sn IdentScreenName) string {
+				code, err := f.NewPasswordResetCode(context.Background(), sn, now.Add(time.Hour))
+				require.NoError(t, err)
+				return code
+			},
+			password:     "abc",
+			wantErr:      ErrPasswordInvalid,
+			wantPassword: "welcome1",
+			wantReusable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := newTestDBPath(t)
+
+			f, err := NewSQLiteUserStore(testFile)
+			require.NoError(t, err)
+
+			user, err := NewStubUser("me")
+			require.NoError(t, err)
+			require.NoError(t, f.InsertUser(context.Background(), user))
+
+			code := tt.given(t, f, user.IdentScreenName)
+			have, err := f.RedeemPasswordResetCode(context.Background(), code, tt.password, now)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, user.IdentScreenName, have)
+			}
+
+			u, err := f.User(context.Background(), user.IdentScreenName)
+			require.NoError(t, err)
+			assert.True(t, u.ValidatePlaintextPass([]byte(tt.wantPassword)))
+
+			// codes are single-use
+			_, err = f.RedeemPasswordResetCode(context.Background(), code, "another", now)
+			if tt.wantReusable {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrPasswordResetFailed)
+			}
+		})
+	}
+}
+
+func TestSQLiteUserStore_NewPasswordResetCode_NoUser(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	_, err = f.NewPasswordResetCode(context.Background(), NewIdentScreenName("nobody"), time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, ErrNoUser)
+}
+
+func TestPasswordResetter(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	user, err := NewStubUser("Me")
+	require.NoError(t, err)
+	require.NoError(t, f.InsertUser(ctx, user))
+
+	sm := NewInMemorySessionManager(slog.Default())
+	sess, err := sm.AddSession(ctx, user.DisplayScreenName)
+	require.NoError(t, err)
+	sess.SetSignonComplete()
+
+	sender := &fakeEmailSender{}
+	p := NewPasswordResetter(f, sender, sm, "https://aim.example.com/reset-password", slog.Default())
+
+	t.Run("no verified email address", func(t *testing.T) {
+		addr := &mail.Address{Address: "me@example.com"}
+		require.NoError(t, f.UpdateEmailAddress(ctx, user.IdentScreenName, addr))
+		assert.ErrorIs(t, p.RequestReset(ctx, user.IdentScreenName), ErrPasswordResetNoEmail)
+
+		token, err := f.NewEmailVerificationToken(ctx, user.IdentScreenName, addr, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		_, err = f.ConfirmEmailAddress(ctx, token, time.Now())
+		require.NoError(t, err)
+	})
+
+	t.Run("unknown user gets the same response", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/reset-password/request", strings.NewReader(url.Values{"screen_name": {"nobody"}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		p.RequestHandler()(rec, req)
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Nil(t, sender.to)
+	})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/reset-password/request", strings.NewReader(url.Values{"screen_name": {"me"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	p.RequestHandler()(rec, req)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	require.NotNil(t, sender.to)
+	assert.Equal(t, "me@example.com", sender.to.Address)
+
+	link := regexp.MustCompile(`https://\S+`).FindString(sender.body)
+	require.NotEmpty(t, link)
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	code := u.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	redeem := func(code, password string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/reset-password", strings.NewReader(url.Values{"code": {code}, "password": {password}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		p.RedeemHandler()(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, redeem(code, "abc"))
+	select {
+	case <-sess.Closed():
+		t.Fatal("session closed by rejected reset")
+	default:
+	}
+
+	assert.Equal(t, http.StatusOK, redeem(code, "newpass"))
+	select {
+	case <-sess.Closed():
+	default:
+		t.Fatal("session not closed by reset")
+	}
+	assert.Equal(t, DisconnectPasswordReset, sess.DisconnectReason())
+
+	assert.Equal(t, http.StatusBadRequest, redeem(code, "newpass"))
+
+	t.Run("admin issued code", func(t *testing.T) {
+		code, err := p.IssueCode(ctx, user.IdentScreenName)
+		require.NoError(t, err)
+		screenName, err := p.Redeem(ctx, code, "adminpass")
+		require.NoError(t, err)
+		assert.Equal(t, user.IdentScreenName, screenName)
+
+		_, err = p.IssueCode(ctx, NewIdentScreenName("nobody"))
+		assert.ErrorIs(t, err, ErrNoUser)
+	})
+}
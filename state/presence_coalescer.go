@@ -0,0 +1,226 @@
+package state
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PresenceBroadcaster sends a user's buddy arrival (arrived is true) or
+// departure notification to their watchers. A departure comes with the
+// reason the user's session was closed, so that the broadcaster can log why
+// watchers saw the user leave; arrivals come with DisconnectNone. It should read the user's
+// current session when sending an arrival, since the arrival may be
+// delivered some time after the event that caused it, using
+// InMemorySessionManager.RetrievePresenceSession so that a linked identity
+// without a session of its own is announced with its linked session.
+type PresenceBroadcaster func(ctx context.Context, screenName IdentScreenName, arrived bool, reason DisconnectReason)
+
+// presenceFlap tracks a user's presence broadcasts within the current
+// debounce window.
+type presenceFlap struct {
+	// sentArrived is the state of the last broadcast.
+	sentArrived bool
+	// pending indicates that events arrived since the last broadcast.
+	pending bool
+	// arrived is the state of the latest pending event.
+	arrived bool
+	// reason is the disconnect reason of the latest pending departure.
+	reason DisconnectReason
+	ctx    context.Context
+	timer  *time.Timer
+}
+
+// pendingDeparture is a departure withheld during the departure grace
+// period.
+type pendingDeparture struct {
+	timer *time.Timer
+}
+
+// PresenceCoalescer protects the server from broadcast storms caused by
+// users who repeatedly sign on and off, such as a popular account with
+// thousands of watchers on a flaky connection. The first arrival or
+// departure of a user is broadcast immediately. Events that follow within
+// the debounce window are coalesced, and only the latest is broadcast when
+// the window ends, so each user causes at most one broadcast per window. A
+// departure is dropped if the user was already last announced as departed,
+// so a sign-off and sign-on within the window turns into a single arrival
+// update. Departures can also be held back for a grace period, see
+// SetDepartureGrace. A PresenceCoalescer is safe for concurrent use by
+// multiple goroutines.
+type PresenceCoalescer struct {
+	window     time.Duration
+	grace      time.Duration
+	broadcast  PresenceBroadcaster
+	mirrors    func(screenName IdentScreenName) []IdentScreenName
+	mutex      sync.Mutex
+	flaps      map[IdentScreenName]*presenceFlap
+	departures map[IdentScreenName]*pendingDeparture
+}
+
+// NewPresenceCoalescer creates a new instance of PresenceCoalescer. A
+// window of 0 disables coalescing, so that every event is broadcast
+// immediately.
+func NewPresenceCoalescer(window time.Duration, broadcast PresenceBroadcaster) *PresenceCoalescer {
+	return &PresenceCoalescer{
+		window:     window,
+		broadcast:  broadcast,
+		flaps:      make(map[IdentScreenName]*presenceFlap),
+		departures: make(map[IdentScreenName]*pendingDeparture),
+	}
+}
+
+// SetDepartureGrace holds back each departure for grace before handling it
+// as usual. A user who signs back on within the grace period, such as after
+// a network blip, never appears to leave: the departure is dropped and the
+// arrival reaches watchers as an update of a buddy who is still online. A
+// grace of 0, the default, handles departures right away. Departures
+// already held back keep their grace period.
+func (c *PresenceCoalescer) SetDepartureGrace(grace time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.grace = grace
+}
+
+// SetPresenceMirrors sets the function that returns the identities whose
+// presence follows a user's, such as
+// InMemorySessionManager.PresenceMirrors. Each arrival and departure of the
+// user is then also broadcast for those identities, so that linked AIM and
+// ICQ identities appear online together.
+func (c *PresenceCoalescer) SetPresenceMirrors(mirrors func(screenName IdentScreenName) []IdentScreenName) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.mirrors = mirrors
+}
+
+// Arrived broadcasts, now or at the end of the debounce window, that the
+// user signed on or changed their user info.
+func (c *PresenceCoalescer) Arrived(ctx context.Context, screenName IdentScreenName) {
+	c.mutex.Lock()
+	if d, ok := c.departures[screenName]; ok {
+		d.timer.Stop()
+		delete(c.departures, screenName)
+	}
+	c.mutex.Unlock()
+
+	c.announce(ctx, screenName, true, DisconnectNone)
+}
+
+// Departed broadcasts, now or at the end of the debounce window, that the
+// user signed off, along with the reason their session was closed. With a
+// departure grace period, the departure is handled once the period ends,
+// unless the user signs back on first. Departures whose reason doesn't
+// call for one, see DisconnectReason.AnnounceDeparture, are dropped.
+func (c *PresenceCoalescer) Departed(ctx context.Context, screenName IdentScreenName, reason DisconnectReason) {
+	if !reason.AnnounceDeparture() {
+		return
+	}
+
+	c.mutex.Lock()
+	if c.grace == 0 {
+		c.mutex.Unlock()
+		c.announce(ctx, screenName, false, reason)
+		return
+	}
+	if _, ok := c.departures[screenName]; ok {
+		c.mutex.Unlock()
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	d := &pendingDeparture{}
+	d.timer = time.AfterFunc(c.grace, func() {
+		c.mutex.Lock()
+		if c.departures[screenName] != d {
+			c.mutex.Unlock()
+			return
+		}
+		delete(c.departures, screenName)
+		c.mutex.Unlock()
+
+		c.announce(ctx, screenName, false, reason)
+	})
+	c.departures[screenName] = d
+	c.mutex.Unlock()
+}
+
+// announce queues the event for the user and for the identities whose
+// presence follows the user's.
+func (c *PresenceCoalescer) announce(ctx context.Context, screenName IdentScreenName, arrived bool, reason DisconnectReason) {
+	c.mutex.Lock()
+	mirrors := c.mirrors
+	c.mutex.Unlock()
+
+	c.queue(ctx, screenName, arrived, reason)
+	if mirrors == nil {
+		return
+	}
+	for _, mirror := range mirrors(screenName) {
+		c.queue(ctx, mirror, arrived, reason)
+	}
+}
+
+func (c *PresenceCoalescer) queue(ctx context.Context, screenName IdentScreenName, arrived bool, reason DisconnectReason) {
+	if c.window == 0 {
+		c.broadcast(ctx, screenName, arrived, reason)
+		return
+	}
+
+	c.mutex.Lock()
+	if flap, ok := c.flaps[screenName]; ok {
+		flap.pending = true
+		flap.arrived = arrived
+		flap.reason = reason
+		flap.ctx = context.WithoutCancel(ctx)
+		c.mutex.Unlock()
+		return
+	}
+	flap := &presenceFlap{sentArrived: arrived}
+	flap.timer = time.AfterFunc(c.window, func() {
+		c.endWindow(screenName)
+	})
+	c.flaps[screenName] = flap
+	c.mutex.Unlock()
+
+	c.broadcast(ctx, screenName, arrived, reason)
+}
+
+// endWindow broadcasts the latest event coalesced during the user's
+// debounce window. If there was one, a new window starts, which keeps
+// damping a user who is still flapping.
+func (c *PresenceCoalescer) endWindow(screenName IdentScreenName) {
+	c.mutex.Lock()
+	flap, ok := c.flaps[screenName]
+	if !ok {
+		c.mutex.Unlock()
+		return
+	}
+	if !flap.pending || (!flap.arrived && !flap.sentArrived) {
+		delete(c.flaps, screenName)
+		c.mutex.Unlock()
+		return
+	}
+
+	arrived, reason, ctx := flap.arrived, flap.reason, flap.ctx
+	flap.sentArrived = arrived
+	flap.pending = false
+	flap.ctx = nil
+	flap.timer.Reset(c.window)
+	c.mutex.Unlock()
+
+	c.broadcast(ctx, screenName, arrived, reason)
+}
+
+// Stop cancels the pending broadcasts, including departures held back for
+// the grace period. Events queued afterward are handled as usual.
+func (c *PresenceCoalescer) Stop() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for screenName, flap := range c.flaps {
+		flap.timer.Stop()
+		delete(c.flaps, screenName)
+	}
+	for screenName, d := range c.departures {
+		d.timer.Stop()
+		delete(c.departures, screenName)
+	}
+}
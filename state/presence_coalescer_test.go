@@ -0,0 +1,200 @@
+package state
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type presenceEvent struct {
+	screenName IdentScreenName
+	arrived    bool
+}
+
+type presenceRecorder struct {
+	mutex  sync.Mutex
+	events []presenceEvent
+	// reasons holds the disconnect reason of each departure.
+	reasons []DisconnectReason
+}
+
+func (r *presenceRecorder) broadcast(_ context.Context, screenName IdentScreenName, arrived bool, reason DisconnectReason) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = append(r.events, presenceEvent{screenName: screenName, arrived: arrived})
+	if !arrived {
+		r.reasons = append(r.reasons, reason)
+	}
+}
+
+func (r *presenceRecorder) getReasons() []DisconnectReason {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]DisconnectReason(nil), r.reasons...)
+}
+
+func (r *presenceRecorder) get() []presenceEvent {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]presenceEvent(nil), r.events...)
+}
+
+func TestPresenceCoalescer(t *testing.T) {
+	ctx := context.Background()
+	celeb := NewIdentScreenName("celeb")
+	fan := NewIdentScreenName("fan")
+	window := 50 * time.Millisecond
+
+	t.Run("flaps within the window are coalesced", func(t *testing.T) {
+		rec := &presenceRecorder{}
+		c := NewPresenceCoalescer(window, rec.broadcast)
+		defer c.Stop()
+
+		c.Arrived(ctx, celeb)
+		for range 100 {
+			c.Departed(ctx, celeb, DisconnectSignoff)
+			c.Arrived(ctx, celeb)
+		}
+		c.Arrived(ctx, fan)
+
+		assert.Equal(t, []presenceEvent{{celeb, true}, {fan, true}}, rec.get())
+		assert.Eventually(t, func() bool {
+			return len(rec.get()) == 3
+		}, time.Second, 5*time.Millisecond)
+		assert.Equal(t, presenceEvent{celeb, true}, rec.get()[2])
+	})
+
+	t.Run("final departure is broadcast", func(t *testing.T) {
+		rec := &presenceRecorder{}
+		c := NewPresenceCoalescer(window, rec.broadcast)
+		defer c.Stop()
+
+		c.Arrived(ctx, celeb)
+		c.Departed(ctx, celeb, DisconnectSignoff)
+		c.Arrived(ctx, celeb)
+		c.Departed(ctx, celeb, DisconnectSignoff)
+
+		assert.Eventually(t, func() bool {
+			return len(rec.get()) == 2
+		}, time.Second, 5*time.Millisecond)
+		assert.Equal(t, []presenceEvent{{celeb, true}, {celeb, false}}, rec.get())
+	})
+
+	t.Run("redundant departure is dropped", func(t *testing.T) {
+		rec := &presenceRecorder{}
+		c := NewPresenceCoalescer(window, rec.broadcast)
+		defer c.Stop()
+
+		c.Departed(ctx, celeb, DisconnectSignoff)
+		c.Arrived(ctx, celeb)
+		c.Departed(ctx, celeb, DisconnectSignoff)
+
+		time.Sleep(3 * window)
+		assert.Equal(t, []presenceEvent{{celeb, false}}, rec.get())
+
+		// the window has ended, so the next event is broadcast right away
+		c.Arrived(ctx, celeb)
+		assert.Equal(t, []presenceEvent{{celeb, false}, {celeb, true}}, rec.get())
+	})
+
+	t.Run("reconnect within the departure grace period", func(t *testing.T) {
+		rec := &presenceRecorder{}
+		c := NewPresenceCoalescer(0, rec.broadcast)
+		c.SetDepartureGrace(window)
+		defer c.Stop()
+
+		c.Departed(ctx, celeb, DisconnectSignoff)
+		c.Arrived(ctx, celeb)
+
+		time.Sleep(3 * window)
+		assert.Equal(t, []presenceEvent{{celeb, true}}, rec.get())
+	})
+
+	t.Run("departure is broadcast after the grace period", func(t *testing.T) {
+		rec := &presenceRecorder{}
+		c := NewPresenceCoalescer(0, rec.broadcast)
+		c.SetDepartureGrace(window)
+		defer c.Stop()
+
+		c.Departed(ctx, celeb, DisconnectSignoff)
+		c.Departed(ctx, celeb, DisconnectSignoff)
+		c.Arrived(ctx, fan)
+		assert.Equal(t, []presenceEvent{{fan, true}}, rec.get())
+
+		assert.Eventually(t, func() bool {
+			return len(rec.get()) == 2
+		}, time.Second, 5*time.Millisecond)
+		assert.Equal(t, presenceEvent{celeb, false}, rec.get()[1])
+		time.Sleep(2 * window)
+		assert.Len(t, rec.get(), 2)
+	})
+
+	t.Run("stop cancels departures held back", func(t *testing.T) {
+		rec := &presenceRecorder{}
+		c := NewPresenceCoalescer(0, rec.broadcast)
+		c.SetDepartureGrace(window)
+
+		c.Departed(ctx, celeb, DisconnectSignoff)
+		c.Stop()
+
+		time.Sleep(3 * window)
+		assert.Empty(t, rec.get())
+	})
+
+	t.Run("zero window disables coalescing", func(t *testing.T) {
+		rec := &presenceRecorder{}
+		c := NewPresenceCoalescer(0, rec.broadcast)
+
+		c.Arrived(ctx, celeb)
+		c.Departed(ctx, celeb, DisconnectSignoff)
+		c.Arrived(ctx, celeb)
+
+		assert.Equal(t, []presenceEvent{{celeb, true}, {celeb, false}, {celeb, true}}, rec.get())
+	})
+
+	t.Run("mirrored identities follow the user", func(t *testing.T) {
+		rec := &presenceRecorder{}
+		c := NewPresenceCoalescer(0, rec.broadcast)
+		c.SetPresenceMirrors(func(screenName IdentScreenName) []IdentScreenName {
+			if screenName == celeb {
+				return []IdentScreenName{fan}
+			}
+			return nil
+		})
+
+		c.Arrived(ctx, celeb)
+		c.Departed(ctx, celeb, DisconnectSignoff)
+
+		assert.Equal(t, []presenceEvent{{celeb, true}, {fan, true}, {celeb, false}, {fan, false}}, rec.get())
+	})
+
+	t.Run("departure carries its reason", func(t *testing.T) {
+		rec := &presenceRecorder{}
+		c := NewPresenceCoalescer(window, rec.broadcast)
+		defer c.Stop()
+
+		c.Departed(ctx, celeb, DisconnectSuspended)
+		c.Arrived(ctx, fan)
+		c.Departed(ctx, fan, DisconnectSignoff)
+		c.Departed(ctx, fan, DisconnectKicked)
+
+		assert.Eventually(t, func() bool {
+			return len(rec.get()) == 3
+		}, time.Second, 5*time.Millisecond)
+		assert.Equal(t, []DisconnectReason{DisconnectSuspended, DisconnectKicked}, rec.getReasons())
+	})
+
+	t.Run("new login departure is dropped", func(t *testing.T) {
+		rec := &presenceRecorder{}
+		c := NewPresenceCoalescer(0, rec.broadcast)
+
+		c.Arrived(ctx, celeb)
+		c.Departed(ctx, celeb, DisconnectNewLogin)
+		c.Arrived(ctx, celeb)
+
+		assert.Equal(t, []presenceEvent{{celeb, true}, {celeb, true}}, rec.get())
+	})
+}
@@ -0,0 +1,423 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// PresenceEventSchema is the WebhookEvent schema of presence events, whose
+// Data is a PresenceEvent.
+const PresenceEventSchema = "presence.v1"
+
+// PresenceStatusChanged is the type of the event sent when a watched buddy
+// changes status.
+const PresenceStatusChanged = "status_changed"
+
+const (
+	// PresenceWebhookMaxBuddies is the most buddies a presence webhook may
+	// watch.
+	PresenceWebhookMaxBuddies = 50
+	// PresenceWebhookWindow is the window over which the events sent to
+	// each user's presence webhook are limited.
+	PresenceWebhookWindow = time.Minute
+)
+
+var (
+	// ErrPresenceWebhookNotAllowed indicates that a user hasn't been allowed
+	// by an operator to register a presence webhook.
+	ErrPresenceWebhookNotAllowed = errors.New("presence webhooks aren't allowed for this user")
+	// ErrPresenceWebhookNotFound indicates that a user has no presence
+	// webhook.
+	ErrPresenceWebhookNotFound = errors.New("presence webhook not found")
+	// ErrPresenceWebhookInvalid indicates that a presence webhook has a bad
+	// URL or watches too many buddies.
+	ErrPresenceWebhookInvalid = errors.New("invalid presence webhook")
+)
+
+// PresenceStatus is a buddy's status as reported in a PresenceEvent.
+type PresenceStatus string
+
+const (
+	// PresenceOnline is reported when a buddy signs on or comes back from
+	// away.
+	PresenceOnline PresenceStatus = "online"
+	// PresenceAway is reported when a buddy goes away.
+	PresenceAway PresenceStatus = "away"
+	// PresenceOffline is reported when a buddy signs off.
+	PresenceOffline PresenceStatus = "offline"
+)
+
+// PresenceEvent describes a status change of a buddy watched by a user's
+// presence webhook.
+type PresenceEvent struct {
+	// ScreenName is the user who registered the webhook.
+	ScreenName string `json:"screen_name"`
+	// Buddy is the buddy whose status changed.
+	Buddy string `json:"buddy"`
+	// Status is the buddy's new status.
+	Status PresenceStatus `json:"status"`
+}
+
+// PresenceWebhook is a callback registered by a user to hear about status
+// changes of specific buddies.
+type PresenceWebhook struct {
+	// Owner is the user who registered the webhook.
+	Owner IdentScreenName
+	// URL is the absolute http or https URL events are posted to.
+	URL string
+	// Secret signs the requests in WebhookSignatureHeader. Empty leaves
+	// requests unsigned.
+	Secret string
+	// Buddies are the buddies whose status changes are posted.
+	Buddies []IdentScreenName
+}
+
+// validate checks the webhook's URL and number of buddies.
+func (h PresenceWebhook) validate() error {
+	u, err := url.Parse(h.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: URL %q must be an absolute http or https URL", ErrPresenceWebhookInvalid, h.URL)
+	}
+	if len(h.Buddies) > PresenceWebhookMaxBuddies {
+		return fmt.Errorf("%w: watches %d buddies, at most %d are allowed", ErrPresenceWebhookInvalid,
+			len(h.Buddies), PresenceWebhookMaxBuddies)
+	}
+	return nil
+}
+
+// SetPresenceWebhookAllowed sets whether screenName may register a presence
+// webhook. Revoking the grant deletes the user's webhook. It returns ErrNoUser
+// if the user doesn't exist.
+func (us SQLiteUserStore) SetPresenceWebhookAllowed(ctx context.Context, screenName IdentScreenName, allowed bool) (err error) {
+	var tx storeTx
+	tx, err = us.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var res sql.Result
+	q := `UPDATE users SET presenceWebhookAllowed = ? WHERE identScreenName = ?`
+	res, err = tx.ExecContext(ctx, q, allowed, screenName.String())
+	if err != nil {
+		return fmt.Errorf("SetPresenceWebhookAllowed: %w", err)
+	}
+	var n int64
+	if n, err = res.RowsAffected(); err != nil {
+		return fmt.Errorf("SetPresenceWebhookAllowed: %w", err)
+	}
+	if n == 0 {
+		err = ErrNoUser
+		return err
+	}
+
+	if !allowed {
+		q = `DELETE FROM presenceWebhook WHERE identScreenName = ?`
+		if _, err = tx.ExecContext(ctx, q, screenName.String()); err != nil {
+			return fmt.Errorf("SetPresenceWebhookAllowed: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// SetPresenceWebhook registers hook for its owner, replacing the owner's
+// previous webhook and buddies. It returns ErrNoUser if the owner doesn't
+// exist, ErrPresenceWebhookNotAllowed if an operator hasn't allowed them to
+// register one and ErrPresenceWebhookInvalid if the hook is malformed.
+func (us SQLiteUserStore) SetPresenceWebhook(ctx context.Context, hook PresenceWebhook) (err error) {
+	if err = hook.validate(); err != nil {
+		return err
+	}
+
+	var tx storeTx
+	tx, err = us.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var allowed bool
+	q := `SELECT presenceWebhookAllowed FROM users WHERE identScreenName = ?`
+	err = tx.QueryRowContext(ctx, q, hook.Owner.String()).Scan(&allowed)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = ErrNoUser
+		return err
+	case err != nil:
+		return fmt.Errorf("SetPresenceWebhook: %w", err)
+	case !allowed:
+		err = ErrPresenceWebhookNotAllowed
+		return err
+	}
+
+	q = `
+		INSERT INTO presenceWebhook (identScreenName, url, secret)
+		VALUES (?, ?, ?)
+		ON CONFLICT (identScreenName) DO UPDATE SET url = excluded.url, secret = excluded.secret
+	`
+	if _, err = tx.ExecContext(ctx, q, hook.Owner.String(), hook.URL, hook.Secret); err != nil {
+		return fmt.Errorf("SetPresenceWebhook: %w", err)
+	}
+	q = `DELETE FROM presenceWebhookBuddy WHERE identScreenName = ?`
+	if _, err = tx.ExecContext(ctx, q, hook.Owner.String()); err != nil {
+		return fmt.Errorf("SetPresenceWebhook: %w", err)
+	}
+	q = `INSERT INTO presenceWebhookBuddy (identScreenName, buddy) VALUES (?, ?) ON CONFLICT DO NOTHING`
+	for _, buddy := range hook.Buddies {
+		if _, err = tx.ExecContext(ctx, q, hook.Owner.String(), buddy.String()); err != nil {
+			return fmt.Errorf("SetPresenceWebhook: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// PresenceWebhook returns the webhook registered by screenName, with its
+// buddies in alphabetical order. It returns ErrPresenceWebhookNotFound if
+// the user has none.
+func (us SQLiteUserStore) PresenceWebhook(ctx context.Context, screenName IdentScreenName) (PresenceWebhook, error) {
+	hook := PresenceWebhook{Owner: screenName}
+	q := `SELECT url, secret FROM presenceWebhook WHERE identScreenName = ?`
+	err := us.db.QueryRowContext(ctx, q, screenName.String()).Scan(&hook.URL, &hook.Secret)
+	if errors.Is(err, sql.ErrNoRows) {
+		return PresenceWebhook{}, ErrPresenceWebhookNotFound
+	}
+	if err != nil {
+		return PresenceWebhook{}, fmt.Errorf("PresenceWebhook: %w", err)
+	}
+
+	q = `SELECT buddy FROM presenceWebhookBuddy WHERE identScreenName = ? ORDER BY buddy`
+	rows, err := us.db.QueryContext(ctx, q, screenName.String())
+	if err != nil {
+		return PresenceWebhook{}, fmt.Errorf("PresenceWebhook: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var buddy string
+		if err := rows.Scan(&buddy); err != nil {
+			return PresenceWebhook{}, fmt.Errorf("PresenceWebhook: %w", err)
+		}
+		hook.Buddies = append(hook.Buddies, NewIdentScreenName(buddy))
+	}
+	if err := rows.Err(); err != nil {
+		return PresenceWebhook{}, fmt.Errorf("PresenceWebhook: %w", err)
+	}
+	return hook, nil
+}
+
+// DeletePresenceWebhook deletes the webhook registered by screenName. It
+// returns ErrPresenceWebhookNotFound if the user has none.
+func (us SQLiteUserStore) DeletePresenceWebhook(ctx context.Context, screenName IdentScreenName) error {
+	q := `DELETE FROM presenceWebhook WHERE identScreenName = ?`
+	res, err := us.db.ExecContext(ctx, q, screenName.String())
+	if err != nil {
+		return fmt.Errorf("DeletePresenceWebhook: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("DeletePresenceWebhook: %w", err)
+	}
+	if n == 0 {
+		return ErrPresenceWebhookNotFound
+	}
+	return nil
+}
+
+// PresenceWebhooksWatching returns the webhooks that watch buddy, skipping
+// those of users whose grant has been revoked. The Buddies of the returned
+// webhooks aren't populated.
+func (us SQLiteUserStore) PresenceWebhooksWatching(ctx context.Context, buddy IdentScreenName) ([]PresenceWebhook, error) {
+	q := `
+		SELECT presenceWebhook.identScreenName, presenceWebhook.url, presenceWebhook.secret
+		FROM presenceWebhookBuddy
+		JOIN presenceWebhook ON presenceWebhook.identScreenName = presenceWebhookBuddy.identScreenName
+		JOIN users ON users.identScreenName = presenceWebhook.identScreenName
+		WHERE presenceWebhookBuddy.buddy = ? AND users.presenceWebhookAllowed
+		ORDER BY presenceWebhook.identScreenName
+	`
+	rows, err := us.db.QueryContext(ctx, q, buddy.String())
+	if err != nil {
+		return nil, fmt.Errorf("PresenceWebhooksWatching: %w", err)
+	}
+	defer rows.Close()
+
+	var hooks []PresenceWebhook
+	for rows.Next() {
+		var owner string
+		var hook PresenceWebhook
+		if err := rows.Scan(&owner, &hook.URL, &hook.Secret); err != nil {
+			return nil, fmt.Errorf("PresenceWebhooksWatching: %w", err)
+		}
+		hook.Owner = NewIdentScreenName(owner)
+		hooks = append(hooks, hook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("PresenceWebhooksWatching: %w", err)
+	}
+	return hooks, nil
+}
+
+// PresenceWebhookFinder looks up the presence webhooks that watch a buddy.
+type PresenceWebhookFinder interface {
+	PresenceWebhooksWatching(ctx context.Context, buddy IdentScreenName) ([]PresenceWebhook, error)
+}
+
+// presenceDelivery is an event queued for a user's presence webhook.
+type presenceDelivery struct {
+	hook  PresenceWebhook
+	event WebhookEvent
+}
+
+// presenceWindow counts the events sent to a user's webhook since start.
+type presenceWindow struct {
+	start time.Time
+	count int
+}
+
+// PresenceWebhooks posts buddies' status changes to the webhooks users
+// registered for them. Each user's webhook gets at most limit events per
+// PresenceWebhookWindow; events past the limit, events for buddies who
+// block the user and events that don't fit in the queue are dropped.
+// Events are posted one at a time by Run, so that slow endpoints never hold
+// up the server. A nil *PresenceWebhooks posts nothing, so callers don't
+// need to check whether presence webhooks are enabled.
+type PresenceWebhooks struct {
+	finder        PresenceWebhookFinder
+	relationships RelationshipFetcher
+	client        *http.Client
+	queue         chan presenceDelivery
+	limit         int
+	mutex         sync.Mutex
+	windows       map[IdentScreenName]*presenceWindow
+	logger        *slog.Logger
+	nowFn         func() time.Time
+}
+
+// NewPresenceWebhooks creates a new instance of PresenceWebhooks that sends
+// each user's webhook at most limit events per PresenceWebhookWindow.
+func NewPresenceWebhooks(finder PresenceWebhookFinder, relationships RelationshipFetcher, limit int, logger *slog.Logger) *PresenceWebhooks {
+	return &PresenceWebhooks{
+		finder:        finder,
+		relationships: relationships,
+		client:        &http.Client{Timeout: WebhookTimeout},
+		queue:         make(chan presenceDelivery, WebhookQueueSize),
+		limit:         limit,
+		windows:       make(map[IdentScreenName]*presenceWindow),
+		logger:        logger,
+		nowFn:         time.Now,
+	}
+}
+
+// StatusChanged queues an event for every webhook that watches buddy.
+// Handlers call it when buddy signs on, goes away, comes back or signs off,
+// but not while buddy is invisible, so that webhooks reveal no more than
+// buddy lists do.
+func (p *PresenceWebhooks) StatusChanged(ctx context.Context, buddy IdentScreenName, status PresenceStatus) {
+	if p == nil {
+		return
+	}
+
+	hooks, err := p.finder.PresenceWebhooksWatching(ctx, buddy)
+	if err != nil {
+		p.logger.ErrorContext(ctx, "unable to look up presence webhooks", "buddy", buddy.String(), "err", err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if hook.Owner == buddy {
+			continue
+		}
+		rel, err := p.relationships.Relationship(ctx, hook.Owner, buddy)
+		if err != nil {
+			p.logger.ErrorContext(ctx, "unable to check relationship for presence webhook", "owner", hook.Owner.String(),
+				"buddy", buddy.String(), "err", err)
+			continue
+		}
+		if rel.BlocksYou {
+			continue
+		}
+		if !p.allow(hook.Owner) {
+			p.logger.DebugContext(ctx, "presence webhook rate limited, dropped event", "owner", hook.Owner.String(),
+				"buddy", buddy.String())
+			continue
+		}
+
+		delivery := presenceDelivery{
+			hook: hook,
+			event: WebhookEvent{
+				Schema: PresenceEventSchema,
+				Type:   PresenceStatusChanged,
+				Time:   p.nowFn(),
+				Data:   PresenceEvent{ScreenName: hook.Owner.String(), Buddy: buddy.String(), Status: status},
+			},
+		}
+		select {
+		case p.queue <- delivery:
+		default:
+			p.logger.WarnContext(ctx, "presence webhook queue full, dropped event", "owner", hook.Owner.String())
+		}
+	}
+}
+
+// allow counts an event for owner's webhook and reports whether it's
+// within the limit.
+func (p *PresenceWebhooks) allow(owner IdentScreenName) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := p.nowFn()
+	w, ok := p.windows[owner]
+	if !ok || now.Sub(w.start) >= PresenceWebhookWindow {
+		// forget windows that have ended so that the map doesn't grow
+		// with every user who ever had a webhook
+		for sn, other := range p.windows {
+			if now.Sub(other.start) >= PresenceWebhookWindow {
+				delete(p.windows, sn)
+			}
+		}
+		w = &presenceWindow{start: now}
+		p.windows[owner] = w
+	}
+	if w.count >= p.limit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+// Run posts queued events until ctx is done.
+func (p *PresenceWebhooks) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-p.queue:
+			if err := postWebhook(ctx, p.client, d.hook.URL, []byte(d.hook.Secret), d.event); err != nil {
+				p.logger.ErrorContext(ctx, "unable to post presence webhook event", "owner", d.hook.Owner.String(),
+					"err", err)
+			}
+		}
+	}
+}
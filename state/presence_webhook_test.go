@@ -0,0 +1,135 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestSQLiteUserStore_PresenceWebhook(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	alice := NewIdentScreenName("alice")
+	bob := NewIdentScreenName("bob")
+	for _, sn := range []IdentScreenName{alice, bob} {
+		require.NoError(t, f.InsertUser(ctx, User{IdentScreenName: sn, DisplayScreenName: DisplayScreenName(sn.String())}))
+	}
+
+	hook := PresenceWebhook{
+		Owner:   alice,
+		URL:     "https://example.com/presence",
+		Secret:  "s3cret",
+		Buddies: []IdentScreenName{bob, NewIdentScreenName("carol")},
+	}
+	assert.ErrorIs(t, f.SetPresenceWebhook(ctx, hook), ErrPresenceWebhookNotAllowed)
+	assert.ErrorIs(t, f.SetPresenceWebhookAllowed(ctx, NewIdentScreenName("nobody"), true), ErrNoUser)
+
+	require.NoError(t, f.SetPresenceWebhookAllowed(ctx, alice, true))
+	assert.ErrorIs(t, f.SetPresenceWebhook(ctx, PresenceWebhook{Owner: alice, URL: "/presence"}), ErrPresenceWebhookInvalid)
+	require.NoError(t, f.SetPresenceWebhook(ctx, hook))
+
+	have, err := f.PresenceWebhook(ctx, alice)
+	require.NoError(t, err)
+	assert.Equal(t, hook, have)
+
+	watching, err := f.PresenceWebhooksWatching(ctx, bob)
+	require.NoError(t, err)
+	assert.Equal(t, []PresenceWebhook{{Owner: alice, URL: "https://example.com/presence", Secret: "s3cret"}}, watching)
+
+	// replacing the webhook replaces its buddies
+	hook.Buddies = []IdentScreenName{NewIdentScreenName("carol")}
+	require.NoError(t, f.SetPresenceWebhook(ctx, hook))
+	watching, err = f.PresenceWebhooksWatching(ctx, bob)
+	require.NoError(t, err)
+	assert.Empty(t, watching)
+
+	// revoking the grant deletes the webhook
+	require.NoError(t, f.SetPresenceWebhookAllowed(ctx, alice, false))
+	_, err = f.PresenceWebhook(ctx, alice)
+	assert.ErrorIs(t, err, ErrPresenceWebhookNotFound)
+	assert.ErrorIs(t, f.DeletePresenceWebhook(ctx, alice), ErrPresenceWebhookNotFound)
+}
+
+func TestPresenceWebhooks_StatusChanged(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	requests := make(chan []byte, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- body
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	alice := NewIdentScreenName("alice")
+	bob := NewIdentScreenName("bob")
+	carol := NewIdentScreenName("carol")
+	for _, sn := range []IdentScreenName{alice, bob, carol} {
+		require.NoError(t, f.InsertUser(ctx, User{IdentScreenName: sn, DisplayScreenName: DisplayScreenName(sn.String())}))
+	}
+	require.NoError(t, f.SetPresenceWebhookAllowed(ctx, alice, true))
+	require.NoError(t, f.SetPresenceWebhook(ctx, PresenceWebhook{Owner: alice, URL: srv.URL, Buddies: []IdentScreenName{bob, carol}}))
+	require.NoError(t, f.RegisterBuddyList(ctx, alice))
+	require.NoError(t, f.RegisterBuddyList(ctx, carol))
+	require.NoError(t, f.SetPDMode(ctx, carol, wire.FeedbagPDModeDenySome))
+	require.NoError(t, f.DenyBuddy(ctx, carol, alice))
+
+	p := NewPresenceWebhooks(f, f, 1, slog.Default())
+	go p.Run(ctx)
+
+	p.StatusChanged(ctx, carol, PresenceOnline) // carol blocks alice
+	p.StatusChanged(ctx, bob, PresenceAway)
+	p.StatusChanged(ctx, bob, PresenceOnline) // over the limit
+
+	select {
+	case body := <-requests:
+		var have WebhookEvent
+		have.Data = &PresenceEvent{}
+		require.NoError(t, json.Unmarshal(body, &have))
+		assert.Equal(t, PresenceEventSchema, have.Schema)
+		assert.Equal(t, PresenceStatusChanged, have.Type)
+		assert.Equal(t, &PresenceEvent{ScreenName: "alice", Buddy: "bob", Status: PresenceAway}, have.Data)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not posted")
+	}
+	select {
+	case body := <-requests:
+		t.Fatalf("unexpected webhook %s", body)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// the limit resets with the next window
+	p.nowFn = func() time.Time { return time.Now().Add(PresenceWebhookWindow) }
+	p.StatusChanged(ctx, bob, PresenceOffline)
+	select {
+	case <-requests:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not posted")
+	}
+
+	var nilHooks *PresenceWebhooks
+	nilHooks.StatusChanged(ctx, bob, PresenceOnline)
+}
@@ -0,0 +1,50 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrProbation indicates that an account on probation attempted an
+// operation that is withheld from new accounts, such as creating a chat
+// room or messaging a user who doesn't have them on their buddy list.
+var ErrProbation = errors.New("operation not allowed while account is on probation")
+
+// ProbationPolicy puts newly registered accounts on probation for a while,
+// to slow down spam from throwaway accounts. Accounts on probation can't
+// create chat rooms, can only send IMs to users who have them on their
+// buddy list, and send IMs under the limits of ICBMClassProbation.
+// Probation lifts by itself once Period has passed since registration.
+// Accounts registered before registration times were recorded are never on
+// probation.
+type ProbationPolicy struct {
+	// Period is how long new accounts stay on probation. Zero disables
+	// probation.
+	Period time.Duration
+}
+
+// Until returns when user's probation ends, or the zero time if the
+// account isn't subject to probation. Handlers pass it to
+// Session.SetProbationUntil at sign-on.
+func (p ProbationPolicy) Until(user User) time.Time {
+	if p.Period <= 0 || user.CreatedAt.IsZero() || user.IsBot {
+		return time.Time{}
+	}
+	return user.CreatedAt.Add(p.Period)
+}
+
+// OnProbation indicates whether user is on probation at now.
+func (p ProbationPolicy) OnProbation(user User, now time.Time) bool {
+	return now.Before(p.Until(user))
+}
+
+// CheckProbationIM returns ErrProbation if the account of sess is on
+// probation and may not send an IM to a user it has rel with, because the
+// recipient doesn't have the sender on their buddy list.
+func CheckProbationIM(sess *Session, rel Relationship) error {
+	if !sess.OnProbation() || rel.IsOnTheirList {
+		return nil
+	}
+	return fmt.Errorf("%w: %s does not have you on their buddy list", ErrProbation, rel.User)
+}
@@ -0,0 +1,67 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestProbationPolicy(t *testing.T) {
+	registered := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newbie := User{IdentScreenName: NewIdentScreenName("newbie"), CreatedAt: registered}
+	veteran := User{IdentScreenName: NewIdentScreenName("veteran")}
+	bot := User{IdentScreenName: NewIdentScreenName("bot"), CreatedAt: registered, IsBot: true}
+
+	p := ProbationPolicy{Period: 72 * time.Hour}
+	assert.Equal(t, registered.Add(72*time.Hour), p.Until(newbie))
+	assert.True(t, p.OnProbation(newbie, registered.Add(time.Hour)))
+	assert.False(t, p.OnProbation(newbie, registered.Add(72*time.Hour)))
+	assert.False(t, p.OnProbation(veteran, registered.Add(time.Hour)), "accounts without a registration time are exempt")
+	assert.False(t, p.OnProbation(bot, registered.Add(time.Hour)))
+
+	assert.False(t, ProbationPolicy{}.OnProbation(newbie, registered.Add(time.Hour)), "zero period disables probation")
+}
+
+func TestCheckProbationIM(t *testing.T) {
+	now := time.Now()
+	sess := NewSession()
+	sess.nowFn = func() time.Time { return now }
+	sess.SetProbationUntil(now.Add(time.Hour))
+
+	stranger := Relationship{User: NewIdentScreenName("stranger")}
+	friend := Relationship{User: NewIdentScreenName("friend"), IsOnTheirList: true}
+
+	assert.ErrorIs(t, CheckProbationIM(sess, stranger), ErrProbation)
+	assert.Equal(t, wire.ErrorCodeInsufficientRights, ErrorCode(CheckProbationIM(sess, stranger)))
+	assert.NoError(t, CheckProbationIM(sess, friend))
+	assert.Equal(t, ICBMClassProbation, ICBMClassOf(sess))
+
+	// probation lifts by itself
+	now = now.Add(time.Hour)
+	assert.False(t, sess.OnProbation())
+	assert.NoError(t, CheckProbationIM(sess, stranger))
+	assert.Equal(t, ICBMClassStandard, ICBMClassOf(sess))
+}
+
+func TestChatCreatePolicy_Probation(t *testing.T) {
+	admin := NewIdentScreenName("admin")
+	p := ChatCreatePolicy{
+		Admins:    []IdentScreenName{admin},
+		Probation: ProbationPolicy{Period: time.Hour},
+	}
+
+	newbie := User{IdentScreenName: NewIdentScreenName("newbie"), CreatedAt: time.Now()}
+	err := p.CanCreate(PrivateExchange, newbie)
+	assert.ErrorIs(t, err, ErrChatCreateNotAllowed)
+	assert.ErrorIs(t, err, ErrProbation)
+	assert.Zero(t, p.NavCreatePerms(PrivateExchange, newbie))
+
+	newAdmin := User{IdentScreenName: admin, CreatedAt: time.Now()}
+	assert.NoError(t, p.CanCreate(PublicExchange, newAdmin))
+
+	newbie.CreatedAt = time.Now().Add(-2 * time.Hour)
+	assert.NoError(t, p.CanCreate(PrivateExchange, newbie))
+}
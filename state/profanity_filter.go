@@ -0,0 +1,192 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+// ProfanityScope is the kind of user content that the profanity filter
+// checks. Each scope has its own ProfanityAction.
+type ProfanityScope uint8
+
+const (
+	// ProfanityScopeIM covers instant messages.
+	ProfanityScopeIM ProfanityScope = iota
+	// ProfanityScopeChat covers chat room messages.
+	ProfanityScopeChat
+	// ProfanityScopeProfile covers profiles and away messages.
+	ProfanityScopeProfile
+)
+
+// String returns the config name of the scope.
+func (s ProfanityScope) String() string {
+	switch s {
+	case ProfanityScopeIM:
+		return "im"
+	case ProfanityScopeChat:
+		return "chat"
+	case ProfanityScopeProfile:
+		return "profile"
+	default:
+		return "unknown"
+	}
+}
+
+// ProfanityAction is what the profanity filter does with content that
+// contains a profane word.
+type ProfanityAction uint8
+
+const (
+	// ProfanityAllow lets the content through unchanged.
+	ProfanityAllow ProfanityAction = iota
+	// ProfanityMask replaces the letters of profane words with asterisks.
+	ProfanityMask
+	// ProfanityReject refuses the content with ErrProfanityRejected.
+	ProfanityReject
+	// ProfanityFlag lets the content through unchanged and logs it for
+	// moderators, without telling the sender.
+	ProfanityFlag
+)
+
+// ErrProfanityRejected indicates that content was refused because it
+// contains a profane word.
+var ErrProfanityRejected = errors.New("content contains profanity")
+
+var (
+	profanityScopeNames = map[string]ProfanityScope{
+		"im":      ProfanityScopeIM,
+		"chat":    ProfanityScopeChat,
+		"profile": ProfanityScopeProfile,
+	}
+	profanityActionNames = map[string]ProfanityAction{
+		"allow":  ProfanityAllow,
+		"mask":   ProfanityMask,
+		"reject": ProfanityReject,
+		"flag":   ProfanityFlag,
+	}
+)
+
+// ProfanityPolicy maps content scopes to the action taken on profane
+// content. Scopes that are not in the policy are not filtered.
+type ProfanityPolicy map[ProfanityScope]ProfanityAction
+
+// ParseProfanityPolicy builds a ProfanityPolicy from scope names (im, chat,
+// profile) mapped to action names (allow, mask, reject, flag).
+func ParseProfanityPolicy(cfg map[string]string) (ProfanityPolicy, error) {
+	policy := make(ProfanityPolicy, len(cfg))
+	for scopeName, actionName := range cfg {
+		scope, ok := profanityScopeNames[scopeName]
+		if !ok {
+			return nil, fmt.Errorf("unknown profanity filter scope %q", scopeName)
+		}
+		action, ok := profanityActionNames[actionName]
+		if !ok {
+			return nil, fmt.Errorf("unknown profanity filter action %q", actionName)
+		}
+		policy[scope] = action
+	}
+	return policy, nil
+}
+
+// ProfanityFilter checks user content for profane words and masks,
+// rejects or flags it according to a ProfanityPolicy. Words match whole
+// words of the text, ignoring case. HTML markup is left alone.
+type ProfanityFilter struct {
+	words  map[string]struct{}
+	policy ProfanityPolicy
+	events *ModerationEvents
+	logger *slog.Logger
+}
+
+// NewProfanityFilter creates a new instance of ProfanityFilter that looks
+// for words.
+func NewProfanityFilter(words []string, policy ProfanityPolicy, logger *slog.Logger) *ProfanityFilter {
+	set := make(map[string]struct{}, len(words))
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			set[word] = struct{}{}
+		}
+	}
+	return &ProfanityFilter{
+		words:  set,
+		policy: policy,
+		logger: logger,
+	}
+}
+
+// Filter checks text that sender wants to send or publish in scope. It
+// returns the text to relay or store, which is masked under ProfanityMask,
+// or ErrProfanityRejected under ProfanityReject. Handlers should report the
+// error to the sender with ErrorCode.
+func (f *ProfanityFilter) Filter(ctx context.Context, scope ProfanityScope, sender IdentScreenName, text string) (string, error) {
+	action := f.policy[scope]
+	if action == ProfanityAllow || len(f.words) == 0 {
+		return text, nil
+	}
+
+	masked, matches := f.mask(text)
+	if len(matches) == 0 {
+		return text, nil
+	}
+
+	switch action {
+	case ProfanityMask:
+		return masked, nil
+	case ProfanityReject:
+		f.logger.InfoContext(ctx, "rejected content with profanity", "screen_name", sender, "scope", scope, "words", matches)
+		f.events.profanityFiltered(ctx, sender, scope, action, matches)
+		return "", ErrProfanityRejected
+	default:
+		f.logger.WarnContext(ctx, "flagged content with profanity for moderators", "screen_name", sender,
+			"scope", scope, "words", matches, "text", text)
+		f.events.profanityFiltered(ctx, sender, scope, action, matches)
+		return text, nil
+	}
+}
+
+// mask returns text with the letters of profane words outside HTML tags
+// replaced by asterisks, along with the profane words found.
+func (f *ProfanityFilter) mask(text string) (string, []string) {
+	var b strings.Builder
+	var matches []string
+
+	maskSpan := func(span string) {
+		for len(span) > 0 {
+			// copy everything up to the next word
+			i := strings.IndexFunc(span, isWordRune)
+			if i < 0 {
+				b.WriteString(span)
+				return
+			}
+			b.WriteString(span[:i])
+			span = span[i:]
+
+			end := strings.IndexFunc(span, func(r rune) bool { return !isWordRune(r) })
+			if end < 0 {
+				end = len(span)
+			}
+			word := span[:end]
+			if _, ok := f.words[strings.ToLower(word)]; ok {
+				matches = append(matches, word)
+				b.WriteString(strings.Repeat("*", utf8.RuneCountInString(word)))
+			} else {
+				b.WriteString(word)
+			}
+			span = span[end:]
+		}
+	}
+
+	last := 0
+	for _, tag := range chatHTMLTagRegexp.FindAllStringIndex(text, -1) {
+		maskSpan(text[last:tag[0]])
+		b.WriteString(text[tag[0]:tag[1]])
+		last = tag[1]
+	}
+	maskSpan(text[last:])
+
+	return b.String(), matches
+}
@@ -0,0 +1,94 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestParseProfanityPolicy(t *testing.T) {
+	policy, err := ParseProfanityPolicy(map[string]string{"im": "mask", "chat": "reject", "profile": "flag"})
+	require.NoError(t, err)
+	assert.Equal(t, ProfanityPolicy{
+		ProfanityScopeIM:      ProfanityMask,
+		ProfanityScopeChat:    ProfanityReject,
+		ProfanityScopeProfile: ProfanityFlag,
+	}, policy)
+
+	_, err = ParseProfanityPolicy(map[string]string{"email": "mask"})
+	assert.Error(t, err)
+	_, err = ParseProfanityPolicy(map[string]string{"im": "ban"})
+	assert.Error(t, err)
+}
+
+func TestProfanityFilter_Filter(t *testing.T) {
+	ctx := context.Background()
+	sender := NewIdentScreenName("Sender")
+	filter := NewProfanityFilter([]string{"darn", " Heck "}, ProfanityPolicy{
+		ProfanityScopeIM:      ProfanityMask,
+		ProfanityScopeChat:    ProfanityReject,
+		ProfanityScopeProfile: ProfanityFlag,
+	}, slog.Default())
+
+	tests := []struct {
+		name    string
+		scope   ProfanityScope
+		text    string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "mask keeps markup and other words",
+			scope: ProfanityScopeIM,
+			text:  `<HTML><FONT COLOR="heck">Darn it, what the HECK!</FONT></HTML>`,
+			want:  `<HTML><FONT COLOR="heck">**** it, what the ****!</FONT></HTML>`,
+		},
+		{
+			name:  "mask matches whole words only",
+			scope: ProfanityScopeIM,
+			text:  "darned heckler",
+			want:  "darned heckler",
+		},
+		{
+			name:    "reject",
+			scope:   ProfanityScopeChat,
+			text:    "oh darn",
+			wantErr: ErrProfanityRejected,
+		},
+		{
+			name:  "reject lets clean text through",
+			scope: ProfanityScopeChat,
+			text:  "oh dear",
+			want:  "oh dear",
+		},
+		{
+			name:  "flag lets text through unchanged",
+			scope: ProfanityScopeProfile,
+			text:  "darn",
+			want:  "darn",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := filter.Filter(ctx, tt.scope, sender, tt.text)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("scope without action", func(t *testing.T) {
+		f := NewProfanityFilter([]string{"darn"}, ProfanityPolicy{ProfanityScopeChat: ProfanityMask}, slog.Default())
+		got, err := f.Filter(ctx, ProfanityScopeIM, sender, "darn")
+		require.NoError(t, err)
+		assert.Equal(t, "darn", got)
+	})
+
+	t.Run("rejection error code", func(t *testing.T) {
+		assert.Equal(t, wire.ErrorCodeRequestDenied, ErrorCode(ErrProfanityRejected))
+	})
+}
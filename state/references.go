@@ -0,0 +1,191 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// ErrBARTItemInUse indicates that a BART asset can't be deleted because
+// it's still referenced by a feedbag.
+var ErrBARTItemInUse = errors.New("can't delete BART asset that is referenced by a feedbag")
+
+// ReferenceCount is the number of records of one kind that refer to an
+// entity, such as the number of buddy lists a user appears on.
+type ReferenceCount struct {
+	// Source describes the kind of referring record, e.g. "buddy lists".
+	Source string
+	// Count is the number of referring records.
+	Count int
+}
+
+// References lists what refers to an entity, so that callers can refuse or
+// warn about a delete before it happens. Sources with no references are
+// left out, so an entity that isn't referenced has an empty report.
+type References []ReferenceCount
+
+// Total returns the number of references across all sources.
+func (r References) Total() int {
+	total := 0
+	for _, c := range r {
+		total += c.Count
+	}
+	return total
+}
+
+// String returns a human-readable summary of the references, e.g.
+// "37 buddy lists, 2 deny lists".
+func (r References) String() string {
+	if len(r) == 0 {
+		return "no references"
+	}
+	parts := make([]string, len(r))
+	for i, c := range r {
+		parts[i] = fmt.Sprintf("%d %s", c.Count, c.Source)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// referenceQuery counts the references from one source. q must return a
+// single COUNT.
+type referenceQuery struct {
+	source string
+	q      string
+	args   []any
+}
+
+// countReferences runs queries and reports the sources that have at least
+// one reference.
+func countReferences(ctx context.Context, db sqlConn, queries ...referenceQuery) (References, error) {
+	var refs References
+	for _, rq := range queries {
+		var count int
+		if err := db.QueryRowContext(ctx, rq.q, rq.args...).Scan(&count); err != nil {
+			return nil, fmt.Errorf("count %s: %w", rq.source, err)
+		}
+		if count > 0 {
+			refs = append(refs, ReferenceCount{Source: rq.source, Count: count})
+		}
+	}
+	return refs, nil
+}
+
+// KeywordReferences reports the users who list the directory keyword id
+// among their interests.
+func (us SQLiteUserStore) KeywordReferences(ctx context.Context, id uint8) (References, error) {
+	refs, err := countReferences(ctx, us.db, referenceQuery{
+		source: "users",
+		q: `
+			SELECT COUNT(*)
+			FROM users
+			WHERE ? IN (aim_keyword1, aim_keyword2, aim_keyword3, aim_keyword4, aim_keyword5)
+		`,
+		args: []any{id},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("KeywordReferences: %w", err)
+	}
+	return refs, nil
+}
+
+// CategoryReferences reports the users who list any keyword of the
+// directory category categoryID among their interests.
+func (us SQLiteUserStore) CategoryReferences(ctx context.Context, categoryID uint8) (References, error) {
+	refs, err := countReferences(ctx, us.db, referenceQuery{
+		source: "users",
+		q: `
+			SELECT COUNT(*)
+			FROM users
+			WHERE EXISTS (SELECT 1
+			              FROM aimKeyword
+			              WHERE aimKeyword.parent = ?
+			                AND aimKeyword.id IN (aim_keyword1, aim_keyword2, aim_keyword3, aim_keyword4, aim_keyword5))
+		`,
+		args: []any{categoryID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CategoryReferences: %w", err)
+	}
+	return refs, nil
+}
+
+// BARTItemReferences reports the feedbags that reference the BART asset
+// hash, whether as a buddy icon, a per-buddy sound or a BART list entry.
+// Feedbag attributes are matched against the raw hash bytes rather than
+// decoded, which is exact for all practical purposes since hashes are
+// 16-byte digests.
+func (us SQLiteUserStore) BARTItemReferences(ctx context.Context, hash []byte) (References, error) {
+	refs, err := countReferences(ctx, us.db, referenceQuery{
+		source: "feedbags",
+		q: `
+			SELECT COUNT(DISTINCT screenName)
+			FROM feedbag
+			WHERE INSTR(attributes, ?) > 0
+		`,
+		args: []any{hash},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("BARTItemReferences: %w", err)
+	}
+	return refs, nil
+}
+
+// UserReferences reports what refers to screenName: the buddy, permit and
+// deny lists it appears on, server-side or client-side, and the
+// subscribers to groups it shares. These references outlive the account,
+// so admins use the report to gauge the impact of deleting it.
+func (us SQLiteUserStore) UserReferences(ctx context.Context, screenName IdentScreenName) (References, error) {
+	listQuery := func(source string, classID uint16, flag string) referenceQuery {
+		return referenceQuery{
+			source: source,
+			q: `
+				SELECT COUNT(*)
+				FROM (SELECT screenName
+				      FROM feedbag
+				      WHERE classID = ? AND name = ? AND screenName != ?
+				      UNION
+				      SELECT me
+				      FROM clientSideBuddyList
+				      WHERE them = ? AND me != ? AND ` + flag + ` IS TRUE)
+			`,
+			args: []any{classID, screenName.String(), screenName.String(), screenName.String(), screenName.String()},
+		}
+	}
+	refs, err := countReferences(ctx, us.db,
+		listQuery("buddy lists", wire.FeedbagClassIdBuddy, "isBuddy"),
+		listQuery("permit lists", wire.FeedbagClassIDPermit, "isPermit"),
+		listQuery("deny lists", wire.FeedbagClassIDDeny, "isDeny"),
+		referenceQuery{
+			source: "shared group subscribers",
+			q: `
+				SELECT COUNT(DISTINCT subscriber)
+				FROM sharedGroupSubscription
+				         JOIN sharedGroup ON sharedGroup.id = sharedGroupSubscription.sharedGroupID
+				WHERE sharedGroup.owner = ?
+			`,
+			args: []any{screenName.String()},
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("UserReferences: %w", err)
+	}
+	return refs, nil
+}
+
+// DeleteUnreferencedBARTItem deletes the BART asset hash unless a feedbag
+// still references it, in which case it returns ErrBARTItemInUse along
+// with the references. Unlike DeleteBARTItem, it's safe to use for
+// deletes requested by admins.
+func (us SQLiteUserStore) DeleteUnreferencedBARTItem(ctx context.Context, hash []byte) error {
+	refs, err := us.BARTItemReferences(ctx, hash)
+	if err != nil {
+		return fmt.Errorf("DeleteUnreferencedBARTItem: %w", err)
+	}
+	if len(refs) > 0 {
+		return fmt.Errorf("%w: %s", ErrBARTItemInUse, refs)
+	}
+	return us.DeleteBARTItem(ctx, hash)
+}
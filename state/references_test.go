@@ -0,0 +1,116 @@
+package state
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestReferences_String(t *testing.T) {
+	assert.Equal(t, "no references", References{}.String())
+
+	refs := References{{Source: "buddy lists", Count: 37}, {Source: "deny lists", Count: 2}}
+	assert.Equal(t, "37 buddy lists, 2 deny lists", refs.String())
+	assert.Equal(t, 39, refs.Total())
+}
+
+func TestSQLiteUserStore_References(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, sn := range []DisplayScreenName{"target", "alice", "bob", "carol"} {
+		user, err := NewStubUser(sn)
+		require.NoError(t, err)
+		require.NoError(t, f.InsertUser(ctx, user))
+	}
+	target := NewIdentScreenName("target")
+	alice := NewIdentScreenName("alice")
+	bob := NewIdentScreenName("bob")
+	carol := NewIdentScreenName("carol")
+
+	t.Run("keywords and categories", func(t *testing.T) {
+		category, err := f.CreateCategory(ctx, "Music")
+		require.NoError(t, err)
+		used, err := f.CreateKeyword(ctx, "Jazz", category.ID)
+		require.NoError(t, err)
+		unused, err := f.CreateKeyword(ctx, "Blues", category.ID)
+		require.NoError(t, err)
+
+		require.NoError(t, f.SetKeywords(ctx, alice, [5]string{"Jazz"}))
+		require.NoError(t, f.SetKeywords(ctx, bob, [5]string{"", "Jazz"}))
+
+		refs, err := f.KeywordReferences(ctx, used.ID)
+		require.NoError(t, err)
+		assert.Equal(t, References{{Source: "users", Count: 2}}, refs)
+
+		refs, err = f.CategoryReferences(ctx, category.ID)
+		require.NoError(t, err)
+		assert.Equal(t, References{{Source: "users", Count: 2}}, refs)
+
+		err = f.DeleteKeyword(ctx, used.ID)
+		assert.ErrorIs(t, err, ErrKeywordInUse)
+		assert.ErrorContains(t, err, "2 users")
+		assert.ErrorIs(t, f.DeleteCategory(ctx, category.ID), ErrKeywordInUse)
+
+		refs, err = f.KeywordReferences(ctx, unused.ID)
+		require.NoError(t, err)
+		assert.Empty(t, refs)
+		assert.NoError(t, f.DeleteKeyword(ctx, unused.ID))
+		assert.ErrorIs(t, f.DeleteKeyword(ctx, unused.ID), ErrKeywordNotFound)
+	})
+
+	t.Run("BART items", func(t *testing.T) {
+		icon := wire.BARTInfo{Flags: wire.BARTFlagsKnown, Hash: []byte("0123456789abcdef")}
+		require.NoError(t, f.InsertBARTItem(ctx, icon.Hash, []byte("icon-data"), wire.BARTTypesBuddyIcon))
+		spare := []byte("fedcba9876543210")
+		require.NoError(t, f.InsertBARTItem(ctx, spare, []byte("spare-data"), wire.BARTTypesBuddyIcon))
+
+		item := newFeedbagItem(wire.FeedbagClassIdBart, 1, strconv.Itoa(int(wire.BARTTypesBuddyIcon)))
+		item.Append(wire.NewTLVBE(wire.FeedbagAttributesBartInfo, icon))
+		require.NoError(t, f.FeedbagUpsert(ctx, alice, []wire.FeedbagItem{item}))
+
+		refs, err := f.BARTItemReferences(ctx, icon.Hash)
+		require.NoError(t, err)
+		assert.Equal(t, References{{Source: "feedbags", Count: 1}}, refs)
+
+		assert.ErrorIs(t, f.DeleteUnreferencedBARTItem(ctx, icon.Hash), ErrBARTItemInUse)
+		assert.NoError(t, f.DeleteUnreferencedBARTItem(ctx, spare))
+		assert.ErrorIs(t, f.DeleteUnreferencedBARTItem(ctx, spare), ErrBARTItemNotFound)
+	})
+
+	t.Run("users", func(t *testing.T) {
+		require.NoError(t, f.FeedbagUpsert(ctx, alice, []wire.FeedbagItem{
+			newFeedbagItem(wire.FeedbagClassIdBuddy, 2, "Target"),
+			newFeedbagItem(wire.FeedbagClassIDDeny, 3, "target"),
+		}))
+		// the same user counts once whether the buddy is server- or
+		// client-side
+		require.NoError(t, f.AddBuddy(ctx, alice, target))
+		require.NoError(t, f.AddBuddy(ctx, bob, target))
+		require.NoError(t, f.PermitBuddy(ctx, carol, target))
+		// users on their own lists don't count
+		require.NoError(t, f.AddBuddy(ctx, target, target))
+
+		refs, err := f.UserReferences(ctx, target)
+		require.NoError(t, err)
+		assert.Equal(t, References{
+			{Source: "buddy lists", Count: 2},
+			{Source: "permit lists", Count: 1},
+			{Source: "deny lists", Count: 1},
+		}, refs)
+
+		refs, err = f.UserReferences(ctx, carol)
+		require.NoError(t, err)
+		assert.Empty(t, refs)
+	})
+}
@@ -118,7 +118,8 @@ SELECT COALESCE(yourBuddyList._screenName, theirBuddyLists._screenName) AS scree
            ELSE false
            END                                                        AS blocksYou,
        IFNULL(theirBuddyLists.isBuddy, false)                         AS onTheirBuddyList,
-       IFNULL(yourBuddyList.isBuddy, false)                           AS onYourBuddyList
+       IFNULL(yourBuddyList.isBuddy, false)                           AS onYourBuddyList,
+       IFNULL(yourBuddyList.isPermit, false)                          AS onYourPermitList
 FROM theirBuddyLists
          FULL OUTER JOIN yourBuddyList
               ON (yourBuddyList._screenName = theirBuddyLists._screenName)
@@ -158,6 +159,9 @@ type Relationship struct {
 	IsOnTheirList bool
 	// IsOnYourList indicates whether this user is on your buddy list.
 	IsOnYourList bool
+	// IsOnYourPermitList indicates whether this user is on your permit
+	// (visible) list.
+	IsOnYourPermitList bool
 }
 
 func tmplMustCompile(data any) string {
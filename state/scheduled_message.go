@@ -0,0 +1,288 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"modernc.org/sqlite"
+	lib "modernc.org/sqlite/lib"
+)
+
+// ScheduledMessageKind is how a scheduled system message is shown to its
+// recipient.
+type ScheduledMessageKind uint8
+
+const (
+	// ScheduledIM is an IM from SystemMessageScreenName.
+	ScheduledIM ScheduledMessageKind = iota
+	// ScheduledPopup is a popup window, see wire.PopupDisplay.
+	ScheduledPopup
+)
+
+// ErrUnknownScheduledMessageKind indicates that a scheduled message has a
+// kind other than ScheduledIM or ScheduledPopup.
+var ErrUnknownScheduledMessageKind = errors.New("unknown scheduled message kind")
+
+// ScheduledMessage is a system message waiting for delivery.
+type ScheduledMessage struct {
+	// ID identifies the message in the queue.
+	ID int64
+	// Recipient is the user the message is for.
+	Recipient IdentScreenName
+	// Kind is how the message is shown.
+	Kind ScheduledMessageKind
+	// Text is the HTML text of the message.
+	Text string
+	// URL is the page shown in a popup. It's ignored for IMs.
+	URL string
+	// DeliverAt is the time after which the message is delivered. The zero
+	// value means the recipient's next sign-on.
+	DeliverAt time.Time
+	// CreatedAt is when the message was scheduled.
+	CreatedAt time.Time
+}
+
+// ScheduledMessageStore persists the queue of scheduled system messages.
+type ScheduledMessageStore interface {
+	ScheduleMessage(ctx context.Context, msg ScheduledMessage) (int64, error)
+	ScheduledMessages(ctx context.Context, recipient IdentScreenName, now time.Time) ([]ScheduledMessage, error)
+	DueScheduledMessages(ctx context.Context, now time.Time) ([]ScheduledMessage, error)
+	DeleteScheduledMessage(ctx context.Context, id int64) (bool, error)
+}
+
+// SystemMessageQueue delivers system IMs and popups, such as maintenance
+// notices and moderation warnings, at a later time or at the recipient's
+// next sign-on. Messages are kept in the database, so they survive
+// restarts. A message that comes due while its recipient is signed off is
+// delivered when they sign on.
+type SystemMessageQueue struct {
+	store    ScheduledMessageStore
+	sessions SessionRetriever
+	logger   *slog.Logger
+	nowFn    func() time.Time
+}
+
+// NewSystemMessageQueue creates a new instance of SystemMessageQueue.
+func NewSystemMessageQueue(store ScheduledMessageStore, sessions SessionRetriever, logger *slog.Logger) *SystemMessageQueue {
+	return &SystemMessageQueue{
+		store:    store,
+		sessions: sessions,
+		logger:   logger,
+		nowFn:    time.Now,
+	}
+}
+
+// ScheduleIM queues an IM from SystemMessageScreenName for delivery to
+// recipient after at, or at their next sign-on if at is zero. It returns
+// the ID of the queued message, or ErrNoUser if recipient doesn't exist.
+func (q *SystemMessageQueue) ScheduleIM(ctx context.Context, recipient IdentScreenName, text string, at time.Time) (int64, error) {
+	return q.schedule(ctx, ScheduledMessage{
+		Recipient: recipient,
+		Kind:      ScheduledIM,
+		Text:      text,
+		DeliverAt: at,
+	})
+}
+
+// SchedulePopup queues a popup showing text, and the page at url if it's
+// not empty, for delivery to recipient after at, or at their next sign-on
+// if at is zero. It returns the ID of the queued message, or ErrNoUser if
+// recipient doesn't exist.
+func (q *SystemMessageQueue) SchedulePopup(ctx context.Context, recipient IdentScreenName, text, url string, at time.Time) (int64, error) {
+	return q.schedule(ctx, ScheduledMessage{
+		Recipient: recipient,
+		Kind:      ScheduledPopup,
+		Text:      text,
+		URL:       url,
+		DeliverAt: at,
+	})
+}
+
+func (q *SystemMessageQueue) schedule(ctx context.Context, msg ScheduledMessage) (int64, error) {
+	msg.CreatedAt = q.nowFn()
+	id, err := q.store.ScheduleMessage(ctx, msg)
+	if err != nil {
+		return 0, err
+	}
+	q.logger.DebugContext(ctx, "scheduled system message", "id", id, "screen_name", msg.Recipient, "deliver_at", msg.DeliverAt)
+	return id, nil
+}
+
+// Cancel removes a queued message. It reports whether the message was
+// still queued.
+func (q *SystemMessageQueue) Cancel(ctx context.Context, id int64) (bool, error) {
+	return q.store.DeleteScheduledMessage(ctx, id)
+}
+
+// SignOn delivers to sess the messages queued for its user's next sign-on
+// and those that came due while they were signed off. It should be called
+// once the session's sign-on is complete.
+func (q *SystemMessageQueue) SignOn(ctx context.Context, sess *Session) error {
+	msgs, err := q.store.ScheduledMessages(ctx, sess.IdentScreenName(), q.nowFn())
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		if err := q.deliver(ctx, sess, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeliverDue delivers the messages that are due to recipients who are
+// signed on. The others stay queued until the recipient signs on.
+func (q *SystemMessageQueue) DeliverDue(ctx context.Context) error {
+	msgs, err := q.store.DueScheduledMessages(ctx, q.nowFn())
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		sess := q.sessions.RetrieveSession(msg.Recipient)
+		if sess == nil {
+			continue
+		}
+		if err := q.deliver(ctx, sess, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Schedule adds DeliverDue to s as the "system_messages" job, to run every
+// interval. Messages are delivered up to interval after they come due.
+func (q *SystemMessageQueue) Schedule(s *Scheduler, interval time.Duration) error {
+	return s.Add("system_messages", interval, interval/10, q.DeliverDue)
+}
+
+// deliver removes msg from the queue and sends it to sess. The message is
+// only sent if this call removed it, so a message that comes due as its
+// recipient signs on isn't delivered twice.
+func (q *SystemMessageQueue) deliver(ctx context.Context, sess *Session, msg ScheduledMessage) error {
+	ok, err := q.store.DeleteScheduledMessage(ctx, msg.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	switch msg.Kind {
+	case ScheduledIM:
+		im, err := newServerIM(SystemMessageScreenName, msg.Text)
+		if err != nil {
+			q.logger.ErrorContext(ctx, "unable to build scheduled IM", "id", msg.ID, "err", err)
+			return nil
+		}
+		if sess.RelayMessage(im) != SessSendOK {
+			q.logger.DebugContext(ctx, "unable to send scheduled IM", "id", msg.ID, "screen_name", sess.IdentScreenName())
+		}
+	case ScheduledPopup:
+		if sess.RelayMessage(newServerPopup(msg.Text, msg.URL)) != SessSendOK {
+			q.logger.DebugContext(ctx, "unable to send scheduled popup", "id", msg.ID, "screen_name", sess.IdentScreenName())
+		}
+	default:
+		q.logger.ErrorContext(ctx, "dropped scheduled message", "id", msg.ID, "err", ErrUnknownScheduledMessageKind)
+	}
+	return nil
+}
+
+// ScheduleMessage adds msg to the queue of scheduled messages and returns
+// its ID. It returns ErrNoUser if the recipient doesn't exist.
+func (us SQLiteUserStore) ScheduleMessage(ctx context.Context, msg ScheduledMessage) (int64, error) {
+	var deliverAt int64
+	if !msg.DeliverAt.IsZero() {
+		deliverAt = msg.DeliverAt.Unix()
+	}
+	q := `
+		INSERT INTO scheduledMessage (recipient, kind, text, url, deliverAt, createdAt)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	res, err := us.db.ExecContext(ctx, q, msg.Recipient.String(), msg.Kind, msg.Text, msg.URL, deliverAt, msg.CreatedAt.Unix())
+	if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == lib.SQLITE_CONSTRAINT_FOREIGNKEY {
+		return 0, ErrNoUser
+	} else if err != nil {
+		return 0, fmt.Errorf("ScheduleMessage: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("ScheduleMessage: %w", err)
+	}
+	return id, nil
+}
+
+// ScheduledMessages returns the messages queued for recipient that are
+// due at now, including those queued for their next sign-on, oldest
+// first.
+func (us SQLiteUserStore) ScheduledMessages(ctx context.Context, recipient IdentScreenName, now time.Time) ([]ScheduledMessage, error) {
+	q := `
+		SELECT id, recipient, kind, text, url, deliverAt, createdAt
+		FROM scheduledMessage
+		WHERE recipient = ? AND deliverAt <= ?
+		ORDER BY id
+	`
+	msgs, err := us.queryScheduledMessages(ctx, q, recipient.String(), now.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("ScheduledMessages: %w", err)
+	}
+	return msgs, nil
+}
+
+// DueScheduledMessages returns the messages of all users that were
+// scheduled for a time up to now, oldest first. Messages queued for the
+// next sign-on are not included.
+func (us SQLiteUserStore) DueScheduledMessages(ctx context.Context, now time.Time) ([]ScheduledMessage, error) {
+	q := `
+		SELECT id, recipient, kind, text, url, deliverAt, createdAt
+		FROM scheduledMessage
+		WHERE deliverAt > 0 AND deliverAt <= ?
+		ORDER BY id
+	`
+	msgs, err := us.queryScheduledMessages(ctx, q, now.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("DueScheduledMessages: %w", err)
+	}
+	return msgs, nil
+}
+
+func (us SQLiteUserStore) queryScheduledMessages(ctx context.Context, q string, args ...any) ([]ScheduledMessage, error) {
+	rows, err := us.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []ScheduledMessage
+	for rows.Next() {
+		var msg ScheduledMessage
+		var recipient string
+		var deliverAt, createdAt int64
+		if err := rows.Scan(&msg.ID, &recipient, &msg.Kind, &msg.Text, &msg.URL, &deliverAt, &createdAt); err != nil {
+			return nil, err
+		}
+		msg.Recipient = NewIdentScreenName(recipient)
+		if deliverAt > 0 {
+			msg.DeliverAt = time.Unix(deliverAt, 0)
+		}
+		msg.CreatedAt = time.Unix(createdAt, 0)
+		msgs = append(msgs, msg)
+	}
+	return msgs, rows.Err()
+}
+
+// DeleteScheduledMessage removes a message from the queue. It reports
+// whether the message was still queued.
+func (us SQLiteUserStore) DeleteScheduledMessage(ctx context.Context, id int64) (bool, error) {
+	res, err := us.db.ExecContext(ctx, `DELETE FROM scheduledMessage WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("DeleteScheduledMessage: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("DeleteScheduledMessage: %w", err)
+	}
+	return n > 0, nil
+}
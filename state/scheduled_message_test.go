@@ -0,0 +1,103 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestSystemMessageQueue(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, sn := range []DisplayScreenName{"Online Olly", "Offline Oscar"} {
+		user, err := NewStubUser(sn)
+		require.NoError(t, err)
+		require.NoError(t, f.InsertUser(ctx, user))
+	}
+
+	sessions := NewInMemorySessionManager(slog.Default())
+	olly, err := sessions.AddSession(ctx, "Online Olly")
+	require.NoError(t, err)
+	olly.SetSignonComplete()
+
+	now := time.Unix(1_700_000_000, 0)
+	q := NewSystemMessageQueue(f, sessions, slog.Default())
+	q.nowFn = func() time.Time { return now }
+
+	ollySN := NewIdentScreenName("Online Olly")
+	oscarSN := NewIdentScreenName("Offline Oscar")
+
+	_, err = q.ScheduleIM(ctx, ollySN, "maintenance at noon", now.Add(time.Hour))
+	require.NoError(t, err)
+	_, err = q.SchedulePopup(ctx, ollySN, "you were warned", "http://example.com/tos", now.Add(-time.Minute))
+	require.NoError(t, err)
+	_, err = q.ScheduleIM(ctx, oscarSN, "due while away", now.Add(-time.Minute))
+	require.NoError(t, err)
+	_, err = q.ScheduleIM(ctx, oscarSN, "next sign-on", time.Time{})
+	require.NoError(t, err)
+	canceled, err := q.ScheduleIM(ctx, oscarSN, "never mind", time.Time{})
+	require.NoError(t, err)
+
+	_, err = q.ScheduleIM(ctx, NewIdentScreenName("nobody"), "hi", time.Time{})
+	assert.ErrorIs(t, err, ErrNoUser)
+
+	ok, err := q.Cancel(ctx, canceled)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = q.Cancel(ctx, canceled)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// only the due popup goes out; Oscar's due IM waits for his sign-on
+	require.NoError(t, q.DeliverDue(ctx))
+	msg, ok := receiveWelcome(t, olly)
+	require.True(t, ok)
+	assert.Equal(t, newServerPopup("you were warned", "http://example.com/tos"), msg)
+	_, ok = receiveWelcome(t, olly)
+	assert.False(t, ok)
+
+	// nothing is delivered twice
+	require.NoError(t, q.DeliverDue(ctx))
+	_, ok = receiveWelcome(t, olly)
+	assert.False(t, ok)
+
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, q.DeliverDue(ctx))
+	msg, ok = receiveWelcome(t, olly)
+	require.True(t, ok)
+	assert.Equal(t, wire.ScreenName(SystemMessageScreenName), msg.Body.(wire.SNAC_0x04_0x07_ICBMChannelMsgToClient).ScreenName)
+
+	oscar := NewSession()
+	oscar.SetIdentScreenName(oscarSN)
+	require.NoError(t, q.SignOn(ctx, oscar))
+	var texts []string
+	for {
+		msg, ok := receiveWelcome(t, oscar)
+		if !ok {
+			break
+		}
+		body := msg.Body.(wire.SNAC_0x04_0x07_ICBMChannelMsgToClient)
+		b, ok := body.Bytes(wire.ICBMTLVAOLIMData)
+		require.True(t, ok)
+		text, err := wire.UnmarshalICBMMessageText(b)
+		require.NoError(t, err)
+		texts = append(texts, text)
+	}
+	assert.Equal(t, []string{"due while away", "next sign-on"}, texts)
+
+	msgs, err := f.ScheduledMessages(ctx, oscarSN, now)
+	require.NoError(t, err)
+	assert.Empty(t, msgs)
+}
@@ -0,0 +1,210 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrJobExists indicates that a job with the same name is already
+	// scheduled.
+	ErrJobExists = errors.New("job already scheduled")
+	// ErrSchedulerStopped indicates that a job was added after the
+	// scheduler was stopped.
+	ErrSchedulerStopped = errors.New("scheduler stopped")
+)
+
+// JobFunc is one run of a background job. It should return promptly once
+// ctx is done.
+type JobFunc func(ctx context.Context) error
+
+// JobStats describes the runs of a scheduled job.
+type JobStats struct {
+	// Name is the name the job was added under.
+	Name string
+	// Interval is the nominal time between runs.
+	Interval time.Duration
+	// Runs is the number of completed runs.
+	Runs int64
+	// Failures is the number of runs that returned an error or panicked.
+	Failures int64
+	// Running indicates whether the job is running right now.
+	Running bool
+	// LastRun is when the most recent run started.
+	LastRun time.Time
+	// LastDuration is how long the most recent completed run took.
+	LastDuration time.Duration
+	// LastErr is the error of the most recent run, if it failed.
+	LastErr error
+}
+
+// scheduledJob is a job and its stats. The stats are guarded by the
+// scheduler's mutex.
+type scheduledJob struct {
+	fn     JobFunc
+	jitter time.Duration
+	stats  JobStats
+}
+
+// Scheduler runs named background jobs, such as purges and garbage
+// collection, at fixed intervals so that subsystems don't each manage their
+// own tickers and goroutines. Each job runs once when the scheduler starts
+// and then every interval plus a random delay of up to its jitter, which
+// keeps jobs with the same interval from running in lockstep. A job never
+// overlaps with itself. A Scheduler is safe for concurrent use by multiple
+// goroutines.
+type Scheduler struct {
+	logger  *slog.Logger
+	mutex   sync.Mutex
+	jobs    map[string]*scheduledJob
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+	wg      sync.WaitGroup
+	nowFn   func() time.Time
+}
+
+// NewScheduler creates a new instance of Scheduler.
+func NewScheduler(logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		logger: logger,
+		jobs:   make(map[string]*scheduledJob),
+		nowFn:  time.Now,
+	}
+}
+
+// Add schedules fn to run every interval plus up to jitter under name. Jobs
+// added after Start begin running right away. It returns ErrJobExists if
+// name is taken and ErrSchedulerStopped if Stop was called.
+func (s *Scheduler) Add(name string, interval, jitter time.Duration, fn JobFunc) error {
+	if interval <= 0 {
+		return fmt.Errorf("job %s: interval must be positive", name)
+	}
+	if jitter < 0 {
+		return fmt.Errorf("job %s: jitter must not be negative", name)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopped {
+		return ErrSchedulerStopped
+	}
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("%w: %s", ErrJobExists, name)
+	}
+
+	job := &scheduledJob{
+		fn:     fn,
+		jitter: jitter,
+		stats:  JobStats{Name: name, Interval: interval},
+	}
+	s.jobs[name] = job
+	if s.ctx != nil {
+		s.wg.Go(func() { s.loop(s.ctx, job) })
+	}
+
+	return nil
+}
+
+// Start runs every job in the background until ctx is done or Stop is
+// called. Calling Start more than once has no effect.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.ctx != nil || s.stopped {
+		return
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	for _, job := range s.jobs {
+		s.wg.Go(func() { s.loop(s.ctx, job) })
+	}
+}
+
+// Stop cancels the context of running jobs and waits for them to return.
+// No runs start after Stop is called.
+func (s *Scheduler) Stop() {
+	s.mutex.Lock()
+	s.stopped = true
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+}
+
+// Stats returns the stats of every job, sorted by name.
+func (s *Scheduler) Stats() []JobStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stats := make([]JobStats, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		stats = append(stats, job.stats)
+	}
+	slices.SortFunc(stats, func(a, b JobStats) int { return strings.Compare(a.Name, b.Name) })
+
+	return stats
+}
+
+func (s *Scheduler) loop(ctx context.Context, job *scheduledJob) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		s.run(ctx, job)
+
+		delay := job.stats.Interval
+		if job.jitter > 0 {
+			delay += rand.N(job.jitter)
+		}
+		timer.Reset(delay)
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, job *scheduledJob) {
+	s.mutex.Lock()
+	name := job.stats.Name
+	start := s.nowFn()
+	job.stats.Running = true
+	job.stats.LastRun = start
+	s.mutex.Unlock()
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return job.fn(ctx)
+	}()
+
+	s.mutex.Lock()
+	job.stats.Running = false
+	job.stats.Runs++
+	job.stats.LastDuration = s.nowFn().Sub(start)
+	job.stats.LastErr = err
+	if err != nil {
+		job.stats.Failures++
+	}
+	s.mutex.Unlock()
+
+	if err != nil && ctx.Err() == nil {
+		s.logger.ErrorContext(ctx, "background job failed", "job", name, "err", err)
+	}
+}
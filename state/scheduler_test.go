@@ -0,0 +1,100 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler_RunsJobs(t *testing.T) {
+	s := NewScheduler(slog.Default())
+
+	var fast, failing atomic.Int64
+	require.NoError(t, s.Add("fast", time.Millisecond, time.Millisecond, func(ctx context.Context) error {
+		fast.Add(1)
+		return nil
+	}))
+	require.NoError(t, s.Add("failing", time.Millisecond, 0, func(ctx context.Context) error {
+		if failing.Add(1) == 1 {
+			panic("boom")
+		}
+		return errors.New("job failed")
+	}))
+
+	s.Start(context.Background())
+	assert.Eventually(t, func() bool {
+		return fast.Load() >= 3 && failing.Load() >= 3
+	}, time.Second, time.Millisecond)
+	s.Stop()
+
+	stats := s.Stats()
+	require.Len(t, stats, 2)
+
+	assert.Equal(t, "failing", stats[0].Name)
+	assert.Equal(t, failing.Load(), stats[0].Runs)
+	assert.Equal(t, stats[0].Runs, stats[0].Failures)
+	assert.EqualError(t, stats[0].LastErr, "job failed")
+
+	assert.Equal(t, "fast", stats[1].Name)
+	assert.Equal(t, fast.Load(), stats[1].Runs)
+	assert.Zero(t, stats[1].Failures)
+	assert.NoError(t, stats[1].LastErr)
+	assert.False(t, stats[1].Running)
+	assert.False(t, stats[1].LastRun.IsZero())
+}
+
+func TestScheduler_StopWaitsForRunningJobs(t *testing.T) {
+	s := NewScheduler(slog.Default())
+
+	started := make(chan struct{})
+	var finished atomic.Bool
+	require.NoError(t, s.Add("slow", time.Hour, 0, func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		finished.Store(true)
+		return ctx.Err()
+	}))
+
+	s.Start(context.Background())
+	<-started
+	s.Stop()
+	assert.True(t, finished.Load())
+
+	// jobs can't be added once stopped
+	assert.ErrorIs(t, s.Add("late", time.Hour, 0, func(ctx context.Context) error { return nil }), ErrSchedulerStopped)
+}
+
+func TestScheduler_AddAfterStart(t *testing.T) {
+	s := NewScheduler(slog.Default())
+	s.Start(context.Background())
+	defer s.Stop()
+
+	ran := make(chan struct{}, 1)
+	require.NoError(t, s.Add("late", time.Hour, 0, func(ctx context.Context) error {
+		ran <- struct{}{}
+		return nil
+	}))
+
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("job added after Start didn't run")
+	}
+}
+
+func TestScheduler_Add_Invalid(t *testing.T) {
+	s := NewScheduler(slog.Default())
+	noop := func(ctx context.Context) error { return nil }
+
+	require.NoError(t, s.Add("job", time.Minute, 0, noop))
+	assert.ErrorIs(t, s.Add("job", time.Minute, 0, noop), ErrJobExists)
+	assert.Error(t, s.Add("zero", 0, 0, noop))
+	assert.Error(t, s.Add("negative jitter", time.Minute, -time.Second, noop))
+}
@@ -0,0 +1,180 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// ErrSchemaTooNew indicates that the database was migrated by a newer
+// release than this one, so this release doesn't know its schema.
+var ErrSchemaTooNew = errors.New("database schema is newer than this release supports")
+
+// ErrSchemaOutdated indicates that a database opened read-only has pending
+// migrations, which can't be applied to it.
+var ErrSchemaOutdated = errors.New("database schema is older than this release requires")
+
+// latestMigration returns the version of the last migration in src.
+func latestMigration(src source.Driver) (uint, error) {
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("unable to list migrations: %w", err)
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("unable to list migrations: %w", err)
+		}
+		version = next
+	}
+}
+
+// LatestSchemaVersion returns the schema version that this release migrates
+// databases to.
+func LatestSchemaVersion() (uint, error) {
+	db, err := sql.Open("sqlite", "file::memory:")
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	m, src, err := newMigrate(db)
+	if err != nil {
+		return 0, err
+	}
+	defer m.Close()
+	return latestMigration(src)
+}
+
+// checkSchemaVersion returns ErrSchemaTooNew if the database's schema
+// version is past the last migration in src, which happens when a release
+// is rolled back without downgrading the database first.
+func checkSchemaVersion(m *migrate.Migrate, src source.Driver) error {
+	version, _, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read schema version: %w", err)
+	}
+	latest, err := latestMigration(src)
+	if err != nil {
+		return err
+	}
+	if version > latest {
+		return fmt.Errorf("%w: database is at schema version %d, this release supports up to %d. "+
+			"Run 'dbtool downgrade -to %d' from the release that upgraded the database, or upgrade this server",
+			ErrSchemaTooNew, version, latest, latest)
+	}
+	return nil
+}
+
+// checkMigrated verifies, without writing to the database, that its schema
+// is at the version of the last embedded migration. It returns
+// ErrSchemaOutdated if migrations are pending and ErrSchemaTooNew if the
+// schema is past the last migration.
+func (us SQLiteUserStore) checkMigrated() error {
+	m, src, err := newMigrate(us.pool)
+	if err != nil {
+		return err
+	}
+	if err := checkSchemaVersion(m, src); err != nil {
+		return err
+	}
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("unable to read schema version: %w", err)
+	}
+	latest, err := latestMigration(src)
+	if err != nil {
+		return err
+	}
+	if dirty || version < latest {
+		return fmt.Errorf("%w: database is at schema version %d, this release requires %d. "+
+			"Open the database read-write once to migrate it", ErrSchemaOutdated, version, latest)
+	}
+	return nil
+}
+
+// Downgrade reverts the migrations applied to the database at dbFilePath
+// after version target, newest first, so that an older release can open
+// it. Data in tables and columns added by the reverted migrations is lost,
+// so the database should be backed up first. It returns the reverted
+// migrations, which are pending again. If a migration fails, the returned
+// list covers the migrations reverted up to that point.
+func Downgrade(ctx context.Context, dbFilePath string, target uint) ([]PendingMigration, error) {
+	if _, err := os.Stat(dbFilePath); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", sqliteDSN(dbFilePath, "_pragma=foreign_keys=on"))
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	m, src, err := newMigrate(db)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	defer m.Close()
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return nil, errors.New("database has no schema")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read schema version: %w", err)
+	}
+	if dirty {
+		return nil, fmt.Errorf("schema version %d is dirty", version)
+	}
+	if target > version {
+		return nil, fmt.Errorf("target version %d is newer than the database's schema version %d", target, version)
+	}
+	if target > 0 {
+		if _, _, err := src.ReadUp(target); err != nil {
+			return nil, fmt.Errorf("unknown target version %d: %w", target, err)
+		}
+	}
+
+	var reverted []PendingMigration
+	for version > target {
+		if err := ctx.Err(); err != nil {
+			return reverted, err
+		}
+		r, name, err := src.ReadDown(version)
+		if err != nil {
+			return reverted, fmt.Errorf("no down migration for version %d: %w", version, err)
+		}
+		_ = r.Close()
+
+		start := time.Now()
+		err = m.Steps(-1)
+		reverted = append(reverted, PendingMigration{Version: version, Name: name, Duration: time.Since(start)})
+		if err != nil {
+			return reverted, fmt.Errorf("reverting migration %d (%s) failed: %w", version, name, err)
+		}
+
+		version, _, err = m.Version()
+		if errors.Is(err, migrate.ErrNilVersion) {
+			break
+		}
+		if err != nil {
+			return reverted, fmt.Errorf("unable to read schema version: %w", err)
+		}
+	}
+
+	return reverted, nil
+}
@@ -0,0 +1,88 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestSchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	latest, err := LatestSchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, latest, migrateTestFileTo(t, testFile, 0))
+}
+
+func TestDowngrade(t *testing.T) {
+	t.Parallel()
+
+	t.Run("every migration can be reverted and reapplied", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		store, err := NewSQLiteUserStore(testFile)
+		require.NoError(t, err)
+		user, err := NewStubUser("alice")
+		require.NoError(t, err)
+		require.NoError(t, store.InsertUser(context.Background(), user))
+		require.NoError(t, store.pool.Close())
+
+		latest, err := LatestSchemaVersion()
+		require.NoError(t, err)
+
+		reverted, err := Downgrade(context.Background(), testFile, 1)
+		require.NoError(t, err)
+		require.Len(t, reverted, int(latest)-1)
+		assert.Equal(t, latest, reverted[0].Version)
+		assert.Equal(t, uint(2), reverted[len(reverted)-1].Version)
+		assert.Equal(t, uint(1), migrateTestFileTo(t, testFile, -1))
+
+		_, err = NewSQLiteUserStore(testFile)
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid targets", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		latest := migrateTestFileTo(t, testFile, 0)
+		_, err := Downgrade(context.Background(), testFile, latest+1)
+		assert.ErrorContains(t, err, "is newer than")
+		_, err = Downgrade(context.Background(), testFile, latest+1000)
+		assert.Error(t, err)
+
+		reverted, err := Downgrade(context.Background(), testFile, latest)
+		assert.NoError(t, err)
+		assert.Empty(t, reverted)
+	})
+
+	t.Run("missing database", func(t *testing.T) {
+		_, err := Downgrade(context.Background(), filepath.Join(t.TempDir(), "missing.db"), 1)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestNewSQLiteUserStore_SchemaTooNew(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	latest := migrateTestFileTo(t, testFile, 0)
+
+	db, err := sql.Open("sqlite", sqliteDSN(testFile, ""))
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE schema_migrations SET version = ?`, latest+1)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	_, err = NewSQLiteUserStore(testFile)
+	assert.ErrorIs(t, err, ErrSchemaTooNew)
+	assert.ErrorContains(t, err, fmt.Sprintf("dbtool downgrade -to %d", latest))
+}
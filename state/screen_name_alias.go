@@ -0,0 +1,173 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/pchchv/go-icq/wire"
+	"modernc.org/sqlite"
+	lib "modernc.org/sqlite/lib"
+)
+
+// AddScreenNameAlias registers alias as an additional screen name of the
+// primary account. An alias has no user record of its own: users can sign
+// on with the alias and the primary account's password, and messages and
+// presence lookups addressed to the alias reach the primary account's
+// session. It returns ErrDupUser if alias is already a screen name or an
+// alias and ErrNoUser if primary doesn't exist.
+func (us SQLiteUserStore) AddScreenNameAlias(ctx context.Context, primary IdentScreenName, alias DisplayScreenName) (err error) {
+	if alias.IsUIN() {
+		err = alias.ValidateUIN()
+	} else {
+		err = alias.ValidateAIMHandle()
+	}
+	if err != nil {
+		return err
+	}
+
+	err = us.WithTx(ctx, func(tx *SQLiteUserStore) error {
+		var exists bool
+		q := `SELECT EXISTS(SELECT 1 FROM users WHERE identScreenName = ?)`
+		if err := tx.db.QueryRowContext(ctx, q, alias.IdentScreenName().String()).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return ErrDupUser
+		}
+
+		q = `
+			INSERT INTO screenNameAlias (alias, displayAlias, primaryScreenName)
+			VALUES (?, ?, ?)
+		`
+		_, err := tx.db.ExecContext(ctx, q, alias.IdentScreenName().String(), alias.String(), primary.String())
+		if sqliteErr, ok := err.(*sqlite.Error); ok {
+			switch sqliteErr.Code() {
+			case lib.SQLITE_CONSTRAINT_PRIMARYKEY:
+				return ErrDupUser
+			case lib.SQLITE_CONSTRAINT_FOREIGNKEY:
+				return ErrNoUser
+			}
+		}
+		return err
+	})
+	if err != nil && !errors.Is(err, ErrDupUser) && !errors.Is(err, ErrNoUser) {
+		return fmt.Errorf("AddScreenNameAlias: %w", err)
+	}
+	return err
+}
+
+// RemoveScreenNameAlias deletes an alias. It returns
+// ErrScreenNameAliasNotFound if alias isn't registered.
+func (us SQLiteUserStore) RemoveScreenNameAlias(ctx context.Context, alias IdentScreenName) error {
+	res, err := us.db.ExecContext(ctx, `DELETE FROM screenNameAlias WHERE alias = ?`, alias.String())
+	if err != nil {
+		return fmt.Errorf("RemoveScreenNameAlias: %w", err)
+	}
+
+	if c, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("RemoveScreenNameAlias: %w", err)
+	} else if c == 0 {
+		return ErrScreenNameAliasNotFound
+	}
+
+	return nil
+}
+
+// ScreenNameAliases returns the aliases of the primary account in the order
+// they were added.
+func (us SQLiteUserStore) ScreenNameAliases(ctx context.Context, primary IdentScreenName) ([]DisplayScreenName, error) {
+	q := `
+		SELECT displayAlias
+		FROM screenNameAlias
+		WHERE primaryScreenName = ?
+		ORDER BY created, rowid
+	`
+	rows, err := us.db.QueryContext(ctx, q, primary.String())
+	if err != nil {
+		return nil, fmt.Errorf("ScreenNameAliases: %w", err)
+	}
+	defer rows.Close()
+
+	var aliases []DisplayScreenName
+	for rows.Next() {
+		var alias string
+		if err := rows.Scan(&alias); err != nil {
+			return nil, fmt.Errorf("ScreenNameAliases: %w", err)
+		}
+		aliases = append(aliases, DisplayScreenName(alias))
+	}
+
+	return aliases, rows.Err()
+}
+
+// ResolveScreenNameAlias returns the primary account of screenName if it is
+// an alias, or screenName itself otherwise.
+func (us SQLiteUserStore) ResolveScreenNameAlias(ctx context.Context, screenName IdentScreenName) (IdentScreenName, error) {
+	var primary string
+	q := `SELECT primaryScreenName FROM screenNameAlias WHERE alias = ?`
+	err := us.db.QueryRowContext(ctx, q, screenName.String()).Scan(&primary)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return screenName, nil
+	case err != nil:
+		return IdentScreenName{}, fmt.Errorf("ResolveScreenNameAlias: %w", err)
+	}
+	return NewIdentScreenName(primary), nil
+}
+
+// LoginUser returns the account a user signs on to with screenName, which
+// may be an alias. Credentials must be checked against the returned user,
+// so an alias signs on with the primary account's password. It returns nil
+// if no such account exists.
+func (us SQLiteUserStore) LoginUser(ctx context.Context, screenName IdentScreenName) (*User, error) {
+	primary, err := us.ResolveScreenNameAlias(ctx, screenName)
+	if err != nil {
+		return nil, fmt.Errorf("LoginUser: %w", err)
+	}
+	return us.User(ctx, primary)
+}
+
+// SetScreenNameAliases registers the aliases of a signed-on primary account
+// so that RetrieveSession and the relay methods route screen names in
+// aliases to the primary account's session. It replaces any aliases
+// previously registered for primary. Aliases are unregistered when the
+// primary account's session is removed.
+func (s *InMemorySessionManager) SetScreenNameAliases(primary IdentScreenName, aliases []DisplayScreenName) {
+	s.mapMutex.Lock()
+	defer s.mapMutex.Unlock()
+
+	s.removeAliases(primary)
+	for _, alias := range aliases {
+		s.aliases[alias.IdentScreenName()] = primary
+	}
+}
+
+// removeAliases unregisters the aliases of primary. The caller must hold
+// mapMutex.
+func (s *InMemorySessionManager) removeAliases(primary IdentScreenName) {
+	for alias, p := range s.aliases {
+		if p == primary {
+			delete(s.aliases, alias)
+		}
+	}
+}
+
+// resolveAlias returns the primary account of screenName if it is a
+// registered alias, or screenName itself otherwise. The caller must hold
+// mapMutex.
+func (s *InMemorySessionManager) resolveAlias(screenName IdentScreenName) IdentScreenName {
+	if primary, ok := s.aliases[screenName]; ok {
+		return primary
+	}
+	return screenName
+}
+
+// TLVUserInfoAs returns the session's user info presented under
+// screenName, such as an alias that a buddy has on their buddy list.
+func (s *Session) TLVUserInfoAs(screenName DisplayScreenName) wire.TLVUserInfo {
+	info := s.TLVUserInfo()
+	info.ScreenName = screenName.Wire()
+	return info
+}
@@ -0,0 +1,117 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestSQLiteUserStore_ScreenNameAlias(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	primary, err := NewStubUser("PrimaryPat")
+	require.NoError(t, err)
+	require.NoError(t, store.InsertUser(ctx, primary))
+	other, err := NewStubUser("OtherOlly")
+	require.NoError(t, err)
+	require.NoError(t, store.InsertUser(ctx, other))
+
+	require.NoError(t, store.AddScreenNameAlias(ctx, primary.IdentScreenName, "Pat Alias"))
+	require.NoError(t, store.AddScreenNameAlias(ctx, primary.IdentScreenName, "PatOther"))
+
+	t.Run("list aliases", func(t *testing.T) {
+		aliases, err := store.ScreenNameAliases(ctx, primary.IdentScreenName)
+		require.NoError(t, err)
+		assert.Equal(t, []DisplayScreenName{"Pat Alias", "PatOther"}, aliases)
+	})
+
+	t.Run("resolve alias", func(t *testing.T) {
+		sn, err := store.ResolveScreenNameAlias(ctx, NewIdentScreenName("patalias"))
+		require.NoError(t, err)
+		assert.Equal(t, primary.IdentScreenName, sn)
+
+		sn, err = store.ResolveScreenNameAlias(ctx, other.IdentScreenName)
+		require.NoError(t, err)
+		assert.Equal(t, other.IdentScreenName, sn)
+	})
+
+	t.Run("login via alias", func(t *testing.T) {
+		user, err := store.LoginUser(ctx, NewIdentScreenName("PatOther"))
+		require.NoError(t, err)
+		require.NotNil(t, user)
+		assert.Equal(t, primary.IdentScreenName, user.IdentScreenName)
+		assert.Equal(t, primary.StrongMD5Pass, user.StrongMD5Pass)
+	})
+
+	t.Run("alias collides with user", func(t *testing.T) {
+		err := store.AddScreenNameAlias(ctx, primary.IdentScreenName, "OtherOlly")
+		assert.ErrorIs(t, err, ErrDupUser)
+	})
+
+	t.Run("alias collides with alias", func(t *testing.T) {
+		err := store.AddScreenNameAlias(ctx, other.IdentScreenName, "PatOther")
+		assert.ErrorIs(t, err, ErrDupUser)
+	})
+
+	t.Run("primary does not exist", func(t *testing.T) {
+		err := store.AddScreenNameAlias(ctx, NewIdentScreenName("nobody"), "NobodyAlias")
+		assert.ErrorIs(t, err, ErrNoUser)
+	})
+
+	t.Run("user can't take an alias", func(t *testing.T) {
+		user, err := NewStubUser("PatOther")
+		require.NoError(t, err)
+		assert.ErrorIs(t, store.InsertUser(ctx, user), ErrDupUser)
+	})
+
+	t.Run("remove alias", func(t *testing.T) {
+		require.NoError(t, store.RemoveScreenNameAlias(ctx, NewIdentScreenName("PatOther")))
+		assert.ErrorIs(t, store.RemoveScreenNameAlias(ctx, NewIdentScreenName("PatOther")), ErrScreenNameAliasNotFound)
+
+		aliases, err := store.ScreenNameAliases(ctx, primary.IdentScreenName)
+		require.NoError(t, err)
+		assert.Equal(t, []DisplayScreenName{"Pat Alias"}, aliases)
+	})
+
+	t.Run("aliases are deleted with the primary account", func(t *testing.T) {
+		require.NoError(t, store.DeleteUser(ctx, primary.IdentScreenName))
+		sn, err := store.ResolveScreenNameAlias(ctx, NewIdentScreenName("patalias"))
+		require.NoError(t, err)
+		assert.Equal(t, NewIdentScreenName("patalias"), sn)
+	})
+}
+
+func TestInMemorySessionManager_ScreenNameAliases(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+
+	sess, err := sm.AddSession(context.Background(), "PrimaryPat")
+	require.NoError(t, err)
+	sess.SetSignonComplete()
+	sm.SetScreenNameAliases(sess.IdentScreenName(), []DisplayScreenName{"Pat Alias"})
+
+	alias := NewIdentScreenName("patalias")
+	assert.Same(t, sess, sm.RetrieveSession(alias))
+
+	msg := wire.SNACMessage{Frame: wire.SNACFrame{FoodGroup: wire.ICBM}}
+	sm.RelayToScreenName(context.Background(), alias, msg)
+	assert.Equal(t, msg, <-sess.ReceiveMessage())
+	sm.RelayToScreenNames(context.Background(), []IdentScreenName{alias}, msg)
+	assert.Equal(t, msg, <-sess.ReceiveMessage())
+
+	assert.Equal(t, wire.ScreenName("Pat Alias"), sess.TLVUserInfoAs("Pat Alias").ScreenName)
+
+	sm.RemoveSession(sess)
+	assert.Nil(t, sm.RetrieveSession(alias))
+	assert.Empty(t, sm.aliases)
+}
@@ -0,0 +1,210 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+// screenNameFilterMinCapacity is the smallest number of screen names a
+// ScreenNameFilter is sized for, so that a new server doesn't rebuild its
+// filter with every few registrations.
+const screenNameFilterMinCapacity = 1024
+
+// ScreenNameFilter is a bloom filter of the ident screen names that exist
+// in the user store. It answers "definitely doesn't exist" without a
+// database query, which lets lookups of unknown screen names, such as IMs
+// and buddy list entries for mistyped names, skip the database. It may
+// report that a screen name exists when it doesn't, at a rate of about
+// falsePositiveRate while it holds no more than its capacity.
+//
+// Screen names can't be removed from a bloom filter, so deleted accounts
+// stay in the filter until it's rebuilt. This only costs a database query
+// for their names. See Stale.
+type ScreenNameFilter struct {
+	mu                sync.RWMutex
+	bits              []uint64
+	hashes            uint64
+	capacity          int
+	falsePositiveRate float64
+	added             int
+	removed           int
+	// pending records the screen names added while a rebuild is reading
+	// the user table, so that the rebuilt filter doesn't miss them. It's
+	// nil when no rebuild is in progress.
+	pending []IdentScreenName
+}
+
+// NewScreenNameFilter creates an empty ScreenNameFilter sized for capacity
+// screen names with the given false positive rate, such as 0.01.
+func NewScreenNameFilter(capacity int, falsePositiveRate float64) *ScreenNameFilter {
+	f := &ScreenNameFilter{falsePositiveRate: falsePositiveRate}
+	f.reset(capacity)
+	return f
+}
+
+// reset empties the filter and sizes it for capacity screen names.
+func (f *ScreenNameFilter) reset(capacity int) {
+	capacity = max(capacity, screenNameFilterMinCapacity)
+	// optimal size and number of hash functions for a bloom filter holding
+	// n items with false positive rate p: m = -n*ln(p)/ln(2)^2, k = m/n*ln(2)
+	m := math.Ceil(-float64(capacity) * math.Log(f.falsePositiveRate) / (math.Ln2 * math.Ln2))
+	f.bits = make([]uint64, (int(m)+63)/64)
+	f.hashes = uint64(max(1, math.Round(m/float64(capacity)*math.Ln2)))
+	f.capacity = capacity
+	f.added = 0
+	f.removed = 0
+}
+
+// positions calls fn with the bit positions of screenName, using double
+// hashing of a 64-bit FNV-1a hash.
+func (f *ScreenNameFilter) positions(screenName IdentScreenName, fn func(word int, mask uint64) bool) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(screenName.String()))
+	sum := h.Sum64()
+	h1, h2 := sum&math.MaxUint32, sum>>32|1
+
+	m := uint64(len(f.bits)) * 64
+	for i := range f.hashes {
+		bit := (h1 + i*h2) % m
+		if !fn(int(bit/64), 1<<(bit%64)) {
+			return
+		}
+	}
+}
+
+// Add records that screenName exists.
+func (f *ScreenNameFilter) Add(screenName IdentScreenName) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.add(screenName)
+	if f.pending != nil {
+		f.pending = append(f.pending, screenName)
+	}
+}
+
+func (f *ScreenNameFilter) add(screenName IdentScreenName) {
+	f.positions(screenName, func(word int, mask uint64) bool {
+		f.bits[word] |= mask
+		return true
+	})
+	f.added++
+}
+
+// Removed records that an account was deleted. The screen name stays in
+// the filter, but counts towards Stale.
+func (f *ScreenNameFilter) Removed() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removed++
+}
+
+// MayExist reports whether screenName may exist. If it returns false, the
+// screen name definitely doesn't exist.
+func (f *ScreenNameFilter) MayExist(screenName IdentScreenName) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	found := true
+	f.positions(screenName, func(word int, mask uint64) bool {
+		found = f.bits[word]&mask != 0
+		return found
+	})
+	return found
+}
+
+// Stale reports whether the filter should be rebuilt, either because it
+// holds more screen names than it's sized for, which raises its false
+// positive rate, or because a tenth of its screen names were deleted.
+func (f *ScreenNameFilter) Stale() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.added > f.capacity || f.removed > f.added/10
+}
+
+// Rebuild refills the filter with the screen names returned by load, sized
+// for twice their number. Screen names added while load runs are kept.
+func (f *ScreenNameFilter) Rebuild(ctx context.Context, load func(ctx context.Context) ([]IdentScreenName, error)) error {
+	f.mu.Lock()
+	f.pending = []IdentScreenName{}
+	f.mu.Unlock()
+
+	names, err := load(ctx)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	pending := f.pending
+	f.pending = nil
+	if err != nil {
+		return err
+	}
+
+	f.reset(2 * len(names))
+	for _, screenName := range names {
+		f.add(screenName)
+	}
+	for _, screenName := range pending {
+		f.add(screenName)
+	}
+	return nil
+}
+
+// EnableScreenNameFilter builds a ScreenNameFilter from the user table and
+// attaches it to the store. Afterwards, User and FindByUIN skip the
+// database for screen names that definitely don't exist, InsertUser and
+// RenameScreenName add the new names to the filter, and account deletions
+// count towards its staleness. It must be called before the store is
+// shared, and only if all accounts are created through this store, since
+// accounts created by other processes would look nonexistent.
+func (us *SQLiteUserStore) EnableScreenNameFilter(ctx context.Context, falsePositiveRate float64) error {
+	filter := NewScreenNameFilter(0, falsePositiveRate)
+	if err := filter.Rebuild(ctx, us.allIdentScreenNames); err != nil {
+		return fmt.Errorf("EnableScreenNameFilter: %w", err)
+	}
+	us.nameFilter = filter
+	return nil
+}
+
+// RebuildScreenNameFilter rebuilds the store's screen name filter if it's
+// stale. It does nothing if the filter is not enabled.
+func (us SQLiteUserStore) RebuildScreenNameFilter(ctx context.Context) error {
+	if us.nameFilter == nil || !us.nameFilter.Stale() {
+		return nil
+	}
+	if err := us.nameFilter.Rebuild(ctx, us.allIdentScreenNames); err != nil {
+		return fmt.Errorf("RebuildScreenNameFilter: %w", err)
+	}
+	return nil
+}
+
+// ScheduleScreenNameFilter registers the "screen_name_filter" job that
+// rebuilds the screen name filter every interval when it's stale.
+func (us SQLiteUserStore) ScheduleScreenNameFilter(s *Scheduler, interval time.Duration) error {
+	return s.Add("screen_name_filter", interval, interval/10, us.RebuildScreenNameFilter)
+}
+
+// mayExist reports whether screenName may exist according to the screen
+// name filter. It returns true if the filter is not enabled.
+func (us SQLiteUserStore) mayExist(screenName IdentScreenName) bool {
+	return us.nameFilter == nil || us.nameFilter.MayExist(screenName)
+}
+
+func (us SQLiteUserStore) allIdentScreenNames(ctx context.Context) ([]IdentScreenName, error) {
+	rows, err := us.db.QueryContext(ctx, `SELECT identScreenName FROM users`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []IdentScreenName
+	for rows.Next() {
+		var sn string
+		if err := rows.Scan(&sn); err != nil {
+			return nil, err
+		}
+		names = append(names, NewIdentScreenName(sn))
+	}
+	return names, rows.Err()
+}
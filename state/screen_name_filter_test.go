@@ -0,0 +1,128 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScreenNameFilter(t *testing.T) {
+	f := NewScreenNameFilter(1000, 0.01)
+
+	var added []IdentScreenName
+	for i := range 1000 {
+		sn := NewIdentScreenName(fmt.Sprintf("user%d", i))
+		f.Add(sn)
+		added = append(added, sn)
+	}
+	for _, sn := range added {
+		assert.True(t, f.MayExist(sn), "false negative for %s", sn)
+	}
+
+	falsePositives := 0
+	for i := range 10000 {
+		if f.MayExist(NewIdentScreenName(fmt.Sprintf("stranger%d", i))) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 300, "false positive rate too high")
+	assert.False(t, f.Stale())
+
+	t.Run("deletions make the filter stale", func(t *testing.T) {
+		for range 101 {
+			f.Removed()
+		}
+		assert.True(t, f.Stale())
+	})
+
+	t.Run("rebuild keeps names added while loading", func(t *testing.T) {
+		late := NewIdentScreenName("latecomer")
+		err := f.Rebuild(context.Background(), func(context.Context) ([]IdentScreenName, error) {
+			f.Add(late)
+			return added[:10], nil
+		})
+		require.NoError(t, err)
+		assert.True(t, f.MayExist(added[0]))
+		assert.True(t, f.MayExist(late))
+		assert.False(t, f.Stale())
+	})
+
+	t.Run("failed rebuild keeps the filter", func(t *testing.T) {
+		err := f.Rebuild(context.Background(), func(context.Context) ([]IdentScreenName, error) {
+			return nil, errors.New("database is locked")
+		})
+		assert.Error(t, err)
+		assert.True(t, f.MayExist(added[0]))
+	})
+}
+
+func TestSQLiteUserStore_ScreenNameFilter(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	existing, err := NewStubUser("Existing")
+	require.NoError(t, err)
+	require.NoError(t, f.InsertUser(ctx, existing))
+
+	require.NoError(t, f.EnableScreenNameFilter(ctx, 0.01))
+	assert.True(t, f.nameFilter.MayExist(existing.IdentScreenName))
+
+	user, err := f.User(ctx, existing.IdentScreenName)
+	require.NoError(t, err)
+	require.NotNil(t, user)
+
+	user, err = f.User(ctx, NewIdentScreenName("nobody"))
+	require.NoError(t, err)
+	assert.Nil(t, user)
+
+	_, err = f.FindByUIN(ctx, 100001)
+	assert.ErrorIs(t, err, ErrNoUser)
+
+	t.Run("inserted users are found", func(t *testing.T) {
+		newUser, err := NewStubUser("100001")
+		require.NoError(t, err)
+		require.NoError(t, f.InsertUser(ctx, newUser))
+
+		found, err := f.FindByUIN(ctx, 100001)
+		require.NoError(t, err)
+		assert.Equal(t, newUser.IdentScreenName, found.IdentScreenName)
+	})
+
+	t.Run("renamed users are found", func(t *testing.T) {
+		require.NoError(t, f.RenameScreenName(ctx, existing.IdentScreenName, "Renamed"))
+		user, err := f.User(ctx, NewIdentScreenName("Renamed"))
+		require.NoError(t, err)
+		assert.NotNil(t, user)
+	})
+
+	t.Run("users inserted in a transaction are found", func(t *testing.T) {
+		txUser, err := NewStubUser("TxUser")
+		require.NoError(t, err)
+		require.NoError(t, f.WithTx(ctx, func(tx *SQLiteUserStore) error {
+			return tx.InsertUser(ctx, txUser)
+		}))
+		user, err := f.User(ctx, txUser.IdentScreenName)
+		require.NoError(t, err)
+		assert.NotNil(t, user)
+	})
+
+	t.Run("rebuild after deletions", func(t *testing.T) {
+		require.NoError(t, f.DeleteUser(ctx, NewIdentScreenName("Renamed")))
+		assert.True(t, f.nameFilter.Stale())
+		require.NoError(t, f.RebuildScreenNameFilter(ctx))
+		assert.False(t, f.nameFilter.Stale())
+
+		user, err := f.User(ctx, NewIdentScreenName("TxUser"))
+		require.NoError(t, err)
+		assert.NotNil(t, user)
+	})
+}
@@ -0,0 +1,156 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+const (
+	// screenNameGenerateAttempts is how many random screen names are tried
+	// before giving up on finding one that is allowed and free.
+	screenNameGenerateAttempts = 20
+	// screenNameMaxDigits is the largest number of digits appended to a
+	// generated screen name.
+	screenNameMaxDigits = 4
+)
+
+// ErrScreenNameUnavailable indicates that no allowed and free screen name
+// could be generated.
+var ErrScreenNameUnavailable = errors.New("unable to generate an available screen name")
+
+var (
+	// defaultScreenNameAdjectives are the adjectives used when a
+	// ScreenNameGenerator has none configured. They're short so that
+	// adjective, noun and digits fit in a screen name after a prefix.
+	defaultScreenNameAdjectives = []string{
+		"Cool", "Fast", "Lazy", "Happy", "Sunny", "Crazy", "Funky", "Jazzy",
+		"Silly", "Lucky", "Fuzzy", "Swift", "Mystic", "Cosmic", "Rad", "Neon",
+		"Silver", "Golden", "Wild", "Sly", "Zany", "Groovy", "Sleepy", "Bold",
+	}
+	// defaultScreenNameNouns are the nouns used when a ScreenNameGenerator
+	// has none configured.
+	defaultScreenNameNouns = []string{
+		"Surfer", "Skater", "Dude", "Kitty", "Tiger", "Dragon", "Wizard",
+		"Rocker", "Gamer", "Angel", "Otter", "Panda", "Ninja", "Pirate",
+		"Comet", "Falcon", "Fox", "Wolf", "Raven", "Star", "Pixel", "Modem",
+	}
+)
+
+// ScreenNameGenerator generates random screen names in the style of the
+// era, made of an adjective, a noun and digits, such as "CoolSurfer1987".
+// Names that contain a blocked word anywhere, including across word
+// boundaries, are never generated.
+type ScreenNameGenerator struct {
+	// Prefix is prepended to every generated name, such as the guest
+	// screen name prefix.
+	Prefix string
+	// Adjectives are the adjectives to pick from. Empty uses a built-in
+	// list.
+	Adjectives []string
+	// Nouns are the nouns to pick from. Empty uses a built-in list.
+	Nouns []string
+	// Blocklist lists words that must not appear in generated names,
+	// compared case-insensitively, such as the profanity filter's words.
+	Blocklist []string
+}
+
+// Generate returns a random screen name. The adjective is left out when a
+// long prefix leaves no room for it, and as many digits are appended as fit
+// in a screen name, up to 4. It returns ErrScreenNameUnavailable if the
+// word lists and blocklist keep producing names that aren't allowed.
+func (g ScreenNameGenerator) Generate() (DisplayScreenName, error) {
+	adjectives := g.Adjectives
+	if len(adjectives) == 0 {
+		adjectives = defaultScreenNameAdjectives
+	}
+	nouns := g.Nouns
+	if len(nouns) == 0 {
+		nouns = defaultScreenNameNouns
+	}
+
+	for range screenNameGenerateAttempts {
+		noun := nouns[rand.IntN(len(nouns))]
+		name := g.Prefix + adjectives[rand.IntN(len(adjectives))] + noun
+		if len(name) > 15 {
+			name = g.Prefix + noun
+		}
+		if len(name) > 16 {
+			continue
+		}
+		if digits := min(screenNameMaxDigits, 16-len(name)); digits > 0 {
+			low := pow10(digits - 1)
+			name += strconv.Itoa(low + rand.IntN(9*low))
+		}
+
+		if g.blocked(name) {
+			continue
+		}
+		sn := DisplayScreenName(name)
+		if err := sn.ValidateAIMHandle(); err != nil {
+			continue
+		}
+		return sn, nil
+	}
+
+	return "", ErrScreenNameUnavailable
+}
+
+// blocked indicates whether name contains a word from the blocklist.
+func (g ScreenNameGenerator) blocked(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range g.Blocklist {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" && strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// pow10 returns 10 to the power of n.
+func pow10(n int) int {
+	p := 1
+	for range n {
+		p *= 10
+	}
+	return p
+}
+
+// NewRandomStubUser creates a new user with a screen name from g and the
+// canned credentials of NewStubUser. The screen name may already be taken;
+// use SQLiteUserStore.GenerateScreenName to get a free one.
+func NewRandomStubUser(g ScreenNameGenerator) (User, error) {
+	sn, err := g.Generate()
+	if err != nil {
+		return User{}, err
+	}
+	return NewStubUser(sn)
+}
+
+// GenerateScreenName returns a screen name from g that doesn't belong to an
+// account, isn't an alias and isn't reserved. It returns
+// ErrScreenNameUnavailable if every name tried is taken.
+func (us SQLiteUserStore) GenerateScreenName(ctx context.Context, g ScreenNameGenerator) (DisplayScreenName, error) {
+	q := `
+		SELECT EXISTS(SELECT 1 FROM users WHERE identScreenName = ?1)
+		    OR EXISTS(SELECT 1 FROM screenNameAlias WHERE alias = ?1)
+		    OR EXISTS(SELECT 1 FROM reservedScreenName WHERE identScreenName = ?1)
+	`
+	for range screenNameGenerateAttempts {
+		sn, err := g.Generate()
+		if err != nil {
+			return "", fmt.Errorf("GenerateScreenName: %w", err)
+		}
+		var taken bool
+		if err := us.db.QueryRowContext(ctx, q, sn.IdentScreenName().String()).Scan(&taken); err != nil {
+			return "", fmt.Errorf("GenerateScreenName: %w", err)
+		}
+		if !taken {
+			return sn, nil
+		}
+	}
+	return "", fmt.Errorf("GenerateScreenName: %w", ErrScreenNameUnavailable)
+}
@@ -0,0 +1,102 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScreenNameGenerator_Generate(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		for range 100 {
+			sn, err := ScreenNameGenerator{}.Generate()
+			require.NoError(t, err)
+			assert.NoError(t, sn.ValidateAIMHandle())
+			assert.Regexp(t, `^[A-Z][a-z]+[A-Z][a-z]+[1-9]\d*$`, sn)
+		}
+	})
+
+	t.Run("digits fill the remaining room", func(t *testing.T) {
+		g := ScreenNameGenerator{Adjectives: []string{"Cool"}, Nouns: []string{"Surfer"}}
+		sn, err := g.Generate()
+		require.NoError(t, err)
+		assert.Regexp(t, `^CoolSurfer[1-9]\d{3}$`, sn)
+
+		g.Prefix = "Guest"
+		sn, err = g.Generate()
+		require.NoError(t, err)
+		assert.Regexp(t, `^GuestCoolSurfer[1-9]$`, sn)
+
+		// the adjective goes first when there's no room for a digit
+		g.Prefix = "Guests"
+		sn, err = g.Generate()
+		require.NoError(t, err)
+		assert.Regexp(t, `^GuestsSurfer[1-9]\d{3}$`, sn)
+	})
+
+	t.Run("adjective is dropped when there's no room", func(t *testing.T) {
+		g := ScreenNameGenerator{Prefix: "WebVisitor", Adjectives: []string{"Sleepy"}, Nouns: []string{"Fox"}}
+		sn, err := g.Generate()
+		require.NoError(t, err)
+		assert.Regexp(t, `^WebVisitorFox\d{3}$`, sn)
+	})
+
+	t.Run("blocked words are never generated", func(t *testing.T) {
+		// "assassin" spans the adjective and the noun
+		g := ScreenNameGenerator{
+			Adjectives: []string{"Sass", "Cool"},
+			Nouns:      []string{"Assin", "Cat"},
+			Blocklist:  []string{" SASS "},
+		}
+		for range 50 {
+			sn, err := g.Generate()
+			require.NoError(t, err)
+			assert.NotContains(t, sn.String(), "Sass")
+		}
+
+		g.Blocklist = []string{"cat", "assin"}
+		g.Adjectives = []string{"Cool"}
+		_, err := g.Generate()
+		assert.ErrorIs(t, err, ErrScreenNameUnavailable)
+	})
+}
+
+func TestNewRandomStubUser(t *testing.T) {
+	u, err := NewRandomStubUser(ScreenNameGenerator{Adjectives: []string{"Lazy"}, Nouns: []string{"Otter"}})
+	require.NoError(t, err)
+	assert.Regexp(t, `^LazyOtter\d+$`, u.DisplayScreenName)
+	assert.Equal(t, u.DisplayScreenName.IdentScreenName(), u.IdentScreenName)
+	assert.NotEmpty(t, u.StrongMD5Pass)
+}
+
+func TestSQLiteUserStore_GenerateScreenName(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// a single possible name, taken in turn by an account and a reservation
+	g := ScreenNameGenerator{Prefix: "WebChatGuest", Nouns: []string{"Wolf"}}
+
+	sn, err := f.GenerateScreenName(ctx, g)
+	require.NoError(t, err)
+	assert.Equal(t, DisplayScreenName("WebChatGuestWolf"), sn)
+
+	_, err = f.ReserveScreenName(ctx, sn.IdentScreenName())
+	require.NoError(t, err)
+	_, err = f.GenerateScreenName(ctx, g)
+	assert.ErrorIs(t, err, ErrScreenNameUnavailable)
+
+	g.Prefix = "WebChatGuests"
+	g.Nouns = []string{"Fox"}
+	user, err := NewStubUser("WebChatGuestsFox")
+	require.NoError(t, err)
+	require.NoError(t, f.InsertUser(ctx, user))
+	_, err = f.GenerateScreenName(ctx, g)
+	assert.ErrorIs(t, err, ErrScreenNameUnavailable)
+}
@@ -0,0 +1,100 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// ScreenNameMatchKind describes how a screen name matched a search.
+type ScreenNameMatchKind uint8
+
+const (
+	// ScreenNameMatchExact indicates that the screen name equals the query.
+	ScreenNameMatchExact ScreenNameMatchKind = iota
+	// ScreenNameMatchPrefix indicates that the screen name starts with the
+	// query.
+	ScreenNameMatchPrefix
+	// ScreenNameMatchSubstring indicates that the screen name contains the
+	// query elsewhere.
+	ScreenNameMatchSubstring
+)
+
+// ScreenNameMatch is a result of SearchScreenNames.
+type ScreenNameMatch struct {
+	IdentScreenName   IdentScreenName
+	DisplayScreenName DisplayScreenName
+	IsICQ             bool
+	Match             ScreenNameMatchKind
+}
+
+// SearchScreenNames returns up to limit users whose screen name contains
+// query, ignoring case and spaces. Exact matches rank first, then prefix
+// matches, then other substring matches; matches of the same kind are
+// ordered by length, then alphabetically. Queries of 3 or more characters
+// are served by a trigram index; shorter queries scan all screen names.
+func (us SQLiteUserStore) SearchScreenNames(ctx context.Context, query string, limit int) ([]ScreenNameMatch, error) {
+	// LIKE wildcards can't appear in screen names, so they are dropped
+	// rather than escaped
+	q := strings.NewReplacer("%", "", "_", "").Replace(NewIdentScreenName(query).String())
+	if q == "" || limit <= 0 {
+		return nil, nil
+	}
+
+	sql := `
+		SELECT u.identScreenName,
+			   u.displayScreenName,
+			   u.isICQ,
+			   CASE
+				   WHEN u.identScreenName = ? THEN 0
+				   WHEN substr(u.identScreenName, 1, ?) = ? THEN 1
+				   ELSE 2
+			   END AS matchKind
+		FROM screenNameSearch s
+				 JOIN users u ON u.rowid = s.rowid
+		WHERE s.identScreenName LIKE ?
+		ORDER BY matchKind, length(u.identScreenName), u.identScreenName
+		LIMIT ?
+	`
+	rows, err := us.db.QueryContext(ctx, sql, q, len(q), q, "%"+q+"%", limit)
+	if err != nil {
+		return nil, fmt.Errorf("SearchScreenNames: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []ScreenNameMatch
+	for rows.Next() {
+		var identSN, displaySN string
+		m := ScreenNameMatch{}
+		if err := rows.Scan(&identSN, &displaySN, &m.IsICQ, &m.Match); err != nil {
+			return nil, fmt.Errorf("SearchScreenNames: %w", err)
+		}
+		m.IdentScreenName = NewIdentScreenName(identSN)
+		m.DisplayScreenName = DisplayScreenName(displaySN)
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("SearchScreenNames: %w", err)
+	}
+
+	return matches, nil
+}
+
+// ScreenNameSearchReply builds the SNAC(0x0F,0x03) InfoReply for a
+// directory search of type wire.ODirSearchByScreenName, listing the
+// matches in rank order.
+func ScreenNameSearchReply(matches []ScreenNameMatch) wire.SNAC_0x0F_0x03_InfoReply {
+	reply := wire.SNAC_0x0F_0x03_InfoReply{
+		Status: wire.ODirSearchResponseOK,
+	}
+	for _, m := range matches {
+		reply.Results.List = append(reply.Results.List, wire.TLVBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ODirTLVScreenName, m.DisplayScreenName.String()),
+			},
+		})
+	}
+	return reply
+}
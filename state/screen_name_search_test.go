@@ -0,0 +1,100 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestSQLiteUserStore_SearchScreenNames(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, sn := range []DisplayScreenName{"Chuck", "ChattingChuck", "Chuckles", "Up Chuck", "Alice", "100001"} {
+		user, err := NewStubUser(sn)
+		require.NoError(t, err)
+		require.NoError(t, f.InsertUser(ctx, user))
+	}
+
+	names := func(matches []ScreenNameMatch) []DisplayScreenName {
+		var ret []DisplayScreenName
+		for _, m := range matches {
+			ret = append(ret, m.DisplayScreenName)
+		}
+		return ret
+	}
+
+	t.Run("exact, then prefix, then substring", func(t *testing.T) {
+		matches, err := f.SearchScreenNames(ctx, "CHUCK", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []DisplayScreenName{"Chuck", "Chuckles", "Up Chuck", "ChattingChuck"}, names(matches))
+		assert.Equal(t, ScreenNameMatchExact, matches[0].Match)
+		assert.Equal(t, ScreenNameMatchPrefix, matches[1].Match)
+		assert.Equal(t, ScreenNameMatchSubstring, matches[2].Match)
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		matches, err := f.SearchScreenNames(ctx, "chuck", 2)
+		require.NoError(t, err)
+		assert.Equal(t, []DisplayScreenName{"Chuck", "Chuckles"}, names(matches))
+	})
+
+	t.Run("query shorter than a trigram", func(t *testing.T) {
+		matches, err := f.SearchScreenNames(ctx, "li", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []DisplayScreenName{"Alice"}, names(matches))
+	})
+
+	t.Run("spaces are ignored", func(t *testing.T) {
+		matches, err := f.SearchScreenNames(ctx, "up ch", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []DisplayScreenName{"Up Chuck"}, names(matches))
+	})
+
+	t.Run("wildcards match literally nothing", func(t *testing.T) {
+		matches, err := f.SearchScreenNames(ctx, "%", 10)
+		require.NoError(t, err)
+		assert.Empty(t, matches)
+	})
+
+	t.Run("UINs", func(t *testing.T) {
+		matches, err := f.SearchScreenNames(ctx, "0000", 10)
+		require.NoError(t, err)
+		require.Len(t, matches, 1)
+		assert.True(t, matches[0].IsICQ)
+	})
+
+	t.Run("index follows renames and deletes", func(t *testing.T) {
+		require.NoError(t, f.RenameScreenName(ctx, NewIdentScreenName("Chuckles"), "Giggles"))
+		require.NoError(t, f.DeleteUser(ctx, NewIdentScreenName("Up Chuck")))
+
+		matches, err := f.SearchScreenNames(ctx, "chuck", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []DisplayScreenName{"Chuck", "ChattingChuck"}, names(matches))
+
+		matches, err = f.SearchScreenNames(ctx, "giggle", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []DisplayScreenName{"Giggles"}, names(matches))
+	})
+}
+
+func TestScreenNameSearchReply(t *testing.T) {
+	reply := ScreenNameSearchReply([]ScreenNameMatch{
+		{DisplayScreenName: "Chuck"},
+		{DisplayScreenName: "Chuckles"},
+	})
+	assert.Equal(t, wire.ODirSearchResponseOK, reply.Status)
+	require.Len(t, reply.Results.List, 2)
+	sn, ok := reply.Results.List[1].String(wire.ODirTLVScreenName)
+	assert.True(t, ok)
+	assert.Equal(t, "Chuckles", sn)
+}
@@ -0,0 +1,248 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrScreenNameReserved indicates that a screen name can't be registered
+// because it's on the reserved list.
+var ErrScreenNameReserved = errors.New("screen name is reserved")
+
+// Seed declares accounts, chat rooms, directory keywords and reserved
+// screen names that must exist in the database. It's loaded from a YAML
+// file, such as:
+//
+//	users:
+//	  - screen_name: Alice
+//	    password: secret123
+//	bots:
+//	  - screen_name: WeatherBot
+//	    password: secret123
+//	chat_rooms:
+//	  - name: Lobby
+//	    exchange: 5
+//	keyword_categories:
+//	  - name: Music
+//	    keywords: [Jazz, Rock]
+//	reserved_names: [admin, support]
+type Seed struct {
+	// Users are the regular accounts to create.
+	Users []SeedUser `yaml:"users"`
+	// Bots are the accounts to create and flag as bots.
+	Bots []SeedUser `yaml:"bots"`
+	// ChatRooms are the chat rooms to create.
+	ChatRooms []SeedChatRoom `yaml:"chat_rooms"`
+	// KeywordCategories are the directory keyword categories to create,
+	// along with their keywords.
+	KeywordCategories []SeedKeywordCategory `yaml:"keyword_categories"`
+	// ReservedNames are the screen names that can't be registered.
+	ReservedNames []string `yaml:"reserved_names"`
+}
+
+// SeedUser declares an account.
+type SeedUser struct {
+	// ScreenName is the AIM screen name or ICQ UIN of the account.
+	ScreenName string `yaml:"screen_name"`
+	// Password is the account's password.
+	Password string `yaml:"password"`
+}
+
+// SeedChatRoom declares a chat room.
+type SeedChatRoom struct {
+	// Name is the room name.
+	Name string `yaml:"name"`
+	// Exchange is the exchange the room belongs to. Zero means the public
+	// exchange 5.
+	Exchange uint16 `yaml:"exchange"`
+}
+
+// SeedKeywordCategory declares a directory keyword category.
+type SeedKeywordCategory struct {
+	// Name is the category name.
+	Name string `yaml:"name"`
+	// Keywords are the keywords in the category.
+	Keywords []string `yaml:"keywords"`
+}
+
+// SeedReport lists what ApplySeed created. Entries that already existed are
+// left out.
+type SeedReport struct {
+	Users             []IdentScreenName
+	ChatRooms         []string
+	KeywordCategories []string
+	Keywords          []string
+	ReservedNames     []IdentScreenName
+}
+
+// LoadSeed reads the seed file at path. Unknown keys are rejected so that
+// typos don't silently leave things out.
+func LoadSeed(path string) (Seed, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Seed{}, err
+	}
+	return ParseSeed(b)
+}
+
+// ParseSeed parses a seed file. See Seed for the format.
+func ParseSeed(b []byte) (Seed, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+
+	var seed Seed
+	if err := dec.Decode(&seed); err != nil && !errors.Is(err, io.EOF) {
+		return Seed{}, fmt.Errorf("invalid seed file: %w", err)
+	}
+	return seed, nil
+}
+
+// ApplySeed creates whatever seed declares that doesn't exist yet. It's
+// idempotent, so it can run at every startup: existing accounts keep their
+// password, and existing rooms, categories and keywords are left alone.
+// Bots that exist as regular accounts are flagged as bots.
+func (us SQLiteUserStore) ApplySeed(ctx context.Context, seed Seed) (SeedReport, error) {
+	var report SeedReport
+
+	createUser := func(su SeedUser, bot bool) error {
+		sn := DisplayScreenName(su.ScreenName)
+		if sn.IsUIN() {
+			if err := sn.ValidateUIN(); err != nil {
+				return fmt.Errorf("user %q: %w", sn, err)
+			}
+		} else if err := sn.ValidateAIMHandle(); err != nil {
+			return fmt.Errorf("user %q: %w", sn, err)
+		}
+
+		u, err := NewStubUser(sn)
+		if err != nil {
+			return err
+		}
+		if err := u.HashPassword(su.Password); err != nil {
+			return fmt.Errorf("user %q: %w", sn, err)
+		}
+		switch err := us.InsertUser(ctx, u); {
+		case errors.Is(err, ErrDupUser):
+		case err != nil:
+			return fmt.Errorf("user %q: %w", sn, err)
+		default:
+			report.Users = append(report.Users, u.IdentScreenName)
+		}
+		if bot {
+			if err := us.SetBotStatus(ctx, true, u.IdentScreenName); err != nil {
+				return fmt.Errorf("user %q: %w", sn, err)
+			}
+		}
+		return nil
+	}
+	for _, su := range seed.Users {
+		if err := createUser(su, false); err != nil {
+			return report, fmt.Errorf("ApplySeed: %w", err)
+		}
+	}
+	for _, su := range seed.Bots {
+		if err := createUser(su, true); err != nil {
+			return report, fmt.Errorf("ApplySeed: %w", err)
+		}
+	}
+
+	for _, room := range seed.ChatRooms {
+		exchange := room.Exchange
+		if exchange == 0 {
+			exchange = PublicExchange
+		}
+		_, err := us.ChatRoomByName(ctx, exchange, room.Name)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, ErrChatRoomNotFound) {
+			return report, fmt.Errorf("ApplySeed: chat room %q: %w", room.Name, err)
+		}
+		chatRoom := NewChatRoom(room.Name, NewIdentScreenName(""), exchange)
+		if err := us.CreateChatRoom(ctx, &chatRoom); err != nil {
+			return report, fmt.Errorf("ApplySeed: chat room %q: %w", room.Name, err)
+		}
+		report.ChatRooms = append(report.ChatRooms, room.Name)
+	}
+
+	for _, sc := range seed.KeywordCategories {
+		category, err := us.CreateCategory(ctx, sc.Name)
+		switch {
+		case errors.Is(err, ErrKeywordCategoryExists):
+			categories, err := us.Categories(ctx)
+			if err != nil {
+				return report, fmt.Errorf("ApplySeed: %w", err)
+			}
+			i := slices.IndexFunc(categories, func(c Category) bool { return c.Name == sc.Name })
+			if i < 0 {
+				return report, fmt.Errorf("ApplySeed: keyword category %q: %w", sc.Name, ErrKeywordCategoryNotFound)
+			}
+			category = categories[i]
+		case err != nil:
+			return report, fmt.Errorf("ApplySeed: keyword category %q: %w", sc.Name, err)
+		default:
+			report.KeywordCategories = append(report.KeywordCategories, sc.Name)
+		}
+
+		for _, name := range sc.Keywords {
+			switch _, err := us.CreateKeyword(ctx, name, category.ID); {
+			case errors.Is(err, ErrKeywordExists):
+			case err != nil:
+				return report, fmt.Errorf("ApplySeed: keyword %q: %w", name, err)
+			default:
+				report.Keywords = append(report.Keywords, name)
+			}
+		}
+	}
+
+	for _, name := range seed.ReservedNames {
+		sn := NewIdentScreenName(name)
+		added, err := us.ReserveScreenName(ctx, sn)
+		if err != nil {
+			return report, fmt.Errorf("ApplySeed: %w", err)
+		}
+		if added {
+			report.ReservedNames = append(report.ReservedNames, sn)
+		}
+	}
+
+	return report, nil
+}
+
+// ReserveScreenName adds screenName to the reserved list, so that it can't
+// be registered. It returns false if the name was already reserved.
+// Existing accounts are unaffected.
+func (us SQLiteUserStore) ReserveScreenName(ctx context.Context, screenName IdentScreenName) (bool, error) {
+	q := `INSERT INTO reservedScreenName (identScreenName) VALUES (?) ON CONFLICT DO NOTHING`
+	res, err := us.db.ExecContext(ctx, q, screenName.String())
+	if err != nil {
+		return false, fmt.Errorf("ReserveScreenName: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ReserveScreenName: %w", err)
+	}
+	return n > 0, nil
+}
+
+// CheckScreenNameReserved returns ErrScreenNameReserved if screenName is on
+// the reserved list. Registration handlers call it before creating an
+// account.
+func (us SQLiteUserStore) CheckScreenNameReserved(ctx context.Context, screenName IdentScreenName) error {
+	q := `SELECT EXISTS(SELECT 1 FROM reservedScreenName WHERE identScreenName = ?)`
+	var reserved bool
+	if err := us.db.QueryRowContext(ctx, q, screenName.String()).Scan(&reserved); err != nil {
+		return fmt.Errorf("CheckScreenNameReserved: %w", err)
+	}
+	if reserved {
+		return fmt.Errorf("%w: %s", ErrScreenNameReserved, screenName)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSeed = `
+users:
+  - screen_name: Alice
+    password: secret123
+  - screen_name: "100003"
+    password: secret
+bots:
+  - screen_name: WeatherBot
+    password: secret123
+chat_rooms:
+  - name: Lobby
+  - name: Private Room
+    exchange: 4
+keyword_categories:
+  - name: Music
+    keywords: [Jazz, Rock]
+reserved_names: [Admin, support]
+`
+
+func TestParseSeed(t *testing.T) {
+	seed, err := ParseSeed([]byte(testSeed))
+	require.NoError(t, err)
+	assert.Equal(t, []SeedUser{{ScreenName: "Alice", Password: "secret123"}, {ScreenName: "100003", Password: "secret"}}, seed.Users)
+	assert.Equal(t, []SeedChatRoom{{Name: "Lobby"}, {Name: "Private Room", Exchange: 4}}, seed.ChatRooms)
+	assert.Equal(t, []string{"Admin", "support"}, seed.ReservedNames)
+
+	_, err = ParseSeed([]byte("userz: []"))
+	assert.ErrorContains(t, err, "invalid seed file")
+
+	seed, err = ParseSeed(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, seed.Users)
+}
+
+func TestSQLiteUserStore_ApplySeed(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	seed, err := ParseSeed([]byte(testSeed))
+	require.NoError(t, err)
+
+	report, err := f.ApplySeed(ctx, seed)
+	require.NoError(t, err)
+	assert.Equal(t, SeedReport{
+		Users:             []IdentScreenName{NewIdentScreenName("alice"), NewIdentScreenName("100003"), NewIdentScreenName("weatherbot")},
+		ChatRooms:         []string{"Lobby", "Private Room"},
+		KeywordCategories: []string{"Music"},
+		Keywords:          []string{"Jazz", "Rock"},
+		ReservedNames:     []IdentScreenName{NewIdentScreenName("admin"), NewIdentScreenName("support")},
+	}, report)
+
+	bot, err := f.User(ctx, NewIdentScreenName("weatherbot"))
+	require.NoError(t, err)
+	assert.True(t, bot.IsBot)
+	icq, err := f.User(ctx, NewIdentScreenName("100003"))
+	require.NoError(t, err)
+	assert.True(t, icq.IsICQ)
+
+	_, err = f.ChatRoomByName(ctx, PublicExchange, "Lobby")
+	assert.NoError(t, err)
+	_, err = f.ChatRoomByName(ctx, 4, "Private Room")
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, f.CheckScreenNameReserved(ctx, NewIdentScreenName("ADMIN")), ErrScreenNameReserved)
+	assert.NoError(t, f.CheckScreenNameReserved(ctx, NewIdentScreenName("alice")))
+
+	t.Run("applying again changes nothing", func(t *testing.T) {
+		require.NoError(t, f.SetUserPassword(ctx, NewIdentScreenName("alice"), "changed1"))
+		before, err := f.User(ctx, NewIdentScreenName("alice"))
+		require.NoError(t, err)
+
+		seed.KeywordCategories[0].Keywords = append(seed.KeywordCategories[0].Keywords, "Blues")
+		report, err := f.ApplySeed(ctx, seed)
+		require.NoError(t, err)
+		assert.Equal(t, SeedReport{Keywords: []string{"Blues"}}, report)
+
+		after, err := f.User(ctx, NewIdentScreenName("alice"))
+		require.NoError(t, err)
+		assert.Equal(t, before.StrongMD5Pass, after.StrongMD5Pass)
+	})
+
+	t.Run("invalid screen name", func(t *testing.T) {
+		_, err := f.ApplySeed(ctx, Seed{Users: []SeedUser{{ScreenName: "1bad", Password: "secret123"}}})
+		assert.ErrorContains(t, err, `user "1bad"`)
+	})
+}
@@ -0,0 +1,366 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatsSnapshot is a set of aggregate server stats suitable for a status
+// dashboard. Daily figures cover the current UTC day.
+type StatsSnapshot struct {
+	// Time is when the snapshot was taken.
+	Time time.Time `json:"time"`
+	// TotalUsers is the number of registered accounts.
+	TotalUsers int64 `json:"total_users"`
+	// DailyActives is the number of users who signed on today.
+	DailyActives int64 `json:"daily_actives"`
+	// MessagesToday is the number of instant messages sent today.
+	MessagesToday int64 `json:"messages_today"`
+	// PeakSessionsToday is the highest number of concurrent sessions seen
+	// today.
+	PeakSessionsToday int64 `json:"peak_sessions_today"`
+	// OfflineMessagesPending is the number of stored messages waiting for
+	// their recipients to sign on.
+	OfflineMessagesPending int64 `json:"offline_messages_pending"`
+	// RoomsOpen is the number of chat rooms with at least one occupant.
+	RoomsOpen int `json:"rooms_open"`
+	// Sessions is the number of signed-on sessions.
+	Sessions int `json:"sessions"`
+}
+
+// statsDay returns the UTC date that daily stats for t are recorded under.
+func statsDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// StatsSnapshot returns the stored stats for the UTC day of now. Totals are
+// kept up to date by triggers, so it reads a handful of rows regardless of
+// the size of the database. RoomsOpen and Sessions are left for the caller
+// to fill in.
+func (us SQLiteUserStore) StatsSnapshot(ctx context.Context, now time.Time) (StatsSnapshot, error) {
+	snap := StatsSnapshot{Time: now}
+	q := `
+		SELECT
+			(SELECT value FROM statCounter WHERE name = 'users'),
+			(SELECT value FROM statCounter WHERE name = 'offlineMessages'),
+			COALESCE((SELECT actives FROM dailyStat WHERE day = ?), 0),
+			COALESCE((SELECT messages FROM dailyStat WHERE day = ?), 0),
+			COALESCE((SELECT peakSessions FROM dailyStat WHERE day = ?), 0)
+	`
+	day := statsDay(now)
+	err := us.db.QueryRowContext(ctx, q, day, day, day).Scan(
+		&snap.TotalUsers,
+		&snap.OfflineMessagesPending,
+		&snap.DailyActives,
+		&snap.MessagesToday,
+		&snap.PeakSessionsToday,
+	)
+	if err != nil {
+		return StatsSnapshot{}, fmt.Errorf("StatsSnapshot: %w", err)
+	}
+	return snap, nil
+}
+
+// RecordDailyActive counts the user as active on the UTC day of now. Each
+// user is counted once per day.
+func (us SQLiteUserStore) RecordDailyActive(ctx context.Context, screenName IdentScreenName, now time.Time) error {
+	q := `INSERT OR IGNORE INTO dailyActive (day, screenName) VALUES (?, ?)`
+	if _, err := us.db.ExecContext(ctx, q, statsDay(now), screenName.String()); err != nil {
+		return fmt.Errorf("RecordDailyActive: %w", err)
+	}
+	return nil
+}
+
+// AddDailyMessages adds count to the number of messages sent on the UTC day
+// of day.
+func (us SQLiteUserStore) AddDailyMessages(ctx context.Context, day time.Time, count int64) error {
+	q := `
+		INSERT INTO dailyStat (day, messages) VALUES (?, ?)
+		ON CONFLICT (day) DO UPDATE SET messages = messages + excluded.messages
+	`
+	if _, err := us.db.ExecContext(ctx, q, statsDay(day), count); err != nil {
+		return fmt.Errorf("AddDailyMessages: %w", err)
+	}
+	return nil
+}
+
+// RecordPeakSessions raises the peak concurrent sessions of the UTC day of
+// day to sessions, if it is higher.
+func (us SQLiteUserStore) RecordPeakSessions(ctx context.Context, day time.Time, sessions int64) error {
+	q := `
+		INSERT INTO dailyStat (day, peakSessions) VALUES (?, ?)
+		ON CONFLICT (day) DO UPDATE SET peakSessions = MAX(peakSessions, excluded.peakSessions)
+	`
+	if _, err := us.db.ExecContext(ctx, q, statsDay(day), sessions); err != nil {
+		return fmt.Errorf("RecordPeakSessions: %w", err)
+	}
+	return nil
+}
+
+// DailyStat holds the activity totals of a UTC day.
+type DailyStat struct {
+	// Day is the UTC date, such as 2024-01-31.
+	Day string `json:"day"`
+	// Actives is the number of unique users who signed on.
+	Actives int64 `json:"actives"`
+	// Messages is the number of instant messages sent.
+	Messages int64 `json:"messages"`
+	// PeakSessions is the highest number of concurrent sessions.
+	PeakSessions int64 `json:"peak_sessions"`
+}
+
+// DailyStats returns the stored totals of the UTC days from the day of from
+// to the day of to, inclusive, oldest first. Days without activity are
+// omitted.
+func (us SQLiteUserStore) DailyStats(ctx context.Context, from, to time.Time) ([]DailyStat, error) {
+	q := `
+		SELECT day, actives, messages, peakSessions
+		FROM dailyStat
+		WHERE day BETWEEN ? AND ?
+		ORDER BY day
+	`
+	rows, err := us.db.QueryContext(ctx, q, statsDay(from), statsDay(to))
+	if err != nil {
+		return nil, fmt.Errorf("DailyStats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []DailyStat
+	for rows.Next() {
+		stat := DailyStat{}
+		if err := rows.Scan(&stat.Day, &stat.Actives, &stat.Messages, &stat.PeakSessions); err != nil {
+			return nil, fmt.Errorf("DailyStats: %w", err)
+		}
+		stats = append(stats, stat)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("DailyStats: %w", err)
+	}
+	return stats, nil
+}
+
+// PruneDailyActives deletes the sign-on records of days before the UTC day
+// of now. The daily totals they contributed to are kept.
+func (us SQLiteUserStore) PruneDailyActives(ctx context.Context, now time.Time) error {
+	if _, err := us.db.ExecContext(ctx, `DELETE FROM dailyActive WHERE day < ?`, statsDay(now)); err != nil {
+		return fmt.Errorf("PruneDailyActives: %w", err)
+	}
+	return nil
+}
+
+// ServerStatsStore persists the stats served by ServerStats.
+type ServerStatsStore interface {
+	StatsSnapshot(ctx context.Context, now time.Time) (StatsSnapshot, error)
+	RecordDailyActive(ctx context.Context, screenName IdentScreenName, now time.Time) error
+	AddDailyMessages(ctx context.Context, day time.Time, count int64) error
+	RecordPeakSessions(ctx context.Context, day time.Time, sessions int64) error
+	DailyStats(ctx context.Context, from, to time.Time) ([]DailyStat, error)
+	PruneDailyActives(ctx context.Context, now time.Time) error
+}
+
+// RoomCounter reports the number of chat rooms with occupants.
+type RoomCounter interface {
+	RoomCount() int
+}
+
+// ServerStats collects and serves aggregate server stats. Messages and peak
+// concurrent sessions are counted in memory and written to the store in
+// batches by Flush, so that counting doesn't add a write per message or
+// sign-on. A ServerStats is safe for
+// concurrent use by multiple goroutines.
+type ServerStats struct {
+	store    ServerStatsStore
+	sessions SessionCounter
+	rooms    RoomCounter
+	logger   *slog.Logger
+	nowFn    func() time.Time
+	mutex    sync.Mutex
+	// messages holds the message counts not yet flushed, keyed by day.
+	messages map[string]int64
+	// peaks holds the peak concurrent sessions not yet flushed, keyed by
+	// day.
+	peaks map[string]int64
+}
+
+// NewServerStats creates a new instance of ServerStats.
+func NewServerStats(store ServerStatsStore, sessions SessionCounter, rooms RoomCounter, logger *slog.Logger) *ServerStats {
+	return &ServerStats{
+		store:    store,
+		sessions: sessions,
+		rooms:    rooms,
+		logger:   logger,
+		nowFn:    time.Now,
+		messages: make(map[string]int64),
+		peaks:    make(map[string]int64),
+	}
+}
+
+// SignOn counts the user as active today and samples the number of
+// concurrent sessions for today's peak. It should be called once the user's
+// sign-on is complete and their session is registered. The session count
+// only grows at sign-on, so sampling it there catches every peak.
+func (s *ServerStats) SignOn(ctx context.Context, screenName IdentScreenName) {
+	now := s.nowFn()
+	if err := s.store.RecordDailyActive(ctx, screenName, now); err != nil {
+		s.logger.ErrorContext(ctx, "unable to record daily active user", "err", err)
+	}
+
+	day := statsDay(now)
+	count := int64(s.sessions.SessionCount())
+	s.mutex.Lock()
+	s.peaks[day] = max(s.peaks[day], count)
+	s.mutex.Unlock()
+}
+
+// MessageSent counts an instant message sent now.
+func (s *ServerStats) MessageSent() {
+	day := statsDay(s.nowFn())
+	s.mutex.Lock()
+	s.messages[day]++
+	s.mutex.Unlock()
+}
+
+// Flush writes the message counts and peak sessions collected since the
+// last flush to the store. Counts that fail to be written are kept for the
+// next flush.
+func (s *ServerStats) Flush(ctx context.Context) error {
+	s.mutex.Lock()
+	pending, peaks := s.messages, s.peaks
+	s.messages = make(map[string]int64)
+	s.peaks = make(map[string]int64)
+	s.mutex.Unlock()
+
+	var err error
+	for day, count := range pending {
+		t, _ := time.Parse(time.DateOnly, day)
+		if err = s.store.AddDailyMessages(ctx, t, count); err != nil {
+			s.mutex.Lock()
+			s.messages[day] += count
+			s.mutex.Unlock()
+		}
+	}
+	for day, peak := range peaks {
+		t, _ := time.Parse(time.DateOnly, day)
+		if err = s.store.RecordPeakSessions(ctx, t, peak); err != nil {
+			s.mutex.Lock()
+			s.peaks[day] = max(s.peaks[day], peak)
+			s.mutex.Unlock()
+		}
+	}
+	return err
+}
+
+// Schedule adds a "stats_flush" job to s that flushes message counts and
+// prunes old sign-on records every interval.
+func (s *ServerStats) Schedule(sched *Scheduler, interval time.Duration) error {
+	return sched.Add("stats_flush", interval, interval/10, func(ctx context.Context) error {
+		if err := s.Flush(ctx); err != nil {
+			return err
+		}
+		return s.store.PruneDailyActives(ctx, s.nowFn())
+	})
+}
+
+// Snapshot returns the current stats, including messages not yet flushed.
+func (s *ServerStats) Snapshot(ctx context.Context) (StatsSnapshot, error) {
+	now := s.nowFn()
+	snap, err := s.store.StatsSnapshot(ctx, now)
+	if err != nil {
+		return StatsSnapshot{}, err
+	}
+
+	s.mutex.Lock()
+	snap.MessagesToday += s.messages[statsDay(now)]
+	snap.PeakSessionsToday = max(snap.PeakSessionsToday, s.peaks[statsDay(now)])
+	s.mutex.Unlock()
+
+	snap.Sessions = s.sessions.SessionCount()
+	snap.RoomsOpen = s.rooms.RoomCount()
+	return snap, nil
+}
+
+// Handler serves the current StatsSnapshot as JSON.
+func (s *ServerStats) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		snap, err := s.Snapshot(r.Context())
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "unable to take stats snapshot", "err", err)
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+			return
+		}
+		writeHealthJSON(w, http.StatusOK, snap)
+	}
+}
+
+// StatsHistoryMaxDays is the most days of history served by HistoryHandler.
+const StatsHistoryMaxDays = 366
+
+// History returns the totals of the last days UTC days, including today,
+// oldest first. Counts not yet flushed are included.
+func (s *ServerStats) History(ctx context.Context, days int) ([]DailyStat, error) {
+	now := s.nowFn()
+	from := now.AddDate(0, 0, 1-days)
+	stats, err := s.store.DailyStats(ctx, from, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// add the days that only have counts not yet flushed
+	first := statsDay(from)
+	seen := make(map[string]bool, len(stats))
+	for _, stat := range stats {
+		seen[stat.Day] = true
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, pending := range []map[string]int64{s.messages, s.peaks} {
+		for day := range pending {
+			if !seen[day] && day >= first {
+				seen[day] = true
+				stats = append(stats, DailyStat{Day: day})
+			}
+		}
+	}
+	for i := range stats {
+		stats[i].Messages += s.messages[stats[i].Day]
+		stats[i].PeakSessions = max(stats[i].PeakSessions, s.peaks[stats[i].Day])
+	}
+	slices.SortFunc(stats, func(a, b DailyStat) int { return strings.Compare(a.Day, b.Day) })
+	return stats, nil
+}
+
+// HistoryHandler serves the daily totals returned by History as JSON. The
+// "days" query parameter sets how many days to return, from 1 to
+// StatsHistoryMaxDays; the default is 30.
+func (s *ServerStats) HistoryHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		days := 30
+		if v := r.URL.Query().Get("days"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > StatsHistoryMaxDays {
+				http.Error(w, fmt.Sprintf("days must be between 1 and %d.", StatsHistoryMaxDays), http.StatusBadRequest)
+				return
+			}
+			days = n
+		}
+
+		stats, err := s.History(r.Context(), days)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "unable to read stats history", "err", err)
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+			return
+		}
+		if stats == nil {
+			stats = []DailyStat{}
+		}
+		writeHealthJSON(w, http.StatusOK, stats)
+	}
+}
@@ -0,0 +1,203 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+type fakeCounter int
+
+func (c fakeCounter) SessionCount() int { return int(c) }
+func (c fakeCounter) RoomCount() int    { return int(c) }
+
+func TestSQLiteUserStore_StatsSnapshot(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	now := time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC)
+
+	for _, name := range []DisplayScreenName{"alice", "bob", "carol"} {
+		u, err := NewStubUser(name)
+		require.NoError(t, err)
+		require.NoError(t, f.InsertUser(ctx, u))
+	}
+	require.NoError(t, f.DeleteUser(ctx, NewIdentScreenName("carol")))
+
+	for _, recip := range []string{"alice", "alice", "bob"} {
+		_, err := f.SaveMessage(ctx, OfflineMessage{
+			Sender:    NewIdentScreenName("bob"),
+			Recipient: NewIdentScreenName(recip),
+			Message:   wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{},
+			Sent:      now,
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.DeleteMessages(ctx, NewIdentScreenName("bob")))
+
+	require.NoError(t, f.RecordDailyActive(ctx, NewIdentScreenName("alice"), now))
+	require.NoError(t, f.RecordDailyActive(ctx, NewIdentScreenName("alice"), now))
+	require.NoError(t, f.RecordDailyActive(ctx, NewIdentScreenName("bob"), now))
+	require.NoError(t, f.RecordDailyActive(ctx, NewIdentScreenName("bob"), now.Add(-24*time.Hour)))
+
+	require.NoError(t, f.AddDailyMessages(ctx, now, 5))
+	require.NoError(t, f.AddDailyMessages(ctx, now, 2))
+	require.NoError(t, f.AddDailyMessages(ctx, now.Add(-24*time.Hour), 100))
+
+	require.NoError(t, f.RecordPeakSessions(ctx, now, 12))
+	require.NoError(t, f.RecordPeakSessions(ctx, now, 9))
+
+	snap, err := f.StatsSnapshot(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, StatsSnapshot{
+		Time:                   now,
+		TotalUsers:             2,
+		DailyActives:           2,
+		MessagesToday:          7,
+		PeakSessionsToday:      12,
+		OfflineMessagesPending: 2,
+	}, snap)
+
+	stats, err := f.DailyStats(ctx, now.Add(-48*time.Hour), now)
+	require.NoError(t, err)
+	assert.Equal(t, []DailyStat{
+		{Day: "2024-01-30", Actives: 1, Messages: 100},
+		{Day: "2024-01-31", Actives: 2, Messages: 7, PeakSessions: 12},
+	}, stats)
+
+	// the next day starts from zero
+	snap, err = f.StatsSnapshot(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, snap.DailyActives)
+	assert.Zero(t, snap.MessagesToday)
+
+	// pruning keeps the daily totals and today's records
+	require.NoError(t, f.PruneDailyActives(ctx, now))
+	require.NoError(t, f.RecordDailyActive(ctx, NewIdentScreenName("alice"), now))
+	snap, err = f.StatsSnapshot(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), snap.DailyActives)
+	snap, err = f.StatsSnapshot(ctx, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), snap.DailyActives)
+}
+
+func TestServerStats(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	u, err := NewStubUser("alice")
+	require.NoError(t, err)
+	require.NoError(t, f.InsertUser(ctx, u))
+
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	s := NewServerStats(f, fakeCounter(4), fakeCounter(3), slog.Default())
+	s.nowFn = func() time.Time { return now }
+
+	s.SignOn(ctx, u.IdentScreenName)
+	s.MessageSent()
+	s.MessageSent()
+
+	snap, err := s.Snapshot(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), snap.TotalUsers)
+	assert.Equal(t, int64(1), snap.DailyActives)
+	assert.Equal(t, int64(2), snap.MessagesToday)
+	assert.Equal(t, 4, snap.Sessions)
+	assert.Equal(t, 3, snap.RoomsOpen)
+
+	// flushed counts aren't counted twice
+	require.NoError(t, s.Flush(ctx))
+	s.MessageSent()
+
+	rec := httptest.NewRecorder()
+	s.Handler()(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	have := StatsSnapshot{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&have))
+	assert.Equal(t, int64(3), have.MessagesToday)
+	assert.Equal(t, int64(4), have.PeakSessionsToday)
+}
+
+func TestServerStats_History(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	now := time.Date(2024, 1, 30, 12, 0, 0, 0, time.UTC)
+	sessions := fakeCounter(7)
+	s := NewServerStats(f, &sessions, fakeCounter(0), slog.Default())
+	s.nowFn = func() time.Time { return now }
+
+	s.SignOn(ctx, NewIdentScreenName("alice"))
+	sessions = 3
+	s.SignOn(ctx, NewIdentScreenName("bob"))
+	s.MessageSent()
+	require.NoError(t, s.Flush(ctx))
+
+	// the next day has only counts that are not flushed yet
+	now = now.Add(24 * time.Hour)
+	s.MessageSent()
+	s.SignOn(ctx, NewIdentScreenName("alice"))
+
+	stats, err := s.History(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []DailyStat{
+		{Day: "2024-01-30", Actives: 2, Messages: 1, PeakSessions: 7},
+		{Day: "2024-01-31", Actives: 1, Messages: 1, PeakSessions: 3},
+	}, stats)
+
+	stats, err = s.History(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, stats, 1)
+
+	rec := httptest.NewRecorder()
+	s.HistoryHandler()(rec, httptest.NewRequest(http.MethodGet, "/stats/history?days=7", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var have []DailyStat
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&have))
+	assert.Equal(t, stats[0], have[1])
+
+	rec = httptest.NewRecorder()
+	s.HistoryHandler()(rec, httptest.NewRequest(http.MethodGet, "/stats/history?days=0", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestInMemoryChatSessionManager_RoomCount(t *testing.T) {
+	sm := NewInMemoryChatSessionManager(slog.Default())
+	assert.Zero(t, sm.RoomCount())
+
+	sess, err := sm.AddSession(context.Background(), "room-1", "alice")
+	require.NoError(t, err)
+	_, err = sm.AddSession(context.Background(), "room-2", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, 2, sm.RoomCount())
+
+	sm.RemoveSession(sess)
+	assert.Equal(t, 1, sm.RoomCount())
+}
@@ -1,8 +1,11 @@
 package state
 
 import (
+	"math"
+	"math/rand/v2"
 	"net/netip"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pchchv/go-icq/wire"
@@ -17,6 +20,18 @@ const (
 	SessSendClosed
 )
 
+const (
+	// MaxIdleDuration is the longest idle duration a client may report. It is
+	// the largest value representable by the OServiceUserInfoIdleTime TLV.
+	MaxIdleDuration = math.MaxUint16 * time.Minute
+	// IdleNotificationInterval is the minimum amount of time that must pass
+	// between idle notifications accepted from a client.
+	IdleNotificationInterval = 5 * time.Second
+	// DefaultSessionQueueDepth is the number of outbound messages a session
+	// buffers before its client is considered a slow consumer.
+	DefaultSessionQueueDepth = 1000
+)
+
 // SessSendStatus is the result of sending a message to a user.
 type SessSendStatus int
 
@@ -47,16 +62,26 @@ type RateClassState struct {
 // all methods may be safely accessed by multiple goroutines.
 type Session struct {
 	awayMessage             string
+	awayMessageTime         time.Time
 	buddyIcon               wire.BARTID
 	caps                    [][16]byte
+	capPolicy               *CapPolicy
 	chatRoomCookie          string
 	clientID                string
 	closed                  bool
+	disconnectReason        DisconnectReason
 	displayScreenName       DisplayScreenName
 	foodGroupVersions       [wire.MDir + 1]uint16
+	guest                   bool
+	icqMood                 uint8
+	hasICQMood              bool
+	id                      uint64
 	identScreenName         IdentScreenName
 	idle                    bool
 	idleTime                time.Time
+	lastActivity            time.Time
+	lastIdleNotification    time.Time
+	lastICBMSent            time.Time
 	lastObservedStates      [5]RateClassState
 	msgCh                   chan wire.SNACMessage
 	multiConnFlag           wire.MultiConnFlag
@@ -65,6 +90,7 @@ type Session struct {
 	nowFn                   func() time.Time
 	rateLimitStates         [5]RateClassState
 	rateLimitStatesOriginal [5]RateClassState
+	recentICBMs             map[icbmDedupKey]time.Time
 	remoteAddr              *netip.AddrPort
 	signonComplete          bool
 	signonTime              time.Time
@@ -76,20 +102,27 @@ type Session struct {
 	warning                 uint16
 	warningCh               chan uint16
 	lastWarnUpdate          time.Time
+	maxQueueDepth           int
+	peakQueueDepth          atomic.Int64
+	probationUntil          time.Time
+	slowConsumer            atomic.Bool
+	snacHistory             *SNACHistory
 	profile                 UserProfile
 	memberSince             time.Time
 	offlineMsgCount         int
 }
 
 // NewSession returns a new instance of Session.
-// By default, the user may have up to 1000 pending messages before blocking.
+// By default, the user may have up to DefaultSessionQueueDepth pending
+// messages before blocking.
 func NewSession() *Session {
 	now := time.Now()
 	return &Session{
-		msgCh:             make(chan wire.SNACMessage, 1000),
+		msgCh:             make(chan wire.SNACMessage, DefaultSessionQueueDepth),
 		nowFn:             time.Now,
 		stopCh:            make(chan struct{}),
 		signonTime:        now,
+		id:                rand.Uint64(),
 		caps:              make([][16]byte, 0),
 		userInfoBitmask:   wire.OServiceUserFlagOSCARFree,
 		userStatusBitmask: wire.OServiceUserStatusAvailable,
@@ -132,10 +165,15 @@ func (s *Session) SetRemoteAddr(remoteAddr *netip.AddrPort) {
 	s.remoteAddr = remoteAddr
 }
 
-// SetAwayMessage sets the user's away message.
+// SetAwayMessage sets the user's away message. Changing the message
+// records the time of the change, which is reported in the user info so
+// that watchers know to fetch the new message.
 func (s *Session) SetAwayMessage(awayMessage string) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	if awayMessage != s.awayMessage {
+		s.awayMessageTime = s.nowFn()
+	}
 	s.awayMessage = awayMessage
 }
 
@@ -222,6 +260,74 @@ func (s *Session) SetKerberosAuth(enabled bool) {
 	s.kerberosAuth = enabled
 }
 
+// SetGuest marks the session as an ephemeral chat-only guest session.
+func (s *Session) SetGuest(guest bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.guest = guest
+}
+
+// SetProbationUntil puts the session's account on probation until t. See
+// ProbationPolicy.
+func (s *Session) SetProbationUntil(t time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.probationUntil = t
+}
+
+// OnProbation indicates whether the session's account is still on
+// probation. Probation lifts by itself once its time has passed, even
+// mid-session.
+func (s *Session) OnProbation() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.nowFn().Before(s.probationUntil)
+}
+
+// SetMaxQueueDepth lowers the number of outbound messages the session buffers
+// before RelayMessage reports SessQueueFull. Values that are zero or exceed
+// the queue's capacity use the full capacity.
+func (s *Session) SetMaxQueueDepth(depth int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.maxQueueDepth = depth
+}
+
+// SetSNACHistorySize starts keeping the last size SNACs exchanged with the
+// client, discarding any SNACs already kept. Zero stops keeping them.
+func (s *Session) SetSNACHistorySize(size int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if size > 0 {
+		s.snacHistory = NewSNACHistory(size)
+	} else {
+		s.snacHistory = nil
+	}
+}
+
+// RecordSNAC adds msg to the session's SNAC history, if it keeps one.
+// Handlers call it for each SNAC read from or written to the client.
+func (s *Session) RecordSNAC(dir SNACDirection, msg wire.SNACMessage) {
+	s.mutex.RLock()
+	h, now := s.snacHistory, s.nowFn
+	s.mutex.RUnlock()
+	if h != nil {
+		h.Record(now(), dir, msg)
+	}
+}
+
+// SNACHistory returns the last SNACs exchanged with the client, oldest
+// first. It's empty unless SetSNACHistorySize was called.
+func (s *Session) SNACHistory() []SNACRecord {
+	s.mutex.RLock()
+	h := s.snacHistory
+	s.mutex.RUnlock()
+	if h == nil {
+		return nil
+	}
+	return h.Records()
+}
+
 // SetFoodGroupVersions sets the client's supported food group versions
 func (s *Session) SetFoodGroupVersions(versions [wire.MDir + 1]uint16) {
 	s.mutex.Lock()
@@ -280,13 +386,32 @@ func (s *Session) SetDisplayScreenName(displayScreenName DisplayScreenName) {
 	s.displayScreenName = displayScreenName
 }
 
-// SetIdle sets the user's idle state.
-func (s *Session) SetIdle(dur time.Duration) {
+// SetIdle sets the user's idle state. The client-reported duration is
+// clamped to MaxIdleDuration, and the resulting idle start time never
+// precedes sign-on or the last activity observed by the server. Idle
+// notifications that arrive within IdleNotificationInterval of the last
+// accepted one are ignored; SetIdle reports whether the notification was
+// accepted, so that callers only announce accepted changes to buddies.
+func (s *Session) SetIdle(dur time.Duration) bool {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	now := s.nowFn()
+	if !s.lastIdleNotification.IsZero() && now.Sub(s.lastIdleNotification) < IdleNotificationInterval {
+		return false
+	}
+	s.lastIdleNotification = now
 	s.idle = true
+	dur = min(max(dur, 0), MaxIdleDuration)
 	// set the time the user became idle
-	s.idleTime = s.nowFn().Add(-dur)
+	idleTime := now.Add(-dur)
+	if idleTime.Before(s.signonTime) {
+		idleTime = s.signonTime
+	}
+	if idleTime.Before(s.lastActivity) {
+		idleTime = s.lastActivity
+	}
+	s.idleTime = idleTime
+	return true
 }
 
 // SetBuddyIcon stores the session's buddy icon metadata.
@@ -296,6 +421,23 @@ func (s *Session) SetBuddyIcon(icon wire.BARTID) {
 	s.buddyIcon = icon
 }
 
+// SetICQMood stores the ICQ mood shown next to the user on their buddies'
+// contact lists.
+func (s *Session) SetICQMood(mood uint8) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.icqMood = mood
+	s.hasICQMood = true
+}
+
+// ClearICQMood removes the session's ICQ mood.
+func (s *Session) ClearICQMood() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.icqMood = 0
+	s.hasICQMood = false
+}
+
 // SetClientID sets the client ID.
 func (s *Session) SetClientID(clientID string) {
 	s.mutex.Lock()
@@ -367,6 +509,32 @@ func (s *Session) TypingEventsEnabled() bool {
 	return s.typingEventsEnabled
 }
 
+// Guest indicates whether this is an ephemeral chat-only guest session.
+func (s *Session) Guest() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.guest
+}
+
+// QueueDepth returns the number of outbound messages waiting to be read by
+// the client.
+func (s *Session) QueueDepth() int {
+	return len(s.msgCh)
+}
+
+// PeakQueueDepth returns the highest outbound queue depth seen during the
+// session.
+func (s *Session) PeakQueueDepth() int {
+	return int(s.peakQueueDepth.Load())
+}
+
+// SlowConsumer indicates whether a message was dropped because the client
+// stopped reading and its outbound queue filled up. The connection handler
+// should send the client a sign-off frame and disconnect it.
+func (s *Session) SlowConsumer() bool {
+	return s.slowConsumer.Load()
+}
+
 // KerberosAuth indicates whether Kerberos authentication was used for this session.
 func (s *Session) KerberosAuth() bool {
 	s.mutex.RLock()
@@ -402,6 +570,13 @@ func (s *Session) MultiConnFlag() wire.MultiConnFlag {
 	return s.multiConnFlag
 }
 
+// ID returns a random identifier that distinguishes this session from
+// other sessions of the same screen name. It doesn't change for the life of
+// the session.
+func (s *Session) ID() uint64 {
+	return s.id
+}
+
 // SignonTime reports when the user signed on
 func (s *Session) SignonTime() time.Time {
 	s.mutex.RLock()
@@ -446,11 +621,20 @@ func (s *Session) Idle() bool {
 	return s.idle
 }
 
-// IdleTime reports when the user went idle
+// IdleTime reports when the user went idle. If the server observed activity
+// after the client reported going idle, the time of that activity is returned.
 func (s *Session) IdleTime() time.Time {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
-	return s.idleTime
+	return s.idleSince()
+}
+
+// LastActivity reports the last time the server observed activity from the
+// user, which is when the user last sent an IM that passed ICBMParams.CheckICBM.
+func (s *Session) LastActivity() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.lastActivity
 }
 
 // UnsetIdle removes the user's idle state.
@@ -470,6 +654,14 @@ func (s *Session) BuddyIcon() (wire.BARTID, bool) {
 	return icon, icon.Type != 0
 }
 
+// ICQMood returns the session's ICQ mood and reports whether it has been
+// set.
+func (s *Session) ICQMood() (uint8, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.icqMood, s.hasICQMood
+}
+
 // ClientID retrieves the client ID.
 func (s *Session) ClientID() string {
 	s.mutex.RLock()
@@ -569,7 +761,8 @@ func (s *Session) SubscribeRateLimits(classes []wire.RateLimitClassID) {
 
 // EvaluateRateLimit checks and updates the
 // session’s rate limit state for the given rate class ID.
-// If the rate status reaches 'disconnect', the session is closed.
+// If the rate status reaches 'disconnect', the session is closed with
+// DisconnectRateLimited.
 // Rate limits are not enforced if the user is a bot
 // (has wire.OServiceUserFlagBot set in their user info bitmask).
 func (s *Session) EvaluateRateLimit(now time.Time, rateClassID wire.RateLimitClassID) wire.RateLimitStatus {
@@ -587,6 +780,9 @@ func (s *Session) EvaluateRateLimit(now time.Time, rateClassID wire.RateLimitCla
 	rateClass.LastTime = now
 	rateClass.LimitedNow = status == wire.RateLimitStatusLimited
 	if status == wire.RateLimitStatusDisconnect {
+		if !s.closed {
+			s.disconnectReason = DisconnectRateLimited
+		}
 		s.close()
 	}
 
@@ -634,12 +830,29 @@ func (s *Session) ObserveRateChanges(now time.Time) (classDelta []RateClassState
 // It is not possible to re-open message relaying once closed.
 // It is safe to call from multiple go routines.
 func (s *Session) Close() {
+	s.CloseWithReason(DisconnectSignoff)
+}
+
+// CloseWithReason closes the session like Close and records why. Only the
+// reason given by the first call is kept.
+func (s *Session) CloseWithReason(reason DisconnectReason) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	if !s.closed {
+		s.disconnectReason = reason
+	}
 	s.close()
 }
 
+// DisconnectReason returns why the session was closed, or DisconnectNone if
+// it's still open.
+func (s *Session) DisconnectReason() DisconnectReason {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.disconnectReason
+}
+
 // Closed blocks until the session is closed.
 func (s *Session) Closed() <-chan struct{} {
 	return s.stopCh
@@ -665,12 +878,24 @@ func (s *Session) RelayMessage(msg wire.SNACMessage) SessSendStatus {
 		return SessSendClosed
 	}
 
+	if s.maxQueueDepth > 0 && len(s.msgCh) >= s.maxQueueDepth {
+		s.slowConsumer.Store(true)
+		return SessQueueFull
+	}
+
 	select {
 	case s.msgCh <- msg:
+		depth := int64(len(s.msgCh))
+		for peak := s.peakQueueDepth.Load(); depth > peak; peak = s.peakQueueDepth.Load() {
+			if s.peakQueueDepth.CompareAndSwap(peak, depth) {
+				break
+			}
+		}
 		return SessSendOK
 	case <-s.stopCh:
 		return SessSendClosed
 	default:
+		s.slowConsumer.Store(true)
 		return SessQueueFull
 	}
 }
@@ -682,7 +907,7 @@ func (s *Session) TLVUserInfo() wire.TLVUserInfo {
 	defer s.mutex.RUnlock()
 
 	return wire.TLVUserInfo{
-		ScreenName:   string(s.displayScreenName),
+		ScreenName:   s.displayScreenName.Wire(),
 		WarningLevel: uint16(s.warning),
 		TLVBlock: wire.TLVBlock{
 			TLVList: s.userInfo(),
@@ -690,7 +915,7 @@ func (s *Session) TLVUserInfo() wire.TLVUserInfo {
 	}
 }
 
-// Invisible returns true if the user is idle.
+// Invisible returns true if the user is invisible.
 func (s *Session) Invisible() bool {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
@@ -706,11 +931,25 @@ func (s *Session) close() {
 	s.closed = true
 }
 
+// idleSince returns the later of the client-reported idle start time and the
+// last activity observed by the server.
+func (s *Session) idleSince() time.Time {
+	if s.lastActivity.After(s.idleTime) {
+		return s.lastActivity
+	}
+	return s.idleTime
+}
+
 func (s *Session) userInfo() wire.TLVList {
 	tlvs := wire.TLVList{}
 
 	// sign-in timestamp
-	tlvs.Append(wire.NewTLVBE(wire.OServiceUserInfoSignonTOD, uint32(s.signonTime.Unix())))
+	tlvs.Append(wire.NewTLVBE(wire.OServiceUserInfoSignonTOD, wire.EpochSeconds(s.signonTime)))
+
+	// account creation timestamp
+	if !s.memberSince.IsZero() {
+		tlvs.Append(wire.NewTLVBE(wire.OServiceUserInfoMemberSince, wire.EpochSeconds(s.memberSince)))
+	}
 
 	// user info flags
 	uFlags := s.userInfoBitmask
@@ -724,12 +963,19 @@ func (s *Session) userInfo() wire.TLVList {
 
 	// idle status
 	if s.idle {
-		tlvs.Append(wire.NewTLVBE(wire.OServiceUserInfoIdleTime, uint16(s.nowFn().Sub(s.idleTime).Minutes())))
+		tlvs.Append(wire.NewTLVBE(wire.OServiceUserInfoIdleTime, wire.DurationMinutes(s.nowFn().Sub(s.idleSince()))))
 	}
 
-	// set buddy icon metadata, if user has buddy icon
-	if bartID, hasIcon := s.BuddyIcon(); hasIcon {
-		tlvs.Append(wire.NewTLVBE(wire.OServiceUserInfoBARTInfo, bartID))
+	// set buddy icon and ICQ mood metadata, if the user has either
+	var bartIDs []wire.BARTID
+	if icon := s.buddyIcon; icon.Type != 0 {
+		bartIDs = append(bartIDs, icon)
+	}
+	if s.hasICQMood {
+		bartIDs = append(bartIDs, wire.NewICQMoodBARTID(s.icqMood))
+	}
+	if len(bartIDs) > 0 {
+		tlvs.Append(wire.NewTLVBE(wire.OServiceUserInfoBARTInfo, bartIDs))
 	}
 
 	// ICQ direct-connect info. The TLV is required for buddy arrival events to
@@ -739,8 +985,18 @@ func (s *Session) userInfo() wire.TLVList {
 	}
 
 	// capabilities (buddy icon, chat, etc...)
-	if len(s.caps) > 0 {
-		tlvs.Append(wire.NewTLVBE(wire.OServiceUserInfoOscarCaps, s.caps))
+	isICQ := s.userInfoBitmask&wire.OServiceUserFlagICQ == wire.OServiceUserFlagICQ
+	if caps := s.capPolicy.Apply(s.identScreenName, s.guest, isICQ, s.caps); len(caps) > 0 {
+		tlvs.Append(wire.NewTLVBE(wire.OServiceUserInfoOscarCaps, caps))
+	}
+
+	// profile and away message update times, which let clients know to
+	// re-fetch a cached profile or away message
+	if !s.profile.UpdateTime.IsZero() {
+		tlvs.Append(wire.NewTLVBE(wire.OServiceUserInfoSigTime, wire.EpochSeconds(s.profile.UpdateTime)))
+	}
+	if s.awayMessage != "" {
+		tlvs.Append(wire.NewTLVBE(wire.OServiceUserInfoAwayTime, wire.EpochSeconds(s.awayMessageTime)))
 	}
 
 	tlvs.Append(wire.NewTLVBE(wire.OServiceUserInfoMySubscriptions, uint32(0)))
@@ -0,0 +1,169 @@
+package state
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// SessionDetail is a snapshot of everything the server knows about a
+// signed-on user's session, for debugging one user's client through the
+// admin API.
+type SessionDetail struct {
+	ScreenName        string    `json:"screen_name"`
+	DisplayScreenName string    `json:"display_screen_name"`
+	SessionID         uint64    `json:"session_id"`
+	ClientID          string    `json:"client_id"`
+	RemoteAddr        string    `json:"remote_addr,omitempty"`
+	SignonTime        time.Time `json:"signon_time"`
+	SignonComplete    bool      `json:"signon_complete"`
+	KerberosAuth      bool      `json:"kerberos_auth"`
+	Guest             bool      `json:"guest"`
+	Away              bool      `json:"away"`
+	Invisible         bool      `json:"invisible"`
+	Warning           uint16    `json:"warning"`
+	// Idle indicates that the client reported the user as idle.
+	Idle bool `json:"idle"`
+	// IdleSeconds is how long the user has been idle, or 0 if they're not.
+	IdleSeconds int64 `json:"idle_seconds"`
+	// LastActivity is the last time the server observed activity from the
+	// user.
+	LastActivity time.Time `json:"last_activity,omitempty"`
+	// Services lists the services the user is connected to: "BOS" and
+	// "chat:" followed by the cookie of each chat room they're in.
+	Services []string `json:"services"`
+	// QueueDepth is the number of outbound messages the client hasn't read
+	// yet, out of QueueCapacity.
+	QueueDepth     int  `json:"queue_depth"`
+	QueueCapacity  int  `json:"queue_capacity"`
+	PeakQueueDepth int  `json:"peak_queue_depth"`
+	SlowConsumer   bool `json:"slow_consumer"`
+	// FoodGroupVersions maps the names of the food groups the client
+	// negotiated to their versions.
+	FoodGroupVersions map[string]uint16 `json:"food_group_versions"`
+	// RateClasses is the state of the user's rate limiter for each class.
+	RateClasses []RateClassDetail `json:"rate_classes"`
+}
+
+// RateClassDetail is the state of one of a session's rate limit classes.
+type RateClassDetail struct {
+	ID           wire.RateLimitClassID `json:"id"`
+	Status       string                `json:"status"`
+	CurrentLevel int32                 `json:"current_level"`
+	AlertLevel   int32                 `json:"alert_level"`
+	LimitLevel   int32                 `json:"limit_level"`
+	ClearLevel   int32                 `json:"clear_level"`
+	LimitedNow   bool                  `json:"limited_now"`
+	LastTime     time.Time             `json:"last_time,omitempty"`
+}
+
+// rateLimitStatusNames are the names of the rate limit statuses reported by
+// the admin API.
+var rateLimitStatusNames = map[wire.RateLimitStatus]string{
+	wire.RateLimitStatusLimited:    "limited",
+	wire.RateLimitStatusAlert:      "alert",
+	wire.RateLimitStatusClear:      "clear",
+	wire.RateLimitStatusDisconnect: "disconnect",
+}
+
+// InspectSession returns the detail of sess at now. chatRooms are the
+// cookies of the chat rooms the user is in.
+func InspectSession(sess *Session, chatRooms []string, now time.Time) SessionDetail {
+	d := SessionDetail{
+		ScreenName:        sess.IdentScreenName().String(),
+		DisplayScreenName: sess.DisplayScreenName().String(),
+		SessionID:         sess.ID(),
+		ClientID:          sess.ClientID(),
+		SignonTime:        sess.SignonTime(),
+		SignonComplete:    sess.SignonComplete(),
+		KerberosAuth:      sess.KerberosAuth(),
+		Guest:             sess.Guest(),
+		Away:              sess.AwayMessage() != "",
+		Invisible:         sess.Invisible(),
+		Warning:           sess.Warning(),
+		Idle:              sess.Idle(),
+		LastActivity:      sess.LastActivity(),
+		Services:          []string{"BOS"},
+		QueueDepth:        sess.QueueDepth(),
+		QueueCapacity:     cap(sess.ReceiveMessage()),
+		PeakQueueDepth:    sess.PeakQueueDepth(),
+		SlowConsumer:      sess.SlowConsumer(),
+		FoodGroupVersions: make(map[string]uint16),
+	}
+	if addr := sess.RemoteAddr(); addr != nil {
+		d.RemoteAddr = addr.String()
+	}
+	if d.Idle {
+		d.IdleSeconds = int64(now.Sub(sess.IdleTime()).Seconds())
+	}
+	for _, cookie := range chatRooms {
+		d.Services = append(d.Services, "chat:"+cookie)
+	}
+
+	for foodGroup, version := range sess.FoodGroupVersions() {
+		if version != 0 {
+			d.FoodGroupVersions[wire.FoodGroupName(uint16(foodGroup))] = version
+		}
+	}
+
+	for _, state := range sess.RateLimitStates() {
+		if state.ID == 0 {
+			continue
+		}
+		d.RateClasses = append(d.RateClasses, RateClassDetail{
+			ID:           state.ID,
+			Status:       rateLimitStatusNames[state.CurrentStatus],
+			CurrentLevel: state.CurrentLevel,
+			AlertLevel:   state.AlertLevel,
+			LimitLevel:   state.LimitLevel,
+			ClearLevel:   state.ClearLevel,
+			LimitedNow:   state.LimitedNow,
+			LastTime:     state.LastTime,
+		})
+	}
+
+	return d
+}
+
+// RoomsOf returns the cookies of the chat rooms that user is in, sorted.
+func (s *InMemoryChatSessionManager) RoomsOf(user IdentScreenName) []string {
+	s.mapMutex.RLock()
+	defer s.mapMutex.RUnlock()
+
+	var cookies []string
+	for cookie, sessionManager := range s.store {
+		if sessionManager.RetrieveSession(user) != nil {
+			cookies = append(cookies, cookie)
+		}
+	}
+	slices.Sort(cookies)
+	return cookies
+}
+
+// SessionDetailHandler serves the SessionDetail of the session of the user
+// named by the "screen_name" query parameter as JSON, for the admin API.
+// Chat rooms are looked up in chats, which may be nil. It responds 400 if
+// the parameter is missing and 404 if the user isn't signed on.
+func (s *InMemorySessionManager) SessionDetailHandler(chats *InMemoryChatSessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		screenName := NewIdentScreenName(r.URL.Query().Get("screen_name"))
+		if screenName.String() == "" {
+			http.Error(w, "Missing screen_name.", http.StatusBadRequest)
+			return
+		}
+
+		sess := s.RetrieveSession(screenName)
+		if sess == nil {
+			http.Error(w, "Session not found.", http.StatusNotFound)
+			return
+		}
+
+		var rooms []string
+		if chats != nil {
+			rooms = chats.RoomsOf(screenName)
+		}
+		writeHealthJSON(w, http.StatusOK, InspectSession(sess, rooms, time.Now()))
+	}
+}
@@ -0,0 +1,93 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestInMemorySessionManager_SessionDetailHandler(t *testing.T) {
+	ctx := context.Background()
+	sm := NewInMemorySessionManager(slog.Default())
+	chats := NewInMemoryChatSessionManager(slog.Default())
+
+	sess, err := sm.AddSession(ctx, "Alice")
+	require.NoError(t, err)
+	sess.SetSignonComplete()
+	sess.SetClientID("ICQ 2000b")
+	addr := netip.MustParseAddrPort("10.0.0.1:4000")
+	sess.SetRemoteAddr(&addr)
+	sess.SetIdle(5 * time.Minute)
+	sess.SetRateClasses(time.Now(), wire.DefaultRateLimitClasses())
+
+	var versions [wire.MDir + 1]uint16
+	versions[wire.OService] = 4
+	versions[wire.ICBM] = 1
+	sess.SetFoodGroupVersions(versions)
+
+	for _, member := range []struct {
+		cookie     string
+		screenName DisplayScreenName
+	}{
+		{"4-0-b", "Alice"},
+		{"4-0-a", "Alice"},
+		{"4-0-c", "Bob"},
+	} {
+		chatSess, err := chats.AddSession(ctx, member.cookie, member.screenName)
+		require.NoError(t, err)
+		chatSess.SetSignonComplete()
+	}
+
+	t.Run("inspect via admin API", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		sm.SessionDetailHandler(chats)(rec, httptest.NewRequest(http.MethodGet, "/session/detail?screen_name=alice", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var detail SessionDetail
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &detail))
+		assert.Equal(t, "alice", detail.ScreenName)
+		assert.Equal(t, "Alice", detail.DisplayScreenName)
+		assert.Equal(t, "ICQ 2000b", detail.ClientID)
+		assert.Equal(t, "10.0.0.1:4000", detail.RemoteAddr)
+		assert.True(t, detail.Idle)
+		// idle time is clamped to sign-on, which was just now
+		assert.Less(t, detail.IdleSeconds, int64(300))
+		assert.Equal(t, []string{"BOS", "chat:4-0-a", "chat:4-0-b"}, detail.Services)
+		assert.Equal(t, map[string]uint16{"OService": 4, "ICBM": 1}, detail.FoodGroupVersions)
+		assert.Zero(t, detail.QueueDepth)
+		assert.Positive(t, detail.QueueCapacity)
+		require.Len(t, detail.RateClasses, 5)
+		assert.Equal(t, wire.RateLimitClassID(1), detail.RateClasses[0].ID)
+		assert.Equal(t, "clear", detail.RateClasses[0].Status)
+	})
+
+	t.Run("without chat sessions", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		sm.SessionDetailHandler(nil)(rec, httptest.NewRequest(http.MethodGet, "/session/detail?screen_name=alice", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var detail SessionDetail
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &detail))
+		assert.Equal(t, []string{"BOS"}, detail.Services)
+	})
+
+	t.Run("unknown or missing screen name", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		sm.SessionDetailHandler(chats)(rec, httptest.NewRequest(http.MethodGet, "/session/detail?screen_name=nobody", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = httptest.NewRecorder()
+		sm.SessionDetailHandler(chats)(rec, httptest.NewRequest(http.MethodGet, "/session/detail", nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
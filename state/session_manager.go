@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pchchv/go-icq/wire"
@@ -22,26 +23,53 @@ type sessionSlot struct {
 // provides synchronized message relay between sessions in the session pool.
 // An InMemorySessionManager is safe for concurrent use by multiple goroutines.
 type InMemorySessionManager struct {
-	store    map[IdentScreenName]*sessionSlot
-	mapMutex sync.RWMutex
-	logger   *slog.Logger
+	store                   map[IdentScreenName]*sessionSlot
+	aliases                 map[IdentScreenName]IdentScreenName
+	links                   map[IdentScreenName]IdentScreenName
+	mapMutex                sync.RWMutex
+	logger                  *slog.Logger
+	maxQueueDepth           atomic.Int64
+	snacHistorySize         atomic.Int64
+	slowConsumerDisconnects atomic.Int64
+	duplicateICBMs          atomic.Int64
+	capPolicy               atomic.Pointer[CapPolicy]
+	moderation              *ModerationEvents
+	latency                 *DeliveryLatency
+}
+
+// SessionQueueStats summarizes the outbound message queues of all sessions.
+type SessionQueueStats struct {
+	// Sessions is the number of sessions in the pool.
+	Sessions int
+	// TotalDepth is the number of messages queued across all sessions.
+	TotalDepth int
+	// MaxDepth is the deepest queue among current sessions.
+	MaxDepth int
+	// MaxDepthScreenName is the owner of the deepest queue.
+	MaxDepthScreenName IdentScreenName
+	// SlowConsumerDisconnects is the number of sessions closed because
+	// their client stopped reading messages.
+	SlowConsumerDisconnects int64
 }
 
 // NewInMemorySessionManager creates a new instance of InMemorySessionManager.
 func NewInMemorySessionManager(logger *slog.Logger) *InMemorySessionManager {
 	return &InMemorySessionManager{
-		logger: logger,
-		store:  make(map[IdentScreenName]*sessionSlot),
+		logger:  logger,
+		store:   make(map[IdentScreenName]*sessionSlot),
+		aliases: make(map[IdentScreenName]IdentScreenName),
+		links:   make(map[IdentScreenName]IdentScreenName),
 	}
 }
 
-// RetrieveSession finds a session with a matching sessionID.
+// RetrieveSession finds a session with a matching sessionID. If screenName
+// is a registered alias, the primary account's session is returned.
 // Returns nil if session is not found.
 func (s *InMemorySessionManager) RetrieveSession(screenName IdentScreenName) *Session {
 	s.mapMutex.RLock()
 	defer s.mapMutex.RUnlock()
 
-	if rec, ok := s.store[screenName]; ok {
+	if rec, ok := s.store[s.resolveAlias(screenName)]; ok {
 		if rec.sess.SignonComplete() {
 			return rec.sess
 		}
@@ -85,6 +113,7 @@ func (s *InMemorySessionManager) retrieveByScreenNames(screenNames []IdentScreen
 	defer s.mapMutex.RUnlock()
 
 	for _, sn := range screenNames {
+		sn = s.resolveAlias(sn)
 		for _, rec := range s.store {
 			if rec.sess.SignonComplete() && sn == rec.sess.IdentScreenName() {
 				ret = append(ret, rec.sess)
@@ -104,7 +133,8 @@ func (s *InMemorySessionManager) AddSession(ctx context.Context, screenName Disp
 		s.mapMutex.Unlock()
 
 		// signal to callers that this session has to go
-		active.sess.Close()
+		s.logger.InfoContext(ctx, "disconnecting previous session", "screen_name", screenName, "reason", DisconnectNewLogin)
+		active.sess.CloseWithReason(DisconnectNewLogin)
 
 		select {
 		// wait for RemoveSession to be called
@@ -124,15 +154,59 @@ func (s *InMemorySessionManager) AddSession(ctx context.Context, screenName Disp
 		return nil, errSessConflict
 	}
 
+	return s.newSession(screenName), nil
+}
+
+// newSession creates a session for screenName with the session manager's
+// queue, history and capability settings and adds it to the session pool.
+// The caller must hold mapMutex.
+func (s *InMemorySessionManager) newSession(screenName DisplayScreenName) *Session {
 	sess := NewSession()
 	sess.SetIdentScreenName(screenName.IdentScreenName())
 	sess.SetDisplayScreenName(screenName)
+	sess.SetMaxQueueDepth(int(s.maxQueueDepth.Load()))
+	sess.SetSNACHistorySize(int(s.snacHistorySize.Load()))
+	sess.SetCapPolicy(s.capPolicy.Load())
 	s.store[sess.IdentScreenName()] = &sessionSlot{
 		sess:    sess,
 		removed: make(chan bool),
 	}
+	return sess
+}
 
-	return sess, nil
+// SetMaxQueueDepth sets the outbound queue bound applied to sessions added
+// from now on. A session whose client lets its queue grow past the bound is
+// disconnected as a slow consumer instead of blocking message relay.
+// Zero uses DefaultSessionQueueDepth.
+func (s *InMemorySessionManager) SetMaxQueueDepth(depth int) {
+	s.maxQueueDepth.Store(int64(depth))
+}
+
+// SetSNACHistorySize sets the number of SNACs kept in the history of
+// sessions added from now on. Zero keeps no history.
+func (s *InMemorySessionManager) SetSNACHistorySize(size int) {
+	s.snacHistorySize.Store(int64(size))
+}
+
+// QueueStats returns outbound queue metrics for the session pool.
+func (s *InMemorySessionManager) QueueStats() SessionQueueStats {
+	s.mapMutex.RLock()
+	defer s.mapMutex.RUnlock()
+
+	stats := SessionQueueStats{
+		Sessions:                len(s.store),
+		SlowConsumerDisconnects: s.slowConsumerDisconnects.Load(),
+	}
+	for _, rec := range s.store {
+		depth := rec.sess.QueueDepth()
+		stats.TotalDepth += depth
+		if depth > stats.MaxDepth {
+			stats.MaxDepth = depth
+			stats.MaxDepthScreenName = rec.sess.IdentScreenName()
+		}
+	}
+
+	return stats
 }
 
 // RemoveSession takes a session out of the session pool.
@@ -142,7 +216,20 @@ func (s *InMemorySessionManager) RemoveSession(sess *Session) {
 
 	if rec, ok := s.store[sess.IdentScreenName()]; ok && rec.sess == sess {
 		delete(s.store, sess.IdentScreenName())
+		s.removeAliases(sess.IdentScreenName())
 		close(rec.removed)
+		if reason := sess.DisconnectReason(); reason != DisconnectNone {
+			s.logger.Debug("removed session", "screen_name", sess.IdentScreenName(), "reason", reason)
+		}
+		if reason := sess.DisconnectReason(); reason.Abnormal() {
+			if history := sess.SNACHistory(); len(history) > 0 {
+				s.logger.Warn("abnormal disconnect", "screen_name", sess.IdentScreenName(), "reason", reason,
+					"snacs", FormatSNACHistory(history))
+			}
+		}
+		if sess.DisconnectReason() == DisconnectRateLimited {
+			s.moderation.FloodKick(context.Background(), sess)
+		}
 	}
 }
 
@@ -161,6 +248,21 @@ func (s *InMemorySessionManager) AllSessions() (sessions []*Session) {
 	return
 }
 
+// SessionCount returns the number of sessions that have completed sign-on.
+func (s *InMemorySessionManager) SessionCount() int {
+	s.mapMutex.RLock()
+	defer s.mapMutex.RUnlock()
+
+	count := 0
+	for _, rec := range s.store {
+		if rec.sess.SignonComplete() {
+			count++
+		}
+	}
+
+	return count
+}
+
 // Empty returns true if the session pool contains 0 sessions.
 func (s *InMemorySessionManager) Empty() bool {
 	s.mapMutex.RLock()
@@ -168,14 +270,17 @@ func (s *InMemorySessionManager) Empty() bool {
 	return len(s.store) == 0
 }
 
-func (s *InMemorySessionManager) maybeRelayMessage(ctx context.Context, msg wire.SNACMessage, sess *Session) {
-	switch sess.RelayMessage(msg) {
+func (s *InMemorySessionManager) maybeRelayMessage(ctx context.Context, msg wire.SNACMessage, sess *Session) SessSendStatus {
+	status := sess.RelayMessage(msg)
+	switch status {
 	case SessSendClosed:
 		s.logger.WarnContext(ctx, "can't send notification because the user's session is closed", "recipient", sess.IdentScreenName(), "message", msg)
 	case SessQueueFull:
-		s.logger.WarnContext(ctx, "can't send notification because queue is full", "recipient", sess.IdentScreenName(), "message", msg)
-		sess.Close()
+		s.logger.WarnContext(ctx, "disconnecting slow consumer because queue is full", "recipient", sess.IdentScreenName(), "queue_depth", sess.QueueDepth(), "message", msg)
+		s.slowConsumerDisconnects.Add(1)
+		sess.CloseWithReason(DisconnectSlowConsumer)
 	}
+	return status
 }
 
 func (s *InMemorySessionManager) findRec(identScreenName IdentScreenName) *sessionSlot {
@@ -192,16 +297,34 @@ func (s *InMemorySessionManager) findRec(identScreenName IdentScreenName) *sessi
 // It provides thread-safe operations to add,
 // remove, and manipulate sessions as well as relay messages to participants.
 type InMemoryChatSessionManager struct {
-	logger   *slog.Logger
-	mapMutex sync.RWMutex
-	store    map[string]*InMemorySessionManager
+	logger      *slog.Logger
+	mapMutex    sync.RWMutex
+	store       map[string]*InMemorySessionManager
+	batchMutex  sync.Mutex
+	batches     map[string]*chatPresenceBatch
+	batchWindow func(occupants int) time.Duration
+	// presence holds the users' main sessions, which OccupantInfo builds
+	// user info from. It's nil unless set with SetPresenceSource.
+	presence SessionRetriever
+	// transcripts holds recent messages for replay to users who join a
+	// room. Its lock must not be held while taking mapMutex.
+	transcripts chatTranscripts
+	// roomClosed, if set, is called when the last occupant of a room
+	// leaves. See SetRoomClosedFunc.
+	roomClosed func(cookie string)
 }
 
 // NewInMemoryChatSessionManager creates a new instance of InMemoryChatSessionManager.
 func NewInMemoryChatSessionManager(logger *slog.Logger) *InMemoryChatSessionManager {
 	return &InMemoryChatSessionManager{
-		store:  make(map[string]*InMemorySessionManager),
-		logger: logger,
+		store:       make(map[string]*InMemorySessionManager),
+		logger:      logger,
+		batches:     make(map[string]*chatPresenceBatch),
+		batchWindow: ChatBatchWindow,
+		transcripts: chatTranscripts{
+			entries: make(map[string][]chatTranscriptEntry),
+			nowFn:   time.Now,
+		},
 	}
 }
 
@@ -248,19 +371,33 @@ func (s *InMemoryChatSessionManager) AddSession(ctx context.Context, chatCookie
 // It panics if you attempt to remove the session twice.
 func (s *InMemoryChatSessionManager) RemoveSession(sess *Session) {
 	s.mapMutex.Lock()
-	defer s.mapMutex.Unlock()
 
 	sessionManager, ok := s.store[sess.ChatRoomCookie()]
 	if !ok {
+		s.mapMutex.Unlock()
 		panic("attempting to remove a session after its room has been deleted")
 	}
 	sessionManager.RemoveSession(sess)
 
-	if sessionManager.Empty() {
+	closed := sessionManager.Empty()
+	if closed {
 		delete(s.store, sess.ChatRoomCookie())
+		s.transcripts.discard(sess.ChatRoomCookie())
+	}
+	s.mapMutex.Unlock()
+
+	if closed && s.roomClosed != nil {
+		s.roomClosed(sess.ChatRoomCookie())
 	}
 }
 
+// RoomCount returns the number of chat rooms with at least one occupant.
+func (s *InMemoryChatSessionManager) RoomCount() int {
+	s.mapMutex.RLock()
+	defer s.mapMutex.RUnlock()
+	return len(s.store)
+}
+
 // AllSessions returns all chat room participants.
 // Returns ErrChatRoomNotFound if the room does not exist.
 func (s *InMemoryChatSessionManager) AllSessions(cookie string) []*Session {
@@ -286,11 +423,11 @@ func (s *InMemoryChatSessionManager) RelayToAllExcept(ctx context.Context, cooki
 		s.logger.Error("trying to relay message to all for non-existent room", "cookie", cookie)
 		return
 	} else {
-		for _, sess := range sessionManager.AllSessions() {
+		fanout(sessionManager.AllSessions(), func(sess *Session) {
 			if sess.IdentScreenName() != except {
 				sessionManager.maybeRelayMessage(ctx, msg, sess)
 			}
-		}
+		})
 	}
 }
 
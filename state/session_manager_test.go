@@ -595,3 +595,40 @@ func TestInMemoryChatSessionManager_RemoveUserFromAllChats(t *testing.T) {
 	assert.True(t, lookup[user2sess])
 
 }
+
+func TestInMemorySessionManager_QueueStats(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+	sm.SetMaxQueueDepth(2)
+
+	slow, err := sm.AddSession(context.Background(), "slow-user")
+	assert.NoError(t, err)
+	slow.SetSignonComplete()
+
+	fast, err := sm.AddSession(context.Background(), "fast-user")
+	assert.NoError(t, err)
+	fast.SetSignonComplete()
+
+	for range 2 {
+		sm.RelayToScreenName(context.Background(), slow.IdentScreenName(), wire.SNACMessage{})
+	}
+	sm.RelayToScreenName(context.Background(), fast.IdentScreenName(), wire.SNACMessage{})
+
+	stats := sm.QueueStats()
+	assert.Equal(t, 2, stats.Sessions)
+	assert.Equal(t, 3, stats.TotalDepth)
+	assert.Equal(t, 2, stats.MaxDepth)
+	assert.Equal(t, slow.IdentScreenName(), stats.MaxDepthScreenName)
+	assert.Zero(t, stats.SlowConsumerDisconnects)
+
+	// the slow client's queue is at its bound, so the next message
+	// disconnects it instead of blocking
+	sm.RelayToScreenName(context.Background(), slow.IdentScreenName(), wire.SNACMessage{})
+
+	select {
+	case <-slow.Closed():
+	default:
+		t.Fatal("expected slow consumer session to be closed")
+	}
+	assert.True(t, slow.SlowConsumer())
+	assert.Equal(t, int64(1), sm.QueueStats().SlowConsumerDisconnects)
+}
@@ -157,6 +157,7 @@ func TestSession_TLVUserInfo(t *testing.T) {
 			givenSessionFn: func() *Session {
 				s := NewSession()
 				s.SetSignonTime(time.Unix(1, 0))
+				s.nowFn = func() time.Time { return time.Unix(2, 0) }
 				s.SetAwayMessage("here's my away message")
 				return s
 			},
@@ -166,6 +167,27 @@ func TestSession_TLVUserInfo(t *testing.T) {
 						wire.NewTLVBE(wire.OServiceUserInfoSignonTOD, uint32(1)),
 						wire.NewTLVBE(wire.OServiceUserInfoUserFlags, uint16(0x30)),
 						wire.NewTLVBE(wire.OServiceUserInfoStatus, uint32(0x0000)),
+						wire.NewTLVBE(wire.OServiceUserInfoAwayTime, uint32(2)),
+						wire.NewTLVBE(wire.OServiceUserInfoMySubscriptions, uint32(0)),
+					},
+				},
+			},
+		},
+		{
+			name: "user has profile set",
+			givenSessionFn: func() *Session {
+				s := NewSession()
+				s.SetSignonTime(time.Unix(1, 0))
+				s.SetProfile(UserProfile{ProfileText: "hello", UpdateTime: time.Unix(3, 0)})
+				return s
+			},
+			want: wire.TLVUserInfo{
+				TLVBlock: wire.TLVBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.OServiceUserInfoSignonTOD, uint32(1)),
+						wire.NewTLVBE(wire.OServiceUserInfoUserFlags, uint16(0x10)),
+						wire.NewTLVBE(wire.OServiceUserInfoStatus, uint32(0x0000)),
+						wire.NewTLVBE(wire.OServiceUserInfoSigTime, uint32(3)),
 						wire.NewTLVBE(wire.OServiceUserInfoMySubscriptions, uint32(0)),
 					},
 				},
@@ -358,6 +380,26 @@ func TestSession_SendMessage_SessQueueFull(t *testing.T) {
 	assert.Equal(t, SessQueueFull, s.RelayMessage(wire.SNACMessage{}))
 }
 
+func TestSession_SetMaxQueueDepth(t *testing.T) {
+	s := NewSession()
+	s.SetMaxQueueDepth(3)
+
+	for range 3 {
+		assert.Equal(t, SessSendOK, s.RelayMessage(wire.SNACMessage{}))
+	}
+	assert.Equal(t, 3, s.QueueDepth())
+	assert.False(t, s.SlowConsumer())
+
+	assert.Equal(t, SessQueueFull, s.RelayMessage(wire.SNACMessage{}))
+	assert.True(t, s.SlowConsumer())
+
+	// draining the queue lowers the depth but not the peak
+	<-s.ReceiveMessage()
+	<-s.ReceiveMessage()
+	assert.Equal(t, 1, s.QueueDepth())
+	assert.Equal(t, 3, s.PeakQueueDepth())
+}
+
 func TestSession_Close_Twice(t *testing.T) {
 	s := Session{
 		stopCh: make(chan struct{}),
@@ -493,6 +535,7 @@ func TestSession_EvaluateRateLimit_ObserveRateChanges(t *testing.T) {
 		default:
 			t.Error("expected session to be closed")
 		}
+		assert.Equal(t, DisconnectRateLimited, sess.DisconnectReason())
 	})
 
 	t.Run("reach rate limit threshold, wait for clear threshold", func(t *testing.T) {
@@ -678,10 +721,17 @@ func TestSession_SetAndGetProfile(t *testing.T) {
 func TestSession_SetAndGetMemberSince(t *testing.T) {
 	s := NewSession()
 	assert.True(t, s.MemberSince().IsZero())
+	info := s.TLVUserInfo()
+	assert.False(t, info.HasTag(wire.OServiceUserInfoMemberSince))
 
 	memberTime := time.Unix(1234567890, 0)
 	s.SetMemberSince(memberTime)
 	assert.Equal(t, memberTime, s.MemberSince())
+
+	info = s.TLVUserInfo()
+	secs, ok := info.Uint32BE(wire.OServiceUserInfoMemberSince)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(1234567890), secs)
 }
 
 func TestSession_SetAndGetOfflineMsgCount(t *testing.T) {
@@ -955,3 +1005,107 @@ func TestSession_ScaleWarningAndRateLimit(t *testing.T) {
 		wg.Wait()
 	})
 }
+
+func TestSession_SetIdle_Clamp(t *testing.T) {
+	tests := []struct {
+		name           string
+		givenSessionFn func() *Session
+		givenIdle      time.Duration
+		wantIdleTime   time.Time
+	}{
+		{
+			name: "negative duration is treated as zero",
+			givenSessionFn: func() *Session {
+				s := NewSession()
+				s.SetSignonTime(time.Unix(0, 0))
+				s.nowFn = func() time.Time { return time.Unix(600, 0) }
+				return s
+			},
+			givenIdle:    -5 * time.Minute,
+			wantIdleTime: time.Unix(600, 0),
+		},
+		{
+			name: "idle start is clamped to sign-on time",
+			givenSessionFn: func() *Session {
+				s := NewSession()
+				s.SetSignonTime(time.Unix(300, 0))
+				s.nowFn = func() time.Time { return time.Unix(600, 0) }
+				return s
+			},
+			givenIdle:    time.Hour,
+			wantIdleTime: time.Unix(300, 0),
+		},
+		{
+			name: "duration is clamped to max idle duration",
+			givenSessionFn: func() *Session {
+				s := NewSession()
+				s.SetSignonTime(time.Unix(0, 0))
+				s.nowFn = func() time.Time { return time.Unix(0, 0).Add(2 * MaxIdleDuration) }
+				return s
+			},
+			givenIdle:    10 * MaxIdleDuration,
+			wantIdleTime: time.Unix(0, 0).Add(MaxIdleDuration),
+		},
+		{
+			name: "idle start is clamped to last observed activity",
+			givenSessionFn: func() *Session {
+				s := NewSession()
+				s.SetSignonTime(time.Unix(0, 0))
+				s.nowFn = func() time.Time { return time.Unix(500, 0) }
+				s.markICBMSent(0)
+				s.nowFn = func() time.Time { return time.Unix(600, 0) }
+				return s
+			},
+			givenIdle:    5 * time.Minute,
+			wantIdleTime: time.Unix(500, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.givenSessionFn()
+			s.SetIdle(tt.givenIdle)
+			assert.True(t, s.Idle())
+			assert.Equal(t, tt.wantIdleTime, s.IdleTime())
+		})
+	}
+}
+
+func TestSession_TLVUserInfo_IdlePrefersObservedActivity(t *testing.T) {
+	s := NewSession()
+	timeBegin := time.Unix(0, 0)
+	s.SetSignonTime(timeBegin)
+
+	// client reports 10m of idle time at t=+20m (idle @ t=+10m)
+	s.nowFn = func() time.Time { return timeBegin.Add(20 * time.Minute) }
+	s.SetIdle(10 * time.Minute)
+	// server sees the user send an IM at t=+25m
+	s.nowFn = func() time.Time { return timeBegin.Add(25 * time.Minute) }
+	s.markICBMSent(0)
+	assert.Equal(t, timeBegin.Add(25*time.Minute), s.LastActivity())
+
+	// at t=+30m, idle time is 5m rather than 20m
+	s.nowFn = func() time.Time { return timeBegin.Add(30 * time.Minute) }
+	info := s.TLVUserInfo()
+	idle, ok := info.Uint16BE(wire.OServiceUserInfoIdleTime)
+	assert.True(t, ok)
+	assert.Equal(t, uint16(5), idle)
+}
+
+func TestSession_SetIdle_Throttled(t *testing.T) {
+	s := NewSession()
+	now := time.Unix(1000, 0)
+	s.SetSignonTime(time.Unix(0, 0))
+	s.nowFn = func() time.Time { return now }
+
+	assert.True(t, s.SetIdle(time.Minute))
+	assert.Equal(t, now.Add(-time.Minute), s.IdleTime())
+
+	// notifications within the interval are ignored
+	now = now.Add(IdleNotificationInterval - time.Second)
+	assert.False(t, s.SetIdle(10*time.Minute))
+	assert.Equal(t, time.Unix(1000, 0).Add(-time.Minute), s.IdleTime())
+
+	now = now.Add(time.Second)
+	assert.True(t, s.SetIdle(10*time.Minute))
+	assert.Equal(t, now.Add(-10*time.Minute), s.IdleTime())
+}
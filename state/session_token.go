@@ -0,0 +1,150 @@
+package state
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// DefaultSessionTokenTTL is how long session tokens stay valid when no TTL
+// is given to NewSessionTokenIssuer.
+const DefaultSessionTokenTTL = 5 * time.Minute
+
+var (
+	// ErrSessionTokenInvalid indicates that a session token is malformed or
+	// its signature doesn't match.
+	ErrSessionTokenInvalid = errors.New("invalid session token")
+	// ErrSessionTokenExpired indicates that a session token is past its
+	// expiry time.
+	ErrSessionTokenExpired = errors.New("session token expired")
+	// ErrSessionTokenStale indicates that the session a token was issued
+	// for has signed off.
+	ErrSessionTokenStale = errors.New("session is no longer signed on")
+)
+
+// SessionTokenClaims is what a session token proves: that ScreenName was
+// signed on as session SessionID when the token was issued.
+type SessionTokenClaims struct {
+	ScreenName DisplayScreenName `json:"screen_name"`
+	SessionID  uint64            `json:"session_id,string"`
+	ExpiresAt  time.Time         `json:"expires_at"`
+}
+
+// sessionTokenPayload is the signed part of a session token.
+type sessionTokenPayload struct {
+	ScreenName DisplayScreenName `oscar:"len_prefix=uint8"`
+	SessionID  uint64
+	Expiry     uint32
+}
+
+// SessionTokenIssuer mints short-lived signed tokens that let external web
+// applications, such as forums showing AIM presence, confirm that a user is
+// signed on. Tokens are URL-safe strings signed with a key generated at
+// startup, so they don't survive a server restart.
+type SessionTokenIssuer struct {
+	key      []byte
+	sessions SessionRetriever
+	ttl      time.Duration
+	nowFn    func() time.Time
+}
+
+// NewSessionTokenIssuer creates a new instance of SessionTokenIssuer that
+// checks tokens against the sessions in sessions. A ttl of 0 uses
+// DefaultSessionTokenTTL.
+func NewSessionTokenIssuer(sessions SessionRetriever, ttl time.Duration) (*SessionTokenIssuer, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("cannot generate random HMAC key: %w", err)
+	}
+	if ttl == 0 {
+		ttl = DefaultSessionTokenTTL
+	}
+	return &SessionTokenIssuer{
+		key:      key,
+		sessions: sessions,
+		ttl:      ttl,
+		nowFn:    time.Now,
+	}, nil
+}
+
+// Issue mints a token for sess.
+func (i *SessionTokenIssuer) Issue(sess *Session) (string, error) {
+	payload := sessionTokenPayload{
+		ScreenName: sess.DisplayScreenName(),
+		SessionID:  sess.ID(),
+		Expiry:     wire.EpochSeconds(i.nowFn().Add(i.ttl)),
+	}
+	buf := &bytes.Buffer{}
+	if err := wire.MarshalBE(payload, buf); err != nil {
+		return "", fmt.Errorf("unable to marshal session token payload: %w", err)
+	}
+
+	tok := hmacToken{Data: buf.Bytes()}
+	tok.hash(i.key)
+
+	buf = &bytes.Buffer{}
+	if err := wire.MarshalBE(tok, buf); err != nil {
+		return "", fmt.Errorf("unable to marshal session token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// Verify checks the token's signature and expiry and that the session it
+// was issued for is still signed on.
+func (i *SessionTokenIssuer) Verify(token string) (SessionTokenClaims, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return SessionTokenClaims{}, ErrSessionTokenInvalid
+	}
+
+	tok := hmacToken{}
+	if err := wire.UnmarshalBE(&tok, bytes.NewReader(b)); err != nil {
+		return SessionTokenClaims{}, ErrSessionTokenInvalid
+	}
+	if !tok.validate(i.key) {
+		return SessionTokenClaims{}, ErrSessionTokenInvalid
+	}
+
+	payload := sessionTokenPayload{}
+	if err := wire.UnmarshalBE(&payload, bytes.NewReader(tok.Data)); err != nil {
+		return SessionTokenClaims{}, ErrSessionTokenInvalid
+	}
+
+	claims := SessionTokenClaims{
+		ScreenName: payload.ScreenName,
+		SessionID:  payload.SessionID,
+		ExpiresAt:  wire.FromEpochSeconds(payload.Expiry),
+	}
+	if !i.nowFn().Before(claims.ExpiresAt) {
+		return SessionTokenClaims{}, ErrSessionTokenExpired
+	}
+
+	sess := i.sessions.RetrieveSession(claims.ScreenName.IdentScreenName())
+	if sess == nil || sess.ID() != claims.SessionID {
+		return SessionTokenClaims{}, fmt.Errorf("%w: %s", ErrSessionTokenStale, claims.ScreenName)
+	}
+
+	return claims, nil
+}
+
+// VerifyHandler serves token checks for external web applications. The
+// token is passed in the "token" query parameter. It responds 200 with the
+// SessionTokenClaims as JSON if the token is valid and 401 otherwise.
+func (i *SessionTokenIssuer) VerifyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := i.Verify(r.URL.Query().Get("token"))
+		if err != nil {
+			writeHealthJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
+		writeHealthJSON(w, http.StatusOK, claims)
+	}
+}
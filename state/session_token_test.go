@@ -0,0 +1,114 @@
+package state
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTokenTestSession(t *testing.T, sm *InMemorySessionManager, screenName DisplayScreenName) *Session {
+	sess, err := sm.AddSession(t.Context(), screenName)
+	require.NoError(t, err)
+	sess.SetSignonComplete()
+	return sess
+}
+
+func TestSessionTokenIssuer_Verify(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+	sess := newTokenTestSession(t, sm, "Alice")
+
+	issuer, err := NewSessionTokenIssuer(sm, time.Minute)
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	issuer.nowFn = func() time.Time { return now }
+
+	token, err := issuer.Issue(sess)
+	require.NoError(t, err)
+
+	t.Run("valid", func(t *testing.T) {
+		claims, err := issuer.Verify(token)
+		require.NoError(t, err)
+		assert.Equal(t, SessionTokenClaims{
+			ScreenName: "Alice",
+			SessionID:  sess.ID(),
+			ExpiresAt:  now.Add(time.Minute).UTC(),
+		}, claims)
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		b := []byte(token)
+		b[len(b)/2] ^= 1
+		_, err := issuer.Verify(string(b))
+		assert.ErrorIs(t, err, ErrSessionTokenInvalid)
+	})
+
+	t.Run("garbage", func(t *testing.T) {
+		_, err := issuer.Verify("not a token")
+		assert.ErrorIs(t, err, ErrSessionTokenInvalid)
+	})
+
+	t.Run("signed by another issuer", func(t *testing.T) {
+		other, err := NewSessionTokenIssuer(sm, time.Minute)
+		require.NoError(t, err)
+		_, err = other.Verify(token)
+		assert.ErrorIs(t, err, ErrSessionTokenInvalid)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		expired := *issuer
+		expired.nowFn = func() time.Time { return now.Add(time.Minute) }
+		_, err := expired.Verify(token)
+		assert.ErrorIs(t, err, ErrSessionTokenExpired)
+	})
+}
+
+func TestSessionTokenIssuer_Verify_SessionReplaced(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+	sess := newTokenTestSession(t, sm, "Alice")
+
+	issuer, err := NewSessionTokenIssuer(sm, 0)
+	require.NoError(t, err)
+	token, err := issuer.Issue(sess)
+	require.NoError(t, err)
+
+	// signing on again replaces the session the token was issued for
+	go func() {
+		<-sess.Closed()
+		sm.RemoveSession(sess)
+	}()
+	newTokenTestSession(t, sm, "Alice")
+
+	_, err = issuer.Verify(token)
+	assert.ErrorIs(t, err, ErrSessionTokenStale)
+}
+
+func TestSessionTokenIssuer_VerifyHandler(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+	sess := newTokenTestSession(t, sm, "Alice")
+
+	issuer, err := NewSessionTokenIssuer(sm, 0)
+	require.NoError(t, err)
+	token, err := issuer.Issue(sess)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	issuer.VerifyHandler()(rec, httptest.NewRequest(http.MethodGet, "/session?token="+url.QueryEscape(token), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	claims := SessionTokenClaims{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&claims))
+	assert.Equal(t, DisplayScreenName("Alice"), claims.ScreenName)
+	assert.Equal(t, sess.ID(), claims.SessionID)
+
+	sm.RemoveSession(sess)
+
+	rec = httptest.NewRecorder()
+	issuer.VerifyHandler()(rec, httptest.NewRequest(http.MethodGet, "/session?token="+url.QueryEscape(token), nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,590 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+	"modernc.org/sqlite"
+	lib "modernc.org/sqlite/lib"
+)
+
+var (
+	// ErrSharedGroupNotFound indicates that a shared group or subscription
+	// doesn't exist.
+	ErrSharedGroupNotFound = errors.New("shared group not found")
+	// ErrSharedGroupSubscribed indicates that the user already subscribes
+	// to the shared group.
+	ErrSharedGroupSubscribed = errors.New("already subscribed to shared group")
+	// ErrSharedGroupOwner indicates that a user tried to subscribe to a
+	// group they published themselves.
+	ErrSharedGroupOwner = errors.New("can't subscribe to own shared group")
+)
+
+// SharedGroup is a buddy group published by its owner for other users to
+// subscribe to.
+type SharedGroup struct {
+	// ID identifies the shared group.
+	ID int64
+	// Owner is the user whose feedbag holds the source group.
+	Owner IdentScreenName
+	// GroupID is the ID of the source group in the owner's feedbag.
+	GroupID uint16
+	// Name is the current name of the source group.
+	Name string
+	// Subscribers is the number of users subscribed to the group.
+	Subscribers int
+	// CreatedAt is when the group was published.
+	CreatedAt time.Time
+}
+
+// PublishFeedbagGroup shares the owner's group groupID so that other users
+// can subscribe to it, and marks the group with FeedbagAttributesShared.
+// Operators publish groups on behalf of an account the same way. Publishing
+// a group that's already shared returns the existing shared group. It
+// returns ErrFeedbagGroupNotFound if the group doesn't exist.
+func (us SQLiteUserStore) PublishFeedbagGroup(ctx context.Context, owner IdentScreenName, groupID uint16) (_ SharedGroup, err error) {
+	if groupID == 0 {
+		return SharedGroup{}, fmt.Errorf("%w: the root group can't be shared", ErrFeedbagGroupInvalid)
+	}
+
+	var tx storeTx
+	tx, err = us.begin(ctx)
+	if err != nil {
+		return SharedGroup{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var group wire.FeedbagItem
+	group, err = feedbagGroupTx(ctx, tx, owner, groupID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrFeedbagGroupNotFound
+		return SharedGroup{}, err
+	} else if err != nil {
+		return SharedGroup{}, fmt.Errorf("select group: %w", err)
+	}
+
+	q := `
+		INSERT INTO sharedGroup (owner, groupID, createdAt)
+		VALUES (?, ?, UNIXEPOCH())
+		ON CONFLICT (owner, groupID) DO NOTHING
+	`
+	if _, err = tx.ExecContext(ctx, q, owner.String(), groupID); err != nil {
+		if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == lib.SQLITE_CONSTRAINT_FOREIGNKEY {
+			err = ErrNoUser
+			return SharedGroup{}, err
+		}
+		return SharedGroup{}, fmt.Errorf("PublishFeedbagGroup: %w", err)
+	}
+	if !group.Shared() {
+		group.SetShared(true)
+		if err = updateFeedbagGroupTx(ctx, tx, owner, group); err != nil {
+			return SharedGroup{}, fmt.Errorf("update group: %w", err)
+		}
+	}
+
+	var shared []SharedGroup
+	shared, err = sharedGroupsTx(ctx, tx, `WHERE sg.owner = ? AND sg.groupID = ?`, owner.String(), groupID)
+	if err != nil {
+		return SharedGroup{}, fmt.Errorf("PublishFeedbagGroup: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return SharedGroup{}, fmt.Errorf("commit: %w", err)
+	}
+	return shared[0], nil
+}
+
+// UnpublishFeedbagGroup stops sharing the owner's group groupID. Subscribers
+// keep their copies as ordinary groups that are no longer kept in sync. It
+// returns the subscribers whose feedbags changed, so that their clients can
+// be sent the update, or ErrSharedGroupNotFound if the group isn't shared.
+func (us SQLiteUserStore) UnpublishFeedbagGroup(ctx context.Context, owner IdentScreenName, groupID uint16) (_ []IdentScreenName, err error) {
+	var tx storeTx
+	tx, err = us.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var id int64
+	q := `SELECT id FROM sharedGroup WHERE owner = ? AND groupID = ?`
+	err = tx.QueryRowContext(ctx, q, owner.String(), groupID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrSharedGroupNotFound
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("UnpublishFeedbagGroup: %w", err)
+	}
+
+	var changed []IdentScreenName
+	changed, err = unpublishTx(ctx, tx, id)
+	if err != nil {
+		return nil, fmt.Errorf("UnpublishFeedbagGroup: %w", err)
+	}
+
+	var group wire.FeedbagItem
+	group, err = feedbagGroupTx(ctx, tx, owner, groupID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = nil
+	case err != nil:
+		return nil, fmt.Errorf("select group: %w", err)
+	default:
+		group.SetShared(false)
+		if err = updateFeedbagGroupTx(ctx, tx, owner, group); err != nil {
+			return nil, fmt.Errorf("update group: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return changed, nil
+}
+
+// SharedGroups returns all published groups, oldest first.
+func (us SQLiteUserStore) SharedGroups(ctx context.Context) ([]SharedGroup, error) {
+	groups, err := sharedGroupsTx(ctx, us.db, ``)
+	if err != nil {
+		return nil, fmt.Errorf("SharedGroups: %w", err)
+	}
+	return groups, nil
+}
+
+// SubscribeSharedGroup adds a copy of the shared group id to the
+// subscriber's feedbag and returns the copy's group ID. The copy is marked
+// with FeedbagAttributesShared and is kept in sync with the source group by
+// SyncSharedGroup. It returns ErrSharedGroupNotFound if the group isn't
+// shared, ErrSharedGroupOwner if the subscriber owns it and
+// ErrSharedGroupSubscribed if they already subscribe to it.
+func (us SQLiteUserStore) SubscribeSharedGroup(ctx context.Context, subscriber IdentScreenName, id int64) (_ uint16, err error) {
+	var tx storeTx
+	tx, err = us.begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var shared []SharedGroup
+	shared, err = sharedGroupsTx(ctx, tx, `WHERE sg.id = ?`, id)
+	switch {
+	case err != nil:
+		return 0, fmt.Errorf("SubscribeSharedGroup: %w", err)
+	case len(shared) == 0:
+		err = ErrSharedGroupNotFound
+		return 0, err
+	case shared[0].Owner == subscriber:
+		err = ErrSharedGroupOwner
+		return 0, err
+	}
+
+	var subscribed int
+	q := `SELECT COUNT(*) FROM sharedGroupSubscription WHERE sharedGroupID = ? AND subscriber = ?`
+	if err = tx.QueryRowContext(ctx, q, id, subscriber.String()).Scan(&subscribed); err != nil {
+		return 0, fmt.Errorf("SubscribeSharedGroup: %w", err)
+	}
+	if subscribed > 0 {
+		err = ErrSharedGroupSubscribed
+		return 0, err
+	}
+
+	var maxGroupID int
+	q = `SELECT COALESCE(MAX(groupID), 0) FROM feedbag WHERE screenName = ?`
+	if err = tx.QueryRowContext(ctx, q, subscriber.String()).Scan(&maxGroupID); err != nil {
+		return 0, fmt.Errorf("SubscribeSharedGroup: %w", err)
+	}
+	if maxGroupID >= math.MaxUint16 {
+		err = fmt.Errorf("%w: no free group ID", ErrFeedbagGroupInvalid)
+		return 0, err
+	}
+	copyID := uint16(maxGroupID + 1)
+
+	q = `
+		INSERT INTO sharedGroupSubscription (sharedGroupID, subscriber, groupID)
+		VALUES (?, ?, ?)
+	`
+	if _, err = tx.ExecContext(ctx, q, id, subscriber.String(), copyID); err != nil {
+		if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == lib.SQLITE_CONSTRAINT_FOREIGNKEY {
+			err = ErrNoUser
+			return 0, err
+		}
+		return 0, fmt.Errorf("SubscribeSharedGroup: %w", err)
+	}
+
+	// the group is filled in by the sync
+	group := wire.FeedbagItem{GroupID: copyID, ClassID: wire.FeedbagClassIdGroup, Name: shared[0].Name}
+	if err = feedbagUpsertTx(ctx, tx, subscriber, []wire.FeedbagItem{group}); err != nil {
+		return 0, fmt.Errorf("insert group: %w", err)
+	}
+
+	var source sharedGroupSource
+	source, err = sharedGroupSourceTx(ctx, tx, shared[0].Owner, shared[0].GroupID)
+	if err != nil {
+		return 0, fmt.Errorf("SubscribeSharedGroup: %w", err)
+	}
+	if _, err = syncSharedGroupCopyTx(ctx, tx, subscriber, copyID, source); err != nil {
+		return 0, fmt.Errorf("SubscribeSharedGroup: %w", err)
+	}
+	if err = reconcileRootOrderTx(ctx, tx, subscriber); err != nil {
+		return 0, fmt.Errorf("SubscribeSharedGroup: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	return copyID, nil
+}
+
+// UnsubscribeSharedGroup removes the subscriber's copy of the shared group
+// id from their feedbag. It returns ErrSharedGroupNotFound if they don't
+// subscribe to the group.
+func (us SQLiteUserStore) UnsubscribeSharedGroup(ctx context.Context, subscriber IdentScreenName, id int64) (err error) {
+	var tx storeTx
+	tx, err = us.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var copyID uint16
+	q := `SELECT groupID FROM sharedGroupSubscription WHERE sharedGroupID = ? AND subscriber = ?`
+	err = tx.QueryRowContext(ctx, q, id, subscriber.String()).Scan(&copyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrSharedGroupNotFound
+		return err
+	} else if err != nil {
+		return fmt.Errorf("UnsubscribeSharedGroup: %w", err)
+	}
+
+	q = `DELETE FROM sharedGroupSubscription WHERE sharedGroupID = ? AND subscriber = ?`
+	if _, err = tx.ExecContext(ctx, q, id, subscriber.String()); err != nil {
+		return fmt.Errorf("UnsubscribeSharedGroup: %w", err)
+	}
+	q = `DELETE FROM feedbag WHERE screenName = ? AND groupID = ?`
+	if _, err = tx.ExecContext(ctx, q, subscriber.String(), copyID); err != nil {
+		return fmt.Errorf("UnsubscribeSharedGroup: %w", err)
+	}
+	if err = reconcileRootOrderTx(ctx, tx, subscriber); err != nil {
+		return fmt.Errorf("UnsubscribeSharedGroup: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// SyncSharedGroup brings the subscribers' copies of the owner's group
+// groupID up to date with the source group: the copies get the group's
+// name, its buddies and their aliases, in the group's order. Handlers call
+// it after the owner changes a group; it does nothing for groups that
+// aren't shared. If the owner deleted the group, the group is unpublished.
+// Subscriptions whose copy the subscriber deleted are dropped.
+//
+// It returns the subscribers whose feedbags changed, so that their clients
+// can be sent the update.
+func (us SQLiteUserStore) SyncSharedGroup(ctx context.Context, owner IdentScreenName, groupID uint16) (_ []IdentScreenName, err error) {
+	var tx storeTx
+	tx, err = us.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var id int64
+	q := `SELECT id FROM sharedGroup WHERE owner = ? AND groupID = ?`
+	err = tx.QueryRowContext(ctx, q, owner.String(), groupID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+		_ = tx.Rollback()
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("SyncSharedGroup: %w", err)
+	}
+
+	var changed []IdentScreenName
+	var source sharedGroupSource
+	source, err = sharedGroupSourceTx(ctx, tx, owner, groupID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if changed, err = unpublishTx(ctx, tx, id); err != nil {
+			return nil, fmt.Errorf("SyncSharedGroup: %w", err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("SyncSharedGroup: %w", err)
+	default:
+		var subs []sharedGroupSubscription
+		if subs, err = sharedGroupSubscriptionsTx(ctx, tx, id); err != nil {
+			return nil, fmt.Errorf("SyncSharedGroup: %w", err)
+		}
+		for _, sub := range subs {
+			var ok bool
+			ok, err = syncSharedGroupCopyTx(ctx, tx, sub.subscriber, sub.groupID, source)
+			if errors.Is(err, sql.ErrNoRows) {
+				q = `DELETE FROM sharedGroupSubscription WHERE sharedGroupID = ? AND subscriber = ?`
+				if _, err = tx.ExecContext(ctx, q, id, sub.subscriber.String()); err != nil {
+					return nil, fmt.Errorf("SyncSharedGroup: %w", err)
+				}
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("SyncSharedGroup: %w", err)
+			}
+			if ok {
+				changed = append(changed, sub.subscriber)
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return changed, nil
+}
+
+// sharedGroupsTx returns the shared groups matching the where clause,
+// oldest first.
+func sharedGroupsTx(ctx context.Context, tx sqlConn, where string, args ...any) ([]SharedGroup, error) {
+	q := `
+		SELECT sg.id,
+			   sg.owner,
+			   sg.groupID,
+			   COALESCE(f.name, ''),
+			   (SELECT COUNT(*) FROM sharedGroupSubscription s WHERE s.sharedGroupID = sg.id),
+			   sg.createdAt
+		FROM sharedGroup sg
+		LEFT JOIN feedbag f
+			ON f.screenName = sg.owner AND f.groupID = sg.groupID AND f.itemID = 0 AND f.classID = ?
+	` + where + `
+		ORDER BY sg.id
+	`
+	rows, err := tx.QueryContext(ctx, q, append([]any{wire.FeedbagClassIdGroup}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []SharedGroup
+	for rows.Next() {
+		var g SharedGroup
+		var owner string
+		var createdAt int64
+		if err := rows.Scan(&g.ID, &owner, &g.GroupID, &g.Name, &g.Subscribers, &createdAt); err != nil {
+			return nil, err
+		}
+		g.Owner = NewIdentScreenName(owner)
+		g.CreatedAt = time.Unix(createdAt, 0).UTC()
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+type sharedGroupSubscription struct {
+	subscriber IdentScreenName
+	groupID    uint16
+}
+
+func sharedGroupSubscriptionsTx(ctx context.Context, tx sqlConn, id int64) ([]sharedGroupSubscription, error) {
+	q := `SELECT subscriber, groupID FROM sharedGroupSubscription WHERE sharedGroupID = ? ORDER BY subscriber`
+	rows, err := tx.QueryContext(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []sharedGroupSubscription
+	for rows.Next() {
+		var sub sharedGroupSubscription
+		var subscriber string
+		if err := rows.Scan(&subscriber, &sub.groupID); err != nil {
+			return nil, err
+		}
+		sub.subscriber = NewIdentScreenName(subscriber)
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// unpublishTx deletes the shared group id and turns the subscribers' copies
+// into ordinary groups. It returns the subscribers whose copy changed.
+func unpublishTx(ctx context.Context, tx sqlConn, id int64) ([]IdentScreenName, error) {
+	subs, err := sharedGroupSubscriptionsTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []IdentScreenName
+	for _, sub := range subs {
+		group, err := feedbagGroupTx(ctx, tx, sub.subscriber, sub.groupID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		group.SetShared(false)
+		if err := updateFeedbagGroupTx(ctx, tx, sub.subscriber, group); err != nil {
+			return nil, err
+		}
+		changed = append(changed, sub.subscriber)
+	}
+
+	// subscriptions are deleted by cascade
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sharedGroup WHERE id = ?`, id); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// sharedGroupSource is the source group of a shared group and its buddies
+// in display order.
+type sharedGroupSource struct {
+	group   wire.FeedbagItem
+	buddies []wire.FeedbagItem
+}
+
+// sharedGroupSourceTx loads the owner's group groupID, or returns
+// sql.ErrNoRows if it doesn't exist.
+func sharedGroupSourceTx(ctx context.Context, tx sqlConn, owner IdentScreenName, groupID uint16) (sharedGroupSource, error) {
+	group, err := feedbagGroupTx(ctx, tx, owner, groupID)
+	if err != nil {
+		return sharedGroupSource{}, err
+	}
+	buddies, err := feedbagGroupBuddiesTx(ctx, tx, owner, groupID)
+	if err != nil {
+		return sharedGroupSource{}, err
+	}
+
+	// buddies the order doesn't list go last, by item ID
+	order, _ := group.Order()
+	pos := func(item wire.FeedbagItem) int {
+		if i := slices.Index(order, item.ItemID); i >= 0 {
+			return i
+		}
+		return len(order)
+	}
+	slices.SortStableFunc(buddies, func(a, b wire.FeedbagItem) int {
+		return pos(a) - pos(b)
+	})
+	return sharedGroupSource{group: group, buddies: buddies}, nil
+}
+
+// feedbagGroupBuddiesTx returns the buddies of the group, by item ID.
+func feedbagGroupBuddiesTx(ctx context.Context, tx sqlConn, screenName IdentScreenName, groupID uint16) ([]wire.FeedbagItem, error) {
+	items, err := feedbagTx(ctx, tx, screenName)
+	if err != nil {
+		return nil, err
+	}
+	var buddies []wire.FeedbagItem
+	for _, item := range items {
+		if item.GroupID == groupID && item.ClassID == wire.FeedbagClassIdBuddy {
+			buddies = append(buddies, item)
+		}
+	}
+	slices.SortFunc(buddies, func(a, b wire.FeedbagItem) int {
+		return int(a.ItemID) - int(b.ItemID)
+	})
+	return buddies, nil
+}
+
+// syncSharedGroupCopyTx makes the subscriber's group copyID a copy of
+// source and reports whether anything changed. Buddy aliases are copied
+// from the source; other attributes the subscriber set on the copied
+// buddies are kept. It returns sql.ErrNoRows if the copy doesn't exist.
+func syncSharedGroupCopyTx(ctx context.Context, tx sqlConn, subscriber IdentScreenName, copyID uint16, source sharedGroupSource) (bool, error) {
+	group, err := feedbagGroupTx(ctx, tx, subscriber, copyID)
+	if err != nil {
+		return false, err
+	}
+	items, err := feedbagTx(ctx, tx, subscriber)
+	if err != nil {
+		return false, err
+	}
+
+	copies := make(map[IdentScreenName]wire.FeedbagItem)
+	var nextItemID uint16
+	for _, item := range items {
+		nextItemID = max(nextItemID, item.ItemID)
+		if item.GroupID == copyID && item.ClassID == wire.FeedbagClassIdBuddy {
+			copies[NewIdentScreenName(item.Name)] = item
+		}
+	}
+
+	var changed bool
+	var upserts []wire.FeedbagItem
+	var order []uint16
+	for _, buddy := range source.buddies {
+		name := NewIdentScreenName(buddy.Name)
+		alias, _ := buddy.Alias()
+
+		item, ok := copies[name]
+		delete(copies, name)
+		if !ok {
+			if nextItemID == math.MaxUint16 {
+				return false, fmt.Errorf("%w: no free item ID", ErrFeedbagGroupInvalid)
+			}
+			nextItemID++
+			item = wire.FeedbagItem{GroupID: copyID, ItemID: nextItemID, ClassID: wire.FeedbagClassIdBuddy, Name: buddy.Name}
+		}
+		if current, _ := item.Alias(); !ok || current != alias {
+			if err := item.SetAlias(alias); err != nil {
+				return false, err
+			}
+			upserts = append(upserts, item)
+		}
+		order = append(order, item.ItemID)
+	}
+	if len(upserts) > 0 {
+		if err := feedbagUpsertTx(ctx, tx, subscriber, upserts); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	for _, item := range copies {
+		q := `DELETE FROM feedbag WHERE screenName = ? AND groupID = ? AND itemID = ?`
+		if _, err := tx.ExecContext(ctx, q, subscriber.String(), copyID, item.ItemID); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	updated := wire.FeedbagItem{GroupID: copyID, ClassID: wire.FeedbagClassIdGroup, Name: source.group.Name}
+	updated.TLVList = slices.Clone(group.TLVList)
+	updated.SetShared(true)
+	if err := updated.SetOrder(order); err != nil {
+		return false, err
+	}
+	if updated.Name != group.Name || !updated.Equal(group.TLVList) {
+		if err := updateFeedbagGroupTx(ctx, tx, subscriber, updated); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	return changed, nil
+}
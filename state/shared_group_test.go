@@ -0,0 +1,196 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestSQLiteUserStore_SharedGroups(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, sn := range []DisplayScreenName{"owner", "sub"} {
+		user, err := NewStubUser(sn)
+		require.NoError(t, err)
+		require.NoError(t, f.InsertUser(ctx, user))
+	}
+	owner := NewIdentScreenName("owner")
+	sub := NewIdentScreenName("sub")
+
+	group := func(groupID uint16, name string, order ...uint16) wire.FeedbagItem {
+		item := wire.FeedbagItem{GroupID: groupID, ClassID: wire.FeedbagClassIdGroup, Name: name}
+		require.NoError(t, item.SetOrder(order))
+		return item
+	}
+	buddy := func(groupID, itemID uint16, name, alias string) wire.FeedbagItem {
+		item := wire.FeedbagItem{GroupID: groupID, ItemID: itemID, ClassID: wire.FeedbagClassIdBuddy, Name: name}
+		require.NoError(t, item.SetAlias(alias))
+		return item
+	}
+	// copyOf returns the subscriber's group copyID and its buddies in order.
+	copyOf := func(copyID uint16) (wire.FeedbagItem, []string) {
+		items, err := f.Feedbag(ctx, sub)
+		require.NoError(t, err)
+		var g wire.FeedbagItem
+		byID := map[uint16]wire.FeedbagItem{}
+		for _, item := range items {
+			if item.GroupID != copyID {
+				continue
+			}
+			if item.ClassID == wire.FeedbagClassIdGroup {
+				g = item
+			} else {
+				byID[item.ItemID] = item
+			}
+		}
+		order, _ := g.Order()
+		var buddies []string
+		for _, id := range order {
+			item := byID[id]
+			alias, _ := item.Alias()
+			buddies = append(buddies, item.Name+"/"+alias)
+		}
+		assert.Len(t, byID, len(order))
+		return g, buddies
+	}
+
+	require.NoError(t, f.FeedbagUpsert(ctx, owner, []wire.FeedbagItem{
+		group(0, "", 1),
+		group(1, "Team", 11, 10),
+		buddy(1, 10, "alice", "Alice"),
+		buddy(1, 11, "bob", ""),
+	}))
+	require.NoError(t, f.FeedbagUpsert(ctx, sub, []wire.FeedbagItem{
+		group(0, "", 1),
+		group(1, "Buddies", 10),
+		buddy(1, 10, "carol", ""),
+	}))
+
+	_, err = f.PublishFeedbagGroup(ctx, owner, 9)
+	assert.ErrorIs(t, err, ErrFeedbagGroupNotFound)
+
+	shared, err := f.PublishFeedbagGroup(ctx, owner, 1)
+	require.NoError(t, err)
+	assert.Equal(t, owner, shared.Owner)
+	assert.Equal(t, "Team", shared.Name)
+	again, err := f.PublishFeedbagGroup(ctx, owner, 1)
+	require.NoError(t, err)
+	assert.Equal(t, shared.ID, again.ID)
+
+	_, err = f.SubscribeSharedGroup(ctx, owner, shared.ID)
+	assert.ErrorIs(t, err, ErrSharedGroupOwner)
+	_, err = f.SubscribeSharedGroup(ctx, sub, shared.ID+1)
+	assert.ErrorIs(t, err, ErrSharedGroupNotFound)
+
+	copyID, err := f.SubscribeSharedGroup(ctx, sub, shared.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(2), copyID)
+	_, err = f.SubscribeSharedGroup(ctx, sub, shared.ID)
+	assert.ErrorIs(t, err, ErrSharedGroupSubscribed)
+
+	g, buddies := copyOf(copyID)
+	assert.Equal(t, "Team", g.Name)
+	assert.True(t, g.Shared())
+	assert.Equal(t, []string{"bob/", "alice/Alice"}, buddies)
+
+	root, err := feedbagGroupTx(ctx, f.db, sub, 0)
+	require.NoError(t, err)
+	rootOrder, _ := root.Order()
+	assert.Equal(t, []uint16{1, 2}, rootOrder)
+
+	groups, err := f.SharedGroups(ctx)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, 1, groups[0].Subscribers)
+
+	t.Run("sync is a no-op when nothing changed", func(t *testing.T) {
+		changed, err := f.SyncSharedGroup(ctx, owner, 1)
+		require.NoError(t, err)
+		assert.Empty(t, changed)
+	})
+
+	t.Run("groups that aren't shared are ignored", func(t *testing.T) {
+		changed, err := f.SyncSharedGroup(ctx, sub, 1)
+		require.NoError(t, err)
+		assert.Empty(t, changed)
+	})
+
+	t.Run("source changes are copied", func(t *testing.T) {
+		require.NoError(t, f.FeedbagDelete(ctx, owner, []wire.FeedbagItem{buddy(1, 11, "bob", "")}))
+		require.NoError(t, f.FeedbagUpsert(ctx, owner, []wire.FeedbagItem{
+			group(1, "Core Team", 12, 10),
+			buddy(1, 10, "alice", "Al"),
+			buddy(1, 12, "dave", ""),
+		}))
+
+		changed, err := f.SyncSharedGroup(ctx, owner, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []IdentScreenName{sub}, changed)
+
+		g, buddies := copyOf(copyID)
+		assert.Equal(t, "Core Team", g.Name)
+		assert.Equal(t, []string{"dave/", "alice/Al"}, buddies)
+	})
+
+	t.Run("unsubscribe removes the copy", func(t *testing.T) {
+		require.NoError(t, f.UnsubscribeSharedGroup(ctx, sub, shared.ID))
+		assert.ErrorIs(t, f.UnsubscribeSharedGroup(ctx, sub, shared.ID), ErrSharedGroupNotFound)
+
+		items, err := f.Feedbag(ctx, sub)
+		require.NoError(t, err)
+		for _, item := range items {
+			assert.NotEqual(t, copyID, item.GroupID)
+		}
+	})
+
+	t.Run("unpublish detaches copies", func(t *testing.T) {
+		copyID, err := f.SubscribeSharedGroup(ctx, sub, shared.ID)
+		require.NoError(t, err)
+
+		changed, err := f.UnpublishFeedbagGroup(ctx, owner, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []IdentScreenName{sub}, changed)
+
+		g, buddies := copyOf(copyID)
+		assert.False(t, g.Shared())
+		assert.Len(t, buddies, 2)
+
+		source, err := feedbagGroupTx(ctx, f.db, owner, 1)
+		require.NoError(t, err)
+		assert.False(t, source.Shared())
+
+		groups, err := f.SharedGroups(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, groups)
+		_, err = f.UnpublishFeedbagGroup(ctx, owner, 1)
+		assert.ErrorIs(t, err, ErrSharedGroupNotFound)
+	})
+
+	t.Run("deleting the source group unpublishes it", func(t *testing.T) {
+		shared, err := f.PublishFeedbagGroup(ctx, owner, 1)
+		require.NoError(t, err)
+		copyID, err := f.SubscribeSharedGroup(ctx, sub, shared.ID)
+		require.NoError(t, err)
+
+		require.NoError(t, f.FeedbagDelete(ctx, owner, []wire.FeedbagItem{group(1, "Core Team")}))
+		changed, err := f.SyncSharedGroup(ctx, owner, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []IdentScreenName{sub}, changed)
+
+		g, _ := copyOf(copyID)
+		assert.False(t, g.Shared())
+		groups, err := f.SharedGroups(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, groups)
+	})
+}
@@ -0,0 +1,106 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// signonBackoffBase is the retry delay suggested after the first rejected
+	// sign-on attempt.
+	signonBackoffBase = time.Second
+	// signonBackoffMax caps the retry delay suggested to throttled clients.
+	signonBackoffMax = time.Minute
+)
+
+// SignonThrottledError indicates that a sign-on attempt was turned away
+// because the server is already processing the maximum number of concurrent
+// sign-ons. RetryAfter is how long the client should wait before trying again.
+type SignonThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e SignonThrottledError) Error() string {
+	return fmt.Sprintf("sign-on throttled, retry after %s", e.RetryAfter)
+}
+
+// SignonThrottle is an admission-control queue for sign-on attempts.
+// It bounds the number of sign-ons processed concurrently so that a mass
+// reconnect after a restart doesn't overwhelm the database. Attempts that
+// can't be admitted within the queue timeout are rejected with a
+// SignonThrottledError whose suggested retry delay doubles with each
+// consecutive rejection.
+type SignonThrottle struct {
+	slots      chan struct{}
+	timeout    time.Duration
+	mutex      sync.Mutex
+	rejections int
+}
+
+// NewSignonThrottle creates a new instance of SignonThrottle that admits up
+// to maxConcurrent sign-ons at a time, queueing others for up to timeout.
+// A maxConcurrent value of 0 or less disables throttling.
+func NewSignonThrottle(maxConcurrent int, timeout time.Duration) *SignonThrottle {
+	t := &SignonThrottle{timeout: timeout}
+	if maxConcurrent > 0 {
+		t.slots = make(chan struct{}, maxConcurrent)
+	}
+	return t
+}
+
+// Admit blocks until the sign-on attempt can proceed, the queue timeout
+// elapses, or ctx is done. On success, the caller must invoke release once
+// sign-on processing finishes.
+func (t *SignonThrottle) Admit(ctx context.Context) (release func(), err error) {
+	if t.slots == nil {
+		return func() {}, nil
+	}
+
+	// take a free slot without waiting, if one is available
+	select {
+	case t.slots <- struct{}{}:
+		return t.admitted(), nil
+	default:
+	}
+
+	timer := time.NewTimer(t.timeout)
+	defer timer.Stop()
+
+	select {
+	case t.slots <- struct{}{}:
+		return t.admitted(), nil
+	case <-timer.C:
+		return nil, SignonThrottledError{RetryAfter: t.reject()}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// InFlight returns the number of sign-ons currently admitted.
+func (t *SignonThrottle) InFlight() int {
+	return len(t.slots)
+}
+
+// admitted resets the backoff and returns a function that frees the slot.
+func (t *SignonThrottle) admitted() func() {
+	t.mutex.Lock()
+	t.rejections = 0
+	t.mutex.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-t.slots })
+	}
+}
+
+// reject records a rejected attempt and returns the suggested retry delay.
+func (t *SignonThrottle) reject() time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	backoff := signonBackoffBase << min(t.rejections, 6)
+	t.rejections++
+	return min(backoff, signonBackoffMax)
+}
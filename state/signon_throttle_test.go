@@ -0,0 +1,90 @@
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignonThrottle_Admit(t *testing.T) {
+	throttle := NewSignonThrottle(2, 10*time.Millisecond)
+
+	release1, err := throttle.Admit(context.Background())
+	require.NoError(t, err)
+	release2, err := throttle.Admit(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, throttle.InFlight())
+
+	// queue is full, so the next attempts are rejected with progressive backoff
+	_, err = throttle.Admit(context.Background())
+	assert.Equal(t, SignonThrottledError{RetryAfter: time.Second}, err)
+	_, err = throttle.Admit(context.Background())
+	assert.Equal(t, SignonThrottledError{RetryAfter: 2 * time.Second}, err)
+
+	// releasing twice only frees one slot
+	release1()
+	release1()
+	assert.Equal(t, 1, throttle.InFlight())
+
+	release3, err := throttle.Admit(context.Background())
+	require.NoError(t, err)
+
+	// a successful admission resets the backoff
+	_, err = throttle.Admit(context.Background())
+	assert.Equal(t, SignonThrottledError{RetryAfter: time.Second}, err)
+
+	release2()
+	release3()
+	assert.Zero(t, throttle.InFlight())
+}
+
+func TestSignonThrottle_AdmitWaitsForSlot(t *testing.T) {
+	throttle := NewSignonThrottle(1, time.Second)
+
+	release, err := throttle.Admit(context.Background())
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		release()
+	}()
+
+	release, err = throttle.Admit(context.Background())
+	require.NoError(t, err)
+	release()
+}
+
+func TestSignonThrottle_AdmitContextCanceled(t *testing.T) {
+	throttle := NewSignonThrottle(1, time.Minute)
+
+	_, err := throttle.Admit(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = throttle.Admit(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSignonThrottle_MaxBackoff(t *testing.T) {
+	throttle := NewSignonThrottle(1, 0)
+
+	_, err := throttle.Admit(context.Background())
+	require.NoError(t, err)
+
+	for range 10 {
+		_, err = throttle.Admit(context.Background())
+	}
+	assert.Equal(t, SignonThrottledError{RetryAfter: time.Minute}, err)
+}
+
+func TestSignonThrottle_Disabled(t *testing.T) {
+	throttle := NewSignonThrottle(0, 0)
+	for range 100 {
+		_, err := throttle.Admit(context.Background())
+		require.NoError(t, err)
+	}
+}
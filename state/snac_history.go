@@ -0,0 +1,150 @@
+package state
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// SNACDirection indicates whether a SNAC was received from or sent to a
+// client.
+type SNACDirection uint8
+
+const (
+	// SNACReceived indicates a SNAC sent by the client.
+	SNACReceived SNACDirection = iota
+	// SNACSent indicates a SNAC sent to the client.
+	SNACSent
+)
+
+// String returns "in" for received SNACs and "out" for sent SNACs.
+func (d SNACDirection) String() string {
+	if d == SNACSent {
+		return "out"
+	}
+	return "in"
+}
+
+// SNACRecord is a SNAC kept in a session's history.
+type SNACRecord struct {
+	// Time is when the SNAC was recorded.
+	Time time.Time
+	// Direction indicates whether the client sent or received the SNAC.
+	Direction SNACDirection
+	// Frame is the SNAC header.
+	Frame wire.SNACFrame
+	// Body is the decoded SNAC body.
+	Body any
+}
+
+// String formats the record as a single log line, e.g.
+// "12:00:00.000 in ICBM/ChannelMsgToHost req=1 {...}".
+func (r SNACRecord) String() string {
+	return fmt.Sprintf("%s %s %s/%s req=%d %+v",
+		r.Time.Format("15:04:05.000"),
+		r.Direction,
+		wire.FoodGroupName(r.Frame.FoodGroup),
+		wire.SubGroupName(r.Frame.FoodGroup, r.Frame.SubGroup),
+		r.Frame.RequestID,
+		r.Body)
+}
+
+// SNACHistory is a ring buffer of the last SNACs exchanged with a client.
+// It lets operators see what a misbehaving client sent without turning on
+// packet logging for everyone. A SNACHistory is safe for concurrent use by
+// multiple goroutines.
+type SNACHistory struct {
+	mutex   sync.Mutex
+	records []SNACRecord
+	next    int
+	full    bool
+}
+
+// NewSNACHistory creates a SNACHistory that keeps the last size SNACs.
+func NewSNACHistory(size int) *SNACHistory {
+	return &SNACHistory{records: make([]SNACRecord, size)}
+}
+
+// Record adds msg to the history, evicting the oldest SNAC if the history
+// is full.
+func (h *SNACHistory) Record(now time.Time, dir SNACDirection, msg wire.SNACMessage) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if len(h.records) == 0 {
+		return
+	}
+	h.records[h.next] = SNACRecord{Time: now, Direction: dir, Frame: msg.Frame, Body: msg.Body}
+	h.next = (h.next + 1) % len(h.records)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Records returns the SNACs in the history, oldest first.
+func (h *SNACHistory) Records() []SNACRecord {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if !h.full {
+		return append([]SNACRecord(nil), h.records[:h.next]...)
+	}
+	return append(append([]SNACRecord(nil), h.records[h.next:]...), h.records[:h.next]...)
+}
+
+// FormatSNACHistory formats records one per line, for logs.
+func FormatSNACHistory(records []SNACRecord) string {
+	lines := make([]string, len(records))
+	for i, r := range records {
+		lines[i] = r.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// snacRecordJSON is the JSON form of a SNACRecord served by the admin API.
+type snacRecordJSON struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"`
+	FoodGroup string    `json:"food_group"`
+	SubGroup  string    `json:"sub_group"`
+	RequestID uint32    `json:"request_id"`
+	Body      string    `json:"body"`
+}
+
+// SNACHistoryHandler serves the SNAC history of the session of the user
+// named by the "screen_name" query parameter as JSON, oldest first, for the
+// admin API. It responds 400 if the parameter is missing and 404 if the
+// user isn't signed on.
+func (s *InMemorySessionManager) SNACHistoryHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		screenName := NewIdentScreenName(r.URL.Query().Get("screen_name"))
+		if screenName.String() == "" {
+			http.Error(w, "Missing screen_name.", http.StatusBadRequest)
+			return
+		}
+
+		sess := s.RetrieveSession(screenName)
+		if sess == nil {
+			http.Error(w, "Session not found.", http.StatusNotFound)
+			return
+		}
+
+		records := sess.SNACHistory()
+		out := make([]snacRecordJSON, len(records))
+		for i, rec := range records {
+			out[i] = snacRecordJSON{
+				Time:      rec.Time,
+				Direction: rec.Direction.String(),
+				FoodGroup: wire.FoodGroupName(rec.Frame.FoodGroup),
+				SubGroup:  wire.SubGroupName(rec.Frame.FoodGroup, rec.Frame.SubGroup),
+				RequestID: rec.Frame.RequestID,
+				Body:      fmt.Sprintf("%+v", rec.Body),
+			}
+		}
+		writeHealthJSON(w, http.StatusOK, out)
+	}
+}
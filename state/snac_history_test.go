@@ -0,0 +1,110 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func snacHistoryMsg(requestID uint32) wire.SNACMessage {
+	return wire.SNACMessage{
+		Frame: wire.SNACFrame{FoodGroup: wire.ICBM, SubGroup: wire.ICBMChannelMsgToHost, RequestID: requestID},
+		Body:  wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{ScreenName: "them"},
+	}
+}
+
+func TestSNACHistory(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	h := NewSNACHistory(3)
+	assert.Empty(t, h.Records())
+
+	requestIDs := func() []uint32 {
+		var ids []uint32
+		for _, rec := range h.Records() {
+			ids = append(ids, rec.Frame.RequestID)
+		}
+		return ids
+	}
+
+	h.Record(now, SNACReceived, snacHistoryMsg(1))
+	h.Record(now, SNACSent, snacHistoryMsg(2))
+	assert.Equal(t, []uint32{1, 2}, requestIDs())
+
+	h.Record(now, SNACReceived, snacHistoryMsg(3))
+	h.Record(now, SNACReceived, snacHistoryMsg(4))
+	h.Record(now, SNACReceived, snacHistoryMsg(5))
+	assert.Equal(t, []uint32{3, 4, 5}, requestIDs())
+
+	rec := h.Records()[0]
+	assert.Equal(t, SNACReceived, rec.Direction)
+	assert.Contains(t, rec.String(), "12:00:00.000 in ICBM/ICBMChannelMsgToHost req=3")
+	assert.Contains(t, rec.String(), "ScreenName:them")
+}
+
+func TestSession_RecordSNAC(t *testing.T) {
+	sess := NewSession()
+	sess.RecordSNAC(SNACReceived, snacHistoryMsg(1))
+	assert.Empty(t, sess.SNACHistory())
+
+	sess.SetSNACHistorySize(2)
+	sess.RecordSNAC(SNACReceived, snacHistoryMsg(1))
+	sess.RecordSNAC(SNACSent, snacHistoryMsg(2))
+	sess.RecordSNAC(SNACSent, snacHistoryMsg(3))
+	require.Len(t, sess.SNACHistory(), 2)
+	assert.Equal(t, uint32(2), sess.SNACHistory()[0].Frame.RequestID)
+
+	sess.SetSNACHistorySize(0)
+	assert.Empty(t, sess.SNACHistory())
+}
+
+func TestInMemorySessionManager_SNACHistory(t *testing.T) {
+	logs := &bytes.Buffer{}
+	sm := NewInMemorySessionManager(slog.New(slog.NewTextHandler(logs, nil)))
+	sm.SetSNACHistorySize(10)
+
+	sess, err := sm.AddSession(context.Background(), "Flooder")
+	require.NoError(t, err)
+	sess.SetSignonComplete()
+	sess.RecordSNAC(SNACReceived, snacHistoryMsg(7))
+
+	t.Run("dump via admin API", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		sm.SNACHistoryHandler()(rec, httptest.NewRequest(http.MethodGet, "/session/snacs?screen_name=flooder", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var records []snacRecordJSON
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &records))
+		require.Len(t, records, 1)
+		assert.Equal(t, "in", records[0].Direction)
+		assert.Equal(t, "ICBM", records[0].FoodGroup)
+		assert.Equal(t, "ICBMChannelMsgToHost", records[0].SubGroup)
+		assert.Equal(t, uint32(7), records[0].RequestID)
+	})
+
+	t.Run("unknown or missing screen name", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		sm.SNACHistoryHandler()(rec, httptest.NewRequest(http.MethodGet, "/session/snacs?screen_name=nobody", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = httptest.NewRecorder()
+		sm.SNACHistoryHandler()(rec, httptest.NewRequest(http.MethodGet, "/session/snacs", nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("logged on abnormal disconnect", func(t *testing.T) {
+		sess.CloseWithReason(DisconnectSlowConsumer)
+		sm.RemoveSession(sess)
+		assert.Contains(t, logs.String(), "abnormal disconnect")
+		assert.Contains(t, logs.String(), "req=7")
+	})
+}
@@ -0,0 +1,222 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// SoftLimitCheckInterval is how often SoftLimitMonitor compares usage
+	// against the soft limits.
+	SoftLimitCheckInterval = 5 * time.Minute
+	// SoftLimitEventSchema is the schema of soft limit webhook events.
+	SoftLimitEventSchema = "soft_limit.v1"
+	// SoftLimitEventCrossed is the type of the event sent when usage
+	// reaches a soft limit.
+	SoftLimitEventCrossed = "crossed"
+	// SoftLimitEventCleared is the type of the event sent when usage falls
+	// back below a soft limit.
+	SoftLimitEventCleared = "cleared"
+)
+
+// The names of the soft limits, as used in logs, webhook events and
+// metric labels.
+const (
+	SoftLimitUsers           = "users"
+	SoftLimitDBSize          = "db_size_bytes"
+	SoftLimitOfflineMessages = "offline_messages"
+)
+
+// softLimitNames lists the soft limits in the order they're checked and
+// exported.
+var softLimitNames = []string{SoftLimitUsers, SoftLimitDBSize, SoftLimitOfflineMessages}
+
+// SoftLimits are thresholds that warn operators before the database grows
+// past what the server was provisioned for. Crossing a soft limit never
+// fails a request; it's only reported. A limit of 0 is disabled.
+type SoftLimits struct {
+	// Users is the number of registered accounts.
+	Users int64
+	// DBSizeBytes is the size of the database.
+	DBSizeBytes int64
+	// OfflineMessages is the number of stored offline messages.
+	OfflineMessages int64
+}
+
+// get returns the limit with name.
+func (l SoftLimits) get(name string) int64 {
+	switch name {
+	case SoftLimitUsers:
+		return l.Users
+	case SoftLimitDBSize:
+		return l.DBSizeBytes
+	case SoftLimitOfflineMessages:
+		return l.OfflineMessages
+	default:
+		return 0
+	}
+}
+
+// SoftLimitUsage is the usage measured against the soft limits.
+type SoftLimitUsage SoftLimits
+
+// SoftLimitUsage returns the number of users and offline messages and the
+// size of the database. The counts are kept up to date by triggers, so it
+// reads a handful of rows regardless of the size of the database.
+func (us SQLiteUserStore) SoftLimitUsage(ctx context.Context) (SoftLimitUsage, error) {
+	q := `
+		SELECT
+			(SELECT value FROM statCounter WHERE name = 'users'),
+			(SELECT value FROM statCounter WHERE name = 'offlineMessages'),
+			(SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size())
+	`
+	var usage SoftLimitUsage
+	err := us.db.QueryRowContext(ctx, q).Scan(&usage.Users, &usage.OfflineMessages, &usage.DBSizeBytes)
+	if err != nil {
+		return SoftLimitUsage{}, fmt.Errorf("SoftLimitUsage: %w", err)
+	}
+	return usage, nil
+}
+
+// SoftLimitUsageReader measures usage against the soft limits.
+type SoftLimitUsageReader interface {
+	SoftLimitUsage(ctx context.Context) (SoftLimitUsage, error)
+}
+
+// SoftLimitAlert is the data of a soft limit webhook event.
+type SoftLimitAlert struct {
+	// Limit is the name of the soft limit, such as SoftLimitUsers.
+	Limit string `json:"limit"`
+	// Usage is the usage measured when the limit was crossed or cleared.
+	Usage int64 `json:"usage"`
+	// Threshold is the soft limit.
+	Threshold int64 `json:"threshold"`
+}
+
+// SoftLimitMonitor periodically compares usage against soft limits and
+// alerts operators when usage reaches a limit, with a warning in the logs
+// and a webhook event, and again when it falls back below it. Each crossing
+// is reported once, so a server that stays over a limit doesn't flood the
+// logs. Usage and limits are also exported as metrics. A SoftLimitMonitor
+// is safe for concurrent use by multiple goroutines.
+type SoftLimitMonitor struct {
+	usage   SoftLimitUsageReader
+	limits  SoftLimits
+	sink    WebhookSink
+	logger  *slog.Logger
+	mutex   sync.Mutex
+	last    SoftLimitUsage
+	crossed map[string]bool
+	nowFn   func() time.Time
+}
+
+// NewSoftLimitMonitor creates a new instance of SoftLimitMonitor. A nil
+// sink sends no webhook events.
+func NewSoftLimitMonitor(usage SoftLimitUsageReader, limits SoftLimits, sink WebhookSink, logger *slog.Logger) *SoftLimitMonitor {
+	return &SoftLimitMonitor{
+		usage:   usage,
+		limits:  limits,
+		sink:    sink,
+		logger:  logger,
+		crossed: make(map[string]bool),
+		nowFn:   time.Now,
+	}
+}
+
+// Check measures usage and alerts on the soft limits crossed or cleared
+// since the last check. It returns the limits that usage is at or over.
+func (m *SoftLimitMonitor) Check(ctx context.Context) ([]SoftLimitAlert, error) {
+	usage, err := m.usage.SoftLimitUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.last = usage
+
+	var over []SoftLimitAlert
+	for _, name := range softLimitNames {
+		threshold := m.limits.get(name)
+		if threshold <= 0 {
+			continue
+		}
+		alert := SoftLimitAlert{Limit: name, Usage: SoftLimits(usage).get(name), Threshold: threshold}
+		crossed := alert.Usage >= threshold
+		if crossed {
+			over = append(over, alert)
+		}
+		if crossed == m.crossed[name] {
+			continue
+		}
+		m.crossed[name] = crossed
+
+		eventType := SoftLimitEventCleared
+		if crossed {
+			eventType = SoftLimitEventCrossed
+			m.logger.WarnContext(ctx, "soft limit crossed", "limit", name, "usage", alert.Usage, "threshold", threshold)
+		} else {
+			m.logger.InfoContext(ctx, "soft limit cleared", "limit", name, "usage", alert.Usage, "threshold", threshold)
+		}
+		if m.sink != nil {
+			m.sink.Dispatch(WebhookEvent{
+				Schema: SoftLimitEventSchema,
+				Type:   eventType,
+				Time:   m.nowFn(),
+				Data:   alert,
+			})
+		}
+	}
+	return over, nil
+}
+
+// Schedule adds the check to s as the "soft_limits" job, to run every
+// SoftLimitCheckInterval.
+func (m *SoftLimitMonitor) Schedule(s *Scheduler) error {
+	return s.Add("soft_limits", SoftLimitCheckInterval, SoftLimitCheckInterval/10, func(ctx context.Context) error {
+		_, err := m.Check(ctx)
+		return err
+	})
+}
+
+// Handler serves the usage measured by the last check, the soft limits and
+// whether each is crossed, in the Prometheus text exposition format, for
+// scraping from the management API's /metrics endpoint. Disabled limits
+// are left out.
+func (m *SoftLimitMonitor) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.mutex.Lock()
+		usage := m.last
+		crossed := maps.Clone(m.crossed)
+		m.mutex.Unlock()
+
+		mw := newMetricsWriter(w)
+		mw.family("icq_soft_limit_usage", "Usage measured against each soft limit.", "gauge")
+		for _, name := range softLimitNames {
+			if m.limits.get(name) > 0 {
+				mw.sample("icq_soft_limit_usage", SoftLimits(usage).get(name), "limit", name)
+			}
+		}
+		mw.family("icq_soft_limit_threshold", "The soft limit.", "gauge")
+		for _, name := range softLimitNames {
+			if threshold := m.limits.get(name); threshold > 0 {
+				mw.sample("icq_soft_limit_threshold", threshold, "limit", name)
+			}
+		}
+		mw.family("icq_soft_limit_crossed", "Whether usage is at or over the soft limit.", "gauge")
+		for _, name := range softLimitNames {
+			if m.limits.get(name) > 0 {
+				value := 0
+				if crossed[name] {
+					value = 1
+				}
+				mw.sample("icq_soft_limit_crossed", value, "limit", name)
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// softLimitUsageFunc is a SoftLimitUsageReader backed by a function.
+type softLimitUsageFunc func() SoftLimitUsage
+
+func (f softLimitUsageFunc) SoftLimitUsage(context.Context) (SoftLimitUsage, error) {
+	return f(), nil
+}
+
+func TestSQLiteUserStore_SoftLimitUsage(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for _, name := range []DisplayScreenName{"alice", "bob"} {
+		u, err := NewStubUser(name)
+		require.NoError(t, err)
+		require.NoError(t, f.InsertUser(ctx, u))
+	}
+	_, err = f.SaveMessage(ctx, OfflineMessage{
+		Sender:    NewIdentScreenName("bob"),
+		Recipient: NewIdentScreenName("alice"),
+		Message:   wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{},
+		Sent:      time.Now(),
+	})
+	require.NoError(t, err)
+
+	usage, err := f.SoftLimitUsage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), usage.Users)
+	assert.Equal(t, int64(1), usage.OfflineMessages)
+	assert.Positive(t, usage.DBSizeBytes)
+}
+
+func TestSoftLimitMonitor(t *testing.T) {
+	ctx := context.Background()
+	usage := SoftLimitUsage{Users: 90, DBSizeBytes: 1 << 20, OfflineMessages: 10}
+	var events []WebhookEvent
+	m := NewSoftLimitMonitor(
+		softLimitUsageFunc(func() SoftLimitUsage { return usage }),
+		SoftLimits{Users: 100, OfflineMessages: 10},
+		webhookSinkFunc(func(event WebhookEvent) bool {
+			events = append(events, event)
+			return true
+		}),
+		slog.Default(),
+	)
+	m.nowFn = func() time.Time { return time.Unix(1_700_000_000, 0) }
+
+	// offline messages are at the limit
+	over, err := m.Check(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []SoftLimitAlert{{Limit: SoftLimitOfflineMessages, Usage: 10, Threshold: 10}}, over)
+	assert.Equal(t, []WebhookEvent{{
+		Schema: SoftLimitEventSchema,
+		Type:   SoftLimitEventCrossed,
+		Time:   time.Unix(1_700_000_000, 0),
+		Data:   SoftLimitAlert{Limit: SoftLimitOfflineMessages, Usage: 10, Threshold: 10},
+	}}, events)
+
+	// staying over the limit isn't reported again
+	usage.Users = 95
+	_, err = m.Check(ctx)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	// users cross their limit while offline messages are delivered
+	usage.Users = 101
+	usage.OfflineMessages = 3
+	over, err = m.Check(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []SoftLimitAlert{{Limit: SoftLimitUsers, Usage: 101, Threshold: 100}}, over)
+	require.Len(t, events, 3)
+	assert.Equal(t, SoftLimitEventCrossed, events[1].Type)
+	assert.Equal(t, SoftLimitAlert{Limit: SoftLimitUsers, Usage: 101, Threshold: 100}, events[1].Data)
+	assert.Equal(t, SoftLimitEventCleared, events[2].Type)
+	assert.Equal(t, SoftLimitAlert{Limit: SoftLimitOfflineMessages, Usage: 3, Threshold: 10}, events[2].Data)
+
+	rec := httptest.NewRecorder()
+	m.Handler()(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `# HELP icq_soft_limit_usage Usage measured against each soft limit.
+# TYPE icq_soft_limit_usage gauge
+icq_soft_limit_usage{limit="users"} 101
+icq_soft_limit_usage{limit="offline_messages"} 3
+# HELP icq_soft_limit_threshold The soft limit.
+# TYPE icq_soft_limit_threshold gauge
+icq_soft_limit_threshold{limit="users"} 100
+icq_soft_limit_threshold{limit="offline_messages"} 10
+# HELP icq_soft_limit_crossed Whether usage is at or over the soft limit.
+# TYPE icq_soft_limit_crossed gauge
+icq_soft_limit_crossed{limit="users"} 1
+icq_soft_limit_crossed{limit="offline_messages"} 0
+`, rec.Body.String())
+}
+
+func TestSoftLimitMonitor_NilSink(t *testing.T) {
+	m := NewSoftLimitMonitor(
+		softLimitUsageFunc(func() SoftLimitUsage { return SoftLimitUsage{DBSizeBytes: 2048} }),
+		SoftLimits{DBSizeBytes: 1024},
+		nil,
+		slog.Default(),
+	)
+	over, err := m.Check(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []SoftLimitAlert{{Limit: SoftLimitDBSize, Usage: 2048, Threshold: 1024}}, over)
+}
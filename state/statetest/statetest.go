@@ -0,0 +1,247 @@
+// Package statetest seeds a state.SQLiteUserStore with users, buddy lists,
+// privacy settings and offline messages, so that tests and demos can set up
+// realistic data in a few lines.
+//
+//	store := statetest.NewStore(t)
+//	seed := statetest.New(t, store)
+//	seed.Users("Alice", "Bob", "Carol")
+//	seed.BuddyList("Alice").Group("Friends", "Bob", "Carol").Save()
+//	seed.BuddyList("Carol").PDMode(wire.FeedbagPDModeDenySome).Deny("Bob").Save()
+//	seed.OfflineMessage("Bob", "Alice", "are you there?")
+package statetest
+
+import (
+	"context"
+	"math/rand/v2"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pchchv/go-icq/state"
+	"github.com/pchchv/go-icq/wire"
+)
+
+// Password is the password of the users created by Seeder.
+const Password = "welcome1"
+
+// TB is the part of testing.TB used by Seeder. Tools that seed data outside
+// of tests can implement it to handle failures.
+type TB interface {
+	Helper()
+	Fatalf(format string, args ...any)
+}
+
+// NewStore creates a SQLiteUserStore backed by a database file in a
+// temporary directory that is removed when the test ends.
+func NewStore(t testing.TB) *state.SQLiteUserStore {
+	t.Helper()
+	store, err := state.NewSQLiteUserStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("unable to create store: %s", err)
+	}
+	return store
+}
+
+// Seeder adds data to a store. Every method fails t if the data can't be
+// stored, so callers don't need to check errors.
+type Seeder struct {
+	t     TB
+	store *state.SQLiteUserStore
+	ctx   context.Context
+	nowFn func() time.Time
+}
+
+// New creates a Seeder that adds data to store.
+func New(t TB, store *state.SQLiteUserStore) *Seeder {
+	return &Seeder{
+		t:     t,
+		store: store,
+		ctx:   context.Background(),
+		nowFn: time.Now,
+	}
+}
+
+// User creates a user with Password as their password. A numeric screen
+// name creates an ICQ account with that UIN.
+func (s *Seeder) User(screenName string) state.User {
+	s.t.Helper()
+	user, err := state.NewStubUser(state.DisplayScreenName(screenName))
+	if err != nil {
+		s.t.Fatalf("unable to create user %s: %s", screenName, err)
+	}
+	if err := s.store.InsertUser(s.ctx, user); err != nil {
+		s.t.Fatalf("unable to insert user %s: %s", screenName, err)
+	}
+	return user
+}
+
+// Users creates a user for each screen name, as User does.
+func (s *Seeder) Users(screenNames ...string) []state.User {
+	s.t.Helper()
+	users := make([]state.User, 0, len(screenNames))
+	for _, sn := range screenNames {
+		users = append(users, s.User(sn))
+	}
+	return users
+}
+
+// BuddyList starts building the server-side buddy list (feedbag) of owner.
+// Nothing is stored until Save is called.
+func (s *Seeder) BuddyList(owner string) *BuddyListBuilder {
+	return &BuddyListBuilder{
+		seeder: s,
+		owner:  state.NewIdentScreenName(owner),
+		pdMode: wire.FeedbagPDModePermitAll,
+	}
+}
+
+// OfflineMessage stores an IM from sender for recipient to receive when
+// they next sign on.
+func (s *Seeder) OfflineMessage(sender, recipient, text string) state.OfflineMessage {
+	s.t.Helper()
+	frags, err := wire.ICBMFragmentList(text)
+	if err != nil {
+		s.t.Fatalf("unable to build message: %s", err)
+	}
+	msg := state.OfflineMessage{
+		Sent:      s.nowFn().UTC(),
+		Sender:    state.NewIdentScreenName(sender),
+		Recipient: state.NewIdentScreenName(recipient),
+		Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+			Cookie:     rand.Uint64(),
+			ChannelID:  wire.ICBMChannelIM,
+			ScreenName: wire.ScreenName(recipient),
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ICBMTLVAOLIMData, frags),
+					wire.NewTLVBE(wire.ICBMTLVStore, []byte{}),
+				},
+			},
+		},
+	}
+	if _, err := s.store.SaveMessage(s.ctx, msg); err != nil {
+		s.t.Fatalf("unable to save offline message: %s", err)
+	}
+	return msg
+}
+
+// buddyGroup is a group of buddies on a buddy list.
+type buddyGroup struct {
+	name    string
+	buddies []string
+}
+
+// BuddyListBuilder builds a user's server-side buddy list and privacy
+// settings. It is created by Seeder.BuddyList.
+type BuddyListBuilder struct {
+	seeder *Seeder
+	owner  state.IdentScreenName
+	groups []buddyGroup
+	permit []string
+	deny   []string
+	pdMode wire.FeedbagPDMode
+}
+
+// Group adds a group containing buddies.
+func (b *BuddyListBuilder) Group(name string, buddies ...string) *BuddyListBuilder {
+	b.groups = append(b.groups, buddyGroup{name: name, buddies: buddies})
+	return b
+}
+
+// Permit adds screen names to the permit (visible) list.
+func (b *BuddyListBuilder) Permit(screenNames ...string) *BuddyListBuilder {
+	b.permit = append(b.permit, screenNames...)
+	return b
+}
+
+// Deny adds screen names to the deny (block) list.
+func (b *BuddyListBuilder) Deny(screenNames ...string) *BuddyListBuilder {
+	b.deny = append(b.deny, screenNames...)
+	return b
+}
+
+// PDMode sets the permit/deny mode, which defaults to
+// wire.FeedbagPDModePermitAll.
+func (b *BuddyListBuilder) PDMode(mode wire.FeedbagPDMode) *BuddyListBuilder {
+	b.pdMode = mode
+	return b
+}
+
+// Items returns the feedbag items that Save stores: the root group, the
+// buddy groups and their buddies, the permit and deny lists and the PD
+// info item.
+func (b *BuddyListBuilder) Items() []wire.FeedbagItem {
+	var items []wire.FeedbagItem
+	itemID := uint16(1)
+	nextItemID := func() uint16 {
+		id := itemID
+		itemID++
+		return id
+	}
+
+	var groupIDs []uint16
+	for i, group := range b.groups {
+		groupID := uint16(i + 1)
+		groupIDs = append(groupIDs, groupID)
+
+		var buddyIDs []uint16
+		for _, buddy := range group.buddies {
+			id := nextItemID()
+			buddyIDs = append(buddyIDs, id)
+			items = append(items, wire.FeedbagItem{
+				ClassID: wire.FeedbagClassIdBuddy,
+				GroupID: groupID,
+				ItemID:  id,
+				Name:    buddy,
+			})
+		}
+
+		item := wire.FeedbagItem{ClassID: wire.FeedbagClassIdGroup, GroupID: groupID, Name: group.name}
+		b.mustSetOrder(&item, buddyIDs)
+		items = append(items, item)
+	}
+
+	root := wire.FeedbagItem{ClassID: wire.FeedbagClassIdGroup}
+	b.mustSetOrder(&root, groupIDs)
+	items = append(items, root)
+
+	for _, sn := range b.permit {
+		items = append(items, wire.FeedbagItem{ClassID: wire.FeedbagClassIDPermit, ItemID: nextItemID(), Name: sn})
+	}
+	for _, sn := range b.deny {
+		items = append(items, wire.FeedbagItem{ClassID: wire.FeedbagClassIDDeny, ItemID: nextItemID(), Name: sn})
+	}
+
+	pdInfo := wire.FeedbagItem{ClassID: wire.FeedbagClassIdPdinfo, ItemID: nextItemID()}
+	if err := pdInfo.SetPDMode(b.pdMode); err != nil {
+		b.seeder.t.Helper()
+		b.seeder.t.Fatalf("unable to set PD mode: %s", err)
+	}
+	items = append(items, pdInfo)
+
+	return items
+}
+
+// Save stores the buddy list and switches the owner to server-side buddy
+// lists, so that the list takes part in presence and privacy checks.
+func (b *BuddyListBuilder) Save() []wire.FeedbagItem {
+	b.seeder.t.Helper()
+	items := b.Items()
+	if err := b.seeder.store.UseFeedbag(b.seeder.ctx, b.owner); err != nil {
+		b.seeder.t.Fatalf("unable to enable feedbag for %s: %s", b.owner, err)
+	}
+	if err := b.seeder.store.FeedbagUpsert(b.seeder.ctx, b.owner, items); err != nil {
+		b.seeder.t.Fatalf("unable to store buddy list of %s: %s", b.owner, err)
+	}
+	return items
+}
+
+func (b *BuddyListBuilder) mustSetOrder(item *wire.FeedbagItem, order []uint16) {
+	if len(order) == 0 {
+		return
+	}
+	if err := item.SetOrder(order); err != nil {
+		b.seeder.t.Helper()
+		b.seeder.t.Fatalf("unable to set group order: %s", err)
+	}
+}
@@ -0,0 +1,72 @@
+package statetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/state"
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestSeeder(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(t)
+	seed := New(t, store)
+
+	users := seed.Users("Alice", "Bob", "Carol", "100001")
+	assert.True(t, users[3].IsICQ)
+
+	seed.BuddyList("Alice").Group("Friends", "Bob", "Carol").Save()
+	seed.BuddyList("Bob").Group("Friends", "Alice").Save()
+	seed.BuddyList("Carol").Group("Friends", "Alice").PDMode(wire.FeedbagPDModeDenySome).Deny("Bob").Save()
+
+	t.Run("users sign on with the default password", func(t *testing.T) {
+		user, err := store.User(ctx, state.NewIdentScreenName("Alice"))
+		require.NoError(t, err)
+		require.NotNil(t, user)
+		assert.True(t, user.ValidatePlaintextPass([]byte(Password)))
+	})
+
+	t.Run("buddy lists take part in relationships", func(t *testing.T) {
+		rels, err := store.AllRelationships(ctx, state.NewIdentScreenName("Bob"), nil)
+		require.NoError(t, err)
+
+		byUser := make(map[state.IdentScreenName]state.Relationship)
+		for _, rel := range rels {
+			byUser[rel.User] = rel
+		}
+		alice := byUser[state.NewIdentScreenName("Alice")]
+		assert.True(t, alice.IsOnYourList)
+		assert.True(t, alice.IsOnTheirList)
+		assert.True(t, byUser[state.NewIdentScreenName("Carol")].BlocksYou)
+	})
+
+	t.Run("group order lists the buddies", func(t *testing.T) {
+		items, err := store.Feedbag(ctx, state.NewIdentScreenName("Alice"))
+		require.NoError(t, err)
+		for _, item := range items {
+			if item.ClassID == wire.FeedbagClassIdGroup && item.Name == "Friends" {
+				order, ok := item.Order()
+				assert.True(t, ok)
+				assert.Len(t, order, 2)
+			}
+		}
+	})
+
+	t.Run("offline messages are delivered", func(t *testing.T) {
+		seed.OfflineMessage("Bob", "Alice", "are you there?")
+		msgs, err := store.RetrieveMessages(ctx, state.NewIdentScreenName("Alice"))
+		require.NoError(t, err)
+		require.Len(t, msgs, 1)
+		assert.Equal(t, state.NewIdentScreenName("Bob"), msgs[0].Sender)
+
+		data, ok := msgs[0].Message.Bytes(wire.ICBMTLVAOLIMData)
+		require.True(t, ok)
+		text, err := wire.UnmarshalICBMMessageText(data)
+		require.NoError(t, err)
+		assert.Equal(t, "are you there?", text)
+	})
+}
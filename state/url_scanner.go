@@ -0,0 +1,189 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// URLScanAction is what the URL scanner does with content that links to a
+// blocked URL.
+type URLScanAction uint8
+
+const (
+	// URLScanStrip removes blocked URLs from the content, leaving the rest
+	// of it, such as the text of a link, in place.
+	URLScanStrip URLScanAction = iota
+	// URLScanFlag lets the content through unchanged and logs it for
+	// moderators, without telling the sender.
+	URLScanFlag
+)
+
+// String returns the config name of the action.
+func (a URLScanAction) String() string {
+	switch a {
+	case URLScanStrip:
+		return "strip"
+	case URLScanFlag:
+		return "flag"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseURLScanAction returns the URLScanAction with the config name name,
+// "strip" or "flag".
+func ParseURLScanAction(name string) (URLScanAction, error) {
+	switch name {
+	case "strip":
+		return URLScanStrip, nil
+	case "flag":
+		return URLScanFlag, nil
+	default:
+		return 0, fmt.Errorf("unknown URL scan action %q", name)
+	}
+}
+
+// urlScanRegexp matches URLs in text and in HTML attributes such as href
+// and src. A URL ends at whitespace, a quote or the end of a tag.
+var urlScanRegexp = regexp.MustCompile(`(?i)\b(?:[a-z][a-z0-9+.-]*://|www\.)[^\s"'<>]+`)
+
+// URLBlocklist decides whether a URL is known to be malicious. It may be
+// backed by a static list, a DNS blocklist or a remote lookup service.
+type URLBlocklist interface {
+	// Blocked reports whether u is on the blocklist.
+	Blocked(ctx context.Context, u *url.URL) (bool, error)
+}
+
+// URLBlocklistFunc adapts a function to a URLBlocklist.
+type URLBlocklistFunc func(ctx context.Context, u *url.URL) (bool, error)
+
+// Blocked calls f(ctx, u).
+func (f URLBlocklistFunc) Blocked(ctx context.Context, u *url.URL) (bool, error) {
+	return f(ctx, u)
+}
+
+// HostBlocklist is a URLBlocklist of host names. A host blocks itself and
+// its subdomains, so "evil.example" also blocks "www.evil.example".
+type HostBlocklist map[string]struct{}
+
+// NewHostBlocklist creates a HostBlocklist of hosts, ignoring case and
+// empty entries.
+func NewHostBlocklist(hosts []string) HostBlocklist {
+	list := make(HostBlocklist, len(hosts))
+	for _, host := range hosts {
+		host = strings.Trim(strings.ToLower(strings.TrimSpace(host)), ".")
+		if host != "" {
+			list[host] = struct{}{}
+		}
+	}
+	return list
+}
+
+// Blocked reports whether the host of u or one of its parent domains is
+// on the list.
+func (l HostBlocklist) Blocked(_ context.Context, u *url.URL) (bool, error) {
+	host := strings.Trim(strings.ToLower(u.Hostname()), ".")
+	for host != "" {
+		if _, ok := l[host]; ok {
+			return true, nil
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			break
+		}
+		host = parent
+	}
+	return false, nil
+}
+
+// URLScanner checks the URLs embedded in profiles and away messages
+// against a URLBlocklist before they're stored, and strips or flags the
+// blocked ones. Old clients render this content in embedded browsers with
+// long-since-fixed vulnerabilities, so a link that's harmless to a modern
+// browser can compromise the user who views it.
+type URLScanner struct {
+	blocklist URLBlocklist
+	action    URLScanAction
+	events    *ModerationEvents
+	logger    *slog.Logger
+}
+
+// NewURLScanner creates a new instance of URLScanner.
+func NewURLScanner(blocklist URLBlocklist, action URLScanAction, logger *slog.Logger) *URLScanner {
+	return &URLScanner{
+		blocklist: blocklist,
+		action:    action,
+		logger:    logger,
+	}
+}
+
+// Scan checks the URLs in text, a profile or away message that sender
+// wants to publish. It returns the text to store, which has the blocked
+// URLs removed under URLScanStrip, and the blocked URLs. It returns an
+// error if the blocklist can't be consulted.
+func (s *URLScanner) Scan(ctx context.Context, sender IdentScreenName, text string) (string, []string, error) {
+	var blocked []string
+	verdicts := make(map[string]bool)
+	for _, raw := range urlScanRegexp.FindAllString(text, -1) {
+		if _, seen := verdicts[raw]; seen {
+			continue
+		}
+		ok, err := s.blocked(ctx, raw)
+		if err != nil {
+			return "", nil, fmt.Errorf("URLScanner.Scan: %w", err)
+		}
+		verdicts[raw] = ok
+		if ok {
+			blocked = append(blocked, raw)
+		}
+	}
+	if len(blocked) == 0 {
+		return text, nil, nil
+	}
+
+	s.events.urlsBlocked(ctx, sender, s.action, blocked)
+	if s.action == URLScanFlag {
+		s.logger.WarnContext(ctx, "flagged content with blocked URLs for moderators", "screen_name", sender,
+			"urls", blocked, "text", text)
+		return text, blocked, nil
+	}
+
+	s.logger.InfoContext(ctx, "stripped blocked URLs", "screen_name", sender, "urls", blocked)
+	stripped := urlScanRegexp.ReplaceAllStringFunc(text, func(raw string) string {
+		if verdicts[raw] {
+			return ""
+		}
+		return raw
+	})
+	return stripped, blocked, nil
+}
+
+// ScanProfile scans the text of profile, which sender wants to store with
+// SetProfile. See Scan.
+func (s *URLScanner) ScanProfile(ctx context.Context, sender IdentScreenName, profile UserProfile) (UserProfile, []string, error) {
+	text, blocked, err := s.Scan(ctx, sender, profile.ProfileText)
+	if err != nil {
+		return UserProfile{}, nil, err
+	}
+	profile.ProfileText = text
+	return profile, blocked, nil
+}
+
+// blocked parses raw, a URL found in content, and looks it up in the
+// blocklist. URLs without a scheme, such as "www.example.com", are treated
+// as http URLs, which is how clients open them. Text that doesn't parse as
+// a URL isn't a link a client can follow, so it's not blocked.
+func (s *URLScanner) blocked(ctx context.Context, raw string) (bool, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return false, nil
+	}
+	return s.blocklist.Blocked(ctx, u)
+}
@@ -0,0 +1,142 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseURLScanAction(t *testing.T) {
+	action, err := ParseURLScanAction("flag")
+	require.NoError(t, err)
+	assert.Equal(t, URLScanFlag, action)
+
+	_, err = ParseURLScanAction("quarantine")
+	assert.Error(t, err)
+}
+
+func TestHostBlocklist_Blocked(t *testing.T) {
+	list := NewHostBlocklist([]string{" Evil.Example ", "", "bad.test."})
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{url: "http://evil.example/x", want: true},
+		{url: "https://WWW.EVIL.EXAMPLE:8443/", want: true},
+		{url: "http://bad.test./", want: true},
+		{url: "http://notevil.example/", want: false},
+		{url: "http://example/", want: false},
+		{url: "http://evil.example.com/", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+			blocked, err := list.Blocked(context.Background(), u)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, blocked)
+		})
+	}
+}
+
+func TestURLScanner_Scan(t *testing.T) {
+	ctx := context.Background()
+	sender := NewIdentScreenName("Sender")
+	list := NewHostBlocklist([]string{"evil.example"})
+
+	tests := []struct {
+		name        string
+		action      URLScanAction
+		text        string
+		want        string
+		wantBlocked []string
+	}{
+		{
+			name:   "strip removes blocked links and keeps the rest",
+			action: URLScanStrip,
+			text: `<HTML><A HREF="http://evil.example/x.hta">free icons</A> ` +
+				`<A HREF="http://good.example/">my page</A> www.evil.example</HTML>`,
+			want: `<HTML><A HREF="">free icons</A> ` +
+				`<A HREF="http://good.example/">my page</A> </HTML>`,
+			wantBlocked: []string{"http://evil.example/x.hta", "www.evil.example"},
+		},
+		{
+			name:        "repeated URLs are reported once",
+			action:      URLScanStrip,
+			text:        "ftp://evil.example/a ftp://evil.example/a",
+			want:        " ",
+			wantBlocked: []string{"ftp://evil.example/a"},
+		},
+		{
+			name:   "clean text is left alone",
+			action: URLScanStrip,
+			text:   `away, see <a href='https://good.example'>here</a>`,
+			want:   `away, see <a href='https://good.example'>here</a>`,
+		},
+		{
+			name:        "flag lets text through unchanged",
+			action:      URLScanFlag,
+			text:        "brb http://evil.example",
+			want:        "brb http://evil.example",
+			wantBlocked: []string{"http://evil.example"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewURLScanner(list, tt.action, slog.Default())
+			got, blocked, err := scanner.Scan(ctx, sender, tt.text)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantBlocked, blocked)
+		})
+	}
+}
+
+func TestURLScanner_ScanProfile(t *testing.T) {
+	ctx := context.Background()
+	sender := NewIdentScreenName("Sender")
+	events, sent := newTestModerationEvents()
+
+	var looked []string
+	scanner := NewURLScanner(URLBlocklistFunc(func(_ context.Context, u *url.URL) (bool, error) {
+		looked = append(looked, u.String())
+		return u.Host == "evil.example", nil
+	}), URLScanStrip, slog.Default())
+	scanner.SetModerationEvents(events)
+
+	profile, blocked, err := scanner.ScanProfile(ctx, sender, UserProfile{
+		ProfileText: `hi <img src="http://evil.example/x.jpg">`,
+		MIMEType:    `text/aolrtf; charset="us-ascii"`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, UserProfile{
+		ProfileText: `hi <img src="">`,
+		MIMEType:    `text/aolrtf; charset="us-ascii"`,
+	}, profile)
+	assert.Equal(t, []string{"http://evil.example/x.jpg"}, blocked)
+	assert.Equal(t, []string{"http://evil.example/x.jpg"}, looked)
+
+	require.Len(t, *sent, 1)
+	assert.Equal(t, ModerationMessageBlocked, (*sent)[0].Type)
+	assert.Equal(t, ModerationEvent{
+		ScreenName: "sender",
+		Scope:      "profile",
+		Action:     "strip",
+		Reason:     "blocked URL: http://evil.example/x.jpg",
+	}, (*sent)[0].Data)
+
+	t.Run("blocklist error", func(t *testing.T) {
+		errLookup := errors.New("lookup failed")
+		scanner := NewURLScanner(URLBlocklistFunc(func(context.Context, *url.URL) (bool, error) {
+			return false, errLookup
+		}), URLScanStrip, slog.Default())
+		_, _, err := scanner.ScanProfile(ctx, sender, UserProfile{ProfileText: "www.example.com"})
+		assert.ErrorIs(t, err, errLookup)
+	})
+}
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
-	"strings"
 	"time"
 	"unicode"
 
@@ -21,8 +20,13 @@ var (
 	ErrAIMHandleLength        = errors.New("screen name must be between 3 and 16 characters")
 	ErrICQUINInvalidFormat    = errors.New("uin must be a number in the range 10000-2147483646")
 	ErrAIMHandleInvalidFormat = errors.New("screen name must start with a letter, cannot end with a space, and must contain only letters, numbers, and spaces")
+	ErrBirthDateInvalid       = errors.New("invalid birth date")
 )
 
+// minBirthYear is the earliest birth year accepted by
+// ICQMoreInfo.ValidateBirthDate.
+const minBirthYear = 1900
+
 type OfflineMessage struct {
 	Sent      time.Time
 	Sender    IdentScreenName
@@ -30,6 +34,39 @@ type OfflineMessage struct {
 	Message   wire.SNAC_0x04_0x06_ICBMChannelMsgToHost
 }
 
+// AutoResponseMsgType returns the ICQ auto-response message type that
+// corresponds to an ICQ status, checked from the most to the least
+// restrictive status bit. It returns false for statuses that don't carry an
+// auto-response, such as available or invisible.
+func AutoResponseMsgType(status uint32) (uint8, bool) {
+	switch {
+	case status&wire.OServiceUserStatusDND == wire.OServiceUserStatusDND:
+		return wire.ICBMMsgTypeAutoDND, true
+	case status&wire.OServiceUserStatusBusy == wire.OServiceUserStatusBusy:
+		return wire.ICBMMsgTypeAutoBusy, true
+	case status&wire.OServiceUserStatusOut == wire.OServiceUserStatusOut:
+		return wire.ICBMMsgTypeAutoNA, true
+	case status&wire.OServiceUserStatusChat == wire.OServiceUserStatusChat:
+		return wire.ICBMMsgTypeAutoFFC, true
+	case status&wire.OServiceUserStatusAway == wire.OServiceUserStatusAway:
+		return wire.ICBMMsgTypeAutoAway, true
+	}
+	return 0, false
+}
+
+// PendingContact is a contact received in an ICBMMsgTypeContacts message
+// that is offered to the recipient for auto-add on their next feedbag sync.
+type PendingContact struct {
+	// ScreenName is the contact being offered.
+	ScreenName IdentScreenName
+	// Nick is the nickname the sender has for the contact.
+	Nick string
+	// Sender is the user who sent the contact.
+	Sender IdentScreenName
+	// Received is when the contact was received.
+	Received time.Time
+}
+
 // Category represents an AIM directory category.
 type Category struct {
 	// ID is the category ID
@@ -231,9 +268,13 @@ type IdentScreenName struct {
 
 // NewIdentScreenName creates a new IdentScreenName.
 func NewIdentScreenName(screenName string) IdentScreenName {
-	str := strings.ReplaceAll(screenName, " ", "")
-	str = strings.ToLower(str)
-	return IdentScreenName{screenName: str}
+	return IdentScreenName{screenName: wire.ScreenName(screenName).Ident()}
+}
+
+// NewIdentScreenNameFromWire creates a new IdentScreenName from a screen
+// name read from a SNAC.
+func NewIdentScreenNameFromWire(screenName wire.ScreenName) IdentScreenName {
+	return IdentScreenName{screenName: screenName.Ident()}
 }
 
 // String returns the string representation of the IdentScreenName.
@@ -258,6 +299,12 @@ func (s DisplayScreenName) IdentScreenName() IdentScreenName {
 	return NewIdentScreenName(string(s))
 }
 
+// Wire returns the DisplayScreenName for use in a SNAC, preserving the
+// user-defined casing and spaces.
+func (s DisplayScreenName) Wire() wire.ScreenName {
+	return wire.ScreenName(s)
+}
+
 // String returns the original display string of the screen name,
 // preserving the user-defined casing and spaces.
 func (s DisplayScreenName) String() string {
@@ -350,6 +397,9 @@ type User struct {
 	SuspendedStatus uint16
 	// EmailAddress is the email address set by the AIM client.
 	EmailAddress string
+	// EmailVerified indicates whether the user confirmed EmailAddress by
+	// following the link sent to it.
+	EmailVerified bool
 	// ICQAffiliations holds information about the user's affiliations,
 	// including past and current affiliations.
 	ICQAffiliations ICQAffiliations
@@ -381,6 +431,22 @@ type User struct {
 	LastWarnLevel uint16
 	// OfflineMsgCount is the count of offline messages for the user.
 	OfflineMsgCount int
+	// ExpiresAt is when a trial or temporary account expires. The zero
+	// value means the account never expires.
+	ExpiresAt time.Time
+	// ExpiredStatus is the login error reported for an account whose
+	// ExpiresAt has passed, wire.LoginErrSuspendedAccountAge, or 0 if the
+	// account hasn't expired. Expired accounts are refused at login like
+	// suspended ones.
+	ExpiredStatus uint16
+	// CreatedAt is when the account was registered. The zero value means
+	// the account predates registration times being recorded.
+	CreatedAt time.Time
+}
+
+// Expired indicates whether the account has an expiry time that has passed.
+func (u User) Expired(now time.Time) bool {
+	return !u.ExpiresAt.IsZero() && !now.Before(u.ExpiresAt)
 }
 
 // NewStubUser creates a new user with canned credentials.
@@ -461,25 +527,61 @@ func (u *User) ValidatePlaintextPass(plaintextPass []byte) bool {
 	return bytes.Equal(u.WeakMD5Pass, md5Hash)
 }
 
-// Age returns the user's age relative to their birthday and timeNow.
+// Age returns the user's age relative to their birthday and timeNow. It
+// returns 0 if the birth year isn't set or the birth date is incomplete or
+// in the future. Users born on February 29 turn a year older on March 1 in
+// non-leap years.
 func (u *User) Age(timeNow func() time.Time) uint16 {
 	now := timeNow().UTC()
+	info := u.ICQMoreInfo
 	switch {
-	case u.ICQMoreInfo.BirthYear > 0 && u.ICQMoreInfo.BirthDay == 0 && u.ICQMoreInfo.BirthMonth == 0:
-		bday := time.Date(int(u.ICQMoreInfo.BirthYear), time.January, 1, 0, 0, 0, 0, time.UTC)
-		return uint16(now.Year() - bday.Year())
-	case u.ICQMoreInfo.BirthYear > 0 && u.ICQMoreInfo.BirthDay > 0 && u.ICQMoreInfo.BirthMonth > 0:
-		bday := time.Date(int(u.ICQMoreInfo.BirthYear), time.Month(u.ICQMoreInfo.BirthMonth), int(u.ICQMoreInfo.BirthDay), 0, 0, 0, 0, time.UTC)
-		years := now.Year() - bday.Year()
-		if now.YearDay() < bday.YearDay() {
+	case info.BirthYear > 0 && info.BirthDay == 0 && info.BirthMonth == 0:
+		return uint16(max(now.Year()-int(info.BirthYear), 0))
+	case info.BirthYear > 0 && info.BirthDay > 0 && info.BirthMonth > 0:
+		years := now.Year() - int(info.BirthYear)
+		if now.Month() < time.Month(info.BirthMonth) ||
+			(now.Month() == time.Month(info.BirthMonth) && now.Day() < int(info.BirthDay)) {
 			years--
 		}
-		return uint16(years)
+		return uint16(max(years, 0))
 	default: // invalid date
 		return 0
 	}
 }
 
+// ValidateBirthDate returns ErrBirthDateInvalid if the birth date in m is
+// impossible or after now. The birth date may be left unset, set to just a
+// year, set to just a month and day, or set in full.
+func (m ICQMoreInfo) ValidateBirthDate(now time.Time) error {
+	if (m.BirthMonth == 0) != (m.BirthDay == 0) {
+		return fmt.Errorf("%w: month and day must be set together", ErrBirthDateInvalid)
+	}
+	if m.BirthYear > 0 && (m.BirthYear < minBirthYear || int(m.BirthYear) > now.Year()) {
+		return fmt.Errorf("%w: year %d is out of range", ErrBirthDateInvalid, m.BirthYear)
+	}
+	if m.BirthMonth == 0 {
+		return nil
+	}
+	if m.BirthMonth > 12 {
+		return fmt.Errorf("%w: month %d is out of range", ErrBirthDateInvalid, m.BirthMonth)
+	}
+
+	// without a year, check the day against a leap year so that February 29
+	// is accepted
+	year := int(m.BirthYear)
+	if year == 0 {
+		year = 2000
+	}
+	bday := time.Date(year, time.Month(m.BirthMonth), int(m.BirthDay), 0, 0, 0, 0, time.UTC)
+	if bday.Day() != int(m.BirthDay) {
+		return fmt.Errorf("%w: %s has no day %d", ErrBirthDateInvalid, time.Month(m.BirthMonth), m.BirthDay)
+	}
+	if m.BirthYear > 0 && bday.After(now) {
+		return fmt.Errorf("%w: date is in the future", ErrBirthDateInvalid)
+	}
+	return nil
+}
+
 // UserProfile represents a user's profile information.
 type UserProfile struct {
 	// ProfileText is the free-form profile body content.
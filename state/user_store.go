@@ -12,12 +12,15 @@ import (
 	"math"
 	"net/http"
 	"net/mail"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
 	"github.com/golang-migrate/migrate/v4/source/httpfs"
 	"github.com/pchchv/go-icq/wire"
 	"modernc.org/sqlite"
@@ -32,7 +35,17 @@ var (
 
 	ErrBARTItemExists          = errors.New("BART asset already exists")
 	ErrBARTItemNotFound        = errors.New("BART asset not found")
+	ErrEmailVerificationFailed = errors.New("email verification token is invalid or expired")
+	ErrFeedbagBackupNotFound   = errors.New("feedbag backup not found")
+	ErrFeedbagGroupExists      = errors.New("feedbag group name already in use")
+	ErrFeedbagGroupInvalid     = errors.New("invalid feedbag group")
+	ErrFeedbagGroupNotFound    = errors.New("feedbag group not found")
 	ErrOfflineInboxFull        = errors.New("offline inbox full")
+	ErrPasswordResetFailed     = errors.New("password reset code is invalid or expired")
+	ErrRenameNotAllowed        = errors.New("ICQ accounts can't be renamed")
+	ErrScreenNameAliasNotFound = errors.New("screen name alias not found")
+	ErrAccountLinked           = errors.New("account is already linked")
+	ErrAccountLinkInvalid      = errors.New("an AIM screen name can only be linked to an ICQ UIN")
 	ErrKeywordInUse            = errors.New("can't delete keyword that is associated with a user")
 	ErrKeywordExists           = errors.New("keyword already exists")
 	ErrKeywordNotFound         = errors.New("keyword not found")
@@ -42,23 +55,120 @@ var (
 	errTooManyKeywords         = errors.New("there are too many keywords")
 )
 
+// FeedbagBARTReference is a BART asset referenced by a feedbag item, such as
+// a buddy icon or a per-buddy arrival or departure sound.
+type FeedbagBARTReference struct {
+	// ClassID is the class of the feedbag item that holds the reference.
+	ClassID uint16
+	// ItemName is the name of the feedbag item that holds the reference.
+	ItemName string
+	// ID identifies the referenced BART asset.
+	ID wire.BARTID
+	// Stored indicates whether the asset is present in the BART store.
+	Stored bool
+}
+
 // BARTItem represents a BART asset with its hash and type.
 type BARTItem struct {
 	Hash string
 	Type uint16
 }
 
+// BARTBlob is a stored BART item including its body.
+type BARTBlob struct {
+	Hash []byte
+	Body []byte
+	Type uint16
+}
+
 // SQLiteUserStore stores user feedbag (buddy list), profile,
 // and authentication credentials information in a SQLite database.
 type SQLiteUserStore struct {
-	db *sql.DB
+	// db runs the store's queries. It's the connection pool, or the
+	// transaction of a store handed out by WithTx.
+	db sqlConn
+	// pool is the underlying connection pool.
+	pool *sql.DB
+	// nameFilter, if set, lets lookups skip the database for screen names
+	// that don't exist. See EnableScreenNameFilter.
+	nameFilter *ScreenNameFilter
+	// repairedIndexes are the missing indexes recreated when the store was
+	// opened.
+	repairedIndexes []string
+	// rejectCustomFeedbagClasses refuses feedbag items of unknown classes
+	// above wire.FeedbagClassIdMaxPredefined. See
+	// RejectCustomFeedbagClasses.
+	rejectCustomFeedbagClasses bool
+	// guestPolicy reserves the guest screen name prefix for guest sessions.
+	// See ReserveGuestScreenNames.
+	guestPolicy GuestPolicy
+	// nowFn returns the current time, such as to tell whether an account
+	// has expired.
+	nowFn func() time.Time
+	// latency records how long offline messages take to store. See
+	// SetDeliveryLatency.
+	latency *DeliveryLatency
+}
+
+// sqliteDSN returns the data source name of the SQLite database at path with
+// the URI parameters in query. Characters that SQLite would read as the start
+// of the query string or fragment are percent-encoded, so that any file
+// system path can be opened, such as one returned by os.MkdirTemp.
+func sqliteDSN(path string, query string) string {
+	path = strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23").Replace(path)
+	if query == "" {
+		return "file:" + path
+	}
+	return "file:" + path + "?" + query
+}
+
+// storeDSN returns the data source name that NewSQLiteUserStore opens for
+// dbFilePath and whether the database is opened read-only. Foreign keys are
+// enforced unless a URI sets the pragma itself.
+func storeDSN(dbFilePath string) (string, bool, error) {
+	if dbFilePath == ":memory:" {
+		return "file::memory:?_pragma=foreign_keys=on", false, nil
+	}
+	if !strings.HasPrefix(dbFilePath, "file:") {
+		return sqliteDSN(dbFilePath, "_pragma=foreign_keys=on"), false, nil
+	}
+
+	dsn, _, _ := strings.Cut(dbFilePath, "#")
+	_, rawQuery, _ := strings.Cut(dsn, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid database URI %s: %w", dbFilePath, err)
+	}
+	if !slices.ContainsFunc(query["_pragma"], func(p string) bool {
+		return strings.HasPrefix(strings.ToLower(p), "foreign_keys")
+	}) {
+		if rawQuery == "" {
+			dsn = strings.TrimSuffix(dsn, "?") + "?_pragma=foreign_keys=on"
+		} else {
+			dsn += "&_pragma=foreign_keys=on"
+		}
+	}
+	readOnly := query.Get("mode") == "ro" || query.Get("immutable") == "1"
+	return dsn, readOnly, nil
 }
 
 // NewSQLiteUserStore creates a new instance of SQLiteUserStore.
 // If the database does not already exist,
 // a new one is created with the required schema.
+//
+// Besides a file path, dbFilePath may be ":memory:" for a private
+// in-memory database that lasts as long as the store, or a SQLite URI
+// starting with "file:", such as "file:icq?mode=memory&cache=shared" for an
+// in-memory database shared by the stores of a process, or
+// "file:go-icq.sqlite?mode=ro" for a read-only connection to a live
+// database. Read-only databases aren't migrated; their schema must already
+// be up to date.
 func NewSQLiteUserStore(dbFilePath string) (*SQLiteUserStore, error) {
-	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=foreign_keys=on", dbFilePath))
+	dsn, readOnly, err := storeDSN(dbFilePath)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -71,15 +181,46 @@ func NewSQLiteUserStore(dbFilePath string) (*SQLiteUserStore, error) {
 	// thus avoiding any potential locking issues.
 	db.SetMaxOpenConns(1)
 
-	store := &SQLiteUserStore{db: db}
+	// Refuse to start on a damaged database rather than failing later
+	// with opaque query errors.
+	store := &SQLiteUserStore{db: db, pool: db, nowFn: time.Now}
+	if err := store.quickCheck(context.Background()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("integrity check of %s failed: %w", dbFilePath, err)
+	}
+	if readOnly {
+		if err := store.checkMigrated(); err != nil {
+			db.Close()
+			return nil, err
+		}
+		return store, nil
+	}
 	if err := store.runMigrations(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
+	if store.repairedIndexes, err = store.verifySchema(context.Background()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("schema check of %s failed: %w", dbFilePath, err)
+	}
 
 	return store, nil
 }
 
+// Ping verifies that the database is reachable and able to answer queries.
+func (us SQLiteUserStore) Ping(ctx context.Context) error {
+	var one int
+	if err := us.db.QueryRowContext(ctx, `SELECT 1`).Scan(&one); err != nil {
+		return fmt.Errorf("Ping: %w", err)
+	}
+	return nil
+}
+
 func (us SQLiteUserStore) User(ctx context.Context, screenName IdentScreenName) (*User, error) {
+	if !us.mayExist(screenName) {
+		return nil, nil
+	}
+
 	users, err := us.queryUsers(ctx, `identScreenName = ?`, []any{screenName.String()})
 	if err != nil {
 		return nil, fmt.Errorf("User: %w", err)
@@ -96,9 +237,13 @@ func (us SQLiteUserStore) InsertUser(ctx context.Context, u User) error {
 	if u.DisplayScreenName.IsUIN() && !u.IsICQ {
 		return errors.New("inserting user with UIN and isICQ=false")
 	}
+	if err := us.checkGuestScreenName(u.IdentScreenName); err != nil {
+		return err
+	}
 	q := `
-		INSERT INTO users (identScreenName, displayScreenName, authKey, weakMD5Pass, strongMD5Pass, isICQ, isBot)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO users (identScreenName, displayScreenName, authKey, weakMD5Pass, strongMD5Pass, isICQ, isBot, createdAt)
+		SELECT ?, ?, ?, ?, ?, ?, ?, UNIXEPOCH()
+		WHERE NOT EXISTS (SELECT 1 FROM screenNameAlias WHERE alias = ?)
 		ON CONFLICT (identScreenName) DO NOTHING
 	`
 	result, err := us.db.ExecContext(ctx,
@@ -110,17 +255,21 @@ func (us SQLiteUserStore) InsertUser(ctx context.Context, u User) error {
 		u.StrongMD5Pass,
 		u.IsICQ,
 		u.IsBot,
+		u.IdentScreenName.String(),
 	)
 	if err != nil {
-		return err
+		return fmt.Errorf("InsertUser: %w", err)
 	}
 
 	if rowsAffected, err := result.RowsAffected(); err != nil {
-		return err
+		return fmt.Errorf("InsertUser: %w", err)
 	} else if rowsAffected == 0 {
 		return ErrDupUser
 	}
 
+	if us.nameFilter != nil {
+		us.nameFilter.Add(u.IdentScreenName)
+	}
 	return nil
 }
 
@@ -130,15 +279,18 @@ func (us SQLiteUserStore) DeleteUser(ctx context.Context, screenName IdentScreen
 	`
 	result, err := us.db.ExecContext(ctx, q, screenName.String())
 	if err != nil {
-		return err
+		return fmt.Errorf("DeleteUser: %w", err)
 	}
 
 	if rowsAffected, err := result.RowsAffected(); err != nil {
-		return err
+		return fmt.Errorf("DeleteUser: %w", err)
 	} else if rowsAffected == 0 {
 		return ErrNoUser
 	}
 
+	if us.nameFilter != nil {
+		us.nameFilter.Removed()
+	}
 	return nil
 }
 
@@ -146,7 +298,7 @@ func (us SQLiteUserStore) AllUsers(ctx context.Context) ([]User, error) {
 	q := `SELECT identScreenName, displayScreenName, isICQ, isBot FROM users`
 	rows, err := us.db.QueryContext(ctx, q)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("AllUsers: %w", err)
 	}
 	defer rows.Close()
 
@@ -155,7 +307,7 @@ func (us SQLiteUserStore) AllUsers(ctx context.Context) ([]User, error) {
 		var identSN, displaySN string
 		var isICQ, isBot bool
 		if err := rows.Scan(&identSN, &displaySN, &isICQ, &isBot); err != nil {
-			return nil, err
+			return nil, fmt.Errorf("AllUsers: %w", err)
 		}
 		users = append(users, User{
 			IdentScreenName:   NewIdentScreenName(identSN),
@@ -166,13 +318,17 @@ func (us SQLiteUserStore) AllUsers(ctx context.Context) ([]User, error) {
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("AllUsers: %w", err)
 	}
 
 	return users, nil
 }
 
 func (us SQLiteUserStore) FindByUIN(ctx context.Context, UIN uint32) (User, error) {
+	if !us.mayExist(NewIdentScreenName(strconv.Itoa(int(UIN)))) {
+		return User{}, ErrNoUser
+	}
+
 	users, err := us.queryUsers(ctx, `identScreenName = ?`, []any{strconv.Itoa(int(UIN))})
 	if err != nil {
 		return User{}, fmt.Errorf("FindByUIN: %w", err)
@@ -247,6 +403,25 @@ func (us SQLiteUserStore) FindByICQInterests(ctx context.Context, code uint16, k
 	return users, nil
 }
 
+// FindByICQBirthday returns the users whose birthday falls on the UTC
+// calendar day of day. On February 28 of non-leap years, it also returns
+// users born on February 29.
+func (us SQLiteUserStore) FindByICQBirthday(ctx context.Context, day time.Time) ([]User, error) {
+	day = day.UTC()
+	cond := `icq_moreInfo_birthMonth = ? AND icq_moreInfo_birthDay = ?`
+	args := []any{int(day.Month()), day.Day()}
+	if day.Month() == time.February && day.Day() == 28 && day.AddDate(0, 0, 1).Month() == time.March {
+		cond = `icq_moreInfo_birthMonth = ? AND icq_moreInfo_birthDay IN (?, 29)`
+	}
+
+	users, err := us.queryUsers(ctx, cond, args)
+	if err != nil {
+		return users, fmt.Errorf("FindByICQBirthday: %w", err)
+	}
+
+	return users, nil
+}
+
 func (us SQLiteUserStore) FindByICQKeyword(ctx context.Context, keyword string) ([]User, error) {
 	var args []any
 	var clauses []string
@@ -326,8 +501,10 @@ func (us SQLiteUserStore) FindByAIMNameAndAddr(ctx context.Context, info AIMName
 	return users, nil
 }
 
+// FindByAIMEmail returns the user with the verified AIM email address
+// email. Unverified addresses are never matched.
 func (us SQLiteUserStore) FindByAIMEmail(ctx context.Context, email string) (User, error) {
-	users, err := us.queryUsers(ctx, `emailAddress = ?`, []any{email})
+	users, err := us.queryUsers(ctx, `emailAddress = ? AND emailVerified`, []any{email})
 	if err != nil {
 		return User{}, fmt.Errorf("FindByAIMEmail: %w", err)
 	}
@@ -346,7 +523,7 @@ func (us SQLiteUserStore) FindByAIMKeyword(ctx context.Context, keyword string)
 	`
 	users, err := us.queryUsers(ctx, where, []any{keyword})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("FindByAIMKeyword: %w", err)
 	}
 
 	return users, nil
@@ -377,9 +554,9 @@ func (us SQLiteUserStore) SetUserNotes(ctx context.Context, name IdentScreenName
 }
 
 func (us SQLiteUserStore) SetUserPassword(ctx context.Context, screenName IdentScreenName, newPassword string) error {
-	tx, err := us.db.Begin()
+	tx, err := us.begin(ctx)
 	if err != nil {
-		return err
+		return fmt.Errorf("SetUserPassword: %w", err)
 	}
 
 	defer func() {
@@ -416,12 +593,12 @@ func (us SQLiteUserStore) SetUserPassword(ctx context.Context, screenName IdentS
 	`
 	result, err := tx.ExecContext(ctx, q, u.AuthKey, u.WeakMD5Pass, u.StrongMD5Pass, screenName.String())
 	if err != nil {
-		return err
+		return fmt.Errorf("SetUserPassword: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return err
+		return fmt.Errorf("SetUserPassword: %w", err)
 	}
 
 	if rowsAffected == 0 {
@@ -430,7 +607,7 @@ func (us SQLiteUserStore) SetUserPassword(ctx context.Context, screenName IdentS
 		var exists int
 		err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE identScreenName = ?", u.IdentScreenName.String()).Scan(&exists)
 		if err != nil {
-			return err // Handle possible SQL errors during the select
+			return fmt.Errorf("SetUserPassword: %w", err) // Handle possible SQL errors during the select
 		}
 		if exists == 0 {
 			err = ErrNoUser // User does not exist
@@ -546,7 +723,13 @@ func (us SQLiteUserStore) SetWorkInfo(ctx context.Context, name IdentScreenName,
 	return nil
 }
 
+// SetMoreInfo sets the user's ICQ "more info" fields. It returns
+// ErrBirthDateInvalid if the birth date is impossible or in the future.
 func (us SQLiteUserStore) SetMoreInfo(ctx context.Context, name IdentScreenName, data ICQMoreInfo) error {
+	if err := data.ValidateBirthDate(time.Now()); err != nil {
+		return err
+	}
+
 	q := `
 		UPDATE users SET
 			icq_moreInfo_birthDay = ?,
@@ -834,9 +1017,9 @@ func (us SQLiteUserStore) SetPDMode(ctx context.Context, me IdentScreenName, pdM
 		return nil
 	}
 
-	tx, err := us.db.Begin()
+	tx, err := us.begin(ctx)
 	if err != nil {
-		return err
+		return fmt.Errorf("SetPDMode: %w", err)
 	}
 
 	defer func() {
@@ -863,6 +1046,10 @@ func (us SQLiteUserStore) SetPDMode(ctx context.Context, me IdentScreenName, pdM
 }
 
 func (us SQLiteUserStore) Feedbag(ctx context.Context, screenName IdentScreenName) ([]wire.FeedbagItem, error) {
+	return feedbagTx(ctx, us.db, screenName)
+}
+
+func feedbagTx(ctx context.Context, tx sqlConn, screenName IdentScreenName) ([]wire.FeedbagItem, error) {
 	q := `
 		SELECT
 			groupID,
@@ -873,7 +1060,7 @@ func (us SQLiteUserStore) Feedbag(ctx context.Context, screenName IdentScreenNam
 		FROM feedbag
 		WHERE screenName = ?
 	`
-	rows, err := us.db.QueryContext(ctx, q, screenName.String())
+	rows, err := tx.QueryContext(ctx, q, screenName.String())
 	if err != nil {
 		return nil, err
 	}
@@ -909,7 +1096,20 @@ func (us SQLiteUserStore) UseFeedbag(ctx context.Context, screenName IdentScreen
 	return err
 }
 
+// FeedbagUpsert inserts or updates feedbag items. It returns
+// wire.ErrInvalidFeedbagItem without storing anything if any item fails
+// validation; use FeedbagItemStatus to report per-item results to the
+// client.
 func (us SQLiteUserStore) FeedbagUpsert(ctx context.Context, screenName IdentScreenName, items []wire.FeedbagItem) error {
+	for i, item := range items {
+		if err := us.validateFeedbagItem(item); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+	return feedbagUpsertTx(ctx, us.db, screenName, items)
+}
+
+func feedbagUpsertTx(ctx context.Context, tx sqlConn, screenName IdentScreenName, items []wire.FeedbagItem) error {
 	q := `
 		INSERT INTO feedbag (screenName, groupID, itemID, classID, name, attributes, pdMode, lastModified)
 		VALUES (?, ?, ?, ?, ?, ?, ?, UNIXEPOCH())
@@ -935,15 +1135,15 @@ func (us SQLiteUserStore) FeedbagUpsert(ctx context.Context, screenName IdentScr
 
 		pdMode := uint8(0)
 		if item.ClassID == wire.FeedbagClassIdPdinfo {
-			var hasMode bool
-			pdMode, hasMode = item.Uint8(wire.FeedbagAttributesPdMode)
+			mode, hasMode := item.PDMode()
 			if !hasMode {
 				// by default, QIP sends a PD info item entry with no mode
-				pdMode = uint8(wire.FeedbagPDModePermitAll)
+				mode = wire.FeedbagPDModePermitAll
 			}
+			pdMode = uint8(mode)
 		}
 
-		_, err := us.db.ExecContext(ctx,
+		_, err := tx.ExecContext(ctx,
 			q,
 			screenName.String(),
 			item.GroupID,
@@ -967,23 +1167,228 @@ func (us SQLiteUserStore) FeedbagLastModified(ctx context.Context, screenName Id
 	return time.Unix(lastModified.Int64, 0), err
 }
 
-func (us SQLiteUserStore) FeedbagDelete(ctx context.Context, screenName IdentScreenName, items []wire.FeedbagItem) error {
-	// todo add transaction
-	q := `DELETE FROM feedbag WHERE screenName = ? AND itemID = ?`
+// FeedbagDelete removes items from the user's feedbag. If the deletion
+// removes buddies or groups, the feedbag is first backed up as described in
+// BackupFeedbag.
+func (us SQLiteUserStore) FeedbagDelete(ctx context.Context, screenName IdentScreenName, items []wire.FeedbagItem) (err error) {
+	var tx storeTx
+	tx, err = us.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if slices.ContainsFunc(items, isSignificantFeedbagItem) {
+		if err = backupFeedbagTx(ctx, tx, screenName, time.Now(), false); err != nil {
+			return fmt.Errorf("backup feedbag: %w", err)
+		}
+	}
 
+	q := `DELETE FROM feedbag WHERE screenName = ? AND itemID = ?`
 	for _, item := range items {
-		if _, err := us.db.ExecContext(ctx, q, screenName.String(), item.ItemID); err != nil {
-			return err
+		if _, err = tx.ExecContext(ctx, q, screenName.String(), item.ItemID); err != nil {
+			return fmt.Errorf("FeedbagDelete: %w", err)
 		}
 	}
 
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
 	return nil
 }
 
+// RenameFeedbagGroup atomically renames the feedbag group identified by
+// groupID. Within the same transaction it repairs the order attributes that
+// depend on the group: the root group's order is made to list groupID, and
+// the group's own order is pruned of items that no longer exist and extended
+// with members it doesn't list yet. It returns ErrFeedbagGroupNotFound if the
+// group doesn't exist and ErrFeedbagGroupExists if another group already has
+// the name.
+func (us SQLiteUserStore) RenameFeedbagGroup(ctx context.Context, screenName IdentScreenName, groupID uint16, newName string) (err error) {
+	if groupID == 0 {
+		return fmt.Errorf("%w: the root group can't be renamed", ErrFeedbagGroupInvalid)
+	}
+	if newName == "" {
+		return fmt.Errorf("%w: group name must not be empty", ErrFeedbagGroupInvalid)
+	}
+
+	var tx storeTx
+	tx, err = us.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var group wire.FeedbagItem
+	group, err = feedbagGroupTx(ctx, tx, screenName, groupID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrFeedbagGroupNotFound
+		return err
+	} else if err != nil {
+		return fmt.Errorf("select group: %w", err)
+	}
+
+	var dups int
+	q := `
+		SELECT COUNT(*)
+		FROM feedbag
+		WHERE screenName = ?
+		  AND classID = ?
+		  AND itemID = 0
+		  AND groupID != ?
+		  AND name = ? COLLATE NOCASE
+	`
+	if err = tx.QueryRowContext(ctx, q, screenName.String(), wire.FeedbagClassIdGroup, groupID, newName).Scan(&dups); err != nil {
+		return fmt.Errorf("select duplicate groups: %w", err)
+	}
+	if dups > 0 {
+		err = ErrFeedbagGroupExists
+		return err
+	}
+
+	var members []uint16
+	members, err = feedbagIDsTx(ctx, tx, `SELECT itemID FROM feedbag WHERE screenName = ? AND groupID = ? AND itemID != 0 ORDER BY itemID`, screenName.String(), groupID)
+	if err != nil {
+		return fmt.Errorf("select group members: %w", err)
+	}
+	group.Name = newName
+	if err = reconcileFeedbagOrder(&group, members); err != nil {
+		return fmt.Errorf("reconcile group order: %w", err)
+	}
+	if err = updateFeedbagGroupTx(ctx, tx, screenName, group); err != nil {
+		return fmt.Errorf("update group: %w", err)
+	}
+
+	if err = reconcileRootOrderTx(ctx, tx, screenName); err != nil {
+		return fmt.Errorf("reconcile root order: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
+
+// reconcileRootOrderTx repairs the root group's order so that it lists
+// exactly the user's groups. Feedbags without a root group are left alone.
+func reconcileRootOrderTx(ctx context.Context, tx sqlConn, screenName IdentScreenName) error {
+	root, err := feedbagGroupTx(ctx, tx, screenName, 0)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// clients that never created a root group have no order to repair
+		return nil
+	case err != nil:
+		return fmt.Errorf("select root group: %w", err)
+	}
+	groups, err := feedbagIDsTx(ctx, tx, `SELECT groupID FROM feedbag WHERE screenName = ? AND classID = ? AND itemID = 0 AND groupID != 0 ORDER BY groupID`, screenName.String(), wire.FeedbagClassIdGroup)
+	if err != nil {
+		return fmt.Errorf("select groups: %w", err)
+	}
+	if err := reconcileFeedbagOrder(&root, groups); err != nil {
+		return err
+	}
+	if err := updateFeedbagGroupTx(ctx, tx, screenName, root); err != nil {
+		return fmt.Errorf("update root group: %w", err)
+	}
+	return nil
+}
+
+// feedbagGroupTx returns the group item for groupID, or sql.ErrNoRows if
+// the group doesn't exist.
+func feedbagGroupTx(ctx context.Context, tx sqlConn, screenName IdentScreenName, groupID uint16) (wire.FeedbagItem, error) {
+	q := `
+		SELECT name, attributes
+		FROM feedbag
+		WHERE screenName = ?
+		  AND groupID = ?
+		  AND itemID = 0
+		  AND classID = ?
+	`
+	item := wire.FeedbagItem{GroupID: groupID, ClassID: wire.FeedbagClassIdGroup}
+	var attrs []byte
+	if err := tx.QueryRowContext(ctx, q, screenName.String(), groupID, wire.FeedbagClassIdGroup).Scan(&item.Name, &attrs); err != nil {
+		return item, err
+	}
+	if err := wire.UnmarshalBE(&item.TLVLBlock, bytes.NewBuffer(attrs)); err != nil {
+		return item, err
+	}
+	return item, nil
+}
+
+func feedbagIDsTx(ctx context.Context, tx sqlConn, q string, args ...any) ([]uint16, error) {
+	rows, err := tx.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uint16
+	for rows.Next() {
+		var id uint16
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func updateFeedbagGroupTx(ctx context.Context, tx sqlConn, screenName IdentScreenName, group wire.FeedbagItem) error {
+	buf := &bytes.Buffer{}
+	if err := wire.MarshalBE(group.TLVLBlock, buf); err != nil {
+		return err
+	}
+	q := `
+		UPDATE feedbag
+		SET name         = ?,
+			attributes   = ?,
+			lastModified = UNIXEPOCH()
+		WHERE screenName = ?
+		  AND groupID = ?
+		  AND itemID = 0
+	`
+	_, err := tx.ExecContext(ctx, q, group.Name, buf.Bytes(), screenName.String(), group.GroupID)
+	return err
+}
+
+// reconcileFeedbagOrder drops IDs from the group's order that aren't in
+// existing and appends existing IDs the order doesn't list yet, preserving
+// the relative order of everything else. Groups without an order attribute
+// are left alone.
+func reconcileFeedbagOrder(group *wire.FeedbagItem, existing []uint16) error {
+	order, ok := group.Order()
+	if !ok {
+		return nil
+	}
+
+	var kept []uint16
+	for _, id := range order {
+		if slices.Contains(existing, id) && !slices.Contains(kept, id) {
+			kept = append(kept, id)
+		}
+	}
+	for _, id := range existing {
+		if !slices.Contains(kept, id) {
+			kept = append(kept, id)
+		}
+	}
+
+	return group.SetOrder(kept)
+}
+
 func (us SQLiteUserStore) CreateCategory(ctx context.Context, name string) (Category, error) {
-	tx, err := us.db.Begin()
+	tx, err := us.begin(ctx)
 	if err != nil {
-		return Category{}, err
+		return Category{}, fmt.Errorf("CreateCategory: %w", err)
 	}
 	defer tx.Rollback()
 
@@ -993,12 +1398,12 @@ func (us SQLiteUserStore) CreateCategory(ctx context.Context, name string) (Cate
 		if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == lib.SQLITE_CONSTRAINT_UNIQUE {
 			err = ErrKeywordCategoryExists
 		}
-		return Category{}, err
+		return Category{}, fmt.Errorf("CreateCategory: %w", err)
 	}
 
 	id, err := res.LastInsertId()
 	if err != nil {
-		return Category{}, err
+		return Category{}, fmt.Errorf("CreateCategory: %w", err)
 	}
 
 	if id > math.MaxUint8 {
@@ -1006,7 +1411,7 @@ func (us SQLiteUserStore) CreateCategory(ctx context.Context, name string) (Cate
 	}
 
 	if err := tx.Commit(); err != nil {
-		return Category{}, err
+		return Category{}, fmt.Errorf("CreateCategory: %w", err)
 	}
 
 	return Category{
@@ -1016,17 +1421,26 @@ func (us SQLiteUserStore) CreateCategory(ctx context.Context, name string) (Cate
 }
 
 func (us SQLiteUserStore) DeleteCategory(ctx context.Context, categoryID uint8) error {
+	refs, err := us.CategoryReferences(ctx, categoryID)
+	if err != nil {
+		return fmt.Errorf("DeleteCategory: %w", err)
+	}
+	if len(refs) > 0 {
+		return fmt.Errorf("%w: %s", ErrKeywordInUse, refs)
+	}
+
 	q := `DELETE FROM aimKeywordCategory WHERE id = ?`
 	res, err := us.db.ExecContext(ctx, q, categoryID)
 	if err != nil {
-		// check if the error is a foreign key constraint violation
+		// a concurrent update can still trip the foreign key constraint
 		if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == lib.SQLITE_CONSTRAINT_FOREIGNKEY {
 			return ErrKeywordInUse
 		}
+		return fmt.Errorf("DeleteCategory: %w", err)
 	}
 
 	if c, err := res.RowsAffected(); err != nil {
-		return err
+		return fmt.Errorf("DeleteCategory: %w", err)
 	} else if c == 0 {
 		return ErrKeywordCategoryNotFound
 	}
@@ -1038,7 +1452,7 @@ func (us SQLiteUserStore) Categories(ctx context.Context) ([]Category, error) {
 	q := `SELECT id, name FROM aimKeywordCategory ORDER BY name`
 	rows, err := us.db.QueryContext(ctx, q)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("Categories: %w", err)
 	}
 	defer rows.Close()
 
@@ -1046,13 +1460,13 @@ func (us SQLiteUserStore) Categories(ctx context.Context) ([]Category, error) {
 	for rows.Next() {
 		category := Category{}
 		if err := rows.Scan(&category.ID, &category.Name); err != nil {
-			return nil, err
+			return nil, fmt.Errorf("Categories: %w", err)
 		}
 		categories = append(categories, category)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("Categories: %w", err)
 	}
 
 	return categories, nil
@@ -1079,13 +1493,16 @@ func (us SQLiteUserStore) EmailAddress(ctx context.Context, screenName IdentScre
 	return e, nil
 }
 
+// UpdateEmailAddress sets the user's AIM email address. A new address is
+// stored unverified; see NewEmailVerificationToken.
 func (us SQLiteUserStore) UpdateEmailAddress(ctx context.Context, screenName IdentScreenName, emailAddress *mail.Address) error {
 	q := `
 		UPDATE users
-		SET emailAddress = ?
+		SET emailAddress  = ?,
+			emailVerified = emailVerified AND emailAddress = ?
 		WHERE identScreenName = ?
 	`
-	_, err := us.db.ExecContext(ctx, q, emailAddress.Address, screenName.String())
+	_, err := us.db.ExecContext(ctx, q, emailAddress.Address, emailAddress.Address, screenName.String())
 	return err
 }
 
@@ -1102,7 +1519,7 @@ func (us SQLiteUserStore) Profile(ctx context.Context, screenName IdentScreenNam
 		return UserProfile{}, nil
 	}
 	if err != nil {
-		return UserProfile{}, err
+		return UserProfile{}, fmt.Errorf("Profile: %w", err)
 	}
 	if updateTimeUnix > 0 {
 		profile.UpdateTime = time.Unix(updateTimeUnix, 0).UTC()
@@ -1143,7 +1560,7 @@ func (us SQLiteUserStore) AllChatRooms(ctx context.Context, exchange uint16) ([]
 	`
 	rows, err := us.db.QueryContext(ctx, q, exchange)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("AllChatRooms: %w", err)
 	}
 	defer rows.Close()
 
@@ -1155,7 +1572,7 @@ func (us SQLiteUserStore) AllChatRooms(ctx context.Context, exchange uint16) ([]
 		}
 
 		if err := rows.Scan(&cr.createTime, &creator, &cr.name); err != nil {
-			return nil, err
+			return nil, fmt.Errorf("AllChatRooms: %w", err)
 		}
 
 		cr.creator = NewIdentScreenName(creator)
@@ -1163,7 +1580,7 @@ func (us SQLiteUserStore) AllChatRooms(ctx context.Context, exchange uint16) ([]
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("AllChatRooms: %w", err)
 	}
 
 	return users, nil
@@ -1241,9 +1658,9 @@ func (us SQLiteUserStore) ChatRoomByName(ctx context.Context, exchange uint16, n
 }
 
 func (us SQLiteUserStore) CreateKeyword(ctx context.Context, name string, categoryID uint8) (Keyword, error) {
-	tx, err := us.db.Begin()
+	tx, err := us.begin(ctx)
 	if err != nil {
-		return Keyword{}, err
+		return Keyword{}, fmt.Errorf("CreateKeyword: %w", err)
 	}
 	defer tx.Rollback()
 
@@ -1260,12 +1677,12 @@ func (us SQLiteUserStore) CreateKeyword(ctx context.Context, name string, catego
 		} else if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == lib.SQLITE_CONSTRAINT_FOREIGNKEY {
 			err = ErrKeywordCategoryNotFound
 		}
-		return Keyword{}, err
+		return Keyword{}, fmt.Errorf("CreateKeyword: %w", err)
 	}
 
 	id, err := res.LastInsertId()
 	if err != nil {
-		return Keyword{}, err
+		return Keyword{}, fmt.Errorf("CreateKeyword: %w", err)
 	}
 
 	if id > math.MaxUint8 {
@@ -1273,7 +1690,7 @@ func (us SQLiteUserStore) CreateKeyword(ctx context.Context, name string, catego
 	}
 
 	if err := tx.Commit(); err != nil {
-		return Keyword{}, err
+		return Keyword{}, fmt.Errorf("CreateKeyword: %w", err)
 	}
 
 	return Keyword{
@@ -1283,17 +1700,26 @@ func (us SQLiteUserStore) CreateKeyword(ctx context.Context, name string, catego
 }
 
 func (us SQLiteUserStore) DeleteKeyword(ctx context.Context, id uint8) error {
+	refs, err := us.KeywordReferences(ctx, id)
+	if err != nil {
+		return fmt.Errorf("DeleteKeyword: %w", err)
+	}
+	if len(refs) > 0 {
+		return fmt.Errorf("%w: %s", ErrKeywordInUse, refs)
+	}
+
 	q := `DELETE FROM aimKeyword WHERE id = ?`
 	res, err := us.db.ExecContext(ctx, q, id)
 	if err != nil {
-		// Check if the error is a foreign key constraint violation
+		// a concurrent update can still trip the foreign key constraint
 		if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == lib.SQLITE_CONSTRAINT_FOREIGNKEY {
 			return ErrKeywordInUse
 		}
+		return fmt.Errorf("DeleteKeyword: %w", err)
 	}
 
 	if c, err := res.RowsAffected(); err != nil {
-		return err
+		return fmt.Errorf("DeleteKeyword: %w", err)
 	} else if c == 0 {
 		return ErrKeywordNotFound
 	}
@@ -1309,7 +1735,7 @@ func (us SQLiteUserStore) KeywordsByCategory(ctx context.Context, categoryID uin
 
 	rows, err := us.db.QueryContext(ctx, q, categoryID)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("KeywordsByCategory: %w", err)
 	}
 	defer rows.Close()
 
@@ -1317,20 +1743,20 @@ func (us SQLiteUserStore) KeywordsByCategory(ctx context.Context, categoryID uin
 	for rows.Next() {
 		keyword := Keyword{}
 		if err := rows.Scan(&keyword.ID, &keyword.Name); err != nil {
-			return nil, err
+			return nil, fmt.Errorf("KeywordsByCategory: %w", err)
 		}
 		keywords = append(keywords, keyword)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("KeywordsByCategory: %w", err)
 	}
 
 	if len(keywords) == 0 {
 		var exists int
 		err = us.db.QueryRow("SELECT COUNT(*) FROM aimKeywordCategory WHERE id = ?", categoryID).Scan(&exists)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("KeywordsByCategory: %w", err)
 		}
 		if exists == 0 {
 			return nil, ErrKeywordCategoryNotFound
@@ -1410,7 +1836,7 @@ func (us SQLiteUserStore) InterestList(ctx context.Context) ([]wire.ODirKeywordL
 
 	rows, err := us.db.QueryContext(ctx, q)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("InterestList: %w", err)
 	}
 	defer rows.Close()
 
@@ -1419,7 +1845,7 @@ func (us SQLiteUserStore) InterestList(ctx context.Context) ([]wire.ODirKeywordL
 		var sortPrio int
 		msg := wire.ODirKeywordListItem{}
 		if err := rows.Scan(&msg.ID, &sortPrio, &msg.Name); err != nil {
-			return nil, err
+			return nil, fmt.Errorf("InterestList: %w", err)
 		}
 
 		switch sortPrio {
@@ -1433,7 +1859,7 @@ func (us SQLiteUserStore) InterestList(ctx context.Context) ([]wire.ODirKeywordL
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("InterestList: %w", err)
 	}
 
 	return list, nil
@@ -1445,8 +1871,8 @@ func (us SQLiteUserStore) SaveMessage(ctx context.Context, offlineMessage Offlin
 		return 0, fmt.Errorf("marshal: %w", err)
 	}
 
-	var tx *sql.Tx
-	tx, err = us.db.BeginTx(ctx, nil)
+	var tx storeTx
+	tx, err = us.begin(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("begin tx: %w", err)
 	}
@@ -1476,6 +1902,20 @@ func (us SQLiteUserStore) SaveMessage(ctx context.Context, offlineMessage Offlin
 		return 0, err
 	}
 
+	// the sender has no foreign key so that messages from the ICQ system
+	// UIN can be stored, so check for other senders here
+	if offlineMessage.Sender != icqSystemScreenName {
+		var exists bool
+		q := `SELECT EXISTS(SELECT 1 FROM users WHERE identScreenName = ?)`
+		if err = tx.QueryRowContext(ctx, q, offlineMessage.Sender.String()).Scan(&exists); err != nil {
+			return 0, fmt.Errorf("check sender: %w", err)
+		}
+		if !exists {
+			err = ErrNoUser
+			return 0, err
+		}
+	}
+
 	q := `
 		INSERT INTO offlineMessage (sender, recipient, message, sent)
 		VALUES (?, ?, ?, ?)
@@ -1492,7 +1932,7 @@ func (us SQLiteUserStore) SaveMessage(ctx context.Context, offlineMessage Offlin
 		} else {
 			err = fmt.Errorf("insert: %w", err)
 		}
-		return 0, err
+		return 0, fmt.Errorf("SaveMessage: %w", err)
 	}
 
 	newCount = currentCount + 1
@@ -1513,6 +1953,7 @@ func (us SQLiteUserStore) SaveMessage(ctx context.Context, offlineMessage Offlin
 	if err = tx.Commit(); err != nil {
 		return 0, fmt.Errorf("commit: %w", err)
 	}
+	us.latency.ObserveSince(DeliveryPathOffline, offlineMessage.Sent)
 
 	return newCount, nil
 }
@@ -1528,7 +1969,7 @@ func (us SQLiteUserStore) RetrieveMessages(ctx context.Context, recip IdentScree
 	`
 	rows, err := us.db.QueryContext(ctx, q, recip.String())
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("RetrieveMessages: %w", err)
 	}
 	defer rows.Close()
 
@@ -1538,7 +1979,7 @@ func (us SQLiteUserStore) RetrieveMessages(ctx context.Context, recip IdentScree
 		var buf []byte
 		var sent time.Time
 		if err := rows.Scan(&sender, &buf, &sent); err != nil {
-			return nil, err
+			return nil, fmt.Errorf("RetrieveMessages: %w", err)
 		}
 
 		var msg wire.SNAC_0x04_0x06_ICBMChannelMsgToHost
@@ -1555,7 +1996,7 @@ func (us SQLiteUserStore) RetrieveMessages(ctx context.Context, recip IdentScree
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("RetrieveMessages: %w", err)
 	}
 
 	return messages, nil
@@ -1569,6 +2010,199 @@ func (us SQLiteUserStore) DeleteMessages(ctx context.Context, recip IdentScreenN
 	return err
 }
 
+// MoveOfflineMessages reassigns the offline messages waiting for from to
+// to, such as when accounts are merged, in a single transaction. Messages
+// keep their sender and send time, and the offline message counts of both
+// users are updated. Moved messages don't count against the offline inbox
+// limit. It returns the number of messages moved, or ErrNoUser if to
+// doesn't exist.
+func (us SQLiteUserStore) MoveOfflineMessages(ctx context.Context, from, to IdentScreenName) (moved int, err error) {
+	if from == to {
+		return 0, nil
+	}
+
+	var tx storeTx
+	tx, err = us.begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var exists bool
+	q := `SELECT EXISTS(SELECT 1 FROM users WHERE identScreenName = ?)`
+	if err = tx.QueryRowContext(ctx, q, to.String()).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("check recipient: %w", err)
+	}
+	if !exists {
+		err = ErrNoUser
+		return 0, err
+	}
+
+	q = `UPDATE offlineMessage SET recipient = ? WHERE recipient = ?`
+	res, err := tx.ExecContext(ctx, q, to.String(), from.String())
+	if err != nil {
+		return 0, fmt.Errorf("move messages: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected: %w", err)
+	}
+
+	q = `
+		UPDATE users
+		SET offlineMsgCount = (SELECT COUNT(*) FROM offlineMessage WHERE recipient = users.identScreenName)
+		WHERE identScreenName IN (?, ?)
+	`
+	if _, err = tx.ExecContext(ctx, q, from.String(), to.String()); err != nil {
+		return 0, fmt.Errorf("update offlineMsgCount: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	return int(n), nil
+}
+
+// OfferContacts records contacts sent by sender in an ICBMMsgTypeContacts
+// message so they can be offered to recipient on their next feedbag sync.
+// A contact that was already offered is replaced by the newer offer.
+func (us SQLiteUserStore) OfferContacts(ctx context.Context, sender IdentScreenName, recipient IdentScreenName, contacts []wire.ICQContact) (err error) {
+	var tx storeTx
+	tx, err = us.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	q := `
+		INSERT OR REPLACE INTO pendingContact (recipient, screenName, sender, nick, received)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+	`
+	for _, contact := range contacts {
+		contactName := NewIdentScreenName(contact.ScreenName)
+		if contactName == recipient {
+			continue
+		}
+		if _, err = tx.ExecContext(ctx, q, recipient.String(), contactName.String(), sender.String(), contact.Nick); err != nil {
+			if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == lib.SQLITE_CONSTRAINT_FOREIGNKEY {
+				err = ErrNoUser
+			} else {
+				err = fmt.Errorf("insert: %w", err)
+			}
+			return fmt.Errorf("OfferContacts: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
+
+// PendingContacts returns the contacts offered to recipient that aren't
+// already buddies in recipient's feedbag.
+func (us SQLiteUserStore) PendingContacts(ctx context.Context, recipient IdentScreenName) ([]PendingContact, error) {
+	q := `
+		SELECT screenName, nick, sender, received
+		FROM pendingContact
+		WHERE recipient = ?
+		  AND NOT EXISTS (SELECT 1
+		                  FROM feedbag
+		                  WHERE feedbag.screenName = pendingContact.recipient
+		                    AND feedbag.classID = ?
+		                    AND feedbag.name = pendingContact.screenName)
+		ORDER BY received, screenName
+	`
+	rows, err := us.db.QueryContext(ctx, q, recipient.String(), wire.FeedbagClassIdBuddy)
+	if err != nil {
+		return nil, fmt.Errorf("PendingContacts: %w", err)
+	}
+	defer rows.Close()
+
+	var contacts []PendingContact
+	for rows.Next() {
+		var screenName, sender string
+		var contact PendingContact
+		if err := rows.Scan(&screenName, &contact.Nick, &sender, &contact.Received); err != nil {
+			return nil, fmt.Errorf("PendingContacts: %w", err)
+		}
+		contact.ScreenName = NewIdentScreenName(screenName)
+		contact.Sender = NewIdentScreenName(sender)
+		contacts = append(contacts, contact)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("PendingContacts: %w", err)
+	}
+
+	return contacts, nil
+}
+
+// DeletePendingContacts clears all contacts offered to recipient.
+func (us SQLiteUserStore) DeletePendingContacts(ctx context.Context, recipient IdentScreenName) error {
+	q := `
+		DELETE FROM pendingContact WHERE recipient = ?
+	`
+	_, err := us.db.ExecContext(ctx, q, recipient.String())
+	return err
+}
+
+// SetAutoResponse stores the auto-response message a user wants returned
+// for msgType (one of the wire.ICBMMsgTypeAuto* values), so the server can
+// answer auto-response requests while the user is away. An empty message
+// clears the stored auto-response.
+func (us SQLiteUserStore) SetAutoResponse(ctx context.Context, screenName IdentScreenName, msgType uint8, message string) error {
+	if message == "" {
+		q := `
+			DELETE FROM autoResponse WHERE screenName = ? AND msgType = ?
+		`
+		_, err := us.db.ExecContext(ctx, q, screenName.String(), msgType)
+		return err
+	}
+
+	q := `
+		INSERT INTO autoResponse (screenName, msgType, message, updated)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (screenName, msgType)
+			DO UPDATE SET message = excluded.message,
+			              updated = excluded.updated
+	`
+	if _, err := us.db.ExecContext(ctx, q, screenName.String(), msgType, message); err != nil {
+		if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == lib.SQLITE_CONSTRAINT_FOREIGNKEY {
+			return ErrNoUser
+		}
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	return nil
+}
+
+// AutoResponse returns the auto-response message stored for msgType. It
+// returns an empty string if the user hasn't stored one.
+func (us SQLiteUserStore) AutoResponse(ctx context.Context, screenName IdentScreenName, msgType uint8) (string, error) {
+	q := `
+		SELECT message
+		FROM autoResponse
+		WHERE screenName = ? AND msgType = ?
+	`
+	var message string
+	err := us.db.QueryRowContext(ctx, q, screenName.String(), msgType).Scan(&message)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	return message, nil
+}
+
 func (us SQLiteUserStore) RegStatus(ctx context.Context, screenName IdentScreenName) (uint16, error) {
 	var regStatus uint16
 	q := `
@@ -1629,6 +2263,70 @@ func (us SQLiteUserStore) UpdateSuspendedStatus(ctx context.Context, suspendedSt
 	return err
 }
 
+// SetAccountExpiry sets when a trial or temporary account expires. A zero
+// expiresAt removes the expiry.
+func (us SQLiteUserStore) SetAccountExpiry(ctx context.Context, screenName IdentScreenName, expiresAt time.Time) error {
+	var expiresAtUnix int64
+	if !expiresAt.IsZero() {
+		expiresAtUnix = expiresAt.Unix()
+	}
+
+	q := `
+		UPDATE users
+		SET expiresAt = ?
+		WHERE identScreenName = ?
+	`
+	res, err := us.db.ExecContext(ctx, q, expiresAtUnix, screenName.String())
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	c, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if c == 0 {
+		return ErrNoUser
+	}
+
+	return nil
+}
+
+// PurgeExpiredUsers deletes accounts that expired at or before cutoff and
+// returns the screen names that were deleted.
+func (us SQLiteUserStore) PurgeExpiredUsers(ctx context.Context, cutoff time.Time) ([]IdentScreenName, error) {
+	q := `
+		DELETE FROM users
+		WHERE expiresAt > 0 AND expiresAt <= ?
+		RETURNING identScreenName
+	`
+	rows, err := us.db.QueryContext(ctx, q, cutoff.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("PurgeExpiredUsers: %w", err)
+	}
+	defer rows.Close()
+
+	var purged []IdentScreenName
+	for rows.Next() {
+		var sn string
+		if err := rows.Scan(&sn); err != nil {
+			return nil, fmt.Errorf("PurgeExpiredUsers: %w", err)
+		}
+		purged = append(purged, NewIdentScreenName(sn))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("PurgeExpiredUsers: %w", err)
+	}
+
+	if us.nameFilter != nil {
+		for range purged {
+			us.nameFilter.Removed()
+		}
+	}
+	return purged, nil
+}
+
 func (us SQLiteUserStore) UpdateDisplayScreenName(ctx context.Context, displayScreenName DisplayScreenName) error {
 	q := `
 		UPDATE users
@@ -1639,6 +2337,192 @@ func (us SQLiteUserStore) UpdateDisplayScreenName(ctx context.Context, displaySc
 	return err
 }
 
+// RenameScreenName changes a user's identity screen name to newName in a
+// single transaction. The user's own feedbag, profile, buddy list state, and
+// chat rooms are moved to the new name, and other users' buddy, permit, and
+// deny feedbag entries that reference the old name are rewritten, as are
+// blocked-message counts, presence webhook buddies and a reservation of the
+// old name. Offline messages and other per-user rows follow the rename via
+// their foreign key constraints.
+// It returns ErrNoUser if oldName does not exist, ErrDupUser if newName is
+// already taken by a user or an alias and ErrScreenNameReserved if newName is
+// a guest screen name. ICQ accounts, which are identified by UIN, can't be renamed.
+func (us SQLiteUserStore) RenameScreenName(ctx context.Context, oldName IdentScreenName, newName DisplayScreenName) (err error) {
+	if err = newName.ValidateAIMHandle(); err != nil {
+		return err
+	}
+
+	var tx storeTx
+	tx, err = us.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var isICQ bool
+	err = tx.QueryRowContext(ctx, `SELECT isICQ FROM users WHERE identScreenName = ?`, oldName.String()).Scan(&isICQ)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrNoUser
+		return err
+	} else if err != nil {
+		return fmt.Errorf("select user: %w", err)
+	}
+	if isICQ {
+		err = ErrRenameNotAllowed
+		return err
+	}
+
+	newIdent := newName.IdentScreenName()
+	if newIdent != oldName {
+		if err = us.checkGuestScreenName(newIdent); err != nil {
+			return err
+		}
+
+		var exists int
+		q := `
+			SELECT (SELECT COUNT(*) FROM users WHERE identScreenName = ?) +
+				   (SELECT COUNT(*) FROM screenNameAlias WHERE alias = ?)
+		`
+		if err = tx.QueryRowContext(ctx, q, newIdent.String(), newIdent.String()).Scan(&exists); err != nil {
+			return fmt.Errorf("select new user: %w", err)
+		}
+		if exists > 0 {
+			err = ErrDupUser
+			return err
+		}
+	}
+
+	q := `
+		UPDATE users
+		SET identScreenName   = ?,
+			displayScreenName = ?
+		WHERE identScreenName = ?
+	`
+	if _, err = tx.ExecContext(ctx, q, newIdent.String(), newName.String(), oldName.String()); err != nil {
+		return fmt.Errorf("update users: %w", err)
+	}
+
+	// rows left behind by previously deleted accounts may occupy the new
+	// name, so stale rows are replaced rather than tripping constraints
+	queries := []string{
+		`UPDATE OR REPLACE feedbag SET screenName = ? WHERE screenName = ?`,
+		`UPDATE OR REPLACE profile SET screenName = ? WHERE screenName = ?`,
+		`UPDATE OR REPLACE buddyListMode SET screenName = ? WHERE screenName = ?`,
+		`UPDATE OR REPLACE clientSideBuddyList SET me = ? WHERE me = ?`,
+		`UPDATE OR REPLACE clientSideBuddyList SET them = ? WHERE them = ?`,
+		`UPDATE chatRoom SET creator = ? WHERE creator = ?`,
+		// tables that name users without a foreign key to them
+		`UPDATE OR REPLACE blockedMessage SET sender = ? WHERE sender = ?`,
+		`UPDATE OR REPLACE presenceWebhookBuddy SET buddy = ? WHERE buddy = ?`,
+		`UPDATE OR REPLACE reservedScreenName SET identScreenName = ? WHERE identScreenName = ?`,
+	}
+	for _, q := range queries {
+		if _, err = tx.ExecContext(ctx, q, newIdent.String(), oldName.String()); err != nil {
+			return fmt.Errorf("exec: %w", err)
+		}
+	}
+
+	q = `
+		UPDATE feedbag
+		SET name         = ?,
+			lastModified = UNIXEPOCH()
+		WHERE name = ?
+		  AND classID IN (?, ?, ?)
+	`
+	if _, err = tx.ExecContext(ctx, q, newIdent.String(), oldName.String(),
+		wire.FeedbagClassIdBuddy, wire.FeedbagClassIDPermit, wire.FeedbagClassIDDeny); err != nil {
+		return fmt.Errorf("update feedbag references: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	if us.nameFilter != nil {
+		us.nameFilter.Add(newIdent)
+		us.nameFilter.Removed()
+	}
+	return nil
+}
+
+// LinkAccounts links an AIM screen name and an ICQ UIN so that both
+// identities belong to the same underlying account.
+// It returns ErrAccountLinkInvalid if aimScreenName is a UIN or icqUIN is not,
+// ErrAccountLinked if either identity is already linked, and ErrNoUser if
+// either identity does not exist.
+func (us SQLiteUserStore) LinkAccounts(ctx context.Context, aimScreenName DisplayScreenName, icqUIN DisplayScreenName) error {
+	if aimScreenName.IsUIN() || !icqUIN.IsUIN() {
+		return ErrAccountLinkInvalid
+	}
+
+	q := `
+		INSERT INTO linkedAccount (aimScreenName, icqUIN)
+		VALUES (?, ?)
+	`
+	_, err := us.db.ExecContext(ctx, q, aimScreenName.IdentScreenName().String(), icqUIN.IdentScreenName().String())
+	if err != nil {
+		if sqliteErr, ok := err.(*sqlite.Error); ok {
+			switch sqliteErr.Code() {
+			case lib.SQLITE_CONSTRAINT_PRIMARYKEY, lib.SQLITE_CONSTRAINT_UNIQUE:
+				return ErrAccountLinked
+			case lib.SQLITE_CONSTRAINT_FOREIGNKEY:
+				return ErrNoUser
+			}
+		}
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	return nil
+}
+
+// UnlinkAccounts removes the link that includes screenName, which may be
+// either the AIM or the ICQ identity.
+// It returns ErrNoUser if screenName is not linked.
+func (us SQLiteUserStore) UnlinkAccounts(ctx context.Context, screenName IdentScreenName) error {
+	q := `
+		DELETE FROM linkedAccount
+		WHERE aimScreenName = ? OR icqUIN = ?
+	`
+	res, err := us.db.ExecContext(ctx, q, screenName.String(), screenName.String())
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	if c, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	} else if c == 0 {
+		return ErrNoUser
+	}
+
+	return nil
+}
+
+// LinkedScreenNames returns every identity that belongs to the same account
+// as screenName, starting with screenName itself. The result is passed to
+// InMemorySessionManager.SetLinkedScreenNames when screenName signs on, so
+// that messages and presence are shared by all of the identities.
+func (us SQLiteUserStore) LinkedScreenNames(ctx context.Context, screenName IdentScreenName) ([]IdentScreenName, error) {
+	q := `
+		SELECT CASE WHEN aimScreenName = ? THEN icqUIN ELSE aimScreenName END
+		FROM linkedAccount
+		WHERE aimScreenName = ? OR icqUIN = ?
+	`
+	var linked string
+	err := us.db.QueryRowContext(ctx, q, screenName.String(), screenName.String(), screenName.String()).Scan(&linked)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return []IdentScreenName{screenName}, nil
+	case err != nil:
+		return nil, fmt.Errorf("LinkedScreenNames: %w", err)
+	}
+
+	return []IdentScreenName{screenName, NewIdentScreenName(linked)}, nil
+}
+
 func (us SQLiteUserStore) BARTItem(ctx context.Context, hash []byte) (body []byte, err error) {
 	q := `
 		SELECT body
@@ -1660,7 +2544,7 @@ func (us SQLiteUserStore) ListBARTItems(ctx context.Context, itemType uint16) ([
 	`
 	rows, err := us.db.QueryContext(ctx, q, itemType)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("ListBARTItems: %w", err)
 	}
 	defer rows.Close()
 
@@ -1670,14 +2554,14 @@ func (us SQLiteUserStore) ListBARTItems(ctx context.Context, itemType uint16) ([
 		var hashBytes []byte
 		err := rows.Scan(&hashBytes, &item.Type)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("ListBARTItems: %w", err)
 		}
 		item.Hash = hex.EncodeToString(hashBytes)
 		items = append(items, item)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("ListBARTItems: %w", err)
 	}
 
 	return items, nil
@@ -1694,12 +2578,43 @@ func (us SQLiteUserStore) InsertBARTItem(ctx context.Context, hash []byte, blob
 				return ErrBARTItemExists
 			}
 		}
-		return err
+		return fmt.Errorf("InsertBARTItem: %w", err)
 	}
 
 	return nil
 }
 
+// SampleBARTItems returns up to n randomly chosen BART items along with
+// their bodies.
+func (us SQLiteUserStore) SampleBARTItems(ctx context.Context, n int) ([]BARTBlob, error) {
+	q := `
+		SELECT hash, body, type
+		FROM bartItem
+		ORDER BY RANDOM()
+		LIMIT ?
+	`
+	rows, err := us.db.QueryContext(ctx, q, n)
+	if err != nil {
+		return nil, fmt.Errorf("SampleBARTItems: %w", err)
+	}
+	defer rows.Close()
+
+	var items []BARTBlob
+	for rows.Next() {
+		var item BARTBlob
+		if err := rows.Scan(&item.Hash, &item.Body, &item.Type); err != nil {
+			return nil, fmt.Errorf("SampleBARTItems: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("SampleBARTItems: %w", err)
+	}
+
+	return items, nil
+}
+
 func (us SQLiteUserStore) DeleteBARTItem(ctx context.Context, hash []byte) error {
 	q := `
 		DELETE FROM bartItem
@@ -1707,11 +2622,11 @@ func (us SQLiteUserStore) DeleteBARTItem(ctx context.Context, hash []byte) error
 	`
 	result, err := us.db.ExecContext(ctx, q, hash)
 	if err != nil {
-		return err
+		return fmt.Errorf("DeleteBARTItem: %w", err)
 	}
 
 	if rowsAffected, err := result.RowsAffected(); err != nil {
-		return err
+		return fmt.Errorf("DeleteBARTItem: %w", err)
 	} else if rowsAffected == 0 {
 		return ErrBARTItemNotFound
 	}
@@ -1773,11 +2688,11 @@ func (us SQLiteUserStore) RemovePermitBuddy(ctx context.Context, me IdentScreenN
 
 func (us SQLiteUserStore) ClearBuddyListRegistry(ctx context.Context) error {
 	if _, err := us.db.ExecContext(ctx, `DELETE FROM buddyListMode`); err != nil {
-		return err
+		return fmt.Errorf("ClearBuddyListRegistry: %w", err)
 	}
 
 	if _, err := us.db.ExecContext(ctx, `DELETE FROM clientSideBuddyList`); err != nil {
-		return err
+		return fmt.Errorf("ClearBuddyListRegistry: %w", err)
 	}
 
 	return nil
@@ -1794,11 +2709,11 @@ func (us SQLiteUserStore) RegisterBuddyList(ctx context.Context, user IdentScree
 
 func (us SQLiteUserStore) UnregisterBuddyList(ctx context.Context, user IdentScreenName) error {
 	if _, err := us.db.ExecContext(ctx, `DELETE FROM buddyListMode WHERE screenName = ?`, user.String()); err != nil {
-		return err
+		return fmt.Errorf("UnregisterBuddyList: %w", err)
 	}
 
 	if _, err := us.db.ExecContext(ctx, `DELETE FROM clientSideBuddyList WHERE me = ?`, user.String()); err != nil {
-		return err
+		return fmt.Errorf("UnregisterBuddyList: %w", err)
 	}
 
 	return nil
@@ -1838,19 +2753,14 @@ func (us SQLiteUserStore) BuddyIconMetadata(ctx context.Context, screenName Iden
 	}
 
 	if err := wire.UnmarshalBE(&item.TLVLBlock, bytes.NewBuffer(attrs)); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("BuddyIconMetadata: %w", err)
 	}
 
-	b, hasBuf := item.Bytes(wire.FeedbagAttributesBartInfo)
-	if !hasBuf {
+	bartInfo, hasInfo := item.BARTInfo()
+	if !hasInfo {
 		return nil, errors.New("unable to extract icon payload")
 	}
 
-	bartInfo := wire.BARTInfo{}
-	if err := wire.UnmarshalBE(&bartInfo, bytes.NewBuffer(b)); err != nil {
-		return nil, err
-	}
-
 	return &wire.BARTID{
 		Type: wire.BARTTypesBuddyIcon,
 		BARTInfo: wire.BARTInfo{
@@ -1860,6 +2770,41 @@ func (us SQLiteUserStore) BuddyIconMetadata(ctx context.Context, screenName Iden
 	}, nil
 }
 
+// FeedbagBARTReferences returns the BART assets referenced by a user's
+// feedbag. References come from BART items, per-buddy arrival and departure
+// sounds, and BART lists. Each reference reports whether the asset can be
+// resolved through the BART store.
+func (us SQLiteUserStore) FeedbagBARTReferences(ctx context.Context, screenName IdentScreenName) ([]FeedbagBARTReference, error) {
+	items, err := us.Feedbag(ctx, screenName)
+	if err != nil {
+		return nil, fmt.Errorf("Feedbag: %w", err)
+	}
+
+	var refs []FeedbagBARTReference
+	for _, item := range items {
+		ids, err := feedbagBARTIDs(item)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", item.ItemID, err)
+		}
+		for _, id := range ids {
+			refs = append(refs, FeedbagBARTReference{
+				ClassID:  item.ClassID,
+				ItemName: item.Name,
+				ID:       id,
+			})
+		}
+	}
+
+	q := `SELECT EXISTS(SELECT 1 FROM bartItem WHERE hash = ?)`
+	for i, ref := range refs {
+		if err := us.db.QueryRowContext(ctx, q, ref.ID.Hash).Scan(&refs[i].Stored); err != nil {
+			return nil, fmt.Errorf("exists: %w", err)
+		}
+	}
+
+	return refs, nil
+}
+
 // Relationship retrieves the relationship between the
 // specified user (`me`) and another user (`them`).
 //
@@ -1917,7 +2862,7 @@ func (f SQLiteUserStore) AllRelationships(ctx context.Context, me IdentScreenNam
 	for rows.Next() {
 		var screenName string
 		rel := Relationship{}
-		err = rows.Scan(&screenName, &rel.YouBlock, &rel.BlocksYou, &rel.IsOnTheirList, &rel.IsOnYourList)
+		err = rows.Scan(&screenName, &rel.YouBlock, &rel.BlocksYou, &rel.IsOnTheirList, &rel.IsOnYourList, &rel.IsOnYourPermitList)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning row: %w", err)
 		}
@@ -1934,31 +2879,88 @@ func (f SQLiteUserStore) AllRelationships(ctx context.Context, me IdentScreenNam
 }
 
 func (us SQLiteUserStore) runMigrations() error {
+	m, src, err := newMigrate(us.pool)
+	if err != nil {
+		return err
+	}
+
+	if err := checkSchemaVersion(m, src); err != nil {
+		return err
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return nil
+}
+
+// newMigrate returns a migrate instance that applies the embedded migrations
+// to db, along with the source it reads them from.
+func newMigrate(db *sql.DB) (*migrate.Migrate, source.Driver, error) {
 	migrationFS, err := fs.Sub(migrations, "migrations")
 	if err != nil {
-		return fmt.Errorf("failed to prepare migration subdirectory: %v", err)
+		return nil, nil, fmt.Errorf("failed to prepare migration subdirectory: %w", err)
 	}
 
 	sourceInstance, err := httpfs.New(http.FS(migrationFS), ".")
 	if err != nil {
-		return fmt.Errorf("failed to create source instance from embedded filesystem: %v", err)
+		return nil, nil, fmt.Errorf("failed to create source instance from embedded filesystem: %w", err)
 	}
 
-	driver, err := migratesqlite.WithInstance(us.db, &migratesqlite.Config{})
+	driver, err := migratesqlite.WithInstance(db, &migratesqlite.Config{})
 	if err != nil {
-		return fmt.Errorf("cannot create database driver: %v", err)
+		return nil, nil, fmt.Errorf("cannot create database driver: %w", err)
 	}
 
 	m, err := migrate.NewWithInstance("httpfs", sourceInstance, "sqlite", driver)
 	if err != nil {
-		return fmt.Errorf("failed to create migrate instance: %v", err)
+		return nil, nil, fmt.Errorf("failed to create migrate instance: %w", err)
 	}
 
-	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return fmt.Errorf("failed to run migrations: %v", err)
+	return m, sourceInstance, nil
+}
+
+// feedbagBARTIDs extracts the BART IDs referenced by a feedbag item.
+func feedbagBARTIDs(item wire.FeedbagItem) ([]wire.BARTID, error) {
+	var ids []wire.BARTID
+	unmarshalInfo := func(tag uint16, bartType uint16) error {
+		b, ok := item.Bytes(tag)
+		if !ok {
+			return nil
+		}
+		info := wire.BARTInfo{}
+		if err := wire.UnmarshalBE(&info, bytes.NewBuffer(b)); err != nil {
+			return fmt.Errorf("unmarshal BART info: %w", err)
+		}
+		ids = append(ids, wire.BARTID{Type: bartType, BARTInfo: info})
+		return nil
 	}
 
-	return nil
+	if item.ClassID == wire.FeedbagClassIdBart {
+		// BART items are named after the asset type they hold
+		if bartType, err := strconv.ParseUint(item.Name, 10, 16); err == nil {
+			if err := unmarshalInfo(wire.FeedbagAttributesBartInfo, uint16(bartType)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := unmarshalInfo(wire.FeedbagAttributesArriveSound, wire.BARTTypesArriveSound); err != nil {
+		return nil, err
+	}
+	if err := unmarshalInfo(wire.FeedbagAttributesLeaveSound, wire.BARTTypesDepartSound); err != nil {
+		return nil, err
+	}
+
+	if b, ok := item.Bytes(wire.FeedbagAttributesBartList); ok {
+		var list []wire.BARTID
+		if err := wire.UnmarshalBE(&list, bytes.NewBuffer(b)); err != nil {
+			return nil, fmt.Errorf("unmarshal BART list: %w", err)
+		}
+		ids = append(ids, list...)
+	}
+
+	return ids, nil
 }
 
 // queryUsers retrieves a list of users from the database based on the
@@ -2047,7 +3049,10 @@ func (us SQLiteUserStore) queryUsers(ctx context.Context, whereClause string, qu
 			tocConfig,
 			lastWarnUpdate,
 			lastWarnLevel,
-			offlineMsgCount
+			offlineMsgCount,
+			expiresAt,
+			emailVerified,
+			createdAt
 		FROM users
 		WHERE %s
 	`
@@ -2063,6 +3068,8 @@ func (us SQLiteUserStore) queryUsers(ctx context.Context, whereClause string, qu
 		var u User
 		var sn string
 		var lastWarnUpdateUnix int64
+		var expiresAtUnix int64
+		var createdAtUnix int64
 		err := rows.Scan(
 			&sn,
 			&u.DisplayScreenName,
@@ -2145,6 +3152,9 @@ func (us SQLiteUserStore) queryUsers(ctx context.Context, whereClause string, qu
 			&lastWarnUpdateUnix,
 			&u.LastWarnLevel,
 			&u.OfflineMsgCount,
+			&expiresAtUnix,
+			&u.EmailVerified,
+			&createdAtUnix,
 		)
 		if err != nil {
 			return nil, err
@@ -2152,6 +3162,15 @@ func (us SQLiteUserStore) queryUsers(ctx context.Context, whereClause string, qu
 
 		u.IdentScreenName = NewIdentScreenName(sn)
 		u.LastWarnUpdate = time.Unix(lastWarnUpdateUnix, 0).UTC()
+		if createdAtUnix > 0 {
+			u.CreatedAt = time.Unix(createdAtUnix, 0).UTC()
+		}
+		if expiresAtUnix > 0 {
+			u.ExpiresAt = time.Unix(expiresAtUnix, 0).UTC()
+			if u.Expired(us.nowFn()) {
+				u.ExpiredStatus = wire.LoginErrSuspendedAccountAge
+			}
+		}
 		users = append(users, u)
 	}
 
@@ -2179,7 +3198,7 @@ func (us SQLiteUserStore) isPDModeEqual(ctx context.Context, me IdentScreenName,
 }
 
 // clearClientSidePDFlags clears permit/deny flags.
-func clearClientSidePDFlags(ctx context.Context, tx *sql.Tx, me IdentScreenName, pdMode wire.FeedbagPDMode) error {
+func clearClientSidePDFlags(ctx context.Context, tx sqlConn, me IdentScreenName, pdMode wire.FeedbagPDMode) error {
 	q := `
 		UPDATE clientSideBuddyList
 		SET isDeny = false, isPermit = false
@@ -2191,7 +3210,7 @@ func clearClientSidePDFlags(ctx context.Context, tx *sql.Tx, me IdentScreenName,
 
 // clearBlankClientSideBuddies removes client-side buddy where
 // all flags (isBuddy, isPermit, isDeny) are false.
-func clearBlankClientSideBuddies(ctx context.Context, tx *sql.Tx, me IdentScreenName, pdMode wire.FeedbagPDMode) error {
+func clearBlankClientSideBuddies(ctx context.Context, tx sqlConn, me IdentScreenName, pdMode wire.FeedbagPDMode) error {
 	q := `
 		DELETE FROM clientSideBuddyList
 		WHERE isBuddy IS FALSE
@@ -2204,7 +3223,7 @@ func clearBlankClientSideBuddies(ctx context.Context, tx *sql.Tx, me IdentScreen
 }
 
 // setClientSidePDMode sets the permit/deny mode for my client-side buddy list.
-func setClientSidePDMode(ctx context.Context, tx *sql.Tx, me IdentScreenName, pdMode wire.FeedbagPDMode) error {
+func setClientSidePDMode(ctx context.Context, tx sqlConn, me IdentScreenName, pdMode wire.FeedbagPDMode) error {
 	q := `
 		INSERT INTO buddyListMode (screenName, clientSidePDMode) VALUES(?, ?)
 		ON CONFLICT (screenName)
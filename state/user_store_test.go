@@ -6,7 +6,11 @@ import (
 	"math"
 	"net/mail"
 	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,9 +19,17 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-const testFile string = "aim_test.db"
+// newTestDBPath returns the path of a database file in a temporary directory
+// that's removed when t and its subtests complete, so that tests don't share
+// a database and can run in parallel.
+func newTestDBPath(t testing.TB) string {
+	t.Helper()
+	return filepath.Join(t.TempDir(), "aim_test.db")
+}
 
 func TestSQLiteUserStore_AllRelationships(t *testing.T) {
+	t.Parallel()
+
 	// buddyList represents the contents of a client-side or server-side buddy list
 	type buddyList struct {
 		// privacyMode is your current privacy mode.
@@ -1199,11 +1211,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			serverSideLists: map[IdentScreenName]buddyList{},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     false,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          false,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1228,11 +1241,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     false,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          false,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1256,11 +1270,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			serverSideLists: map[IdentScreenName]buddyList{},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     false,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          false,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1285,11 +1300,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     false,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          false,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1313,11 +1329,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			serverSideLists: map[IdentScreenName]buddyList{},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     false,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          false,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1342,11 +1359,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     false,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          false,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1370,11 +1388,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			serverSideLists: map[IdentScreenName]buddyList{},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     true,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          true,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1399,11 +1418,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     true,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          true,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1427,11 +1447,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			serverSideLists: map[IdentScreenName]buddyList{},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     true,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          true,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1456,11 +1477,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     true,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          true,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1485,11 +1507,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     false,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          false,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1513,11 +1536,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     false,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          false,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1542,11 +1566,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     false,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          false,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1570,11 +1595,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     false,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          false,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1599,11 +1625,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     false,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          false,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1627,11 +1654,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     false,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          false,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1656,11 +1684,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     true,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          true,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1684,11 +1713,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     true,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          true,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1713,11 +1743,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     true,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          true,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -1741,11 +1772,12 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 			},
 			expect: []Relationship{
 				{
-					User:          NewIdentScreenName("them"),
-					BlocksYou:     true,
-					YouBlock:      false,
-					IsOnTheirList: true,
-					IsOnYourList:  true,
+					User:               NewIdentScreenName("them"),
+					BlocksYou:          true,
+					YouBlock:           false,
+					IsOnTheirList:      true,
+					IsOnYourList:       true,
+					IsOnYourPermitList: true,
 				},
 			},
 		},
@@ -3141,9 +3173,7 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			defer func() {
-				_ = os.Remove(testFile)
-			}()
+			testFile := newTestDBPath(t)
 
 			feedbagStore, err := NewSQLiteUserStore(testFile)
 			assert.NoError(t, err)
@@ -3197,10 +3227,10 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 }
 
 func TestSQLiteUserStore_FeedbagUpsert(t *testing.T) {
+	t.Parallel()
+
 	t.Run("buddy screen name is converted to ident screen name", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
@@ -3247,9 +3277,7 @@ func TestSQLiteUserStore_FeedbagUpsert(t *testing.T) {
 	})
 
 	t.Run("upsert PD info with mode", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
@@ -3278,9 +3306,7 @@ func TestSQLiteUserStore_FeedbagUpsert(t *testing.T) {
 	})
 
 	t.Run("upsert PD info without mode (QIP behavior)", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
@@ -3306,11 +3332,11 @@ func TestSQLiteUserStore_FeedbagUpsert(t *testing.T) {
 }
 
 func TestFeedbagDelete(t *testing.T) {
+	t.Parallel()
+
 	screenName := NewIdentScreenName("sn2day")
 
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -3361,11 +3387,11 @@ func TestFeedbagDelete(t *testing.T) {
 }
 
 func TestLastModifiedEmpty(t *testing.T) {
+	t.Parallel()
+
 	screenName := NewIdentScreenName("sn2day")
 
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -3377,12 +3403,11 @@ func TestLastModifiedEmpty(t *testing.T) {
 }
 
 func TestLastModifiedNotEmpty(t *testing.T) {
+	t.Parallel()
 
 	screenName := NewIdentScreenName("sn2day")
 
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -3406,11 +3431,11 @@ func TestLastModifiedNotEmpty(t *testing.T) {
 }
 
 func TestProfile(t *testing.T) {
+	t.Parallel()
+
 	screenName := NewIdentScreenName("sn2day")
 
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -3481,12 +3506,11 @@ func TestProfile(t *testing.T) {
 }
 
 func TestProfileNonExistent(t *testing.T) {
+	t.Parallel()
 
 	screenName := NewIdentScreenName("sn2day")
 
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -3499,11 +3523,11 @@ func TestProfileNonExistent(t *testing.T) {
 }
 
 func TestProfile_MimeTypeAndUpdateTime(t *testing.T) {
+	t.Parallel()
+
 	screenName := NewIdentScreenName("testuser")
 
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	require.NoError(t, err)
@@ -3562,9 +3586,9 @@ func TestProfile_MimeTypeAndUpdateTime(t *testing.T) {
 }
 
 func TestGetUser(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -3587,15 +3611,19 @@ func TestGetUser(t *testing.T) {
 		t.Fatalf("failed to get user: %s", err.Error())
 	}
 
+	// the registration time is set by the store
+	assert.WithinDuration(t, time.Now(), actualUser.CreatedAt, 5*time.Second)
+	insertedUser.CreatedAt = actualUser.CreatedAt
+
 	if !reflect.DeepEqual(insertedUser, actualUser) {
 		t.Fatalf("users are not equal. expect: %v actual: %v", insertedUser, actualUser)
 	}
 }
 
 func TestSQLiteUserStore_User_OfflineMsgCount(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -3621,9 +3649,9 @@ func TestSQLiteUserStore_User_OfflineMsgCount(t *testing.T) {
 }
 
 func TestGetUserNotFound(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -3639,9 +3667,9 @@ func TestGetUserNotFound(t *testing.T) {
 }
 
 func TestSQLiteUserStore_Users(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -3679,9 +3707,9 @@ func TestSQLiteUserStore_Users(t *testing.T) {
 }
 
 func TestSQLiteUserStore_InsertUser_UINButNotIsICQ(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -3696,9 +3724,9 @@ func TestSQLiteUserStore_InsertUser_UINButNotIsICQ(t *testing.T) {
 }
 
 func TestSQLiteUserStore_DeleteUser_DeleteExistentUser(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -3728,9 +3756,9 @@ func TestSQLiteUserStore_DeleteUser_DeleteExistentUser(t *testing.T) {
 }
 
 func TestSQLiteUserStore_DeleteUser_DeleteNonExistentUser(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -3754,13 +3782,13 @@ func TestNewStubUser(t *testing.T) {
 }
 
 func TestSQLiteUserStore_SetBuddyIconAndRetrieve(t *testing.T) {
+	t.Parallel()
+
 	hash := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
 	item := []byte{'a', 'b', 'c', 'd'}
 
 	t.Run("insert_and_retrieve", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 
 		feedbagStore, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
@@ -3778,9 +3806,7 @@ func TestSQLiteUserStore_SetBuddyIconAndRetrieve(t *testing.T) {
 	})
 
 	t.Run("duplicate_insert_returns_error", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 
 		feedbagStore, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
@@ -3797,10 +3823,10 @@ func TestSQLiteUserStore_SetBuddyIconAndRetrieve(t *testing.T) {
 }
 
 func TestSQLiteUserStore_ListBARTItems(t *testing.T) {
+	t.Parallel()
+
 	t.Run("empty_list", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 
 		feedbagStore, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
@@ -3811,9 +3837,7 @@ func TestSQLiteUserStore_ListBARTItems(t *testing.T) {
 	})
 
 	t.Run("list_with_items", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 
 		feedbagStore, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
@@ -3843,9 +3867,9 @@ func TestSQLiteUserStore_ListBARTItems(t *testing.T) {
 }
 
 func TestSQLiteUserStore_SetUserPassword_UserExists(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	feedbagStore, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -3874,9 +3898,9 @@ func TestSQLiteUserStore_SetUserPassword_UserExists(t *testing.T) {
 }
 
 func TestSQLiteUserStore_SetUserPassword_ErrNoUser(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	feedbagStore, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -3886,12 +3910,12 @@ func TestSQLiteUserStore_SetUserPassword_ErrNoUser(t *testing.T) {
 }
 
 func TestUpdateDisplayScreenName(t *testing.T) {
+	t.Parallel()
+
 	screenNameOriginal := DisplayScreenName("chattingchuck")
 	screenNameFormatted := DisplayScreenName("Chatting Chuck")
 
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -3918,9 +3942,9 @@ func TestUpdateDisplayScreenName(t *testing.T) {
 }
 
 func TestSQLiteUserStore_SetWorkInfo(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -4004,10 +4028,10 @@ func TestSQLiteUserStore_SetWorkInfo(t *testing.T) {
 }
 
 func TestSQLiteUserStore_SetMoreInfo(t *testing.T) {
+	t.Parallel()
+
 	// Cleanup after test
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	// Initialize the SQLiteUserStore with a test database file
 	f, err := NewSQLiteUserStore(testFile)
@@ -4060,6 +4084,19 @@ func TestSQLiteUserStore_SetMoreInfo(t *testing.T) {
 		assert.ErrorIs(t, err, ErrNoUser)
 	})
 
+	t.Run("Invalid Birth Date", func(t *testing.T) {
+		invalid := moreInfo
+		invalid.BirthMonth = 2
+		invalid.BirthDay = 30
+		err := f.SetMoreInfo(context.Background(), screenName, invalid)
+		assert.ErrorIs(t, err, ErrBirthDateInvalid)
+
+		// the previous info is kept
+		updatedUser, err := f.User(context.Background(), screenName)
+		assert.NoError(t, err)
+		assert.Equal(t, moreInfo.BirthMonth, updatedUser.ICQMoreInfo.BirthMonth)
+	})
+
 	t.Run("Empty More Info", func(t *testing.T) {
 		// Test updating with empty more info
 		emptyMoreInfo := ICQMoreInfo{}
@@ -4081,10 +4118,10 @@ func TestSQLiteUserStore_SetMoreInfo(t *testing.T) {
 }
 
 func TestSQLiteUserStore_SetUserNotes(t *testing.T) {
+	t.Parallel()
+
 	// Cleanup after test
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	// Initialize the SQLiteUserStore with a test database file
 	f, err := NewSQLiteUserStore(testFile)
@@ -4137,10 +4174,10 @@ func TestSQLiteUserStore_SetUserNotes(t *testing.T) {
 }
 
 func TestSQLiteUserStore_SetInterests(t *testing.T) {
+	t.Parallel()
+
 	// Cleanup after test
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	// Initialize the SQLiteUserStore with a test database file
 	f, err := NewSQLiteUserStore(testFile)
@@ -4214,10 +4251,10 @@ func TestSQLiteUserStore_SetInterests(t *testing.T) {
 }
 
 func TestSQLiteUserStore_SetAffiliations(t *testing.T) {
+	t.Parallel()
+
 	// Cleanup after test
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	// Initialize the SQLiteUserStore with a test database file
 	f, err := NewSQLiteUserStore(testFile)
@@ -4303,10 +4340,10 @@ func TestSQLiteUserStore_SetAffiliations(t *testing.T) {
 }
 
 func TestSQLiteUserStore_SetBasicInfo(t *testing.T) {
+	t.Parallel()
+
 	// Cleanup after test
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	// Initialize the SQLiteUserStore with a test database file
 	f, err := NewSQLiteUserStore(testFile)
@@ -4398,10 +4435,10 @@ func TestSQLiteUserStore_SetBasicInfo(t *testing.T) {
 }
 
 func TestSQLiteUserStore_FindByICQInterests(t *testing.T) {
+	t.Parallel()
+
 	// Cleanup after test
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	// Initialize the SQLiteUserStore with a test database file
 	f, err := NewSQLiteUserStore(testFile)
@@ -4505,11 +4542,64 @@ func TestSQLiteUserStore_FindByICQInterests(t *testing.T) {
 	})
 }
 
+func TestSQLiteUserStore_FindByICQBirthday(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	birthdays := map[string]ICQMoreInfo{
+		"100001": {BirthYear: 1990, BirthMonth: 2, BirthDay: 28},
+		"100002": {BirthYear: 2000, BirthMonth: 2, BirthDay: 29},
+		"100003": {BirthMonth: 2, BirthDay: 28},
+		"100004": {BirthYear: 1985, BirthMonth: 3, BirthDay: 1},
+		"100005": {BirthYear: 1985},
+	}
+	for sn, info := range birthdays {
+		user := User{IdentScreenName: NewIdentScreenName(sn), DisplayScreenName: DisplayScreenName(sn), IsICQ: true}
+		assert.NoError(t, f.InsertUser(ctx, user))
+		assert.NoError(t, f.SetMoreInfo(ctx, user.IdentScreenName, info))
+	}
+
+	screenNames := func(users []User) []string {
+		var names []string
+		for _, u := range users {
+			names = append(names, u.IdentScreenName.String())
+		}
+		return names
+	}
+
+	t.Run("leap year", func(t *testing.T) {
+		users, err := f.FindByICQBirthday(ctx, time.Date(2024, 2, 28, 15, 0, 0, 0, time.UTC))
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"100001", "100003"}, screenNames(users))
+
+		users, err = f.FindByICQBirthday(ctx, time.Date(2024, 2, 29, 15, 0, 0, 0, time.UTC))
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"100002"}, screenNames(users))
+	})
+
+	t.Run("leap day birthdays on February 28 of non-leap years", func(t *testing.T) {
+		users, err := f.FindByICQBirthday(ctx, time.Date(2025, 2, 28, 15, 0, 0, 0, time.UTC))
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"100001", "100002", "100003"}, screenNames(users))
+	})
+
+	t.Run("no birthdays", func(t *testing.T) {
+		users, err := f.FindByICQBirthday(ctx, time.Date(2025, 7, 4, 0, 0, 0, 0, time.UTC))
+		assert.NoError(t, err)
+		assert.Empty(t, users)
+	})
+}
+
 func TestSQLiteUserStore_FindByICQKeyword(t *testing.T) {
+	t.Parallel()
+
 	// Cleanup after test
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	// Initialize the SQLiteUserStore with a test database file
 	f, err := NewSQLiteUserStore(testFile)
@@ -4582,10 +4672,10 @@ func TestSQLiteUserStore_FindByICQKeyword(t *testing.T) {
 }
 
 func TestSQLiteUserStore_FindByICQName(t *testing.T) {
+	t.Parallel()
+
 	// Cleanup after test
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	// Initialize the SQLiteUserStore with a test database file
 	f, err := NewSQLiteUserStore(testFile)
@@ -4692,10 +4782,10 @@ func TestSQLiteUserStore_FindByICQName(t *testing.T) {
 }
 
 func TestSQLiteUserStore_FindByDirectoryInfo(t *testing.T) {
+	t.Parallel()
+
 	// Cleanup after test
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	// Initialize the SQLiteUserStore with a test database file
 	f, err := NewSQLiteUserStore(testFile)
@@ -4815,10 +4905,10 @@ func TestSQLiteUserStore_FindByDirectoryInfo(t *testing.T) {
 }
 
 func TestSQLiteUserStore_FindByICQEmail(t *testing.T) {
+	t.Parallel()
+
 	// Cleanup after test
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	// Initialize the SQLiteUserStore with a test database file
 	f, err := NewSQLiteUserStore(testFile)
@@ -4883,9 +4973,9 @@ func TestSQLiteUserStore_FindByICQEmail(t *testing.T) {
 }
 
 func TestSQLiteUserStore_FindByAIMEmail(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -4916,6 +5006,22 @@ func TestSQLiteUserStore_FindByAIMEmail(t *testing.T) {
 	err = f.UpdateEmailAddress(context.Background(), user3.IdentScreenName, &mail.Address{Address: "user3@example.com"})
 	assert.NoError(t, err)
 
+	user4 := User{
+		IdentScreenName: NewIdentScreenName("user4"),
+	}
+	err = f.InsertUser(context.Background(), user4)
+	assert.NoError(t, err)
+	err = f.UpdateEmailAddress(context.Background(), user4.IdentScreenName, &mail.Address{Address: "user4@example.com"})
+	assert.NoError(t, err)
+
+	for _, u := range []User{user1, user2, user3} {
+		token, err := f.NewEmailVerificationToken(context.Background(), u.IdentScreenName,
+			&mail.Address{Address: u.IdentScreenName.String() + "@example.com"}, time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		_, err = f.ConfirmEmailAddress(context.Background(), token, time.Now())
+		assert.NoError(t, err)
+	}
+
 	t.Run("Find User by Email", func(t *testing.T) {
 		// Search for user with email "user1@example.com"
 		user, err := f.FindByAIMEmail(context.Background(), "user1@example.com")
@@ -4938,13 +5044,18 @@ func TestSQLiteUserStore_FindByAIMEmail(t *testing.T) {
 		_, err := f.FindByAIMEmail(context.Background(), "nonexistent@example.com")
 		assert.ErrorIs(t, err, ErrNoUser)
 	})
+
+	t.Run("Unverified Email", func(t *testing.T) {
+		_, err := f.FindByAIMEmail(context.Background(), "user4@example.com")
+		assert.ErrorIs(t, err, ErrNoUser)
+	})
 }
 
 func TestSQLiteUserStore_FindByUIN(t *testing.T) {
+	t.Parallel()
+
 	// Cleanup after test
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	testFile := newTestDBPath(t)
 
 	// Initialize the SQLiteUserStore with a test database file
 	f, err := NewSQLiteUserStore(testFile)
@@ -4994,9 +5105,9 @@ func TestSQLiteUserStore_FindByUIN(t *testing.T) {
 }
 
 func TestSQLiteUserStore_RetrieveMessages(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -5071,9 +5182,9 @@ func TestSQLiteUserStore_RetrieveMessages(t *testing.T) {
 }
 
 func TestSQLiteUserStore_DeleteMessages(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -5154,10 +5265,85 @@ func TestSQLiteUserStore_DeleteMessages(t *testing.T) {
 	})
 }
 
+func TestSQLiteUserStore_MoveOfflineMessages(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+	ctx := context.Background()
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	for _, name := range []DisplayScreenName{"John", "Jack", "Anne"} {
+		user, err := NewStubUser(name)
+		require.NoError(t, err)
+		require.NoError(t, f.InsertUser(ctx, user))
+	}
+	john := NewIdentScreenName("John")
+	jack := NewIdentScreenName("Jack")
+	anne := NewIdentScreenName("Anne")
+
+	sendTime := time.Now().UTC().Truncate(time.Second)
+	for i, msg := range []OfflineMessage{
+		{Sender: john, Recipient: jack},
+		{Sender: anne, Recipient: jack},
+		{Sender: john, Recipient: anne},
+	} {
+		msg.Message = wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{Cookie: uint64(i + 1)}
+		msg.Sent = sendTime
+		_, err := f.SaveMessage(ctx, msg)
+		require.NoError(t, err)
+	}
+
+	t.Run("unknown recipient", func(t *testing.T) {
+		_, err := f.MoveOfflineMessages(ctx, jack, NewIdentScreenName("Nobody"))
+		assert.ErrorIs(t, err, ErrNoUser)
+
+		messages, err := f.RetrieveMessages(ctx, jack)
+		require.NoError(t, err)
+		assert.Len(t, messages, 2)
+	})
+
+	t.Run("move to another account", func(t *testing.T) {
+		moved, err := f.MoveOfflineMessages(ctx, jack, anne)
+		require.NoError(t, err)
+		assert.Equal(t, 2, moved)
+
+		messages, err := f.RetrieveMessages(ctx, jack)
+		require.NoError(t, err)
+		assert.Empty(t, messages)
+
+		messages, err = f.RetrieveMessages(ctx, anne)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []OfflineMessage{
+			{Sender: john, Recipient: anne, Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{Cookie: 1}, Sent: sendTime},
+			{Sender: anne, Recipient: anne, Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{Cookie: 2}, Sent: sendTime},
+			{Sender: john, Recipient: anne, Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{Cookie: 3}, Sent: sendTime},
+		}, messages)
+
+		user, err := f.User(ctx, jack)
+		require.NoError(t, err)
+		assert.Zero(t, user.OfflineMsgCount)
+		user, err = f.User(ctx, anne)
+		require.NoError(t, err)
+		assert.Equal(t, 3, user.OfflineMsgCount)
+	})
+
+	t.Run("nothing to move", func(t *testing.T) {
+		moved, err := f.MoveOfflineMessages(ctx, jack, anne)
+		require.NoError(t, err)
+		assert.Zero(t, moved)
+
+		moved, err = f.MoveOfflineMessages(ctx, anne, anne)
+		require.NoError(t, err)
+		assert.Zero(t, moved)
+	})
+}
+
 func TestSQLiteUserStore_SaveMessage(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	store, err := NewSQLiteUserStore(testFile)
 	require.NoError(t, err)
@@ -5220,12 +5406,30 @@ func TestSQLiteUserStore_SaveMessage(t *testing.T) {
 		_, err := store.SaveMessage(context.Background(), missingRecipientMsg)
 		require.ErrorIs(t, err, ErrNoUser)
 	})
+
+	t.Run("deleting sender deletes their messages", func(t *testing.T) {
+		createStubUser(t, *store, DisplayScreenName("Sender2"))
+		createStubUser(t, *store, DisplayScreenName("Recipient2"))
+		_, err := store.SaveMessage(context.Background(), OfflineMessage{
+			Sender:    NewIdentScreenName("Sender2"),
+			Recipient: NewIdentScreenName("Recipient2"),
+			Message:   msg.Message,
+			Sent:      time.Now().UTC(),
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, store.DeleteUser(context.Background(), NewIdentScreenName("Sender2")))
+
+		msgs, err := store.RetrieveMessages(context.Background(), NewIdentScreenName("Recipient2"))
+		require.NoError(t, err)
+		assert.Empty(t, msgs)
+	})
 }
 
 func TestSQLiteUserStore_BuddyIconMetadataExistingRef(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 	screenName := NewIdentScreenName("TalkingTyler")
 	testHash := []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'}
 
@@ -5258,9 +5462,9 @@ func TestSQLiteUserStore_BuddyIconMetadataExistingRef(t *testing.T) {
 }
 
 func TestSQLiteUserStore_BuddyIconMetadataMissingRef(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	existingScreenName := NewIdentScreenName("TalkingTyler")
 	queryScreenName := NewIdentScreenName("SingingSuzy")
@@ -5295,9 +5499,9 @@ func TestSQLiteUserStore_BuddyIconMetadataMissingRef(t *testing.T) {
 }
 
 func TestSQLiteUserStore_SetDirectoryInfo(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -5368,10 +5572,10 @@ func TestSQLiteUserStore_SetDirectoryInfo(t *testing.T) {
 }
 
 func TestSQLiteUserStore_Categories(t *testing.T) {
+	t.Parallel()
+
 	t.Run("Retrieve Keyword Categories Successfully", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
 
@@ -5406,9 +5610,7 @@ func TestSQLiteUserStore_Categories(t *testing.T) {
 	})
 
 	t.Run("No Categories Exist", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
 
@@ -5422,9 +5624,7 @@ func TestSQLiteUserStore_Categories(t *testing.T) {
 	})
 
 	t.Run("SQL Error Handling", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
 
@@ -5437,9 +5637,7 @@ func TestSQLiteUserStore_Categories(t *testing.T) {
 	})
 
 	t.Run("Unique Constraint Violation", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
 
@@ -5455,10 +5653,10 @@ func TestSQLiteUserStore_Categories(t *testing.T) {
 }
 
 func TestSQLiteUserStore_CreateCategory(t *testing.T) {
+	t.Parallel()
+
 	t.Run("Successfully Create Keyword Category", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
 
@@ -5477,9 +5675,7 @@ func TestSQLiteUserStore_CreateCategory(t *testing.T) {
 	})
 
 	t.Run("Duplicate Category Cookie", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
 
@@ -5495,9 +5691,7 @@ func TestSQLiteUserStore_CreateCategory(t *testing.T) {
 	})
 
 	t.Run("ID Overflow", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
 
@@ -5513,9 +5707,7 @@ func TestSQLiteUserStore_CreateCategory(t *testing.T) {
 	})
 
 	t.Run("SQL Error Handling", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
 
@@ -5529,12 +5721,13 @@ func TestSQLiteUserStore_CreateCategory(t *testing.T) {
 }
 
 func TestSQLiteUserStore_DeleteCategory(t *testing.T) {
+	t.Parallel()
+
 	t.Run("Successfully Delete Keyword Category", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
 
 		// Insert a test category
 		categoryName := "CategoryToDelete"
@@ -5557,11 +5750,10 @@ func TestSQLiteUserStore_DeleteCategory(t *testing.T) {
 	})
 
 	t.Run("Delete Non-Existent Category", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
 
 		// Attempt to delete a category that does not exist
 		nonExistentCategoryID := uint8(99)
@@ -5570,11 +5762,10 @@ func TestSQLiteUserStore_DeleteCategory(t *testing.T) {
 	})
 
 	t.Run("Delete category and all of its keywords", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
 
 		// Insert a category
 		categoryName := "CategoryInUse"
@@ -5603,10 +5794,10 @@ func TestSQLiteUserStore_DeleteCategory(t *testing.T) {
 }
 
 func TestSQLiteUserStore_CreateKeyword(t *testing.T) {
+	t.Parallel()
+
 	t.Run("Successfully Create Keyword", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
 
@@ -5635,9 +5826,7 @@ func TestSQLiteUserStore_CreateKeyword(t *testing.T) {
 	})
 
 	t.Run("Create Keyword Without Category", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
 
@@ -5661,9 +5850,7 @@ func TestSQLiteUserStore_CreateKeyword(t *testing.T) {
 	})
 
 	t.Run("Create Keyword With Unknown Category", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
 
@@ -5674,9 +5861,7 @@ func TestSQLiteUserStore_CreateKeyword(t *testing.T) {
 	})
 
 	t.Run("Duplicate Keyword Cookie", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
 
@@ -5692,9 +5877,7 @@ func TestSQLiteUserStore_CreateKeyword(t *testing.T) {
 	})
 
 	t.Run("ID Overflow", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
 
@@ -5715,9 +5898,7 @@ func TestSQLiteUserStore_CreateKeyword(t *testing.T) {
 	})
 
 	t.Run("SQL Error Handling", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
 
@@ -5731,12 +5912,13 @@ func TestSQLiteUserStore_CreateKeyword(t *testing.T) {
 }
 
 func TestSQLiteUserStore_DeleteKeyword(t *testing.T) {
+	t.Parallel()
+
 	t.Run("Successfully Delete Keyword", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
 
 		// Insert a category
 		categoryName := "TestCategory"
@@ -5764,11 +5946,10 @@ func TestSQLiteUserStore_DeleteKeyword(t *testing.T) {
 	})
 
 	t.Run("Delete Non-Existent Keyword", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
 
 		// Attempt to delete a keyword that does not exist
 		nonExistentKeywordID := uint8(99)
@@ -5777,11 +5958,10 @@ func TestSQLiteUserStore_DeleteKeyword(t *testing.T) {
 	})
 
 	t.Run("Delete Keyword Associated with User", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
 
 		// Insert a category
 		categoryName := "CategoryInUse"
@@ -5810,12 +5990,13 @@ func TestSQLiteUserStore_DeleteKeyword(t *testing.T) {
 }
 
 func TestSQLiteUserStore_InterestList(t *testing.T) {
+	t.Parallel()
+
 	t.Run("Full list", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
 
 		tech, err := f.CreateCategory(context.Background(), "Technology")
 		assert.NoError(t, err)
@@ -5912,11 +6093,10 @@ func TestSQLiteUserStore_InterestList(t *testing.T) {
 	})
 
 	t.Run("Empty list list", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
 
 		actual, err := f.InterestList(context.Background())
 		assert.NoError(t, err)
@@ -5925,10 +6105,10 @@ func TestSQLiteUserStore_InterestList(t *testing.T) {
 }
 
 func TestSQLiteUserStore_KeywordsByCategory(t *testing.T) {
+	t.Parallel()
+
 	t.Run("Category Does Not Exist", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
 
@@ -5944,9 +6124,9 @@ func TestSQLiteUserStore_KeywordsByCategory(t *testing.T) {
 }
 
 func TestSQLiteUserStore_UnregisterBuddyList(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -6002,9 +6182,9 @@ func TestSQLiteUserStore_UnregisterBuddyList(t *testing.T) {
 }
 
 func TestSQLiteUserStore_ClearBuddyListRegistry(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -6046,9 +6226,9 @@ func TestSQLiteUserStore_ClearBuddyListRegistry(t *testing.T) {
 }
 
 func TestSQLiteUserStore_RemoveBuddy(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -6093,9 +6273,9 @@ func TestSQLiteUserStore_RemoveBuddy(t *testing.T) {
 }
 
 func TestSQLiteUserStore_RemoveDenyBuddy(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -6146,9 +6326,9 @@ func TestSQLiteUserStore_RemoveDenyBuddy(t *testing.T) {
 }
 
 func TestSQLiteUserStore_RemovePermitBuddy(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -6171,11 +6351,12 @@ func TestSQLiteUserStore_RemovePermitBuddy(t *testing.T) {
 
 	expect := []Relationship{
 		{
-			User:          them,
-			IsOnTheirList: false,
-			IsOnYourList:  false,
-			YouBlock:      false,
-			BlocksYou:     false,
+			User:               them,
+			IsOnTheirList:      false,
+			IsOnYourList:       false,
+			IsOnYourPermitList: true,
+			YouBlock:           false,
+			BlocksYou:          false,
 		},
 	}
 	assert.ElementsMatch(t, relationships, expect)
@@ -6199,10 +6380,10 @@ func TestSQLiteUserStore_RemovePermitBuddy(t *testing.T) {
 }
 
 func TestSQLiteUserStore_SetPDMode(t *testing.T) {
+	t.Parallel()
+
 	t.Run("Ensure idempotency", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
@@ -6225,20 +6406,19 @@ func TestSQLiteUserStore_SetPDMode(t *testing.T) {
 
 		expect := []Relationship{
 			{
-				User:          users[1],
-				IsOnTheirList: false,
-				IsOnYourList:  false,
-				YouBlock:      false,
-				BlocksYou:     false,
+				User:               users[1],
+				IsOnTheirList:      false,
+				IsOnYourList:       false,
+				IsOnYourPermitList: true,
+				YouBlock:           false,
+				BlocksYou:          false,
 			},
 		}
 		assert.ElementsMatch(t, relationships, expect)
 	})
 
 	t.Run("Ensure transition from one mode to another clears previously set flags", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
@@ -6264,9 +6444,9 @@ func TestSQLiteUserStore_SetPDMode(t *testing.T) {
 
 // Ensure that transitioning between all the PD modes works.
 func TestSQLiteUserStore_PermitDenyTransitionIntegration(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -6302,11 +6482,12 @@ func TestSQLiteUserStore_PermitDenyTransitionIntegration(t *testing.T) {
 			BlocksYou:     false,
 		},
 		{
-			User:          users[2],
-			IsOnTheirList: false,
-			IsOnYourList:  false,
-			YouBlock:      false,
-			BlocksYou:     false,
+			User:               users[2],
+			IsOnTheirList:      false,
+			IsOnYourList:       false,
+			IsOnYourPermitList: true,
+			YouBlock:           false,
+			BlocksYou:          false,
 		},
 	}
 	assert.ElementsMatch(t, relationships, expect)
@@ -6346,11 +6527,12 @@ func TestSQLiteUserStore_PermitDenyTransitionIntegration(t *testing.T) {
 			BlocksYou:     false,
 		},
 		{
-			User:          users[3],
-			IsOnTheirList: false,
-			IsOnYourList:  false,
-			YouBlock:      false,
-			BlocksYou:     false,
+			User:               users[3],
+			IsOnTheirList:      false,
+			IsOnYourList:       false,
+			IsOnYourPermitList: true,
+			YouBlock:           false,
+			BlocksYou:          false,
 		},
 	}
 	assert.ElementsMatch(t, relationships, expect)
@@ -6481,9 +6663,9 @@ func TestSQLiteUserStore_PermitDenyTransitionIntegration(t *testing.T) {
 }
 
 func TestSQLiteUserStore_UpdateSuspendedStatus(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -6511,9 +6693,9 @@ func TestSQLiteUserStore_UpdateSuspendedStatus(t *testing.T) {
 }
 
 func TestSQLiteUserStore_SetBotStatus(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	f, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -6550,10 +6732,10 @@ func TestSQLiteUserStore_SetBotStatus(t *testing.T) {
 }
 
 func TestSQLiteUserStore_SetWarnLevel(t *testing.T) {
+	t.Parallel()
+
 	t.Run("Happy Path - Update Warning Level for Existing User", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
@@ -6580,9 +6762,7 @@ func TestSQLiteUserStore_SetWarnLevel(t *testing.T) {
 	})
 
 	t.Run("User Does Not Exist", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 
 		f, err := NewSQLiteUserStore(testFile)
 		assert.NoError(t, err)
@@ -6597,10 +6777,10 @@ func TestSQLiteUserStore_SetWarnLevel(t *testing.T) {
 }
 
 func TestSQLiteUserStore_DeleteBARTItem(t *testing.T) {
+	t.Parallel()
+
 	t.Run("delete_existing_item", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 
 		f, err := NewSQLiteUserStore(testFile)
 		require.NoError(t, err)
@@ -6631,9 +6811,7 @@ func TestSQLiteUserStore_DeleteBARTItem(t *testing.T) {
 	})
 
 	t.Run("delete_nonexistent_item", func(t *testing.T) {
-		defer func() {
-			assert.NoError(t, os.Remove(testFile))
-		}()
+		testFile := newTestDBPath(t)
 
 		f, err := NewSQLiteUserStore(testFile)
 		require.NoError(t, err)
@@ -6647,6 +6825,8 @@ func TestSQLiteUserStore_DeleteBARTItem(t *testing.T) {
 }
 
 func TestSQLiteUserStore_ChatRoomByCookie(t *testing.T) {
+	t.Parallel()
+
 	tests := []struct {
 		name        string
 		givenRoom   ChatRoom
@@ -6675,9 +6855,7 @@ func TestSQLiteUserStore_ChatRoomByCookie(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			defer func() {
-				assert.NoError(t, os.Remove(testFile))
-			}()
+			testFile := newTestDBPath(t)
 
 			userStore, err := NewSQLiteUserStore(testFile)
 			assert.NoError(t, err)
@@ -6695,6 +6873,8 @@ func TestSQLiteUserStore_ChatRoomByCookie(t *testing.T) {
 }
 
 func TestSQLiteUserStore_ChatRoomByName(t *testing.T) {
+	t.Parallel()
+
 	tests := []struct {
 		name        string
 		givenRoom   ChatRoom
@@ -6723,9 +6903,7 @@ func TestSQLiteUserStore_ChatRoomByName(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			defer func() {
-				assert.NoError(t, os.Remove(testFile))
-			}()
+			testFile := newTestDBPath(t)
 
 			userStore, err := NewSQLiteUserStore(testFile)
 			assert.NoError(t, err)
@@ -6743,9 +6921,9 @@ func TestSQLiteUserStore_ChatRoomByName(t *testing.T) {
 }
 
 func TestSQLiteUserStore_AllChatRooms(t *testing.T) {
-	defer func() {
-		assert.NoError(t, os.Remove(testFile))
-	}()
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
 
 	userStore, err := NewSQLiteUserStore(testFile)
 	assert.NoError(t, err)
@@ -6777,6 +6955,7 @@ func TestSQLiteUserStore_AllChatRooms(t *testing.T) {
 }
 
 func TestSQLiteUserStore_CreateChatRoom_ErrChatRoomExists(t *testing.T) {
+	t.Parallel()
 
 	tt := []struct {
 		name         string
@@ -6800,9 +6979,7 @@ func TestSQLiteUserStore_CreateChatRoom_ErrChatRoomExists(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			defer func() {
-				assert.NoError(t, os.Remove(testFile))
-			}()
+			testFile := newTestDBPath(t)
 
 			userStore, err := NewSQLiteUserStore(testFile)
 			assert.NoError(t, err)
@@ -6816,6 +6993,659 @@ func TestSQLiteUserStore_CreateChatRoom_ErrChatRoomExists(t *testing.T) {
 	}
 }
 
+func TestSQLiteUserStore_RenameScreenName(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	setup := func(t *testing.T) *SQLiteUserStore {
+		store, err := NewSQLiteUserStore(newTestDBPath(t))
+		require.NoError(t, err)
+		for _, sn := range []DisplayScreenName{"Old Name", "Buddy", "Taken"} {
+			u, err := NewStubUser(sn)
+			require.NoError(t, err)
+			require.NoError(t, store.InsertUser(ctx, u))
+		}
+		return store
+	}
+
+	t.Run("rename moves all references", func(t *testing.T) {
+		store := setup(t)
+
+		oldName := NewIdentScreenName("Old Name")
+		buddy := NewIdentScreenName("Buddy")
+
+		require.NoError(t, store.FeedbagUpsert(ctx, oldName, []wire.FeedbagItem{
+			newFeedbagItem(wire.FeedbagClassIdBuddy, 1, "buddy"),
+		}))
+		require.NoError(t, store.FeedbagUpsert(ctx, buddy, []wire.FeedbagItem{
+			newFeedbagItem(wire.FeedbagClassIdBuddy, 1, "oldname"),
+			newFeedbagItem(wire.FeedbagClassIDDeny, 2, "oldname"),
+			newFeedbagItem(wire.FeedbagClassIdGroup, 3, "oldname"),
+		}))
+		require.NoError(t, store.RegisterBuddyList(ctx, buddy))
+		require.NoError(t, store.RegisterBuddyList(ctx, oldName))
+		require.NoError(t, store.AddBuddy(ctx, buddy, oldName))
+		_, err := store.SaveMessage(ctx, OfflineMessage{
+			Sender:    buddy,
+			Recipient: oldName,
+			Sent:      time.Now().UTC(),
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, store.RenameScreenName(ctx, oldName, "New Name"))
+		newName := NewIdentScreenName("New Name")
+
+		u, err := store.User(ctx, oldName)
+		require.NoError(t, err)
+		assert.Nil(t, u)
+
+		u, err = store.User(ctx, newName)
+		require.NoError(t, err)
+		require.NotNil(t, u)
+		assert.Equal(t, DisplayScreenName("New Name"), u.DisplayScreenName)
+
+		items, err := store.Feedbag(ctx, newName)
+		require.NoError(t, err)
+		assert.Len(t, items, 1)
+
+		items, err = store.Feedbag(ctx, buddy)
+		require.NoError(t, err)
+		require.Len(t, items, 3)
+		for _, item := range items {
+			if item.ClassID == wire.FeedbagClassIdGroup {
+				assert.Equal(t, "oldname", item.Name)
+			} else {
+				assert.Equal(t, newName.String(), item.Name)
+			}
+		}
+
+		rel, err := store.Relationship(ctx, buddy, newName)
+		require.NoError(t, err)
+		assert.True(t, rel.IsOnYourList)
+
+		msgs, err := store.RetrieveMessages(ctx, newName)
+		require.NoError(t, err)
+		assert.Len(t, msgs, 1)
+	})
+
+	t.Run("rename moves references without foreign keys", func(t *testing.T) {
+		store := setup(t)
+
+		oldName := NewIdentScreenName("Old Name")
+		buddy := NewIdentScreenName("Buddy")
+		now := time.Now().UTC()
+
+		// Buddy blocked messages from the user, both before and after a
+		// previous account under the new name was deleted
+		require.NoError(t, store.RecordBlockedMessage(ctx, buddy, oldName, now))
+		require.NoError(t, store.RecordBlockedMessage(ctx, buddy, NewIdentScreenName("New Name"), now))
+		require.NoError(t, store.SetPresenceWebhookAllowed(ctx, buddy, true))
+		require.NoError(t, store.SetPresenceWebhook(ctx, PresenceWebhook{
+			Owner:   buddy,
+			URL:     "https://example.com/presence",
+			Buddies: []IdentScreenName{oldName},
+		}))
+		_, err := store.ReserveScreenName(ctx, oldName)
+		require.NoError(t, err)
+
+		require.NoError(t, store.RenameScreenName(ctx, oldName, "New Name"))
+		newName := NewIdentScreenName("New Name")
+
+		blocked, err := store.BlockedMessagesFrom(ctx, oldName)
+		require.NoError(t, err)
+		assert.Empty(t, blocked)
+		blocked, err = store.BlockedMessagesFrom(ctx, newName)
+		require.NoError(t, err)
+		require.Len(t, blocked, 1)
+		assert.Equal(t, buddy, blocked[0].ScreenName)
+
+		hook, err := store.PresenceWebhook(ctx, buddy)
+		require.NoError(t, err)
+		assert.Equal(t, []IdentScreenName{newName}, hook.Buddies)
+		watching, err := store.PresenceWebhooksWatching(ctx, newName)
+		require.NoError(t, err)
+		assert.Len(t, watching, 1)
+
+		assert.NoError(t, store.CheckScreenNameReserved(ctx, oldName))
+		assert.ErrorIs(t, store.CheckScreenNameReserved(ctx, newName), ErrScreenNameReserved)
+	})
+
+	t.Run("new name collides with existing user", func(t *testing.T) {
+		store := setup(t)
+
+		err := store.RenameScreenName(ctx, NewIdentScreenName("Old Name"), "TAKEN")
+		assert.ErrorIs(t, err, ErrDupUser)
+
+		u, err := store.User(ctx, NewIdentScreenName("Old Name"))
+		require.NoError(t, err)
+		assert.NotNil(t, u)
+	})
+
+	t.Run("old name does not exist", func(t *testing.T) {
+		store := setup(t)
+
+		err := store.RenameScreenName(ctx, NewIdentScreenName("Nobody"), "New Name")
+		assert.ErrorIs(t, err, ErrNoUser)
+	})
+
+	t.Run("new name is invalid", func(t *testing.T) {
+		store := setup(t)
+
+		err := store.RenameScreenName(ctx, NewIdentScreenName("Old Name"), "1nvalid")
+		assert.ErrorIs(t, err, ErrAIMHandleInvalidFormat)
+	})
+}
+
+func TestSQLiteUserStore_LinkAccounts(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	ctx := context.Background()
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	for _, sn := range []DisplayScreenName{"AIM User", "100001", "Other User", "100002"} {
+		u, err := NewStubUser(sn)
+		require.NoError(t, err)
+		require.NoError(t, store.InsertUser(ctx, u))
+	}
+
+	aim := NewIdentScreenName("AIM User")
+	icq := NewIdentScreenName("100001")
+
+	t.Run("unlinked user resolves to itself", func(t *testing.T) {
+		names, err := store.LinkedScreenNames(ctx, aim)
+		require.NoError(t, err)
+		assert.Equal(t, []IdentScreenName{aim}, names)
+	})
+
+	t.Run("link accounts", func(t *testing.T) {
+		require.NoError(t, store.LinkAccounts(ctx, "AIM User", "100001"))
+
+		names, err := store.LinkedScreenNames(ctx, aim)
+		require.NoError(t, err)
+		assert.Equal(t, []IdentScreenName{aim, icq}, names)
+
+		names, err = store.LinkedScreenNames(ctx, icq)
+		require.NoError(t, err)
+		assert.Equal(t, []IdentScreenName{icq, aim}, names)
+	})
+
+	t.Run("identity already linked", func(t *testing.T) {
+		assert.ErrorIs(t, store.LinkAccounts(ctx, "AIM User", "100002"), ErrAccountLinked)
+		assert.ErrorIs(t, store.LinkAccounts(ctx, "Other User", "100001"), ErrAccountLinked)
+	})
+
+	t.Run("invalid identity types", func(t *testing.T) {
+		assert.ErrorIs(t, store.LinkAccounts(ctx, "100002", "100001"), ErrAccountLinkInvalid)
+		assert.ErrorIs(t, store.LinkAccounts(ctx, "Other User", "AIM User"), ErrAccountLinkInvalid)
+	})
+
+	t.Run("identity does not exist", func(t *testing.T) {
+		assert.ErrorIs(t, store.LinkAccounts(ctx, "Nobody", "100002"), ErrNoUser)
+	})
+
+	t.Run("unlink accounts", func(t *testing.T) {
+		require.NoError(t, store.UnlinkAccounts(ctx, icq))
+		names, err := store.LinkedScreenNames(ctx, aim)
+		require.NoError(t, err)
+		assert.Equal(t, []IdentScreenName{aim}, names)
+
+		assert.ErrorIs(t, store.UnlinkAccounts(ctx, icq), ErrNoUser)
+	})
+
+	t.Run("deleting a user removes the link", func(t *testing.T) {
+		require.NoError(t, store.LinkAccounts(ctx, "Other User", "100002"))
+		require.NoError(t, store.DeleteUser(ctx, NewIdentScreenName("100002")))
+		names, err := store.LinkedScreenNames(ctx, NewIdentScreenName("Other User"))
+		require.NoError(t, err)
+		assert.Len(t, names, 1)
+	})
+}
+
+func TestSQLiteUserStore_Ping(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	assert.NoError(t, store.Ping(context.Background()))
+
+	require.NoError(t, store.pool.Close())
+	assert.Error(t, store.Ping(context.Background()))
+}
+
+func TestSQLiteUserStore_FeedbagBARTReferences(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	ctx := context.Background()
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	me := NewIdentScreenName("me")
+	arriveSound := wire.BARTInfo{Flags: wire.BARTFlagsCustom, Hash: []byte{'a', 'r', 'r', 'i', 'v', 'e'}}
+	leaveSound := wire.BARTInfo{Flags: wire.BARTFlagsCustom, Hash: []byte{'l', 'e', 'a', 'v', 'e'}}
+	icon := wire.BARTInfo{Flags: wire.BARTFlagsKnown, Hash: []byte{'i', 'c', 'o', 'n'}}
+	skin := wire.BARTID{Type: wire.BARTTypesImSkin, BARTInfo: wire.BARTInfo{Hash: []byte{'s', 'k', 'i', 'n'}}}
+
+	buddy := newFeedbagItem(wire.FeedbagClassIdBuddy, 1, "them")
+	buddy.Append(wire.NewTLVBE(wire.FeedbagAttributesArriveSound, arriveSound))
+	buddy.Append(wire.NewTLVBE(wire.FeedbagAttributesLeaveSound, leaveSound))
+	buddy.Append(wire.NewTLVBE(wire.FeedbagAttributesBartList, []wire.BARTID{skin}))
+	bartItem := newFeedbagItem(wire.FeedbagClassIdBart, 2, strconv.Itoa(int(wire.BARTTypesBuddyIcon)))
+	bartItem.Append(wire.NewTLVBE(wire.FeedbagAttributesBartInfo, icon))
+
+	require.NoError(t, store.FeedbagUpsert(ctx, me, []wire.FeedbagItem{buddy, bartItem}))
+	require.NoError(t, store.InsertBARTItem(ctx, arriveSound.Hash, []byte("sound-data"), wire.BARTTypesArriveSound))
+
+	// attributes are returned untouched
+	items, err := store.Feedbag(ctx, me)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []wire.FeedbagItem{buddy, bartItem}, items)
+
+	refs, err := store.FeedbagBARTReferences(ctx, me)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []FeedbagBARTReference{
+		{
+			ClassID:  wire.FeedbagClassIdBuddy,
+			ItemName: "them",
+			ID:       wire.BARTID{Type: wire.BARTTypesArriveSound, BARTInfo: arriveSound},
+			Stored:   true,
+		},
+		{
+			ClassID:  wire.FeedbagClassIdBuddy,
+			ItemName: "them",
+			ID:       wire.BARTID{Type: wire.BARTTypesDepartSound, BARTInfo: leaveSound},
+		},
+		{
+			ClassID:  wire.FeedbagClassIdBuddy,
+			ItemName: "them",
+			ID:       skin,
+		},
+		{
+			ClassID:  wire.FeedbagClassIdBart,
+			ItemName: "1",
+			ID:       wire.BARTID{Type: wire.BARTTypesBuddyIcon, BARTInfo: icon},
+		},
+	}, refs)
+
+	i := slices.IndexFunc(refs, func(ref FeedbagBARTReference) bool {
+		return ref.ID.Type == wire.BARTTypesArriveSound
+	})
+	require.NotEqual(t, -1, i, "arrive sound reference not found")
+	body, err := store.BARTItem(ctx, refs[i].ID.Hash)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("sound-data"), body)
+}
+
+func TestSQLiteUserStore_PendingContacts(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	ctx := context.Background()
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	for _, sn := range []DisplayScreenName{"100001", "100002"} {
+		u, err := NewStubUser(sn)
+		require.NoError(t, err)
+		require.NoError(t, store.InsertUser(ctx, u))
+	}
+
+	sender := NewIdentScreenName("100001")
+	recip := NewIdentScreenName("100002")
+
+	contacts := []wire.ICQContact{
+		{ScreenName: "100003", Nick: "bob"},
+		{ScreenName: "Chatting Chuck", Nick: "chuck"},
+		{ScreenName: "100002", Nick: "myself"},
+	}
+	require.NoError(t, store.OfferContacts(ctx, sender, recip, contacts))
+
+	// chuck is already a buddy, so he shouldn't be offered
+	require.NoError(t, store.FeedbagUpsert(ctx, recip, []wire.FeedbagItem{
+		newFeedbagItem(wire.FeedbagClassIdBuddy, 1, "chattingchuck"),
+	}))
+
+	have, err := store.PendingContacts(ctx, recip)
+	require.NoError(t, err)
+	require.Len(t, have, 1)
+	assert.Equal(t, NewIdentScreenName("100003"), have[0].ScreenName)
+	assert.Equal(t, "bob", have[0].Nick)
+	assert.Equal(t, sender, have[0].Sender)
+
+	err = store.OfferContacts(ctx, NewIdentScreenName("nobody"), recip, contacts[:1])
+	assert.ErrorIs(t, err, ErrNoUser)
+
+	require.NoError(t, store.DeletePendingContacts(ctx, recip))
+	have, err = store.PendingContacts(ctx, recip)
+	require.NoError(t, err)
+	assert.Empty(t, have)
+}
+
+func TestSQLiteUserStore_AutoResponse(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	ctx := context.Background()
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	u, err := NewStubUser("100001")
+	require.NoError(t, err)
+	require.NoError(t, store.InsertUser(ctx, u))
+
+	msg, err := store.AutoResponse(ctx, u.IdentScreenName, wire.ICBMMsgTypeAutoNA)
+	require.NoError(t, err)
+	assert.Empty(t, msg)
+
+	require.NoError(t, store.SetAutoResponse(ctx, u.IdentScreenName, wire.ICBMMsgTypeAutoNA, "gone fishing"))
+	require.NoError(t, store.SetAutoResponse(ctx, u.IdentScreenName, wire.ICBMMsgTypeAutoDND, "do not disturb"))
+	require.NoError(t, store.SetAutoResponse(ctx, u.IdentScreenName, wire.ICBMMsgTypeAutoNA, "still fishing"))
+
+	msg, err = store.AutoResponse(ctx, u.IdentScreenName, wire.ICBMMsgTypeAutoNA)
+	require.NoError(t, err)
+	assert.Equal(t, "still fishing", msg)
+
+	msg, err = store.AutoResponse(ctx, u.IdentScreenName, wire.ICBMMsgTypeAutoDND)
+	require.NoError(t, err)
+	assert.Equal(t, "do not disturb", msg)
+
+	require.NoError(t, store.SetAutoResponse(ctx, u.IdentScreenName, wire.ICBMMsgTypeAutoDND, ""))
+	msg, err = store.AutoResponse(ctx, u.IdentScreenName, wire.ICBMMsgTypeAutoDND)
+	require.NoError(t, err)
+	assert.Empty(t, msg)
+
+	err = store.SetAutoResponse(ctx, NewIdentScreenName("nobody"), wire.ICBMMsgTypeAutoAway, "away")
+	assert.ErrorIs(t, err, ErrNoUser)
+}
+
+func TestSQLiteUserStore_QueryPlansUseIndices(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+
+	explain := func(q string, args ...any) string {
+		rows, err := store.db.Query("EXPLAIN QUERY PLAN "+q, args...)
+		require.NoError(t, err)
+		defer rows.Close()
+
+		var plan strings.Builder
+		for rows.Next() {
+			var id, parent, notUsed int
+			var detail string
+			require.NoError(t, rows.Scan(&id, &parent, &notUsed, &detail))
+			plan.WriteString(detail)
+			plan.WriteString("\n")
+		}
+		require.NoError(t, rows.Err())
+		return plan.String()
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		args      []any
+		wantIndex string
+	}{
+		{
+			name:      "feedbag items by class",
+			query:     `SELECT groupID, itemID, name FROM feedbag WHERE screenName = ? AND classID = ?`,
+			args:      []any{"me", wire.FeedbagClassIDPermit},
+			wantIndex: "idx_feedbag_screenName_classID",
+		},
+		{
+			name:      "users who have me on their list",
+			query:     `SELECT screenName FROM feedbag WHERE name = ? AND classID IN (0, 2, 3)`,
+			args:      []any{"me"},
+			wantIndex: "idx_feedbag_name_classID",
+		},
+		{
+			name:      "relationship query",
+			query:     queryWithoutFiltering,
+			args:      []any{"me"},
+			wantIndex: "idx_feedbag_name_classID",
+		},
+		{
+			name:      "client-side buddy lists that include me",
+			query:     `SELECT me FROM clientSideBuddyList WHERE them = ?`,
+			args:      []any{"me"},
+			wantIndex: "idx_clientSideBuddyList_them",
+		},
+		{
+			name:      "BART items by type",
+			query:     `SELECT hash, type FROM bartItem WHERE type = ?`,
+			args:      []any{wire.BARTTypesBuddyIcon},
+			wantIndex: "idx_bartItem_type_hash",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Contains(t, explain(tt.query, tt.args...), tt.wantIndex)
+		})
+	}
+}
+
+func TestSQLiteUserStore_AccountExpiry(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	ctx := context.Background()
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.nowFn = func() time.Time { return now }
+
+	for _, sn := range []DisplayScreenName{"trial user", "expired user", "regular user"} {
+		u, err := NewStubUser(sn)
+		require.NoError(t, err)
+		require.NoError(t, store.InsertUser(ctx, u))
+	}
+
+	require.NoError(t, store.SetAccountExpiry(ctx, NewIdentScreenName("trial user"), now.Add(24*time.Hour)))
+	require.NoError(t, store.SetAccountExpiry(ctx, NewIdentScreenName("expired user"), now.Add(-48*time.Hour)))
+	assert.ErrorIs(t, store.SetAccountExpiry(ctx, NewIdentScreenName("nobody"), now), ErrNoUser)
+
+	trial, err := store.User(ctx, NewIdentScreenName("trial user"))
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(24*time.Hour), trial.ExpiresAt)
+	assert.Zero(t, trial.ExpiredStatus)
+
+	expired, err := store.User(ctx, NewIdentScreenName("expired user"))
+	require.NoError(t, err)
+	assert.True(t, expired.Expired(now))
+	assert.Equal(t, wire.LoginErrSuspendedAccountAge, expired.ExpiredStatus)
+	assert.Zero(t, expired.SuspendedStatus)
+
+	// the trial account expires once the store's clock passes its expiry
+	store.nowFn = func() time.Time { return now.Add(24 * time.Hour) }
+	trial, err = store.User(ctx, NewIdentScreenName("trial user"))
+	require.NoError(t, err)
+	assert.Equal(t, wire.LoginErrSuspendedAccountAge, trial.ExpiredStatus)
+	store.nowFn = func() time.Time { return now }
+
+	regular, err := store.User(ctx, NewIdentScreenName("regular user"))
+	require.NoError(t, err)
+	assert.True(t, regular.ExpiresAt.IsZero())
+	assert.False(t, regular.Expired(now))
+	assert.Zero(t, regular.ExpiredStatus)
+	assert.WithinDuration(t, time.Now(), regular.CreatedAt, 5*time.Second)
+
+	// still within the grace period
+	purged, err := store.PurgeExpiredUsers(ctx, now.Add(-72*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, purged)
+
+	purged, err = store.PurgeExpiredUsers(ctx, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []IdentScreenName{NewIdentScreenName("expired user")}, purged)
+
+	u, err := store.User(ctx, NewIdentScreenName("expired user"))
+	require.NoError(t, err)
+	assert.Nil(t, u)
+
+	// removing the expiry keeps the account
+	require.NoError(t, store.SetAccountExpiry(ctx, NewIdentScreenName("trial user"), time.Time{}))
+	purged, err = store.PurgeExpiredUsers(ctx, now.Add(365*24*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, purged)
+}
+
+func TestSQLiteUserStore_RenameFeedbagGroup(t *testing.T) {
+	t.Parallel()
+
+	me := NewIdentScreenName("me")
+
+	groupItem := func(groupID uint16, name string, order ...uint16) wire.FeedbagItem {
+		item := wire.FeedbagItem{GroupID: groupID, ClassID: wire.FeedbagClassIdGroup, Name: name}
+		if order != nil {
+			assert.NoError(t, item.SetOrder(order))
+		}
+		return item
+	}
+	buddyItem := func(groupID, itemID uint16, name string) wire.FeedbagItem {
+		return wire.FeedbagItem{GroupID: groupID, ItemID: itemID, ClassID: wire.FeedbagClassIdBuddy, Name: name}
+	}
+	findGroup := func(items []wire.FeedbagItem, groupID uint16) wire.FeedbagItem {
+		for _, item := range items {
+			if item.ClassID == wire.FeedbagClassIdGroup && item.GroupID == groupID {
+				return item
+			}
+		}
+		t.Fatalf("group %d not found", groupID)
+		return wire.FeedbagItem{}
+	}
+
+	t.Run("rename and repair order attributes", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		f, err := NewSQLiteUserStore(testFile)
+		assert.NoError(t, err)
+
+		assert.NoError(t, f.FeedbagUpsert(context.Background(), me, []wire.FeedbagItem{
+			// root order is missing group 2
+			groupItem(0, "", 1),
+			groupItem(1, "Buddies", 10),
+			// group 2 lists a deleted buddy (99) and is missing buddy 21
+			groupItem(2, "Family", 99, 20),
+			buddyItem(1, 10, "alice"),
+			buddyItem(2, 20, "bob"),
+			buddyItem(2, 21, "carol"),
+		}))
+
+		assert.NoError(t, f.RenameFeedbagGroup(context.Background(), me, 2, "Relatives"))
+
+		items, err := f.Feedbag(context.Background(), me)
+		assert.NoError(t, err)
+		assert.Len(t, items, 6)
+
+		renamed := findGroup(items, 2)
+		assert.Equal(t, "Relatives", renamed.Name)
+		order, ok := renamed.Order()
+		assert.True(t, ok)
+		assert.Equal(t, []uint16{20, 21}, order)
+
+		root := findGroup(items, 0)
+		order, ok = root.Order()
+		assert.True(t, ok)
+		assert.Equal(t, []uint16{1, 2}, order)
+
+		// untouched group keeps its name and order
+		untouched := findGroup(items, 1)
+		assert.Equal(t, "Buddies", untouched.Name)
+		order, ok = untouched.Order()
+		assert.True(t, ok)
+		assert.Equal(t, []uint16{10}, order)
+	})
+
+	t.Run("rename group without order attributes", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		f, err := NewSQLiteUserStore(testFile)
+		assert.NoError(t, err)
+
+		assert.NoError(t, f.FeedbagUpsert(context.Background(), me, []wire.FeedbagItem{
+			groupItem(1, "Buddies"),
+		}))
+
+		assert.NoError(t, f.RenameFeedbagGroup(context.Background(), me, 1, "Friends"))
+
+		items, err := f.Feedbag(context.Background(), me)
+		assert.NoError(t, err)
+		group := findGroup(items, 1)
+		assert.Equal(t, "Friends", group.Name)
+		_, ok := group.Order()
+		assert.False(t, ok)
+	})
+
+	t.Run("group not found", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		f, err := NewSQLiteUserStore(testFile)
+		assert.NoError(t, err)
+
+		assert.NoError(t, f.FeedbagUpsert(context.Background(), me, []wire.FeedbagItem{
+			buddyItem(1, 10, "alice"),
+		}))
+
+		err = f.RenameFeedbagGroup(context.Background(), me, 1, "Friends")
+		assert.ErrorIs(t, err, ErrFeedbagGroupNotFound)
+	})
+
+	t.Run("name already used by another group", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		f, err := NewSQLiteUserStore(testFile)
+		assert.NoError(t, err)
+
+		assert.NoError(t, f.FeedbagUpsert(context.Background(), me, []wire.FeedbagItem{
+			groupItem(1, "Buddies"),
+			groupItem(2, "Family"),
+		}))
+
+		err = f.RenameFeedbagGroup(context.Background(), me, 2, "buddies")
+		assert.ErrorIs(t, err, ErrFeedbagGroupExists)
+
+		items, err := f.Feedbag(context.Background(), me)
+		assert.NoError(t, err)
+		assert.Equal(t, "Family", findGroup(items, 2).Name)
+	})
+
+	t.Run("changing the case of a group name", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		f, err := NewSQLiteUserStore(testFile)
+		assert.NoError(t, err)
+
+		assert.NoError(t, f.FeedbagUpsert(context.Background(), me, []wire.FeedbagItem{
+			groupItem(1, "buddies"),
+		}))
+
+		assert.NoError(t, f.RenameFeedbagGroup(context.Background(), me, 1, "Buddies"))
+	})
+
+	t.Run("root group can't be renamed", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		f, err := NewSQLiteUserStore(testFile)
+		assert.NoError(t, err)
+
+		assert.Error(t, f.RenameFeedbagGroup(context.Background(), me, 0, "Root"))
+	})
+}
+
 func newFeedbagItem(classID uint16, itemID uint16, name string) wire.FeedbagItem {
 	return wire.FeedbagItem{
 		ClassID: classID,
@@ -6835,3 +7665,155 @@ func pdInfoItem(itemID uint16, pdMode wire.FeedbagPDMode) wire.FeedbagItem {
 		},
 	}
 }
+
+func TestNewSQLiteUserStore_PathWithURICharacters(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "50% off? #1")
+	require.NoError(t, os.Mkdir(dir, 0o700))
+	testFile := filepath.Join(dir, "aim_test.db")
+
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	user, err := NewStubUser("alice")
+	require.NoError(t, err)
+	require.NoError(t, store.InsertUser(context.Background(), user))
+	require.NoError(t, store.pool.Close())
+
+	_, err = os.Stat(testFile)
+	assert.NoError(t, err)
+
+	report, err := MigrateDryRun(context.Background(), testFile)
+	require.NoError(t, err)
+	assert.Empty(t, report.Pending)
+}
+
+func TestNewSQLiteUserStore_DSN(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	alice, err := NewStubUser("alice")
+	require.NoError(t, err)
+
+	t.Run("private in-memory database", func(t *testing.T) {
+		store, err := NewSQLiteUserStore(":memory:")
+		require.NoError(t, err)
+		require.NoError(t, store.InsertUser(ctx, alice))
+		u, err := store.User(ctx, alice.IdentScreenName)
+		require.NoError(t, err)
+		assert.NotNil(t, u)
+
+		other, err := NewSQLiteUserStore(":memory:")
+		require.NoError(t, err)
+		u, err = other.User(ctx, alice.IdentScreenName)
+		require.NoError(t, err)
+		assert.Nil(t, u)
+	})
+
+	t.Run("shared in-memory database", func(t *testing.T) {
+		dsn := "file:TestNewSQLiteUserStore_DSN?mode=memory&cache=shared"
+		store, err := NewSQLiteUserStore(dsn)
+		require.NoError(t, err)
+		require.NoError(t, store.InsertUser(ctx, alice))
+
+		other, err := NewSQLiteUserStore(dsn)
+		require.NoError(t, err)
+		u, err := other.User(ctx, alice.IdentScreenName)
+		require.NoError(t, err)
+		assert.NotNil(t, u)
+	})
+
+	t.Run("read-only connection to a database file", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		store, err := NewSQLiteUserStore(testFile)
+		require.NoError(t, err)
+		require.NoError(t, store.InsertUser(ctx, alice))
+
+		ro, err := NewSQLiteUserStore("file:" + testFile + "?mode=ro")
+		require.NoError(t, err)
+		u, err := ro.User(ctx, alice.IdentScreenName)
+		require.NoError(t, err)
+		assert.NotNil(t, u)
+
+		bob, err := NewStubUser("bob")
+		require.NoError(t, err)
+		assert.Error(t, ro.InsertUser(ctx, bob))
+	})
+
+	t.Run("read-only database with pending migrations", func(t *testing.T) {
+		testFile := newTestDBPath(t)
+
+		latest := migrateTestFileTo(t, testFile, 0)
+		migrateTestFileTo(t, testFile, int(latest)-1)
+
+		_, err := NewSQLiteUserStore("file:" + testFile + "?mode=ro")
+		assert.ErrorIs(t, err, ErrSchemaOutdated)
+		assert.Equal(t, latest-1, migrateTestFileTo(t, testFile, -1))
+	})
+}
+
+func TestStoreDSN(t *testing.T) {
+	tests := []struct {
+		name         string
+		dbFilePath   string
+		wantDSN      string
+		wantReadOnly bool
+		wantErr      bool
+	}{
+		{
+			name:       "file path",
+			dbFilePath: "go-icq.sqlite",
+			wantDSN:    "file:go-icq.sqlite?_pragma=foreign_keys=on",
+		},
+		{
+			name:       "in-memory database",
+			dbFilePath: ":memory:",
+			wantDSN:    "file::memory:?_pragma=foreign_keys=on",
+		},
+		{
+			name:       "URI without parameters",
+			dbFilePath: "file:go-icq.sqlite",
+			wantDSN:    "file:go-icq.sqlite?_pragma=foreign_keys=on",
+		},
+		{
+			name:       "URI with parameters",
+			dbFilePath: "file:icq?mode=memory&cache=shared",
+			wantDSN:    "file:icq?mode=memory&cache=shared&_pragma=foreign_keys=on",
+		},
+		{
+			name:       "URI that sets foreign keys",
+			dbFilePath: "file:go-icq.sqlite?_pragma=foreign_keys(0)",
+			wantDSN:    "file:go-icq.sqlite?_pragma=foreign_keys(0)",
+		},
+		{
+			name:         "read-only URI",
+			dbFilePath:   "file:go-icq.sqlite?mode=ro#fragment",
+			wantDSN:      "file:go-icq.sqlite?mode=ro&_pragma=foreign_keys=on",
+			wantReadOnly: true,
+		},
+		{
+			name:         "immutable URI",
+			dbFilePath:   "file:go-icq.sqlite?immutable=1",
+			wantDSN:      "file:go-icq.sqlite?immutable=1&_pragma=foreign_keys=on",
+			wantReadOnly: true,
+		},
+		{
+			name:       "invalid URI",
+			dbFilePath: "file:go-icq.sqlite?mode=%zz",
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dsn, readOnly, err := storeDSN(tt.dbFilePath)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantDSN, dsn)
+			assert.Equal(t, tt.wantReadOnly, readOnly)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// sqlConn is the query interface shared by *sql.DB and *sql.Tx.
+type sqlConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// storeTx is a transaction started by SQLiteUserStore.begin.
+type storeTx interface {
+	sqlConn
+	Commit() error
+	Rollback() error
+}
+
+// savepoint is a transaction nested in an enclosing *sql.Tx. SQLite doesn't
+// support nested BEGIN, so it's implemented with SAVEPOINT.
+type savepoint struct {
+	*sql.Tx
+	ctx  context.Context
+	done bool
+}
+
+func (sp *savepoint) Commit() error {
+	if sp.done {
+		return sql.ErrTxDone
+	}
+	sp.done = true
+	_, err := sp.ExecContext(sp.ctx, `RELEASE store_tx`)
+	return err
+}
+
+func (sp *savepoint) Rollback() error {
+	if sp.done {
+		return sql.ErrTxDone
+	}
+	sp.done = true
+	if _, err := sp.ExecContext(sp.ctx, `ROLLBACK TO store_tx`); err != nil {
+		return err
+	}
+	_, err := sp.ExecContext(sp.ctx, `RELEASE store_tx`)
+	return err
+}
+
+// begin starts a transaction. Within WithTx it starts a savepoint of the
+// enclosing transaction instead, so that methods with their own transaction
+// still roll back their partial work on failure.
+func (us SQLiteUserStore) begin(ctx context.Context) (storeTx, error) {
+	if tx, ok := us.db.(*sql.Tx); ok {
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT store_tx`); err != nil {
+			return nil, err
+		}
+		return &savepoint{Tx: tx, ctx: ctx}, nil
+	}
+
+	tx, err := us.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// WithTx calls fn with a store whose methods all run in a single
+// transaction. The transaction is committed if fn returns nil and rolled
+// back otherwise, letting callers compose several store calls atomically.
+//
+// fn must only use the store passed to it. The database has a single
+// connection, so calls made on us block until the transaction ends.
+func (us SQLiteUserStore) WithTx(ctx context.Context, fn func(tx *SQLiteUserStore) error) (err error) {
+	var tx storeTx
+	tx, err = us.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("WithTx: begin tx: %w", err)
+	}
+	defer func() {
+		// no-op once committed; also releases the connection if fn panics
+		_ = tx.Rollback()
+	}()
+
+	txStore := us
+	if sp, ok := tx.(*savepoint); ok {
+		txStore.db = sp.Tx
+	} else {
+		txStore.db = tx
+	}
+
+	if err = fn(&txStore); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("WithTx: commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteUserStore_WithTx(t *testing.T) {
+	t.Parallel()
+
+	errAbort := errors.New("abort")
+
+	tests := []struct {
+		name      string
+		fn        func(ctx context.Context, tx *SQLiteUserStore) error
+		wantErr   error
+		wantUsers []IdentScreenName
+		noUsers   []IdentScreenName
+	}{
+		{
+			name: "commit several calls atomically",
+			fn: func(ctx context.Context, tx *SQLiteUserStore) error {
+				if err := tx.InsertUser(ctx, User{IdentScreenName: NewIdentScreenName("user1")}); err != nil {
+					return err
+				}
+				return tx.InsertUser(ctx, User{IdentScreenName: NewIdentScreenName("user2")})
+			},
+			wantUsers: []IdentScreenName{NewIdentScreenName("user1"), NewIdentScreenName("user2")},
+		},
+		{
+			name: "roll back every call when fn fails",
+			fn: func(ctx context.Context, tx *SQLiteUserStore) error {
+				if err := tx.InsertUser(ctx, User{IdentScreenName: NewIdentScreenName("user1")}); err != nil {
+					return err
+				}
+				return errAbort
+			},
+			wantErr: errAbort,
+			noUsers: []IdentScreenName{NewIdentScreenName("user1")},
+		},
+		{
+			name: "methods with their own transaction join the outer one",
+			fn: func(ctx context.Context, tx *SQLiteUserStore) error {
+				if err := tx.InsertUser(ctx, User{IdentScreenName: NewIdentScreenName("user1")}); err != nil {
+					return err
+				}
+				// fails and rolls back its savepoint without aborting the
+				// outer transaction
+				if err := tx.SetUserPassword(ctx, NewIdentScreenName("nobody"), "password"); !errors.Is(err, ErrNoUser) {
+					return err
+				}
+				return tx.SetUserPassword(ctx, NewIdentScreenName("user1"), "password")
+			},
+			wantUsers: []IdentScreenName{NewIdentScreenName("user1")},
+		},
+		{
+			name: "failed nested WithTx only discards its own work",
+			fn: func(ctx context.Context, tx *SQLiteUserStore) error {
+				if err := tx.InsertUser(ctx, User{IdentScreenName: NewIdentScreenName("user1")}); err != nil {
+					return err
+				}
+				err := tx.WithTx(ctx, func(tx *SQLiteUserStore) error {
+					if err := tx.InsertUser(ctx, User{IdentScreenName: NewIdentScreenName("user2")}); err != nil {
+						return err
+					}
+					return errAbort
+				})
+				if !errors.Is(err, errAbort) {
+					return err
+				}
+				return nil
+			},
+			wantUsers: []IdentScreenName{NewIdentScreenName("user1")},
+			noUsers:   []IdentScreenName{NewIdentScreenName("user2")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := newTestDBPath(t)
+
+			store, err := NewSQLiteUserStore(testFile)
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			err = store.WithTx(ctx, func(tx *SQLiteUserStore) error {
+				return tt.fn(ctx, tx)
+			})
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			for _, sn := range tt.wantUsers {
+				u, err := store.User(ctx, sn)
+				require.NoError(t, err)
+				assert.NotNil(t, u, "expected user %s", sn)
+			}
+			for _, sn := range tt.noUsers {
+				u, err := store.User(ctx, sn)
+				require.NoError(t, err)
+				assert.Nil(t, u, "unexpected user %s", sn)
+			}
+		})
+	}
+}
@@ -523,6 +523,60 @@ func TestAge(t *testing.T) {
 			},
 			expectedAge: 0,
 		},
+		{
+			name: "Leap day birthday, day before March 1 in non-leap year",
+			user: User{
+				ICQMoreInfo: ICQMoreInfo{
+					BirthYear:  2000,
+					BirthMonth: 2,
+					BirthDay:   29,
+				},
+			},
+			timeNow: func() time.Time {
+				return time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC)
+			},
+			expectedAge: 24,
+		},
+		{
+			name: "Leap day birthday, March 1 in non-leap year",
+			user: User{
+				ICQMoreInfo: ICQMoreInfo{
+					BirthYear:  2000,
+					BirthMonth: 2,
+					BirthDay:   29,
+				},
+			},
+			timeNow: func() time.Time {
+				return time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+			},
+			expectedAge: 25,
+		},
+		{
+			name: "Birthday not yet passed in a leap year",
+			user: User{
+				ICQMoreInfo: ICQMoreInfo{
+					BirthYear:  1990,
+					BirthMonth: 3,
+					BirthDay:   1,
+				},
+			},
+			timeNow: func() time.Time {
+				return time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC)
+			},
+			expectedAge: 32,
+		},
+		{
+			name: "Birth year in the future",
+			user: User{
+				ICQMoreInfo: ICQMoreInfo{
+					BirthYear: 2030,
+				},
+			},
+			timeNow: func() time.Time {
+				return time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+			},
+			expectedAge: 0,
+		},
 	}
 
 	for _, tt := range tests {
@@ -534,3 +588,63 @@ func TestAge(t *testing.T) {
 		})
 	}
 }
+
+func TestAutoResponseMsgType(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   uint32
+		want     uint8
+		wantFlag bool
+	}{
+		{name: "available", status: wire.OServiceUserStatusAvailable},
+		{name: "invisible", status: wire.OServiceUserStatusInvisible},
+		{name: "away", status: wire.OServiceUserStatusAway, want: wire.ICBMMsgTypeAutoAway, wantFlag: true},
+		{name: "n/a", status: 0x05, want: wire.ICBMMsgTypeAutoNA, wantFlag: true},
+		{name: "occupied", status: 0x11, want: wire.ICBMMsgTypeAutoBusy, wantFlag: true},
+		{name: "dnd", status: 0x13, want: wire.ICBMMsgTypeAutoDND, wantFlag: true},
+		{name: "free for chat", status: wire.OServiceUserStatusChat, want: wire.ICBMMsgTypeAutoFFC, wantFlag: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, ok := AutoResponseMsgType(tt.status)
+			assert.Equal(t, tt.wantFlag, ok)
+			assert.Equal(t, tt.want, have)
+		})
+	}
+}
+
+func TestICQMoreInfo_ValidateBirthDate(t *testing.T) {
+	now := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		info    ICQMoreInfo
+		wantErr bool
+	}{
+		{name: "unset", info: ICQMoreInfo{}},
+		{name: "year only", info: ICQMoreInfo{BirthYear: 1990}},
+		{name: "month and day only", info: ICQMoreInfo{BirthMonth: 2, BirthDay: 29}},
+		{name: "full date", info: ICQMoreInfo{BirthYear: 1990, BirthMonth: 8, BirthDay: 15}},
+		{name: "leap day in leap year", info: ICQMoreInfo{BirthYear: 2000, BirthMonth: 2, BirthDay: 29}},
+		{name: "today", info: ICQMoreInfo{BirthYear: 2024, BirthMonth: 8, BirthDay: 1}},
+		{name: "leap day in non-leap year", info: ICQMoreInfo{BirthYear: 1999, BirthMonth: 2, BirthDay: 29}, wantErr: true},
+		{name: "April 31", info: ICQMoreInfo{BirthYear: 1990, BirthMonth: 4, BirthDay: 31}, wantErr: true},
+		{name: "month 13", info: ICQMoreInfo{BirthYear: 1990, BirthMonth: 13, BirthDay: 1}, wantErr: true},
+		{name: "month without day", info: ICQMoreInfo{BirthYear: 1990, BirthMonth: 8}, wantErr: true},
+		{name: "day without month", info: ICQMoreInfo{BirthDay: 8}, wantErr: true},
+		{name: "year too early", info: ICQMoreInfo{BirthYear: 1850}, wantErr: true},
+		{name: "year in the future", info: ICQMoreInfo{BirthYear: 2025}, wantErr: true},
+		{name: "date in the future", info: ICQMoreInfo{BirthYear: 2024, BirthMonth: 8, BirthDay: 2}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.info.ValidateBirthDate(now)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrBirthDateInvalid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
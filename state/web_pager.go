@@ -0,0 +1,214 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+const (
+	// ICQSystemUIN is the UIN that web pager and email express messages
+	// appear to come from.
+	ICQSystemUIN uint32 = 10
+	// WebPagerMaxTextLen is the longest message text accepted by the web
+	// pager.
+	WebPagerMaxTextLen = 450
+	// WebPagerMaxFormSize bounds the size of a web pager form submission.
+	WebPagerMaxFormSize = 4096
+)
+
+// icqSystemScreenName is the screen name of ICQSystemUIN. It has no user
+// record.
+var icqSystemScreenName = NewIdentScreenName(strconv.Itoa(int(ICQSystemUIN)))
+
+// ErrWebPagerInvalid indicates that a web pager form is missing a field or
+// has a field that fails validation.
+var ErrWebPagerInvalid = errors.New("invalid web pager message")
+
+// WebPagerUserStore finds web pager recipients and stores messages for
+// recipients who are offline.
+type WebPagerUserStore interface {
+	FindByUIN(ctx context.Context, UIN uint32) (User, error)
+	SaveMessage(ctx context.Context, offlineMessage OfflineMessage) (int, error)
+}
+
+// WebPagerRelayer delivers messages to signed-on users.
+type WebPagerRelayer interface {
+	DeliverToScreenName(ctx context.Context, screenName IdentScreenName, msg wire.SNACMessage, received time.Time) DeliveryState
+}
+
+// WebPager delivers web pager (ICBMMsgTypeWWP) and email express
+// (ICBMMsgTypeEExpress) messages submitted by people who don't have an ICQ
+// account. Messages are relayed on ICBM channel 4 from ICQSystemUIN to the
+// recipient if they are signed on and stored as offline messages otherwise.
+type WebPager struct {
+	store    WebPagerUserStore
+	sessions WebPagerRelayer
+	logger   *slog.Logger
+	nowFn    func() time.Time
+}
+
+// NewWebPager creates a new instance of WebPager.
+func NewWebPager(store WebPagerUserStore, sessions WebPagerRelayer, logger *slog.Logger) *WebPager {
+	return &WebPager{
+		store:    store,
+		sessions: sessions,
+		logger:   logger,
+		nowFn:    time.Now,
+	}
+}
+
+// Send delivers msg to the ICQ user identified by uin. msgType must be
+// wire.ICBMMsgTypeWWP or wire.ICBMMsgTypeEExpress. It returns ErrNoUser if
+// no account has that UIN and ErrOfflineInboxFull if the recipient is
+// offline and can't store any more messages.
+func (p *WebPager) Send(ctx context.Context, uin uint32, msgType uint8, msg wire.ICQWebPagerMessage) (DeliveryState, error) {
+	received := p.nowFn().UTC()
+	if err := validateWebPagerMessage(msgType, msg); err != nil {
+		return DeliveryOffline, err
+	}
+
+	user, err := p.store.FindByUIN(ctx, uin)
+	if err != nil {
+		return DeliveryOffline, err
+	}
+
+	ch4, err := wire.MarshalBEAppend(wire.ICBMCh4Message{
+		UIN:         ICQSystemUIN,
+		MessageType: msgType,
+		Message:     wire.MarshalICQWebPager(msg),
+	}, nil)
+	if err != nil {
+		return DeliveryOffline, fmt.Errorf("unable to marshal ICBM channel 4 message: %w", err)
+	}
+
+	cookie := rand.Uint64()
+
+	state := p.sessions.DeliverToScreenName(ctx, user.IdentScreenName, wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.ICBM,
+			SubGroup:  wire.ICBMChannelMsgToClient,
+		},
+		Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+			Cookie:    cookie,
+			ChannelID: wire.ICBMChannelICQ,
+			TLVUserInfo: wire.TLVUserInfo{
+				ScreenName: wire.ScreenName(icqSystemScreenName.String()),
+			},
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ICBMTLVData, ch4),
+				},
+			},
+		},
+	}, received)
+	if state.Delivered() {
+		return state, nil
+	}
+
+	offlineMsg := OfflineMessage{
+		Sent:      received,
+		Sender:    icqSystemScreenName,
+		Recipient: user.IdentScreenName,
+		Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+			Cookie:     cookie,
+			ChannelID:  wire.ICBMChannelICQ,
+			ScreenName: user.DisplayScreenName.Wire(),
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ICBMTLVData, ch4),
+					wire.NewTLVBE(wire.ICBMTLVStore, []byte{}),
+				},
+			},
+		},
+	}
+	if _, err := p.store.SaveMessage(ctx, offlineMsg); err != nil {
+		return state, fmt.Errorf("unable to save offline web pager message: %w", err)
+	}
+
+	return DeliveryOffline, nil
+}
+
+func validateWebPagerMessage(msgType uint8, msg wire.ICQWebPagerMessage) error {
+	if msgType != wire.ICBMMsgTypeWWP && msgType != wire.ICBMMsgTypeEExpress {
+		return fmt.Errorf("%w: unsupported message type %d", ErrWebPagerInvalid, msgType)
+	}
+	if msg.Text == "" {
+		return fmt.Errorf("%w: message text is empty", ErrWebPagerInvalid)
+	}
+	if len(msg.Text) > WebPagerMaxTextLen {
+		return fmt.Errorf("%w: message text longer than %d bytes", ErrWebPagerInvalid, WebPagerMaxTextLen)
+	}
+	if msg.Email != "" {
+		addr, err := mail.ParseAddress(msg.Email)
+		if err != nil || addr.Address != msg.Email {
+			return fmt.Errorf("%w: bad email address %q", ErrWebPagerInvalid, msg.Email)
+		}
+	}
+	return nil
+}
+
+// Handler serves the web pager form. It accepts a POST with the form
+// fields "to" (recipient UIN), "from" (sender name), "fromemail" (sender
+// email address) and "body" (message text). Setting "type" to "eexpress"
+// sends an email express message instead of a web pager message. It
+// responds 200 if the message was delivered or stored, 400 if the form is
+// invalid, 404 if the UIN doesn't exist and 503 if the recipient's offline
+// inbox is full.
+func (p *WebPager) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, WebPagerMaxFormSize)
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Invalid form.", http.StatusBadRequest)
+			return
+		}
+
+		uin, err := strconv.ParseUint(r.PostForm.Get("to"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid recipient UIN.", http.StatusBadRequest)
+			return
+		}
+
+		msgType := wire.ICBMMsgTypeWWP
+		if r.PostForm.Get("type") == "eexpress" {
+			msgType = wire.ICBMMsgTypeEExpress
+		}
+
+		state, err := p.Send(r.Context(), uint32(uin), msgType, wire.ICQWebPagerMessage{
+			Nick:  r.PostForm.Get("from"),
+			Email: r.PostForm.Get("fromemail"),
+			Text:  r.PostForm.Get("body"),
+		})
+		switch {
+		case errors.Is(err, ErrWebPagerInvalid):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrNoUser):
+			http.Error(w, "No such ICQ user.", http.StatusNotFound)
+		case errors.Is(err, ErrOfflineInboxFull):
+			http.Error(w, "The recipient's message box is full.", http.StatusServiceUnavailable)
+		case err != nil:
+			p.logger.ErrorContext(r.Context(), "unable to send web pager message", "err", err)
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		default:
+			p.logger.InfoContext(r.Context(), "web pager message sent", "uin", uin, "state", state)
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = fmt.Fprintln(w, "Your message has been sent.")
+		}
+	}
+}
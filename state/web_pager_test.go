@@ -0,0 +1,156 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// webPagerText extracts the web pager message carried by an ICBM channel 4
+// TLV list.
+func webPagerText(t *testing.T, tlvs wire.TLVRestBlock) (wire.ICBMCh4Message, wire.ICQWebPagerMessage) {
+	b, ok := tlvs.Bytes(wire.ICBMTLVData)
+	require.True(t, ok)
+	ch4 := wire.ICBMCh4Message{}
+	require.NoError(t, wire.UnmarshalBEStrict(&ch4, b))
+	msg, err := wire.UnmarshalICQWebPager(ch4.Message)
+	require.NoError(t, err)
+	return ch4, msg
+}
+
+func TestWebPager_Send(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	for _, sn := range []string{"100001", "100002"} {
+		require.NoError(t, store.InsertUser(context.Background(), User{IdentScreenName: NewIdentScreenName(sn)}))
+	}
+
+	sm := NewInMemorySessionManager(slog.Default())
+	online, err := sm.AddSession(context.Background(), "100001")
+	require.NoError(t, err)
+	online.SetSignonComplete()
+
+	pager := NewWebPager(store, sm, slog.Default())
+	msg := wire.ICQWebPagerMessage{Nick: "Bob", Email: "bob@example.com", Text: "hello"}
+
+	t.Run("online recipient", func(t *testing.T) {
+		state, err := pager.Send(context.Background(), 100001, wire.ICBMMsgTypeWWP, msg)
+		require.NoError(t, err)
+		assert.Equal(t, DeliveryEnqueued, state)
+
+		snac := <-online.ReceiveMessage()
+		body, ok := snac.Body.(wire.SNAC_0x04_0x07_ICBMChannelMsgToClient)
+		require.True(t, ok)
+		assert.Equal(t, wire.ICBMChannelICQ, body.ChannelID)
+		assert.Equal(t, wire.ScreenName("10"), body.ScreenName)
+
+		ch4, have := webPagerText(t, body.TLVRestBlock)
+		assert.Equal(t, ICQSystemUIN, ch4.UIN)
+		assert.Equal(t, wire.ICBMMsgTypeWWP, ch4.MessageType)
+		assert.Equal(t, msg, have)
+	})
+
+	t.Run("offline recipient", func(t *testing.T) {
+		state, err := pager.Send(context.Background(), 100002, wire.ICBMMsgTypeEExpress, msg)
+		require.NoError(t, err)
+		assert.Equal(t, DeliveryOffline, state)
+
+		msgs, err := store.RetrieveMessages(context.Background(), NewIdentScreenName("100002"))
+		require.NoError(t, err)
+		require.Len(t, msgs, 1)
+		assert.Equal(t, NewIdentScreenName("10"), msgs[0].Sender)
+		assert.True(t, msgs[0].Message.HasTag(wire.ICBMTLVStore))
+
+		ch4, have := webPagerText(t, msgs[0].Message.TLVRestBlock)
+		assert.Equal(t, wire.ICBMMsgTypeEExpress, ch4.MessageType)
+		assert.Equal(t, msg, have)
+	})
+
+	t.Run("unknown UIN", func(t *testing.T) {
+		_, err := pager.Send(context.Background(), 100003, wire.ICBMMsgTypeWWP, msg)
+		assert.ErrorIs(t, err, ErrNoUser)
+	})
+
+	t.Run("invalid message", func(t *testing.T) {
+		_, err := pager.Send(context.Background(), 100001, wire.ICBMMsgTypeWWP, wire.ICQWebPagerMessage{Nick: "Bob"})
+		assert.ErrorIs(t, err, ErrWebPagerInvalid)
+
+		_, err = pager.Send(context.Background(), 100001, wire.ICBMMsgTypeWWP, wire.ICQWebPagerMessage{Email: "not an address", Text: "hi"})
+		assert.ErrorIs(t, err, ErrWebPagerInvalid)
+
+		_, err = pager.Send(context.Background(), 100001, wire.ICBMMsgTypePlain, msg)
+		assert.ErrorIs(t, err, ErrWebPagerInvalid)
+	})
+}
+
+func TestWebPager_Handler(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	store, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	require.NoError(t, store.InsertUser(context.Background(), User{IdentScreenName: NewIdentScreenName("100001")}))
+
+	pager := NewWebPager(store, NewInMemorySessionManager(slog.Default()), slog.Default())
+
+	tests := []struct {
+		name     string
+		method   string
+		form     url.Values
+		wantCode int
+	}{
+		{
+			name:     "message stored",
+			method:   http.MethodPost,
+			form:     url.Values{"to": {"100001"}, "from": {"Bob"}, "fromemail": {"bob@example.com"}, "body": {"hello"}},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "unknown UIN",
+			method:   http.MethodPost,
+			form:     url.Values{"to": {"100002"}, "body": {"hello"}},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "bad UIN",
+			method:   http.MethodPost,
+			form:     url.Values{"to": {"bob"}, "body": {"hello"}},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "empty body",
+			method:   http.MethodPost,
+			form:     url.Values{"to": {"100001"}},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "wrong method",
+			method:   http.MethodGet,
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/wwp", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+			pager.Handler()(rec, req)
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+}
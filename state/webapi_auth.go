@@ -8,6 +8,13 @@ import (
 	"time"
 )
 
+var (
+	// ErrWebAPITokenInvalid indicates that a Web API token doesn't exist.
+	ErrWebAPITokenInvalid = errors.New("invalid token")
+	// ErrWebAPITokenExpired indicates that a Web API token has expired.
+	ErrWebAPITokenExpired = errors.New("token expired")
+)
+
 // AuthenticateUser verifies username and password.
 // This implementation uses the existing user store for authentication.
 func (u *SQLiteUserStore) AuthenticateUser(ctx context.Context, username, password string) (*User, error) {
@@ -17,7 +24,10 @@ func (u *SQLiteUserStore) AuthenticateUser(ctx context.Context, username, passwo
 	// try to find the user
 	user, err := u.User(ctx, identSN)
 	if err != nil {
-		return nil, fmt.Errorf("user not found: %w", err)
+		return nil, fmt.Errorf("AuthenticateUser: %w", err)
+	}
+	if user == nil {
+		return nil, ErrNoUser
 	}
 
 	// in development mode with DISABLE_AUTH=true,
@@ -60,7 +70,7 @@ func (s *WebAPITokenStore) ValidateToken(ctx context.Context, token string) (Ide
 	`
 	if err := s.store.db.QueryRowContext(ctx, query, token).Scan(&screenNameStr, &expiresAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return NewIdentScreenName(""), errors.New("invalid token")
+			return NewIdentScreenName(""), ErrWebAPITokenInvalid
 		} else {
 			return NewIdentScreenName(""), fmt.Errorf("failed to validate token: %w", err)
 		}
@@ -70,7 +80,7 @@ func (s *WebAPITokenStore) ValidateToken(ctx context.Context, token string) (Ide
 	if time.Now().After(expiresAt) {
 		// clean up expired token
 		s.DeleteToken(ctx, token)
-		return NewIdentScreenName(""), errors.New("token expired")
+		return NewIdentScreenName(""), ErrWebAPITokenExpired
 	} else {
 		return NewIdentScreenName(screenNameStr), nil
 	}
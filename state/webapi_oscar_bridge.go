@@ -9,6 +9,10 @@ import (
 	"time"
 )
 
+// ErrBridgeSessionNotFound indicates that a Web API session has no OSCAR
+// bridge session.
+var ErrBridgeSessionNotFound = errors.New("bridge session not found")
+
 // OSCARBridgeSession represents a bridge between WebAPI and OSCAR sessions.
 type OSCARBridgeSession struct {
 	WebSessionID  string    // WebAPI session identifier
@@ -73,7 +77,7 @@ func (s *OSCARBridgeStore) DeleteBridgeSession(ctx context.Context, webSessionID
 	if rowsAffected, err := result.RowsAffected(); err != nil {
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	} else if rowsAffected == 0 {
-		return fmt.Errorf("bridge session not found")
+		return ErrBridgeSessionNotFound
 	}
 
 	return nil
@@ -152,7 +156,7 @@ func (s *OSCARBridgeStore) GetBridgeSession(ctx context.Context, webSessionID st
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("bridge session not found")
+			return nil, ErrBridgeSessionNotFound
 		}
 		return nil, fmt.Errorf("failed to get bridge session: %w", err)
 	}
@@ -303,7 +307,7 @@ func (s *OSCARBridgeStore) ValidateOSCARCookie(ctx context.Context, cookie []byt
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("cookie not found")
+			return nil, fmt.Errorf("%w: no session for cookie", ErrBridgeSessionNotFound)
 		}
 		return nil, fmt.Errorf("failed to validate cookie: %w", err)
 	}
@@ -43,11 +43,11 @@ func (f SQLiteUserStore) DeleteAPIKey(ctx context.Context, devID string) error {
 	`
 	result, err := f.db.ExecContext(ctx, q, devID)
 	if err != nil {
-		return err
+		return fmt.Errorf("DeleteAPIKey: %w", err)
 	}
 
 	if rowsAffected, err := result.RowsAffected(); err != nil {
-		return err
+		return fmt.Errorf("DeleteAPIKey: %w", err)
 	} else if rowsAffected == 0 {
 		return ErrNoAPIKey
 	}
@@ -95,11 +95,11 @@ func (f SQLiteUserStore) CreateAPIKey(ctx context.Context, key WebAPIKey) error
 		string(capabilitiesJSON),
 	)
 	if err != nil {
-		return err
+		return fmt.Errorf("CreateAPIKey: %w", err)
 	}
 
 	if rowsAffected, err := result.RowsAffected(); err != nil {
-		return err
+		return fmt.Errorf("CreateAPIKey: %w", err)
 	} else if rowsAffected == 0 {
 		return ErrDupAPIKey
 	}
@@ -132,7 +132,7 @@ func (f *SQLiteUserStore) GetAPIKeyByDevKey(ctx context.Context, devKey string)
 		if err == sql.ErrNoRows {
 			err = ErrNoAPIKey
 		}
-		return nil, err
+		return nil, fmt.Errorf("GetAPIKeyByDevKey: %w", err)
 	}
 
 	key.CreatedAt = time.Unix(createdAt.Int64, 0)
@@ -178,7 +178,7 @@ func (f SQLiteUserStore) GetAPIKeyByDevID(ctx context.Context, devID string) (*W
 		if err == sql.ErrNoRows {
 			err = ErrNoAPIKey
 		}
-		return nil, err
+		return nil, fmt.Errorf("GetAPIKeyByDevID: %w", err)
 	}
 
 	key.CreatedAt = time.Unix(createdAt.Int64, 0)
@@ -207,7 +207,7 @@ func (f SQLiteUserStore) ListAPIKeys(ctx context.Context) ([]WebAPIKey, error) {
 	`
 	rows, err := f.db.QueryContext(ctx, q)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("ListAPIKeys: %w", err)
 	}
 	defer rows.Close()
 
@@ -228,7 +228,7 @@ func (f SQLiteUserStore) ListAPIKeys(ctx context.Context) ([]WebAPIKey, error) {
 			&capabilitiesJSON,
 		)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("ListAPIKeys: %w", err)
 		}
 
 		key.CreatedAt = time.Unix(createdAt.Int64, 0)
@@ -249,7 +249,7 @@ func (f SQLiteUserStore) ListAPIKeys(ctx context.Context) ([]WebAPIKey, error) {
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("ListAPIKeys: %w", err)
 	}
 
 	return keys, nil
@@ -307,10 +307,10 @@ func (f SQLiteUserStore) UpdateAPIKey(ctx context.Context, devID string, updates
 		WHERE dev_id = ?
 	`, joinStrings(setClauses, ", "))
 	if result, err := f.db.ExecContext(ctx, q, args...); err != nil {
-		return err
+		return fmt.Errorf("UpdateAPIKey: %w", err)
 	} else {
 		if rowsAffected, err := result.RowsAffected(); err != nil {
-			return err
+			return fmt.Errorf("UpdateAPIKey: %w", err)
 		} else if rowsAffected == 0 {
 			return ErrNoAPIKey
 		}
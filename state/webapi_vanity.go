@@ -11,6 +11,17 @@ import (
 	"time"
 )
 
+var (
+	// ErrVanityURLInvalid indicates that a vanity URL has the wrong length
+	// or characters.
+	ErrVanityURLInvalid = errors.New("invalid vanity URL")
+	// ErrVanityURLTaken indicates that a vanity URL is reserved or belongs
+	// to another user.
+	ErrVanityURLTaken = errors.New("vanity URL is not available")
+	// ErrVanityURLNotFound indicates that no user has the vanity URL.
+	ErrVanityURLNotFound = errors.New("vanity URL not found")
+)
+
 // VanityInfo represents the response for vanity URL lookups.
 type VanityInfo struct {
 	Bio         string                 `json:"bio,omitempty"`
@@ -81,7 +92,7 @@ func (m *VanityURLManager) CreateOrUpdateVanityURL(ctx context.Context, screenNa
 
 	// check if URL is reserved
 	if m.isReserved(vanityURL) {
-		return fmt.Errorf("vanity URL '%s' is reserved", vanityURL)
+		return fmt.Errorf("%w: '%s' is reserved", ErrVanityURLTaken, vanityURL)
 	}
 
 	// extract optional fields from info
@@ -126,7 +137,7 @@ func (m *VanityURLManager) CreateOrUpdateVanityURL(ctx context.Context, screenNa
 	)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE") {
-			return fmt.Errorf("vanity URL '%s' is already taken", vanityURL)
+			return fmt.Errorf("%w: '%s' is already taken", ErrVanityURLTaken, vanityURL)
 		}
 		return fmt.Errorf("failed to create vanity URL: %w", err)
 	}
@@ -157,8 +168,8 @@ func (m *VanityURLManager) GetVanityInfo(ctx context.Context, vanityURL string)
 		&v.Website, &createdAt, &updatedAt, &v.IsActive, &v.ClickCount, &lastAccessed,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("vanity URL not found: %s", vanityURL)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", ErrVanityURLNotFound, vanityURL)
 		}
 		return nil, fmt.Errorf("failed to get vanity info: %w", err)
 	}
@@ -356,17 +367,17 @@ func (m *VanityURLManager) validateVanityURL(vanityURL string) error {
 	vanityURL = strings.ToLower(strings.TrimSpace(vanityURL))
 	// check length
 	if len(vanityURL) < 3 || len(vanityURL) > 30 {
-		return errors.New("vanity URL must be between 3 and 30 characters")
+		return fmt.Errorf("%w: must be between 3 and 30 characters", ErrVanityURLInvalid)
 	}
 
 	// check format (alphanumeric, hyphens, underscores only)
 	if validFormat := regexp.MustCompile(`^[a-z0-9_-]+$`); !validFormat.MatchString(vanityURL) {
-		return errors.New("vanity URL can only contain letters, numbers, hyphens, and underscores")
+		return fmt.Errorf("%w: can only contain letters, numbers, hyphens, and underscores", ErrVanityURLInvalid)
 	}
 
 	// can't start or end with special characters
 	if strings.HasPrefix(vanityURL, "-") || strings.HasPrefix(vanityURL, "_") || strings.HasSuffix(vanityURL, "-") || strings.HasSuffix(vanityURL, "_") {
-		return errors.New("vanity URL cannot start or end with hyphens or underscores")
+		return fmt.Errorf("%w: cannot start or end with hyphens or underscores", ErrVanityURLInvalid)
 	}
 
 	return nil
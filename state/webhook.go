@@ -0,0 +1,139 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// WebhookQueueSize is the number of events WebhookDispatcher buffers
+	// before it starts dropping them.
+	WebhookQueueSize = 1000
+	// WebhookTimeout bounds each POST made by WebhookDispatcher.
+	WebhookTimeout = 10 * time.Second
+	// WebhookSignatureHeader carries the hex HMAC-SHA256 of the request
+	// body, keyed with the webhook secret and prefixed with "sha256=".
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// WebhookEvent is an event posted to the webhook endpoint as a JSON object.
+// Schema names the layout of Data, such as ModerationEventSchema, so that
+// receivers can route events without knowing every Type.
+type WebhookEvent struct {
+	Schema string    `json:"schema"`
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+	Data   any       `json:"data"`
+}
+
+// WebhookSink accepts events for delivery to a webhook endpoint.
+type WebhookSink interface {
+	Dispatch(event WebhookEvent) bool
+}
+
+// WebhookDispatcher posts events to an external HTTP endpoint, such as a
+// moderation dashboard. Events are queued and posted one at a time by Run,
+// so that a slow endpoint never holds up the server. If secret is set, each
+// request is signed in WebhookSignatureHeader. Events that don't fit in the
+// queue and events the endpoint fails to accept are dropped and logged.
+type WebhookDispatcher struct {
+	url     string
+	secret  []byte
+	client  *http.Client
+	queue   chan WebhookEvent
+	dropped atomic.Int64
+	logger  *slog.Logger
+}
+
+// NewWebhookDispatcher creates a new instance of WebhookDispatcher that
+// posts to url.
+func NewWebhookDispatcher(url, secret string, logger *slog.Logger) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: WebhookTimeout},
+		queue:  make(chan WebhookEvent, WebhookQueueSize),
+		logger: logger,
+	}
+}
+
+// Dispatch queues event for delivery. It never blocks, and reports false
+// if the queue is full and the event was dropped.
+func (d *WebhookDispatcher) Dispatch(event WebhookEvent) bool {
+	select {
+	case d.queue <- event:
+		return true
+	default:
+		d.dropped.Add(1)
+		d.logger.Warn("webhook queue full, dropped event", "schema", event.Schema, "type", event.Type)
+		return false
+	}
+}
+
+// Dropped returns the number of events dropped because the queue was full.
+func (d *WebhookDispatcher) Dropped() int64 {
+	return d.dropped.Load()
+}
+
+// Run posts queued events until ctx is done.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-d.queue:
+			if err := d.post(ctx, event); err != nil {
+				d.logger.ErrorContext(ctx, "unable to post webhook event", "schema", event.Schema,
+					"type", event.Type, "err", err)
+			}
+		}
+	}
+}
+
+func (d *WebhookDispatcher) post(ctx context.Context, event WebhookEvent) error {
+	return postWebhook(ctx, d.client, d.url, d.secret, event)
+}
+
+// postWebhook posts event as JSON to url, signed with secret if it's set.
+func postWebhook(ctx context.Context, client *http.Client, url string, secret []byte, event WebhookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(secret) > 0 {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+WebhookSignature(secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// WebhookSignature returns the hex HMAC-SHA256 of body keyed with secret,
+// as sent in WebhookSignatureHeader.
+func WebhookSignature(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
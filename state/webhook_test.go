@@ -0,0 +1,66 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDispatcher(t *testing.T) {
+	type request struct {
+		signature string
+		body      []byte
+	}
+	requests := make(chan request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{signature: r.Header.Get(WebhookSignatureHeader), body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	d := NewWebhookDispatcher(srv.URL, "s3cret", slog.Default())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.True(t, d.Dispatch(WebhookEvent{
+		Schema: ModerationEventSchema,
+		Type:   ModerationFloodKick,
+		Time:   at,
+		Data:   ModerationEvent{ScreenName: "chattingchuck"},
+	}))
+
+	select {
+	case req := <-requests:
+		assert.Equal(t, "sha256="+WebhookSignature([]byte("s3cret"), req.body), req.signature)
+		var have map[string]any
+		require.NoError(t, json.Unmarshal(req.body, &have))
+		assert.Equal(t, map[string]any{
+			"schema": "moderation.v1",
+			"type":   "flood_kick",
+			"time":   "2024-05-01T12:00:00Z",
+			"data":   map[string]any{"screen_name": "chattingchuck"},
+		}, have)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not posted")
+	}
+}
+
+func TestWebhookDispatcher_QueueFull(t *testing.T) {
+	d := NewWebhookDispatcher("http://127.0.0.1:0", "", slog.Default())
+	for range WebhookQueueSize {
+		require.True(t, d.Dispatch(WebhookEvent{}))
+	}
+	assert.False(t, d.Dispatch(WebhookEvent{}))
+	assert.Equal(t, int64(1), d.Dropped())
+}
@@ -0,0 +1,193 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/pchchv/go-icq/wire"
+	"modernc.org/sqlite"
+	lib "modernc.org/sqlite/lib"
+)
+
+// SystemMessageScreenName is the screen name that system messages, such as
+// the welcome message, appear to come from. It has no user record.
+const SystemMessageScreenName = "AOL System Msg"
+
+// MarkWelcomed records that the user was sent the welcome message and
+// reports whether this is the first time. It returns ErrNoUser if the user
+// doesn't exist.
+func (us SQLiteUserStore) MarkWelcomed(ctx context.Context, screenName IdentScreenName, now time.Time) (bool, error) {
+	q := `INSERT OR IGNORE INTO welcomeSent (screenName, sentAt) VALUES (?, ?)`
+	res, err := us.db.ExecContext(ctx, q, screenName.String(), now.Unix())
+	if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == lib.SQLITE_CONSTRAINT_FOREIGNKEY {
+		return false, ErrNoUser
+	} else if err != nil {
+		return false, fmt.Errorf("MarkWelcomed: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("MarkWelcomed: %w", err)
+	}
+	return n > 0, nil
+}
+
+// WelcomeStore records which users were sent the welcome message.
+type WelcomeStore interface {
+	MarkWelcomed(ctx context.Context, screenName IdentScreenName, now time.Time) (bool, error)
+}
+
+// welcomeData is the data that welcome templates are executed with.
+type welcomeData struct {
+	// ScreenName is the user's display screen name.
+	ScreenName string
+}
+
+// Welcomer sends a welcome message to users as they sign on, as an IM from
+// SystemMessageScreenName, a popup window, or both. Messages are
+// html/template templates executed with the user's display screen name as
+// {{.ScreenName}}.
+type Welcomer struct {
+	store       WelcomeStore
+	im          *template.Template
+	popup       *template.Template
+	popupURL    string
+	everySignon bool
+	logger      *slog.Logger
+	nowFn       func() time.Time
+}
+
+// NewWelcomer creates a new instance of Welcomer. im and popup are the
+// templates of the IM and popup text; either may be empty to skip that
+// part. popupURL is an optional page shown in the popup. If everySignon is
+// false, users are only welcomed on their first sign-on, which is tracked
+// in store.
+func NewWelcomer(store WelcomeStore, im, popup, popupURL string, everySignon bool, logger *slog.Logger) (*Welcomer, error) {
+	w := &Welcomer{
+		store:       store,
+		popupURL:    popupURL,
+		everySignon: everySignon,
+		logger:      logger,
+		nowFn:       time.Now,
+	}
+	var err error
+	if im != "" {
+		if w.im, err = template.New("im").Parse(im); err != nil {
+			return nil, fmt.Errorf("invalid welcome IM template: %w", err)
+		}
+	}
+	if popup != "" {
+		if w.popup, err = template.New("popup").Parse(popup); err != nil {
+			return nil, fmt.Errorf("invalid welcome popup template: %w", err)
+		}
+	}
+	return w, nil
+}
+
+// SignOn sends sess the welcome message if it is due. It should be called
+// once the session's sign-on is complete. Guests are welcomed every time,
+// since each guest session is a new user.
+func (w *Welcomer) SignOn(ctx context.Context, sess *Session) {
+	if !w.everySignon && !sess.Guest() {
+		first, err := w.store.MarkWelcomed(ctx, sess.IdentScreenName(), w.nowFn())
+		if err != nil {
+			w.logger.ErrorContext(ctx, "unable to record welcome message", "err", err)
+			return
+		}
+		if !first {
+			return
+		}
+	}
+
+	data := welcomeData{ScreenName: sess.DisplayScreenName().String()}
+
+	if w.im != nil {
+		msg, err := w.imMessage(data)
+		if err != nil {
+			w.logger.ErrorContext(ctx, "unable to build welcome IM", "err", err)
+		} else if sess.RelayMessage(msg) != SessSendOK {
+			w.logger.DebugContext(ctx, "unable to send welcome IM", "screen_name", sess.IdentScreenName())
+		}
+	}
+
+	if w.popup != nil {
+		msg, err := w.popupMessage(data)
+		if err != nil {
+			w.logger.ErrorContext(ctx, "unable to build welcome popup", "err", err)
+		} else if sess.RelayMessage(msg) != SessSendOK {
+			w.logger.DebugContext(ctx, "unable to send welcome popup", "screen_name", sess.IdentScreenName())
+		}
+	}
+}
+
+// imMessage builds the welcome IM from SystemMessageScreenName.
+func (w *Welcomer) imMessage(data welcomeData) (wire.SNACMessage, error) {
+	text := &strings.Builder{}
+	if err := w.im.Execute(text, data); err != nil {
+		return wire.SNACMessage{}, err
+	}
+	return newServerIM(SystemMessageScreenName, text.String())
+}
+
+// newServerIM builds an IM that the server sends on behalf of a screen name
+// that has no session, such as SystemMessageScreenName or a bot.
+func newServerIM(from string, text string) (wire.SNACMessage, error) {
+	frags, err := wire.ICBMFragmentList(text)
+	if err != nil {
+		return wire.SNACMessage{}, err
+	}
+
+	return wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.ICBM,
+			SubGroup:  wire.ICBMChannelMsgToClient,
+		},
+		Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+			Cookie:    rand.Uint64(),
+			ChannelID: wire.ICBMChannelIM,
+			TLVUserInfo: wire.TLVUserInfo{
+				ScreenName: wire.ScreenName(from),
+			},
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ICBMTLVAOLIMData, frags),
+				},
+			},
+		},
+	}, nil
+}
+
+// popupMessage builds the welcome SNAC(0x08,0x02) PopupDisplay.
+func (w *Welcomer) popupMessage(data welcomeData) (wire.SNACMessage, error) {
+	text := &strings.Builder{}
+	if err := w.popup.Execute(text, data); err != nil {
+		return wire.SNACMessage{}, err
+	}
+
+	return newServerPopup(text.String(), w.popupURL), nil
+}
+
+// newServerPopup builds a SNAC(0x08,0x02) PopupDisplay showing text, and
+// the page at url if it's not empty.
+func newServerPopup(text string, url string) wire.SNACMessage {
+	tlvs := wire.TLVList{
+		wire.NewTLVBE(wire.PopupTLVMessage, text),
+	}
+	if url != "" {
+		tlvs = append(tlvs, wire.NewTLVBE(wire.PopupTLVURL, url))
+	}
+
+	return wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.Popup,
+			SubGroup:  wire.PopupDisplay,
+		},
+		Body: wire.SNAC_0x08_0x02_PopupDisplay{
+			TLVRestBlock: wire.TLVRestBlock{TLVList: tlvs},
+		},
+	}
+}
@@ -0,0 +1,123 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func receiveWelcome(t *testing.T, sess *Session) (wire.SNACMessage, bool) {
+	t.Helper()
+	select {
+	case msg := <-sess.ReceiveMessage():
+		return msg, true
+	case <-time.After(100 * time.Millisecond):
+		return wire.SNACMessage{}, false
+	}
+}
+
+func TestSQLiteUserStore_MarkWelcomed(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	user, err := NewStubUser("WelcomeWendy")
+	require.NoError(t, err)
+	require.NoError(t, f.InsertUser(ctx, user))
+
+	first, err := f.MarkWelcomed(ctx, user.IdentScreenName, time.Now())
+	require.NoError(t, err)
+	assert.True(t, first)
+
+	first, err = f.MarkWelcomed(ctx, user.IdentScreenName, time.Now())
+	require.NoError(t, err)
+	assert.False(t, first)
+
+	_, err = f.MarkWelcomed(ctx, NewIdentScreenName("nobody"), time.Now())
+	assert.ErrorIs(t, err, ErrNoUser)
+}
+
+func TestWelcomer_SignOn(t *testing.T) {
+	t.Parallel()
+
+	testFile := newTestDBPath(t)
+
+	f, err := NewSQLiteUserStore(testFile)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	user, err := NewStubUser("Welcome Wendy")
+	require.NoError(t, err)
+	require.NoError(t, f.InsertUser(ctx, user))
+
+	sm := NewInMemorySessionManager(slog.Default())
+	sess, err := sm.AddSession(ctx, user.DisplayScreenName)
+	require.NoError(t, err)
+
+	w, err := NewWelcomer(f, "Welcome, <b>{{.ScreenName}}</b>!", "Hi {{.ScreenName}}", "https://example.com/welcome", false, slog.Default())
+	require.NoError(t, err)
+
+	w.SignOn(ctx, sess)
+
+	msg, ok := receiveWelcome(t, sess)
+	require.True(t, ok, "no welcome IM")
+	assert.Equal(t, wire.SNACFrame{FoodGroup: wire.ICBM, SubGroup: wire.ICBMChannelMsgToClient}, msg.Frame)
+	im := msg.Body.(wire.SNAC_0x04_0x07_ICBMChannelMsgToClient)
+	assert.Equal(t, wire.ScreenName(SystemMessageScreenName), im.ScreenName)
+	data, ok := im.Bytes(wire.ICBMTLVAOLIMData)
+	require.True(t, ok)
+	text, err := wire.UnmarshalICBMMessageText(data)
+	require.NoError(t, err)
+	assert.Equal(t, "Welcome, <b>Welcome Wendy</b>!", text)
+
+	msg, ok = receiveWelcome(t, sess)
+	require.True(t, ok, "no welcome popup")
+	assert.Equal(t, wire.SNACFrame{FoodGroup: wire.Popup, SubGroup: wire.PopupDisplay}, msg.Frame)
+	popup := msg.Body.(wire.SNAC_0x08_0x02_PopupDisplay)
+	popupText, _ := popup.String(wire.PopupTLVMessage)
+	assert.Equal(t, "Hi Welcome Wendy", popupText)
+	popupURL, _ := popup.String(wire.PopupTLVURL)
+	assert.Equal(t, "https://example.com/welcome", popupURL)
+
+	// users are only welcomed on their first sign-on
+	w.SignOn(ctx, sess)
+	_, ok = receiveWelcome(t, sess)
+	assert.False(t, ok)
+
+	// unless the welcome is sent on every sign-on
+	w, err = NewWelcomer(f, "Welcome back!", "", "", true, slog.Default())
+	require.NoError(t, err)
+	w.SignOn(ctx, sess)
+	_, ok = receiveWelcome(t, sess)
+	assert.True(t, ok)
+	_, ok = receiveWelcome(t, sess)
+	assert.False(t, ok, "popup sent without a template")
+}
+
+func TestWelcomer_EscapesScreenName(t *testing.T) {
+	w, err := NewWelcomer(nil, "Hi {{.ScreenName}}", "", "", true, slog.Default())
+	require.NoError(t, err)
+
+	msg, err := w.imMessage(welcomeData{ScreenName: "<script>"})
+	require.NoError(t, err)
+	im := msg.Body.(wire.SNAC_0x04_0x07_ICBMChannelMsgToClient)
+	data, _ := im.Bytes(wire.ICBMTLVAOLIMData)
+	text, err := wire.UnmarshalICBMMessageText(data)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi &lt;script&gt;", text)
+}
+
+func TestNewWelcomer_InvalidTemplate(t *testing.T) {
+	_, err := NewWelcomer(nil, "Hi {{.ScreenName", "", "", true, slog.Default())
+	assert.ErrorContains(t, err, "invalid welcome IM template")
+}
@@ -0,0 +1,74 @@
+package state
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+// WellKnownFilesPath is the path under which WellKnownFileHandler is
+// mounted on the management API.
+const WellKnownFilesPath = "/wellknown/"
+
+// WellKnownURLsMessage builds the SNAC(0x01,0x15) OServiceWellKnownURLs
+// sent to clients at sign-on, with one TLV per URL in urls, keyed by TLV
+// tag. It returns false if urls is empty, in which case nothing should be
+// sent.
+func WellKnownURLsMessage(urls map[uint16]string) (wire.SNACMessage, bool) {
+	if len(urls) == 0 {
+		return wire.SNACMessage{}, false
+	}
+
+	var tlvs wire.TLVList
+	for _, tag := range slices.Sorted(maps.Keys(urls)) {
+		tlvs = append(tlvs, wire.NewTLVBE(tag, urls[tag]))
+	}
+
+	return wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.OService,
+			SubGroup:  wire.OServiceWellKnownUrls,
+		},
+		Body: wire.SNAC_0x01_0x15_OServiceWellKnownURLs{
+			TLVRestBlock: wire.TLVRestBlock{TLVList: tlvs},
+		},
+	}, true
+}
+
+// WellKnownFileHandler serves the static files in dir that the well-known
+// URLs point at, such as spell-check dictionaries. It's mounted at
+// WellKnownFilesPath. Clients treat failed fetches as errors, so requests
+// for files that don't exist get an empty 200 response instead of a 404.
+// Directories are never listed.
+func WellKnownFileHandler(dir string) http.Handler {
+	root := os.DirFS(dir)
+	return http.StripPrefix(strings.TrimSuffix(WellKnownFilesPath, "/"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if name == "" {
+			name = "."
+		}
+		info, err := fs.Stat(root, name)
+		switch {
+		case errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()):
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.WriteHeader(http.StatusOK)
+		case err != nil:
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		default:
+			http.ServeFileFS(w, r, root, name)
+		}
+	}))
+}
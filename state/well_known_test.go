@@ -0,0 +1,97 @@
+package state
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pchchv/go-icq/wire"
+)
+
+func TestWellKnownURLsMessage(t *testing.T) {
+	_, ok := WellKnownURLsMessage(nil)
+	assert.False(t, ok)
+
+	msg, ok := WellKnownURLsMessage(map[uint16]string{
+		2: "http://example.com/b",
+		1: "http://example.com/a",
+	})
+	require.True(t, ok)
+	assert.Equal(t, wire.SNACFrame{FoodGroup: wire.OService, SubGroup: wire.OServiceWellKnownUrls}, msg.Frame)
+
+	body := msg.Body.(wire.SNAC_0x01_0x15_OServiceWellKnownURLs)
+	assert.Equal(t, wire.TLVList{
+		wire.NewTLVBE(1, "http://example.com/a"),
+		wire.NewTLVBE(2, "http://example.com/b"),
+	}, body.TLVList)
+}
+
+func TestWellKnownFileHandler(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "spellcheck.dic"), []byte("aardvark\n"), 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o700))
+
+	handler := WellKnownFileHandler(dir)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "existing file",
+			method:     http.MethodGet,
+			path:       "/wellknown/spellcheck.dic",
+			wantStatus: http.StatusOK,
+			wantBody:   "aardvark\n",
+		},
+		{
+			name:       "missing file is empty",
+			method:     http.MethodGet,
+			path:       "/wellknown/triton/ads.xml",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "directories aren't listed",
+			method:     http.MethodGet,
+			path:       "/wellknown/sub/",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "root isn't listed",
+			method:     http.MethodGet,
+			path:       "/wellknown/",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "escaping the directory",
+			method:     http.MethodGet,
+			path:       "/wellknown/../../etc/passwd",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "wrong method",
+			method:     http.MethodPost,
+			path:       "/wellknown/spellcheck.dic",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   "Method not allowed.\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			req.URL.Path = tt.path
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantBody, rec.Body.String())
+		})
+	}
+}
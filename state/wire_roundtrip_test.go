@@ -0,0 +1,199 @@
+package state_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"log/slog"
+	"testing"
+
+	"github.com/pchchv/go-icq/state"
+	"github.com/pchchv/go-icq/state/statetest"
+	"github.com/pchchv/go-icq/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sendOverWire marshals v as a client or the server would put it on the
+// connection and unmarshals it into out as the peer would read it.
+func sendOverWire(t *testing.T, v any, out any) {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	require.NoError(t, wire.MarshalBE(v, buf))
+	require.NoError(t, wire.UnmarshalBEStrict(out, buf.Bytes()))
+}
+
+// TestStateWireRoundTrip walks two users from sign-on to buddy icon upload
+// through the exported API of the state package, marshaling each SNAC the
+// way the OSCAR services would send it and unmarshaling it as the peer
+// would read it. It checks that state and wire fit together. It is not an
+// end-to-end test: no OSCAR handler, listener or client connection is
+// involved.
+func TestStateWireRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := statetest.NewStore(t)
+	seed := statetest.New(t, store)
+	seed.Users("Alice", "Bob")
+	alice := state.NewIdentScreenName("Alice")
+	bob := state.NewIdentScreenName("Bob")
+
+	sessions := state.NewInMemorySessionManager(slog.Default())
+	chats := state.NewInMemoryChatSessionManager(slog.Default())
+	cookies := state.NewCookieStore(0, 0)
+
+	signOn := func(screenName state.DisplayScreenName) *state.Session {
+		// the auth service hands the client a cookie for BOS
+		buf := &bytes.Buffer{}
+		require.NoError(t, wire.MarshalBE(state.ServerCookie{Service: wire.BOS, ScreenName: screenName}, buf))
+		cookie, err := cookies.Issue(buf.Bytes())
+		require.NoError(t, err)
+
+		// BOS redeems the cookie the client presents, exactly once
+		data, err := cookies.Crack(cookie)
+		require.NoError(t, err)
+		_, err = cookies.Crack(cookie)
+		require.ErrorIs(t, err, state.ErrCookieInvalid)
+
+		serverCookie := state.ServerCookie{}
+		require.NoError(t, wire.UnmarshalBE(&serverCookie, bytes.NewReader(data)))
+		require.Equal(t, screenName, serverCookie.ScreenName)
+
+		sess, err := sessions.AddSession(ctx, serverCookie.ScreenName)
+		require.NoError(t, err)
+		require.NoError(t, store.RegisterBuddyList(ctx, sess.IdentScreenName()))
+		sess.SetSignonComplete()
+		return sess
+	}
+	aliceSess := signOn("Alice")
+	bobSess := signOn("Bob")
+	assert.Equal(t, state.CookieStats{Issued: 2, Redeemed: 2}, cookies.Stats())
+
+	t.Run("feedbag sync", func(t *testing.T) {
+		insert := wire.SNAC_0x13_0x08_FeedbagInsertItem{
+			Items: []wire.FeedbagItem{
+				{GroupID: 1, ClassID: wire.FeedbagClassIdGroup, Name: "Friends"},
+				{GroupID: 1, ItemID: 2, ClassID: wire.FeedbagClassIdBuddy, Name: "Bob"},
+			},
+		}
+		require.NoError(t, store.UseFeedbag(ctx, alice))
+		received := wire.SNAC_0x13_0x08_FeedbagInsertItem{}
+		sendOverWire(t, insert, &received)
+		require.NoError(t, store.FeedbagUpsert(ctx, alice, received.Items))
+
+		items, err := store.Feedbag(ctx, alice)
+		require.NoError(t, err)
+		lastModified, err := store.FeedbagLastModified(ctx, alice)
+		require.NoError(t, err)
+
+		reply := wire.SNAC_0x13_0x06_FeedbagReply{}
+		sendOverWire(t, wire.SNAC_0x13_0x06_FeedbagReply{
+			Items:      items,
+			LastUpdate: uint32(lastModified.Unix()),
+		}, &reply)
+		require.Len(t, reply.Items, 2)
+		assert.ElementsMatch(t, []string{"Friends", "bob"}, []string{reply.Items[0].Name, reply.Items[1].Name})
+		assert.NotZero(t, reply.LastUpdate)
+
+		rel, err := store.Relationship(ctx, bob, alice)
+		require.NoError(t, err)
+		assert.True(t, rel.IsOnTheirList)
+	})
+
+	t.Run("IM exchange", func(t *testing.T) {
+		frags, err := wire.ICBMFragmentList("hello bob")
+		require.NoError(t, err)
+		sent := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+			Cookie:     1234,
+			ChannelID:  wire.ICBMChannelIM,
+			ScreenName: "bob",
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{wire.NewTLVBE(wire.ICBMTLVAOLIMData, frags)},
+			},
+		}
+		toHost := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{}
+		sendOverWire(t, sent, &toHost)
+
+		recipient := state.NewIdentScreenName(string(toHost.ScreenName))
+		sessions.RelayToScreenName(ctx, recipient, wire.SNACMessage{
+			Frame: wire.SNACFrame{FoodGroup: wire.ICBM, SubGroup: wire.ICBMChannelMsgToClient},
+			Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+				Cookie:       toHost.Cookie,
+				ChannelID:    toHost.ChannelID,
+				TLVUserInfo:  aliceSess.TLVUserInfo(),
+				TLVRestBlock: toHost.TLVRestBlock,
+			},
+		})
+
+		msg := <-bobSess.ReceiveMessage()
+		toClient := wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{}
+		sendOverWire(t, msg.Body, &toClient)
+		assert.Equal(t, uint64(1234), toClient.Cookie)
+		assert.Equal(t, "Alice", string(toClient.ScreenName))
+		b, ok := toClient.Bytes(wire.ICBMTLVAOLIMData)
+		require.True(t, ok)
+		text, err := wire.UnmarshalICBMMessageText(b)
+		require.NoError(t, err)
+		assert.Equal(t, "hello bob", text)
+	})
+
+	t.Run("chat join", func(t *testing.T) {
+		room := state.NewChatRoom("Lobby", alice, state.PublicExchange)
+		require.NoError(t, store.CreateChatRoom(ctx, &room))
+
+		aliceChat, err := chats.AddSession(ctx, room.Cookie(), "Alice")
+		require.NoError(t, err)
+		aliceChat.SetSignonComplete()
+		bobChat, err := chats.AddSession(ctx, room.Cookie(), "Bob")
+		require.NoError(t, err)
+		bobChat.SetSignonComplete()
+		assert.Len(t, chats.AllSessions(room.Cookie()), 2)
+
+		info := wire.TLVRestBlock{TLVList: wire.TLVList{wire.NewTLVBE(wire.ChatTLVMessageInfoText, "hi all")}}
+		sent := wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+			Cookie:  5678,
+			Channel: wire.ICBMChannelMIME,
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{wire.NewTLVBE(wire.ChatTLVMessageInfo, info)},
+			},
+		}
+		toHost := wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{}
+		sendOverWire(t, sent, &toHost)
+
+		chats.RelayToAllExcept(ctx, room.Cookie(), alice, wire.SNACMessage{
+			Frame: wire.SNACFrame{FoodGroup: wire.Chat, SubGroup: wire.ChatChannelMsgToClient},
+			Body: wire.SNAC_0x0E_0x06_ChatChannelMsgToClient{
+				Cookie:       toHost.Cookie,
+				Channel:      toHost.Channel,
+				TLVRestBlock: toHost.TLVRestBlock,
+			},
+		})
+
+		msg := <-bobChat.ReceiveMessage()
+		toClient := wire.SNAC_0x0E_0x06_ChatChannelMsgToClient{}
+		sendOverWire(t, msg.Body, &toClient)
+		b, ok := toClient.Bytes(wire.ChatTLVMessageInfo)
+		require.True(t, ok)
+		text, err := wire.UnmarshalChatMessageText(b)
+		require.NoError(t, err)
+		assert.Equal(t, "hi all", text)
+		assert.Empty(t, aliceChat.ReceiveMessage())
+	})
+
+	t.Run("icon upload", func(t *testing.T) {
+		icon := []byte("GIF89a not really an icon")
+		upload := wire.SNAC_0x10_0x02_BARTUploadQuery{}
+		sendOverWire(t, wire.SNAC_0x10_0x02_BARTUploadQuery{Type: wire.BARTTypesBuddyIcon, Data: icon}, &upload)
+
+		hash := md5.Sum(upload.Data)
+		require.NoError(t, store.InsertBARTItem(ctx, hash[:], upload.Data, upload.Type))
+
+		reply := wire.SNAC_0x10_0x03_BARTUploadReply{}
+		sendOverWire(t, wire.SNAC_0x10_0x03_BARTUploadReply{
+			ID: wire.BARTID{Type: upload.Type, BARTInfo: wire.BARTInfo{Hash: hash[:]}},
+		}, &reply)
+
+		body, err := store.BARTItem(ctx, reply.ID.Hash)
+		require.NoError(t, err)
+		assert.Equal(t, icon, body)
+	})
+}
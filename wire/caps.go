@@ -0,0 +1,109 @@
+package wire
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CapChatGUID is the canonical text form of CapChat, the capability of chat
+// room invitations.
+const CapChatGUID = "748F2420-6287-11D1-8222-444553540000"
+
+// ErrInvalidCapability indicates that a capability GUID can't be parsed.
+var ErrInvalidCapability = errors.New("invalid capability GUID")
+
+// Capability UUIDs advertised by clients in the OServiceUserInfoOscarCaps
+// TLV. CapICQServerRelay is declared alongside the ICQ plugin messages.
+var (
+	// CapChat indicates that the client can join chat rooms.
+	CapChat = [16]byte{
+		0x74, 0x8F, 0x24, 0x20, 0x62, 0x87, 0x11, 0xD1,
+		0x82, 0x22, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00,
+	}
+	// CapVoice indicates that the client supports voice chat.
+	CapVoice = [16]byte{
+		0x09, 0x46, 0x13, 0x41, 0x4C, 0x7F, 0x11, 0xD1,
+		0x82, 0x22, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00,
+	}
+	// CapFileTransfer indicates that the client can receive files.
+	CapFileTransfer = [16]byte{
+		0x09, 0x46, 0x13, 0x43, 0x4C, 0x7F, 0x11, 0xD1,
+		0x82, 0x22, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00,
+	}
+	// CapDirectIM indicates that the client supports direct IM connections.
+	CapDirectIM = [16]byte{
+		0x09, 0x46, 0x13, 0x45, 0x4C, 0x7F, 0x11, 0xD1,
+		0x82, 0x22, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00,
+	}
+	// CapBuddyIcon indicates that the client displays buddy icons.
+	CapBuddyIcon = [16]byte{
+		0x09, 0x46, 0x13, 0x46, 0x4C, 0x7F, 0x11, 0xD1,
+		0x82, 0x22, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00,
+	}
+	// CapAddIns indicates that the client supports add-ins.
+	CapAddIns = [16]byte{
+		0x09, 0x46, 0x13, 0x47, 0x4C, 0x7F, 0x11, 0xD1,
+		0x82, 0x22, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00,
+	}
+	// CapFileShare indicates that the client shares a folder of files.
+	CapFileShare = [16]byte{
+		0x09, 0x46, 0x13, 0x48, 0x4C, 0x7F, 0x11, 0xD1,
+		0x82, 0x22, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00,
+	}
+	// CapGames indicates that the client supports games.
+	CapGames = [16]byte{
+		0x09, 0x46, 0x13, 0x4A, 0x4C, 0x7F, 0x11, 0xD1,
+		0x82, 0x22, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00,
+	}
+	// CapBuddyListTransfer indicates that the client can receive buddy
+	// lists.
+	CapBuddyListTransfer = [16]byte{
+		0x09, 0x46, 0x13, 0x4B, 0x4C, 0x7F, 0x11, 0xD1,
+		0x82, 0x22, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00,
+	}
+	// CapUTF8 indicates that the client accepts UTF-8 encoded messages.
+	CapUTF8 = [16]byte{
+		0x09, 0x46, 0x13, 0x4E, 0x4C, 0x7F, 0x11, 0xD1,
+		0x82, 0x22, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00,
+	}
+)
+
+// CapNames maps the capability names used in server configuration to their
+// UUIDs.
+var CapNames = map[string][16]byte{
+	"chat":              CapChat,
+	"voice":             CapVoice,
+	"filetransfer":      CapFileTransfer,
+	"directim":          CapDirectIM,
+	"buddyicon":         CapBuddyIcon,
+	"addins":            CapAddIns,
+	"fileshare":         CapFileShare,
+	"games":             CapGames,
+	"buddylisttransfer": CapBuddyListTransfer,
+	"utf8":              CapUTF8,
+	"icqserverrelay":    CapICQServerRelay,
+}
+
+// ParseCapability parses a capability GUID in the text form
+// "748F2420-6287-11D1-8222-444553540000", in either case and optionally in
+// braces.
+func ParseCapability(s string) ([16]byte, error) {
+	var c [16]byte
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	if len(trimmed) != 36 || trimmed[8] != '-' || trimmed[13] != '-' || trimmed[18] != '-' || trimmed[23] != '-' {
+		return c, fmt.Errorf("%w: %q", ErrInvalidCapability, s)
+	}
+	if _, err := hex.Decode(c[:], []byte(strings.ReplaceAll(trimmed, "-", ""))); err != nil {
+		return c, fmt.Errorf("%w: %q", ErrInvalidCapability, s)
+	}
+	return c, nil
+}
+
+// CapabilityString returns the canonical text form of capability GUID c, as
+// parsed by ParseCapability.
+func CapabilityString(c [16]byte) string {
+	h := strings.ToUpper(hex.EncodeToString(c[:]))
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
@@ -0,0 +1,45 @@
+package wire
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCapability(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    [16]byte
+		wantErr bool
+	}{
+		{name: "canonical chat GUID", in: CapChatGUID, want: CapChat},
+		{name: "lower case", in: "748f2420-6287-11d1-8222-444553540000", want: CapChat},
+		{name: "braces", in: "{09461343-4C7F-11D1-8222-444553540000}", want: CapFileTransfer},
+		{name: "missing hyphens", in: "748F2420628711D18222444553540000", wantErr: true},
+		{name: "misplaced hyphen", in: "748F242-06287-11D1-8222-444553540000", wantErr: true},
+		{name: "not hex", in: "748F2420-6287-11D1-8222-44455354000Z", wantErr: true},
+		{name: "empty", in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCapability(tt.in)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidCapability)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCapabilityString(t *testing.T) {
+	assert.Equal(t, CapChatGUID, CapabilityString(CapChat))
+	for name, c := range CapNames {
+		got, err := ParseCapability(CapabilityString(c))
+		require.NoError(t, err, name)
+		assert.Equal(t, c, got, name)
+	}
+}
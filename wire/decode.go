@@ -6,14 +6,34 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"reflect"
 )
 
 var (
 	ErrUnmarshalFailure  = errors.New("failed to unmarshal")
 	errNotNullTerminated = errors.New("nullterm tag is set, but string is not null-terminated")
+	// ErrTrailingBytes indicates that a payload decoded in strict mode has
+	// bytes left over after the message. See TrailingBytesError.
+	ErrTrailingBytes = errors.New("unexpected trailing bytes")
 )
 
+// TrailingBytesError is returned by UnmarshalBEStrict and UnmarshalLEStrict
+// when the payload is longer than the message it was decoded into. It
+// matches ErrTrailingBytes with errors.Is.
+type TrailingBytesError struct {
+	// Count is the number of bytes left over.
+	Count int
+}
+
+func (e TrailingBytesError) Error() string {
+	return fmt.Sprintf("%s: %d", ErrTrailingBytes, e.Count)
+}
+
+func (e TrailingBytesError) Is(target error) bool {
+	return target == ErrTrailingBytes
+}
+
 // UnmarshalBE unmarshalls OSCAR protocol messages in big-endian format.
 func UnmarshalBE(v any, r io.Reader) error {
 	if err := unmarshal(reflect.TypeOf(v).Elem(), reflect.ValueOf(v).Elem(), "", r, binary.BigEndian); err != nil {
@@ -30,6 +50,30 @@ func UnmarshalLE(v any, r io.Reader) error {
 	return nil
 }
 
+// UnmarshalBEStrict is like UnmarshalBE, but fails with a TrailingBytesError
+// if b holds more bytes than the message. Production paths tolerate extra
+// bytes, which some clients send; strict mode is for tests and debugging
+// tools that check that a message type covers the whole payload.
+func UnmarshalBEStrict(v any, b []byte) error {
+	return unmarshalStrict(v, b, binary.BigEndian)
+}
+
+// UnmarshalLEStrict is the little-endian counterpart of UnmarshalBEStrict.
+func UnmarshalLEStrict(v any, b []byte) error {
+	return unmarshalStrict(v, b, binary.LittleEndian)
+}
+
+func unmarshalStrict(v any, b []byte, order binary.ByteOrder) error {
+	r := bytes.NewReader(b)
+	if err := unmarshal(reflect.TypeOf(v).Elem(), reflect.ValueOf(v).Elem(), "", r, order); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnmarshalFailure, err)
+	}
+	if r.Len() > 0 {
+		return fmt.Errorf("%w: %w", ErrUnmarshalFailure, TrailingBytesError{Count: r.Len()})
+	}
+	return nil
+}
+
 func unmarshalUnsignedInt(intType reflect.Kind, r io.Reader, order binary.ByteOrder) (bufLen int, err error) {
 	switch intType {
 	case reflect.Uint8:
@@ -44,8 +88,14 @@ func unmarshalUnsignedInt(intType reflect.Kind, r io.Reader, order binary.ByteOr
 			return 0, err
 		}
 		bufLen = int(l)
+	case reflect.Uint32:
+		var l uint32
+		if err = binary.Read(r, order, &l); err != nil {
+			return 0, err
+		}
+		bufLen = int(l)
 	default:
-		panic(fmt.Sprintf("unsupported type %s. allowed types: uint8, uint16", intType))
+		panic(fmt.Sprintf("unsupported type %s. allowed types: uint8, uint16, uint32", intType))
 	}
 	return bufLen, nil
 }
@@ -60,11 +110,11 @@ func unmarshalString(v reflect.Value, oscTag oscarTag, r io.Reader, order binary
 		return err
 	}
 
-	buf := make([]byte, bufLen)
+	buf, err := readPrefixed(r, bufLen)
+	if err != nil {
+		return err
+	}
 	if bufLen > 0 {
-		if _, err := io.ReadFull(r, buf); err != nil {
-			return err
-		}
 		if oscTag.nullTerminated {
 			if buf[len(buf)-1] != 0x00 {
 				return errNotNullTerminated
@@ -77,6 +127,27 @@ func unmarshalString(v reflect.Value, oscTag oscarTag, r io.Reader, order binary
 	return nil
 }
 
+// readPrefixed reads the n bytes that follow a length prefix. Lengths that
+// don't fit in a uint16 are read incrementally so that a bogus uint32 prefix
+// can't force a large up-front allocation.
+func readPrefixed(r io.Reader, n int) ([]byte, error) {
+	if n <= math.MaxUint16 {
+		buf := make([]byte, n)
+		if n > 0 {
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	}
+
+	buf := &bytes.Buffer{}
+	if _, err := io.CopyN(buf, r, int64(n)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 func unmarshalStruct(t reflect.Type, v reflect.Value, oscTag oscarTag, r io.Reader, order binary.ByteOrder) error {
 	if oscTag.hasLenPrefix {
 		bufLen, err := unmarshalUnsignedInt(oscTag.lenPrefix, r, order)
@@ -84,11 +155,9 @@ func unmarshalStruct(t reflect.Type, v reflect.Value, oscTag oscarTag, r io.Read
 			return err
 		}
 
-		b := make([]byte, bufLen)
-		if bufLen > 0 {
-			if _, err := io.ReadFull(r, b); err != nil {
-				return err
-			}
+		b, err := readPrefixed(r, bufLen)
+		if err != nil {
+			return err
 		}
 
 		r = bytes.NewBuffer(b)
@@ -98,7 +167,20 @@ func unmarshalStruct(t reflect.Type, v reflect.Value, oscTag oscarTag, r io.Read
 		field := t.Field(i)
 		value := v.Field(i)
 		if field.Type.Kind() == reflect.Ptr {
-			if i != v.NumField()-1 {
+			oscTag, err := parseOSCARTag(field.Tag)
+			if err != nil {
+				return fmt.Errorf("error parsing tag: %w", err)
+			}
+			if oscTag.condition != nil {
+				present, err := oscTag.condition.holds(t, v, i)
+				if err != nil {
+					return err
+				}
+				if !present {
+					value.Set(reflect.Zero(field.Type))
+					continue
+				}
+			} else if i != v.NumField()-1 {
 				return fmt.Errorf("pointer type found at non-final field %s", field.Name)
 			}
 			if field.Type.Elem().Kind() != reflect.Struct {
@@ -138,11 +220,9 @@ func unmarshalSlice(v reflect.Value, oscTag oscarTag, r io.Reader, order binary.
 			return err
 		}
 
-		b := make([]byte, bufLen)
-		if bufLen > 0 {
-			if _, err := io.ReadFull(r, b); err != nil {
-				return err
-			}
+		b, err := readPrefixed(r, bufLen)
+		if err != nil {
+			return err
 		}
 
 		buf := bytes.NewBuffer(b)
@@ -192,7 +272,7 @@ func unmarshal(t reflect.Type, v reflect.Value, tag reflect.StructTag, r io.Read
 	if oscTag.optional {
 		v.Set(reflect.New(t.Elem()))
 		err := unmarshalStruct(t.Elem(), v.Elem(), oscTag, r, order)
-		if errors.Is(err, io.EOF) {
+		if errors.Is(err, io.EOF) && oscTag.condition == nil {
 			// no values to read, but that's ok since this struct is optional
 			v.Set(reflect.Zero(t))
 			err = nil
@@ -208,6 +288,12 @@ func unmarshal(t reflect.Type, v reflect.Value, tag reflect.StructTag, r io.Read
 	case reflect.Slice:
 		return unmarshalSlice(v, oscTag, r, order)
 	case reflect.String:
+		if enc, ok := stringEncodings[t]; ok {
+			if err := checkTypedStringTag(t, oscTag); err != nil {
+				return err
+			}
+			return unmarshalTypedString(enc, v, r, order)
+		}
 		return unmarshalString(v, oscTag, r, order)
 	case reflect.Struct:
 		return unmarshalStruct(t, v, oscTag, r, order)
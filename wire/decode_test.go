@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUnmarshal(t *testing.T) {
@@ -132,6 +133,28 @@ func TestUnmarshal(t *testing.T) {
 				[]byte{0x0, 0xa}, /* len prefix */
 				[]byte{0x74, 0x65, 0x73, 0x74, 0x2d, 0x76, 0x61, 0x6c, 0x75, 0x65}...), /* str val */
 		},
+		{
+			name: "string32",
+			prototype: &struct {
+				Val string `oscar:"len_prefix=uint32"`
+			}{},
+			want: &struct {
+				Val string `oscar:"len_prefix=uint32"`
+			}{
+				Val: "test-value",
+			},
+			given: append(
+				[]byte{0x0, 0x0, 0x0, 0xa}, /* len prefix */
+				[]byte{0x74, 0x65, 0x73, 0x74, 0x2d, 0x76, 0x61, 0x6c, 0x75, 0x65}...), /* str val */
+		},
+		{
+			name: "string32 with length exceeding payload",
+			prototype: &struct {
+				Val string `oscar:"len_prefix=uint32"`
+			}{},
+			given:   []byte{0xff, 0xff, 0xff, 0xff, 0x74, 0x65},
+			wantErr: io.EOF,
+		},
 		{
 			name: "null-terminated string16",
 			prototype: &struct {
@@ -504,6 +527,86 @@ func TestUnmarshal(t *testing.T) {
 				0x00, 0x64, // Val1
 			},
 		},
+		{
+			name: "conditional struct whose condition holds",
+			prototype: &struct {
+				Flags uint8
+				Cond  *struct {
+					Val1 uint16
+				} `oscar:"optional,when=Flags&0x02"`
+				Val2 uint8
+			}{},
+			want: &struct {
+				Flags uint8
+				Cond  *struct {
+					Val1 uint16
+				} `oscar:"optional,when=Flags&0x02"`
+				Val2 uint8
+			}{
+				Flags: 0x03,
+				Cond: &struct {
+					Val1 uint16
+				}{
+					Val1: 100,
+				},
+				Val2: 7,
+			},
+			given: []byte{
+				0x03,       // Flags
+				0x00, 0x64, // Val1
+				0x07, // Val2
+			},
+		},
+		{
+			name: "conditional struct whose condition doesn't hold",
+			prototype: &struct {
+				Channel uint16
+				Cond    *struct {
+					Val1 uint16
+				} `oscar:"optional,when=Channel==2"`
+				Val2 uint8
+			}{},
+			want: &struct {
+				Channel uint16
+				Cond    *struct {
+					Val1 uint16
+				} `oscar:"optional,when=Channel==2"`
+				Val2 uint8
+			}{
+				Channel: 1,
+				Val2:    7,
+			},
+			given: []byte{
+				0x00, 0x01, // Channel
+				0x07, // Val2
+			},
+		},
+		{
+			name: "conditional struct missing although its condition holds",
+			prototype: &struct {
+				Channel uint16
+				Cond    *struct {
+					Val1 uint16
+				} `oscar:"optional,when=Channel==2"`
+			}{},
+			given: []byte{
+				0x00, 0x02, // Channel
+			},
+			wantErr: ErrUnmarshalFailure,
+		},
+		{
+			name: "conditional struct with invalid condition",
+			prototype: &struct {
+				Channel uint16
+				Cond    *struct {
+					Val1 uint16
+				} `oscar:"optional,when=Channel>2"`
+			}{},
+			given: []byte{
+				0x00, 0x02, // Channel
+			},
+			wantErr: ErrUnmarshalFailure,
+		},
 		{
 			name: "optional struct with value missing `optional` struct tag",
 			prototype: &struct {
@@ -690,3 +793,93 @@ func TestUnmarshal(t *testing.T) {
 		})
 	}
 }
+
+func TestUnmarshalStrict(t *testing.T) {
+	type val struct {
+		Val0 uint8
+		Val1 *struct {
+			Val2 uint16
+		} `oscar:"len_prefix=uint16,optional"`
+	}
+
+	tests := []struct {
+		name         string
+		unmarshal    func(v any, b []byte) error
+		given        []byte
+		want         val
+		wantErr      error
+		wantTrailing int
+	}{
+		{
+			name:      "big-endian input consumed exactly",
+			unmarshal: UnmarshalBEStrict,
+			given: []byte{
+				0x22,       // Val0
+				0x00, 0x02, // Val1 struct len
+				0x00, 0x10, // Val2
+			},
+			want: val{Val0: 34, Val1: &struct {
+				Val2 uint16
+			}{Val2: 16}},
+		},
+		{
+			name:      "little-endian input consumed exactly",
+			unmarshal: UnmarshalLEStrict,
+			given: []byte{
+				0x22,       // Val0
+				0x02, 0x00, // Val1 struct len
+				0x10, 0x00, // Val2
+			},
+			want: val{Val0: 34, Val1: &struct {
+				Val2 uint16
+			}{Val2: 16}},
+		},
+		{
+			name:      "optional struct absent",
+			unmarshal: UnmarshalBEStrict,
+			given: []byte{
+				0x22, // Val0
+			},
+			want: val{Val0: 34},
+		},
+		{
+			name:      "trailing bytes after the last field",
+			unmarshal: UnmarshalBEStrict,
+			given: []byte{
+				0x22,       // Val0
+				0x00, 0x02, // Val1 struct len
+				0x00, 0x10, // Val2
+				0x00, 0x20, 0x30, // trailing
+			},
+			wantErr:      ErrTrailingBytes,
+			wantTrailing: 3,
+		},
+		{
+			name:      "truncated input",
+			unmarshal: UnmarshalBEStrict,
+			given: []byte{
+				0x22,       // Val0
+				0x00, 0x02, // Val1 struct len
+				0x00, // Val2
+			},
+			wantErr: ErrUnmarshalFailure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got val
+			err := tt.unmarshal(&got, tt.given)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, tt.want, got)
+			}
+			if tt.wantTrailing > 0 {
+				assert.ErrorIs(t, err, ErrUnmarshalFailure)
+				var trailing TrailingBytesError
+				require.ErrorAs(t, err, &trailing)
+				assert.Equal(t, tt.wantTrailing, trailing.Count)
+			}
+		})
+	}
+}
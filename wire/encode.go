@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 var (
@@ -16,6 +18,7 @@ var (
 	errOptionalNonPointer    = errors.New("optional fields must be pointers")
 	errNonOptionalPointer    = errors.New("pointer fields must reference structs and have an `optional` struct tag")
 	errMarshalFailureNilSNAC = errors.New("attempting to marshal a nil SNAC")
+	errConditionMismatch     = errors.New("conditional field must be set if and only if its condition holds")
 )
 
 type oscarTag struct {
@@ -25,6 +28,66 @@ type oscarTag struct {
 	lenPrefix      reflect.Kind
 	optional       bool
 	nullTerminated bool
+	// condition makes an optional field present only if an earlier field
+	// matches, set with `when`.
+	condition *fieldCondition
+}
+
+// fieldCondition is the condition of a `when` struct tag. It compares an
+// earlier unsigned integer field of the same struct, which may be promoted
+// from an embedded struct, against a value:
+//
+//	`oscar:"optional,when=Channel==2"`  // present if Channel is 2
+//	`oscar:"optional,when=Flags&0x01"`  // present if bit 0x01 of Flags is set
+type fieldCondition struct {
+	field string
+	// mask makes the condition a bit test instead of an equality test.
+	mask  bool
+	value uint64
+}
+
+func parseFieldCondition(s string) (fieldCondition, error) {
+	var c fieldCondition
+	name, value, found := strings.Cut(s, "==")
+	if !found {
+		name, value, found = strings.Cut(s, "&")
+		c.mask = true
+	}
+	if !found || name == "" {
+		return c, fmt.Errorf("%w: invalid condition %s. valid formats: FIELD==VALUE, FIELD&MASK",
+			errInvalidStructTag, s)
+	}
+
+	val, err := strconv.ParseUint(value, 0, 64)
+	if err != nil {
+		return c, fmt.Errorf("%w: invalid condition value %s", errInvalidStructTag, value)
+	}
+	c.field = name
+	c.value = val
+	return c, nil
+}
+
+// holds reports whether the condition of the field at index i of struct v
+// is met.
+func (c fieldCondition) holds(t reflect.Type, v reflect.Value, i int) (bool, error) {
+	sf, ok := t.FieldByName(c.field)
+	if !ok || sf.Index[0] >= i {
+		return false, fmt.Errorf("%w: condition field %s must come before field %s",
+			errInvalidStructTag, c.field, t.Field(i).Name)
+	}
+
+	fv := v.FieldByIndex(sf.Index)
+	switch fv.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return false, fmt.Errorf("%w: condition field %s must be an unsigned integer, got %v",
+			errInvalidStructTag, c.field, fv.Kind())
+	}
+
+	if c.mask {
+		return fv.Uint()&c.value != 0, nil
+	}
+	return fv.Uint() == c.value, nil
 }
 
 // MarshalBE marshals OSCAR protocol messages in big-endian format.
@@ -43,54 +106,113 @@ func MarshalLE(v any, w io.Writer) error {
 	return nil
 }
 
+// MarshalBEAppend marshals OSCAR protocol messages in big-endian format,
+// appending the output to buf and returning the extended buffer. Unlike
+// MarshalBE, it avoids intermediate allocations, so hot paths can reuse
+// buffers (for example from a sync.Pool) across messages. On error, the
+// returned buffer holds partial output.
+func MarshalBEAppend(v any, buf []byte) ([]byte, error) {
+	return marshalAppend(v, buf, binary.BigEndian)
+}
+
+// MarshalLEAppend is the little-endian counterpart of MarshalBEAppend for
+// ICQ protocol messages.
+func MarshalLEAppend(v any, buf []byte) ([]byte, error) {
+	return marshalAppend(v, buf, binary.LittleEndian)
+}
+
+func marshalAppend(v any, buf []byte, order binary.ByteOrder) ([]byte, error) {
+	aw := appendWriterPool.Get().(*appendWriter)
+	aw.buf = buf
+	err := marshal(reflect.TypeOf(v), reflect.ValueOf(v), "", aw, order)
+	buf = aw.buf
+	aw.buf = nil
+	appendWriterPool.Put(aw)
+
+	if err != nil {
+		return buf, fmt.Errorf("%w: %w", ErrMarshalFailure, err)
+	}
+	return buf, nil
+}
+
+// appendWriter is an io.Writer that appends to a byte slice. The marshal
+// functions recognize it and write to the slice directly.
+type appendWriter struct {
+	buf []byte
+}
+
+var appendWriterPool = sync.Pool{
+	New: func() any { return &appendWriter{} },
+}
+
+func (aw *appendWriter) Write(p []byte) (int, error) {
+	aw.buf = append(aw.buf, p...)
+	return len(p), nil
+}
+
+func (aw *appendWriter) WriteString(s string) (int, error) {
+	aw.buf = append(aw.buf, s...)
+	return len(s), nil
+}
+
 func parseOSCARTag(tag reflect.StructTag) (oscTag oscarTag, err error) {
 	val, ok := tag.Lookup("oscar")
 	if !ok {
 		return
 	}
 
-	for _, kv := range strings.Split(val, ",") {
-		kvSplit := strings.SplitN(kv, "=", 2)
-		if len(kvSplit) == 2 {
-			switch kvSplit[0] {
+	for kv := range strings.SplitSeq(val, ",") {
+		key, value, hasValue := strings.Cut(kv, "=")
+		if hasValue {
+			switch key {
 			case "len_prefix":
 				oscTag.hasLenPrefix = true
-				switch kvSplit[1] {
+				switch value {
 				case "uint8":
 					oscTag.lenPrefix = reflect.Uint8
 				case "uint16":
 					oscTag.lenPrefix = reflect.Uint16
+				case "uint32":
+					oscTag.lenPrefix = reflect.Uint32
 				default:
-					return oscTag, fmt.Errorf("%w: unsupported type %s. allowed types: uint8, uint16",
-						errInvalidStructTag, kvSplit[1])
+					return oscTag, fmt.Errorf("%w: unsupported type %s. allowed types: uint8, uint16, uint32",
+						errInvalidStructTag, value)
 				}
+			case "when":
+				cond, err := parseFieldCondition(value)
+				if err != nil {
+					return oscTag, err
+				}
+				oscTag.condition = &cond
 			case "count_prefix":
 				oscTag.hasCountPrefix = true
-				switch kvSplit[1] {
+				switch value {
 				case "uint8":
 					oscTag.countPrefix = reflect.Uint8
 				case "uint16":
 					oscTag.countPrefix = reflect.Uint16
 				default:
 					return oscTag, fmt.Errorf("%w: unsupported type %s. allowed types: uint8, uint16",
-						errInvalidStructTag, kvSplit[1])
+						errInvalidStructTag, value)
 				}
 			}
 		} else {
-			switch kvSplit[0] {
+			switch key {
 			case "optional":
 				oscTag.optional = true
 			case "nullterm":
 				oscTag.nullTerminated = true
 			default:
 				return oscTag, fmt.Errorf("%w: unsupported struct tag %s",
-					errInvalidStructTag, kvSplit[0])
+					errInvalidStructTag, key)
 			}
 		}
 	}
 
 	if oscTag.hasCountPrefix && oscTag.hasLenPrefix {
 		err = fmt.Errorf("%w: struct elem has both len_prefix and count_prefix", errInvalidStructTag)
+	} else if oscTag.condition != nil && !oscTag.optional {
+		err = fmt.Errorf("%w: when requires optional", errInvalidStructTag)
 	}
 
 	return oscTag, err
@@ -98,16 +220,89 @@ func parseOSCARTag(tag reflect.StructTag) (oscTag oscarTag, err error) {
 
 func marshalUnsignedInt(intType reflect.Kind, intVal int, w io.Writer, order binary.ByteOrder) error {
 	switch intType {
-	case reflect.Uint8:
-		if err := binary.Write(w, order, uint8(intVal)); err != nil {
-			return err
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return writeUint(intType, uint64(intVal), w, order)
+	default:
+		panic(fmt.Sprintf("unsupported type %s. allowed types: uint8, uint16, uint32", intType))
+	}
+}
+
+// writeUint writes val as an unsigned integer of the given kind. Writes to
+// an appendWriter skip the allocations made by binary.Write.
+func writeUint(intType reflect.Kind, val uint64, w io.Writer, order binary.ByteOrder) error {
+	if aw, ok := w.(*appendWriter); ok {
+		if ao, ok := order.(binary.AppendByteOrder); ok {
+			switch intType {
+			case reflect.Uint8:
+				aw.buf = append(aw.buf, uint8(val))
+			case reflect.Uint16:
+				aw.buf = ao.AppendUint16(aw.buf, uint16(val))
+			case reflect.Uint32:
+				aw.buf = ao.AppendUint32(aw.buf, uint32(val))
+			default:
+				aw.buf = ao.AppendUint64(aw.buf, val)
+			}
+			return nil
 		}
+	}
+
+	switch intType {
+	case reflect.Uint8:
+		return binary.Write(w, order, uint8(val))
 	case reflect.Uint16:
-		if err := binary.Write(w, order, uint16(intVal)); err != nil {
+		return binary.Write(w, order, uint16(val))
+	case reflect.Uint32:
+		return binary.Write(w, order, uint32(val))
+	default:
+		return binary.Write(w, order, val)
+	}
+}
+
+// writeLenPrefixed writes the output of marshalFn preceded by its length.
+func writeLenPrefixed(lenPrefix reflect.Kind, w io.Writer, order binary.ByteOrder, marshalFn func(w io.Writer) error) error {
+	if aw, ok := w.(*appendWriter); ok {
+		// reserve room for the length and fill it in once it's known
+		var size int
+		switch lenPrefix {
+		case reflect.Uint8:
+			size = 1
+		case reflect.Uint16:
+			size = 2
+		case reflect.Uint32:
+			size = 4
+		default:
+			panic(fmt.Sprintf("unsupported type %s. allowed types: uint8, uint16, uint32", lenPrefix))
+		}
+		start := len(aw.buf)
+		aw.buf = append(aw.buf, make([]byte, size)...)
+		if err := marshalFn(aw); err != nil {
 			return err
 		}
-	default:
-		panic(fmt.Sprintf("unsupported type %s. allowed types: uint8, uint16", intType))
+
+		n := len(aw.buf) - start - size
+		switch lenPrefix {
+		case reflect.Uint8:
+			aw.buf[start] = uint8(n)
+		case reflect.Uint16:
+			order.PutUint16(aw.buf[start:], uint16(n))
+		case reflect.Uint32:
+			order.PutUint32(aw.buf[start:], uint32(n))
+		}
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	if err := marshalFn(buf); err != nil {
+		return err
+	}
+	// write length
+	if err := marshalUnsignedInt(lenPrefix, buf.Len(), w, order); err != nil {
+		return err
+	}
+	// write bytes
+	if buf.Len() > 0 {
+		_, err := w.Write(buf.Bytes())
+		return err
 	}
 	return nil
 }
@@ -128,7 +323,8 @@ func marshalString(oscTag oscarTag, v reflect.Value, w io.Writer, order binary.B
 		return nil
 	}
 
-	return binary.Write(w, order, []byte(str))
+	_, err := io.WriteString(w, str)
+	return err
 }
 
 func marshalStruct(t reflect.Type, v reflect.Value, oscTag oscarTag, w io.Writer, order binary.ByteOrder) error {
@@ -142,7 +338,19 @@ func marshalStruct(t reflect.Type, v reflect.Value, oscTag oscarTag, w io.Writer
 			field := t.Field(i)
 			value := v.Field(i)
 			if field.Type.Kind() == reflect.Ptr {
-				if i != t.NumField()-1 {
+				oscTag, err := parseOSCARTag(field.Tag)
+				if err != nil {
+					return err
+				}
+				if oscTag.condition != nil {
+					present, err := oscTag.condition.holds(t, v, i)
+					if err != nil {
+						return err
+					}
+					if present == value.IsNil() {
+						return fmt.Errorf("%w: field %s", errConditionMismatch, field.Name)
+					}
+				} else if i != t.NumField()-1 {
 					return fmt.Errorf("pointer type found at non-final field %s", field.Name)
 				}
 				if field.Type.Elem().Kind() != reflect.Struct {
@@ -157,20 +365,7 @@ func marshalStruct(t reflect.Type, v reflect.Value, oscTag oscarTag, w io.Writer
 		return nil
 	}
 	if oscTag.hasLenPrefix {
-		buf := &bytes.Buffer{}
-		if err := marshalEachField(buf); err != nil {
-			return err
-		}
-		// write struct length
-		if err := marshalUnsignedInt(oscTag.lenPrefix, buf.Len(), w, order); err != nil {
-			return err
-		}
-		// write struct bytes
-		if buf.Len() > 0 {
-			_, err := w.Write(buf.Bytes())
-			return err
-		}
-		return nil
+		return writeLenPrefixed(oscTag.lenPrefix, w, order, marshalEachField)
 	}
 	return marshalEachField(w)
 }
@@ -198,37 +393,38 @@ func marshalInterface(v reflect.Value, w io.Writer, tag oscarTag, order binary.B
 	return marshalStruct(elem.Type(), elem, tag, w, order)
 }
 
-// TODO: only write to temporary buffer if len_prefix is set
 func marshalSlice(t reflect.Type, v reflect.Value, oscTag oscarTag, w io.Writer, order binary.ByteOrder) error {
-	buf := &bytes.Buffer{}
-	if t.Elem().Kind() == reflect.Struct {
-		for j := 0; j < v.Len(); j++ {
-			if err := marshalStruct(t.Elem(), v.Index(j), oscarTag{}, buf, order); err != nil {
-				return err
+	marshalElems := func(w io.Writer) error {
+		if t.Elem().Kind() == reflect.Struct {
+			for j := 0; j < v.Len(); j++ {
+				if err := marshalStruct(t.Elem(), v.Index(j), oscarTag{}, w, order); err != nil {
+					return err
+				}
 			}
+			return nil
 		}
-	} else {
-		if err := binary.Write(buf, order, v.Interface()); err != nil {
-			return fmt.Errorf("error marshalling %s", t.Elem().Kind())
+		if t.Elem().Kind() == reflect.Uint8 {
+			if v.Len() == 0 {
+				return nil
+			}
+			_, err := w.Write(v.Bytes())
+			return err
+		}
+		if err := binary.Write(w, order, v.Interface()); err != nil {
+			return fmt.Errorf("error marshalling %s: %w", t.Elem().Kind(), err)
 		}
+		return nil
 	}
 
 	if oscTag.hasLenPrefix {
-		if err := marshalUnsignedInt(oscTag.lenPrefix, buf.Len(), w, order); err != nil {
-			return err
-		}
+		return writeLenPrefixed(oscTag.lenPrefix, w, order, marshalElems)
 	} else if oscTag.hasCountPrefix {
 		if err := marshalUnsignedInt(oscTag.countPrefix, v.Len(), w, order); err != nil {
 			return err
 		}
 	}
 
-	if buf.Len() > 0 {
-		_, err := w.Write(buf.Bytes())
-		return err
-	}
-
-	return nil
+	return marshalElems(w)
 }
 
 func marshal(t reflect.Type, v reflect.Value, tag reflect.StructTag, w io.Writer, order binary.ByteOrder) error {
@@ -262,11 +458,17 @@ func marshal(t reflect.Type, v reflect.Value, tag reflect.StructTag, w io.Writer
 	case reflect.Slice:
 		return marshalSlice(t, v, oscTag, w, order)
 	case reflect.String:
+		if enc, ok := stringEncodings[t]; ok {
+			if err := checkTypedStringTag(t, oscTag); err != nil {
+				return err
+			}
+			return marshalTypedString(enc, v, w, order)
+		}
 		return marshalString(oscTag, v, w, order)
 	case reflect.Struct:
 		return marshalStruct(t, v, oscTag, w, order)
 	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return binary.Write(w, order, v.Interface())
+		return writeUint(t.Kind(), v.Uint(), w, order)
 	case reflect.Interface:
 		return marshalInterface(v, w, oscTag, order)
 	default:
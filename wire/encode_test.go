@@ -3,6 +3,7 @@ package wire
 import (
 	"bytes"
 	"io"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -107,6 +108,18 @@ func TestMarshal(t *testing.T) {
 				[]byte{0x0, 0xa}, /* len prefix */
 				[]byte{0x74, 0x65, 0x73, 0x74, 0x2d, 0x76, 0x61, 0x6c, 0x75, 0x65}...), /* str val */
 		},
+		{
+			name: "string32",
+			w:    &bytes.Buffer{},
+			given: struct {
+				Val string `oscar:"len_prefix=uint32"`
+			}{
+				Val: "test-value",
+			},
+			want: append(
+				[]byte{0x0, 0x0, 0x0, 0xa}, /* len prefix */
+				[]byte{0x74, 0x65, 0x73, 0x74, 0x2d, 0x76, 0x61, 0x6c, 0x75, 0x65}...), /* str val */
+		},
 		{
 			name: "null-terminated string16",
 			w:    &bytes.Buffer{},
@@ -476,6 +489,99 @@ func TestMarshal(t *testing.T) {
 				0x00, 0x64, // Val1
 			},
 		},
+		{
+			name: "conditional struct whose condition holds",
+			w:    &bytes.Buffer{},
+			given: struct {
+				Flags uint8
+				Cond  *struct {
+					Val1 uint16
+				} `oscar:"optional,when=Flags&0x02"`
+				Val2 uint8
+			}{
+				Flags: 0x03,
+				Cond: &struct {
+					Val1 uint16
+				}{
+					Val1: 100,
+				},
+				Val2: 7,
+			},
+			want: []byte{
+				0x03,       // Flags
+				0x00, 0x64, // Val1
+				0x07, // Val2
+			},
+		},
+		{
+			name: "conditional struct whose condition doesn't hold",
+			w:    &bytes.Buffer{},
+			given: struct {
+				Channel uint16
+				Cond    *struct {
+					Val1 uint16
+				} `oscar:"optional,when=Channel==2"`
+				Val2 uint8
+			}{
+				Channel: 1,
+				Val2:    7,
+			},
+			want: []byte{
+				0x00, 0x01, // Channel
+				0x07, // Val2
+			},
+		},
+		{
+			name: "conditional struct set although its condition doesn't hold",
+			w:    &bytes.Buffer{},
+			given: struct {
+				Channel uint16
+				Cond    *struct {
+					Val1 uint16
+				} `oscar:"optional,when=Channel==2"`
+			}{
+				Channel: 1,
+				Cond: &struct {
+					Val1 uint16
+				}{},
+			},
+			wantErr: errConditionMismatch,
+		},
+		{
+			name: "conditional struct missing although its condition holds",
+			w:    &bytes.Buffer{},
+			given: struct {
+				Channel uint16
+				Cond    *struct {
+					Val1 uint16
+				} `oscar:"optional,when=Channel==2"`
+			}{
+				Channel: 2,
+			},
+			wantErr: errConditionMismatch,
+		},
+		{
+			name: "conditional struct referring to a later field",
+			w:    &bytes.Buffer{},
+			given: struct {
+				Cond *struct {
+					Val1 uint16
+				} `oscar:"optional,when=Channel==2"`
+				Channel uint16
+			}{},
+			wantErr: errInvalidStructTag,
+		},
+		{
+			name: "conditional struct without optional tag",
+			w:    &bytes.Buffer{},
+			given: struct {
+				Channel uint16
+				Cond    *struct {
+					Val1 uint16
+				} `oscar:"when=Channel==2"`
+			}{},
+			wantErr: errInvalidStructTag,
+		},
 		{
 			name: "optional struct with value missing `optional` struct tag",
 			w:    &bytes.Buffer{},
@@ -744,5 +850,104 @@ func TestMarshal(t *testing.T) {
 				}
 			}
 		})
+		if _, ok := tt.w.(*bytes.Buffer); !ok {
+			// append can't fail to write
+			continue
+		}
+		t.Run(tt.name+" append", func(t *testing.T) {
+			prefix := []byte{0xFF}
+			have, err := MarshalBEAppend(tt.given, prefix)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, append([]byte{0xFF}, tt.want...), have)
+			}
+		})
+	}
+}
+
+func TestMarshalLEAppend(t *testing.T) {
+	given := struct {
+		Val1 uint16
+		Val2 []uint16 `oscar:"len_prefix=uint16"`
+		Val3 string   `oscar:"len_prefix=uint16,nullterm"`
+	}{
+		Val1: 0x0102,
+		Val2: []uint16{0x0304},
+		Val3: "hi",
+	}
+
+	want := &bytes.Buffer{}
+	assert.NoError(t, MarshalLE(given, want))
+
+	have, err := MarshalLEAppend(given, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, want.Bytes(), have)
+	assert.Equal(t, []byte{0x02, 0x01, 0x02, 0x00, 0x04, 0x03, 0x03, 0x00, 'h', 'i', 0x00}, have)
+}
+
+// benchmarkSNAC is a typical server-to-client message, an IM with sender
+// user info and several TLVs.
+var benchmarkSNAC = SNACMessage{
+	Frame: SNACFrame{
+		FoodGroup: ICBM,
+		SubGroup:  ICBMChannelMsgToClient,
+		RequestID: 1234,
+	},
+	Body: SNAC_0x04_0x07_ICBMChannelMsgToClient{
+		Cookie:    0x0102030405060708,
+		ChannelID: ICBMChannelIM,
+		TLVUserInfo: TLVUserInfo{
+			ScreenName:   "SomeBuddy",
+			WarningLevel: 0,
+			TLVBlock: TLVBlock{
+				TLVList: TLVList{
+					NewTLVBE(OServiceUserInfoUserFlags, uint16(0x0010)),
+					NewTLVBE(OServiceUserInfoSignonTOD, uint32(1700000000)),
+					NewTLVBE(OServiceUserInfoIdleTime, uint16(0)),
+				},
+			},
+		},
+		TLVRestBlock: TLVRestBlock{
+			TLVList: TLVList{
+				NewTLVBE(ICBMTLVAOLIMData, []byte("<HTML><BODY>hello there, how are you doing today?</BODY></HTML>")),
+				NewTLVBE(ICBMTLVWantEvents, []byte{}),
+			},
+		},
+	},
+}
+
+func BenchmarkMarshalBE(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		buf := &bytes.Buffer{}
+		if err := MarshalBE(benchmarkSNAC.Frame, buf); err != nil {
+			b.Fatal(err)
+		}
+		if err := MarshalBE(benchmarkSNAC.Body, buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalBEAppend(b *testing.B) {
+	pool := sync.Pool{
+		New: func() any {
+			buf := make([]byte, 0, 512)
+			return &buf
+		},
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		bufPtr := pool.Get().(*[]byte)
+		buf, err := MarshalBEAppend(benchmarkSNAC.Frame, (*bufPtr)[:0])
+		if err != nil {
+			b.Fatal(err)
+		}
+		if buf, err = MarshalBEAppend(benchmarkSNAC.Body, buf); err != nil {
+			b.Fatal(err)
+		}
+		*bufPtr = buf
+		pool.Put(bufPtr)
 	}
 }
@@ -0,0 +1,295 @@
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"strings"
+	"unicode"
+)
+
+const (
+	// FeedbagAliasMaxLen is the maximum length of a buddy alias.
+	FeedbagAliasMaxLen = 64
+	// FeedbagPhoneNumberMaxLen is the maximum length of a buddy phone number.
+	FeedbagPhoneNumberMaxLen = 32
+	// FeedbagItemNameMaxLen is the maximum length of a feedbag item name,
+	// advertised as FeedbagRightsMaxItemNameLen.
+	FeedbagItemNameMaxLen = 97
+	// FeedbagItemAttrsMaxLen is the maximum encoded size of a feedbag
+	// item's attributes.
+	FeedbagItemAttrsMaxLen = 8192
+)
+
+// Feedbag status codes sent in SNAC(0x13,0x0E) FeedbagStatus, one for each
+// item in the request.
+const (
+	FeedbagStatusSuccess          uint16 = 0x0000
+	FeedbagStatusDBError          uint16 = 0x0001
+	FeedbagStatusNotFound         uint16 = 0x0002
+	FeedbagStatusAlreadyExists    uint16 = 0x0003
+	FeedbagStatusUnavailable      uint16 = 0x0005
+	FeedbagStatusBadRequest       uint16 = 0x000A
+	FeedbagStatusDBTimeOut        uint16 = 0x000B
+	FeedbagStatusOverRowLimit     uint16 = 0x000C
+	FeedbagStatusNotExecuted      uint16 = 0x000D
+	FeedbagStatusAuthRequired     uint16 = 0x000E
+	FeedbagStatusBadLoginID       uint16 = 0x0010
+	FeedbagStatusOverBuddyLimit   uint16 = 0x0011
+	FeedbagStatusInsertSmartGroup uint16 = 0x0014
+	FeedbagStatusTimeout          uint16 = 0x001A
+)
+
+var (
+	// ErrInvalidFeedbagAttribute indicates that a feedbag attribute value
+	// failed validation.
+	ErrInvalidFeedbagAttribute = errors.New("invalid feedbag attribute")
+	// ErrInvalidFeedbagItem indicates that a feedbag item is malformed, such
+	// as having an overlong name or an illegal class ID.
+	ErrInvalidFeedbagItem = errors.New("invalid feedbag item")
+)
+
+// Validate returns ErrInvalidFeedbagItem if the item's name or attributes
+// are too long, its class ID falls in the reserved range between
+// FeedbagClassIdMaxPredefined and FeedbagClassIdMin, or it is a buddy,
+// permit or deny item with no screen name.
+func (f FeedbagItem) Validate() error {
+	if len(f.Name) > FeedbagItemNameMaxLen {
+		return fmt.Errorf("%w: name longer than %d bytes", ErrInvalidFeedbagItem, FeedbagItemNameMaxLen)
+	}
+
+	attrsLen := 0
+	for _, tlv := range f.TLVList {
+		attrsLen += 4 + len(tlv.Value)
+	}
+	if attrsLen > FeedbagItemAttrsMaxLen {
+		return fmt.Errorf("%w: attributes longer than %d bytes", ErrInvalidFeedbagItem, FeedbagItemAttrsMaxLen)
+	}
+
+	if f.ClassID > FeedbagClassIdMaxPredefined && f.ClassID < FeedbagClassIdMin &&
+		f.ClassID != FeedbagClassIdXIcqStatusNote {
+		return fmt.Errorf("%w: illegal class ID %#04x", ErrInvalidFeedbagItem, f.ClassID)
+	}
+
+	switch f.ClassID {
+	case FeedbagClassIdBuddy, FeedbagClassIDPermit, FeedbagClassIDDeny:
+		if strings.TrimSpace(f.Name) == "" {
+			return fmt.Errorf("%w: class %#04x item has no screen name", ErrInvalidFeedbagItem, f.ClassID)
+		}
+	}
+
+	return nil
+}
+
+// knownFeedbagClasses are the class IDs that the server understands.
+var knownFeedbagClasses = map[uint16]bool{
+	FeedbagClassIdBuddy:            true,
+	FeedbagClassIdGroup:            true,
+	FeedbagClassIDPermit:           true,
+	FeedbagClassIDDeny:             true,
+	FeedbagClassIdPdinfo:           true,
+	FeedbagClassIdBuddyPrefs:       true,
+	FeedbagClassIdNonbuddy:         true,
+	FeedbagClassIdTpaProvider:      true,
+	FeedbagClassIdTpaSubscription:  true,
+	FeedbagClassIdClientPrefs:      true,
+	FeedbagClassIdStock:            true,
+	FeedbagClassIdWeather:          true,
+	FeedbagClassIdWatchList:        true,
+	FeedbagClassIdIgnoreList:       true,
+	FeedbagClassIdDateTime:         true,
+	FeedbagClassIdExternalUser:     true,
+	FeedbagClassIdRootCreator:      true,
+	FeedbagClassIdFish:             true,
+	FeedbagClassIdImportTimestamp:  true,
+	FeedbagClassIdBart:             true,
+	FeedbagClassIdRbOrder:          true,
+	FeedbagClassIdPersonality:      true,
+	FeedbagClassIdAlProf:           true,
+	FeedbagClassIdAlInfo:           true,
+	FeedbagClassIdInteraction:      true,
+	FeedbagClassIdVanityInfo:       true,
+	FeedbagClassIdFavoriteLocation: true,
+	FeedbagClassIdBartPdinfo:       true,
+	FeedbagClassIdCustomEmoticons:  true,
+	FeedbagClassIdXIcqStatusNote:   true,
+}
+
+// IsKnownFeedbagClass indicates whether classID is one of the FeedbagClassId
+// constants. Items of other classes, such as client-defined classes from
+// FeedbagClassIdMin up, are stored opaquely.
+func IsKnownFeedbagClass(classID uint16) bool {
+	return knownFeedbagClasses[classID]
+}
+
+// Alias returns the buddy's alias (FeedbagAttributesAlias).
+func (f *FeedbagItem) Alias() (string, bool) {
+	return f.String(FeedbagAttributesAlias)
+}
+
+// SetAlias sets the buddy's alias. An empty alias removes the attribute.
+func (f *FeedbagItem) SetAlias(alias string) error {
+	if len(alias) > FeedbagAliasMaxLen {
+		return fmt.Errorf("%w: alias longer than %d bytes", ErrInvalidFeedbagAttribute, FeedbagAliasMaxLen)
+	}
+	if strings.IndexFunc(alias, unicode.IsControl) >= 0 {
+		return fmt.Errorf("%w: alias contains control characters", ErrInvalidFeedbagAttribute)
+	}
+	f.setStringAttr(FeedbagAttributesAlias, alias)
+	return nil
+}
+
+// EmailAddr returns the buddy's email address (FeedbagAttributesEmailAddr).
+func (f *FeedbagItem) EmailAddr() (string, bool) {
+	return f.String(FeedbagAttributesEmailAddr)
+}
+
+// SetEmailAddr sets the buddy's email address. It must be a bare address
+// such as "user@example.com". An empty address removes the attribute.
+func (f *FeedbagItem) SetEmailAddr(addr string) error {
+	if addr != "" {
+		parsed, err := mail.ParseAddress(addr)
+		if err != nil || parsed.Address != addr {
+			return fmt.Errorf("%w: bad email address %q", ErrInvalidFeedbagAttribute, addr)
+		}
+	}
+	f.setStringAttr(FeedbagAttributesEmailAddr, addr)
+	return nil
+}
+
+// PhoneNumber returns the buddy's phone number (FeedbagAttributesPhoneNumber).
+func (f *FeedbagItem) PhoneNumber() (string, bool) {
+	return f.String(FeedbagAttributesPhoneNumber)
+}
+
+// SetPhoneNumber sets the buddy's phone number. Only digits, spaces and the
+// characters +-(). are allowed. An empty number removes the attribute.
+func (f *FeedbagItem) SetPhoneNumber(number string) error {
+	if len(number) > FeedbagPhoneNumberMaxLen {
+		return fmt.Errorf("%w: phone number longer than %d bytes", ErrInvalidFeedbagAttribute, FeedbagPhoneNumberMaxLen)
+	}
+	for _, r := range number {
+		if !unicode.IsDigit(r) && !strings.ContainsRune(" +-().", r) {
+			return fmt.Errorf("%w: phone number contains %q", ErrInvalidFeedbagAttribute, r)
+		}
+	}
+	f.setStringAttr(FeedbagAttributesPhoneNumber, number)
+	return nil
+}
+
+// Order returns the IDs listed in FeedbagAttributesOrder. For the root group
+// these are group IDs; for other groups they are buddy item IDs.
+func (f *FeedbagItem) Order() ([]uint16, bool) {
+	b, ok := f.Bytes(FeedbagAttributesOrder)
+	if !ok {
+		return nil, false
+	}
+	var order []uint16
+	if err := UnmarshalBE(&order, bytes.NewReader(b)); err != nil {
+		return nil, false
+	}
+	return order, true
+}
+
+// SetOrder sets the display order of a group's members. It may only be set on
+// group items and must not list the same ID twice.
+func (f *FeedbagItem) SetOrder(order []uint16) error {
+	if f.ClassID != FeedbagClassIdGroup {
+		return fmt.Errorf("%w: order is only valid on group items", ErrInvalidFeedbagAttribute)
+	}
+	seen := make(map[uint16]bool, len(order))
+	for _, id := range order {
+		if seen[id] {
+			return fmt.Errorf("%w: order lists ID %d more than once", ErrInvalidFeedbagAttribute, id)
+		}
+		seen[id] = true
+	}
+	if order == nil {
+		order = []uint16{}
+	}
+	f.setAttr(NewTLVBE(FeedbagAttributesOrder, order))
+	return nil
+}
+
+// BARTInfo returns the BART reference stored in FeedbagAttributesBartInfo.
+func (f *FeedbagItem) BARTInfo() (BARTInfo, bool) {
+	b, ok := f.Bytes(FeedbagAttributesBartInfo)
+	if !ok {
+		return BARTInfo{}, false
+	}
+	info := BARTInfo{}
+	if err := UnmarshalBE(&info, bytes.NewReader(b)); err != nil {
+		return BARTInfo{}, false
+	}
+	return info, true
+}
+
+// SetBARTInfo sets the BART reference of a BART item.
+func (f *FeedbagItem) SetBARTInfo(info BARTInfo) error {
+	if len(info.Hash) > math.MaxUint8 {
+		return fmt.Errorf("%w: BART hash longer than %d bytes", ErrInvalidFeedbagAttribute, math.MaxUint8)
+	}
+	f.setAttr(NewTLVBE(FeedbagAttributesBartInfo, info))
+	return nil
+}
+
+// PDMode returns the permit/deny mode of a PD info item.
+func (f *FeedbagItem) PDMode() (FeedbagPDMode, bool) {
+	mode, ok := f.Uint8(FeedbagAttributesPdMode)
+	return FeedbagPDMode(mode), ok
+}
+
+// SetPDMode sets the permit/deny mode of a PD info item.
+func (f *FeedbagItem) SetPDMode(mode FeedbagPDMode) error {
+	if mode < FeedbagPDModePermitAll || mode > FeedbagPDModePermitOnList {
+		return fmt.Errorf("%w: unknown PD mode %d", ErrInvalidFeedbagAttribute, mode)
+	}
+	f.setAttr(NewTLVBE(FeedbagAttributesPdMode, uint8(mode)))
+	return nil
+}
+
+// Shared indicates whether the group is a shared group
+// (FeedbagAttributesShared), either published by its owner or subscribed
+// to from another user's buddy list.
+func (f *FeedbagItem) Shared() bool {
+	return f.HasTag(FeedbagAttributesShared)
+}
+
+// SetShared marks the group as shared or removes the mark.
+func (f *FeedbagItem) SetShared(shared bool) {
+	if !shared {
+		f.removeAttr(FeedbagAttributesShared)
+		return
+	}
+	f.setAttr(NewTLVBE(FeedbagAttributesShared, []byte{}))
+}
+
+// setStringAttr sets a string attribute, removing it when val is empty.
+func (f *FeedbagItem) setStringAttr(tag uint16, val string) {
+	if val == "" {
+		f.removeAttr(tag)
+		return
+	}
+	f.setAttr(NewTLVBE(tag, val))
+}
+
+// setAttr replaces the attribute with the same tag or appends it if absent.
+func (f *FeedbagItem) setAttr(tlv TLV) {
+	if f.HasTag(tlv.Tag) {
+		f.Replace(tlv)
+	} else {
+		f.Append(tlv)
+	}
+}
+
+func (f *FeedbagItem) removeAttr(tag uint16) {
+	kept := f.TLVList[:0]
+	for _, tlv := range f.TLVList {
+		if tlv.Tag != tag {
+			kept = append(kept, tlv)
+		}
+	}
+	f.TLVList = kept
+}
@@ -0,0 +1,99 @@
+package wire
+
+import "slices"
+
+// FeedbagDiff holds the item operations that turn one feedbag into another.
+type FeedbagDiff struct {
+	// Insert holds items that are new, groups first.
+	Insert []FeedbagItem
+	// Update holds items whose name or attributes changed.
+	Update []FeedbagItem
+	// Delete holds items that were removed.
+	Delete []FeedbagItem
+}
+
+type feedbagKey struct {
+	groupID uint16
+	itemID  uint16
+}
+
+// DiffFeedbag computes the minimal set of insert, update and delete
+// operations that turn the old feedbag into the new one. Items are matched
+// by group ID and item ID. An item whose class changed is deleted and
+// re-inserted, since clients don't expect an update to change an item's
+// class.
+func DiffFeedbag(old, new []FeedbagItem) FeedbagDiff {
+	oldItems := make(map[feedbagKey]FeedbagItem, len(old))
+	for _, item := range old {
+		oldItems[feedbagKey{item.GroupID, item.ItemID}] = item
+	}
+
+	var diff FeedbagDiff
+	seen := make(map[feedbagKey]bool, len(new))
+	for _, item := range new {
+		key := feedbagKey{item.GroupID, item.ItemID}
+		seen[key] = true
+
+		prev, ok := oldItems[key]
+		switch {
+		case !ok:
+			diff.Insert = append(diff.Insert, item)
+		case prev.ClassID != item.ClassID:
+			diff.Delete = append(diff.Delete, prev)
+			diff.Insert = append(diff.Insert, item)
+		case prev.Name != item.Name || !prev.TLVList.Equal(item.TLVList):
+			diff.Update = append(diff.Update, item)
+		}
+	}
+
+	for _, item := range old {
+		if !seen[feedbagKey{item.GroupID, item.ItemID}] {
+			diff.Delete = append(diff.Delete, item)
+		}
+	}
+
+	// groups must exist before the items placed in them
+	slices.SortStableFunc(diff.Insert, func(a, b FeedbagItem) int {
+		aGroup, bGroup := a.ClassID == FeedbagClassIdGroup, b.ClassID == FeedbagClassIdGroup
+		switch {
+		case aGroup && !bGroup:
+			return -1
+		case !aGroup && bGroup:
+			return 1
+		}
+		return 0
+	})
+
+	return diff
+}
+
+// Empty indicates whether the diff contains no operations.
+func (d FeedbagDiff) Empty() bool {
+	return len(d.Insert) == 0 && len(d.Update) == 0 && len(d.Delete) == 0
+}
+
+// SNACs returns the FeedbagDeleteItem, FeedbagInsertItem and
+// FeedbagUpdateItem messages that apply the diff, in that order. Operations
+// with no items are omitted.
+func (d FeedbagDiff) SNACs() []SNACMessage {
+	var msgs []SNACMessage
+	if len(d.Delete) > 0 {
+		msgs = append(msgs, SNACMessage{
+			Frame: SNACFrame{FoodGroup: Feedbag, SubGroup: FeedbagDeleteItem},
+			Body:  SNAC_0x13_0x0A_FeedbagDeleteItem{Items: d.Delete},
+		})
+	}
+	if len(d.Insert) > 0 {
+		msgs = append(msgs, SNACMessage{
+			Frame: SNACFrame{FoodGroup: Feedbag, SubGroup: FeedbagInsertItem},
+			Body:  SNAC_0x13_0x08_FeedbagInsertItem{Items: d.Insert},
+		})
+	}
+	if len(d.Update) > 0 {
+		msgs = append(msgs, SNACMessage{
+			Frame: SNACFrame{FoodGroup: Feedbag, SubGroup: FeedbagUpdateItem},
+			Body:  SNAC_0x13_0x09_FeedbagUpdateItem{Items: d.Update},
+		})
+	}
+	return msgs
+}
@@ -0,0 +1,128 @@
+package wire
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffFeedbag(t *testing.T) {
+	group := func(groupID uint16, name string) FeedbagItem {
+		return FeedbagItem{GroupID: groupID, ClassID: FeedbagClassIdGroup, Name: name}
+	}
+	buddy := func(groupID, itemID uint16, name string, tlvs ...TLV) FeedbagItem {
+		return FeedbagItem{
+			GroupID:   groupID,
+			ItemID:    itemID,
+			ClassID:   FeedbagClassIdBuddy,
+			Name:      name,
+			TLVLBlock: TLVLBlock{TLVList: tlvs},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		old    []FeedbagItem
+		new    []FeedbagItem
+		expect FeedbagDiff
+	}{
+		{
+			name: "no changes",
+			old: []FeedbagItem{
+				group(1, "Buddies"),
+				buddy(1, 10, "alice", NewTLVBE(FeedbagAttributesAlias, "Al"), NewTLVBE(FeedbagAttributesNote, "hi")),
+			},
+			new: []FeedbagItem{
+				// attribute order doesn't matter
+				buddy(1, 10, "alice", NewTLVBE(FeedbagAttributesNote, "hi"), NewTLVBE(FeedbagAttributesAlias, "Al")),
+				group(1, "Buddies"),
+			},
+			expect: FeedbagDiff{},
+		},
+		{
+			name: "insert group and buddy, groups first",
+			old:  nil,
+			new: []FeedbagItem{
+				buddy(1, 10, "alice"),
+				group(1, "Buddies"),
+			},
+			expect: FeedbagDiff{
+				Insert: []FeedbagItem{group(1, "Buddies"), buddy(1, 10, "alice")},
+			},
+		},
+		{
+			name: "update name and attributes",
+			old: []FeedbagItem{
+				group(1, "Buddies"),
+				buddy(1, 10, "alice"),
+			},
+			new: []FeedbagItem{
+				group(1, "Friends"),
+				buddy(1, 10, "alice", NewTLVBE(FeedbagAttributesAlias, "Al")),
+			},
+			expect: FeedbagDiff{
+				Update: []FeedbagItem{group(1, "Friends"), buddy(1, 10, "alice", NewTLVBE(FeedbagAttributesAlias, "Al"))},
+			},
+		},
+		{
+			name: "delete removed items",
+			old: []FeedbagItem{
+				group(1, "Buddies"),
+				buddy(1, 10, "alice"),
+				buddy(1, 11, "bob"),
+			},
+			new: []FeedbagItem{
+				group(1, "Buddies"),
+				buddy(1, 10, "alice"),
+			},
+			expect: FeedbagDiff{
+				Delete: []FeedbagItem{buddy(1, 11, "bob")},
+			},
+		},
+		{
+			name: "class change is delete and insert",
+			old: []FeedbagItem{
+				buddy(0, 20, "carol"),
+			},
+			new: []FeedbagItem{
+				{ItemID: 20, ClassID: FeedbagClassIDDeny, Name: "carol"},
+			},
+			expect: FeedbagDiff{
+				Insert: []FeedbagItem{{ItemID: 20, ClassID: FeedbagClassIDDeny, Name: "carol"}},
+				Delete: []FeedbagItem{buddy(0, 20, "carol")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffFeedbag(tt.old, tt.new)
+			assert.Equal(t, tt.expect, diff)
+			assert.Equal(t, tt.expect.Empty(), diff.Empty())
+		})
+	}
+}
+
+func TestFeedbagDiff_SNACs(t *testing.T) {
+	assert.Empty(t, FeedbagDiff{}.SNACs())
+
+	diff := FeedbagDiff{
+		Insert: []FeedbagItem{{ItemID: 1}},
+		Update: []FeedbagItem{{ItemID: 2}},
+		Delete: []FeedbagItem{{ItemID: 3}},
+	}
+	assert.Equal(t, []SNACMessage{
+		{
+			Frame: SNACFrame{FoodGroup: Feedbag, SubGroup: FeedbagDeleteItem},
+			Body:  SNAC_0x13_0x0A_FeedbagDeleteItem{Items: diff.Delete},
+		},
+		{
+			Frame: SNACFrame{FoodGroup: Feedbag, SubGroup: FeedbagInsertItem},
+			Body:  SNAC_0x13_0x08_FeedbagInsertItem{Items: diff.Insert},
+		},
+		{
+			Frame: SNACFrame{FoodGroup: Feedbag, SubGroup: FeedbagUpdateItem},
+			Body:  SNAC_0x13_0x09_FeedbagUpdateItem{Items: diff.Update},
+		},
+	}, diff.SNACs())
+}
@@ -0,0 +1,224 @@
+package wire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeedbagItem_Alias(t *testing.T) {
+	item := FeedbagItem{ClassID: FeedbagClassIdBuddy, Name: "chattingchuck"}
+
+	_, ok := item.Alias()
+	assert.False(t, ok)
+
+	assert.NoError(t, item.SetAlias("Chuck"))
+	assert.NoError(t, item.SetAlias("Chuck C."))
+	alias, ok := item.Alias()
+	assert.True(t, ok)
+	assert.Equal(t, "Chuck C.", alias)
+	assert.Len(t, item.TLVList, 1)
+
+	assert.ErrorIs(t, item.SetAlias("bad\nalias"), ErrInvalidFeedbagAttribute)
+	assert.ErrorIs(t, item.SetAlias(string(make([]byte, FeedbagAliasMaxLen+1))), ErrInvalidFeedbagAttribute)
+
+	assert.NoError(t, item.SetAlias(""))
+	_, ok = item.Alias()
+	assert.False(t, ok)
+}
+
+func TestFeedbagItem_EmailAddr(t *testing.T) {
+	item := FeedbagItem{}
+
+	assert.NoError(t, item.SetEmailAddr("chuck@example.com"))
+	addr, ok := item.EmailAddr()
+	assert.True(t, ok)
+	assert.Equal(t, "chuck@example.com", addr)
+
+	assert.ErrorIs(t, item.SetEmailAddr("not an address"), ErrInvalidFeedbagAttribute)
+	assert.ErrorIs(t, item.SetEmailAddr("Chuck <chuck@example.com>"), ErrInvalidFeedbagAttribute)
+}
+
+func TestFeedbagItem_PhoneNumber(t *testing.T) {
+	item := FeedbagItem{}
+
+	assert.NoError(t, item.SetPhoneNumber("+1 (555) 123-4567"))
+	number, ok := item.PhoneNumber()
+	assert.True(t, ok)
+	assert.Equal(t, "+1 (555) 123-4567", number)
+
+	assert.ErrorIs(t, item.SetPhoneNumber("555-CALL-NOW"), ErrInvalidFeedbagAttribute)
+}
+
+func TestFeedbagItem_Order(t *testing.T) {
+	group := FeedbagItem{ClassID: FeedbagClassIdGroup, GroupID: 1}
+
+	assert.NoError(t, group.SetOrder([]uint16{3, 1, 2}))
+	order, ok := group.Order()
+	assert.True(t, ok)
+	assert.Equal(t, []uint16{3, 1, 2}, order)
+
+	assert.NoError(t, group.SetOrder(nil))
+	order, ok = group.Order()
+	assert.True(t, ok)
+	assert.Empty(t, order)
+
+	assert.ErrorIs(t, group.SetOrder([]uint16{1, 1}), ErrInvalidFeedbagAttribute)
+
+	buddy := FeedbagItem{ClassID: FeedbagClassIdBuddy}
+	assert.ErrorIs(t, buddy.SetOrder([]uint16{1}), ErrInvalidFeedbagAttribute)
+}
+
+func TestFeedbagItem_BARTInfo(t *testing.T) {
+	item := FeedbagItem{ClassID: FeedbagClassIdBart}
+
+	info := BARTInfo{Flags: BARTFlagsCustom, Hash: []byte{0x01, 0x02, 0x03}}
+	assert.NoError(t, item.SetBARTInfo(info))
+	have, ok := item.BARTInfo()
+	assert.True(t, ok)
+	assert.Equal(t, info, have)
+
+	assert.ErrorIs(t, item.SetBARTInfo(BARTInfo{Hash: make([]byte, 256)}), ErrInvalidFeedbagAttribute)
+}
+
+func TestFeedbagItem_PDMode(t *testing.T) {
+	item := FeedbagItem{ClassID: FeedbagClassIdPdinfo}
+
+	assert.NoError(t, item.SetPDMode(FeedbagPDModeDenySome))
+	mode, ok := item.PDMode()
+	assert.True(t, ok)
+	assert.Equal(t, FeedbagPDModeDenySome, mode)
+
+	assert.ErrorIs(t, item.SetPDMode(0), ErrInvalidFeedbagAttribute)
+	assert.ErrorIs(t, item.SetPDMode(6), ErrInvalidFeedbagAttribute)
+}
+
+func TestFeedbagItem_Shared(t *testing.T) {
+	item := FeedbagItem{ClassID: FeedbagClassIdGroup}
+	assert.False(t, item.Shared())
+
+	item.SetShared(true)
+	item.SetShared(true)
+	assert.True(t, item.Shared())
+	assert.Len(t, item.TLVList, 1)
+
+	item.SetShared(false)
+	assert.False(t, item.Shared())
+	assert.Empty(t, item.TLVList)
+}
+
+func TestFeedbagItem_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    FeedbagItem
+		wantErr error
+	}{
+		{
+			name: "valid buddy",
+			item: FeedbagItem{ClassID: FeedbagClassIdBuddy, Name: "chattingchuck"},
+		},
+		{
+			name: "valid root group",
+			item: FeedbagItem{ClassID: FeedbagClassIdGroup},
+		},
+		{
+			name: "client-defined class",
+			item: FeedbagItem{ClassID: FeedbagClassIdMin, Name: "custom"},
+		},
+		{
+			name: "ICQ status note",
+			item: FeedbagItem{ClassID: FeedbagClassIdXIcqStatusNote},
+		},
+		{
+			name:    "reserved class",
+			item:    FeedbagItem{ClassID: FeedbagClassIdMaxPredefined + 1, Name: "x"},
+			wantErr: ErrInvalidFeedbagItem,
+		},
+		{
+			name:    "name too long",
+			item:    FeedbagItem{ClassID: FeedbagClassIdGroup, Name: string(make([]byte, FeedbagItemNameMaxLen+1))},
+			wantErr: ErrInvalidFeedbagItem,
+		},
+		{
+			name: "attributes too long",
+			item: FeedbagItem{
+				ClassID: FeedbagClassIdBuddy,
+				Name:    "chattingchuck",
+				TLVLBlock: TLVLBlock{
+					TLVList: TLVList{NewTLVBE(FeedbagAttributesNote, make([]byte, FeedbagItemAttrsMaxLen))},
+				},
+			},
+			wantErr: ErrInvalidFeedbagItem,
+		},
+		{
+			name:    "buddy without screen name",
+			item:    FeedbagItem{ClassID: FeedbagClassIDDeny, Name: " "},
+			wantErr: ErrInvalidFeedbagItem,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.item.Validate(), tt.wantErr)
+		})
+	}
+}
+
+// FuzzFeedbagItem checks that no feedbag item makes the decoder or the
+// attribute accessors panic, and that decoded items survive a round trip.
+// Run with go test -fuzz=FuzzFeedbagItem ./wire.
+func FuzzFeedbagItem(f *testing.F) {
+	// items as stored by AIM 5.x, iChat and ICQ 2003b
+	buddy := FeedbagItem{ClassID: FeedbagClassIdBuddy, GroupID: 1, ItemID: 2, Name: "chattingchuck"}
+	require.NoError(f, buddy.SetAlias("Chuck"))
+	require.NoError(f, buddy.SetEmailAddr("chuck@example.com"))
+	group := FeedbagItem{ClassID: FeedbagClassIdGroup, GroupID: 1, Name: "Buddies"}
+	require.NoError(f, group.SetOrder([]uint16{2, 3, 4}))
+	root := FeedbagItem{ClassID: FeedbagClassIdGroup}
+	require.NoError(f, root.SetOrder([]uint16{1, 5}))
+	pdInfo := FeedbagItem{ClassID: FeedbagClassIdPdinfo, ItemID: 6}
+	require.NoError(f, pdInfo.SetPDMode(FeedbagPDModeDenySome))
+	icon := FeedbagItem{ClassID: FeedbagClassIdBart, ItemID: 7, Name: "1"}
+	require.NoError(f, icon.SetBARTInfo(BARTInfo{Flags: BARTFlagsCustom, Hash: bytes.Repeat([]byte{0xAB}, 16)}))
+	pending := FeedbagItem{ClassID: FeedbagClassIdBuddy, GroupID: 1, ItemID: 8, Name: "100004"}
+	pending.Append(NewTLVBE(FeedbagAttributesPending, []byte{}))
+
+	for _, item := range []FeedbagItem{buddy, group, root, pdInfo, icon, pending} {
+		b := &bytes.Buffer{}
+		require.NoError(f, MarshalBE(item, b))
+		f.Add(b.Bytes())
+	}
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		item := FeedbagItem{}
+		if err := UnmarshalBE(&item, bytes.NewReader(b)); err != nil {
+			return
+		}
+
+		_, _ = item.Alias()
+		_, _ = item.EmailAddr()
+		_, _ = item.PhoneNumber()
+		_, _ = item.Order()
+		_, _ = item.BARTInfo()
+		_, _ = item.PDMode()
+		_ = item.Validate()
+
+		out := &bytes.Buffer{}
+		require.NoError(t, MarshalBE(item, out))
+		again := FeedbagItem{}
+		require.NoError(t, UnmarshalBEStrict(&again, out.Bytes()))
+		assert.Equal(t, item.ClassID, again.ClassID)
+		assert.Equal(t, item.Name, again.Name)
+		assert.Equal(t, len(item.TLVList), len(again.TLVList))
+	})
+}
+
+func TestIsKnownFeedbagClass(t *testing.T) {
+	assert.True(t, IsKnownFeedbagClass(FeedbagClassIdBuddy))
+	assert.True(t, IsKnownFeedbagClass(FeedbagClassIdXIcqStatusNote))
+	assert.False(t, IsKnownFeedbagClass(0x000C))
+	assert.False(t, IsKnownFeedbagClass(FeedbagClassIdMin))
+}
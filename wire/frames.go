@@ -20,6 +20,17 @@ type SNACError struct {
 	Code uint16
 }
 
+// SNAC frame flags.
+const (
+	// SNACFlagMoreReplies is set on every SNAC of a multi-part reply except
+	// the last, telling the client that more replies to the same request
+	// follow.
+	SNACFlagMoreReplies uint16 = 0x0001
+	// SNACFlagOptionalTLVs indicates that the SNAC body is preceded by a
+	// length-prefixed block of optional TLVs.
+	SNACFlagOptionalTLVs uint16 = 0x8000
+)
+
 type SNACFrame struct {
 	FoodGroup uint16
 	SubGroup  uint16
@@ -27,6 +38,19 @@ type SNACFrame struct {
 	RequestID uint32
 }
 
+// MoreReplies indicates whether more replies to the same request follow
+// this one.
+func (s SNACFrame) MoreReplies() bool {
+	return s.Flags&SNACFlagMoreReplies == SNACFlagMoreReplies
+}
+
+// String returns the food group and subgroup names of the frame, followed by
+// their numeric values, e.g. "ICBM/ICBMChannelMsgToHost (0x0004/0x0006)".
+func (s SNACFrame) String() string {
+	return fmt.Sprintf("%s/%s (0x%04X/0x%04X)", FoodGroupName(s.FoodGroup),
+		SubGroupName(s.FoodGroup, s.SubGroup), s.FoodGroup, s.SubGroup)
+}
+
 type SNACMessage struct {
 	Frame SNACFrame
 	Body  any
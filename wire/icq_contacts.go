@@ -0,0 +1,74 @@
+package wire
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ICQContactsMaxCount is the maximum number of contacts accepted in a single
+// ICBMMsgTypeContacts message.
+const ICQContactsMaxCount = 100
+
+// icqFieldSep separates fields in 0xFE formatted ICQ messages.
+const icqFieldSep = "\xFE"
+
+var ErrInvalidICQContacts = errors.New("invalid ICQ contacts message")
+
+// ICQContact is a single entry in an ICBMMsgTypeContacts message.
+type ICQContact struct {
+	// ScreenName is the UIN or AIM screen name of the contact.
+	ScreenName string
+	// Nick is the nickname the sender has for the contact.
+	Nick string
+}
+
+// MarshalICQContacts encodes contacts as the 0xFE formatted text of an
+// ICBMMsgTypeContacts message, in the form:
+//
+//	count 0xFE sn1 0xFE nick1 0xFE ... snN 0xFE nickN 0xFE
+func MarshalICQContacts(contacts []ICQContact) string {
+	sb := strings.Builder{}
+	sb.WriteString(strconv.Itoa(len(contacts)))
+	sb.WriteString(icqFieldSep)
+	for _, c := range contacts {
+		sb.WriteString(c.ScreenName)
+		sb.WriteString(icqFieldSep)
+		sb.WriteString(c.Nick)
+		sb.WriteString(icqFieldSep)
+	}
+	return sb.String()
+}
+
+// UnmarshalICQContacts decodes the 0xFE formatted text of an
+// ICBMMsgTypeContacts message. It returns ErrInvalidICQContacts if the
+// declared count doesn't match the entries, the count exceeds
+// ICQContactsMaxCount, or a contact has an empty screen name.
+func UnmarshalICQContacts(text string) ([]ICQContact, error) {
+	fields := strings.Split(strings.TrimSuffix(text, icqFieldSep), icqFieldSep)
+
+	count, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad contact count %q", ErrInvalidICQContacts, fields[0])
+	}
+	if count < 0 || count > ICQContactsMaxCount {
+		return nil, fmt.Errorf("%w: contact count %d out of range", ErrInvalidICQContacts, count)
+	}
+
+	fields = fields[1:]
+	if len(fields) != count*2 {
+		return nil, fmt.Errorf("%w: expected %d contacts, got %d fields", ErrInvalidICQContacts, count, len(fields))
+	}
+
+	contacts := make([]ICQContact, 0, count)
+	for i := 0; i < len(fields); i += 2 {
+		sn := strings.TrimSpace(fields[i])
+		if sn == "" {
+			return nil, fmt.Errorf("%w: contact %d has no screen name", ErrInvalidICQContacts, i/2)
+		}
+		contacts = append(contacts, ICQContact{ScreenName: sn, Nick: fields[i+1]})
+	}
+
+	return contacts, nil
+}
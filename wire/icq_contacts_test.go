@@ -0,0 +1,72 @@
+package wire
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalICQContacts(t *testing.T) {
+	tests := []struct {
+		name    string
+		given   string
+		want    []ICQContact
+		wantErr error
+	}{
+		{
+			name:  "two contacts",
+			given: "2\xFE100003\xFEbob\xFEchattingchuck\xFEchuck\xFE",
+			want: []ICQContact{
+				{ScreenName: "100003", Nick: "bob"},
+				{ScreenName: "chattingchuck", Nick: "chuck"},
+			},
+		},
+		{
+			name:  "no contacts",
+			given: "0\xFE",
+			want:  []ICQContact{},
+		},
+		{
+			name:    "count mismatch",
+			given:   "3\xFE100003\xFEbob\xFE",
+			wantErr: ErrInvalidICQContacts,
+		},
+		{
+			name:    "non-numeric count",
+			given:   "two\xFE100003\xFEbob\xFE",
+			wantErr: ErrInvalidICQContacts,
+		},
+		{
+			name:    "too many contacts",
+			given:   "101\xFE",
+			wantErr: ErrInvalidICQContacts,
+		},
+		{
+			name:    "empty screen name",
+			given:   "1\xFE\xFEbob\xFE",
+			wantErr: ErrInvalidICQContacts,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := UnmarshalICQContacts(tt.given)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, have)
+		})
+	}
+}
+
+func TestMarshalICQContacts(t *testing.T) {
+	contacts := []ICQContact{
+		{ScreenName: "100003", Nick: "bob"},
+		{ScreenName: "chattingchuck", Nick: ""},
+	}
+
+	text := MarshalICQContacts(contacts)
+	assert.Equal(t, "2\xFE100003\xFEbob\xFEchattingchuck\xFE\xFE", text)
+
+	have, err := UnmarshalICQContacts(text)
+	assert.NoError(t, err)
+	assert.Equal(t, contacts, have)
+}
@@ -0,0 +1,59 @@
+package wire
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// icqMetaRequestBodies creates the body of each ICQDBQueryMetaReq subtype
+// that carries one.
+var icqMetaRequestBodies = map[uint16]func() any{
+	ICQDBQueryMetaReqSetBasicInfo:      func() any { return &ICQ_0x07D0_0x03EA_DBQueryMetaReqSetBasicInfo{} },
+	ICQDBQueryMetaReqSetWorkInfo:       func() any { return &ICQ_0x07D0_0x03F3_DBQueryMetaReqSetWorkInfo{} },
+	ICQDBQueryMetaReqSetMoreInfo:       func() any { return &ICQ_0x07D0_0x03FD_DBQueryMetaReqSetMoreInfo{} },
+	ICQDBQueryMetaReqSetNotes:          func() any { return &ICQ_0x07D0_0x0406_DBQueryMetaReqSetNotes{} },
+	ICQDBQueryMetaReqSetEmails:         func() any { return &ICQ_0x07D0_0x040B_DBQueryMetaReqSetEmails{} },
+	ICQDBQueryMetaReqSetInterests:      func() any { return &ICQ_0x07D0_0x0410_DBQueryMetaReqSetInterests{} },
+	ICQDBQueryMetaReqSetAffiliations:   func() any { return &ICQ_0x07D0_0x041A_DBQueryMetaReqSetAffiliations{} },
+	ICQDBQueryMetaReqSetPermissions:    func() any { return &ICQ_0x07D0_0x0424_DBQueryMetaReqSetPermissions{} },
+	ICQDBQueryMetaReqShortInfo:         func() any { return &ICQ_0x07D0_0x04BA_DBQueryMetaReqShortInfo{} },
+	ICQDBQueryMetaReqSearchByDetails:   func() any { return &ICQ_0x07D0_0x0515_DBQueryMetaReqSearchByDetails{} },
+	ICQDBQueryMetaReqSearchByUIN:       func() any { return &ICQ_0x07D0_0x051F_DBQueryMetaReqSearchByUIN{} },
+	ICQDBQueryMetaReqSearchByEmail:     func() any { return &ICQ_0x07D0_0x0529_DBQueryMetaReqSearchByEmail{} },
+	ICQDBQueryMetaReqSearchWhitePages:  func() any { return &ICQ_0x07D0_0x0533_DBQueryMetaReqSearchWhitePages{} },
+	ICQDBQueryMetaReqSearchWhitePages2: func() any { return &ICQ_0x07D0_0x055F_DBQueryMetaReqSearchWhitePages2{} },
+	ICQDBQueryMetaReqSearchByUIN2:      func() any { return &ICQ_0x07D0_0x0569_DBQueryMetaReqSearchByUIN2{} },
+	ICQDBQueryMetaReqSearchByEmail3:    func() any { return &ICQ_0x07D0_0x0573_DBQueryMetaReqSearchByEmail3{} },
+	ICQDBQueryMetaReqXMLReq:            func() any { return &ICQ_0x07D0_0x0898_DBQueryMetaReqXMLReq{} },
+}
+
+// UnmarshalICQMetaRequest decodes the little-endian ICQMessageRequestEnvelope
+// carried by a SNAC(0x15,0x02) ICQDBQuery. It returns the request header
+// and, for ICQDBQueryMetaReq subtypes that have a body, a pointer to the
+// decoded body, such as *ICQ_0x07D0_0x04BA_DBQueryMetaReqShortInfo. The body
+// is nil for other requests, which handlers dispatch on the header alone.
+func UnmarshalICQMetaRequest(b []byte) (ICQMetadataWithSubType, any, error) {
+	env := ICQMessageRequestEnvelope{}
+	if err := UnmarshalLE(&env, bytes.NewReader(b)); err != nil {
+		return ICQMetadataWithSubType{}, nil, err
+	}
+
+	r := bytes.NewReader(env.Body)
+	md := ICQMetadataWithSubType{}
+	if err := UnmarshalLE(&md, r); err != nil {
+		return ICQMetadataWithSubType{}, nil, err
+	}
+	if md.ReqType != ICQDBQueryMetaReq || md.Optional == nil {
+		return md, nil, nil
+	}
+
+	newBody, ok := icqMetaRequestBodies[md.Optional.ReqSubType]
+	if !ok {
+		return md, nil, nil
+	}
+	body := newBody()
+	if err := UnmarshalLE(body, r); err != nil {
+		return md, nil, fmt.Errorf("meta request 0x%04x: %w", md.Optional.ReqSubType, err)
+	}
+	return md, body, nil
+}
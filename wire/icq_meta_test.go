@@ -0,0 +1,134 @@
+package wire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// icqMetaRequest encodes an ICQ meta request as carried by a
+// SNAC(0x15,0x02) ICQDBQuery.
+func icqMetaRequest(t testing.TB, uin uint32, seq uint16, subType uint16, body any) []byte {
+	t.Helper()
+	msg := &bytes.Buffer{}
+	md := ICQMetadataWithSubType{
+		ICQMetadata: ICQMetadata{UIN: uin, Seq: seq, ReqType: ICQDBQueryMetaReq},
+		Optional:    &struct{ ReqSubType uint16 }{ReqSubType: subType},
+	}
+	require.NoError(t, MarshalLE(md, msg))
+	if body != nil {
+		require.NoError(t, MarshalLE(body, msg))
+	}
+	b := &bytes.Buffer{}
+	require.NoError(t, MarshalLE(ICQMessageRequestEnvelope{Body: msg.Bytes()}, b))
+	return b.Bytes()
+}
+
+func TestUnmarshalICQMetaRequest(t *testing.T) {
+	t.Run("meta request with body", func(t *testing.T) {
+		b := icqMetaRequest(t, 100003, 2, ICQDBQueryMetaReqSearchByEmail, ICQ_0x07D0_0x0529_DBQueryMetaReqSearchByEmail{
+			Email: "chuck@example.com",
+		})
+		md, body, err := UnmarshalICQMetaRequest(b)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(100003), md.UIN)
+		assert.Equal(t, ICQDBQueryMetaReq, md.ReqType)
+		assert.Equal(t, ICQDBQueryMetaReqSearchByEmail, md.Optional.ReqSubType)
+		assert.Equal(t, &ICQ_0x07D0_0x0529_DBQueryMetaReqSearchByEmail{Email: "chuck@example.com"}, body)
+	})
+
+	t.Run("meta request without body type", func(t *testing.T) {
+		md, body, err := UnmarshalICQMetaRequest(icqMetaRequest(t, 100003, 3, ICQDBQueryMetaReqStat0758, nil))
+		require.NoError(t, err)
+		assert.Equal(t, ICQDBQueryMetaReqStat0758, md.Optional.ReqSubType)
+		assert.Nil(t, body)
+	})
+
+	t.Run("offline message request", func(t *testing.T) {
+		msg := &bytes.Buffer{}
+		require.NoError(t, MarshalLE(ICQMetadata{UIN: 100003, ReqType: ICQDBQueryOfflineMsgReq}, msg))
+		b := &bytes.Buffer{}
+		require.NoError(t, MarshalLE(ICQMessageRequestEnvelope{Body: msg.Bytes()}, b))
+
+		md, body, err := UnmarshalICQMetaRequest(b.Bytes())
+		require.NoError(t, err)
+		assert.Equal(t, ICQDBQueryOfflineMsgReq, md.ReqType)
+		assert.Nil(t, md.Optional)
+		assert.Nil(t, body)
+	})
+
+	t.Run("non-meta request with trailing bytes", func(t *testing.T) {
+		msg := &bytes.Buffer{}
+		require.NoError(t, MarshalLE(ICQMetadata{UIN: 100003, ReqType: ICQDBQueryOfflineMsgReq}, msg))
+		msg.Write([]byte{0x01, 0x02})
+		b := &bytes.Buffer{}
+		require.NoError(t, MarshalLE(ICQMessageRequestEnvelope{Body: msg.Bytes()}, b))
+
+		md, body, err := UnmarshalICQMetaRequest(b.Bytes())
+		require.NoError(t, err)
+		assert.Nil(t, md.Optional)
+		assert.Nil(t, body)
+	})
+
+	t.Run("truncated body", func(t *testing.T) {
+		b := icqMetaRequest(t, 100003, 4, ICQDBQueryMetaReqShortInfo, []byte{0x01, 0x02})
+		_, _, err := UnmarshalICQMetaRequest(b)
+		assert.ErrorIs(t, err, ErrUnmarshalFailure)
+	})
+}
+
+// FuzzUnmarshalICQMetaRequest checks that no ICQ meta request makes the
+// parser panic. Run with go test -fuzz=FuzzUnmarshalICQMetaRequest ./wire.
+func FuzzUnmarshalICQMetaRequest(f *testing.F) {
+	// requests as sent by ICQ 2000b through ICQ 2003b and ICQ Lite
+	f.Add(icqMetaRequest(f, 100003, 1, ICQDBQueryMetaReqShortInfo, ICQ_0x07D0_0x04BA_DBQueryMetaReqShortInfo{UIN: 100004}))
+	f.Add(icqMetaRequest(f, 100003, 2, ICQDBQueryMetaReqSetBasicInfo, ICQ_0x07D0_0x03EA_DBQueryMetaReqSetBasicInfo{
+		Nickname:     "chuck",
+		FirstName:    "Chuck",
+		LastName:     "Chatter",
+		EmailAddress: "chuck@example.com",
+		City:         "Dulles",
+		State:        "VA",
+		CountryCode:  1,
+		GMTOffset:    0xF6,
+	}))
+	f.Add(icqMetaRequest(f, 100003, 3, ICQDBQueryMetaReqSetEmails, ICQ_0x07D0_0x040B_DBQueryMetaReqSetEmails{
+		Emails: []struct {
+			Publish uint8
+			Email   string `oscar:"len_prefix=uint16,nullterm"`
+		}{{Publish: 1, Email: "chuck@example.com"}, {Email: "cc@example.net"}},
+	}))
+	f.Add(icqMetaRequest(f, 100003, 4, ICQDBQueryMetaReqSetInterests, ICQ_0x07D0_0x0410_DBQueryMetaReqSetInterests{
+		Interests: []struct {
+			Code    uint16
+			Keyword string `oscar:"len_prefix=uint16,nullterm"`
+		}{{Code: 100, Keyword: "Computers"}},
+	}))
+	f.Add(icqMetaRequest(f, 100003, 5, ICQDBQueryMetaReqSearchWhitePages, ICQ_0x07D0_0x0533_DBQueryMetaReqSearchWhitePages{
+		FirstName: "Chuck",
+		MinAge:    18,
+		MaxAge:    99,
+	}))
+	f.Add(icqMetaRequest(f, 100003, 6, ICQDBQueryMetaReqSearchByUIN2, ICQ_0x07D0_0x0569_DBQueryMetaReqSearchByUIN2{
+		TLVRestBlock: TLVRestBlock{TLVList: TLVList{NewTLVLE(0x0136, uint32(100004))}},
+	}))
+	f.Add(icqMetaRequest(f, 100003, 7, ICQDBQueryMetaReqXMLReq, ICQ_0x07D0_0x0898_DBQueryMetaReqXMLReq{
+		XMLRequest: "<key>DataFilesIP</key>",
+	}))
+	f.Add([]byte{0x08, 0x00, 0xA3, 0x86, 0x01, 0x00, 0x00, 0x00, 0x3C, 0x00})
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		md, body, err := UnmarshalICQMetaRequest(b)
+		if err != nil {
+			return
+		}
+		if body != nil {
+			assert.Equal(t, ICQDBQueryMetaReq, md.ReqType)
+		}
+		// a decoded header must encode again
+		assert.NoError(t, MarshalLE(md, &bytes.Buffer{}))
+	})
+}
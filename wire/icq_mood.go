@@ -0,0 +1,57 @@
+package wire
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// icqMoodPrefix precedes the mood number in the hash of a BARTTypesICQMood
+// BART ID, as in "icqmood12".
+const icqMoodPrefix = "icqmood"
+
+// NewICQMoodBARTID returns the BART ID that carries an ICQ 6 / Xtraz mood
+// in TLV OServiceUserInfoBARTInfo. Clients such as ICQ 6 and QIP show the
+// mood as an icon next to the contact.
+func NewICQMoodBARTID(mood uint8) BARTID {
+	return BARTID{
+		Type: BARTTypesICQMood,
+		BARTInfo: BARTInfo{
+			Flags: BARTFlagsKnown,
+			Hash:  []byte(icqMoodPrefix + strconv.Itoa(int(mood))),
+		},
+	}
+}
+
+// ICQMood returns the mood number carried by a BARTTypesICQMood BART ID.
+// It returns false if the BART ID is of another type or clears the mood,
+// which clients do by sending an empty hash.
+func (b BARTID) ICQMood() (uint8, bool) {
+	if b.Type != BARTTypesICQMood {
+		return 0, false
+	}
+	num, ok := strings.CutPrefix(string(b.Hash), icqMoodPrefix)
+	if !ok {
+		return 0, false
+	}
+	mood, err := strconv.ParseUint(num, 10, 8)
+	if err != nil {
+		return 0, false
+	}
+	return uint8(mood), true
+}
+
+// UnmarshalBARTIDs parses the list of BART IDs in TLV
+// OServiceUserInfoBARTInfo.
+func UnmarshalBARTIDs(b []byte) ([]BARTID, error) {
+	var ids []BARTID
+	r := bytes.NewReader(b)
+	for r.Len() > 0 {
+		id := BARTID{}
+		if err := UnmarshalBE(&id, r); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
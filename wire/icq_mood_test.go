@@ -0,0 +1,38 @@
+package wire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBARTID_ICQMood(t *testing.T) {
+	mood, ok := NewICQMoodBARTID(23).ICQMood()
+	assert.True(t, ok)
+	assert.Equal(t, uint8(23), mood)
+
+	_, ok = BARTID{Type: BARTTypesICQMood}.ICQMood()
+	assert.False(t, ok)
+	_, ok = BARTID{Type: BARTTypesICQMood, BARTInfo: BARTInfo{Hash: []byte("icqmood999")}}.ICQMood()
+	assert.False(t, ok)
+	_, ok = BARTID{Type: BARTTypesBuddyIcon, BARTInfo: BARTInfo{Hash: []byte("icqmood1")}}.ICQMood()
+	assert.False(t, ok)
+}
+
+func TestUnmarshalBARTIDs(t *testing.T) {
+	ids := []BARTID{
+		{Type: BARTTypesBuddyIcon, BARTInfo: BARTInfo{Flags: BARTFlagsCustom, Hash: []byte{1, 2, 3, 4}}},
+		NewICQMoodBARTID(5),
+	}
+	buf := &bytes.Buffer{}
+	require.NoError(t, MarshalBE(ids, buf))
+
+	got, err := UnmarshalBARTIDs(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, ids, got)
+
+	_, err = UnmarshalBARTIDs(buf.Bytes()[:buf.Len()-1])
+	assert.Error(t, err)
+}
@@ -0,0 +1,168 @@
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+var (
+	// CapICQServerRelay is the capability advertised by ICQ clients that
+	// accept type-2 extended messages relayed through the server, which is
+	// how plugin messages are carried.
+	CapICQServerRelay = [16]byte{
+		0x09, 0x46, 0x13, 0x49, 0x4C, 0x7F, 0x11, 0xD1,
+		0x82, 0x22, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00,
+	}
+
+	ICQPluginGUIDMessage = [16]byte{
+		0xBE, 0x6B, 0x73, 0x05, 0x0F, 0xC2, 0x10, 0x4F,
+		0xA6, 0xDE, 0x4D, 0xB1, 0xE3, 0x56, 0x4B, 0x0E,
+	}
+	ICQPluginGUIDStatusMsgExt = [16]byte{
+		0x81, 0x1A, 0x18, 0xBC, 0x0E, 0x6C, 0x18, 0x47,
+		0xA5, 0x91, 0x6F, 0x18, 0xDC, 0xC7, 0x6F, 0x1A,
+	}
+	ICQPluginGUIDFile = [16]byte{
+		0xF0, 0x2D, 0x12, 0xD9, 0x30, 0x91, 0xD3, 0x11,
+		0x8D, 0xD7, 0x00, 0x10, 0x4B, 0x06, 0x46, 0x2E,
+	}
+	ICQPluginGUIDWebURL = [16]byte{
+		0x37, 0x1C, 0x58, 0x72, 0xE9, 0x87, 0xD4, 0x11,
+		0xA4, 0xC1, 0x00, 0xD0, 0xB7, 0x59, 0xB1, 0xD9,
+	}
+	ICQPluginGUIDContacts = [16]byte{
+		0x2A, 0x0E, 0x7D, 0x46, 0x76, 0x76, 0xD4, 0x11,
+		0xBC, 0xE6, 0x00, 0x04, 0xAC, 0x96, 0x1E, 0xA6,
+	}
+	ICQPluginGUIDGreetingCard = [16]byte{
+		0x01, 0xE5, 0x3B, 0x48, 0x2A, 0xE4, 0xD1, 0x11,
+		0xB6, 0x79, 0x00, 0x60, 0x97, 0xE1, 0xE2, 0x94,
+	}
+	ICQPluginGUIDChat = [16]byte{
+		0xBF, 0xF7, 0x20, 0xB2, 0x37, 0x8E, 0xD4, 0x11,
+		0xBD, 0x28, 0x00, 0x04, 0xAC, 0x96, 0xD9, 0x05,
+	}
+	ICQPluginGUIDXtrazScript = [16]byte{
+		0x3B, 0x60, 0xB3, 0xEF, 0xD8, 0x2A, 0x6C, 0x45,
+		0xA4, 0xE0, 0x9C, 0x5A, 0x5E, 0x67, 0xE8, 0x65,
+	}
+)
+
+// icqPlugins maps known plugin GUIDs to a human-readable plugin name.
+var icqPlugins = map[[16]byte]string{
+	ICQPluginGUIDMessage:      "Message",
+	ICQPluginGUIDStatusMsgExt: "StatusMsgExt",
+	ICQPluginGUIDFile:         "File",
+	ICQPluginGUIDWebURL:       "WebURL",
+	ICQPluginGUIDContacts:     "Contacts",
+	ICQPluginGUIDGreetingCard: "GreetingCard",
+	ICQPluginGUIDChat:         "Chat",
+	ICQPluginGUIDXtrazScript:  "XtrazScript",
+}
+
+var (
+	ErrICQPluginUnknown       = errors.New("unknown ICQ plugin")
+	ErrICQPluginNotSupported  = errors.New("recipient does not support ICQ plugin messages")
+	ErrICQPluginNotPluginType = errors.New("ICQ message is not a plugin message")
+)
+
+// KnownICQPlugin returns the name of the plugin identified by guid and
+// whether the plugin is known to the server.
+func KnownICQPlugin(guid [16]byte) (string, bool) {
+	name, ok := icqPlugins[guid]
+	return name, ok
+}
+
+// ICQServerRelayMessage is the little-endian ICQ extended message carried in
+// TLV ICBMRdvTLVTagsSvcData of a channel 2 rendezvous sent with the
+// CapICQServerRelay capability.
+type ICQServerRelayMessage struct {
+	Header1 struct {
+		Version     uint16
+		PluginGUID  [16]byte
+		Unknown1    uint16
+		ClientCaps  uint32
+		Unknown2    uint8
+		DownCounter uint16
+	} `oscar:"len_prefix=uint16"`
+	Header2 struct {
+		DownCounter uint16
+		Unknown     [12]byte
+	} `oscar:"len_prefix=uint16"`
+	MsgType  uint8
+	MsgFlags uint8
+	Status   uint16
+	Priority uint16
+	Message  string `oscar:"len_prefix=uint16,nullterm"`
+	// Rest is the message-type specific trailer. For ICBMMsgTypePlugin
+	// messages it contains an ICQPluginMessage.
+	Rest []byte
+}
+
+// ICQPluginMessage is the plugin section that trails an
+// ICBMMsgTypePlugin ICQServerRelayMessage.
+type ICQPluginMessage struct {
+	Header struct {
+		GUID       [16]byte
+		FunctionID uint16
+		Name       string `oscar:"len_prefix=uint32"`
+		Unknown    [15]byte
+	} `oscar:"len_prefix=uint16"`
+	// Data is the opaque plugin payload. It's relayed as-is between
+	// clients.
+	Data []byte `oscar:"len_prefix=uint32"`
+}
+
+// UnmarshalICQPluginMessage extracts the plugin section from an ICQ server
+// relay message. Param b is a slice from TLV ICBMRdvTLVTagsSvcData.
+func UnmarshalICQPluginMessage(b []byte) (ICQPluginMessage, error) {
+	relayMsg := ICQServerRelayMessage{}
+	if err := UnmarshalLE(&relayMsg, bytes.NewReader(b)); err != nil {
+		return ICQPluginMessage{}, fmt.Errorf("unable to unmarshal ICQ relay message: %w", err)
+	}
+	if relayMsg.MsgType != ICBMMsgTypePlugin {
+		return ICQPluginMessage{}, fmt.Errorf("%w: got message type 0x%02X", ErrICQPluginNotPluginType, relayMsg.MsgType)
+	}
+
+	pluginMsg := ICQPluginMessage{}
+	if err := UnmarshalLE(&pluginMsg, bytes.NewReader(relayMsg.Rest)); err != nil {
+		return ICQPluginMessage{}, fmt.Errorf("unable to unmarshal ICQ plugin message: %w", err)
+	}
+
+	return pluginMsg, nil
+}
+
+// CheckICQPluginRelay decides whether a channel 2 ICQ plugin message can be
+// relayed to a recipient advertising recipientCaps. The payload itself is
+// never modified; the server only inspects the plugin GUID. It returns
+// ErrICQPluginNotSupported if the recipient can't accept server-relayed ICQ
+// messages and ErrICQPluginUnknown if the plugin isn't in the registry. Both
+// errors map to ErrorCodeNotSupportedByClient.
+func CheckICQPluginRelay(frag ICBMCh2Fragment, recipientCaps [][16]byte) (ICQPluginMessage, error) {
+	if frag.Capability != CapICQServerRelay {
+		return ICQPluginMessage{}, fmt.Errorf("%w: fragment capability is not ICQ server relay", ErrICQPluginNotPluginType)
+	}
+
+	svcData, ok := frag.Bytes(ICBMRdvTLVTagsSvcData)
+	if !ok {
+		return ICQPluginMessage{}, fmt.Errorf("%w: missing service data TLV", ErrICQPluginNotPluginType)
+	}
+
+	pluginMsg, err := UnmarshalICQPluginMessage(svcData)
+	if err != nil {
+		return ICQPluginMessage{}, err
+	}
+
+	if _, known := KnownICQPlugin(pluginMsg.Header.GUID); !known {
+		return pluginMsg, fmt.Errorf("%w: %X", ErrICQPluginUnknown, pluginMsg.Header.GUID)
+	}
+
+	for _, c := range recipientCaps {
+		if c == CapICQServerRelay {
+			return pluginMsg, nil
+		}
+	}
+
+	return pluginMsg, ErrICQPluginNotSupported
+}
@@ -0,0 +1,107 @@
+package wire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newICQPluginFragment(t *testing.T, msgType uint8, guid [16]byte, data []byte) ICBMCh2Fragment {
+	pluginMsg := ICQPluginMessage{Data: data}
+	pluginMsg.Header.GUID = guid
+	pluginMsg.Header.Name = "plugin"
+	pluginBuf := &bytes.Buffer{}
+	assert.NoError(t, MarshalLE(pluginMsg, pluginBuf))
+
+	relayMsg := ICQServerRelayMessage{
+		MsgType: msgType,
+		Rest:    pluginBuf.Bytes(),
+	}
+	relayBuf := &bytes.Buffer{}
+	assert.NoError(t, MarshalLE(relayMsg, relayBuf))
+
+	return ICBMCh2Fragment{
+		Capability: CapICQServerRelay,
+		TLVRestBlock: TLVRestBlock{
+			TLVList: TLVList{
+				NewTLVBE(ICBMRdvTLVTagsSvcData, relayBuf.Bytes()),
+			},
+		},
+	}
+}
+
+func TestKnownICQPlugin(t *testing.T) {
+	name, ok := KnownICQPlugin(ICQPluginGUIDXtrazScript)
+	assert.True(t, ok)
+	assert.Equal(t, "XtrazScript", name)
+
+	_, ok = KnownICQPlugin([16]byte{})
+	assert.False(t, ok)
+}
+
+func TestCheckICQPluginRelay(t *testing.T) {
+	payload := []byte("<N><QUERY>...</QUERY></N>")
+
+	tests := []struct {
+		name     string
+		frag     ICBMCh2Fragment
+		caps     [][16]byte
+		wantData []byte
+		wantErr  error
+	}{
+		{
+			name:     "known plugin relayed to capable client",
+			frag:     newICQPluginFragment(t, ICBMMsgTypePlugin, ICQPluginGUIDXtrazScript, payload),
+			caps:     [][16]byte{{0x01}, CapICQServerRelay},
+			wantData: payload,
+		},
+		{
+			name:    "known plugin rejected for incapable client",
+			frag:    newICQPluginFragment(t, ICBMMsgTypePlugin, ICQPluginGUIDGreetingCard, payload),
+			caps:    [][16]byte{{0x01}},
+			wantErr: ErrICQPluginNotSupported,
+		},
+		{
+			name:    "unknown plugin rejected",
+			frag:    newICQPluginFragment(t, ICBMMsgTypePlugin, [16]byte{0xDE, 0xAD}, payload),
+			caps:    [][16]byte{CapICQServerRelay},
+			wantErr: ErrICQPluginUnknown,
+		},
+		{
+			name:    "non-plugin message type",
+			frag:    newICQPluginFragment(t, ICBMMsgTypePlain, ICQPluginGUIDContacts, payload),
+			caps:    [][16]byte{CapICQServerRelay},
+			wantErr: ErrICQPluginNotPluginType,
+		},
+		{
+			name:    "fragment without ICQ relay capability",
+			frag:    ICBMCh2Fragment{Capability: [16]byte{0x01}},
+			caps:    [][16]byte{CapICQServerRelay},
+			wantErr: ErrICQPluginNotPluginType,
+		},
+		{
+			name: "truncated service data",
+			frag: ICBMCh2Fragment{
+				Capability: CapICQServerRelay,
+				TLVRestBlock: TLVRestBlock{
+					TLVList: TLVList{
+						NewTLVBE(ICBMRdvTLVTagsSvcData, []byte{0x1B}),
+					},
+				},
+			},
+			caps:    [][16]byte{CapICQServerRelay},
+			wantErr: ErrUnmarshalFailure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := CheckICQPluginRelay(tt.frag, tt.caps)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, tt.wantData, msg.Data)
+			}
+		})
+	}
+}
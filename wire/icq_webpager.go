@@ -0,0 +1,51 @@
+package wire
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// icqWebPagerFields is the number of fields in an ICBMMsgTypeWWP or
+// ICBMMsgTypeEExpress message.
+const icqWebPagerFields = 6
+
+var ErrInvalidICQWebPager = errors.New("invalid ICQ web pager message")
+
+// ICQWebPagerMessage is the content of an ICBMMsgTypeWWP (web pager) or
+// ICBMMsgTypeEExpress (email express) message, which are sent to ICQ users
+// by people who don't have an ICQ account.
+type ICQWebPagerMessage struct {
+	// Nick is the name the sender gave.
+	Nick string
+	// Email is the sender's email address.
+	Email string
+	// Text is the message body.
+	Text string
+}
+
+// MarshalICQWebPager encodes msg as the 0xFE formatted text of an
+// ICBMMsgTypeWWP or ICBMMsgTypeEExpress message, in the form:
+//
+//	nick 0xFE 0xFE 0xFE email 0xFE 3 0xFE text
+//
+// The empty fields and the constant 3 are unused by clients but must be
+// present for them to find the message text.
+func MarshalICQWebPager(msg ICQWebPagerMessage) string {
+	return strings.Join([]string{msg.Nick, "", "", msg.Email, "3", msg.Text}, icqFieldSep)
+}
+
+// UnmarshalICQWebPager decodes the 0xFE formatted text of an
+// ICBMMsgTypeWWP or ICBMMsgTypeEExpress message. The message text may itself
+// contain 0xFE bytes.
+func UnmarshalICQWebPager(text string) (ICQWebPagerMessage, error) {
+	fields := strings.SplitN(text, icqFieldSep, icqWebPagerFields)
+	if len(fields) != icqWebPagerFields {
+		return ICQWebPagerMessage{}, fmt.Errorf("%w: expected %d fields, got %d", ErrInvalidICQWebPager, icqWebPagerFields, len(fields))
+	}
+	return ICQWebPagerMessage{
+		Nick:  fields[0],
+		Email: fields[3],
+		Text:  fields[5],
+	}, nil
+}
@@ -0,0 +1,62 @@
+package wire
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalICQWebPager(t *testing.T) {
+	tests := []struct {
+		name    string
+		given   string
+		want    ICQWebPagerMessage
+		wantErr error
+	}{
+		{
+			name:  "web pager message",
+			given: "Bob\xFE\xFE\xFEbob@example.com\xFE3\xFEhello there",
+			want: ICQWebPagerMessage{
+				Nick:  "Bob",
+				Email: "bob@example.com",
+				Text:  "hello there",
+			},
+		},
+		{
+			name:  "text containing separator",
+			given: "Bob\xFE\xFE\xFEbob@example.com\xFE3\xFEa\xFEb",
+			want: ICQWebPagerMessage{
+				Nick:  "Bob",
+				Email: "bob@example.com",
+				Text:  "a\xFEb",
+			},
+		},
+		{
+			name:    "too few fields",
+			given:   "Bob\xFEbob@example.com\xFEhello",
+			wantErr: ErrInvalidICQWebPager,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := UnmarshalICQWebPager(tt.given)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, have)
+		})
+	}
+}
+
+func TestMarshalICQWebPager(t *testing.T) {
+	msg := ICQWebPagerMessage{
+		Nick:  "Bob",
+		Email: "bob@example.com",
+		Text:  "hello there",
+	}
+	text := MarshalICQWebPager(msg)
+	assert.Equal(t, "Bob\xFE\xFE\xFEbob@example.com\xFE3\xFEhello there", text)
+
+	have, err := UnmarshalICQWebPager(text)
+	assert.NoError(t, err)
+	assert.Equal(t, msg, have)
+}
@@ -0,0 +1,222 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// OFT (OSCAR File Transfer) header types.
+const (
+	OFTTypePrompt         uint16 = 0x0101 // sender offers a file
+	OFTTypeAck            uint16 = 0x0202 // receiver accepts the file
+	OFTTypeDone           uint16 = 0x0204 // receiver got the whole file
+	OFTTypeReceiverResume uint16 = 0x0205 // receiver asks to resume a partial file
+	OFTTypeSenderResume   uint16 = 0x0106 // sender agrees to resume
+	OFTTypeResumeAck      uint16 = 0x0207 // receiver acknowledges the resume offset
+)
+
+const (
+	// OFTHeaderMinLen is the length of an OFT header with the minimum
+	// 64-byte file name field.
+	OFTHeaderMinLen = 256
+	// oftFixedLen is the length of the OFT header fields that precede the
+	// file name.
+	oftFixedLen = 192
+	// oftFileNameMinLen is the minimum size of the null-padded file name
+	// field.
+	oftFileNameMinLen = OFTHeaderMinLen - oftFixedLen
+	// OFTChecksumInit is the checksum of zero bytes.
+	OFTChecksumInit uint32 = 0xFFFF0000
+	// OFTFlagsDone is set in headers sent once the transfer has completed.
+	OFTFlagsDone uint8 = 0x01
+	// OFTFlagsNegotiating is set in headers sent before the file data.
+	OFTFlagsNegotiating uint8 = 0x20
+)
+
+// oftMagic identifies OFT version 2 headers.
+var oftMagic = [4]byte{'O', 'F', 'T', '2'}
+
+// ErrInvalidOFTHeader indicates that an OFT header is malformed or its fields
+// are inconsistent.
+var ErrInvalidOFTHeader = errors.New("invalid OFT header")
+
+// OFTHeader is the header exchanged by peers, directly or through the ARS
+// proxy, before, during and after an OSCAR file transfer. All fields are
+// big-endian.
+type OFTHeader struct {
+	Magic       [4]byte
+	Length      uint16 // length of the whole header, including Magic
+	Type        uint16
+	Cookie      uint64 // the rendezvous cookie
+	Encryption  uint16
+	Compression uint16
+	TotalFiles  uint16
+	FilesLeft   uint16
+	TotalParts  uint16
+	PartsLeft   uint16
+	TotalSize   uint32
+	Size        uint32
+	ModTime     uint32
+	// Checksum is the OFTChecksum of the whole file.
+	Checksum uint32
+	// ResForkRecvChecksum is the checksum of the received part of the Mac
+	// resource fork.
+	ResForkRecvChecksum uint32
+	ResForkSize         uint32
+	CreateTime          uint32
+	ResForkChecksum     uint32
+	// BytesReceived is how much of the file the receiver already has when
+	// resuming.
+	BytesReceived uint32
+	// RecvChecksum is the OFTChecksum of the first BytesReceived bytes.
+	RecvChecksum uint32
+	IDString     [32]byte
+	Flags        uint8
+	NameOffset   uint8
+	SizeOffset   uint8
+	Dummy        [69]byte
+	MacFileInfo  [16]byte
+	Charset      uint16
+	Subcharset   uint16
+	// FileName is the null-padded file name field, at least 64 bytes long.
+	FileName []byte
+}
+
+// NewOFTHeader returns an OFT header of the given type with the constant
+// fields filled in the way AIM clients expect.
+func NewOFTHeader(typ uint16, cookie uint64, fileName string) OFTHeader {
+	h := OFTHeader{
+		Magic:      oftMagic,
+		Type:       typ,
+		Cookie:     cookie,
+		Flags:      OFTFlagsNegotiating,
+		NameOffset: 0x1C,
+		SizeOffset: 0x11,
+	}
+	copy(h.IDString[:], "Cool FileXfer")
+	h.SetFileName(fileName)
+	return h
+}
+
+// Name returns the file name without its null padding.
+func (h OFTHeader) Name() string {
+	name, _, _ := bytes.Cut(h.FileName, []byte{0})
+	return string(name)
+}
+
+// SetFileName sets the file name, null-padding the field to at least 64
+// bytes, and updates Length to match.
+func (h *OFTHeader) SetFileName(name string) {
+	h.FileName = make([]byte, max(len(name)+1, oftFileNameMinLen))
+	copy(h.FileName, name)
+	h.Length = uint16(oftFixedLen + len(h.FileName))
+}
+
+// Validate returns ErrInvalidOFTHeader if the header has the wrong magic or
+// length, an unknown type, or counters that contradict each other.
+func (h OFTHeader) Validate() error {
+	if h.Magic != oftMagic {
+		return fmt.Errorf("%w: bad magic %q", ErrInvalidOFTHeader, h.Magic[:])
+	}
+	if len(h.FileName) < oftFileNameMinLen || int(h.Length) != oftFixedLen+len(h.FileName) {
+		return fmt.Errorf("%w: length %d doesn't match %d byte file name", ErrInvalidOFTHeader, h.Length, len(h.FileName))
+	}
+	switch h.Type {
+	case OFTTypePrompt, OFTTypeAck, OFTTypeDone, OFTTypeReceiverResume, OFTTypeSenderResume, OFTTypeResumeAck:
+	default:
+		return fmt.Errorf("%w: unknown type %#04x", ErrInvalidOFTHeader, h.Type)
+	}
+	if h.FilesLeft > h.TotalFiles {
+		return fmt.Errorf("%w: %d of %d files left", ErrInvalidOFTHeader, h.FilesLeft, h.TotalFiles)
+	}
+	if h.PartsLeft > h.TotalParts {
+		return fmt.Errorf("%w: %d of %d parts left", ErrInvalidOFTHeader, h.PartsLeft, h.TotalParts)
+	}
+	if h.Size > h.TotalSize {
+		return fmt.Errorf("%w: file size %d exceeds total size %d", ErrInvalidOFTHeader, h.Size, h.TotalSize)
+	}
+	if h.BytesReceived > h.Size {
+		return fmt.Errorf("%w: %d bytes received of %d byte file", ErrInvalidOFTHeader, h.BytesReceived, h.Size)
+	}
+	return nil
+}
+
+// ReadOFTHeader reads one OFT header from r. Unlike UnmarshalBE, it stops at
+// the end of the header, so the file data that follows can be read from r.
+func ReadOFTHeader(r io.Reader) (OFTHeader, error) {
+	prefix := make([]byte, 6)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return OFTHeader{}, err
+	}
+	if !bytes.Equal(prefix[:4], oftMagic[:]) {
+		return OFTHeader{}, fmt.Errorf("%w: bad magic %q", ErrInvalidOFTHeader, prefix[:4])
+	}
+	length := binary.BigEndian.Uint16(prefix[4:])
+	if length < OFTHeaderMinLen {
+		return OFTHeader{}, fmt.Errorf("%w: length %d shorter than %d", ErrInvalidOFTHeader, length, OFTHeaderMinLen)
+	}
+
+	buf := make([]byte, length)
+	copy(buf, prefix)
+	if _, err := io.ReadFull(r, buf[len(prefix):]); err != nil {
+		return OFTHeader{}, err
+	}
+
+	h := OFTHeader{}
+	if err := UnmarshalBE(&h, bytes.NewReader(buf)); err != nil {
+		return OFTHeader{}, err
+	}
+	return h, nil
+}
+
+// OFTChecksum computes the checksum AIM clients use to verify transferred
+// files and to agree on resume offsets. It implements io.Writer so that
+// file data can be checksummed as it streams through.
+type OFTChecksum struct {
+	sum uint32
+	odd bool
+}
+
+// NewOFTChecksum returns a checksum of zero bytes, OFTChecksumInit.
+func NewOFTChecksum() *OFTChecksum {
+	return &OFTChecksum{sum: OFTChecksumInit}
+}
+
+// Write adds p to the checksum. It never returns an error.
+func (c *OFTChecksum) Write(p []byte) (int, error) {
+	sum := c.sum >> 16
+	for _, b := range p {
+		prev := sum
+		val := uint32(b)
+		// bytes at even offsets of the file are the high byte of a
+		// 16-bit word
+		if !c.odd {
+			val <<= 8
+		}
+		sum -= val
+		// borrow
+		if sum > prev {
+			sum--
+		}
+		c.odd = !c.odd
+	}
+	sum = (sum & 0xFFFF) + (sum >> 16)
+	sum = (sum & 0xFFFF) + (sum >> 16)
+	c.sum = sum << 16
+	return len(p), nil
+}
+
+// Sum32 returns the checksum of the data written so far.
+func (c *OFTChecksum) Sum32() uint32 {
+	return c.sum
+}
+
+// OFTChecksumBytes returns the OFT checksum of b.
+func OFTChecksumBytes(b []byte) uint32 {
+	c := NewOFTChecksum()
+	_, _ = c.Write(b)
+	return c.Sum32()
+}
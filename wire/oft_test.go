@@ -0,0 +1,131 @@
+package wire
+
+import (
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOFTHeader_RoundTrip(t *testing.T) {
+	h := NewOFTHeader(OFTTypePrompt, 0x1122334455667788, "vacation.jpg")
+	h.TotalFiles = 1
+	h.FilesLeft = 1
+	h.TotalParts = 1
+	h.PartsLeft = 1
+	h.TotalSize = 1024
+	h.Size = 1024
+	h.Checksum = 0xABCD0000
+	require.NoError(t, h.Validate())
+	assert.Equal(t, uint16(OFTHeaderMinLen), h.Length)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, MarshalBE(h, buf))
+	assert.Equal(t, OFTHeaderMinLen, buf.Len())
+
+	// file data following the header must be left unread
+	buf.WriteString("file data")
+
+	have, err := ReadOFTHeader(buf)
+	require.NoError(t, err)
+	assert.Equal(t, h, have)
+	assert.Equal(t, "vacation.jpg", have.Name())
+	assert.Equal(t, "file data", buf.String())
+}
+
+func TestOFTHeader_SetFileName(t *testing.T) {
+	h := NewOFTHeader(OFTTypePrompt, 1, "short.txt")
+	assert.Len(t, h.FileName, 64)
+
+	long := string(bytes.Repeat([]byte{'a'}, 100))
+	h.SetFileName(long)
+	assert.Len(t, h.FileName, 101)
+	assert.Equal(t, uint16(192+101), h.Length)
+	assert.Equal(t, long, h.Name())
+	assert.NoError(t, h.Validate())
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, MarshalBE(h, buf))
+	have, err := ReadOFTHeader(buf)
+	require.NoError(t, err)
+	assert.Equal(t, long, have.Name())
+}
+
+func TestOFTHeader_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(h *OFTHeader)
+	}{
+		{
+			name:   "bad magic",
+			modify: func(h *OFTHeader) { h.Magic = [4]byte{'O', 'F', 'T', '3'} },
+		},
+		{
+			name:   "length mismatch",
+			modify: func(h *OFTHeader) { h.Length = 300 },
+		},
+		{
+			name:   "unknown type",
+			modify: func(h *OFTHeader) { h.Type = 0x0999 },
+		},
+		{
+			name:   "more files left than total",
+			modify: func(h *OFTHeader) { h.FilesLeft = 2 },
+		},
+		{
+			name:   "file larger than total",
+			modify: func(h *OFTHeader) { h.Size = 2048 },
+		},
+		{
+			name:   "received more than file size",
+			modify: func(h *OFTHeader) { h.BytesReceived = 1025 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewOFTHeader(OFTTypeReceiverResume, 1, "file.txt")
+			h.TotalFiles, h.FilesLeft = 1, 1
+			h.TotalParts, h.PartsLeft = 1, 1
+			h.TotalSize, h.Size = 1024, 1024
+			require.NoError(t, h.Validate())
+
+			tt.modify(&h)
+			assert.ErrorIs(t, h.Validate(), ErrInvalidOFTHeader)
+		})
+	}
+}
+
+func TestReadOFTHeader_Invalid(t *testing.T) {
+	_, err := ReadOFTHeader(bytes.NewReader([]byte("OFT3\x01\x00")))
+	assert.ErrorIs(t, err, ErrInvalidOFTHeader)
+
+	_, err = ReadOFTHeader(bytes.NewReader([]byte("OFT2\x00\x10")))
+	assert.ErrorIs(t, err, ErrInvalidOFTHeader)
+
+	_, err = ReadOFTHeader(bytes.NewReader([]byte("OFT2\x01\x00short")))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestOFTChecksum(t *testing.T) {
+	assert.Equal(t, OFTChecksumInit, OFTChecksumBytes(nil))
+	assert.Equal(t, uint32(0xFFFE0000), OFTChecksumBytes([]byte{0x00, 0x01}))
+	assert.Equal(t, uint32(0xFEFF0000), OFTChecksumBytes([]byte{0x01}))
+
+	data := make([]byte, 10_000)
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := range data {
+		data[i] = byte(r.UintN(256))
+	}
+
+	// checksumming in odd-sized chunks gives the same result as one pass
+	c := NewOFTChecksum()
+	for chunk := range slices.Chunk(data, 333) {
+		_, _ = c.Write(chunk)
+	}
+	assert.Equal(t, OFTChecksumBytes(data), c.Sum32())
+}
@@ -0,0 +1,85 @@
+package wire
+
+import (
+	"iter"
+)
+
+// ReplyPageMaxLen is the default bound on the encoded length of the items in
+// each SNAC of a multi-part reply. It keeps each SNAC, with its headers,
+// within the 8 KiB receive buffers of older clients.
+const ReplyPageMaxLen = 7900
+
+// ReplyPages splits items into the pages of a multi-part reply to the
+// request identified by frame. Each page holds as many items as fit in
+// maxLen bytes, as measured by size, but at least one. It yields each page
+// with the frame to send it in: a copy of frame with SNACFlagMoreReplies
+// set on every page but the last. If items is empty, it yields a single
+// empty page so that the request still gets a reply.
+func ReplyPages[T any](frame SNACFrame, items []T, maxLen int, size func(T) int) iter.Seq2[SNACFrame, []T] {
+	return func(yield func(SNACFrame, []T) bool) {
+		start, pageLen := 0, 0
+		for i, item := range items {
+			itemLen := size(item)
+			if i > start && pageLen+itemLen > maxLen {
+				if !yield(moreReplies(frame, true), items[start:i]) {
+					return
+				}
+				start, pageLen = i, 0
+			}
+			pageLen += itemLen
+		}
+		yield(moreReplies(frame, false), items[start:])
+	}
+}
+
+// FeedbagReplyPages splits a feedbag reply into a multi-part reply whose
+// SNACs each hold up to ReplyPageMaxLen bytes of items. Every part carries
+// the version and last update time of reply.
+func FeedbagReplyPages(frame SNACFrame, reply SNAC_0x13_0x06_FeedbagReply) iter.Seq[SNACMessage] {
+	return func(yield func(SNACMessage) bool) {
+		for pageFrame, items := range ReplyPages(frame, reply.Items, ReplyPageMaxLen, encodedLen[FeedbagItem]) {
+			page := reply
+			page.Items = items
+			if !yield(SNACMessage{Frame: pageFrame, Body: page}) {
+				return
+			}
+		}
+	}
+}
+
+// InfoReplyPages splits a directory search reply into a multi-part reply
+// whose SNACs each hold up to ReplyPageMaxLen bytes of results. Every part
+// carries the status of reply.
+func InfoReplyPages(frame SNACFrame, reply SNAC_0x0F_0x03_InfoReply) iter.Seq[SNACMessage] {
+	return func(yield func(SNACMessage) bool) {
+		for pageFrame, results := range ReplyPages(frame, reply.Results.List, ReplyPageMaxLen, encodedLen[TLVBlock]) {
+			page := reply
+			page.Results.List = results
+			if !yield(SNACMessage{Frame: pageFrame, Body: page}) {
+				return
+			}
+		}
+	}
+}
+
+// moreReplies returns a copy of frame with SNACFlagMoreReplies set or
+// cleared.
+func moreReplies(frame SNACFrame, more bool) SNACFrame {
+	if more {
+		frame.Flags |= SNACFlagMoreReplies
+	} else {
+		frame.Flags &^= SNACFlagMoreReplies
+	}
+	return frame
+}
+
+// encodedLen returns the length of v marshalled in big-endian order. Values
+// that can't be marshalled are reported as 0, leaving the error to surface
+// when the reply is sent.
+func encodedLen[T any](v T) int {
+	b, err := MarshalBEAppend(v, nil)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
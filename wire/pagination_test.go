@@ -0,0 +1,118 @@
+package wire
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplyPages(t *testing.T) {
+	frame := SNACFrame{FoodGroup: Feedbag, SubGroup: FeedbagReply, RequestID: 42}
+	byteLen := func(s string) int { return len(s) }
+
+	tests := []struct {
+		name      string
+		items     []string
+		maxLen    int
+		wantPages [][]string
+	}{
+		{
+			name:      "no items",
+			items:     nil,
+			maxLen:    10,
+			wantPages: [][]string{nil},
+		},
+		{
+			name:      "single page",
+			items:     []string{"aaa", "bbb"},
+			maxLen:    10,
+			wantPages: [][]string{{"aaa", "bbb"}},
+		},
+		{
+			name:      "items split at the bound",
+			items:     []string{"aaaa", "bbbb", "cc", "dddd"},
+			maxLen:    8,
+			wantPages: [][]string{{"aaaa", "bbbb"}, {"cc", "dddd"}},
+		},
+		{
+			name:      "oversized item gets its own page",
+			items:     []string{"a", "bbbbbbbbbbbb", "c"},
+			maxLen:    8,
+			wantPages: [][]string{{"a"}, {"bbbbbbbbbbbb"}, {"c"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pages [][]string
+			var frames []SNACFrame
+			for f, page := range ReplyPages(frame, tt.items, tt.maxLen, byteLen) {
+				frames = append(frames, f)
+				pages = append(pages, page)
+			}
+			assert.Equal(t, tt.wantPages, pages)
+			for i, f := range frames {
+				assert.Equal(t, i < len(frames)-1, f.MoreReplies(), "page %d", i)
+				assert.Equal(t, frame.RequestID, f.RequestID)
+			}
+		})
+	}
+}
+
+func TestReplyPages_StopEarly(t *testing.T) {
+	items := []string{"aa", "bb", "cc"}
+	count := 0
+	for range ReplyPages(SNACFrame{}, items, 2, func(s string) int { return len(s) }) {
+		count++
+		break
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestFeedbagReplyPages(t *testing.T) {
+	reply := SNAC_0x13_0x06_FeedbagReply{Version: 0, LastUpdate: 1234}
+	for i := range 500 {
+		reply.Items = append(reply.Items, FeedbagItem{
+			ClassID: FeedbagClassIdBuddy,
+			GroupID: 1,
+			ItemID:  uint16(i + 1),
+			Name:    fmt.Sprintf("buddy%03d", i),
+		})
+	}
+
+	frame := SNACFrame{FoodGroup: Feedbag, SubGroup: FeedbagReply, RequestID: 7}
+	var items []FeedbagItem
+	var msgs []SNACMessage
+	for msg := range FeedbagReplyPages(frame, reply) {
+		msgs = append(msgs, msg)
+		page := msg.Body.(SNAC_0x13_0x06_FeedbagReply)
+		assert.Equal(t, reply.LastUpdate, page.LastUpdate)
+		items = append(items, page.Items...)
+
+		b, err := MarshalBEAppend(page, nil)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, len(b), ReplyPageMaxLen+7)
+	}
+
+	assert.Greater(t, len(msgs), 1)
+	assert.Equal(t, reply.Items, items)
+	for i, msg := range msgs {
+		assert.Equal(t, i < len(msgs)-1, msg.Frame.MoreReplies())
+	}
+}
+
+func TestInfoReplyPages(t *testing.T) {
+	reply := SNAC_0x0F_0x03_InfoReply{Status: 0x05}
+	reply.Results.List = []TLVBlock{
+		{TLVList: TLVList{NewTLVBE(ODirTLVFirstName, "alice")}},
+	}
+
+	var msgs []SNACMessage
+	for msg := range InfoReplyPages(SNACFrame{FoodGroup: ODir, SubGroup: ODirInfoReply}, reply) {
+		msgs = append(msgs, msg)
+	}
+
+	assert.Len(t, msgs, 1)
+	assert.False(t, msgs[0].Frame.MoreReplies())
+	assert.Equal(t, reply, msgs[0].Body)
+}
@@ -0,0 +1,323 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+)
+
+// File transfer rendezvous service data flags.
+const (
+	RdvFileTransferSingleFile    uint16 = 0x0001 // one file
+	RdvFileTransferMultipleFiles uint16 = 0x0002 // several files or a directory
+)
+
+// ErrInvalidRendezvous indicates that a rendezvous payload is malformed.
+var ErrInvalidRendezvous = errors.New("invalid rendezvous")
+
+// Rendezvous is a typed view of the ICBM channel 2 rendezvous fragment that
+// clients use to propose, accept and cancel peer connections such as file
+// transfers and direct IM, and to invite buddies to chat rooms. The zero
+// value of an optional field means its TLV is absent.
+type Rendezvous struct {
+	// Type is one of the ICBMRdvMessage* values.
+	Type uint16
+	// Cookie identifies the rendezvous conversation.
+	Cookie [8]byte
+	// Capability identifies the service, such as CapFileTransfer,
+	// CapDirectIM or CapChat.
+	Capability [16]byte
+	// SeqNum counts the proposals of the conversation, starting at 1.
+	SeqNum uint16
+	// CancelReason is one of the ICBMRdvCancelReasons* values. It is only
+	// set in ICBMRdvMessageCancel payloads.
+	CancelReason uint16
+	// RdvIP is the address proposed for the peer connection.
+	RdvIP netip.Addr
+	// RequesterIP is the address of the proposing client.
+	RequesterIP netip.Addr
+	// VerifiedIP is the address of the proposing client as seen by the
+	// server, which is the only party allowed to set it.
+	VerifiedIP netip.Addr
+	// Port is the port proposed for the peer connection.
+	Port uint16
+	// UseARS requests that the connection go through the rendezvous proxy.
+	UseARS bool
+	// RequestSecure requests that the connection use SSL.
+	RequestSecure bool
+	// RequestHostCheck requests that the server check the recipient's
+	// capabilities.
+	RequestHostCheck bool
+	// Invitation is the invitation text shown to the recipient.
+	Invitation        string
+	InviteMIMECharset string
+	InviteMIMELang    string
+	MaxProtoVersion   uint16
+	MinProtoVersion   uint16
+	DownloadURL       string
+	// SvcData is the service-specific data. File transfers carry a
+	// RdvFileTransfer and chat invitations an ICBMRoomInfo. Direct IM
+	// proposals carry none.
+	SvcData []byte
+	// Unknown holds the TLVs that Rendezvous doesn't model, such as those
+	// of newer clients, in the order they were received. Fragment sends
+	// them unchanged after the modelled TLVs, so that a server that
+	// rewrites a rendezvous doesn't drop features it doesn't know about.
+	Unknown TLVList
+}
+
+// rdvModelledTags are the tags of the TLVs that Rendezvous decodes into its
+// fields and that Fragment encodes from them.
+var rdvModelledTags = map[uint16]bool{
+	ICBMRdvTLVTagsSeqNum:            true,
+	ICBMRdvTLVTagsCancelReason:      true,
+	ICBMRdvTLVTagsRdvIP:             true,
+	ICBMRdvTLVTagsIPXOR:             true,
+	ICBMRdvTLVTagsRequesterIP:       true,
+	ICBMRdvTLVTagsVerifiedIP:        true,
+	ICBMRdvTLVTagsPort:              true,
+	ICBMRdvTLVTagsPortXOR:           true,
+	ICBMRdvTLVTagsUseARS:            true,
+	ICBMRdvTLVTagsRequestSecure:     true,
+	ICBMRdvTLVTagsRequestHostChk:    true,
+	ICBMRdvTLVTagsInvitation:        true,
+	ICBMRdvTLVTagsInviteMIMECharset: true,
+	ICBMRdvTLVTagsInviteMIMELang:    true,
+	ICBMRdvTLVTagsMaxProtoVersion:   true,
+	ICBMRdvTLVTagsMinProtoVersion:   true,
+	ICBMRdvTLVTagsDownloadURL:       true,
+	ICBMRdvTLVTagsSvcData:           true,
+}
+
+// UnmarshalRendezvous parses a rendezvous fragment. Param b is a slice from
+// TLV ICBMTLVData of a channel 2 ICBM. It returns ErrInvalidRendezvous if
+// the fragment can't be parsed, or if the IP or port TLVs don't match their
+// XOR check TLVs.
+func UnmarshalRendezvous(b []byte) (Rendezvous, error) {
+	frag := ICBMCh2Fragment{}
+	if err := UnmarshalBE(&frag, bytes.NewReader(b)); err != nil {
+		return Rendezvous{}, fmt.Errorf("%w: %w", ErrInvalidRendezvous, err)
+	}
+	return NewRendezvous(frag)
+}
+
+// NewRendezvous converts an ICBMCh2Fragment into a Rendezvous. See
+// UnmarshalRendezvous.
+func NewRendezvous(frag ICBMCh2Fragment) (Rendezvous, error) {
+	rdv := Rendezvous{
+		Type:       frag.Type,
+		Cookie:     frag.Cookie,
+		Capability: frag.Capability,
+	}
+
+	rdv.SeqNum, _ = frag.Uint16BE(ICBMRdvTLVTagsSeqNum)
+	rdv.CancelReason, _ = frag.Uint16BE(ICBMRdvTLVTagsCancelReason)
+	rdv.Port, _ = frag.Uint16BE(ICBMRdvTLVTagsPort)
+	rdv.MaxProtoVersion, _ = frag.Uint16BE(ICBMRdvTLVTagsMaxProtoVersion)
+	rdv.MinProtoVersion, _ = frag.Uint16BE(ICBMRdvTLVTagsMinProtoVersion)
+	rdv.Invitation, _ = frag.String(ICBMRdvTLVTagsInvitation)
+	rdv.InviteMIMECharset, _ = frag.String(ICBMRdvTLVTagsInviteMIMECharset)
+	rdv.InviteMIMELang, _ = frag.String(ICBMRdvTLVTagsInviteMIMELang)
+	rdv.DownloadURL, _ = frag.String(ICBMRdvTLVTagsDownloadURL)
+	rdv.SvcData, _ = frag.Bytes(ICBMRdvTLVTagsSvcData)
+	rdv.UseARS = frag.HasTag(ICBMRdvTLVTagsUseARS)
+	rdv.RequestSecure = frag.HasTag(ICBMRdvTLVTagsRequestSecure)
+	rdv.RequestHostCheck = frag.HasTag(ICBMRdvTLVTagsRequestHostChk)
+
+	if ip, ok := frag.Uint32BE(ICBMRdvTLVTagsRdvIP); ok {
+		rdv.RdvIP = rdvAddr(ip)
+		if xor, ok := frag.Uint32BE(ICBMRdvTLVTagsIPXOR); ok && xor != ^ip {
+			return Rendezvous{}, fmt.Errorf("%w: IP address does not match its XOR check", ErrInvalidRendezvous)
+		}
+	}
+	if ip, ok := frag.Uint32BE(ICBMRdvTLVTagsRequesterIP); ok {
+		rdv.RequesterIP = rdvAddr(ip)
+	}
+	if ip, ok := frag.Uint32BE(ICBMRdvTLVTagsVerifiedIP); ok {
+		rdv.VerifiedIP = rdvAddr(ip)
+	}
+	if xor, ok := frag.Uint16BE(ICBMRdvTLVTagsPortXOR); ok && rdv.Port != 0 && xor != ^rdv.Port {
+		return Rendezvous{}, fmt.Errorf("%w: port does not match its XOR check", ErrInvalidRendezvous)
+	}
+
+	for _, tlv := range frag.TLVList {
+		if !rdvModelledTags[tlv.Tag] {
+			rdv.Unknown.Append(tlv)
+		}
+	}
+
+	return rdv, nil
+}
+
+func rdvAddr(ip uint32) netip.Addr {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], ip)
+	return netip.AddrFrom4(b)
+}
+
+func rdvIP(addr netip.Addr) uint32 {
+	b := addr.As4()
+	return binary.BigEndian.Uint32(b[:])
+}
+
+// Fragment converts the rendezvous into an ICBMCh2Fragment, for sending in
+// TLV ICBMTLVData. The IP and port XOR check TLVs are added along with
+// RdvIP and Port, and the Unknown TLVs are added last.
+func (r Rendezvous) Fragment() ICBMCh2Fragment {
+	frag := ICBMCh2Fragment{
+		Type:       r.Type,
+		Cookie:     r.Cookie,
+		Capability: r.Capability,
+	}
+
+	tlvs := &frag.TLVList
+	if r.SeqNum != 0 {
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsSeqNum, r.SeqNum))
+	}
+	if r.CancelReason != 0 {
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsCancelReason, r.CancelReason))
+	}
+	if r.RdvIP.Is4() {
+		ip := rdvIP(r.RdvIP)
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsRdvIP, ip))
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsIPXOR, ^ip))
+	}
+	if r.RequesterIP.Is4() {
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsRequesterIP, rdvIP(r.RequesterIP)))
+	}
+	if r.VerifiedIP.Is4() {
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsVerifiedIP, rdvIP(r.VerifiedIP)))
+	}
+	if r.Port != 0 {
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsPort, r.Port))
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsPortXOR, ^r.Port))
+	}
+	if r.UseARS {
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsUseARS, []byte{}))
+	}
+	if r.RequestSecure {
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsRequestSecure, []byte{}))
+	}
+	if r.RequestHostCheck {
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsRequestHostChk, []byte{}))
+	}
+	if r.Invitation != "" {
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsInvitation, r.Invitation))
+	}
+	if r.InviteMIMECharset != "" {
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsInviteMIMECharset, r.InviteMIMECharset))
+	}
+	if r.InviteMIMELang != "" {
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsInviteMIMELang, r.InviteMIMELang))
+	}
+	if r.MaxProtoVersion != 0 {
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsMaxProtoVersion, r.MaxProtoVersion))
+	}
+	if r.MinProtoVersion != 0 {
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsMinProtoVersion, r.MinProtoVersion))
+	}
+	if r.DownloadURL != "" {
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsDownloadURL, r.DownloadURL))
+	}
+	if r.SvcData != nil {
+		tlvs.Append(NewTLVBE(ICBMRdvTLVTagsSvcData, r.SvcData))
+	}
+	tlvs.AppendList(r.Unknown)
+
+	return frag
+}
+
+// RdvFileTransfer is the service data of a file transfer proposal.
+type RdvFileTransfer struct {
+	// Flags is RdvFileTransferSingleFile or RdvFileTransferMultipleFiles.
+	Flags      uint16
+	FileCount  uint16
+	TotalBytes uint32
+	// FileName is the name of the file, or of the directory when sending
+	// several files.
+	FileName string
+}
+
+// rdvFileTransferFixedLen is the length of the RdvFileTransfer fields that
+// precede the null-terminated file name.
+const rdvFileTransferFixedLen = 8
+
+// FileTransfer returns the file transfer service data of a CapFileTransfer
+// proposal.
+func (r Rendezvous) FileTransfer() (RdvFileTransfer, error) {
+	if r.Capability != CapFileTransfer {
+		return RdvFileTransfer{}, fmt.Errorf("%w: not a file transfer", ErrInvalidRendezvous)
+	}
+	b := r.SvcData
+	if len(b) < rdvFileTransferFixedLen {
+		return RdvFileTransfer{}, fmt.Errorf("%w: file transfer service data too short", ErrInvalidRendezvous)
+	}
+
+	name := b[rdvFileTransferFixedLen:]
+	if i := bytes.IndexByte(name, 0); i >= 0 {
+		name = name[:i]
+	}
+	return RdvFileTransfer{
+		Flags:      binary.BigEndian.Uint16(b[0:2]),
+		FileCount:  binary.BigEndian.Uint16(b[2:4]),
+		TotalBytes: binary.BigEndian.Uint32(b[4:8]),
+		FileName:   string(name),
+	}, nil
+}
+
+// Bytes encodes the file transfer service data, for Rendezvous.SvcData.
+func (f RdvFileTransfer) Bytes() []byte {
+	b := make([]byte, rdvFileTransferFixedLen, rdvFileTransferFixedLen+len(f.FileName)+1)
+	binary.BigEndian.PutUint16(b[0:2], f.Flags)
+	binary.BigEndian.PutUint16(b[2:4], f.FileCount)
+	binary.BigEndian.PutUint32(b[4:8], f.TotalBytes)
+	b = append(b, f.FileName...)
+	return append(b, 0)
+}
+
+// ChatInvite returns the room that a CapChat proposal invites the recipient
+// to. See UnmarshalChatInviteSvcData.
+func (r Rendezvous) ChatInvite() (ICBMRoomInfo, error) {
+	if r.Capability != CapChat {
+		return ICBMRoomInfo{}, fmt.Errorf("%w: not a chat invitation", ErrInvalidRendezvous)
+	}
+	return UnmarshalChatInviteSvcData(r.SvcData)
+}
+
+// chatInviteFixedLen is the length of the exchange and cookie length that
+// start a chat invitation service block.
+const chatInviteFixedLen = 3
+
+// UnmarshalChatInviteSvcData parses the service block of a chat invitation,
+// an ICBMRoomInfo. Not every client encodes the block the way AIM does, so
+// the parser tolerates the variants seen from third-party clients: a cookie
+// null-terminated within its length, a missing instance number (read as 0)
+// and bytes trailing the instance number. It returns ErrInvalidRendezvous if
+// the exchange or cookie are truncated.
+func UnmarshalChatInviteSvcData(b []byte) (ICBMRoomInfo, error) {
+	if len(b) < chatInviteFixedLen {
+		return ICBMRoomInfo{}, fmt.Errorf("%w: chat invitation service data too short", ErrInvalidRendezvous)
+	}
+	cookieLen := int(b[2])
+	if len(b) < chatInviteFixedLen+cookieLen {
+		return ICBMRoomInfo{}, fmt.Errorf("%w: chat invitation cookie truncated", ErrInvalidRendezvous)
+	}
+
+	room := ICBMRoomInfo{
+		Exchange: binary.BigEndian.Uint16(b[0:2]),
+		Cookie:   string(bytes.TrimRight(b[chatInviteFixedLen:chatInviteFixedLen+cookieLen], "\x00")),
+	}
+	if rest := b[chatInviteFixedLen+cookieLen:]; len(rest) >= 2 {
+		room.Instance = binary.BigEndian.Uint16(rest)
+	}
+	return room, nil
+}
+
+// IsDirectIM indicates whether the rendezvous is a direct IM proposal.
+// Direct IM proposals carry no service data; the peers connect to the
+// proposed address and exchange ODC frames.
+func (r Rendezvous) IsDirectIM() bool {
+	return r.Capability == CapDirectIM
+}
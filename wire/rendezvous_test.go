@@ -0,0 +1,268 @@
+package wire
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func marshalRendezvous(t *testing.T, rdv Rendezvous) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	require.NoError(t, MarshalBE(rdv.Fragment(), buf))
+	return buf.Bytes()
+}
+
+func TestRendezvous_RoundTrip(t *testing.T) {
+	ft := RdvFileTransfer{
+		Flags:      RdvFileTransferSingleFile,
+		FileCount:  1,
+		TotalBytes: 1234,
+		FileName:   "photo.jpg",
+	}
+
+	room := ICBMRoomInfo{Exchange: 4, Cookie: "4-0-lobby", Instance: 0}
+	roomBuf := &bytes.Buffer{}
+	require.NoError(t, MarshalBE(room, roomBuf))
+
+	tests := []struct {
+		name string
+		rdv  Rendezvous
+	}{
+		{
+			name: "file transfer proposal",
+			rdv: Rendezvous{
+				Type:        ICBMRdvMessagePropose,
+				Cookie:      [8]byte{1, 2, 3, 4, 5, 6, 7, 8},
+				Capability:  CapFileTransfer,
+				SeqNum:      1,
+				RdvIP:       netip.MustParseAddr("192.168.1.10"),
+				RequesterIP: netip.MustParseAddr("192.168.1.10"),
+				VerifiedIP:  netip.MustParseAddr("203.0.113.7"),
+				Port:        5190,
+				SvcData:     ft.Bytes(),
+			},
+		},
+		{
+			name: "direct IM proposal through the proxy",
+			rdv: Rendezvous{
+				Type:          ICBMRdvMessagePropose,
+				Cookie:        [8]byte{8, 7, 6, 5, 4, 3, 2, 1},
+				Capability:    CapDirectIM,
+				SeqNum:        2,
+				UseARS:        true,
+				RequestSecure: true,
+			},
+		},
+		{
+			name: "chat invitation",
+			rdv: Rendezvous{
+				Type:              ICBMRdvMessagePropose,
+				Capability:        CapChat,
+				SeqNum:            1,
+				Invitation:        "Join me in this chat",
+				InviteMIMECharset: "us-ascii",
+				InviteMIMELang:    "en",
+				RequestHostCheck:  true,
+				SvcData:           roomBuf.Bytes(),
+			},
+		},
+		{
+			name: "cancel",
+			rdv: Rendezvous{
+				Type:         ICBMRdvMessageCancel,
+				Capability:   CapFileTransfer,
+				CancelReason: ICBMRdvCancelReasonsUserCancel,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnmarshalRendezvous(marshalRendezvous(t, tt.rdv))
+			require.NoError(t, err)
+			assert.Equal(t, tt.rdv, got)
+		})
+	}
+
+	t.Run("file transfer service data", func(t *testing.T) {
+		rdv := Rendezvous{Capability: CapFileTransfer, SvcData: ft.Bytes()}
+		got, err := rdv.FileTransfer()
+		require.NoError(t, err)
+		assert.Equal(t, ft, got)
+
+		_, err = rdv.ChatInvite()
+		assert.ErrorIs(t, err, ErrInvalidRendezvous)
+		assert.False(t, rdv.IsDirectIM())
+	})
+
+	t.Run("chat invite service data", func(t *testing.T) {
+		rdv := Rendezvous{Capability: CapChat, SvcData: roomBuf.Bytes()}
+		got, err := rdv.ChatInvite()
+		require.NoError(t, err)
+		assert.Equal(t, room, got)
+
+		_, err = rdv.FileTransfer()
+		assert.ErrorIs(t, err, ErrInvalidRendezvous)
+	})
+}
+
+func TestRendezvous_UnknownTLVs(t *testing.T) {
+	// a proposal from a newer client, with TLVs that Rendezvous doesn't
+	// model before, between and after the ones it does
+	sent := ICBMCh2Fragment{
+		Type:       ICBMRdvMessagePropose,
+		Cookie:     [8]byte{1, 2, 3, 4, 5, 6, 7, 8},
+		Capability: CapFileTransfer,
+		TLVRestBlock: TLVRestBlock{
+			TLVList: TLVList{
+				NewTLVBE(ICBMRdvTLVTagsRdvChan, uint16(2)),
+				NewTLVBE(ICBMRdvTLVTagsSeqNum, uint16(1)),
+				NewTLVBE(ICBMRdvTLVTagsSessID, "session-42"),
+				NewTLVBE(ICBMRdvTLVTagsRequesterIP, uint32(0xC0A8010A)),
+				NewTLVBE(0x7F00, []byte{}),
+				NewTLVBE(0x7F01, []byte{0xDE, 0xAD, 0xBE, 0xEF}),
+				NewTLVBE(0x7F01, []byte{0x00}),
+			},
+		},
+	}
+	buf := &bytes.Buffer{}
+	require.NoError(t, MarshalBE(sent, buf))
+
+	rdv, err := UnmarshalRendezvous(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, TLVList{
+		NewTLVBE(ICBMRdvTLVTagsRdvChan, uint16(2)),
+		NewTLVBE(ICBMRdvTLVTagsSessID, "session-42"),
+		{Tag: 0x7F00},
+		NewTLVBE(0x7F01, []byte{0xDE, 0xAD, 0xBE, 0xEF}),
+		NewTLVBE(0x7F01, []byte{0x00}),
+	}, rdv.Unknown)
+
+	// the server adds the address it sees the client at
+	rdv.VerifiedIP = netip.MustParseAddr("203.0.113.7")
+	got := ICBMCh2Fragment{}
+	require.NoError(t, UnmarshalBE(&got, bytes.NewReader(marshalRendezvous(t, rdv))))
+
+	assert.Equal(t, TLVList{
+		NewTLVBE(ICBMRdvTLVTagsSeqNum, uint16(1)),
+		NewTLVBE(ICBMRdvTLVTagsRequesterIP, uint32(0xC0A8010A)),
+		NewTLVBE(ICBMRdvTLVTagsVerifiedIP, uint32(0xCB007107)),
+	}, got.TLVList[:3])
+	// the unknown TLVs are sent unchanged and in order
+	wantUnknown := &bytes.Buffer{}
+	require.NoError(t, MarshalBE(rdv.Unknown, wantUnknown))
+	gotUnknown := &bytes.Buffer{}
+	require.NoError(t, MarshalBE(got.TLVList[3:], gotUnknown))
+	assert.Equal(t, wantUnknown.Bytes(), gotUnknown.Bytes())
+
+	again, err := NewRendezvous(got)
+	require.NoError(t, err)
+	assert.Equal(t, rdv, again)
+}
+
+func TestICBMCh2Fragment_SetPreservesUnknownTLVs(t *testing.T) {
+	// a handler that edits the fragment in place instead of through
+	// Rendezvous changes only the bytes of the TLV it sets
+	frag := ICBMCh2Fragment{
+		Type:       ICBMRdvMessagePropose,
+		Capability: CapDirectIM,
+		TLVRestBlock: TLVRestBlock{
+			TLVList: TLVList{
+				NewTLVBE(0x7F01, []byte{0xCA, 0xFE}),
+				NewTLVBE(ICBMRdvTLVTagsSeqNum, uint16(1)),
+				NewTLVBE(0x7F02, []byte{}),
+			},
+		},
+	}
+	buf := &bytes.Buffer{}
+	require.NoError(t, MarshalBE(frag, buf))
+
+	received := ICBMCh2Fragment{}
+	require.NoError(t, UnmarshalBEStrict(&received, buf.Bytes()))
+	received.Set(NewTLVBE(ICBMRdvTLVTagsVerifiedIP, uint32(0xCB007107)))
+
+	sent := &bytes.Buffer{}
+	require.NoError(t, MarshalBE(received, sent))
+	want := append(buf.Bytes(), 0x00, 0x04, 0x00, 0x04, 0xCB, 0x00, 0x71, 0x07)
+	assert.Equal(t, want, sent.Bytes())
+}
+
+func TestUnmarshalChatInviteSvcData(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []byte
+		want    ICBMRoomInfo
+		wantErr bool
+	}{
+		{
+			name: "canonical block",
+			in:   []byte{0x00, 0x04, 0x09, '4', '-', '0', '-', 'l', 'o', 'b', 'b', 'y', 0x00, 0x00},
+			want: ICBMRoomInfo{Exchange: 4, Cookie: "4-0-lobby", Instance: 0},
+		},
+		{
+			name: "cookie null-terminated within its length",
+			in:   []byte{0x00, 0x04, 0x0A, '4', '-', '0', '-', 'l', 'o', 'b', 'b', 'y', 0x00, 0x00, 0x00},
+			want: ICBMRoomInfo{Exchange: 4, Cookie: "4-0-lobby", Instance: 0},
+		},
+		{
+			name: "instance number omitted",
+			in:   []byte{0x00, 0x05, 0x09, '5', '-', '0', '-', 'l', 'o', 'b', 'b', 'y'},
+			want: ICBMRoomInfo{Exchange: 5, Cookie: "5-0-lobby", Instance: 0},
+		},
+		{
+			name: "bytes trailing the instance number",
+			in:   []byte{0x00, 0x04, 0x09, '4', '-', '0', '-', 'l', 'o', 'b', 'b', 'y', 0x00, 0x01, 0x00, 0x00},
+			want: ICBMRoomInfo{Exchange: 4, Cookie: "4-0-lobby", Instance: 1},
+		},
+		{
+			name:    "truncated cookie",
+			in:      []byte{0x00, 0x04, 0x09, '4', '-', '0'},
+			wantErr: true,
+		},
+		{
+			name:    "truncated exchange",
+			in:      []byte{0x00, 0x04},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnmarshalChatInviteSvcData(tt.in)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidRendezvous)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUnmarshalRendezvous_Invalid(t *testing.T) {
+	t.Run("truncated fragment", func(t *testing.T) {
+		_, err := UnmarshalRendezvous([]byte{0x00, 0x00, 0x01})
+		assert.ErrorIs(t, err, ErrInvalidRendezvous)
+	})
+
+	t.Run("IP XOR mismatch", func(t *testing.T) {
+		frag := Rendezvous{RdvIP: netip.MustParseAddr("10.0.0.1")}.Fragment()
+		frag.Replace(NewTLVBE(ICBMRdvTLVTagsRdvIP, uint32(0x0A000002)))
+		_, err := NewRendezvous(frag)
+		assert.ErrorIs(t, err, ErrInvalidRendezvous)
+	})
+
+	t.Run("port XOR mismatch", func(t *testing.T) {
+		frag := Rendezvous{Port: 5190}.Fragment()
+		frag.Replace(NewTLVBE(ICBMRdvTLVTagsPort, uint16(5191)))
+		_, err := NewRendezvous(frag)
+		assert.ErrorIs(t, err, ErrInvalidRendezvous)
+	})
+
+	t.Run("short file transfer service data", func(t *testing.T) {
+		_, err := Rendezvous{Capability: CapFileTransfer, SvcData: []byte{0, 1}}.FileTransfer()
+		assert.ErrorIs(t, err, ErrInvalidRendezvous)
+	})
+}
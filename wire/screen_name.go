@@ -0,0 +1,25 @@
+package wire
+
+import "strings"
+
+// ScreenName is a screen name as it appears in a SNAC, with the
+// capitalization and spacing that its owner chose, such as "Joe User". Two
+// ScreenName values may name the same user without being equal; compare
+// them with Equal, or by their Ident forms, rather than with ==.
+type ScreenName string
+
+// String returns the screen name as sent on the wire.
+func (s ScreenName) String() string {
+	return string(s)
+}
+
+// Ident returns the normalized form of the screen name, lowercase and
+// without spaces, which identifies the user regardless of formatting.
+func (s ScreenName) Ident() string {
+	return strings.ToLower(strings.ReplaceAll(string(s), " ", ""))
+}
+
+// Equal indicates whether s and other name the same user.
+func (s ScreenName) Equal(other ScreenName) bool {
+	return s.Ident() == other.Ident()
+}
@@ -0,0 +1,104 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScreenName_Equal(t *testing.T) {
+	assert.True(t, ScreenName("Joe User").Equal("joeuser"))
+	assert.True(t, ScreenName("JOE USER").Equal("Joe User"))
+	assert.False(t, ScreenName("Joe User").Equal("Joe Users"))
+	assert.Equal(t, "joeuser", ScreenName(" Joe User ").Ident())
+}
+
+func TestScreenName_MarshalPreservesDisplayForm(t *testing.T) {
+	in := SNAC_0x04_0x0C_ICBMHostAck{
+		Cookie:     1234,
+		ChannelID:  ICBMChannelIM,
+		ScreenName: "Joe User",
+	}
+	buf := &bytes.Buffer{}
+	require.NoError(t, MarshalBE(in, buf))
+	assert.Equal(t, append([]byte{0, 0, 0, 0, 0, 0, 0x04, 0xD2, 0x00, 0x01, 0x08}, "Joe User"...), buf.Bytes())
+
+	out := SNAC_0x04_0x0C_ICBMHostAck{}
+	require.NoError(t, UnmarshalBEStrict(&out, buf.Bytes()))
+	assert.Equal(t, in, out)
+}
+
+func TestScreenName_SNACsRoundTripDisplayForm(t *testing.T) {
+	tests := []struct {
+		name string
+		in   any
+		out  any
+	}{
+		{
+			name: "ICBMEvilRequest",
+			in:   SNAC_0x04_0x08_ICBMEvilRequest{SendAs: 1, ScreenName: "Joe User"},
+			out:  &SNAC_0x04_0x08_ICBMEvilRequest{},
+		},
+		{
+			name: "LocateUserInfoQuery",
+			in:   SNAC_0x02_0x05_LocateUserInfoQuery{Type: uint16(LocateTypeSig), ScreenName: "Joe User"},
+			out:  &SNAC_0x02_0x05_LocateUserInfoQuery{},
+		},
+		{
+			name: "LocateUserInfoQuery2",
+			in:   SNAC_0x02_0x15_LocateUserInfoQuery2{Type2: 1, ScreenName: "Joe User"},
+			out:  &SNAC_0x02_0x15_LocateUserInfoQuery2{},
+		},
+		{
+			name: "LocateGetDirInfo",
+			in:   SNAC_0x02_0x0B_LocateGetDirInfo{ScreenName: "Joe User"},
+			out:  &SNAC_0x02_0x0B_LocateGetDirInfo{},
+		},
+		{
+			name: "FeedbagRequestAuthorizationToHost",
+			in:   SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost{ScreenName: "Joe User", Reason: "hi"},
+			out:  &SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost{},
+		},
+		{
+			name: "FeedbagRespondAuthorizeToHost",
+			in:   SNAC_0x13_0x1A_FeedbagRespondAuthorizeToHost{ScreenName: "Joe User", Accepted: 1},
+			out:  &SNAC_0x13_0x1A_FeedbagRespondAuthorizeToHost{},
+		},
+		{
+			name: "FeedbagRespondAuthorizeToClient",
+			in:   SNAC_0x13_0x1B_FeedbagRespondAuthorizeToClient{ScreenName: "Joe User", Accepted: 1},
+			out:  &SNAC_0x13_0x1B_FeedbagRespondAuthorizeToClient{},
+		},
+		{
+			name: "BARTDownloadQuery",
+			in:   SNAC_0x10_0x04_BARTDownloadQuery{ScreenName: "Joe User", Command: 1},
+			out:  &SNAC_0x10_0x04_BARTDownloadQuery{},
+		},
+		{
+			name: "BARTDownloadReply",
+			in:   SNAC_0x10_0x05_BARTDownloadReply{ScreenName: "Joe User", Data: []byte{1}},
+			out:  &SNAC_0x10_0x05_BARTDownloadReply{},
+		},
+		{
+			name: "BARTDownload2Query",
+			in:   SNAC_0x10_0x06_BARTDownload2Query{ScreenName: "Joe User", IDs: []BARTID{{Type: BARTTypesBuddyIcon}}},
+			out:  &SNAC_0x10_0x06_BARTDownload2Query{},
+		},
+		{
+			name: "BARTDownload2Reply",
+			in:   SNAC_0x10_0x07_BARTDownload2Reply{ScreenName: "Joe User", Data: []byte{1}},
+			out:  &SNAC_0x10_0x07_BARTDownload2Reply{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			require.NoError(t, MarshalBE(tt.in, buf))
+			require.NoError(t, UnmarshalBE(tt.out, buf))
+			assert.Equal(t, tt.in, reflect.ValueOf(tt.out).Elem().Interface())
+		})
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"fmt"
 )
 
+// Food groups.
+//
+//oscar:foodgroups
 const (
 	BOS         uint16 = 0x0000
 	OService    uint16 = 0x0001
@@ -33,42 +36,400 @@ const (
 	MDir        uint16 = 0x0025
 	ARS         uint16 = 0x044A
 	Kerberos    uint16 = 0x050C
+)
+
+// OService food group subgroups.
+//
+//oscar:subgroups OService
+const (
+	OServiceErr               uint16 = 0x0001
+	OServiceClientOnline      uint16 = 0x0002
+	OServiceHostOnline        uint16 = 0x0003
+	OServiceServiceRequest    uint16 = 0x0004
+	OServiceServiceResponse   uint16 = 0x0005
+	OServiceRateParamsQuery   uint16 = 0x0006
+	OServiceRateParamsReply   uint16 = 0x0007
+	OServiceRateParamsSubAdd  uint16 = 0x0008
+	OServiceRateDelParamSub   uint16 = 0x0009
+	OServiceRateParamChange   uint16 = 0x000A
+	OServicePauseReq          uint16 = 0x000B
+	OServicePauseAck          uint16 = 0x000C
+	OServiceResume            uint16 = 0x000D
+	OServiceUserInfoQuery     uint16 = 0x000E
+	OServiceUserInfoUpdate    uint16 = 0x000F
+	OServiceEvilNotification  uint16 = 0x0010
+	OServiceIdleNotification  uint16 = 0x0011
+	OServiceMigrateGroups     uint16 = 0x0012
+	OServiceMotd              uint16 = 0x0013
+	OServiceSetPrivacyFlags   uint16 = 0x0014
+	OServiceWellKnownUrls     uint16 = 0x0015
+	OServiceNoop              uint16 = 0x0016
+	OServiceClientVersions    uint16 = 0x0017
+	OServiceHostVersions      uint16 = 0x0018
+	OServiceMaxConfigQuery    uint16 = 0x0019
+	OServiceMaxConfigReply    uint16 = 0x001A
+	OServiceStoreConfig       uint16 = 0x001B
+	OServiceConfigQuery       uint16 = 0x001C
+	OServiceConfigReply       uint16 = 0x001D
+	OServiceSetUserInfoFields uint16 = 0x001E
+	OServiceProbeReq          uint16 = 0x001F
+	OServiceProbeAck          uint16 = 0x0020
+	OServiceBartReply         uint16 = 0x0021
+	OServiceBartQuery2        uint16 = 0x0022
+	OServiceBartReply2        uint16 = 0x0023
+)
+
+// Locate food group subgroups.
+//
+//oscar:subgroups Locate
+const (
+	LocateErr                  uint16 = 0x0001
+	LocateRightsQuery          uint16 = 0x0002
+	LocateRightsReply          uint16 = 0x0003
+	LocateSetInfo              uint16 = 0x0004
+	LocateUserInfoQuery        uint16 = 0x0005
+	LocateUserInfoReply        uint16 = 0x0006
+	LocateWatcherSubRequest    uint16 = 0x0007
+	LocateWatcherNotification  uint16 = 0x0008
+	LocateSetDirInfo           uint16 = 0x0009
+	LocateSetDirReply          uint16 = 0x000A
+	LocateGetDirInfo           uint16 = 0x000B
+	LocateGetDirReply          uint16 = 0x000C
+	LocateGroupCapabilityQuery uint16 = 0x000D
+	LocateGroupCapabilityReply uint16 = 0x000E
+	LocateSetKeywordInfo       uint16 = 0x000F
+	LocateSetKeywordReply      uint16 = 0x0010
+	LocateGetKeywordInfo       uint16 = 0x0011
+	LocateGetKeywordReply      uint16 = 0x0012
+	LocateFindListByEmail      uint16 = 0x0013
+	LocateFindListReply        uint16 = 0x0014
+	LocateUserInfoQuery2       uint16 = 0x0015
+)
+
+// Buddy food group subgroups.
+//
+//oscar:subgroups Buddy
+const (
+	BuddyErr                 uint16 = 0x0001
+	BuddyRightsQuery         uint16 = 0x0002
+	BuddyRightsReply         uint16 = 0x0003
+	BuddyAddBuddies          uint16 = 0x0004
+	BuddyDelBuddies          uint16 = 0x0005
+	BuddyWatcherListQuery    uint16 = 0x0006
+	BuddyWatcherListResponse uint16 = 0x0007
+	BuddyWatcherSubRequest   uint16 = 0x0008
+	BuddyWatcherNotification uint16 = 0x0009
+	BuddyRejectNotification  uint16 = 0x000A
+	BuddyArrived             uint16 = 0x000B
+	BuddyDeparted            uint16 = 0x000C
+	BuddyAddTempBuddies      uint16 = 0x000F
+	BuddyDelTempBuddies      uint16 = 0x0010
+)
+
+// ICBM food group subgroups.
+//
+//oscar:subgroups ICBM
+const (
+	ICBMErr                  uint16 = 0x0001
+	ICBMAddParameters        uint16 = 0x0002
+	ICBMDelParameters        uint16 = 0x0003
+	ICBMParameterQuery       uint16 = 0x0004
+	ICBMParameterReply       uint16 = 0x0005
+	ICBMChannelMsgToHost     uint16 = 0x0006
+	ICBMChannelMsgToClient   uint16 = 0x0007
+	ICBMEvilRequest          uint16 = 0x0008
+	ICBMEvilReply            uint16 = 0x0009
+	ICBMMissedCalls          uint16 = 0x000A
+	ICBMClientErr            uint16 = 0x000B
+	ICBMHostAck              uint16 = 0x000C
+	ICBMSinStored            uint16 = 0x000D
+	ICBMSinListQuery         uint16 = 0x000E
+	ICBMSinListReply         uint16 = 0x000F
+	ICBMOfflineRetrieve      uint16 = 0x0010
+	ICBMSinDelete            uint16 = 0x0011
+	ICBMNotifyRequest        uint16 = 0x0012
+	ICBMNotifyReply          uint16 = 0x0013
+	ICBMClientEvent          uint16 = 0x0014
+	ICBMOfflineRetrieveReply uint16 = 0x0017
+)
+
+// Advert food group subgroups.
+//
+//oscar:subgroups Advert
+const (
+	AdvertErr      uint16 = 0x0001
+	AdvertAdsQuery uint16 = 0x0002
+	AdvertAdsReply uint16 = 0x0003
+)
+
+// Invite food group subgroups.
+//
+//oscar:subgroups Invite
+const (
+	InviteErr          uint16 = 0x0001
+	InviteRequestQuery uint16 = 0x0002
+	InviteRequestReply uint16 = 0x0003
+)
+
+// Admin food group subgroups.
+//
+//oscar:subgroups Admin
+const (
+	AdminErr                uint16 = 0x0001
+	AdminInfoQuery          uint16 = 0x0002
+	AdminInfoReply          uint16 = 0x0003
+	AdminInfoChangeRequest  uint16 = 0x0004
+	AdminInfoChangeReply    uint16 = 0x0005
+	AdminAcctConfirmRequest uint16 = 0x0006
+	AdminAcctConfirmReply   uint16 = 0x0007
+	AdminAcctDeleteRequest  uint16 = 0x0008
+	AdminAcctDeleteReply    uint16 = 0x0009
+)
+
+// Popup food group subgroups.
+//
+//oscar:subgroups Popup
+const (
+	PopupErr     uint16 = 0x0001
+	PopupDisplay uint16 = 0x0002
+)
+
+// PermitDeny food group subgroups.
+//
+//oscar:subgroups PermitDeny
+const (
+	PermitDenyErr                      uint16 = 0x0001
+	PermitDenyRightsQuery              uint16 = 0x0002
+	PermitDenyRightsReply              uint16 = 0x0003
+	PermitDenySetGroupPermitMask       uint16 = 0x0004
+	PermitDenyAddPermListEntries       uint16 = 0x0005
+	PermitDenyDelPermListEntries       uint16 = 0x0006
+	PermitDenyAddDenyListEntries       uint16 = 0x0007
+	PermitDenyDelDenyListEntries       uint16 = 0x0008
+	PermitDenyBosErr                   uint16 = 0x0009
+	PermitDenyAddTempPermitListEntries uint16 = 0x000A
+	PermitDenyDelTempPermitListEntries uint16 = 0x000B
+)
+
+// UserLookup food group subgroups.
+//
+//oscar:subgroups UserLookup
+const (
+	UserLookupErr         uint16 = 0x0001
+	UserLookupFindByEmail uint16 = 0x0002
+	UserLookupFindReply   uint16 = 0x0003
+)
 
-	OServiceErr                            uint16 = 0x0001
-	OServiceClientOnline                   uint16 = 0x0002
-	OServiceHostOnline                     uint16 = 0x0003
-	OServiceServiceRequest                 uint16 = 0x0004
-	OServiceServiceResponse                uint16 = 0x0005
-	OServiceRateParamsQuery                uint16 = 0x0006
-	OServiceRateParamsReply                uint16 = 0x0007
-	OServiceRateParamsSubAdd               uint16 = 0x0008
-	OServiceRateDelParamSub                uint16 = 0x0009
-	OServiceRateParamChange                uint16 = 0x000A
-	OServicePauseReq                       uint16 = 0x000B
-	OServicePauseAck                       uint16 = 0x000C
-	OServiceResume                         uint16 = 0x000D
-	OServiceUserInfoQuery                  uint16 = 0x000E
-	OServiceUserInfoUpdate                 uint16 = 0x000F
-	OServiceEvilNotification               uint16 = 0x0010
-	OServiceIdleNotification               uint16 = 0x0011
-	OServiceMigrateGroups                  uint16 = 0x0012
-	OServiceMotd                           uint16 = 0x0013
-	OServiceSetPrivacyFlags                uint16 = 0x0014
-	OServiceWellKnownUrls                  uint16 = 0x0015
-	OServiceNoop                           uint16 = 0x0016
-	OServiceClientVersions                 uint16 = 0x0017
-	OServiceHostVersions                   uint16 = 0x0018
-	OServiceMaxConfigQuery                 uint16 = 0x0019
-	OServiceMaxConfigReply                 uint16 = 0x001A
-	OServiceStoreConfig                    uint16 = 0x001B
-	OServiceConfigQuery                    uint16 = 0x001C
-	OServiceConfigReply                    uint16 = 0x001D
-	OServiceSetUserInfoFields              uint16 = 0x001E
-	OServiceProbeReq                       uint16 = 0x001F
-	OServiceProbeAck                       uint16 = 0x0020
-	OServiceBartReply                      uint16 = 0x0021
-	OServiceBartQuery2                     uint16 = 0x0022
-	OServiceBartReply2                     uint16 = 0x0023
+// Stats food group subgroups.
+//
+//oscar:subgroups Stats
+const (
+	StatsErr                  uint16 = 0x0001
+	StatsSetMinReportInterval uint16 = 0x0002
+	StatsReportEvents         uint16 = 0x0003
+	StatsReportAck            uint16 = 0x0004
+)
+
+// Translate food group subgroups.
+//
+//oscar:subgroups Translate
+const (
+	TranslateErr     uint16 = 0x0001
+	TranslateRequest uint16 = 0x0002
+	TranslateReply   uint16 = 0x0003
+)
+
+// ChatNav food group subgroups.
+//
+//oscar:subgroups ChatNav
+const (
+	ChatNavErr                 uint16 = 0x0001
+	ChatNavRequestChatRights   uint16 = 0x0002
+	ChatNavRequestExchangeInfo uint16 = 0x0003
+	ChatNavRequestRoomInfo     uint16 = 0x0004
+	ChatNavRequestMoreRoomInfo uint16 = 0x0005
+	ChatNavRequestOccupantList uint16 = 0x0006
+	ChatNavSearchForRoom       uint16 = 0x0007
+	ChatNavCreateRoom          uint16 = 0x0008
+	ChatNavNavInfo             uint16 = 0x0009
+)
+
+// Chat food group subgroups.
+//
+//oscar:subgroups Chat
+const (
+	ChatErr                uint16 = 0x0001
+	ChatRoomInfoUpdate     uint16 = 0x0002
+	ChatUsersJoined        uint16 = 0x0003
+	ChatUsersLeft          uint16 = 0x0004
+	ChatChannelMsgToHost   uint16 = 0x0005
+	ChatChannelMsgToClient uint16 = 0x0006
+	ChatEvilRequest        uint16 = 0x0007
+	ChatEvilReply          uint16 = 0x0008
+	ChatClientErr          uint16 = 0x0009
+	ChatPauseRoomReq       uint16 = 0x000A
+	ChatPauseRoomAck       uint16 = 0x000B
+	ChatResumeRoom         uint16 = 0x000C
+	ChatShowMyRow          uint16 = 0x000D
+	ChatShowRowByUsername  uint16 = 0x000E
+	ChatShowRowByNumber    uint16 = 0x000F
+	ChatShowRowByName      uint16 = 0x0010
+	ChatRowInfo            uint16 = 0x0011
+	ChatListRows           uint16 = 0x0012
+	ChatRowListInfo        uint16 = 0x0013
+	ChatMoreRows           uint16 = 0x0014
+	ChatMoveToRow          uint16 = 0x0015
+	ChatToggleChat         uint16 = 0x0016
+	ChatSendQuestion       uint16 = 0x0017
+	ChatSendComment        uint16 = 0x0018
+	ChatTallyVote          uint16 = 0x0019
+	ChatAcceptBid          uint16 = 0x001A
+	ChatSendInvite         uint16 = 0x001B
+	ChatDeclineInvite      uint16 = 0x001C
+	ChatAcceptInvite       uint16 = 0x001D
+	ChatNotifyMessage      uint16 = 0x001E
+	ChatGotoRow            uint16 = 0x001F
+	ChatStageUserJoin      uint16 = 0x0020
+	ChatStageUserLeft      uint16 = 0x0021
+	ChatUnnamedSnac22      uint16 = 0x0022
+	ChatClose              uint16 = 0x0023
+	ChatUserBan            uint16 = 0x0024
+	ChatUserUnban          uint16 = 0x0025
+	ChatJoined             uint16 = 0x0026
+	ChatUnnamedSnac27      uint16 = 0x0027
+	ChatUnnamedSnac28      uint16 = 0x0028
+	ChatUnnamedSnac29      uint16 = 0x0029
+	ChatRoomInfoOwner      uint16 = 0x0030
+)
+
+// ODir food group subgroups.
+//
+//oscar:subgroups ODir
+const (
+	ODirErr              uint16 = 0x0001
+	ODirInfoQuery        uint16 = 0x0002
+	ODirInfoReply        uint16 = 0x0003
+	ODirKeywordListQuery uint16 = 0x0004
+	ODirKeywordListReply uint16 = 0x0005
+)
+
+// BART food group subgroups.
+//
+//oscar:subgroups BART
+const (
+	BARTErr            uint16 = 0x0001
+	BARTUploadQuery    uint16 = 0x0002
+	BARTUploadReply    uint16 = 0x0003
+	BARTDownloadQuery  uint16 = 0x0004
+	BARTDownloadReply  uint16 = 0x0005
+	BARTDownload2Query uint16 = 0x0006
+	BARTDownload2Reply uint16 = 0x0007
+)
+
+// Feedbag food group subgroups.
+//
+//oscar:subgroups Feedbag
+const (
+	FeedbagErr                      uint16 = 0x0001
+	FeedbagRightsQuery              uint16 = 0x0002
+	FeedbagRightsReply              uint16 = 0x0003
+	FeedbagQuery                    uint16 = 0x0004
+	FeedbagQueryIfModified          uint16 = 0x0005
+	FeedbagReply                    uint16 = 0x0006
+	FeedbagUse                      uint16 = 0x0007
+	FeedbagInsertItem               uint16 = 0x0008
+	FeedbagUpdateItem               uint16 = 0x0009
+	FeedbagDeleteItem               uint16 = 0x000A
+	FeedbagInsertClass              uint16 = 0x000B
+	FeedbagUpdateClass              uint16 = 0x000C
+	FeedbagDeleteClass              uint16 = 0x000D
+	FeedbagStatus                   uint16 = 0x000E
+	FeedbagReplyNotModified         uint16 = 0x000F
+	FeedbagDeleteUser               uint16 = 0x0010
+	FeedbagStartCluster             uint16 = 0x0011
+	FeedbagEndCluster               uint16 = 0x0012
+	FeedbagAuthorizeBuddy           uint16 = 0x0013
+	FeedbagPreAuthorizeBuddy        uint16 = 0x0014
+	FeedbagPreAuthorizedBuddy       uint16 = 0x0015
+	FeedbagRemoveMe                 uint16 = 0x0016
+	FeedbagRemoveMe2                uint16 = 0x0017
+	FeedbagRequestAuthorizeToHost   uint16 = 0x0018
+	FeedbagRequestAuthorizeToClient uint16 = 0x0019
+	FeedbagRespondAuthorizeToHost   uint16 = 0x001A
+	FeedbagRespondAuthorizeToClient uint16 = 0x001B
+	FeedbagBuddyAdded               uint16 = 0x001C
+	FeedbagRequestAuthorizeToBadog  uint16 = 0x001D
+	FeedbagRespondAuthorizeToBadog  uint16 = 0x001E
+	FeedbagBuddyAddedToBadog        uint16 = 0x001F
+	FeedbagTestSnac                 uint16 = 0x0021
+	FeedbagForwardMsg               uint16 = 0x0022
+	FeedbagIsAuthRequiredQuery      uint16 = 0x0023
+	FeedbagIsAuthRequiredReply      uint16 = 0x0024
+	FeedbagRecentBuddyUpdate        uint16 = 0x0025
+)
+
+// ICQ food group subgroups.
+//
+//oscar:subgroups ICQ
+const (
+	ICQErr     uint16 = 0x0001
+	ICQDBQuery uint16 = 0x0002
+	ICQDBReply uint16 = 0x0003
+)
+
+// BUCP food group subgroups.
+//
+//oscar:subgroups BUCP
+const (
+	BUCPErr                      uint16 = 0x0001
+	BUCPLoginRequest             uint16 = 0x0002
+	BUCPLoginResponse            uint16 = 0x0003
+	BUCPRegisterRequest          uint16 = 0x0004
+	BUCPChallengeRequest         uint16 = 0x0006
+	BUCPChallengeResponse        uint16 = 0x0007
+	BUCPAsasnRequest             uint16 = 0x0008
+	BUCPSecuridRequest           uint16 = 0x000A
+	BUCPRegistrationImageRequest uint16 = 0x000C
+)
+
+// Alert food group subgroups.
+//
+//oscar:subgroups Alert
+const (
+	AlertErr                       uint16 = 0x0001
+	AlertSetAlertRequest           uint16 = 0x0002
+	AlertSetAlertReply             uint16 = 0x0003
+	AlertGetSubsRequest            uint16 = 0x0004
+	AlertGetSubsResponse           uint16 = 0x0005
+	AlertNotifyCapabilities        uint16 = 0x0006
+	AlertNotify                    uint16 = 0x0007
+	AlertGetRuleRequest            uint16 = 0x0008
+	AlertGetRuleReply              uint16 = 0x0009
+	AlertGetFeedRequest            uint16 = 0x000A
+	AlertGetFeedReply              uint16 = 0x000B
+	AlertRefreshFeed               uint16 = 0x000D
+	AlertEvent                     uint16 = 0x000E
+	AlertQogSnac                   uint16 = 0x000F
+	AlertRefreshFeedStock          uint16 = 0x0010
+	AlertNotifyTransport           uint16 = 0x0011
+	AlertSetAlertRequestV2         uint16 = 0x0012
+	AlertSetAlertReplyV2           uint16 = 0x0013
+	AlertTransitReply              uint16 = 0x0014
+	AlertNotifyAck                 uint16 = 0x0015
+	AlertNotifyDisplayCapabilities uint16 = 0x0016
+	AlertUserOnline                uint16 = 0x0017
+)
+
+// Kerberos food group subgroups.
+//
+//oscar:subgroups Kerberos
+const (
+	KerberosLoginRequest             uint16 = 0x0002
+	KerberosLoginSuccessResponse     uint16 = 0x0003
+	KerberosKerberosLoginErrResponse uint16 = 0x0004
+)
+
+const (
 	OServiceUserInfoUserFlags              uint16 = 0x01
 	OServiceUserInfoSignonTOD              uint16 = 0x03
 	OServiceUserInfoIdleTime               uint16 = 0x04
@@ -81,7 +442,8 @@ const (
 	OServiceUserInfoMySubscriptions        uint16 = 0x1E
 	OServiceUserInfoUserFlags2             uint16 = 0x1F
 	OServiceUserInfoMyInstanceNum          uint16 = 0x14
-	OServiceUserInfoSigTime                uint16 = 0x26
+	OServiceUserInfoSigTime                uint16 = 0x26 // uint32 (dword)	time the profile was last set
+	OServiceUserInfoAwayTime               uint16 = 0x27 // uint32 (dword)	time the away message was last set
 	OServiceUserInfoPrimaryInstance        uint16 = 0x28
 	OServiceUserStatusAvailable            uint32 = 0x00000000 // user is available
 	OServiceUserStatusAway                 uint32 = 0x00000001 // user is away
@@ -127,27 +489,6 @@ const (
 	OServiceServiceResponseSSLStateUse     uint8  = 0x01 // SSL is being used
 	OServiceServiceResponseSSLStateResume  uint8  = 0x02 // SSL is being used and SSL resume is supported if desired
 
-	LocateErr                        uint16 = 0x0001
-	LocateRightsQuery                uint16 = 0x0002
-	LocateRightsReply                uint16 = 0x0003
-	LocateSetInfo                    uint16 = 0x0004
-	LocateUserInfoQuery              uint16 = 0x0005
-	LocateUserInfoReply              uint16 = 0x0006
-	LocateWatcherSubRequest          uint16 = 0x0007
-	LocateWatcherNotification        uint16 = 0x0008
-	LocateSetDirInfo                 uint16 = 0x0009
-	LocateSetDirReply                uint16 = 0x000A
-	LocateGetDirInfo                 uint16 = 0x000B
-	LocateGetDirReply                uint16 = 0x000C
-	LocateGroupCapabilityQuery       uint16 = 0x000D
-	LocateGroupCapabilityReply       uint16 = 0x000E
-	LocateSetKeywordInfo             uint16 = 0x000F
-	LocateSetKeywordReply            uint16 = 0x0010
-	LocateGetKeywordInfo             uint16 = 0x0011
-	LocateGetKeywordReply            uint16 = 0x0012
-	LocateFindListByEmail            uint16 = 0x0013
-	LocateFindListReply              uint16 = 0x0014
-	LocateUserInfoQuery2             uint16 = 0x0015
 	LocateTypeSig                    uint32 = 0x00000001
 	LocateTypeUnavailable            uint32 = 0x00000002
 	LocateTypeCapabilities           uint32 = 0x00000004
@@ -177,46 +518,11 @@ const (
 	LocateGetDirReplyOK                        uint16 = 0x01 // Directory info lookup succeeded
 	LocateGetDirReplyUnavailable               uint16 = 0x02 // Directory info lookup unavailable
 
-	BuddyErr                       uint16 = 0x0001
-	BuddyRightsQuery               uint16 = 0x0002
-	BuddyRightsReply               uint16 = 0x0003
-	BuddyAddBuddies                uint16 = 0x0004
-	BuddyDelBuddies                uint16 = 0x0005
-	BuddyWatcherListQuery          uint16 = 0x0006
-	BuddyWatcherListResponse       uint16 = 0x0007
-	BuddyWatcherSubRequest         uint16 = 0x0008
-	BuddyWatcherNotification       uint16 = 0x0009
-	BuddyRejectNotification        uint16 = 0x000A
-	BuddyArrived                   uint16 = 0x000B
-	BuddyDeparted                  uint16 = 0x000C
-	BuddyAddTempBuddies            uint16 = 0x000F
-	BuddyDelTempBuddies            uint16 = 0x0010
 	BuddyTLVTagsParmMaxBuddies     uint16 = 0x01
 	BuddyTLVTagsParmMaxWatchers    uint16 = 0x02
 	BuddyTLVTagsParmMaxIcqBroad    uint16 = 0x03
 	BuddyTLVTagsParmMaxTempBuddies uint16 = 0x04
 
-	ICBMErr                               uint16 = 0x0001
-	ICBMAddParameters                     uint16 = 0x0002
-	ICBMDelParameters                     uint16 = 0x0003
-	ICBMParameterQuery                    uint16 = 0x0004
-	ICBMParameterReply                    uint16 = 0x0005
-	ICBMChannelMsgToHost                  uint16 = 0x0006
-	ICBMChannelMsgToClient                uint16 = 0x0007
-	ICBMEvilRequest                       uint16 = 0x0008
-	ICBMEvilReply                         uint16 = 0x0009
-	ICBMMissedCalls                       uint16 = 0x000A
-	ICBMClientErr                         uint16 = 0x000B
-	ICBMHostAck                           uint16 = 0x000C
-	ICBMSinStored                         uint16 = 0x000D
-	ICBMSinListQuery                      uint16 = 0x000E
-	ICBMSinListReply                      uint16 = 0x000F
-	ICBMOfflineRetrieve                   uint16 = 0x0010
-	ICBMSinDelete                         uint16 = 0x0011
-	ICBMNotifyRequest                     uint16 = 0x0012
-	ICBMNotifyReply                       uint16 = 0x0013
-	ICBMClientEvent                       uint16 = 0x0014
-	ICBMOfflineRetrieveReply              uint16 = 0x0017
 	ICBMTLVAOLIMData                      uint16 = 0x02
 	ICBMTLVRequestHostAck                 uint16 = 0x03
 	ICBMTLVAutoResponse                   uint16 = 0x04
@@ -327,65 +633,10 @@ const (
 	ICBMSubErrOfflineIMNotAccepted        uint16 = 0x000E // User does not accept offline IMs
 	ICBMSubErrOfflineIMExceedMax          uint16 = 0x000F // Exceeded max storage limit
 
-	TranslateErr     uint16 = 0x0001
-	TranslateRequest uint16 = 0x0002
-	TranslateReply   uint16 = 0x0003
-
-	ChatNavErr                   uint16 = 0x0001
-	ChatNavRequestChatRights     uint16 = 0x0002
-	ChatNavRequestExchangeInfo   uint16 = 0x0003
-	ChatNavRequestRoomInfo       uint16 = 0x0004
-	ChatNavRequestMoreRoomInfo   uint16 = 0x0005
-	ChatNavRequestOccupantList   uint16 = 0x0006
-	ChatNavSearchForRoom         uint16 = 0x0007
-	ChatNavCreateRoom            uint16 = 0x0008
-	ChatNavNavInfo               uint16 = 0x0009
 	ChatNavTLVMaxConcurrentRooms uint16 = 0x0002
 	ChatNavTLVExchangeInfo       uint16 = 0x0003
 	ChatNavTLVRoomInfo           uint16 = 0x0004
 
-	ChatErr                     uint16 = 0x0001
-	ChatRoomInfoUpdate          uint16 = 0x0002
-	ChatUsersJoined             uint16 = 0x0003
-	ChatUsersLeft               uint16 = 0x0004
-	ChatChannelMsgToHost        uint16 = 0x0005
-	ChatChannelMsgToClient      uint16 = 0x0006
-	ChatEvilRequest             uint16 = 0x0007
-	ChatEvilReply               uint16 = 0x0008
-	ChatClientErr               uint16 = 0x0009
-	ChatPauseRoomReq            uint16 = 0x000A
-	ChatPauseRoomAck            uint16 = 0x000B
-	ChatResumeRoom              uint16 = 0x000C
-	ChatShowMyRow               uint16 = 0x000D
-	ChatShowRowByUsername       uint16 = 0x000E
-	ChatShowRowByNumber         uint16 = 0x000F
-	ChatShowRowByName           uint16 = 0x0010
-	ChatRowInfo                 uint16 = 0x0011
-	ChatListRows                uint16 = 0x0012
-	ChatRowListInfo             uint16 = 0x0013
-	ChatMoreRows                uint16 = 0x0014
-	ChatMoveToRow               uint16 = 0x0015
-	ChatToggleChat              uint16 = 0x0016
-	ChatSendQuestion            uint16 = 0x0017
-	ChatSendComment             uint16 = 0x0018
-	ChatTallyVote               uint16 = 0x0019
-	ChatAcceptBid               uint16 = 0x001A
-	ChatSendInvite              uint16 = 0x001B
-	ChatDeclineInvite           uint16 = 0x001C
-	ChatAcceptInvite            uint16 = 0x001D
-	ChatNotifyMessage           uint16 = 0x001E
-	ChatGotoRow                 uint16 = 0x001F
-	ChatStageUserJoin           uint16 = 0x0020
-	ChatStageUserLeft           uint16 = 0x0021
-	ChatUnnamedSnac22           uint16 = 0x0022
-	ChatClose                   uint16 = 0x0023
-	ChatUserBan                 uint16 = 0x0024
-	ChatUserUnban               uint16 = 0x0025
-	ChatJoined                  uint16 = 0x0026
-	ChatUnnamedSnac27           uint16 = 0x0027
-	ChatUnnamedSnac28           uint16 = 0x0028
-	ChatUnnamedSnac29           uint16 = 0x0029
-	ChatRoomInfoOwner           uint16 = 0x0030
 	ChatTLVPublicWhisperFlag    uint16 = 0x01
 	ChatTLVWhisperToUser        uint16 = 0x02
 	ChatTLVSenderInformation    uint16 = 0x03
@@ -521,73 +772,6 @@ const (
 	FeedbagRightsMaxBuddiesPerGroup          uint16 = 0x0C
 	FeedbagRightsMaxMegaBots                 uint16 = 0x0D
 	FeedbagRightsMaxSmartGroups              uint16 = 0x0E
-	FeedbagErr                               uint16 = 0x0001
-	FeedbagRightsQuery                       uint16 = 0x0002
-	FeedbagRightsReply                       uint16 = 0x0003
-	FeedbagQuery                             uint16 = 0x0004
-	FeedbagQueryIfModified                   uint16 = 0x0005
-	FeedbagReply                             uint16 = 0x0006
-	FeedbagUse                               uint16 = 0x0007
-	FeedbagInsertItem                        uint16 = 0x0008
-	FeedbagUpdateItem                        uint16 = 0x0009
-	FeedbagDeleteItem                        uint16 = 0x000A
-	FeedbagInsertClass                       uint16 = 0x000B
-	FeedbagUpdateClass                       uint16 = 0x000C
-	FeedbagDeleteClass                       uint16 = 0x000D
-	FeedbagStatus                            uint16 = 0x000E
-	FeedbagReplyNotModified                  uint16 = 0x000F
-	FeedbagDeleteUser                        uint16 = 0x0010
-	FeedbagStartCluster                      uint16 = 0x0011
-	FeedbagEndCluster                        uint16 = 0x0012
-	FeedbagAuthorizeBuddy                    uint16 = 0x0013
-	FeedbagPreAuthorizeBuddy                 uint16 = 0x0014
-	FeedbagPreAuthorizedBuddy                uint16 = 0x0015
-	FeedbagRemoveMe                          uint16 = 0x0016
-	FeedbagRemoveMe2                         uint16 = 0x0017
-	FeedbagRequestAuthorizeToHost            uint16 = 0x0018
-	FeedbagRequestAuthorizeToClient          uint16 = 0x0019
-	FeedbagRespondAuthorizeToHost            uint16 = 0x001A
-	FeedbagRespondAuthorizeToClient          uint16 = 0x001B
-	FeedbagBuddyAdded                        uint16 = 0x001C
-	FeedbagRequestAuthorizeToBadog           uint16 = 0x001D
-	FeedbagRespondAuthorizeToBadog           uint16 = 0x001E
-	FeedbagBuddyAddedToBadog                 uint16 = 0x001F
-	FeedbagTestSnac                          uint16 = 0x0021
-	FeedbagForwardMsg                        uint16 = 0x0022
-	FeedbagIsAuthRequiredQuery               uint16 = 0x0023
-	FeedbagIsAuthRequiredReply               uint16 = 0x0024
-	FeedbagRecentBuddyUpdate                 uint16 = 0x0025
-
-	AlertErr                       uint16 = 0x0001
-	AlertSetAlertRequest           uint16 = 0x0002
-	AlertSetAlertReply             uint16 = 0x0003
-	AlertGetSubsRequest            uint16 = 0x0004
-	AlertGetSubsResponse           uint16 = 0x0005
-	AlertNotifyCapabilities        uint16 = 0x0006
-	AlertNotify                    uint16 = 0x0007
-	AlertGetRuleRequest            uint16 = 0x0008
-	AlertGetRuleReply              uint16 = 0x0009
-	AlertGetFeedRequest            uint16 = 0x000A
-	AlertGetFeedReply              uint16 = 0x000B
-	AlertRefreshFeed               uint16 = 0x000D
-	AlertEvent                     uint16 = 0x000E
-	AlertQogSnac                   uint16 = 0x000F
-	AlertRefreshFeedStock          uint16 = 0x0010
-	AlertNotifyTransport           uint16 = 0x0011
-	AlertSetAlertRequestV2         uint16 = 0x0012
-	AlertSetAlertReplyV2           uint16 = 0x0013
-	AlertTransitReply              uint16 = 0x0014
-	AlertNotifyAck                 uint16 = 0x0015
-	AlertNotifyDisplayCapabilities uint16 = 0x0016
-	AlertUserOnline                uint16 = 0x0017
-
-	BARTErr            uint16 = 0x0001
-	BARTUploadQuery    uint16 = 0x0002
-	BARTUploadReply    uint16 = 0x0003
-	BARTDownloadQuery  uint16 = 0x0004
-	BARTDownloadReply  uint16 = 0x0005
-	BARTDownload2Query uint16 = 0x0006
-	BARTDownload2Reply uint16 = 0x0007
 
 	BARTFlagsKnown    uint8 = 0x00
 	BARTFlagsCustom   uint8 = 0x01
@@ -618,6 +802,7 @@ const (
 	BARTTypesLocation            uint16 = 0x0B
 	BARTTypesBuddyIconBig        uint16 = 0x0C
 	BARTTypesStatusTextTimestamp uint16 = 0x0D
+	BARTTypesICQMood             uint16 = 0x0E
 	BARTTypesCurrentAvtrack      uint16 = 0x0F
 	BARTTypesDepartSound         uint16 = 0x60
 	BARTTypesImBackground        uint16 = 0x80
@@ -637,38 +822,10 @@ const (
 	BARTTypesSignCertChain       uint16 = 0x403
 	BARTTypesGatewayCert         uint16 = 0x404
 
-	PermitDenyErr                      uint16 = 0x0001
-	PermitDenyRightsQuery              uint16 = 0x0002
-	PermitDenyRightsReply              uint16 = 0x0003
-	PermitDenySetGroupPermitMask       uint16 = 0x0004
-	PermitDenyAddPermListEntries       uint16 = 0x0005
-	PermitDenyDelPermListEntries       uint16 = 0x0006
-	PermitDenyAddDenyListEntries       uint16 = 0x0007
-	PermitDenyDelDenyListEntries       uint16 = 0x0008
-	PermitDenyBosErr                   uint16 = 0x0009
-	PermitDenyAddTempPermitListEntries uint16 = 0x000A
-	PermitDenyDelTempPermitListEntries uint16 = 0x000B
-	PermitDenyTLVMaxPermits            uint16 = 0x01
-	PermitDenyTLVMaxDenies             uint16 = 0x02
-	PermitDenyTLVMaxTempPermits        uint16 = 0x03
-
-	AdvertErr      uint16 = 0x0001
-	AdvertAdsQuery uint16 = 0x0002
-	AdvertAdsReply uint16 = 0x0003
-
-	InviteErr          uint16 = 0x0001
-	InviteRequestQuery uint16 = 0x0002
-	InviteRequestReply uint16 = 0x0003
+	PermitDenyTLVMaxPermits     uint16 = 0x01
+	PermitDenyTLVMaxDenies      uint16 = 0x02
+	PermitDenyTLVMaxTempPermits uint16 = 0x03
 
-	AdminErr                                    uint16 = 0x0001
-	AdminInfoQuery                              uint16 = 0x0002
-	AdminInfoReply                              uint16 = 0x0003
-	AdminInfoChangeRequest                      uint16 = 0x0004
-	AdminInfoChangeReply                        uint16 = 0x0005
-	AdminAcctConfirmRequest                     uint16 = 0x0006
-	AdminAcctConfirmReply                       uint16 = 0x0007
-	AdminAcctDeleteRequest                      uint16 = 0x0008
-	AdminAcctDeleteReply                        uint16 = 0x0009
 	AdminInfoErrorValidateNickName              uint16 = 0x0001
 	AdminInfoErrorValidatePassword              uint16 = 0x0002
 	AdminInfoErrorValidateEmail                 uint16 = 0x0003
@@ -720,10 +877,20 @@ const (
 	AdminTLVEmailAddress                        uint16 = 0x11
 	AdminTLVOldPassword                         uint16 = 0x12
 	AdminTLVRegistrationStatus                  uint16 = 0x13
+	AdminTLVEmailVerified                       uint16 = 0x80 // go-icq extension, ignored by official clients
+
+	AlertTLVMailURL        uint16 = 0x0007 // string	URL of the web mailbox
+	AlertTLVMailCount      uint16 = 0x0080 // uint16 (word)	number of unread messages
+	AlertTLVMailNew        uint16 = 0x0081 // uint8 (byte)	present if new mail arrived since the last notification
+	AlertTLVMailDomain     uint16 = 0x0082 // string	mail domain, such as aol.com
+	AlertTLVMailAlertFlags uint16 = 0x0084 // uint16 (word)	1 if the client should play the new mail alert
+
+	PopupTLVMessage uint16 = 0x0001 // string	HTML text of the popup
+	PopupTLVURL     uint16 = 0x0002 // string	URL of the page shown in the popup
+	PopupTLVWidth   uint16 = 0x0003 // uint16 (word)	popup width in pixels
+	PopupTLVHeight  uint16 = 0x0004 // uint16 (word)	popup height in pixels
+	PopupTLVDelay   uint16 = 0x0005 // uint16 (word)	seconds before the popup is shown
 
-	ICQErr                              uint16 = 0x0001
-	ICQDBQuery                          uint16 = 0x0002
-	ICQDBReply                          uint16 = 0x0003
 	ICQTLVTagsMetadata                  uint16 = 0x0001
 	ICQTLVTagsUIN                       uint16 = 0x0136 // User UIN (search)
 	ICQTLVTagsFirstName                 uint16 = 0x0140 // User first name
@@ -833,11 +1000,6 @@ const (
 	ICQDBQueryMetaReplyLastUserFound   uint16 = 0x01AE
 	ICQDBQueryMetaReplyXMLData         uint16 = 0x08A2
 
-	ODirErr                          uint16 = 0x0001
-	ODirInfoQuery                    uint16 = 0x0002
-	ODirInfoReply                    uint16 = 0x0003
-	ODirKeywordListQuery             uint16 = 0x0004
-	ODirKeywordListReply             uint16 = 0x0005
 	ODirTLVFirstName                 uint16 = 0x0001 // The first name of the individual being searched.
 	ODirTLVLastName                  uint16 = 0x0002 // The last name of the individual being searched.
 	ODirTLVMiddleName                uint16 = 0x0003 // The middle name of the individual being searched.
@@ -863,21 +1025,15 @@ const (
 	ODirSearchResponseNameMissing    uint16 = 0x04 // Missing first or last name
 	ODirSearchResponseOK             uint16 = 0x05 // Successful search
 
-	StatsErr                  uint16 = 0x0001
-	StatsSetMinReportInterval uint16 = 0x0002
-	StatsReportEvents         uint16 = 0x0003
-	StatsReportAck            uint16 = 0x0004
+	ODirSearchByScreenName uint16 = 0x0080 // go-icq extension: find screen names containing ODirTLVScreenName, ignored by official clients
 
-	KerberosLoginRequest             uint16 = 0x0002
-	KerberosLoginSuccessResponse     uint16 = 0x0003
-	KerberosKerberosLoginErrResponse uint16 = 0x0004
-	KerberosTLVTicketRequest         uint16 = 0x0002
-	KerberosTLVBOSServerInfo         uint16 = 0x0003
-	KerberosTLVHostname              uint16 = 0x0005
-	KerberosTLVCookie                uint16 = 0x0006
-	KerberosTLVConnSettings          uint16 = 0x008E
-	KerberosConnUseSSL               uint16 = 0x0002
-	KerberosErrAuthFailure           uint16 = 0x0401
+	KerberosTLVTicketRequest uint16 = 0x0002
+	KerberosTLVBOSServerInfo uint16 = 0x0003
+	KerberosTLVHostname      uint16 = 0x0005
+	KerberosTLVCookie        uint16 = 0x0006
+	KerberosTLVConnSettings  uint16 = 0x008E
+	KerberosConnUseSSL       uint16 = 0x0002
+	KerberosErrAuthFailure   uint16 = 0x0401
 
 	// FeedbagPDModePermitAll allows all users to see and talk to user.
 	// This is the session default.
@@ -959,31 +1115,14 @@ const (
 	ErrorTLVErrorInfoCLSID        uint16 = 0x0029 // UUID specifying format of ERROR_INFO_DATA data
 	ErrorTLVErrorInfoData         uint16 = 0x002A // Extra information describing error
 
-	BUCPErr                      uint16 = 0x0001
-	BUCPLoginRequest             uint16 = 0x0002
-	BUCPLoginResponse            uint16 = 0x0003
-	BUCPRegisterRequest          uint16 = 0x0004
-	BUCPChallengeRequest         uint16 = 0x0006
-	BUCPChallengeResponse        uint16 = 0x0007
-	BUCPAsasnRequest             uint16 = 0x0008
-	BUCPSecuridRequest           uint16 = 0x000A
-	BUCPRegistrationImageRequest uint16 = 0x000C
-
-	UserLookupErr         uint16 = 0x0001
-	UserLookupFindByEmail uint16 = 0x0002
-	UserLookupFindReply   uint16 = 0x0003
-
 	UserLookupErrNoUserFound uint16 = 0x0014
 
 	UserLookupTLVEmailAddress uint16 = 0x0001
-
-	PopupErr     uint16 = 0x0001
-	PopupDisplay uint16 = 0x0002
 )
 
 type TLVUserInfo struct {
 	TLVBlock
-	ScreenName   string `oscar:"len_prefix=uint8"`
+	ScreenName   ScreenName `oscar:"len_prefix=uint8"`
 	WarningLevel uint16
 }
 
@@ -1066,9 +1205,10 @@ type ICQMetadata struct {
 
 type ICQMetadataWithSubType struct {
 	ICQMetadata
+	// Optional is set only for ICQDBQueryMetaReq requests.
 	Optional *struct {
 		ReqSubType uint16
-	} `oscar:"optional"`
+	} `oscar:"optional,when=ReqType==0x07D0"`
 }
 
 type ICQEmail struct {
@@ -1679,6 +1819,12 @@ type SNAC_0x01_0x08_OServiceRateParamsSubAdd struct {
 	ClassIDs []uint16
 }
 
+// SNAC_0x01_0x15_OServiceWellKnownURLs tells the client where to find web
+// services it uses, one URL per TLV.
+type SNAC_0x01_0x15_OServiceWellKnownURLs struct {
+	TLVRestBlock
+}
+
 type SNAC_0x01_0x17_OServiceClientVersions struct {
 	Versions []uint16
 }
@@ -1716,7 +1862,7 @@ type SNAC_0x02_0x0A_LocateSetDirReply struct {
 }
 
 type SNAC_0x02_0x0B_LocateGetDirInfo struct {
-	ScreenName string `oscar:"len_prefix=uint8"`
+	ScreenName ScreenName `oscar:"len_prefix=uint8"`
 }
 
 type SNAC_0x02_0x0C_LocateGetDirReply struct {
@@ -1735,7 +1881,7 @@ type SNAC_0x02_0x10_LocateSetKeywordReply struct {
 
 type SNAC_0x02_0x15_LocateUserInfoQuery2 struct {
 	Type2      uint32
-	ScreenName string `oscar:"len_prefix=uint8"`
+	ScreenName ScreenName `oscar:"len_prefix=uint8"`
 }
 
 type SNAC_0x03_0x02_BuddyRightsQuery struct {
@@ -1791,13 +1937,13 @@ type SNAC_0x04_0x05_ICBMParameterReply struct {
 type SNAC_0x04_0x06_ICBMChannelMsgToHost struct {
 	Cookie     uint64
 	ChannelID  uint16
-	ScreenName string `oscar:"len_prefix=uint8"`
+	ScreenName ScreenName `oscar:"len_prefix=uint8"`
 	TLVRestBlock
 }
 
 type SNAC_0x04_0x08_ICBMEvilRequest struct {
 	SendAs     uint16
-	ScreenName string `oscar:"len_prefix=uint8"`
+	ScreenName ScreenName `oscar:"len_prefix=uint8"`
 }
 
 type SNAC_0x04_0x09_ICBMEvilReply struct {
@@ -1808,7 +1954,7 @@ type SNAC_0x04_0x09_ICBMEvilReply struct {
 type SNAC_0x04_0x0B_ICBMClientErr struct {
 	Cookie     uint64
 	ChannelID  uint16
-	ScreenName string `oscar:"len_prefix=uint8"`
+	ScreenName ScreenName `oscar:"len_prefix=uint8"`
 	Code       uint16
 	ErrInfo    []byte
 }
@@ -1816,13 +1962,13 @@ type SNAC_0x04_0x0B_ICBMClientErr struct {
 type SNAC_0x04_0x0C_ICBMHostAck struct {
 	Cookie     uint64
 	ChannelID  uint16
-	ScreenName string `oscar:"len_prefix=uint8"`
+	ScreenName ScreenName `oscar:"len_prefix=uint8"`
 }
 
 type SNAC_0x04_0x14_ICBMClientEvent struct {
 	Cookie     uint64
 	ChannelID  uint16
-	ScreenName string `oscar:"len_prefix=uint8"`
+	ScreenName ScreenName `oscar:"len_prefix=uint8"`
 	Event      uint16
 }
 
@@ -1891,19 +2037,19 @@ type SNAC_0x13_0x11_FeedbagStartCluster struct {
 }
 
 type SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost struct {
-	ScreenName string `oscar:"len_prefix=uint8"`
-	Reason     string `oscar:"len_prefix=uint16"`
+	ScreenName ScreenName `oscar:"len_prefix=uint8"`
+	Reason     string     `oscar:"len_prefix=uint16"`
 	Unknown    uint16
 }
 
 type SNAC_0x13_0x1A_FeedbagRespondAuthorizeToHost struct {
-	ScreenName string `oscar:"len_prefix=uint8"`
+	ScreenName ScreenName `oscar:"len_prefix=uint8"`
 	Accepted   uint8
 	Reason     string `oscar:"len_prefix=uint16"`
 }
 
 type SNAC_0x13_0x1B_FeedbagRespondAuthorizeToClient struct {
-	ScreenName string `oscar:"len_prefix=uint8"`
+	ScreenName ScreenName `oscar:"len_prefix=uint8"`
 	Accepted   uint8
 	Reason     string `oscar:"len_prefix=uint16"`
 }
@@ -2014,24 +2160,24 @@ type SNAC_0x10_0x03_BARTUploadReply struct {
 }
 
 type SNAC_0x10_0x04_BARTDownloadQuery struct {
-	ScreenName string `oscar:"len_prefix=uint8"`
+	ScreenName ScreenName `oscar:"len_prefix=uint8"`
 	Command    uint8
 	BARTID
 }
 
 type SNAC_0x10_0x05_BARTDownloadReply struct {
-	ScreenName string `oscar:"len_prefix=uint8"`
+	ScreenName ScreenName `oscar:"len_prefix=uint8"`
 	BARTID     BARTID
 	Data       []byte `oscar:"len_prefix=uint16"`
 }
 
 type SNAC_0x10_0x06_BARTDownload2Query struct {
-	ScreenName string   `oscar:"len_prefix=uint8"`
-	IDs        []BARTID `oscar:"count_prefix=uint8"`
+	ScreenName ScreenName `oscar:"len_prefix=uint8"`
+	IDs        []BARTID   `oscar:"count_prefix=uint8"`
 }
 
 type SNAC_0x10_0x07_BARTDownload2Reply struct {
-	ScreenName string `oscar:"len_prefix=uint8"`
+	ScreenName ScreenName `oscar:"len_prefix=uint8"`
 	ReplyID    BartQueryReplyID
 	Data       []byte `oscar:"len_prefix=uint16"`
 }
@@ -2123,7 +2269,7 @@ func (s SNAC_0x01_0x14_OServiceSetPrivacyFlags) MemberFlag() bool {
 
 type SNAC_0x02_0x05_LocateUserInfoQuery struct {
 	Type       uint16
-	ScreenName string `oscar:"len_prefix=uint8"`
+	ScreenName ScreenName `oscar:"len_prefix=uint8"`
 }
 
 func (s SNAC_0x02_0x05_LocateUserInfoQuery) RequestProfile() bool {
@@ -2171,6 +2317,39 @@ type ICBMCh1Message struct {
 	Text     []byte
 }
 
+// DecodedText returns the message text as UTF-8, converting it from the
+// message's charset. Text in ASCII or an unknown charset is returned as is.
+func (m ICBMCh1Message) DecodedText() (string, error) {
+	switch m.Charset {
+	case ICBMMessageEncodingUnicode:
+		return DecodeUCS2(m.Text)
+	case ICBMMessageEncodingLatin1:
+		return DecodeLatin1(m.Text), nil
+	default:
+		return string(m.Text), nil
+	}
+}
+
+// SNAC_0x18_0x07_AlertNotify is the mail status notification that lights up
+// the mail icon of AIM clients. The layout follows the mail status SNAC as
+// parsed by libfaim.
+type SNAC_0x18_0x07_AlertNotify struct {
+	// Cookie identifies the notification.
+	Cookie uint64
+	// Service is the UUID of the alert service, AlertServiceMail for mail.
+	Service [16]byte
+	TLVBlock
+}
+
+// SNAC_0x08_0x02_PopupDisplay asks the client to show a popup window with a
+// message and, optionally, a web page.
+type SNAC_0x08_0x02_PopupDisplay struct {
+	TLVRestBlock
+}
+
+// AlertServiceMail is the alert service UUID of mail notifications.
+var AlertServiceMail = [16]byte{0xb3, 0x80, 0x9a, 0xd8, 0x0d, 0xba, 0x11, 0xd5, 0x9f, 0x8a, 0x00, 0x60, 0xb0, 0xee, 0x06, 0x31}
+
 // ICBMCh4Message represents an ICBM channel 4 (ICQ) message component.
 type ICBMCh4Message struct {
 	UIN         uint32
@@ -2228,7 +2407,8 @@ func UnmarshalChatMessageText(b []byte) (string, error) {
 	}
 }
 
-// UnmarshalICBMMessageText extracts message text from an ICBM fragment list.
+// UnmarshalICBMMessageText extracts message text from an ICBM fragment list
+// and returns it as UTF-8, see ICBMCh1Message.DecodedText.
 // Param b is a slice from TLV wire.ICBMTLVAOLIMData.
 func UnmarshalICBMMessageText(b []byte) (string, error) {
 	var frags []ICBMCh1Fragment
@@ -2239,11 +2419,10 @@ func UnmarshalICBMMessageText(b []byte) (string, error) {
 	for _, frag := range frags {
 		if frag.ID == 1 { // 1 = message text
 			msg := ICBMCh1Message{}
-			err := UnmarshalBE(&msg, bytes.NewBuffer(frag.Payload))
-			if err != nil {
-				err = fmt.Errorf("unable to unmarshal ICBM message: %w", err)
+			if err := UnmarshalBE(&msg, bytes.NewBuffer(frag.Payload)); err != nil {
+				return string(msg.Text), fmt.Errorf("unable to unmarshal ICBM message: %w", err)
 			}
-			return string(msg.Text), err
+			return msg.DecodedText()
 		}
 	}
 
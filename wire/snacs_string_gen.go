@@ -0,0 +1,639 @@
+// Code generated by snac_string_generator; DO NOT EDIT.
+
+package wire
+
+var (
+	foodGroupName = map[uint16]string{
+		BOS:         "BOS",
+		OService:    "OService",
+		Locate:      "Locate",
+		Buddy:       "Buddy",
+		ICBM:        "ICBM",
+		Advert:      "Advert",
+		Invite:      "Invite",
+		Admin:       "Admin",
+		Popup:       "Popup",
+		PermitDeny:  "PermitDeny",
+		UserLookup:  "UserLookup",
+		Stats:       "Stats",
+		Translate:   "Translate",
+		ChatNav:     "ChatNav",
+		Chat:        "Chat",
+		ODir:        "ODir",
+		BART:        "BART",
+		Feedbag:     "Feedbag",
+		ICQ:         "ICQ",
+		BUCP:        "BUCP",
+		Alert:       "Alert",
+		Plugin:      "Plugin",
+		UnnamedFG24: "UnnamedFG24",
+		MDir:        "MDir",
+		ARS:         "ARS",
+		Kerberos:    "Kerberos",
+	}
+	subGroupName = map[uint16]map[uint16]string{
+		OService: {
+			OServiceErr:               "OServiceErr",
+			OServiceClientOnline:      "OServiceClientOnline",
+			OServiceHostOnline:        "OServiceHostOnline",
+			OServiceServiceRequest:    "OServiceServiceRequest",
+			OServiceServiceResponse:   "OServiceServiceResponse",
+			OServiceRateParamsQuery:   "OServiceRateParamsQuery",
+			OServiceRateParamsReply:   "OServiceRateParamsReply",
+			OServiceRateParamsSubAdd:  "OServiceRateParamsSubAdd",
+			OServiceRateDelParamSub:   "OServiceRateDelParamSub",
+			OServiceRateParamChange:   "OServiceRateParamChange",
+			OServicePauseReq:          "OServicePauseReq",
+			OServicePauseAck:          "OServicePauseAck",
+			OServiceResume:            "OServiceResume",
+			OServiceUserInfoQuery:     "OServiceUserInfoQuery",
+			OServiceUserInfoUpdate:    "OServiceUserInfoUpdate",
+			OServiceEvilNotification:  "OServiceEvilNotification",
+			OServiceIdleNotification:  "OServiceIdleNotification",
+			OServiceMigrateGroups:     "OServiceMigrateGroups",
+			OServiceMotd:              "OServiceMotd",
+			OServiceSetPrivacyFlags:   "OServiceSetPrivacyFlags",
+			OServiceWellKnownUrls:     "OServiceWellKnownUrls",
+			OServiceNoop:              "OServiceNoop",
+			OServiceClientVersions:    "OServiceClientVersions",
+			OServiceHostVersions:      "OServiceHostVersions",
+			OServiceMaxConfigQuery:    "OServiceMaxConfigQuery",
+			OServiceMaxConfigReply:    "OServiceMaxConfigReply",
+			OServiceStoreConfig:       "OServiceStoreConfig",
+			OServiceConfigQuery:       "OServiceConfigQuery",
+			OServiceConfigReply:       "OServiceConfigReply",
+			OServiceSetUserInfoFields: "OServiceSetUserInfoFields",
+			OServiceProbeReq:          "OServiceProbeReq",
+			OServiceProbeAck:          "OServiceProbeAck",
+			OServiceBartReply:         "OServiceBartReply",
+			OServiceBartQuery2:        "OServiceBartQuery2",
+			OServiceBartReply2:        "OServiceBartReply2",
+		},
+		Locate: {
+			LocateErr:                  "LocateErr",
+			LocateRightsQuery:          "LocateRightsQuery",
+			LocateRightsReply:          "LocateRightsReply",
+			LocateSetInfo:              "LocateSetInfo",
+			LocateUserInfoQuery:        "LocateUserInfoQuery",
+			LocateUserInfoReply:        "LocateUserInfoReply",
+			LocateWatcherSubRequest:    "LocateWatcherSubRequest",
+			LocateWatcherNotification:  "LocateWatcherNotification",
+			LocateSetDirInfo:           "LocateSetDirInfo",
+			LocateSetDirReply:          "LocateSetDirReply",
+			LocateGetDirInfo:           "LocateGetDirInfo",
+			LocateGetDirReply:          "LocateGetDirReply",
+			LocateGroupCapabilityQuery: "LocateGroupCapabilityQuery",
+			LocateGroupCapabilityReply: "LocateGroupCapabilityReply",
+			LocateSetKeywordInfo:       "LocateSetKeywordInfo",
+			LocateSetKeywordReply:      "LocateSetKeywordReply",
+			LocateGetKeywordInfo:       "LocateGetKeywordInfo",
+			LocateGetKeywordReply:      "LocateGetKeywordReply",
+			LocateFindListByEmail:      "LocateFindListByEmail",
+			LocateFindListReply:        "LocateFindListReply",
+			LocateUserInfoQuery2:       "LocateUserInfoQuery2",
+		},
+		Buddy: {
+			BuddyErr:                 "BuddyErr",
+			BuddyRightsQuery:         "BuddyRightsQuery",
+			BuddyRightsReply:         "BuddyRightsReply",
+			BuddyAddBuddies:          "BuddyAddBuddies",
+			BuddyDelBuddies:          "BuddyDelBuddies",
+			BuddyWatcherListQuery:    "BuddyWatcherListQuery",
+			BuddyWatcherListResponse: "BuddyWatcherListResponse",
+			BuddyWatcherSubRequest:   "BuddyWatcherSubRequest",
+			BuddyWatcherNotification: "BuddyWatcherNotification",
+			BuddyRejectNotification:  "BuddyRejectNotification",
+			BuddyArrived:             "BuddyArrived",
+			BuddyDeparted:            "BuddyDeparted",
+			BuddyAddTempBuddies:      "BuddyAddTempBuddies",
+			BuddyDelTempBuddies:      "BuddyDelTempBuddies",
+		},
+		ICBM: {
+			ICBMErr:                  "ICBMErr",
+			ICBMAddParameters:        "ICBMAddParameters",
+			ICBMDelParameters:        "ICBMDelParameters",
+			ICBMParameterQuery:       "ICBMParameterQuery",
+			ICBMParameterReply:       "ICBMParameterReply",
+			ICBMChannelMsgToHost:     "ICBMChannelMsgToHost",
+			ICBMChannelMsgToClient:   "ICBMChannelMsgToClient",
+			ICBMEvilRequest:          "ICBMEvilRequest",
+			ICBMEvilReply:            "ICBMEvilReply",
+			ICBMMissedCalls:          "ICBMMissedCalls",
+			ICBMClientErr:            "ICBMClientErr",
+			ICBMHostAck:              "ICBMHostAck",
+			ICBMSinStored:            "ICBMSinStored",
+			ICBMSinListQuery:         "ICBMSinListQuery",
+			ICBMSinListReply:         "ICBMSinListReply",
+			ICBMOfflineRetrieve:      "ICBMOfflineRetrieve",
+			ICBMSinDelete:            "ICBMSinDelete",
+			ICBMNotifyRequest:        "ICBMNotifyRequest",
+			ICBMNotifyReply:          "ICBMNotifyReply",
+			ICBMClientEvent:          "ICBMClientEvent",
+			ICBMOfflineRetrieveReply: "ICBMOfflineRetrieveReply",
+		},
+		Advert: {
+			AdvertErr:      "AdvertErr",
+			AdvertAdsQuery: "AdvertAdsQuery",
+			AdvertAdsReply: "AdvertAdsReply",
+		},
+		Invite: {
+			InviteErr:          "InviteErr",
+			InviteRequestQuery: "InviteRequestQuery",
+			InviteRequestReply: "InviteRequestReply",
+		},
+		Admin: {
+			AdminErr:                "AdminErr",
+			AdminInfoQuery:          "AdminInfoQuery",
+			AdminInfoReply:          "AdminInfoReply",
+			AdminInfoChangeRequest:  "AdminInfoChangeRequest",
+			AdminInfoChangeReply:    "AdminInfoChangeReply",
+			AdminAcctConfirmRequest: "AdminAcctConfirmRequest",
+			AdminAcctConfirmReply:   "AdminAcctConfirmReply",
+			AdminAcctDeleteRequest:  "AdminAcctDeleteRequest",
+			AdminAcctDeleteReply:    "AdminAcctDeleteReply",
+		},
+		Popup: {
+			PopupErr:     "PopupErr",
+			PopupDisplay: "PopupDisplay",
+		},
+		PermitDeny: {
+			PermitDenyErr:                      "PermitDenyErr",
+			PermitDenyRightsQuery:              "PermitDenyRightsQuery",
+			PermitDenyRightsReply:              "PermitDenyRightsReply",
+			PermitDenySetGroupPermitMask:       "PermitDenySetGroupPermitMask",
+			PermitDenyAddPermListEntries:       "PermitDenyAddPermListEntries",
+			PermitDenyDelPermListEntries:       "PermitDenyDelPermListEntries",
+			PermitDenyAddDenyListEntries:       "PermitDenyAddDenyListEntries",
+			PermitDenyDelDenyListEntries:       "PermitDenyDelDenyListEntries",
+			PermitDenyBosErr:                   "PermitDenyBosErr",
+			PermitDenyAddTempPermitListEntries: "PermitDenyAddTempPermitListEntries",
+			PermitDenyDelTempPermitListEntries: "PermitDenyDelTempPermitListEntries",
+		},
+		UserLookup: {
+			UserLookupErr:         "UserLookupErr",
+			UserLookupFindByEmail: "UserLookupFindByEmail",
+			UserLookupFindReply:   "UserLookupFindReply",
+		},
+		Stats: {
+			StatsErr:                  "StatsErr",
+			StatsSetMinReportInterval: "StatsSetMinReportInterval",
+			StatsReportEvents:         "StatsReportEvents",
+			StatsReportAck:            "StatsReportAck",
+		},
+		Translate: {
+			TranslateErr:     "TranslateErr",
+			TranslateRequest: "TranslateRequest",
+			TranslateReply:   "TranslateReply",
+		},
+		ChatNav: {
+			ChatNavErr:                 "ChatNavErr",
+			ChatNavRequestChatRights:   "ChatNavRequestChatRights",
+			ChatNavRequestExchangeInfo: "ChatNavRequestExchangeInfo",
+			ChatNavRequestRoomInfo:     "ChatNavRequestRoomInfo",
+			ChatNavRequestMoreRoomInfo: "ChatNavRequestMoreRoomInfo",
+			ChatNavRequestOccupantList: "ChatNavRequestOccupantList",
+			ChatNavSearchForRoom:       "ChatNavSearchForRoom",
+			ChatNavCreateRoom:          "ChatNavCreateRoom",
+			ChatNavNavInfo:             "ChatNavNavInfo",
+		},
+		Chat: {
+			ChatErr:                "ChatErr",
+			ChatRoomInfoUpdate:     "ChatRoomInfoUpdate",
+			ChatUsersJoined:        "ChatUsersJoined",
+			ChatUsersLeft:          "ChatUsersLeft",
+			ChatChannelMsgToHost:   "ChatChannelMsgToHost",
+			ChatChannelMsgToClient: "ChatChannelMsgToClient",
+			ChatEvilRequest:        "ChatEvilRequest",
+			ChatEvilReply:          "ChatEvilReply",
+			ChatClientErr:          "ChatClientErr",
+			ChatPauseRoomReq:       "ChatPauseRoomReq",
+			ChatPauseRoomAck:       "ChatPauseRoomAck",
+			ChatResumeRoom:         "ChatResumeRoom",
+			ChatShowMyRow:          "ChatShowMyRow",
+			ChatShowRowByUsername:  "ChatShowRowByUsername",
+			ChatShowRowByNumber:    "ChatShowRowByNumber",
+			ChatShowRowByName:      "ChatShowRowByName",
+			ChatRowInfo:            "ChatRowInfo",
+			ChatListRows:           "ChatListRows",
+			ChatRowListInfo:        "ChatRowListInfo",
+			ChatMoreRows:           "ChatMoreRows",
+			ChatMoveToRow:          "ChatMoveToRow",
+			ChatToggleChat:         "ChatToggleChat",
+			ChatSendQuestion:       "ChatSendQuestion",
+			ChatSendComment:        "ChatSendComment",
+			ChatTallyVote:          "ChatTallyVote",
+			ChatAcceptBid:          "ChatAcceptBid",
+			ChatSendInvite:         "ChatSendInvite",
+			ChatDeclineInvite:      "ChatDeclineInvite",
+			ChatAcceptInvite:       "ChatAcceptInvite",
+			ChatNotifyMessage:      "ChatNotifyMessage",
+			ChatGotoRow:            "ChatGotoRow",
+			ChatStageUserJoin:      "ChatStageUserJoin",
+			ChatStageUserLeft:      "ChatStageUserLeft",
+			ChatUnnamedSnac22:      "ChatUnnamedSnac22",
+			ChatClose:              "ChatClose",
+			ChatUserBan:            "ChatUserBan",
+			ChatUserUnban:          "ChatUserUnban",
+			ChatJoined:             "ChatJoined",
+			ChatUnnamedSnac27:      "ChatUnnamedSnac27",
+			ChatUnnamedSnac28:      "ChatUnnamedSnac28",
+			ChatUnnamedSnac29:      "ChatUnnamedSnac29",
+			ChatRoomInfoOwner:      "ChatRoomInfoOwner",
+		},
+		ODir: {
+			ODirErr:              "ODirErr",
+			ODirInfoQuery:        "ODirInfoQuery",
+			ODirInfoReply:        "ODirInfoReply",
+			ODirKeywordListQuery: "ODirKeywordListQuery",
+			ODirKeywordListReply: "ODirKeywordListReply",
+		},
+		BART: {
+			BARTErr:            "BARTErr",
+			BARTUploadQuery:    "BARTUploadQuery",
+			BARTUploadReply:    "BARTUploadReply",
+			BARTDownloadQuery:  "BARTDownloadQuery",
+			BARTDownloadReply:  "BARTDownloadReply",
+			BARTDownload2Query: "BARTDownload2Query",
+			BARTDownload2Reply: "BARTDownload2Reply",
+		},
+		Feedbag: {
+			FeedbagErr:                      "FeedbagErr",
+			FeedbagRightsQuery:              "FeedbagRightsQuery",
+			FeedbagRightsReply:              "FeedbagRightsReply",
+			FeedbagQuery:                    "FeedbagQuery",
+			FeedbagQueryIfModified:          "FeedbagQueryIfModified",
+			FeedbagReply:                    "FeedbagReply",
+			FeedbagUse:                      "FeedbagUse",
+			FeedbagInsertItem:               "FeedbagInsertItem",
+			FeedbagUpdateItem:               "FeedbagUpdateItem",
+			FeedbagDeleteItem:               "FeedbagDeleteItem",
+			FeedbagInsertClass:              "FeedbagInsertClass",
+			FeedbagUpdateClass:              "FeedbagUpdateClass",
+			FeedbagDeleteClass:              "FeedbagDeleteClass",
+			FeedbagStatus:                   "FeedbagStatus",
+			FeedbagReplyNotModified:         "FeedbagReplyNotModified",
+			FeedbagDeleteUser:               "FeedbagDeleteUser",
+			FeedbagStartCluster:             "FeedbagStartCluster",
+			FeedbagEndCluster:               "FeedbagEndCluster",
+			FeedbagAuthorizeBuddy:           "FeedbagAuthorizeBuddy",
+			FeedbagPreAuthorizeBuddy:        "FeedbagPreAuthorizeBuddy",
+			FeedbagPreAuthorizedBuddy:       "FeedbagPreAuthorizedBuddy",
+			FeedbagRemoveMe:                 "FeedbagRemoveMe",
+			FeedbagRemoveMe2:                "FeedbagRemoveMe2",
+			FeedbagRequestAuthorizeToHost:   "FeedbagRequestAuthorizeToHost",
+			FeedbagRequestAuthorizeToClient: "FeedbagRequestAuthorizeToClient",
+			FeedbagRespondAuthorizeToHost:   "FeedbagRespondAuthorizeToHost",
+			FeedbagRespondAuthorizeToClient: "FeedbagRespondAuthorizeToClient",
+			FeedbagBuddyAdded:               "FeedbagBuddyAdded",
+			FeedbagRequestAuthorizeToBadog:  "FeedbagRequestAuthorizeToBadog",
+			FeedbagRespondAuthorizeToBadog:  "FeedbagRespondAuthorizeToBadog",
+			FeedbagBuddyAddedToBadog:        "FeedbagBuddyAddedToBadog",
+			FeedbagTestSnac:                 "FeedbagTestSnac",
+			FeedbagForwardMsg:               "FeedbagForwardMsg",
+			FeedbagIsAuthRequiredQuery:      "FeedbagIsAuthRequiredQuery",
+			FeedbagIsAuthRequiredReply:      "FeedbagIsAuthRequiredReply",
+			FeedbagRecentBuddyUpdate:        "FeedbagRecentBuddyUpdate",
+		},
+		ICQ: {
+			ICQErr:     "ICQErr",
+			ICQDBQuery: "ICQDBQuery",
+			ICQDBReply: "ICQDBReply",
+		},
+		BUCP: {
+			BUCPErr:                      "BUCPErr",
+			BUCPLoginRequest:             "BUCPLoginRequest",
+			BUCPLoginResponse:            "BUCPLoginResponse",
+			BUCPRegisterRequest:          "BUCPRegisterRequest",
+			BUCPChallengeRequest:         "BUCPChallengeRequest",
+			BUCPChallengeResponse:        "BUCPChallengeResponse",
+			BUCPAsasnRequest:             "BUCPAsasnRequest",
+			BUCPSecuridRequest:           "BUCPSecuridRequest",
+			BUCPRegistrationImageRequest: "BUCPRegistrationImageRequest",
+		},
+		Alert: {
+			AlertErr:                       "AlertErr",
+			AlertSetAlertRequest:           "AlertSetAlertRequest",
+			AlertSetAlertReply:             "AlertSetAlertReply",
+			AlertGetSubsRequest:            "AlertGetSubsRequest",
+			AlertGetSubsResponse:           "AlertGetSubsResponse",
+			AlertNotifyCapabilities:        "AlertNotifyCapabilities",
+			AlertNotify:                    "AlertNotify",
+			AlertGetRuleRequest:            "AlertGetRuleRequest",
+			AlertGetRuleReply:              "AlertGetRuleReply",
+			AlertGetFeedRequest:            "AlertGetFeedRequest",
+			AlertGetFeedReply:              "AlertGetFeedReply",
+			AlertRefreshFeed:               "AlertRefreshFeed",
+			AlertEvent:                     "AlertEvent",
+			AlertQogSnac:                   "AlertQogSnac",
+			AlertRefreshFeedStock:          "AlertRefreshFeedStock",
+			AlertNotifyTransport:           "AlertNotifyTransport",
+			AlertSetAlertRequestV2:         "AlertSetAlertRequestV2",
+			AlertSetAlertReplyV2:           "AlertSetAlertReplyV2",
+			AlertTransitReply:              "AlertTransitReply",
+			AlertNotifyAck:                 "AlertNotifyAck",
+			AlertNotifyDisplayCapabilities: "AlertNotifyDisplayCapabilities",
+			AlertUserOnline:                "AlertUserOnline",
+		},
+		Kerberos: {
+			KerberosLoginRequest:             "KerberosLoginRequest",
+			KerberosLoginSuccessResponse:     "KerberosLoginSuccessResponse",
+			KerberosKerberosLoginErrResponse: "KerberosKerberosLoginErrResponse",
+		},
+	}
+	tlvTagName = map[string]map[uint16]string{
+		"AdminTLV": {
+			AdminTLVScreenNameFormatted: "AdminTLVScreenNameFormatted",
+			AdminTLVNewPassword:         "AdminTLVNewPassword",
+			AdminTLVUrl:                 "AdminTLVUrl",
+			AdminTLVErrorCode:           "AdminTLVErrorCode",
+			AdminTLVEmailAddress:        "AdminTLVEmailAddress",
+			AdminTLVOldPassword:         "AdminTLVOldPassword",
+			AdminTLVRegistrationStatus:  "AdminTLVRegistrationStatus",
+			AdminTLVEmailVerified:       "AdminTLVEmailVerified",
+		},
+		"AlertTLV": {
+			AlertTLVMailURL:        "AlertTLVMailURL",
+			AlertTLVMailCount:      "AlertTLVMailCount",
+			AlertTLVMailNew:        "AlertTLVMailNew",
+			AlertTLVMailDomain:     "AlertTLVMailDomain",
+			AlertTLVMailAlertFlags: "AlertTLVMailAlertFlags",
+		},
+		"BuddyTLVTags": {
+			BuddyTLVTagsParmMaxBuddies:     "BuddyTLVTagsParmMaxBuddies",
+			BuddyTLVTagsParmMaxWatchers:    "BuddyTLVTagsParmMaxWatchers",
+			BuddyTLVTagsParmMaxIcqBroad:    "BuddyTLVTagsParmMaxIcqBroad",
+			BuddyTLVTagsParmMaxTempBuddies: "BuddyTLVTagsParmMaxTempBuddies",
+		},
+		"ChatNavTLV": {
+			ChatNavTLVMaxConcurrentRooms: "ChatNavTLVMaxConcurrentRooms",
+			ChatNavTLVExchangeInfo:       "ChatNavTLVExchangeInfo",
+			ChatNavTLVRoomInfo:           "ChatNavTLVRoomInfo",
+		},
+		"ChatRoomTLV": {
+			ChatRoomTLVClassPerms:         "ChatRoomTLVClassPerms",
+			ChatRoomTLVMaxConcurrentRooms: "ChatRoomTLVMaxConcurrentRooms",
+			ChatRoomTLVMaxNameLen:         "ChatRoomTLVMaxNameLen",
+			ChatRoomTLVFullyQualifiedName: "ChatRoomTLVFullyQualifiedName",
+			ChatRoomTLVCreateTime:         "ChatRoomTLVCreateTime",
+			ChatRoomTLVFlags:              "ChatRoomTLVFlags",
+			ChatRoomTLVMaxMsgLen:          "ChatRoomTLVMaxMsgLen",
+			ChatRoomTLVMaxOccupancy:       "ChatRoomTLVMaxOccupancy",
+			ChatRoomTLVRoomName:           "ChatRoomTLVRoomName",
+			ChatRoomTLVNavCreatePerms:     "ChatRoomTLVNavCreatePerms",
+			ChatRoomTLVCharSet1:           "ChatRoomTLVCharSet1",
+			ChatRoomTLVLang1:              "ChatRoomTLVLang1",
+			ChatRoomTLVCharSet2:           "ChatRoomTLVCharSet2",
+			ChatRoomTLVLang2:              "ChatRoomTLVLang2",
+			ChatRoomTLVMaxMsgVisLen:       "ChatRoomTLVMaxMsgVisLen",
+		},
+		"ChatTLV": {
+			ChatTLVPublicWhisperFlag:    "ChatTLVPublicWhisperFlag",
+			ChatTLVWhisperToUser:        "ChatTLVWhisperToUser",
+			ChatTLVSenderInformation:    "ChatTLVSenderInformation",
+			ChatTLVMessageInfo:          "ChatTLVMessageInfo",
+			ChatTLVEnableReflectionFlag: "ChatTLVEnableReflectionFlag",
+		},
+		"ErrorTLV": {
+			ErrorTLVFailURL:        "ErrorTLVFailURL",
+			ErrorTLVErrorSubcode:   "ErrorTLVErrorSubcode",
+			ErrorTLVErrorText:      "ErrorTLVErrorText",
+			ErrorTLVErrorInfoCLSID: "ErrorTLVErrorInfoCLSID",
+			ErrorTLVErrorInfoData:  "ErrorTLVErrorInfoData",
+		},
+		"FeedbagAttributes": {
+			FeedbagAttributesShared:                  "FeedbagAttributesShared",
+			FeedbagAttributesInvited:                 "FeedbagAttributesInvited",
+			FeedbagAttributesPending:                 "FeedbagAttributesPending",
+			FeedbagAttributesTimeT:                   "FeedbagAttributesTimeT",
+			FeedbagAttributesDenied:                  "FeedbagAttributesDenied",
+			FeedbagAttributesSwimIndex:               "FeedbagAttributesSwimIndex",
+			FeedbagAttributesRecentBuddy:             "FeedbagAttributesRecentBuddy",
+			FeedbagAttributesAutoBot:                 "FeedbagAttributesAutoBot",
+			FeedbagAttributesInteraction:             "FeedbagAttributesInteraction",
+			FeedbagAttributesMegaBot:                 "FeedbagAttributesMegaBot",
+			FeedbagAttributesOrder:                   "FeedbagAttributesOrder",
+			FeedbagAttributesBuddyPrefs:              "FeedbagAttributesBuddyPrefs",
+			FeedbagAttributesPdMode:                  "FeedbagAttributesPdMode",
+			FeedbagAttributesPdMask:                  "FeedbagAttributesPdMask",
+			FeedbagAttributesPdFlags:                 "FeedbagAttributesPdFlags",
+			FeedbagAttributesClientPrefs:             "FeedbagAttributesClientPrefs",
+			FeedbagAttributesLanguage:                "FeedbagAttributesLanguage",
+			FeedbagAttributesFishUri:                 "FeedbagAttributesFishUri",
+			FeedbagAttributesWirelessPdMode:          "FeedbagAttributesWirelessPdMode",
+			FeedbagAttributesWirelessIgnoreMode:      "FeedbagAttributesWirelessIgnoreMode",
+			FeedbagAttributesFishPdMode:              "FeedbagAttributesFishPdMode",
+			FeedbagAttributesFishIgnoreMode:          "FeedbagAttributesFishIgnoreMode",
+			FeedbagAttributesCreateTime:              "FeedbagAttributesCreateTime",
+			FeedbagAttributesBartInfo:                "FeedbagAttributesBartInfo",
+			FeedbagAttributesBuddyPrefsValid:         "FeedbagAttributesBuddyPrefsValid",
+			FeedbagAttributesBuddyPrefs2:             "FeedbagAttributesBuddyPrefs2",
+			FeedbagAttributesBuddyPrefs2Valid:        "FeedbagAttributesBuddyPrefs2Valid",
+			FeedbagAttributesBartList:                "FeedbagAttributesBartList",
+			FeedbagAttributesArriveSound:             "FeedbagAttributesArriveSound",
+			FeedbagAttributesLeaveSound:              "FeedbagAttributesLeaveSound",
+			FeedbagAttributesImage:                   "FeedbagAttributesImage",
+			FeedbagAttributesColorBg:                 "FeedbagAttributesColorBg",
+			FeedbagAttributesColorFg:                 "FeedbagAttributesColorFg",
+			FeedbagAttributesAlias:                   "FeedbagAttributesAlias",
+			FeedbagAttributesPassword:                "FeedbagAttributesPassword",
+			FeedbagAttributesDisabled:                "FeedbagAttributesDisabled",
+			FeedbagAttributesCollapsed:               "FeedbagAttributesCollapsed",
+			FeedbagAttributesUrl:                     "FeedbagAttributesUrl",
+			FeedbagAttributesActiveList:              "FeedbagAttributesActiveList",
+			FeedbagAttributesEmailAddr:               "FeedbagAttributesEmailAddr",
+			FeedbagAttributesPhoneNumber:             "FeedbagAttributesPhoneNumber",
+			FeedbagAttributesCellPhoneNumber:         "FeedbagAttributesCellPhoneNumber",
+			FeedbagAttributesSmsPhoneNumber:          "FeedbagAttributesSmsPhoneNumber",
+			FeedbagAttributesWireless:                "FeedbagAttributesWireless",
+			FeedbagAttributesNote:                    "FeedbagAttributesNote",
+			FeedbagAttributesAlertPrefs:              "FeedbagAttributesAlertPrefs",
+			FeedbagAttributesBudalertSound:           "FeedbagAttributesBudalertSound",
+			FeedbagAttributesStockalertValue:         "FeedbagAttributesStockalertValue",
+			FeedbagAttributesTpalertEditUrl:          "FeedbagAttributesTpalertEditUrl",
+			FeedbagAttributesTpalertDeleteUrl:        "FeedbagAttributesTpalertDeleteUrl",
+			FeedbagAttributesTpprovMorealertsUrl:     "FeedbagAttributesTpprovMorealertsUrl",
+			FeedbagAttributesFish:                    "FeedbagAttributesFish",
+			FeedbagAttributesXunconfirmedxLastAccess: "FeedbagAttributesXunconfirmedxLastAccess",
+			FeedbagAttributesImSent:                  "FeedbagAttributesImSent",
+			FeedbagAttributesOnlineTime:              "FeedbagAttributesOnlineTime",
+			FeedbagAttributesAwayMsg:                 "FeedbagAttributesAwayMsg",
+			FeedbagAttributesImReceived:              "FeedbagAttributesImReceived",
+			FeedbagAttributesBuddyfeedView:           "FeedbagAttributesBuddyfeedView",
+			FeedbagAttributesWorkPhoneNumber:         "FeedbagAttributesWorkPhoneNumber",
+			FeedbagAttributesOtherPhoneNumber:        "FeedbagAttributesOtherPhoneNumber",
+			FeedbagAttributesWebPdMode:               "FeedbagAttributesWebPdMode",
+			FeedbagAttributesFirstCreationTimeXc:     "FeedbagAttributesFirstCreationTimeXc",
+			FeedbagAttributesPdModeXc:                "FeedbagAttributesPdModeXc",
+		},
+		"ICBMRdvTLVTags": {
+			ICBMRdvTLVTagsRdvChan:             "ICBMRdvTLVTagsRdvChan",
+			ICBMRdvTLVTagsRdvIP:               "ICBMRdvTLVTagsRdvIP",
+			ICBMRdvTLVTagsRequesterIP:         "ICBMRdvTLVTagsRequesterIP",
+			ICBMRdvTLVTagsVerifiedIP:          "ICBMRdvTLVTagsVerifiedIP",
+			ICBMRdvTLVTagsPort:                "ICBMRdvTLVTagsPort",
+			ICBMRdvTLVTagsDownloadURL:         "ICBMRdvTLVTagsDownloadURL",
+			ICBMRdvTLVTagsDownloadURL2:        "ICBMRdvTLVTagsDownloadURL2",
+			ICBMRdvTLVTagsVerifiedDownloadURL: "ICBMRdvTLVTagsVerifiedDownloadURL",
+			ICBMRdvTLVTagsSeqNum:              "ICBMRdvTLVTagsSeqNum",
+			ICBMRdvTLVTagsCancelReason:        "ICBMRdvTLVTagsCancelReason",
+			ICBMRdvTLVTagsInvitation:          "ICBMRdvTLVTagsInvitation",
+			ICBMRdvTLVTagsInviteMIMECharset:   "ICBMRdvTLVTagsInviteMIMECharset",
+			ICBMRdvTLVTagsInviteMIMELang:      "ICBMRdvTLVTagsInviteMIMELang",
+			ICBMRdvTLVTagsRequestHostChk:      "ICBMRdvTLVTagsRequestHostChk",
+			ICBMRdvTLVTagsUseARS:              "ICBMRdvTLVTagsUseARS",
+			ICBMRdvTLVTagsRequestSecure:       "ICBMRdvTLVTagsRequestSecure",
+			ICBMRdvTLVTagsMaxProtoVersion:     "ICBMRdvTLVTagsMaxProtoVersion",
+			ICBMRdvTLVTagsMinProtoVersion:     "ICBMRdvTLVTagsMinProtoVersion",
+			ICBMRdvTLVTagsCounterReason:       "ICBMRdvTLVTagsCounterReason",
+			ICBMRdvTLVTagsInviteMIMEType:      "ICBMRdvTLVTagsInviteMIMEType",
+			ICBMRdvTLVTagsIPXOR:               "ICBMRdvTLVTagsIPXOR",
+			ICBMRdvTLVTagsPortXOR:             "ICBMRdvTLVTagsPortXOR",
+			ICBMRdvTLVTagsAddrList:            "ICBMRdvTLVTagsAddrList",
+			ICBMRdvTLVTagsSessID:              "ICBMRdvTLVTagsSessID",
+			ICBMRdvTLVTagsRolloverID:          "ICBMRdvTLVTagsRolloverID",
+			ICBMRdvTLVTagsSvcData:             "ICBMRdvTLVTagsSvcData",
+		},
+		"ICBMTLV": {
+			ICBMTLVAOLIMData:      "ICBMTLVAOLIMData",
+			ICBMTLVRequestHostAck: "ICBMTLVRequestHostAck",
+			ICBMTLVAutoResponse:   "ICBMTLVAutoResponse",
+			ICBMTLVData:           "ICBMTLVData",
+			ICBMTLVStore:          "ICBMTLVStore",
+			ICBMTLVICQBlob:        "ICBMTLVICQBlob",
+			ICBMTLVAvatarInfo:     "ICBMTLVAvatarInfo",
+			ICBMTLVWantAvatar:     "ICBMTLVWantAvatar",
+			ICBMTLVMultiUser:      "ICBMTLVMultiUser",
+			ICBMTLVWantEvents:     "ICBMTLVWantEvents",
+			ICBMTLVSubscriptions:  "ICBMTLVSubscriptions",
+			ICBMTLVBART:           "ICBMTLVBART",
+			ICBMTLVHostImID:       "ICBMTLVHostImID",
+			ICBMTLVHostImArgs:     "ICBMTLVHostImArgs",
+			ICBMTLVSendTime:       "ICBMTLVSendTime",
+			ICBMTLVFriendlyName:   "ICBMTLVFriendlyName",
+			ICBMTLVAnonymous:      "ICBMTLVAnonymous",
+			ICBMTLVWidgetName:     "ICBMTLVWidgetName",
+		},
+		"ICQTLVTags": {
+			ICQTLVTagsMetadata:                  "ICQTLVTagsMetadata",
+			ICQTLVTagsUIN:                       "ICQTLVTagsUIN",
+			ICQTLVTagsFirstName:                 "ICQTLVTagsFirstName",
+			ICQTLVTagsLastName:                  "ICQTLVTagsLastName",
+			ICQTLVTagsNickname:                  "ICQTLVTagsNickname",
+			ICQTLVTagsEmail:                     "ICQTLVTagsEmail",
+			ICQTLVTagsAgeRangeSearch:            "ICQTLVTagsAgeRangeSearch",
+			ICQTLVTagsAge:                       "ICQTLVTagsAge",
+			ICQTLVTagsGender:                    "ICQTLVTagsGender",
+			ICQTLVTagsSpokenLanguage:            "ICQTLVTagsSpokenLanguage",
+			ICQTLVTagsHomeCityName:              "ICQTLVTagsHomeCityName",
+			ICQTLVTagsHomeStateAbbr:             "ICQTLVTagsHomeStateAbbr",
+			ICQTLVTagsHomeCountryCode:           "ICQTLVTagsHomeCountryCode",
+			ICQTLVTagsWorkCompanyName:           "ICQTLVTagsWorkCompanyName",
+			ICQTLVTagsWorkDepartmentName:        "ICQTLVTagsWorkDepartmentName",
+			ICQTLVTagsWorkPositionTitle:         "ICQTLVTagsWorkPositionTitle",
+			ICQTLVTagsWorkOccupationCode:        "ICQTLVTagsWorkOccupationCode",
+			ICQTLVTagsAffiliationsNode:          "ICQTLVTagsAffiliationsNode",
+			ICQTLVTagsInterestsNode:             "ICQTLVTagsInterestsNode",
+			ICQTLVTagsPastInfoNode:              "ICQTLVTagsPastInfoNode",
+			ICQTLVTagsHomepageCategoryKeywords:  "ICQTLVTagsHomepageCategoryKeywords",
+			ICQTLVTagsHomepageURL:               "ICQTLVTagsHomepageURL",
+			ICQTLVTagsWhitepagesSearchKeywords:  "ICQTLVTagsWhitepagesSearchKeywords",
+			ICQTLVTagsSearchOnlineUsersFlag:     "ICQTLVTagsSearchOnlineUsersFlag",
+			ICQTLVTagsBirthdayInfo:              "ICQTLVTagsBirthdayInfo",
+			ICQTLVTagsNotesText:                 "ICQTLVTagsNotesText",
+			ICQTLVTagsHomeStreetAddress:         "ICQTLVTagsHomeStreetAddress",
+			ICQTLVTagsHomeZipCode:               "ICQTLVTagsHomeZipCode",
+			ICQTLVTagsHomePhoneNumber:           "ICQTLVTagsHomePhoneNumber",
+			ICQTLVTagsHomeFaxNumber:             "ICQTLVTagsHomeFaxNumber",
+			ICQTLVTagsHomeCellularPhoneNumber:   "ICQTLVTagsHomeCellularPhoneNumber",
+			ICQTLVTagsWorkStreetAddress:         "ICQTLVTagsWorkStreetAddress",
+			ICQTLVTagsWorkCityName:              "ICQTLVTagsWorkCityName",
+			ICQTLVTagsWorkStateName:             "ICQTLVTagsWorkStateName",
+			ICQTLVTagsWorkCountryCode:           "ICQTLVTagsWorkCountryCode",
+			ICQTLVTagsWorkZipCode:               "ICQTLVTagsWorkZipCode",
+			ICQTLVTagsWorkPhoneNumber:           "ICQTLVTagsWorkPhoneNumber",
+			ICQTLVTagsWorkFaxNumber:             "ICQTLVTagsWorkFaxNumber",
+			ICQTLVTagsWorkWebpageURL:            "ICQTLVTagsWorkWebpageURL",
+			ICQTLVTagsShowWebStatusPermissions:  "ICQTLVTagsShowWebStatusPermissions",
+			ICQTLVTagsAuthorizationPermissions:  "ICQTLVTagsAuthorizationPermissions",
+			ICQTLVTagsGMTOffset:                 "ICQTLVTagsGMTOffset",
+			ICQTLVTagsOriginallyFromCity:        "ICQTLVTagsOriginallyFromCity",
+			ICQTLVTagsOriginallyFromState:       "ICQTLVTagsOriginallyFromState",
+			ICQTLVTagsOriginallyFromCountryCode: "ICQTLVTagsOriginallyFromCountryCode",
+		},
+		"KerberosTLV": {
+			KerberosTLVTicketRequest: "KerberosTLVTicketRequest",
+			KerberosTLVBOSServerInfo: "KerberosTLVBOSServerInfo",
+			KerberosTLVHostname:      "KerberosTLVHostname",
+			KerberosTLVCookie:        "KerberosTLVCookie",
+			KerberosTLVConnSettings:  "KerberosTLVConnSettings",
+		},
+		"LocateTLVTags": {
+			LocateTLVTagsInfoSigMime:         "LocateTLVTagsInfoSigMime",
+			LocateTLVTagsInfoSigData:         "LocateTLVTagsInfoSigData",
+			LocateTLVTagsInfoUnavailableMime: "LocateTLVTagsInfoUnavailableMime",
+			LocateTLVTagsInfoUnavailableData: "LocateTLVTagsInfoUnavailableData",
+			LocateTLVTagsInfoCapabilities:    "LocateTLVTagsInfoCapabilities",
+			LocateTLVTagsInfoCerts:           "LocateTLVTagsInfoCerts",
+			LocateTLVTagsInfoSigTime:         "LocateTLVTagsInfoSigTime",
+			LocateTLVTagsInfoUnavailableTime: "LocateTLVTagsInfoUnavailableTime",
+			LocateTLVTagsInfoSupportHostSig:  "LocateTLVTagsInfoSupportHostSig",
+			LocateTLVTagsInfoHtmlInfoData:    "LocateTLVTagsInfoHtmlInfoData",
+			LocateTLVTagsInfoHtmlInfoType:    "LocateTLVTagsInfoHtmlInfoType",
+		},
+		"LoginTLVTags": {
+			LoginTLVTagsScreenName:              "LoginTLVTagsScreenName",
+			LoginTLVTagsRoastedPassword:         "LoginTLVTagsRoastedPassword",
+			LoginTLVTagsClientIdentity:          "LoginTLVTagsClientIdentity",
+			LoginTLVTagsReconnectHere:           "LoginTLVTagsReconnectHere",
+			LoginTLVTagsAuthorizationCookie:     "LoginTLVTagsAuthorizationCookie",
+			LoginTLVTagsErrorSubcode:            "LoginTLVTagsErrorSubcode",
+			LoginTLVTagsPasswordHash:            "LoginTLVTagsPasswordHash",
+			LoginTLVTagsMultiConnFlags:          "LoginTLVTagsMultiConnFlags",
+			LoginTLVTagsRoastedKerberosPassword: "LoginTLVTagsRoastedKerberosPassword",
+			LoginTLVTagsRoastedTOCPassword:      "LoginTLVTagsRoastedTOCPassword",
+			LoginTLVTagsPlaintextPassword:       "LoginTLVTagsPlaintextPassword",
+		},
+		"ODirTLV": {
+			ODirTLVFirstName:    "ODirTLVFirstName",
+			ODirTLVLastName:     "ODirTLVLastName",
+			ODirTLVMiddleName:   "ODirTLVMiddleName",
+			ODirTLVMaidenName:   "ODirTLVMaidenName",
+			ODirTLVEmailAddress: "ODirTLVEmailAddress",
+			ODirTLVCountry:      "ODirTLVCountry",
+			ODirTLVState:        "ODirTLVState",
+			ODirTLVCity:         "ODirTLVCity",
+			ODirTLVScreenName:   "ODirTLVScreenName",
+			ODirTLVSearchType:   "ODirTLVSearchType",
+			ODirTLVInterest:     "ODirTLVInterest",
+			ODirTLVNickName:     "ODirTLVNickName",
+			ODirTLVZIP:          "ODirTLVZIP",
+			ODirTLVRegion:       "ODirTLVRegion",
+			ODirTLVAddress:      "ODirTLVAddress",
+		},
+		"OServiceTLVTags": {
+			OServiceTLVTagsReconnectHere: "OServiceTLVTagsReconnectHere",
+			OServiceTLVTagsLoginCookie:   "OServiceTLVTagsLoginCookie",
+			OServiceTLVTagsGroupID:       "OServiceTLVTagsGroupID",
+			OServiceTLVTagsSSLCertName:   "OServiceTLVTagsSSLCertName",
+			OServiceTLVTagsSSLState:      "OServiceTLVTagsSSLState",
+		},
+		"OserviceTLVTags": {
+			OserviceTLVTagsSSLUseSSL: "OserviceTLVTagsSSLUseSSL",
+		},
+		"PermitDenyTLV": {
+			PermitDenyTLVMaxPermits:     "PermitDenyTLVMaxPermits",
+			PermitDenyTLVMaxDenies:      "PermitDenyTLVMaxDenies",
+			PermitDenyTLVMaxTempPermits: "PermitDenyTLVMaxTempPermits",
+		},
+		"PopupTLV": {
+			PopupTLVMessage: "PopupTLVMessage",
+			PopupTLVURL:     "PopupTLVURL",
+			PopupTLVWidth:   "PopupTLVWidth",
+			PopupTLVHeight:  "PopupTLVHeight",
+			PopupTLVDelay:   "PopupTLVDelay",
+		},
+		"UserLookupTLV": {
+			UserLookupTLVEmailAddress: "UserLookupTLVEmailAddress",
+		},
+	}
+)
@@ -21,3 +21,29 @@ func TestSubGroupName_HappyPath(t *testing.T) {
 func TestSubGroupName_InvalidFoodGroup(t *testing.T) {
 	assert.Equal(t, "unknown", SubGroupName(2142, OServiceServiceRequest))
 }
+
+func TestSubGroupName_UnknownSubGroup(t *testing.T) {
+	assert.Equal(t, "unknown", SubGroupName(ICBM, 0xFFFF))
+}
+
+func TestSubGroupName_AllFoodGroups(t *testing.T) {
+	assert.Equal(t, "BUCPChallengeRequest", SubGroupName(BUCP, BUCPChallengeRequest))
+	assert.Equal(t, "KerberosLoginRequest", SubGroupName(Kerberos, KerberosLoginRequest))
+	assert.Equal(t, "UserLookupFindByEmail", SubGroupName(UserLookup, UserLookupFindByEmail))
+}
+
+func TestTLVTagName(t *testing.T) {
+	assert.Equal(t, "ICBMTLVRequestHostAck", TLVTagName("ICBMTLV", ICBMTLVRequestHostAck))
+	assert.Equal(t, "LocateTLVTagsInfoSigData", TLVTagName("LocateTLVTags", LocateTLVTagsInfoSigData))
+	assert.Equal(t, "FeedbagAttributesOrder", TLVTagName("FeedbagAttributes", FeedbagAttributesOrder))
+	assert.Equal(t, "unknown", TLVTagName("ICBMTLV", 0xFFFF))
+	assert.Equal(t, "unknown", TLVTagName("NoSuchTLV", ICBMTLVRequestHostAck))
+}
+
+func TestSNACFrame_String(t *testing.T) {
+	frame := SNACFrame{FoodGroup: ICBM, SubGroup: ICBMChannelMsgToHost}
+	assert.Equal(t, "ICBM/ICBMChannelMsgToHost (0x0004/0x0006)", frame.String())
+
+	frame = SNACFrame{FoodGroup: 0x0BAD, SubGroup: 0x0001}
+	assert.Equal(t, "unknown/unknown (0x0BAD/0x0001)", frame.String())
+}
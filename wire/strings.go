@@ -0,0 +1,166 @@
+package wire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// The string types below declare the encoding of a string field in its
+// type, so that struct definitions don't need `len_prefix` and `nullterm`
+// tags and the conversions between wire bytes and Go strings live in one
+// place. Length prefixes are written in the byte order of the message,
+// which is little-endian for ICQ messages.
+type (
+	// String08 is a byte string, such as an ASCII screen name, preceded by
+	// its length as a uint8.
+	String08 string
+	// String16 is a byte string preceded by its length as a uint16.
+	String16 string
+	// StringNT16 is a null-terminated byte string preceded by its length,
+	// including the null, as a uint16. It's the string format of ICQ meta
+	// requests and replies. The empty string is sent as a zero length with
+	// no null.
+	StringNT16 string
+	// UCS2String16 is text encoded as UCS-2, big-endian UTF-16, preceded by
+	// its length in bytes as a uint16. The Go value holds the text as
+	// UTF-8.
+	UCS2String16 string
+)
+
+var (
+	// ErrStringTooLong indicates that a string doesn't fit in its length
+	// prefix.
+	ErrStringTooLong = errors.New("string too long for its length prefix")
+	// ErrInvalidUCS2 indicates that UCS-2 text has an odd number of bytes.
+	ErrInvalidUCS2 = errors.New("UCS-2 text must have an even number of bytes")
+)
+
+// stringEncoding describes how a typed string is put on the wire.
+type stringEncoding struct {
+	lenPrefix      reflect.Kind
+	nullTerminated bool
+	ucs2           bool
+}
+
+// stringEncodings maps the typed strings to their encoding.
+var stringEncodings = map[reflect.Type]stringEncoding{
+	reflect.TypeFor[String08]():     {lenPrefix: reflect.Uint8},
+	reflect.TypeFor[String16]():     {lenPrefix: reflect.Uint16},
+	reflect.TypeFor[StringNT16]():   {lenPrefix: reflect.Uint16, nullTerminated: true},
+	reflect.TypeFor[UCS2String16](): {lenPrefix: reflect.Uint16, ucs2: true},
+}
+
+// EncodeUCS2 returns s as big-endian UTF-16. Characters outside the Basic
+// Multilingual Plane, which UCS-2 can't represent, are sent as surrogate
+// pairs, which clients that support them display correctly.
+func EncodeUCS2(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 0, 2*len(units))
+	for _, u := range units {
+		b = binary.BigEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+// DecodeUCS2 returns the big-endian UTF-16 text b as UTF-8. It returns
+// ErrInvalidUCS2 if b has an odd number of bytes.
+func DecodeUCS2(b []byte) (string, error) {
+	if len(b)%2 != 0 {
+		return "", ErrInvalidUCS2
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// DecodeLatin1 returns the ISO 8859-1 text b as UTF-8.
+func DecodeLatin1(b []byte) string {
+	buf := make([]byte, 0, len(b))
+	for _, c := range b {
+		buf = utf8.AppendRune(buf, rune(c))
+	}
+	return string(buf)
+}
+
+// checkTypedStringTag returns an error if a typed string field also has
+// tags that set its encoding.
+func checkTypedStringTag(t reflect.Type, oscTag oscarTag) error {
+	if oscTag.hasLenPrefix || oscTag.hasCountPrefix || oscTag.nullTerminated {
+		return fmt.Errorf("%w: %s sets its own encoding and can't have len_prefix, count_prefix or nullterm",
+			errInvalidStructTag, t.Name())
+	}
+	return nil
+}
+
+func marshalTypedString(enc stringEncoding, v reflect.Value, w io.Writer, order binary.ByteOrder) error {
+	var b []byte
+	if enc.ucs2 {
+		b = EncodeUCS2(v.String())
+	} else {
+		b = []byte(v.String())
+	}
+	if enc.nullTerminated && len(b) > 0 {
+		b = append(b, 0x00)
+	}
+
+	if maxLen := uint64(1)<<(8*kindSize(enc.lenPrefix)) - 1; uint64(len(b)) > maxLen {
+		return fmt.Errorf("%w: %d bytes, at most %d allowed", ErrStringTooLong, len(b), maxLen)
+	}
+	if err := marshalUnsignedInt(enc.lenPrefix, len(b), w, order); err != nil {
+		return err
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func unmarshalTypedString(enc stringEncoding, v reflect.Value, r io.Reader, order binary.ByteOrder) error {
+	bufLen, err := unmarshalUnsignedInt(enc.lenPrefix, r, order)
+	if err != nil {
+		return err
+	}
+	buf, err := readPrefixed(r, bufLen)
+	if err != nil {
+		return err
+	}
+	if enc.nullTerminated && len(buf) > 0 {
+		if buf[len(buf)-1] != 0x00 {
+			return errNotNullTerminated
+		}
+		buf = buf[:len(buf)-1]
+	}
+
+	if !enc.ucs2 {
+		v.SetString(string(buf))
+		return nil
+	}
+	s, err := DecodeUCS2(buf)
+	if err != nil {
+		return err
+	}
+	v.SetString(s)
+	return nil
+}
+
+// kindSize returns the size in bytes of an unsigned integer kind.
+func kindSize(k reflect.Kind) int {
+	switch k {
+	case reflect.Uint8:
+		return 1
+	case reflect.Uint16:
+		return 2
+	case reflect.Uint32:
+		return 4
+	default:
+		return 8
+	}
+}
@@ -0,0 +1,149 @@
+package wire
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedStrings(t *testing.T) {
+	type message struct {
+		ScreenName String08
+		Profile    String16
+		Nickname   StringNT16
+		Away       UCS2String16
+	}
+
+	tests := []struct {
+		name         string
+		given        message
+		littleEndian bool
+		want         []byte
+	}{
+		{
+			name: "big-endian",
+			given: message{
+				ScreenName: "chuck",
+				Profile:    "hi",
+				Nickname:   "Chuck",
+				Away:       "brb ☕",
+			},
+			want: []byte{
+				0x05, 'c', 'h', 'u', 'c', 'k',
+				0x00, 0x02, 'h', 'i',
+				0x00, 0x06, 'C', 'h', 'u', 'c', 'k', 0x00,
+				0x00, 0x0A, 0x00, 'b', 0x00, 'r', 0x00, 'b', 0x00, ' ', 0x26, 0x15,
+			},
+		},
+		{
+			name: "little-endian",
+			given: message{
+				ScreenName: "chuck",
+				Profile:    "hi",
+				Nickname:   "Chuck",
+				Away:       "ok",
+			},
+			littleEndian: true,
+			want: []byte{
+				0x05, 'c', 'h', 'u', 'c', 'k',
+				0x02, 0x00, 'h', 'i',
+				0x06, 0x00, 'C', 'h', 'u', 'c', 'k', 0x00,
+				0x04, 0x00, 0x00, 'o', 0x00, 'k',
+			},
+		},
+		{
+			name:  "empty strings",
+			given: message{},
+			want:  []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			have := message{}
+			if tt.littleEndian {
+				require.NoError(t, MarshalLE(tt.given, buf))
+				require.NoError(t, UnmarshalLEStrict(&have, buf.Bytes()))
+			} else {
+				require.NoError(t, MarshalBE(tt.given, buf))
+				require.NoError(t, UnmarshalBEStrict(&have, buf.Bytes()))
+			}
+			assert.Equal(t, tt.want, buf.Bytes())
+			assert.Equal(t, tt.given, have)
+		})
+	}
+}
+
+func TestTypedStrings_Errors(t *testing.T) {
+	t.Run("too long for the length prefix", func(t *testing.T) {
+		err := MarshalBE(struct{ S String08 }{S: String08(strings.Repeat("a", 256))}, &bytes.Buffer{})
+		assert.ErrorIs(t, err, ErrStringTooLong)
+	})
+
+	t.Run("encoding tags aren't allowed", func(t *testing.T) {
+		err := MarshalBE(struct {
+			S String08 `oscar:"len_prefix=uint16"`
+		}{}, &bytes.Buffer{})
+		assert.ErrorIs(t, err, errInvalidStructTag)
+	})
+
+	t.Run("missing null terminator", func(t *testing.T) {
+		have := struct{ S StringNT16 }{}
+		err := UnmarshalBE(&have, bytes.NewReader([]byte{0x00, 0x02, 'h', 'i'}))
+		assert.ErrorIs(t, err, errNotNullTerminated)
+	})
+
+	t.Run("odd UCS-2 length", func(t *testing.T) {
+		have := struct{ S UCS2String16 }{}
+		err := UnmarshalBE(&have, bytes.NewReader([]byte{0x00, 0x03, 0x00, 'h', 0x00}))
+		assert.ErrorIs(t, err, ErrInvalidUCS2)
+	})
+}
+
+func TestUCS2(t *testing.T) {
+	for _, s := range []string{"", "hello", "héllo wörld", "☕", "😀"} {
+		b := EncodeUCS2(s)
+		have, err := DecodeUCS2(b)
+		require.NoError(t, err)
+		assert.Equal(t, s, have)
+	}
+	assert.Equal(t, []byte{0xD8, 0x3D, 0xDE, 0x00}, EncodeUCS2("😀"))
+}
+
+func TestDecodeLatin1(t *testing.T) {
+	assert.Equal(t, "café", DecodeLatin1([]byte{'c', 'a', 'f', 0xE9}))
+}
+
+func TestICBMCh1Message_DecodedText(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  ICBMCh1Message
+		want string
+	}{
+		{
+			name: "ASCII",
+			msg:  ICBMCh1Message{Charset: ICBMMessageEncodingASCII, Text: []byte("hello")},
+			want: "hello",
+		},
+		{
+			name: "Unicode",
+			msg:  ICBMCh1Message{Charset: ICBMMessageEncodingUnicode, Text: EncodeUCS2("привет")},
+			want: "привет",
+		},
+		{
+			name: "Latin-1",
+			msg:  ICBMCh1Message{Charset: ICBMMessageEncodingLatin1, Text: []byte{'c', 'a', 'f', 0xE9}},
+			want: "café",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := tt.msg.DecodedText()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, have)
+		})
+	}
+}
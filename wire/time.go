@@ -0,0 +1,34 @@
+package wire
+
+import (
+	"math"
+	"time"
+)
+
+// EpochSeconds converts t to the timestamp format used by OSCAR TLVs such as
+// OServiceUserInfoSignonTOD, OServiceUserInfoMemberSince and
+// ChatRoomTLVCreateTime: seconds since the Unix epoch, as an unsigned 32-bit
+// value. The value doesn't depend on t's location. The zero time.Time maps
+// to 0, and times outside the range of the format are clamped to it.
+func EpochSeconds(t time.Time) uint32 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint32(min(max(t.Unix(), 0), math.MaxUint32))
+}
+
+// FromEpochSeconds converts an OSCAR timestamp to a time.Time in UTC. It is
+// the inverse of EpochSeconds, so 0 maps to the zero time.Time, which
+// clients use to mean "not set".
+func FromEpochSeconds(secs uint32) time.Time {
+	if secs == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(secs), 0).UTC()
+}
+
+// DurationMinutes converts d to the whole minutes used by OSCAR TLVs such as
+// OServiceUserInfoIdleTime, clamped to the range of a uint16.
+func DurationMinutes(d time.Duration) uint16 {
+	return uint16(min(max(d/time.Minute, 0), math.MaxUint16))
+}
@@ -0,0 +1,74 @@
+package wire
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEpochSeconds(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want uint32
+	}{
+		{
+			name: "zero time",
+			t:    time.Time{},
+			want: 0,
+		},
+		{
+			name: "UTC",
+			t:    time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+			want: 1709294400,
+		},
+		{
+			name: "location doesn't change the instant",
+			t:    time.Date(2024, 3, 1, 7, 0, 0, 0, ny),
+			want: 1709294400,
+		},
+		{
+			name: "sub-second precision is dropped",
+			t:    time.Date(2024, 3, 1, 12, 0, 0, 999_999_999, time.UTC),
+			want: 1709294400,
+		},
+		{
+			name: "before the epoch",
+			t:    time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC),
+			want: 0,
+		},
+		{
+			name: "after 2106",
+			t:    time.Date(2107, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: math.MaxUint32,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, EpochSeconds(tt.t))
+		})
+	}
+}
+
+func TestFromEpochSeconds(t *testing.T) {
+	assert.True(t, FromEpochSeconds(0).IsZero())
+
+	got := FromEpochSeconds(1709294400)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), got)
+	assert.Equal(t, time.UTC, got.Location())
+
+	now := time.Now().Truncate(time.Second)
+	assert.True(t, now.Equal(FromEpochSeconds(EpochSeconds(now))))
+}
+
+func TestDurationMinutes(t *testing.T) {
+	assert.Equal(t, uint16(0), DurationMinutes(-time.Hour))
+	assert.Equal(t, uint16(1), DurationMinutes(119*time.Second))
+	assert.Equal(t, uint16(90), DurationMinutes(90*time.Minute))
+	assert.Equal(t, uint16(math.MaxUint16), DurationMinutes(365*24*time.Hour))
+}
@@ -94,6 +94,41 @@ func (s *TLVList) Replace(new TLV) {
 	}
 }
 
+// Set updates the values of TLVs in the list with the same tag as new, like
+// Replace, or adds new to the end of the list if there are none. The other
+// TLVs are left untouched, so that a message can be modified and sent on
+// without dropping TLVs that the server doesn't know about.
+func (s *TLVList) Set(new TLV) {
+	if !s.HasTag(new.Tag) {
+		s.Append(new)
+		return
+	}
+	s.Replace(new)
+}
+
+// Equal indicates whether s and other contain the same TLVs, regardless of
+// their order.
+func (s *TLVList) Equal(other TLVList) bool {
+	if len(*s) != len(other) {
+		return false
+	}
+	matched := make([]bool, len(other))
+	for _, a := range *s {
+		found := false
+		for i, b := range other {
+			if !matched[i] && a.Tag == b.Tag && bytes.Equal(a.Value, b.Value) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 // Uint8 retrieves a byte value from the TLVList associated with the specified tag.
 //
 // If the specified tag is found,
@@ -121,6 +121,29 @@ func TestTLVList_Replace(t *testing.T) {
 	}
 }
 
+func TestTLVList_Set(t *testing.T) {
+	t.Run("replaces existing TLVs in place", func(t *testing.T) {
+		list := TLVList{
+			NewTLVBE(0x01, []byte{0x01}),
+			NewTLVBE(0x02, []byte{0x02}),
+		}
+		list.Set(NewTLVBE(0x01, []byte{0xAA}))
+		assert.Equal(t, TLVList{
+			NewTLVBE(0x01, []byte{0xAA}),
+			NewTLVBE(0x02, []byte{0x02}),
+		}, list)
+	})
+
+	t.Run("appends a missing TLV", func(t *testing.T) {
+		list := TLVList{NewTLVBE(0x01, []byte{0x01})}
+		list.Set(NewTLVBE(0x07, []byte{0xAA}))
+		assert.Equal(t, TLVList{
+			NewTLVBE(0x01, []byte{0x01}),
+			NewTLVBE(0x07, []byte{0xAA}),
+		}, list)
+	})
+}
+
 func TestTLVList_ICQString(t *testing.T) {
 	// create a new TLV list
 	tlv := TLVList{}
@@ -433,3 +456,18 @@ func TestTLVList_HasTag(t *testing.T) {
 	assert.True(t, list.HasTag(0))
 	assert.False(t, list.HasTag(3))
 }
+
+func TestTLVList_Equal(t *testing.T) {
+	list := TLVList{
+		NewTLVBE(1, uint16(1)),
+		NewTLVBE(2, "two"),
+	}
+
+	assert.True(t, list.Equal(TLVList{NewTLVBE(2, "two"), NewTLVBE(1, uint16(1))}))
+	assert.False(t, list.Equal(TLVList{NewTLVBE(1, uint16(1))}))
+	assert.False(t, list.Equal(TLVList{NewTLVBE(1, uint16(1)), NewTLVBE(2, "three")}))
+	assert.False(t, list.Equal(TLVList{NewTLVBE(1, uint16(1)), NewTLVBE(1, uint16(1))}))
+
+	empty := TLVList{}
+	assert.True(t, empty.Equal(nil))
+}